@@ -0,0 +1,278 @@
+// Package analyze reports how data compresses with LZ4: the distributions
+// of match offsets and lengths, how much of the data is left as literals,
+// and the compression ratio block by block. The reports are plain data, for
+// choosing window sizes, dictionaries and levels for a dataset or for
+// plotting elsewhere.
+//
+// AnalyzeBlock walks the sequences of a compressed block, AnalyzeFrame
+// decodes a stream of frames, and AnalyzeData compresses data at a given
+// level and block size first, so that settings can be compared without
+// writing frames.
+package analyze
+
+import (
+	"io"
+	"math"
+	"math/bits"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Histogram counts values in power-of-two buckets
+type Histogram struct {
+	// Buckets[i] counts the values v with 1<<i <= v < 1<<(i+1)
+	Buckets [32]uint64
+	// Count and Sum are the number and total of the values
+	Count uint64
+	Sum   uint64
+}
+
+// add counts v, which must be positive
+func (h *Histogram) add(v int) {
+	h.Buckets[min(bits.Len(uint(v))-1, len(h.Buckets)-1)]++
+	h.Count++
+	h.Sum += uint64(v)
+}
+
+// Merge adds the counts of o to h
+func (h *Histogram) Merge(o Histogram) {
+	for i, n := range o.Buckets {
+		h.Buckets[i] += n
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+}
+
+// Mean returns the mean of the values, or 0 if there are none
+func (h *Histogram) Mean() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return float64(h.Sum) / float64(h.Count)
+}
+
+// Quantile returns an upper bound for the value below which the fraction q
+// of the values fall: the end of the first bucket reaching it. For offsets
+// it is the window size that would keep that fraction of the matches. It
+// returns 0 if there are no values.
+func (h *Histogram) Quantile(q float64) int {
+	if h.Count == 0 {
+		return 0
+	}
+	want := uint64(math.Ceil(q * float64(h.Count)))
+	var seen uint64
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= want && seen > 0 {
+			return bucketEnd(i)
+		}
+	}
+	return bucketEnd(len(h.Buckets) - 1)
+}
+
+// bucketEnd returns the largest value of bucket i, which is past the range
+// of int for the last buckets on 32-bit platforms and is capped there
+func bucketEnd(i int) int {
+	return int(min(uint64(1)<<(i+1)-1, math.MaxInt))
+}
+
+// BlockStats describes one compressed block, or the sum of several
+type BlockStats struct {
+	// Compressed and Uncompressed are the block's stored and decoded sizes
+	Compressed   int
+	Uncompressed int
+	// Stored is set for a block stored uncompressed in a frame
+	Stored bool
+	// Sequences is the number of sequences, and Literals and Matched the
+	// bytes produced by their literals and their matches
+	Sequences int
+	Literals  int
+	Matched   int
+	// Offsets and MatchLengths are the distributions of the matches, and
+	// LiteralRuns that of the lengths of the non-empty literal runs
+	Offsets      Histogram
+	MatchLengths Histogram
+	LiteralRuns  Histogram
+}
+
+// Ratio returns the compression ratio, uncompressed to compressed size, or
+// 0 for an empty block
+func (b *BlockStats) Ratio() float64 {
+	if b.Compressed == 0 {
+		return 0
+	}
+	return float64(b.Uncompressed) / float64(b.Compressed)
+}
+
+// LiteralRatio returns the fraction of the uncompressed bytes left as
+// literals, or 0 for an empty block
+func (b *BlockStats) LiteralRatio() float64 {
+	if b.Uncompressed == 0 {
+		return 0
+	}
+	return float64(b.Literals) / float64(b.Uncompressed)
+}
+
+// add counts one sequence of the block
+func (b *BlockStats) add(seq compress.Sequence) {
+	b.Sequences++
+	b.Literals += len(seq.Literals)
+	if len(seq.Literals) > 0 {
+		b.LiteralRuns.add(len(seq.Literals))
+	}
+	if seq.MatchLen > 0 {
+		b.Matched += seq.MatchLen
+		b.Offsets.add(seq.Offset)
+		b.MatchLengths.add(seq.MatchLen)
+	}
+}
+
+// merge adds the counts of o to b. Stored is left unchanged.
+func (b *BlockStats) merge(o *BlockStats) {
+	b.Compressed += o.Compressed
+	b.Uncompressed += o.Uncompressed
+	b.Sequences += o.Sequences
+	b.Literals += o.Literals
+	b.Matched += o.Matched
+	b.Offsets.Merge(o.Offsets)
+	b.MatchLengths.Merge(o.MatchLengths)
+	b.LiteralRuns.Merge(o.LiteralRuns)
+}
+
+// Report describes the blocks of a stream
+type Report struct {
+	// Total sums the stats of all blocks
+	Total BlockStats
+	// Blocks holds the stats of every block, in stream order
+	Blocks []BlockStats
+}
+
+// addBlock appends the stats of a block to the report
+func (r *Report) addBlock(b BlockStats) {
+	r.Blocks = append(r.Blocks, b)
+	r.Total.merge(&b)
+}
+
+// Heatmap returns the compression ratios along the stream in at most cells
+// cells, each covering an equal run of consecutive blocks, for plotting how
+// compressible the data is from start to end. With cells of 0 or at least
+// the number of blocks, every block has its own cell.
+func (r *Report) Heatmap(cells int) []float64 {
+	if cells <= 0 || cells > len(r.Blocks) {
+		cells = len(r.Blocks)
+	}
+	heat := make([]float64, cells)
+	for i := range heat {
+		var cell BlockStats
+		for j := i * len(r.Blocks) / cells; j < (i+1)*len(r.Blocks)/cells; j++ {
+			cell.merge(&r.Blocks[j])
+		}
+		heat[i] = cell.Ratio()
+	}
+	return heat
+}
+
+// AnalyzeBlock returns the stats of a compressed block, as produced by
+// compress.CompressBlockLevel. It returns compress.ErrCorruptBlock if the
+// sequences can't be parsed.
+func AnalyzeBlock(block []byte) (BlockStats, error) {
+	stats := BlockStats{Compressed: len(block)}
+	sr := compress.NewSequenceReader(block)
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return BlockStats{}, err
+		}
+		stats.add(seq)
+	}
+	stats.Uncompressed = stats.Literals + stats.Matched
+	return stats, nil
+}
+
+// AnalyzeFrame decodes the frames of r and returns the stats of their
+// blocks. The options are used as for a compress.Reader, for dictionaries
+// and checksums, except that DebugTrace is replaced. On an error the report
+// covers the blocks before it.
+func AnalyzeFrame(r io.Reader, options compress.ReaderOptions) (*Report, error) {
+	c := &collector{report: &Report{}}
+	options.DebugTrace = c.trace
+	zr := compress.NewReaderWithOptions(r, options)
+	zr.SetMetricsRecorder(c)
+	defer zr.Close()
+
+	_, err := io.Copy(io.Discard, zr)
+	return c.report, err
+}
+
+// AnalyzeData compresses data in blocks of blockSize bytes at the given
+// level, as a Writer would, and returns the stats of the blocks. Blocks a
+// Writer would store uncompressed are reported as stored.
+func AnalyzeData(data []byte, level compress.CompressionLevel, blockSize int) (*Report, error) {
+	if blockSize < compress.MinBlockSize || blockSize > compress.MaxBlockSize {
+		return nil, compress.ErrInvalidBlockSize
+	}
+	c, err := compress.NewCompressor(level)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{}
+	var dst []byte
+	for len(data) > 0 {
+		n := min(blockSize, len(data))
+		block := data[:n]
+		data = data[n:]
+
+		if n < compress.MinBlockSize || level == compress.NoCompression {
+			report.addBlock(storedStats(n))
+			continue
+		}
+		dst, err = c.CompressBlock(block, dst[:cap(dst)])
+		if err != nil {
+			return nil, err
+		}
+		if len(dst) >= n {
+			report.addBlock(storedStats(n))
+			continue
+		}
+		stats, err := AnalyzeBlock(dst)
+		if err != nil {
+			return nil, err
+		}
+		report.addBlock(stats)
+	}
+	return report, nil
+}
+
+// storedStats returns the stats of a block of n bytes stored uncompressed
+func storedStats(n int) BlockStats {
+	stats := BlockStats{Compressed: n, Uncompressed: n, Stored: true, Sequences: 1, Literals: n}
+	if n > 0 {
+		stats.LiteralRuns.add(n)
+	}
+	return stats
+}
+
+// collector builds a report from the trace events and block metrics of a
+// Reader, which reports every block's sequences before its sizes
+type collector struct {
+	report  *Report
+	current BlockStats
+}
+
+func (c *collector) trace(ev compress.TraceEvent) {
+	c.current.Stored = ev.Stored
+	c.current.add(ev.Sequence)
+}
+
+// RecordBlock implements compress.MetricsRecorder
+func (c *collector) RecordBlock(compressed, raw int, _ time.Duration) {
+	c.current.Compressed = compressed
+	c.current.Uncompressed = raw
+	c.report.addBlock(c.current)
+	c.current = BlockStats{}
+}
@@ -0,0 +1,190 @@
+package analyze
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// testData returns size bytes of text with repeats at varying distances,
+// followed by size/4 random bytes
+func testData(size int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	words := strings.Fields("the quick brown fox jumps over the lazy dog while compressors find matches")
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[rng.Intn(len(words))])
+		buf.WriteByte(' ')
+	}
+	data := buf.Bytes()[:size]
+	random := make([]byte, size/4)
+	rng.Read(random)
+	return append(data, random...)
+}
+
+// TestHistogram tests bucketing, merging and quantiles
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, v := range []int{1, 2, 3, 4, 7, 8, 65535} {
+		h.add(v)
+	}
+	want := [32]uint64{0: 1, 1: 2, 2: 2, 3: 1, 15: 1}
+	if h.Buckets != want || h.Count != 7 || h.Sum != 65560 {
+		t.Errorf("histogram = %v, want buckets %v, 7 values summing to 65560", h, want)
+	}
+
+	tests := []struct {
+		q    float64
+		want int
+	}{
+		{0, 1},
+		{0.5, 7},
+		{0.85, 15},
+		{1, 65535},
+	}
+	for _, tt := range tests {
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("Quantile(%v) = %d, want %d", tt.q, got, tt.want)
+		}
+	}
+
+	var merged Histogram
+	merged.Merge(h)
+	merged.Merge(h)
+	if merged.Count != 14 || merged.Buckets[1] != 4 || merged.Mean() != h.Mean() {
+		t.Errorf("Merge() = %v, want twice %v", merged, h)
+	}
+	if got := (&Histogram{}).Quantile(0.5); got != 0 {
+		t.Errorf("Quantile() of an empty histogram = %d, want 0", got)
+	}
+
+	// The last bucket ends past the range of int on 32-bit platforms
+	var last Histogram
+	last.Buckets[len(last.Buckets)-1], last.Count = 1, 1
+	if got, want := last.Quantile(1), int(min(uint64(math.MaxUint32), math.MaxInt)); got != want {
+		t.Errorf("Quantile() of the last bucket = %d, want %d", got, want)
+	}
+}
+
+// TestAnalyzeBlock tests that the stats of a block add up to its sizes
+func TestAnalyzeBlock(t *testing.T) {
+	src := testData(32 * 1024)[:32*1024]
+	block, err := compress.CompressBlockLevel(src, nil, compress.DefaultLevel)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel() error = %v", err)
+	}
+
+	stats, err := AnalyzeBlock(block)
+	if err != nil {
+		t.Fatalf("AnalyzeBlock() error = %v", err)
+	}
+	if stats.Compressed != len(block) || stats.Uncompressed != len(src) {
+		t.Errorf("AnalyzeBlock() sizes = %d, %d, want %d, %d", stats.Compressed, stats.Uncompressed, len(block), len(src))
+	}
+	if stats.Offsets.Count != uint64(stats.Sequences-1) || stats.MatchLengths.Sum != uint64(stats.Matched) {
+		t.Errorf("AnalyzeBlock() has %d offsets and %d matched bytes for %d sequences, %d matched",
+			stats.Offsets.Count, stats.MatchLengths.Sum, stats.Sequences, stats.Matched)
+	}
+	if stats.Offsets.Quantile(1) > 65535 || stats.MatchLengths.Buckets[0] != 0 || stats.MatchLengths.Buckets[1] != 0 {
+		t.Errorf("AnalyzeBlock() has offsets beyond the window or matches under 4 bytes")
+	}
+	if r := stats.Ratio(); r < 2 {
+		t.Errorf("Ratio() = %.2f, want at least 2 for text", r)
+	}
+
+	if _, err := AnalyzeBlock([]byte{0xF0, 0xFF}); err != compress.ErrCorruptBlock {
+		t.Errorf("AnalyzeBlock() of a corrupt block error = %v, want %v", err, compress.ErrCorruptBlock)
+	}
+}
+
+// TestAnalyzeFrame tests that analyzing a frame matches analyzing its data
+// compressed block by block, and that the heatmap shows the random tail
+func TestAnalyzeFrame(t *testing.T) {
+	data := testData(192 * 1024)
+
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{Level: compress.DefaultLevel, BlockSize: 64 * 1024})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	report, err := AnalyzeFrame(bytes.NewReader(buf.Bytes()), compress.ReaderOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeFrame() error = %v", err)
+	}
+	want, err := AnalyzeData(data, compress.DefaultLevel, 64*1024)
+	if err != nil {
+		t.Fatalf("AnalyzeData() error = %v", err)
+	}
+
+	if len(report.Blocks) != 4 || report.Total.Uncompressed != len(data) {
+		t.Fatalf("AnalyzeFrame() = %d blocks of %d bytes, want 4 blocks of %d", len(report.Blocks), report.Total.Uncompressed, len(data))
+	}
+	for i := range report.Blocks {
+		got, exp := report.Blocks[i], want.Blocks[i]
+		if got.Compressed != exp.Compressed || got.Stored != exp.Stored || got.Offsets != exp.Offsets || got.Literals != exp.Literals {
+			t.Errorf("block %d: AnalyzeFrame() = %+v, AnalyzeData() = %+v", i, got, exp)
+		}
+	}
+	if !report.Blocks[3].Stored || report.Blocks[3].LiteralRatio() != 1 {
+		t.Errorf("random block not reported as stored literals: %+v", report.Blocks[3])
+	}
+
+	heat := report.Heatmap(2)
+	if len(heat) != 2 || heat[0] <= heat[1] || heat[1] < 1 {
+		t.Errorf("Heatmap(2) = %v, want a falling ratio", heat)
+	}
+	if got := report.Heatmap(0); len(got) != 4 || got[3] != 1 {
+		t.Errorf("Heatmap(0) = %v, want one ratio per block, 1 for the stored one", got)
+	}
+}
+
+// TestAnalyzeFrameError tests that a corrupt frame reports the blocks before
+// the error
+func TestAnalyzeFrameError(t *testing.T) {
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{Level: 1, BlockSize: 64 * 1024})
+	w.Write(testData(128 * 1024)[:128*1024])
+	w.Close()
+
+	report, err := AnalyzeFrame(bytes.NewReader(buf.Bytes()[:buf.Len()-20]), compress.ReaderOptions{})
+	if err == nil || len(report.Blocks) != 1 {
+		t.Errorf("AnalyzeFrame() of a truncated frame = %d blocks, %v, want 1 block and an error", len(report.Blocks), err)
+	}
+}
+
+// TestAnalyzeDataLevels tests that higher levels compress better, and the
+// errors of AnalyzeData
+func TestAnalyzeDataLevels(t *testing.T) {
+	data := testData(64 * 1024)[:64*1024]
+	fast, err := AnalyzeData(data, 1, 64*1024)
+	if err != nil {
+		t.Fatalf("AnalyzeData() error = %v", err)
+	}
+	best, err := AnalyzeData(data, compress.MaxLevel, 64*1024)
+	if err != nil {
+		t.Fatalf("AnalyzeData() error = %v", err)
+	}
+	if best.Total.Ratio() <= fast.Total.Ratio() || best.Total.MatchLengths.Mean() <= fast.Total.MatchLengths.Mean() {
+		t.Errorf("level %d: ratio %.2f, mean match %.1f; level 1: ratio %.2f, mean match %.1f",
+			compress.MaxLevel, best.Total.Ratio(), best.Total.MatchLengths.Mean(), fast.Total.Ratio(), fast.Total.MatchLengths.Mean())
+	}
+
+	stored, err := AnalyzeData(data, compress.NoCompression, 16*1024)
+	if err != nil || len(stored.Blocks) != 4 || stored.Total.Ratio() != 1 {
+		t.Errorf("AnalyzeData() at NoCompression = %+v, %v, want 4 stored blocks", stored, err)
+	}
+	if _, err := AnalyzeData(data, 6, 8); err != compress.ErrInvalidBlockSize {
+		t.Errorf("AnalyzeData() with a block size of 8 error = %v, want %v", err, compress.ErrInvalidBlockSize)
+	}
+	if _, err := AnalyzeData(data, 13, 64*1024); err != compress.ErrInvalidCompressionLevel {
+		t.Errorf("AnalyzeData() at level 13 error = %v, want %v", err, compress.ErrInvalidCompressionLevel)
+	}
+}
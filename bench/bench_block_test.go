@@ -2,10 +2,11 @@ package bench
 
 import (
 	"bytes"
-	"crypto/rand"
+	"fmt"
 	"testing"
 
 	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
 const (
@@ -24,20 +25,17 @@ var (
 	benchLevels = []compress.CompressionLevel{1, 6, 9}
 )
 
-// Generate test data with different compressibility
+// Generate test data with different compressibility. The data is seeded,
+// so every run of a benchmark compresses the same bytes.
 func generateData(size int, compressibility float64) []byte {
-	// Allocate buffer
-	data := make([]byte, size)
-
 	if compressibility <= 0 {
 		// Random data (incompressible)
-		rand.Read(data)
-		return data
+		return testgen.Random(testgen.DefaultSeed, size)
 	}
 
 	if compressibility >= 1 {
 		// All zeros (maximum compressibility)
-		return data
+		return make([]byte, size)
 	}
 
 	// Pattern data with controlled redundancy
@@ -45,19 +43,7 @@ func generateData(size int, compressibility float64) []byte {
 	if patternSize < 4 {
 		patternSize = 4
 	}
-
-	pattern := make([]byte, patternSize)
-	rand.Read(pattern)
-
-	// Fill with repeating pattern
-	for i := 0; i < size; i += patternSize {
-		n := copy(data[i:], pattern)
-		if n < patternSize {
-			break
-		}
-	}
-
-	return data
+	return testgen.Pattern(testgen.DefaultSeed, size, patternSize, 0)
 }
 
 // Benchmark block compression with different input sizes and compression levels
@@ -196,3 +182,247 @@ func BenchmarkBlockDecompress(b *testing.B) {
 		}
 	}
 }
+
+// Benchmark decompressing blocks of unknown size, which scans the block for
+// its size before decoding, against passing the exact size
+func BenchmarkBlockDecompressUnknownSize(b *testing.B) {
+	data := generateData(largeSize, 0.9)
+	compressed, err := compress.CompressBlock(data, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name    string
+		maxSize int
+	}{
+		{"Known", largeSize},
+		{"Unknown", 0},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(int64(largeSize))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				result, compressErr = compress.DecompressBlock(compressed, nil, bench.maxSize)
+				if compressErr != nil {
+					b.Fatal(compressErr)
+				}
+			}
+		})
+	}
+}
+
+// generateLongMatchData returns data made of long runs of single bytes and
+// repeated short patterns, whose blocks are mostly long overlapping matches
+func generateLongMatchData(size int) []byte {
+	data := make([]byte, 0, size)
+	pattern := []byte("abcdefghijklmnopqrstuvwxyz0123456789")
+	for i := 0; len(data) < size; i++ {
+		switch i % 3 {
+		case 0:
+			data = append(data, bytes.Repeat([]byte{byte(i)}, 2000)...)
+		case 1:
+			data = append(data, bytes.Repeat(pattern[:3+i%30], 500)...)
+		default:
+			data = append(data, testgen.Random(int64(i), 64)...)
+		}
+	}
+	return data[:size]
+}
+
+// Benchmark decompressing blocks dominated by long matches, which exercises
+// the match copy rather than literal copies
+func BenchmarkBlockDecompressLongMatches(b *testing.B) {
+	data := generateLongMatchData(largeSize)
+	compressed, err := compress.CompressBlock(data, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	decompressed := make([]byte, largeSize)
+
+	b.SetBytes(int64(largeSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, compressErr = compress.DecompressBlock(compressed, decompressed, largeSize)
+		if compressErr != nil {
+			b.Fatal(compressErr)
+		}
+	}
+	if !bytes.Equal(result, data) {
+		b.Fatal("decompression failed")
+	}
+}
+
+// generateShortOffsetData returns size bytes of short runs of patterns one
+// to seven bytes long between random bytes, whose blocks are mostly short
+// matches at offsets below 8
+func generateShortOffsetData(size int) []byte {
+	data := make([]byte, 0, size)
+	random := generateData(size, 0)
+	for i := 0; len(data) < size; i++ {
+		period := 1 + i%7
+		pattern := random[i%(size-8):][:period]
+		for n := 12 + i*5%24; n > 0; n-- {
+			data = append(data, pattern[n%period])
+		}
+		data = append(data, random[i*3%(size-8):][:8]...)
+	}
+	return data[:size]
+}
+
+// Benchmark decompressing blocks of short matches at offsets below 8, which
+// repeat a pattern shorter than a word
+func BenchmarkBlockDecompressShortOffsets(b *testing.B) {
+	data := generateShortOffsetData(largeSize)
+	compressed, err := compress.CompressBlock(data, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	decompressed := make([]byte, largeSize)
+
+	b.SetBytes(int64(largeSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, compressErr = compress.DecompressBlock(compressed, decompressed, largeSize)
+		if compressErr != nil {
+			b.Fatal(compressErr)
+		}
+	}
+	if !bytes.Equal(result, data) {
+		b.Fatal("decompression failed")
+	}
+}
+
+// Benchmark compressing small messages against a dictionary, as the first
+// blocks of a connection are, with and without the dictionary prewarmed
+func BenchmarkCompressorDictPrewarm(b *testing.B) {
+	dict := generateData(mediumSize, 0.7)
+	msg := append(bytes.Clone(dict[1000:1500]), testgen.Random(testgen.DefaultSeed+1, smallSize)...)
+
+	for _, prewarm := range []bool{false, true} {
+		name := "Cold"
+		if prewarm {
+			name = "Prewarmed"
+		}
+		b.Run(name, func(b *testing.B) {
+			c, err := compress.NewCompressor(6)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if prewarm {
+				c.Prewarm(dict)
+			}
+			dst := make([]byte, 2*len(msg)+16)
+
+			b.SetBytes(int64(len(msg)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				result, compressErr = c.CompressBlockDict(msg, dst, dict)
+				if compressErr != nil {
+					b.Fatal(compressErr)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark decompressing a block of incompressible data, encoded as a
+// single run of literals
+func BenchmarkBlockDecompressLiteralOnly(b *testing.B) {
+	data := generateData(largeSize, 0)
+	compressed, err := compress.CompressBlockLevel(data, nil, compress.NoCompression)
+	if err != nil {
+		b.Fatal(err)
+	}
+	decompressed := make([]byte, largeSize)
+
+	b.SetBytes(int64(largeSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, compressErr = compress.DecompressBlock(compressed, decompressed, largeSize)
+		if compressErr != nil {
+			b.Fatal(compressErr)
+		}
+	}
+	if !bytes.Equal(result, data) {
+		b.Fatal("decompression failed")
+	}
+}
+
+// Benchmark compressing blocks of text from 1KB to 4MB, one-shot and with
+// a reused Compressor, as the hash table is sized by the block
+func BenchmarkBlockCompressSizes(b *testing.B) {
+	text := generateHTMLDocument(testgen.Rand(testgen.DefaultSeed), 20000, 40)
+	for len(text) < 4<<20 {
+		text = append(text, text...)
+	}
+
+	for _, size := range []int{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20} {
+		data := text[:size]
+		for _, level := range []compress.CompressionLevel{2, 9} {
+			name := fmt.Sprintf("%dKB_Level%d", size>>10, level)
+			b.Run(name+"_OneShot", func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					result, compressErr = compress.CompressBlockLevel(data, nil, level)
+					if compressErr != nil {
+						b.Fatal(compressErr)
+					}
+				}
+			})
+			b.Run(name+"_Compressor", func(b *testing.B) {
+				c, err := compress.NewCompressor(level)
+				if err != nil {
+					b.Fatal(err)
+				}
+				dst := make([]byte, size+size/255+16)
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					result, compressErr = c.CompressBlock(data, dst)
+					if compressErr != nil {
+						b.Fatal(compressErr)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Benchmark compressing and decompressing 1MB of every kind of data of the
+// testgen corpus, reporting the compression ratio, so that runs compare on
+// the same bytes
+func BenchmarkBlockCorpus(b *testing.B) {
+	for _, kind := range testgen.Corpus {
+		data := kind.Generate(testgen.DefaultSeed, largeSize)
+		for _, level := range []compress.CompressionLevel{2, 9} {
+			name := fmt.Sprintf("%s_Level%d", kind.Name, level)
+			compressed, err := compress.CompressBlockLevel(data, nil, level)
+			if err != nil {
+				b.Fatal(err)
+			}
+			ratio := float64(len(data)) / float64(len(compressed))
+
+			b.Run(name+"_Compress", func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					result, compressErr = compress.CompressBlockLevel(data, nil, level)
+					if compressErr != nil {
+						b.Fatal(compressErr)
+					}
+				}
+				b.ReportMetric(ratio, "ratio")
+			})
+			b.Run(name+"_Decompress", func(b *testing.B) {
+				dst := make([]byte, len(data))
+				b.SetBytes(int64(len(data)))
+				for i := 0; i < b.N; i++ {
+					if _, compressErr = compress.DecompressBlockInto(compressed, dst); compressErr != nil {
+						b.Fatal(compressErr)
+					}
+				}
+			})
+		}
+	}
+}
@@ -2,10 +2,12 @@ package bench
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"testing"
 
 	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
 // Benchmark streaming compression
@@ -179,3 +181,238 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+// Benchmark reading a frame of stored blocks into a large buffer
+func BenchmarkStreamDecompressStored(b *testing.B) {
+	data := generateData(hugeSize, 0)
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{Store: true, BlockSize: 1 << 20})
+	if _, err := w.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	compressed := buf.Bytes()
+	out := make([]byte, len(data))
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := compress.NewReader(bytes.NewReader(compressed))
+		if _, err := io.ReadFull(r, out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// smallFrames returns n concatenated frames of size bytes each, with the
+// content size and content checksum in every frame
+func smallFrames(b *testing.B, n, size int) []byte {
+	data := generateData(size, 0.5)
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{ContentChecksum: true})
+	for i := 0; i < n; i++ {
+		w.Reset(&buf)
+		w.SetContentSize(uint64(size))
+		if _, err := w.Write(data); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Benchmark reading a stream of many small frames, where the frame header
+// and trailer dominate
+func BenchmarkStreamReadSmallFrames(b *testing.B) {
+	const frames, size = 1000, 256
+	stream := smallFrames(b, frames, size)
+	out := make([]byte, frames*size)
+
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := compress.NewReader(bytes.NewReader(stream))
+		if _, err := io.ReadFull(r, out); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*frames), "ns/frame")
+}
+
+// Benchmark writing many small frames with one Writer
+func BenchmarkStreamWriteSmallFrames(b *testing.B) {
+	const size = 256
+	data := generateData(size, 0.5)
+	w := compress.NewWriterWithOptions(io.Discard, compress.WriterOptions{ContentChecksum: true})
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Reset(io.Discard)
+		w.SetContentSize(size)
+		if _, err := w.Write(data); err != nil {
+			b.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark many small writes to one Writer, where the mutex every Write
+// takes shows against the copy into the block buffer
+func BenchmarkStreamSmallWrites(b *testing.B) {
+	const size = 64
+	data := generateData(1<<20, 0.5)
+
+	for _, unsync := range []bool{false, true} {
+		name := "Synchronized"
+		if unsync {
+			name = "Unsynchronized"
+		}
+		b.Run(name, func(b *testing.B) {
+			w := compress.NewWriterWithOptions(io.Discard, compress.WriterOptions{
+				Store:          true,
+				BlockSize:      64 * 1024,
+				Unsynchronized: unsync,
+			})
+			b.SetBytes(size)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				off := i * size % len(data)
+				if _, err := w.Write(data[off : off+size]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark reading a frame of small stored blocks with block checksums,
+// where the per-block overhead dominates
+func BenchmarkStreamReadSmallBlocks(b *testing.B) {
+	data := generateData(largeSize, 0)
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{
+		Store:         true,
+		BlockSize:     4096,
+		BlockChecksum: compress.XXH32,
+	})
+	if _, err := w.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	compressed := buf.Bytes()
+	out := make([]byte, len(data))
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := compress.NewReader(bytes.NewReader(compressed))
+		if _, err := io.ReadFull(r, out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Benchmark reading a frame of stored blocks through a buffer smaller than
+// a block, which streams them without a block buffer
+func BenchmarkStreamDecompressStoredSmallReads(b *testing.B) {
+	data := generateData(hugeSize, 0)
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{Store: true, BlockSize: 1 << 20})
+	if _, err := w.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		b.Fatal(err)
+	}
+	compressed := buf.Bytes()
+	p := make([]byte, 32*1024)
+
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := compress.NewReader(bytes.NewReader(compressed))
+		for {
+			_, err := r.Read(p)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// Benchmark round trips of small JSON messages as bare blocks from a reused
+// Compressor, as message frames and as frames from EncodeFrame, reporting
+// the bytes each adds to the compressed block
+func BenchmarkMessageRoundTrip(b *testing.B) {
+	c, err := compress.NewCompressor(compress.CurrentDefaultLevel())
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, size := range []int{256, 1 << 10, 4 << 10} {
+		msg := testgen.JSON(testgen.DefaultSeed, size)
+		blk, err := c.CompressBlock(msg, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("Block_%dB", size), func(b *testing.B) {
+			enc := make([]byte, 2*size)
+			dec := make([]byte, size)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if result, compressErr = c.CompressBlock(msg, enc); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+				if _, compressErr = compress.DecompressBlockInto(result, dec); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+			}
+			b.ReportMetric(0, "overhead-bytes")
+		})
+		b.Run(fmt.Sprintf("Message_%dB", size), func(b *testing.B) {
+			enc := make([]byte, 0, 2*size)
+			dec := make([]byte, 0, size)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if result, compressErr = compress.EncodeMessage(enc[:0], msg, compress.MessageOptions{}); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+				if _, compressErr = compress.DecodeMessage(dec[:0], result); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+			}
+			b.ReportMetric(float64(len(result)-len(blk)), "overhead-bytes")
+		})
+		b.Run(fmt.Sprintf("Frame_%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if result, compressErr = compress.EncodeFrame(msg, compress.WriterOptions{}); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+				if _, compressErr = compress.DecodeFrame(result); compressErr != nil {
+					b.Fatal(compressErr)
+				}
+			}
+			b.ReportMetric(float64(len(result)-len(blk)), "overhead-bytes")
+		})
+	}
+}
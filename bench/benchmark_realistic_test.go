@@ -8,29 +8,30 @@ import (
 	"testing"
 
 	goz4x "github.com/harriteja/GoZ4X"
+	"github.com/harriteja/GoZ4X/internal/testgen"
 	v03 "github.com/harriteja/GoZ4X/v03"
 )
 
 // generateRandomText creates random text data
-func generateRandomText(size int) []byte {
+func generateRandomText(rng *rand.Rand, size int) []byte {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 .,;:!?-_()"
 	data := make([]byte, size)
 	for i := range data {
-		data[i] = charset[rand.Intn(len(charset))]
+		data[i] = charset[rng.Intn(len(charset))]
 	}
 	return data
 }
 
 // generateHTMLDocument creates sample HTML data
-func generateHTMLDocument(paragraphs int, wordsPerParagraph int) []byte {
+func generateHTMLDocument(rng *rand.Rand, paragraphs int, wordsPerParagraph int) []byte {
 	var buffer bytes.Buffer
 	buffer.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<title>Sample Document</title>\n</head>\n<body>\n")
 
 	for i := 0; i < paragraphs; i++ {
 		buffer.WriteString("<p>")
 		for j := 0; j < wordsPerParagraph; j++ {
-			wordLength := rand.Intn(10) + 3
-			word := generateRandomText(wordLength)
+			wordLength := rng.Intn(10) + 3
+			word := generateRandomText(rng, wordLength)
 			buffer.Write(word)
 			buffer.WriteByte(' ')
 		}
@@ -42,7 +43,7 @@ func generateHTMLDocument(paragraphs int, wordsPerParagraph int) []byte {
 }
 
 // generateJSONData creates sample JSON data
-func generateJSONData(records int) []byte {
+func generateJSONData(rng *rand.Rand, records int) []byte {
 	data := make([]map[string]interface{}, records)
 
 	for i := 0; i < records; i++ {
@@ -50,20 +51,20 @@ func generateJSONData(records int) []byte {
 			"id":        i,
 			"name":      "User " + strconv.Itoa(i),
 			"email":     "user" + strconv.Itoa(i) + "@example.com",
-			"active":    rand.Intn(2) == 1,
-			"age":       rand.Intn(80) + 18,
-			"timestamp": rand.Int63(),
+			"active":    rng.Intn(2) == 1,
+			"age":       rng.Intn(80) + 18,
+			"timestamp": rng.Int63(),
 			"data": map[string]interface{}{
 				"preferences": map[string]interface{}{
 					"theme":     "light",
-					"fontSize":  rand.Intn(5) + 10,
-					"showIntro": rand.Intn(2) == 1,
+					"fontSize":  rng.Intn(5) + 10,
+					"showIntro": rng.Intn(2) == 1,
 				},
 				"permissions": []string{"read", "write", "admin"},
 				"metrics": map[string]float64{
-					"logins":    float64(rand.Intn(1000)),
-					"pageViews": float64(rand.Intn(5000)),
-					"clickRate": rand.Float64(),
+					"logins":    float64(rng.Intn(1000)),
+					"pageViews": float64(rng.Intn(5000)),
+					"clickRate": rng.Float64(),
 				},
 			},
 		}
@@ -77,17 +78,17 @@ func generateJSONData(records int) []byte {
 // BenchmarkRealisticUseCase tests compression performance on realistic data
 func BenchmarkRealisticUseCase(b *testing.B) {
 	// Generate test data
-	rand.Seed(42) // For reproducibility
+	rng := testgen.Rand(42) // For reproducibility
 
 	// HTML document (reduced from 500KB to 50KB to prevent hanging)
-	htmlData := generateHTMLDocument(50, 100)
+	htmlData := generateHTMLDocument(rng, 50, 100)
 
 	// JSON data (reduced from 1MB to 100KB to prevent hanging)
-	jsonData := generateJSONData(100)
+	jsonData := generateJSONData(rng, 100)
 
 	// Binary data (reduced from 2MB to 200KB to prevent hanging)
 	binaryData := make([]byte, 200*1024)
-	rand.Read(binaryData)
+	rng.Read(binaryData)
 
 	testCases := []struct {
 		name string
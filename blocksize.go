@@ -0,0 +1,39 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// BlockSizeCode selects the maximum block size of a frame.
+type BlockSizeCode = compress.BlockSizeCode
+
+// Block size codes defined by the LZ4 frame format.
+const (
+	BlockSize64KB  = compress.BlockSize64KB
+	BlockSize256KB = compress.BlockSize256KB
+	BlockSize1MB   = compress.BlockSize1MB
+	BlockSize4MB   = compress.BlockSize4MB
+)
+
+// BlockSizeFromCode returns the maximum block size in bytes of a block size
+// code, or 0 for an invalid code.
+func BlockSizeFromCode(code BlockSizeCode) int {
+	return compress.BlockSizeFromCode(code)
+}
+
+// CodeFromBlockSize returns the smallest block size code whose maximum holds
+// blocks of size bytes, or BlockSize4MB for larger sizes.
+func CodeFromBlockSize(size int) BlockSizeCode {
+	return compress.CodeFromBlockSize(size)
+}
+
+// SetBlockSizeCode sets the maximum block size of the frame.
+// It must be called before the first Write.
+func (w *Writer) SetBlockSizeCode(code BlockSizeCode) error {
+	return w.w.SetBlockSizeCode(code)
+}
+
+// SetSizeHint tells the Writer the expected uncompressed size of the stream so
+// that small streams use smaller blocks. It is ignored after the first Write
+// or once a block size code has been set.
+func (w *Writer) SetSizeHint(size int64) {
+	w.w.SetSizeHint(size)
+}
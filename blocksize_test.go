@@ -0,0 +1,59 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestWriterBlockSize tests writers configured with a block size code
+func TestWriterBlockSize(t *testing.T) {
+	data := bytes.Repeat([]byte("block size codes "), 20000)
+
+	writers := map[string]func(w io.Writer) *Writer{
+		"SetBlockSizeCode": func(w io.Writer) *Writer {
+			z := NewWriterLevel(w, 3)
+			if err := z.SetBlockSizeCode(BlockSize64KB); err != nil {
+				t.Fatalf("SetBlockSizeCode() error = %v", err)
+			}
+			return z
+		},
+		"SetSizeHint": func(w io.Writer) *Writer {
+			z := NewWriter(w)
+			z.SetSizeHint(int64(len(data)))
+			return z
+		},
+		"Options": func(w io.Writer) *Writer {
+			return NewWriterWithOptions(w, WriterOptions{BlockSizeCode: BlockSize256KB})
+		},
+	}
+
+	for name, newWriter := range writers {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", name, err)
+		}
+
+		got, err := io.ReadAll(NewReader(&buf))
+		if err != nil {
+			t.Fatalf("%s: ReadAll() error = %v", name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: decoded %d bytes, want %d", name, len(got), len(data))
+		}
+	}
+}
+
+// TestBlockSizeCodeHelpers tests converting between block sizes and codes
+func TestBlockSizeCodeHelpers(t *testing.T) {
+	if got := BlockSizeFromCode(BlockSize1MB); got != 1024*1024 {
+		t.Errorf("BlockSizeFromCode(BlockSize1MB) = %d, want %d", got, 1024*1024)
+	}
+	if got := CodeFromBlockSize(100 * 1024); got != BlockSize256KB {
+		t.Errorf("CodeFromBlockSize(100KB) = %v, want %v", got, BlockSize256KB)
+	}
+}
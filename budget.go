@@ -0,0 +1,19 @@
+package goz4x
+
+import (
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// BudgetStats reports whether CompressBlockBudget had to trade ratio for
+// time, and where in the block.
+type BudgetStats = compress.BudgetStats
+
+// CompressBlockBudget compresses src at the given level within a soft time
+// budget. As the budget runs low the match search is cut down, and once it
+// is spent the rest of the block is stored as literals, so a realtime
+// caller gets a worse ratio rather than a missed deadline.
+func CompressBlockBudget(src []byte, dst []byte, level int, budget time.Duration) ([]byte, BudgetStats, error) {
+	return compress.CompressBlockBudget(src, dst, compress.CompressionLevel(level), budget)
+}
@@ -0,0 +1,30 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestCompressBlockBudget tests the root CompressBlockBudget wrapper
+func TestCompressBlockBudget(t *testing.T) {
+	src := generateCompressibleData(64 * 1024)
+	want, err := CompressBlockLevel(src, nil, 9)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel error: %v", err)
+	}
+
+	got, stats, err := CompressBlockBudget(src, nil, 9, time.Hour)
+	if err != nil || !bytes.Equal(got, want) || stats.Degraded || stats.Exhausted {
+		t.Errorf("CompressBlockBudget with an hour = %d bytes, %+v, %v, want %d bytes", len(got), stats, err, len(want))
+	}
+
+	got, stats, err = CompressBlockBudget(src, nil, 9, time.Nanosecond)
+	if err != nil || !stats.Exhausted {
+		t.Fatalf("CompressBlockBudget with a nanosecond = %+v, %v, want an exhausted budget", stats, err)
+	}
+	out, err := DecompressBlock(got, nil, len(src))
+	if err != nil || !bytes.Equal(out, src) {
+		t.Errorf("DecompressBlock = %d bytes, %v, want %d", len(out), err, len(src))
+	}
+}
@@ -0,0 +1,17 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// BufferProvider supplies a Reader's block buffers when set as the Buffers
+// option of ReaderOptions. The Reader hands them back on Close.
+type BufferProvider = compress.BufferProvider
+
+// ErrReaderClosed is returned when reading from a closed Reader.
+var ErrReaderClosed = compress.ErrReaderClosed
+
+// Close releases the Reader's block buffers, returning them to its
+// BufferProvider if it has one. It doesn't close the underlying reader
+// unless the CloseUnderlying option is set.
+func (r *Reader) Close() error {
+	return r.r.Close()
+}
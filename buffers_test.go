@@ -0,0 +1,61 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+// poolProvider is a BufferProvider backed by a sync.Pool
+type poolProvider struct {
+	pool sync.Pool
+	gets int
+	puts int
+}
+
+func (p *poolProvider) Get(size int) []byte {
+	p.gets++
+	if buf, ok := p.pool.Get().([]byte); ok && cap(buf) >= size {
+		return buf
+	}
+	return make([]byte, size)
+}
+
+func (p *poolProvider) Put(buf []byte) {
+	p.puts++
+	p.pool.Put(buf)
+}
+
+// TestReaderBuffers tests the Buffers option and Close on Reader
+func TestReaderBuffers(t *testing.T) {
+	data := generateCompressibleData(256 * 1024)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	p := &poolProvider{}
+	r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{Buffers: p})
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Read data doesn't match original")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if p.gets == 0 || p.gets != p.puts {
+		t.Errorf("Get calls = %d, Put calls = %d, want equal and nonzero", p.gets, p.puts)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != ErrReaderClosed {
+		t.Errorf("Read after Close error: %v, want %v", err, ErrReaderClosed)
+	}
+}
@@ -0,0 +1,30 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// Checksummer computes the block checksums enabled by the BlockChecksum
+// option of WriterOptions and verified with the same option of ReaderOptions.
+type Checksummer = compress.Checksummer
+
+// ChecksumFunc adapts a function to the Checksummer interface.
+type ChecksumFunc = compress.ChecksumFunc
+
+// Built-in checksum algorithms. Only XXH32 produces frames that other LZ4
+// implementations can verify; XXH64 and CRC32C are for GoZ4X-only containers.
+var (
+	XXH32  = compress.XXH32
+	XXH64  = compress.XXH64
+	CRC32C = compress.CRC32C
+)
+
+// ChecksumMode sets how a Reader treats checksums, when set as the
+// VerifyChecksums option of ReaderOptions.
+type ChecksumMode = compress.ChecksumMode
+
+// Checksum modes. ChecksumDeferred keeps returning data past a mismatch and
+// reports it from Err and Close; ChecksumIgnore skips verification.
+const (
+	ChecksumVerify   = compress.ChecksumVerify
+	ChecksumDeferred = compress.ChecksumDeferred
+	ChecksumIgnore   = compress.ChecksumIgnore
+)
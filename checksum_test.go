@@ -0,0 +1,29 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestBlockChecksum tests frames written with a non-default checksum algorithm
+func TestBlockChecksum(t *testing.T) {
+	data := bytes.Repeat([]byte("crc32c protected block "), 4000)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockChecksum: CRC32C})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := io.ReadAll(NewReaderWithOptions(&buf, ReaderOptions{BlockChecksum: CRC32C}))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+}
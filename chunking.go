@@ -0,0 +1,30 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// ChunkingOptions configures content-defined chunking.
+type ChunkingOptions = compress.ChunkingOptions
+
+// ChunkInfo describes one chunk of a frame written with content-defined chunking.
+type ChunkInfo = compress.ChunkInfo
+
+// SetChunking makes the Writer end blocks at content-defined boundaries and
+// write a chunk index after the frame. It must be called before the first Write.
+func (w *Writer) SetChunking(opts ChunkingOptions) error {
+	return w.w.SetChunking(opts)
+}
+
+// Chunks returns the chunks written so far when chunking is enabled.
+func (w *Writer) Chunks() []ChunkInfo {
+	return w.w.Chunks()
+}
+
+// ReadChunkIndex returns the chunks listed in the index following a frame
+// written with content-defined chunking, without decompressing the frame.
+func ReadChunkIndex(r io.Reader) ([]ChunkInfo, error) {
+	return compress.ReadChunkIndex(r)
+}
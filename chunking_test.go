@@ -0,0 +1,40 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestChunking tests writing a chunked frame and reading its index
+func TestChunking(t *testing.T) {
+	data := bytes.Repeat([]byte("content defined chunk boundaries "), 20000)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.SetChunking(ChunkingOptions{MinSize: 2048, AvgSize: 8192, MaxSize: 32768}); err != nil {
+		t.Fatalf("SetChunking() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	chunks, err := ReadChunkIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadChunkIndex() error = %v", err)
+	}
+	if len(chunks) == 0 || len(chunks) != len(w.Chunks()) {
+		t.Errorf("ReadChunkIndex() = %d chunks, want %d", len(chunks), len(w.Chunks()))
+	}
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+}
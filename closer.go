@@ -0,0 +1,20 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// NewReadCloser creates a new Reader that decompresses from rc and closes
+// it on Close, as with the CloseUnderlying option of ReaderOptions.
+func NewReadCloser(rc io.ReadCloser) *Reader {
+	return &Reader{r: compress.NewReadCloser(rc)}
+}
+
+// NewWriteCloser creates a new Writer that compresses to wc and closes it
+// on Close, after finishing the frame, as with the CloseUnderlying option
+// of WriterOptions.
+func NewWriteCloser(wc io.WriteCloser) *Writer {
+	return &Writer{w: compress.NewWriteCloser(wc)}
+}
@@ -0,0 +1,47 @@
+package goz4x
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadWriteCloser tests that Readers and Writers that own a file close it
+func TestReadWriteCloser(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	path := filepath.Join(t.TempDir(), "data.lz4")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	w := NewWriteCloser(f)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := f.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("file still open after Writer.Close: Close() error = %v", err)
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	r := NewReadCloser(f)
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAll() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := f.Close(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("file still open after Reader.Close: Close() error = %v", err)
+	}
+}
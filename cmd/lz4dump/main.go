@@ -0,0 +1,469 @@
+// Command lz4dump prints the structure of LZ4 streams: the header fields of
+// every frame, the size and checksum of every block and, with -seq, the
+// sequences of compressed blocks. It parses frames itself with the
+// constants of the format package, reporting bad checksums and truncation
+// where they occur instead of stopping at the first error like a Reader,
+// which makes it useful for debugging streams of other implementations.
+//
+// Usage:
+//
+//	lz4dump [-seq] [-json] [file]
+//
+// Without a file it reads standard input.
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/xxhash"
+)
+
+// Frame describes one frame of the stream
+type Frame struct {
+	Offset    int64  `json:"offset"`
+	Magic     uint32 `json:"magic"`
+	Skippable bool   `json:"skippable,omitempty"`
+	// Size is the data size of a skippable frame, and Kind the GoZ4X
+	// extension it holds, if known
+	Size int64  `json:"size,omitempty"`
+	Kind string `json:"kind,omitempty"`
+
+	Version        int      `json:"version,omitempty"`
+	Flags          []string `json:"flags,omitempty"`
+	BlockMaxSize   int      `json:"blockMaxSize,omitempty"`
+	HeaderChecksum byte     `json:"headerChecksum"`
+	// HeaderChecksumOK reports whether HeaderChecksum matches the descriptor
+	HeaderChecksumOK bool      `json:"headerChecksumOK"`
+	ContentSize      *uint64   `json:"contentSize,omitempty"`
+	DictID           *uint32   `json:"dictID,omitempty"`
+	Blocks           []Block   `json:"blocks,omitempty"`
+	Decoded          int64     `json:"decoded,omitempty"`
+	ContentChecksum  *Checksum `json:"contentChecksum,omitempty"`
+	// Error is set if the frame couldn't be read to its end
+	Error string `json:"error,omitempty"`
+}
+
+// Block describes one block of a frame
+type Block struct {
+	Offset   int64      `json:"offset"`
+	Size     int        `json:"size"`
+	Stored   bool       `json:"stored,omitempty"`
+	Decoded  int        `json:"decoded"`
+	Checksum *Checksum  `json:"checksum,omitempty"`
+	Seqs     []Sequence `json:"sequences,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Checksum is a checksum as stored, and as computed if it could be
+type Checksum struct {
+	Stored   uint32  `json:"stored"`
+	Computed *uint32 `json:"computed,omitempty"`
+}
+
+// OK reports whether the checksum was computed and matches
+func (c *Checksum) OK() bool {
+	return c.Computed != nil && *c.Computed == c.Stored
+}
+
+// Sequence is one sequence of a compressed block
+type Sequence struct {
+	Literals int `json:"literals"`
+	Match    int `json:"match,omitempty"`
+	Offset   int `json:"offset,omitempty"`
+}
+
+// skippableKinds names the skippable frames GoZ4X writes
+var skippableKinds = map[uint32]string{
+	format.StatsTrailerMagic: "stats trailer",
+	format.ChunkIndexMagic:   "chunk index",
+	format.MuxLabelMagic:     "mux label",
+	format.SparseHoleMagic:   "sparse hole",
+}
+
+// flagNames names the FLG bits
+var flagNames = []struct {
+	bit  byte
+	name string
+}{
+	{format.FlagBlockIndependence, "block-independence"},
+	{format.FlagBlockChecksum, "block-checksum"},
+	{format.FlagContentSize, "content-size"},
+	{format.FlagContentChecksum, "content-checksum"},
+	{format.FlagReserved, "reserved"},
+	{format.FlagDictID, "dict-id"},
+}
+
+// sniffSize is the size of the prefix of the input passed to Sniff
+const sniffSize = 1024
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// dumper parses a stream into Frames
+type dumper struct {
+	r    *countingReader
+	seqs bool
+}
+
+// Dump parses the frames of r until its end or the first frame that can't
+// be read to its end, which is returned with its Error set. With seqs, the
+// sequences of compressed blocks are listed.
+func Dump(r io.Reader, seqs bool) []Frame {
+	// Legacy frames and raw blocks are named rather than reported as an
+	// unknown magic number
+	br := bufio.NewReader(r)
+	prefix, _ := br.Peek(sniffSize)
+	if kind := compress.Sniff(prefix); kind == compress.FormatLegacy || kind == compress.FormatBlock {
+		return []Frame{{Magic: binary.LittleEndian.Uint32(prefix), Error: fmt.Sprintf("input is a %v, which lz4dump doesn't read", kind)}}
+	}
+
+	d := &dumper{r: &countingReader{r: br}, seqs: seqs}
+	var frames []Frame
+	for {
+		f, err := d.frame()
+		if err == io.EOF {
+			return frames
+		}
+		if err != nil {
+			f.Error = err.Error()
+			return append(frames, f)
+		}
+		frames = append(frames, f)
+	}
+}
+
+// read reads exactly len(p) bytes, naming what was being read if the
+// stream ends first
+func (d *dumper) read(p []byte, what string) error {
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("stream ends in %s at offset %d", what, d.r.n)
+		}
+		return err
+	}
+	return nil
+}
+
+// frame parses the next frame. It returns io.EOF at the end of the stream.
+func (d *dumper) frame() (Frame, error) {
+	f := Frame{Offset: d.r.n}
+	var word [8]byte
+	if _, err := io.ReadFull(d.r, word[:format.MagicSize]); err != nil {
+		if err == io.EOF {
+			return f, io.EOF
+		}
+		return f, fmt.Errorf("stream ends in magic number at offset %d", d.r.n)
+	}
+	f.Magic = binary.LittleEndian.Uint32(word[:])
+
+	if format.IsSkippable(f.Magic) {
+		f.Skippable = true
+		f.Kind = skippableKinds[f.Magic]
+		if err := d.read(word[:4], "skippable frame size"); err != nil {
+			return f, err
+		}
+		f.Size = int64(binary.LittleEndian.Uint32(word[:]))
+		if n, err := io.CopyN(io.Discard, d.r, f.Size); err != nil {
+			return f, fmt.Errorf("stream ends %d bytes into skippable frame data", n)
+		}
+		return f, nil
+	}
+	if f.Magic != format.FrameMagic {
+		return f, fmt.Errorf("unknown magic number %#08x", f.Magic)
+	}
+
+	// FLG and BD, the optional fields and HC, which covers those before it
+	desc := make([]byte, 2, format.DescriptorSize+format.ContentSizeSize+format.DictIDSize)
+	if err := d.read(desc, "frame descriptor"); err != nil {
+		return f, err
+	}
+	flg, bd := desc[0], desc[1]
+	f.Version = int(flg&format.VersionMask) >> format.VersionShift
+	for _, fl := range flagNames {
+		if flg&fl.bit != 0 {
+			f.Flags = append(f.Flags, fl.name)
+		}
+	}
+	f.BlockMaxSize = format.BlockMaxSize(int(bd&format.BlockSizeMask) >> format.BlockSizeShift)
+	if f.BlockMaxSize == 0 {
+		return f, fmt.Errorf("invalid block maximum size code in BD %#02x", bd)
+	}
+	if flg&format.FlagContentSize != 0 {
+		if err := d.read(word[:format.ContentSizeSize], "content size"); err != nil {
+			return f, err
+		}
+		size := binary.LittleEndian.Uint64(word[:])
+		f.ContentSize = &size
+		desc = append(desc, word[:format.ContentSizeSize]...)
+	}
+	if flg&format.FlagDictID != 0 {
+		if err := d.read(word[:format.DictIDSize], "dictionary ID"); err != nil {
+			return f, err
+		}
+		id := binary.LittleEndian.Uint32(word[:])
+		f.DictID = &id
+		desc = append(desc, word[:format.DictIDSize]...)
+	}
+	if err := d.read(word[:1], "header checksum"); err != nil {
+		return f, err
+	}
+	f.HeaderChecksum = word[0]
+	f.HeaderChecksumOK = byte(xxhash.Sum32(desc)>>8) == word[0]
+
+	// Blocks are decoded to check the content checksum, which isn't
+	// possible without the frame's dictionary
+	decode := f.DictID == nil
+	var content *xxhash.Digest32
+	if decode {
+		content = xxhash.New32()
+	}
+	var history []byte
+	independent := flg&format.FlagBlockIndependence != 0
+
+	for {
+		offset := d.r.n
+		if err := d.read(word[:format.BlockSizeWordSize], "block size"); err != nil {
+			return f, err
+		}
+		sizeWord := binary.LittleEndian.Uint32(word[:])
+		if sizeWord == format.EndMark {
+			break
+		}
+
+		b := Block{
+			Offset: offset,
+			Size:   int(sizeWord & format.BlockSizeWordMask),
+			Stored: sizeWord&format.BlockUncompressed != 0,
+		}
+		if b.Size > f.BlockMaxSize {
+			f.Blocks = append(f.Blocks, b)
+			return f, fmt.Errorf("block at offset %d is %d bytes, over the maximum of %d", offset, b.Size, f.BlockMaxSize)
+		}
+		data := make([]byte, b.Size)
+		if err := d.read(data, "block data"); err != nil {
+			f.Blocks = append(f.Blocks, b)
+			return f, err
+		}
+		if flg&format.FlagBlockChecksum != 0 {
+			if err := d.read(word[:format.ChecksumSize], "block checksum"); err != nil {
+				f.Blocks = append(f.Blocks, b)
+				return f, err
+			}
+			sum := xxhash.Sum32(data)
+			b.Checksum = &Checksum{Stored: binary.LittleEndian.Uint32(word[:]), Computed: &sum}
+		}
+
+		out := data
+		if !b.Stored {
+			d.sequences(&b, data)
+			if decode && b.Error == "" {
+				var err error
+				if independent {
+					history = nil
+				}
+				out, err = compress.DecompressBlockDict(data, history, f.BlockMaxSize)
+				if err != nil {
+					b.Error = err.Error()
+				}
+			}
+		} else {
+			b.Decoded = b.Size
+		}
+		if b.Error != "" {
+			decode = false
+		}
+		if decode {
+			content.Write(out)
+			history = appendWindow(history, out)
+		}
+		f.Decoded += int64(b.Decoded)
+		f.Blocks = append(f.Blocks, b)
+	}
+
+	if flg&format.FlagContentChecksum != 0 {
+		if err := d.read(word[:format.ChecksumSize], "content checksum"); err != nil {
+			return f, err
+		}
+		f.ContentChecksum = &Checksum{Stored: binary.LittleEndian.Uint32(word[:])}
+		if decode {
+			sum := content.Sum32()
+			f.ContentChecksum.Computed = &sum
+		}
+	}
+	return f, nil
+}
+
+// sequences walks the sequences of a compressed block, recording its
+// decoded size and, if asked for, the sequences
+func (d *dumper) sequences(b *Block, data []byte) {
+	sr := compress.NewSequenceReader(data)
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			b.Error = err.Error()
+			return
+		}
+		b.Decoded += len(seq.Literals) + seq.MatchLen
+		if d.seqs {
+			b.Seqs = append(b.Seqs, Sequence{Literals: len(seq.Literals), Match: seq.MatchLen, Offset: seq.Offset})
+		}
+	}
+}
+
+// appendWindow appends out to history, keeping the last 64KB that the
+// next dependent block can reference
+func appendWindow(history, out []byte) []byte {
+	history = append(history, out...)
+	if over := len(history) - 64<<10; over > 0 {
+		history = history[:copy(history, history[over:])]
+	}
+	return history
+}
+
+// WriteText prints frames in a readable form
+func WriteText(w io.Writer, frames []Frame) error {
+	bw := bufio.NewWriter(w)
+	for i, f := range frames {
+		if f.Skippable {
+			kind := f.Kind
+			if kind == "" {
+				kind = "user data"
+			}
+			fmt.Fprintf(bw, "frame %d at %d: skippable %#08x, %d bytes (%s)\n", i, f.Offset, f.Magic, f.Size, kind)
+		} else if f.Magic != format.FrameMagic {
+			fmt.Fprintf(bw, "frame %d at %d: magic %#08x\n", i, f.Offset, f.Magic)
+		} else {
+			hc := ""
+			if !f.HeaderChecksumOK {
+				hc = " BAD"
+			}
+			fmt.Fprintf(bw, "frame %d at %d: LZ4 version %d, block max %d, HC %#02x%s, flags %s\n",
+				i, f.Offset, f.Version, f.BlockMaxSize, f.HeaderChecksum, hc, strings.Join(f.Flags, ","))
+			if f.ContentSize != nil {
+				fmt.Fprintf(bw, "  content size %d\n", *f.ContentSize)
+			}
+			if f.DictID != nil {
+				fmt.Fprintf(bw, "  dictionary %#08x\n", *f.DictID)
+			}
+		}
+
+		for j, b := range f.Blocks {
+			kind := "compressed"
+			if b.Stored {
+				kind = "stored"
+			}
+			fmt.Fprintf(bw, "  block %d at %d: %s, %d -> %d bytes%s\n", j, b.Offset, kind, b.Size, b.Decoded, checksumText(b.Checksum))
+			for k, s := range b.Seqs {
+				if s.Match > 0 {
+					fmt.Fprintf(bw, "    seq %d: %d literals, match %d at offset %d\n", k, s.Literals, s.Match, s.Offset)
+				} else {
+					fmt.Fprintf(bw, "    seq %d: %d literals\n", k, s.Literals)
+				}
+			}
+			if b.Error != "" {
+				fmt.Fprintf(bw, "    error: %s\n", b.Error)
+			}
+		}
+		if f.ContentChecksum != nil {
+			fmt.Fprintf(bw, "  content %d bytes%s\n", f.Decoded, checksumText(f.ContentChecksum))
+		}
+		if f.Error != "" {
+			fmt.Fprintf(bw, "  error: %s\n", f.Error)
+		}
+	}
+	return bw.Flush()
+}
+
+// checksumText describes a checksum for WriteText
+func checksumText(c *Checksum) string {
+	switch {
+	case c == nil:
+		return ""
+	case c.Computed == nil:
+		return fmt.Sprintf(", checksum %#08x unverified", c.Stored)
+	case c.OK():
+		return fmt.Sprintf(", checksum %#08x ok", c.Stored)
+	default:
+		return fmt.Sprintf(", checksum %#08x BAD (computed %#08x)", c.Stored, *c.Computed)
+	}
+}
+
+// failed reports whether any frame has an error or a bad checksum
+func failed(frames []Frame) bool {
+	for _, f := range frames {
+		if f.Error != "" || (f.ContentChecksum != nil && f.ContentChecksum.Computed != nil && !f.ContentChecksum.OK()) ||
+			(f.Magic == format.FrameMagic && !f.HeaderChecksumOK) {
+			return true
+		}
+		for _, b := range f.Blocks {
+			if b.Error != "" || (b.Checksum != nil && !b.Checksum.OK()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func main() {
+	seqs := flag.Bool("seq", false, "List the sequences of compressed blocks")
+	asJSON := flag.Bool("json", false, "Print JSON instead of text")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var in io.Reader = os.Stdin
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(compress.ExitReadError)
+		}
+		defer f.Close()
+		in = f
+	default:
+		flag.Usage()
+		os.Exit(compress.ExitFailure)
+	}
+
+	frames := Dump(in, *seqs)
+	var err error
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(frames)
+	} else {
+		err = WriteText(os.Stdout, frames)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(compress.ExitFailure)
+	}
+	if failed(frames) {
+		os.Exit(compress.ExitDecompression)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// testStream returns two frames with every optional field GoZ4X writes,
+// the first followed by a stats trailer
+func testStream(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	data := bytes.Repeat([]byte("lz4dump reads frames block by block. "), 5000)
+	data = append(data, make([]byte, 1000)...)
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{
+			BlockSize:       64 * 1024,
+			BlockChecksum:   compress.XXH32,
+			ContentChecksum: true,
+			StatsTrailer:    i == 0,
+		})
+		w.SetContentSize(uint64(len(data)))
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+	return buf.Bytes(), data
+}
+
+// TestDump tests the structure reported for a valid stream
+func TestDump(t *testing.T) {
+	stream, data := testStream(t)
+	frames := Dump(bytes.NewReader(stream), true)
+
+	if len(frames) != 3 || !frames[1].Skippable || frames[1].Kind != "stats trailer" {
+		t.Fatalf("Dump() = %d frames, want a frame, a stats trailer and a frame", len(frames))
+	}
+	f := frames[0]
+	if f.Version != 1 || f.BlockMaxSize != 64*1024 || f.ContentSize == nil || *f.ContentSize != uint64(len(data)) {
+		t.Errorf("frame header = %+v, want version 1, 64KB blocks and a content size of %d", f, len(data))
+	}
+	if got := strings.Join(f.Flags, ","); got != "block-independence,block-checksum,content-size,content-checksum" {
+		t.Errorf("flags = %s", got)
+	}
+	if len(f.Blocks) != 3 || f.Decoded != int64(len(data)) || !f.ContentChecksum.OK() {
+		t.Errorf("frame has %d blocks decoding to %d bytes, content checksum %+v, want 3 blocks of %d", len(f.Blocks), f.Decoded, f.ContentChecksum, len(data))
+	}
+	for i, b := range f.Blocks {
+		if !b.Checksum.OK() || len(b.Seqs) == 0 || b.Error != "" {
+			t.Errorf("block %d = %+v, want a good checksum and sequences", i, b)
+		}
+	}
+	if frames[2].Offset != frames[1].Offset+8+frames[1].Size || failed(frames) {
+		t.Errorf("second frame at %d, failed %v", frames[2].Offset, failed(frames))
+	}
+
+	var text bytes.Buffer
+	if err := WriteText(&text, frames); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	for _, want := range []string{"frame 0 at 0: LZ4 version 1", "skippable 0x184d2a5a", "checksum", " ok", "match"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteText() lacks %q:\n%s", want, text.String()[:min(text.Len(), 500)])
+		}
+	}
+	if _, err := json.Marshal(frames); err != nil {
+		t.Errorf("json.Marshal() error = %v", err)
+	}
+}
+
+// TestDumpErrors tests that bad checksums and truncation are reported where
+// they occur
+func TestDumpErrors(t *testing.T) {
+	stream, _ := testStream(t)
+
+	// Flip a byte of the first block's data
+	bad := append([]byte(nil), stream...)
+	bad[30] ^= 0xFF
+	frames := Dump(bytes.NewReader(bad), false)
+	if len(frames) != 3 || frames[0].Blocks[0].Checksum.OK() || !frames[0].Blocks[1].Checksum.OK() || !failed(frames) {
+		t.Errorf("Dump() of a corrupt block didn't report its checksum alone")
+	}
+
+	frames = Dump(bytes.NewReader(stream[:200]), false)
+	if len(frames) != 1 || !strings.Contains(frames[0].Error, "block data") || !failed(frames) {
+		t.Errorf("Dump() of a truncated stream = %+v, want an error in block data", frames)
+	}
+
+	frames = Dump(strings.NewReader("not lz4"), false)
+	if len(frames) != 1 || !strings.Contains(frames[0].Error, "magic") {
+		t.Errorf("Dump() of other data = %+v, want a magic number error", frames)
+	}
+
+	blk, err := compress.CompressBlock(bytes.Repeat([]byte("raw block "), 100), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames = Dump(bytes.NewReader(blk), false)
+	if len(frames) != 1 || !strings.Contains(frames[0].Error, "raw LZ4 block") || !failed(frames) {
+		t.Errorf("Dump() of a raw block = %+v, want it named", frames)
+	}
+}
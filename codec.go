@@ -0,0 +1,17 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// Codec compresses and decompresses whole buffers, with the Encode and
+// Decode methods of the codec interfaces commonly put in front of gzip, zstd
+// and s2.
+type Codec = compress.Codec
+
+// FrameCodec is a Codec encoding to LZ4 frames, pooling its Writers and
+// Readers internally. It is safe for concurrent use.
+type FrameCodec = compress.FrameCodec
+
+// NewFrameCodec returns a FrameCodec writing frames with the given options.
+func NewFrameCodec(options WriterOptions) *FrameCodec {
+	return compress.NewFrameCodec(options)
+}
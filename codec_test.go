@@ -0,0 +1,46 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestFrameCodec tests the root FrameCodec and pooling Readers and Writers
+func TestFrameCodec(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+
+	var c Codec = NewFrameCodec(WriterOptions{})
+	frame, err := c.Encode(nil, data)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := c.Decode(nil, frame)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Decode() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+
+	writers := sync.Pool{New: func() any { return NewWriter(nil) }}
+	readers := sync.Pool{New: func() any { return NewReader(nil) }}
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		w := writers.Get().(*Writer)
+		w.Reset(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		writers.Put(w)
+
+		r := readers.Get().(*Reader)
+		r.Reset(&buf)
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("ReadAll() = %d bytes, %v, want %d", len(got), err, len(data))
+		}
+		readers.Put(r)
+	}
+}
@@ -0,0 +1,36 @@
+package compress
+
+// Abort cancels the frame being written. Buffered data is dropped and
+// nothing more is written to the underlying writer: no pending block, end
+// marker, checksum or chunk index, so whatever was already written is an
+// incomplete frame. The Writer's buffers are released. Afterwards Write
+// fails and Close writes nothing, until Reset starts a new frame. Abort
+// waits for any Write or Close in progress on another goroutine to finish,
+// and with AsyncFlush for the block being written in the background.
+func (z *Writer) Abort() {
+	z.lock()
+	defer z.unlock()
+
+	z.wait()
+	z.closed = true
+	z.bufUsed = 0
+	z.buf, z.spare = nil, nil
+	z.compBuf = nil
+	z.compressor, z.otherCompressor = nil, nil
+	z.chunks = nil
+}
+
+// Abort cancels the frame being written like Writer.Abort: buffered data
+// is dropped, nothing more is written and the block buffer is released.
+// Afterwards Write returns ErrWriterClosed and Close writes nothing, until
+// Reset starts a new frame.
+func (pw *ParallelWriter) Abort() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.closed = true
+	pw.bufferOff = 0
+	pw.buffer = nil
+	pw.dropPending()
+	pw.spareData, pw.spareBuf = nil, nil
+}
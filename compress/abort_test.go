@@ -0,0 +1,70 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// abortWriter is the part of Writer and ParallelWriter under test
+type abortWriter interface {
+	io.WriteCloser
+	Abort()
+	Reset(w io.Writer)
+}
+
+// TestAbort tests that an aborted stream writes nothing more and that the
+// writer can be reused after Reset
+func TestAbort(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	tests := []struct {
+		name      string
+		newWriter func(w io.Writer) abortWriter
+	}{
+		{"Writer", func(w io.Writer) abortWriter { return NewWriter(w) }},
+		{"Writer Block Checksums", func(w io.Writer) abortWriter {
+			return NewWriterWithOptions(w, WriterOptions{BlockChecksum: XXH32, BlockSize: 64 * 1024})
+		}},
+		{"ParallelWriter", func(w io.Writer) abortWriter { return NewParallelWriter(w) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tt.newWriter(&buf)
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			written := buf.Len()
+
+			w.Abort()
+			if buf.Len() != written {
+				t.Errorf("Abort() wrote %d bytes, want 0", buf.Len()-written)
+			}
+			if _, err := w.Write(data); err == nil {
+				t.Errorf("Write() after Abort() succeeded")
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("Close() after Abort() error = %v", err)
+			}
+			if buf.Len() != written {
+				t.Errorf("Close() after Abort() wrote %d bytes, want 0", buf.Len()-written)
+			}
+
+			// A reset writer starts a complete new frame
+			buf.Reset()
+			w.Reset(&buf)
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() after Reset() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() after Reset() error = %v", err)
+			}
+			got, _ := readFrame(t, buf.Bytes())
+			if !bytes.Equal(got, data) {
+				t.Errorf("read %d bytes after Reset(), want %d", len(got), len(data))
+			}
+		})
+	}
+}
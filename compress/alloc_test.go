@@ -0,0 +1,209 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestCompressorAllocs tests that a reused Compressor doesn't allocate
+func TestCompressorAllocs(t *testing.T) {
+	src := generateCompressibleData(64 * 1024)
+	dst := make([]byte, len(src)+len(src)/255+16)
+	dict := generateCompressibleData(32 * 1024)
+
+	for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+		c, err := NewCompressor(level)
+		if err != nil {
+			t.Fatalf("NewCompressor() error = %v", err)
+		}
+
+		allocs := testing.AllocsPerRun(20, func() {
+			if _, err := c.CompressBlock(src, dst); err != nil {
+				t.Fatalf("CompressBlock() error = %v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("level %d: CompressBlock() allocs = %v, want 0", level, allocs)
+		}
+
+		allocs = testing.AllocsPerRun(20, func() {
+			if _, err := c.CompressBlockDict(src, dst, dict); err != nil {
+				t.Fatalf("CompressBlockDict() error = %v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("level %d: CompressBlockDict() allocs = %v, want 0", level, allocs)
+		}
+	}
+}
+
+// TestCompressorMatchesCompressBlockLevel tests that a reused Compressor
+// produces the same blocks as CompressBlockLevel
+func TestCompressorMatchesCompressBlockLevel(t *testing.T) {
+	inputs := [][]byte{
+		generateCompressibleData(100 * 1024),
+		generateRandomData(10 * 1024),
+		generateCompressibleData(1000),
+	}
+
+	for _, level := range []CompressionLevel{1, 2, DefaultLevel, 9, MaxLevel} {
+		c, err := NewCompressor(level)
+		if err != nil {
+			t.Fatalf("NewCompressor() error = %v", err)
+		}
+		for i, src := range inputs {
+			want, err := CompressBlockLevel(src, nil, level)
+			if err != nil {
+				t.Fatalf("CompressBlockLevel() error = %v", err)
+			}
+			got, err := c.CompressBlock(src, nil)
+			if err != nil {
+				t.Fatalf("CompressBlock() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("level %d input %d: CompressBlock() differs from CompressBlockLevel()", level, i)
+			}
+		}
+	}
+
+	if _, err := NewCompressor(MaxLevel + 1); err != ErrInvalidCompressionLevel {
+		t.Errorf("NewCompressor() error = %v, want %v", err, ErrInvalidCompressionLevel)
+	}
+}
+
+// TestWriterWriteAllocs tests that Write doesn't allocate once the frame has
+// started
+func TestWriterWriteAllocs(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := generateCompressibleData(blockSize)
+
+	for _, checksum := range []Checksummer{nil, XXH32} {
+		w := NewWriterWithOptions(io.Discard, WriterOptions{BlockSize: blockSize, BlockChecksum: checksum})
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		allocs := testing.AllocsPerRun(20, func() {
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("Write() allocs = %v, want 0", allocs)
+		}
+	}
+}
+
+// TestReaderReadAllocs tests that Read doesn't allocate once the first
+// block has been read
+func TestReaderReadAllocs(t *testing.T) {
+	const blockSize = 64 * 1024
+	const runs = 20
+
+	for _, checksum := range []Checksummer{nil, XXH32} {
+		// Mix compressed and stored blocks
+		var data []byte
+		for i := 0; i < runs+2; i++ {
+			if i%2 == 0 {
+				data = append(data, generateCompressibleData(blockSize)...)
+			} else {
+				data = append(data, generateRandomData(blockSize)...)
+			}
+		}
+		frame := compressFrame(t, data, func(w *Writer) {
+			w.blockSize = blockSize
+			w.header.blockChecksum = checksum != nil
+		})
+
+		r := NewReader(bytes.NewReader(frame))
+		p := make([]byte, blockSize)
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+
+		allocs := testing.AllocsPerRun(runs, func() {
+			if _, err := io.ReadFull(r, p); err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("Read() allocs = %v, want 0", allocs)
+		}
+	}
+}
+
+// TestFrameHeaderAllocs tests that reading and writing the headers and
+// trailers of concatenated frames doesn't allocate
+func TestFrameHeaderAllocs(t *testing.T) {
+	const size = 1000
+	const runs = 20
+	data := generateCompressibleData(size)
+
+	// Every frame has a content size and a content checksum, and is
+	// preceded by a sparse hole
+	var stream bytes.Buffer
+	for i := 0; i < runs+2; i++ {
+		if err := writeSparseHole(&stream, 1); err != nil {
+			t.Fatalf("writeSparseHole() error = %v", err)
+		}
+		stream.Write(compressFrame(t, data, func(w *Writer) {
+			w.header.contentChecksum = true
+			w.SetContentSize(size)
+		}))
+	}
+	r := NewReaderWithOptions(&stream, ReaderOptions{SparseHoles: true})
+	p := make([]byte, size+1)
+	if _, err := io.ReadFull(r, p); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	allocs := testing.AllocsPerRun(runs, func() {
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Read() of a frame allocs = %v, want 0", allocs)
+	}
+
+	w := NewWriterWithOptions(io.Discard, WriterOptions{ContentChecksum: true})
+	allocs = testing.AllocsPerRun(runs, func() {
+		w.Reset(io.Discard)
+		w.SetContentSize(size)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Writer frame allocs = %v, want 0", allocs)
+	}
+}
+
+// TestReaderDictAllocs tests that reading a frame with a dictionary reuses
+// the block output buffer
+func TestReaderDictAllocs(t *testing.T) {
+	const blockSize = 64 * 1024
+	const runs = 20
+
+	store := NewMemoryDictionaryStore()
+	store.Publish(dictionaryFrom(generateRecords(200, 0)))
+	frame := writeDictFrame(t, generateCompressibleData((runs+2)*blockSize), store)
+
+	r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{Dictionaries: store})
+	p := make([]byte, blockSize)
+	if _, err := io.ReadFull(r, p); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(runs, func() {
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Read() allocs = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// AppendTo is a variant of Reset that prepares the Writer to append to the
+// LZ4 data already stored in rws.
+//
+// If the last frame in rws can be extended, the Writer seeks back over its
+// end marker and continues that frame, adopting its header. A frame can be
+// extended when it declares no content size, content checksum, block
+// checksums or dictionary, since appending would invalidate those fields.
+// Otherwise new data is written as an additional frame after the existing
+// data, which readers decode as one continuous stream. An empty rws simply
+// starts a new frame.
+func (z *Writer) AppendTo(rws io.ReadWriteSeeker) error {
+	z.lock()
+	defer z.unlock()
+
+	// The block in flight may be writing to rws
+	z.wait()
+	end, err := rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	z.reset(rws)
+	if end == 0 {
+		return nil
+	}
+
+	// Walk every frame to find the end marker of the last one
+	if _, err := rws.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := NewReader(rws)
+	var last frameHeader
+	endMarker := int64(-1)
+	frameEnd := int64(0)
+
+	for frameEnd < end {
+		r.header = frameHeader{}
+		if err := r.readFrameHeader(); err != nil {
+			// Only skippable frames remain after the last LZ4 frame
+			if err == io.EOF {
+				break
+			}
+			return unexpectedEOF(err)
+		}
+
+		endMarker, err = skipFrameBlocks(rws, &r.header)
+		if err != nil {
+			return err
+		}
+		last = r.header
+
+		frameEnd = endMarker + 4
+		if last.contentChecksum {
+			frameEnd += 4
+		}
+		if _, err := rws.Seek(frameEnd, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	if frameEnd > end {
+		return io.ErrUnexpectedEOF
+	}
+
+	// Start a new frame when the last one can't be extended in place
+	extendable := endMarker >= 0 && !last.contentSize && !last.contentChecksum &&
+		!last.blockChecksum && !last.dictID && frameEnd == end
+	if !extendable {
+		_, err := rws.Seek(end, io.SeekStart)
+		return err
+	}
+
+	// Overwrite the end marker with new blocks
+	if _, err := rws.Seek(endMarker, io.SeekStart); err != nil {
+		return err
+	}
+
+	z.header = last
+	z.wroteHeader = true
+
+	// Blocks must not exceed the block size declared by the existing header
+	maxSize := BlockSizeCode(last.blockSizeCode).Size()
+	if z.blockSize > maxSize {
+		z.blockSize = maxSize
+	}
+
+	return nil
+}
+
+// skipFrameBlocks seeks past the blocks of a frame whose header has already
+// been read and returns the offset of its end marker
+func skipFrameBlocks(rs io.ReadSeeker, h *frameHeader) (int64, error) {
+	var sizeBuf [4]byte
+	for {
+		if _, err := io.ReadFull(rs, sizeBuf[:]); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+
+		blockSize := binary.LittleEndian.Uint32(sizeBuf[:])
+		if blockSize == 0 {
+			return rs.Seek(-4, io.SeekCurrent)
+		}
+
+		skip := int64(blockSize & 0x7FFFFFFF)
+		if skip > maxBlockSize {
+			return 0, errors.New("block size too large")
+		}
+		if h.blockChecksum {
+			skip += 4
+		}
+		if _, err := rs.Seek(skip, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
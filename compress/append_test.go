@@ -0,0 +1,132 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFrameFile writes data as a single frame to a new file and returns it open
+func writeFrameFile(t *testing.T, data []byte, configure func(w *Writer)) *os.File {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "data.lz4"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	if data == nil {
+		return f
+	}
+
+	if _, err := f.Write(compressFrame(t, data, configure)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return f
+}
+
+// appendAndRead appends data to f with AppendTo and returns the decoded file contents
+func appendAndRead(t *testing.T, f *os.File, data []byte) []byte {
+	t.Helper()
+
+	w := NewWriter(io.Discard)
+	if err := w.AppendTo(f); err != nil {
+		t.Fatalf("AppendTo() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	got, err := io.ReadAll(NewReader(f))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return got
+}
+
+// countFrames counts the frame magic numbers in a file
+func countFrames(t *testing.T, f *os.File) int {
+	t.Helper()
+
+	contents, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return bytes.Count(contents, []byte{0x04, 0x22, 0x4D, 0x18})
+}
+
+// TestWriterAppendTo tests appending to existing frames
+func TestWriterAppendTo(t *testing.T) {
+	first := generateCompressibleData(100 * 1024)
+	second := generateRandomData(10 * 1024)
+	want := append(append([]byte(nil), first...), second...)
+
+	t.Run("Extend existing frame", func(t *testing.T) {
+		f := writeFrameFile(t, first, nil)
+		if got := appendAndRead(t, f, second); !bytes.Equal(got, want) {
+			t.Fatalf("decoded %d bytes, want %d", len(got), len(want))
+		}
+		if n := countFrames(t, f); n != 1 {
+			t.Errorf("file contains %d frames, want 1", n)
+		}
+	})
+
+	t.Run("Extend frame with small blocks", func(t *testing.T) {
+		f := writeFrameFile(t, first, func(w *Writer) {
+			w.header.blockSizeCode = 4
+			w.Reset(w.w)
+		})
+		if got := appendAndRead(t, f, second); !bytes.Equal(got, want) {
+			t.Fatalf("decoded %d bytes, want %d", len(got), len(want))
+		}
+	})
+
+	t.Run("Extend empty frame", func(t *testing.T) {
+		f := writeFrameFile(t, []byte{}, nil)
+		if got := appendAndRead(t, f, second); !bytes.Equal(got, second) {
+			t.Fatalf("decoded %d bytes, want %d", len(got), len(second))
+		}
+	})
+
+	t.Run("Additional frame after content size", func(t *testing.T) {
+		f := writeFrameFile(t, first, func(w *Writer) {
+			w.header.contentSize = true
+			w.header.contentSizeValue = uint64(len(first))
+		})
+		if got := appendAndRead(t, f, second); !bytes.Equal(got, want) {
+			t.Fatalf("decoded %d bytes, want %d", len(got), len(want))
+		}
+		if n := countFrames(t, f); n != 2 {
+			t.Errorf("file contains %d frames, want 2", n)
+		}
+	})
+
+	t.Run("Empty file", func(t *testing.T) {
+		f := writeFrameFile(t, nil, nil)
+		if got := appendAndRead(t, f, second); !bytes.Equal(got, second) {
+			t.Fatalf("decoded %d bytes, want %d", len(got), len(second))
+		}
+	})
+
+	t.Run("Truncated frame", func(t *testing.T) {
+		f := writeFrameFile(t, first, nil)
+		info, _ := f.Stat()
+		if err := f.Truncate(info.Size() - 6); err != nil {
+			t.Fatalf("Truncate() error = %v", err)
+		}
+
+		w := NewWriter(io.Discard)
+		if err := w.AppendTo(f); err != io.ErrUnexpectedEOF {
+			t.Errorf("AppendTo() error = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	})
+}
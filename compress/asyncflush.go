@@ -0,0 +1,58 @@
+package compress
+
+// With WriterOptions.AsyncFlush, a full block buffer is handed to a
+// background goroutine that compresses and writes it while Write fills the
+// Writer's second buffer with the next block. Only one block is in flight
+// at a time, so output order is kept and memory stays at two blocks. The
+// goroutine is the only one touching the compression state and the
+// underlying writer until it is waited for; every method that needs them
+// calls wait first, and Write checks for a finished block with poll.
+
+// flushAsync starts compressing and writing the buffered block in the
+// background and switches Write to the spare buffer. It first waits for the
+// previous block and returns its error, if any.
+func (z *Writer) flushAsync() error {
+	if err := z.wait(); err != nil {
+		return err
+	}
+
+	block := z.buf[:z.bufUsed]
+	z.buf, z.spare = z.spare, z.buf
+	z.bufUsed = 0
+	z.growBuffer()
+
+	done := make(chan struct{})
+	z.pending = done
+	go func() {
+		defer close(done)
+		if err := z.compressBlock(block); err != nil && z.err == nil {
+			z.err = err
+		}
+	}()
+	return nil
+}
+
+// wait waits for the block in flight, if any, and returns the Writer's
+// sticky error
+func (z *Writer) wait() error {
+	if z.pending != nil {
+		<-z.pending
+		z.pending = nil
+	}
+	return z.err
+}
+
+// poll returns the Writer's sticky error without blocking. While a block is
+// in flight only its goroutine may set the error, so it is read only once
+// that block has finished.
+func (z *Writer) poll() error {
+	if z.pending != nil {
+		select {
+		case <-z.pending:
+			z.pending = nil
+		default:
+			return nil
+		}
+	}
+	return z.err
+}
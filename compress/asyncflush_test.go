@@ -0,0 +1,122 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// asyncFrame writes data in writes of chunk bytes and returns the frame
+func asyncFrame(t *testing.T, data []byte, chunk int, options WriterOptions) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, options)
+	for p := data; len(p) > 0; {
+		n := min(chunk, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestWriterAsyncFlush tests that writing blocks in the background gives
+// the same frames as writing them in Write
+func TestWriterAsyncFlush(t *testing.T) {
+	data := append(generateCompressibleData(300*1024), generateRandomData(100*1024)...)
+
+	for _, chunk := range []int{1000, 64 * 1024, len(data)} {
+		options := WriterOptions{BlockSize: 64 * 1024, BlockChecksum: XXH32, ContentChecksum: true}
+		want := asyncFrame(t, data, chunk, options)
+		options.AsyncFlush = true
+		got := asyncFrame(t, data, chunk, options)
+		if !bytes.Equal(got, want) {
+			t.Errorf("writes of %d: async frame of %d bytes differs from the %d written in Write", chunk, len(got), len(want))
+		}
+	}
+
+	// The trace and metrics see every block in order
+	var mu sync.Mutex
+	var blocks []int
+	rec := metricsFunc(func(compressed, raw int, _ time.Duration) {
+		mu.Lock()
+		blocks = append(blocks, raw)
+		mu.Unlock()
+	})
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024, AsyncFlush: true})
+	w.SetMetricsRecorder(rec)
+	w.Write(data)
+	w.Close()
+	if len(blocks) != 7 || blocks[6] != len(data)-6*64*1024 {
+		t.Errorf("recorded blocks %v, want 6 of 64KB and the rest", blocks)
+	}
+}
+
+// metricsFunc adapts a function to a MetricsRecorder
+type metricsFunc func(compressed, raw int, dur time.Duration)
+
+func (f metricsFunc) RecordBlock(compressed, raw int, dur time.Duration) {
+	f(compressed, raw, dur)
+}
+
+// TestWriterAsyncFlushErrors tests that an error writing a block in the
+// background is returned by a later Write or Close, and kept
+func TestWriterAsyncFlushErrors(t *testing.T) {
+	data := generateRandomData(400 * 1024)
+
+	sw := &shortWriter{max: 1 << 20, limit: 100 * 1024}
+	w := NewWriterWithOptions(sw, WriterOptions{BlockSize: 64 * 1024, AsyncFlush: true})
+	var err error
+	for p := data; len(p) > 0 && err == nil; p = p[min(4096, len(p)):] {
+		_, err = w.Write(p[:min(4096, len(p))])
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if _, err := w.Write(data[:10]); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Write() after the error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if err := w.Close(); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Close() after the error = %v, want %v", err, io.ErrShortWrite)
+	}
+
+	// Reset waits for the failed block and starts afresh
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() after Reset error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() after Reset error = %v", err)
+	}
+	if got, err := io.ReadAll(NewReader(&buf)); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() after Reset = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+}
+
+// TestWriterAsyncFlushAbort tests that Abort waits for the block in flight
+// and releases both buffers
+func TestWriterAsyncFlushAbort(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024, AsyncFlush: true})
+	w.Write(generateCompressibleData(200 * 1024))
+	w.Abort()
+	if w.pending != nil || w.buf != nil || w.spare != nil {
+		t.Errorf("Abort() left a block in flight or a buffer")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() after Abort() error = %v", err)
+	}
+}
@@ -1,23 +1,45 @@
 // Package compress provides LZ4HC compression algorithms.
+//
+// Concurrency: the block functions are safe to call from any number of
+// goroutines. Writer, Reader, ParallelWriter, Encoder, Decoder,
+// EncoderSession, DecoderSession, Muxer and Demuxer serialize their methods
+// with an internal mutex, so one instance may be shared between goroutines;
+// concurrent Write calls are each applied whole, in an unspecified order. Reset waits for an in-flight Write or Close
+// to finish. Byte counters such as Written and Consumed can be read at any
+// time without blocking.
 package compress
 
 import (
 	_ "encoding/binary"
 	"errors"
 	_ "math/bits"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+	"github.com/harriteja/GoZ4X/format"
 )
 
 const (
 	// MinBlockSize is the minimum size of a block
 	MinBlockSize = 16
 	// MaxBlockSize is the maximum size of a block
-	MaxBlockSize = 4 << 20 // 4MB
+	MaxBlockSize = block.MaxBlockSize
+
+	// End-of-block rules from the LZ4 block format: the last 5 bytes of a
+	// block are always literals and the last match starts at least 12 bytes
+	// before the end of the block
+	lastLiterals = format.LastLiterals
+	mfLimit      = format.MFLimit
 )
 
 // CompressionLevel defines how much effort to spend on compression
 type CompressionLevel int
 
 const (
+	// NoCompression stores data without searching for matches. Blocks are
+	// encoded as a single run of literals and writers store every block raw,
+	// still producing valid frames with any checksums enabled.
+	NoCompression CompressionLevel = 0
 	// DefaultLevel is the default compression level (6)
 	DefaultLevel CompressionLevel = 6
 	// FastLevel optimizes for speed over compression ratio
@@ -46,6 +68,18 @@ type BlockOptions struct {
 	PreallocateBuffer int
 	// SkipChecksums skips calculating checksums
 	SkipChecksums bool
+	// TimeBudget, if positive, is a soft deadline for compressing the
+	// block, for realtime pipelines that prefer a worse ratio to a missed
+	// deadline. Once three quarters of it are spent the match search is
+	// cut down, and once it is spent the rest of the block is left as
+	// literals. CompressWithStats reports whether that happened. V2Block
+	// ignores it.
+	TimeBudget time.Duration
+	// MaxExtLenBytes, if positive, limits the extension bytes of every
+	// literal and match length, as described for LimitExtLen. Blocks with a
+	// run of literals too long for it fail with ErrExtLenLimit. V2Block
+	// ignores it.
+	MaxExtLenBytes int
 }
 
 // NewBlock creates a new block from input with default options
@@ -73,44 +107,114 @@ func NewBlockWithOptions[T ~[]byte](input T, level CompressionLevel, options Blo
 // CompressToBuffer compresses the block data to the provided buffer
 // This is a new method that will be used by CompressBlockLevel
 func (b *Block[T]) CompressToBuffer(dst []byte) ([]byte, error) {
-	// Input data and length
-	input := b.input
-	inputLen := len(input)
+	if b.options.MaxExtLenBytes > 0 {
+		out, err := b.compress(nil)
+		if err != nil {
+			return nil, err
+		}
+		return LimitExtLen(out, b.options.MaxExtLenBytes, dst)
+	}
+	return b.compress(dst)
+}
 
-	// Create matcher based on level
-	matcher := NewHCMatcher(b.level)
+// compress compresses the block data to dst, with its time budget if any
+func (b *Block[T]) compress(dst []byte) ([]byte, error) {
+	if b.options.TimeBudget > 0 {
+		out, _, err := b.CompressWithStats(dst)
+		return out, err
+	}
+	return compressHC(b.input, 0, dst, b.level)
+}
+
+// compressHC compresses input[start:] into dst. The bytes before start are
+// only used as a dictionary that matches may reference.
+func compressHC(input []byte, start int, dst []byte, level CompressionLevel) ([]byte, error) {
+	if level == NoCompression {
+		return storeBlock(input[start:], dst), nil
+	}
+	return compressWith(newHCMatcherFor(level, len(input)), input, start, dst)
+}
+
+// storeBlock encodes src into dst as a block holding a single run of literals
+func storeBlock(src, dst []byte) []byte {
+	return appendSequence(dst[:0], src, 0, 0)
+}
+
+// compressWith is compressHC with a matcher that may be reused across calls
+func compressWith(matcher *HCMatcher, input []byte, start int, dst []byte) ([]byte, error) {
 	matcher.Reset(input)
+	if start > 0 {
+		matcher.UpdateTables(0, start)
+		matcher.Advance(start)
+	}
+	return compressFrom(matcher, input, start, dst)
+}
+
+// compressFrom compresses input[start:] with a matcher that already holds
+// the hashes of input[:start] and is positioned at start
+func compressFrom(matcher *HCMatcher, input []byte, start int, dst []byte) ([]byte, error) {
+	return compressLimit(matcher, input, start, dst, compressBound(len(input)-start))
+}
 
-	// Calculate worst-case output size
-	worstCaseSize := inputLen + (inputLen / 255) + 16
+// compressLimit is compressFrom with the output limited to limit bytes, at
+// most the worst-case size. It returns ErrOutputLimit as soon as the pending
+// literals or the next sequence would take the output past the limit.
+func compressLimit(matcher *HCMatcher, input []byte, start int, dst []byte, limit int) ([]byte, error) {
+	inputLen := len(input)
 
 	// Allocate buffer if needed
-	if dst == nil || len(dst) < worstCaseSize {
-		dst = make([]byte, worstCaseSize)
+	if dst == nil || len(dst) < limit {
+		dst = make([]byte, limit)
 	}
 
 	// Initialize positions
-	srcPos := 0
+	srcPos := start
 	dstPos := 0
 
 	// LastLiteral is the position where the last literal block started
-	lastLiteral := 0
+	lastLiteral := start
+	// Misses counts the positions searched since the last match
+	misses := 0
 
 	// Main compression loop
 	for !matcher.End() {
+		// No match may start within the last mfLimit bytes
+		if srcPos > inputLen-mfLimit {
+			break
+		}
+		// Past its time budget the rest of the block is left as literals
+		if matcher.budget != nil && matcher.budget.check(srcPos, matcher) {
+			break
+		}
+
 		// Find the best match at the current position
 		offset, matchLen := matcher.FindBestMatch()
 
+		// Keep the last lastLiterals bytes as literals
+		matchLen = min(matchLen, inputLen-lastLiterals-srcPos)
+
 		// If no good match, advance and continue
 		if matchLen < 4 {
-			// Advance the matcher and continue
-			matcher.Advance(1)
-			srcPos++
+			// Advance the matcher, faster the longer matches are missing
+			step := skipStep(misses, matcher.skipTrigger, inputLen-mfLimit+1-srcPos)
+			misses++
+			// Skipped positions are still hashed, so that data after an
+			// incompressible stretch can match them
+			matcher.UpdateTables(srcPos+1, srcPos+step)
+			matcher.Advance(step)
+			srcPos += step
+			// The pending literals alone may no longer fit
+			if dstPos+1+srcPos-lastLiteral > limit {
+				return nil, ErrOutputLimit
+			}
 			continue
 		}
 
 		// We found a match, output the literal sequence since the last match
 		literalLen := srcPos - lastLiteral
+		if dstPos+sequenceSize(literalLen, matchLen) > limit {
+			return nil, ErrOutputLimit
+		}
 
 		// Write token: 4 bits for literal length, 4 bits for match length
 		literalLenCode := literalLen
@@ -164,6 +268,7 @@ func (b *Block[T]) CompressToBuffer(dst []byte) ([]byte, error) {
 		// Advance source position and last literal marker
 		srcPos += matchLen
 		lastLiteral = srcPos
+		misses = 0
 
 		// Advance the matcher
 		matcher.Advance(matchLen)
@@ -172,6 +277,9 @@ func (b *Block[T]) CompressToBuffer(dst []byte) ([]byte, error) {
 	// Handle the final literal block
 	if lastLiteral < inputLen {
 		literalLen := inputLen - lastLiteral
+		if dstPos+sequenceSize(literalLen, 0) > limit {
+			return nil, ErrOutputLimit
+		}
 
 		// Write token: literal only, no match
 		literalLenCode := literalLen
@@ -211,6 +319,7 @@ func CompressBlock(src []byte, dst []byte) ([]byte, error) {
 }
 
 // CompressBlockLevel compresses input with specified compression level.
+// NoCompression encodes the input as literals only.
 // If dst is nil or too small, a new buffer will be allocated.
 func CompressBlockLevel(src []byte, dst []byte, level CompressionLevel) ([]byte, error) {
 	block, err := NewBlock(src, level)
@@ -229,136 +338,3 @@ func max(a, b int) int {
 	}
 	return b
 }
-
-// DecompressBlock decompresses an LZ4 compressed block.
-// If dst is nil or too small, a new buffer will be allocated.
-func DecompressBlock(src []byte, dst []byte, maxSize int) ([]byte, error) {
-	// Validate input
-	if len(src) == 0 {
-		return nil, errors.New("empty source buffer")
-	}
-
-	if maxSize <= 0 {
-		maxSize = 64 * 1024 // Default max size if not specified
-	}
-
-	if dst == nil || len(dst) < maxSize {
-		dst = make([]byte, maxSize)
-	}
-
-	srcPos := 0
-	dstPos := 0
-
-	// Process the block byte by byte
-	for srcPos < len(src) {
-		// Read the token
-		if srcPos >= len(src) {
-			return nil, errors.New("invalid block: unexpected end of input")
-		}
-
-		token := src[srcPos]
-		srcPos++
-
-		// Extract literal length from the high 4 bits
-		literalLen := int(token >> 4)
-
-		// Handle extended literal length (if the literal length is 15)
-		if literalLen == 15 {
-			for srcPos < len(src) {
-				l := int(src[srcPos])
-				srcPos++
-				literalLen += l
-				if l != 255 {
-					break
-				}
-			}
-		}
-
-		// Check if we have enough space for the literal data
-		if srcPos+literalLen > len(src) {
-			return nil, errors.New("source buffer too small for literal data")
-		}
-
-		// Check if we have enough space in the destination buffer
-		if dstPos+literalLen > len(dst) {
-			// Grow the destination buffer if needed
-			newSize := max(len(dst)*2, dstPos+literalLen)
-			if newSize > maxSize {
-				return nil, errors.New("decompressed data would exceed maxSize")
-			}
-
-			newDst := make([]byte, newSize)
-			copy(newDst, dst[:dstPos])
-			dst = newDst
-		}
-
-		// Copy literal data
-		copy(dst[dstPos:], src[srcPos:srcPos+literalLen])
-		srcPos += literalLen
-		dstPos += literalLen
-
-		// If we've reached the end of the block, break
-		if srcPos >= len(src) {
-			break
-		}
-
-		// Extract match offset (2 bytes, little-endian)
-		if srcPos+2 > len(src) {
-			return nil, errors.New("invalid block: missing match offset")
-		}
-
-		offset := int(src[srcPos]) | int(src[srcPos+1])<<8
-		srcPos += 2
-
-		// Zero offset is invalid
-		if offset == 0 {
-			return nil, errors.New("invalid match offset 0")
-		}
-
-		// Extract match length from the low 4 bits of the token
-		matchLen := int(token & 0x0F)
-
-		// Handle extended match length (if match length is 15)
-		if matchLen == 15 {
-			for srcPos < len(src) {
-				l := int(src[srcPos])
-				srcPos++
-				matchLen += l
-				if l != 255 {
-					break
-				}
-			}
-		}
-
-		// LZ4 stores matchLen as (actual-4), add the implicit 4 back
-		matchLen += 4
-
-		// Check if the match offset is valid
-		if offset > dstPos {
-			return nil, errors.New("invalid match: offset beyond current position")
-		}
-
-		// Check if we have enough space in the destination buffer
-		if dstPos+matchLen > len(dst) {
-			// Grow the destination buffer if needed
-			newSize := max(len(dst)*2, dstPos+matchLen)
-			if newSize > maxSize {
-				return nil, errors.New("decompressed data would exceed maxSize")
-			}
-
-			newDst := make([]byte, newSize)
-			copy(newDst, dst[:dstPos])
-			dst = newDst
-		}
-
-		// Copy match data (LZ4 allows overlap between match and destination)
-		src_idx := dstPos - offset
-		for i := 0; i < matchLen; i++ {
-			dst[dstPos+i] = dst[src_idx+i]
-		}
-
-		dstPos += matchLen
-	}
-
-	return dst[:dstPos], nil
-}
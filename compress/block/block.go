@@ -0,0 +1,291 @@
+// Package block decodes raw LZ4 blocks, without the frame format around
+// them: DecompressInto and DecompressAlloc decode a whole block,
+// DecompressAppend decodes after a dictionary or earlier output,
+// DecompressFunc streams the output in chunks, SequenceReader walks the
+// sequences of a block without decoding it, and ValidatingDecoder checks
+// every rule of the format while decoding, for testing encoders.
+//
+// The package depends on nothing beyond the standard library and the
+// format constants, so programs that only decode blocks don't link the
+// match finders, frame codec or package state of compress. The compress
+// package forwards its block decoding functions and types here. Block
+// compression is still only in compress, whose encoders share the match
+// finders of its frame writers.
+package block
+
+import (
+	"errors"
+	"io"
+	"math"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// MaxBlockSize is the largest decompressed size of a block
+const MaxBlockSize = 4 << 20 // 4MB
+
+// maxLength caps the literal and match lengths decoded from a block. It is
+// far beyond any real block, and keeps the sum of extension bytes from
+// overflowing an int on 32-bit platforms.
+const maxLength = math.MaxInt32 - 255
+
+var (
+	errEmptySource = errors.New("empty source buffer")
+	errTooLarge    = errors.New("decompressed data would exceed maxSize")
+	errOffset      = errors.New("invalid match: offset beyond current position")
+)
+
+// DecompressInto decompresses an LZ4 block into dst and returns the number
+// of bytes written. It never allocates: the block must decompress into
+// len(dst) bytes, or it fails with io.ErrShortBuffer.
+func DecompressInto(src, dst []byte) (int, error) {
+	if len(src) == 0 {
+		return 0, errEmptySource
+	}
+	if lits, ok := LiteralRun(src); ok {
+		if len(lits) > len(dst) {
+			return 0, io.ErrShortBuffer
+		}
+		return copy(dst, lits), nil
+	}
+	return decompressInto(src, dst)
+}
+
+// DecompressAlloc decompresses an LZ4 block into a new buffer. maxSize
+// bounds the decompressed size, and the buffer is allocated with that size
+// up front. If maxSize is zero or negative the size is unknown: a first
+// pass scans the sequences for the exact decompressed size, up to
+// MaxBlockSize. A block decompressing to more than maxSize bytes fails with
+// io.ErrShortBuffer.
+func DecompressAlloc(src []byte, maxSize int) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errEmptySource
+	}
+	if lits, ok := LiteralRun(src); ok {
+		if len(lits) > MaxBlockSize || (maxSize > 0 && len(lits) > maxSize) {
+			return nil, io.ErrShortBuffer
+		}
+		return append([]byte(nil), lits...), nil
+	}
+
+	if maxSize <= 0 {
+		n, err := DecodedLen(src, MaxBlockSize)
+		if err != nil {
+			return nil, err
+		}
+		maxSize = n
+	}
+	dst := make([]byte, maxSize)
+	n, err := decompressInto(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:n], nil
+}
+
+// DecompressAppend decompresses an LZ4 block, appending the output to dst,
+// and returns the extended slice. Matches may reach back into the bytes
+// dst already holds, which is how blocks compressed after a dictionary or
+// after the blocks before them are decoded. maxSize bounds the size of the
+// output appended.
+func DecompressAppend(dst, src []byte, maxSize int) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errEmptySource
+	}
+
+	limit := len(dst) + maxSize
+	sr := SequenceReader{src: src}
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return dst, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if room := limit - len(dst); len(seq.Literals) > room || seq.MatchLen > room-len(seq.Literals) {
+			return nil, errTooLarge
+		}
+		dst = append(dst, seq.Literals...)
+		if seq.MatchLen == 0 {
+			continue
+		}
+		if seq.Offset == 0 || seq.Offset > len(dst) {
+			return nil, errOffset
+		}
+
+		dst = AppendMatch(dst, seq.Offset, seq.MatchLen)
+	}
+}
+
+// DecodedLen returns the decompressed length of an LZ4 block from the
+// lengths of its sequences, without decoding it. It fails if the block
+// would decompress to more than maxSize bytes.
+func DecodedLen(src []byte, maxSize int) (int, error) {
+	if len(src) == 0 {
+		return 0, errEmptySource
+	}
+	sr := SequenceReader{src: src}
+	var n int
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		// Compare with the room left so that the sum can't overflow
+		room := maxSize - n
+		if len(seq.Literals) > room || seq.MatchLen > room-len(seq.Literals) {
+			return 0, errTooLarge
+		}
+		n += len(seq.Literals) + seq.MatchLen
+	}
+}
+
+// LiteralRun returns the literals of a block made of a single sequence
+// without a match, as stored and incompressible blocks are, and whether src
+// is such a block. Its output is the literals themselves, so the block
+// needs no decoding.
+func LiteralRun(src []byte) ([]byte, bool) {
+	if len(src) == 0 {
+		return nil, false
+	}
+	token := src[0]
+	if token&0x0F != 0 {
+		return nil, false
+	}
+
+	pos := 1
+	n := int(token >> 4)
+	if n == 15 {
+		for pos < len(src) && n <= maxLength {
+			l := int(src[pos])
+			pos++
+			n += l
+			if l != 255 {
+				break
+			}
+		}
+	}
+	if n != len(src)-pos {
+		return nil, false
+	}
+	return src[pos:], true
+}
+
+// decompressInto decodes the sequences of src into dst, bounded by its
+// length, returning the number of bytes written or io.ErrShortBuffer if
+// they don't fit
+func decompressInto(src, dst []byte) (int, error) {
+	srcPos := 0
+	dstPos := 0
+
+	// Process the block byte by byte
+	for srcPos < len(src) {
+		// Read the token
+		if srcPos >= len(src) {
+			return 0, errors.New("invalid block: unexpected end of input")
+		}
+
+		token := src[srcPos]
+		srcPos++
+
+		// Extract literal length from the high 4 bits
+		literalLen := int(token >> 4)
+
+		// Handle extended literal length (if the literal length is 15)
+		if literalLen == 15 {
+			for srcPos < len(src) {
+				l := int(src[srcPos])
+				srcPos++
+				literalLen += l
+				if literalLen > maxLength {
+					return 0, errors.New("invalid block: literal length overflow")
+				}
+				if l != 255 {
+					break
+				}
+			}
+		}
+
+		// Check if we have enough space for the literal data. Lengths are
+		// compared with the space left so that the sums can't overflow.
+		if literalLen > len(src)-srcPos {
+			return 0, errors.New("source buffer too small for literal data")
+		}
+
+		// dst is never grown
+		if literalLen > len(dst)-dstPos {
+			return 0, io.ErrShortBuffer
+		}
+
+		// Copy literal data
+		copy(dst[dstPos:], src[srcPos:srcPos+literalLen])
+		srcPos += literalLen
+		dstPos += literalLen
+
+		// If we've reached the end of the block, break
+		if srcPos >= len(src) {
+			break
+		}
+
+		// Extract match offset (2 bytes, little-endian)
+		if srcPos+2 > len(src) {
+			return 0, errors.New("invalid block: missing match offset")
+		}
+
+		offset := int(src[srcPos]) | int(src[srcPos+1])<<8
+		srcPos += 2
+
+		// Zero offset is invalid
+		if offset == 0 {
+			return 0, errors.New("invalid match offset 0")
+		}
+
+		// Extract match length from the low 4 bits of the token
+		matchLen := int(token & 0x0F)
+
+		// Handle extended match length (if match length is 15)
+		if matchLen == 15 {
+			for srcPos < len(src) {
+				l := int(src[srcPos])
+				srcPos++
+				matchLen += l
+				if matchLen > maxLength {
+					return 0, errors.New("invalid block: match length overflow")
+				}
+				if l != 255 {
+					break
+				}
+			}
+		}
+
+		// LZ4 stores matchLen as (actual-4), add the implicit 4 back
+		matchLen += format.MinMatch
+
+		// Check if the match offset is valid
+		if offset > dstPos {
+			return 0, errOffset
+		}
+
+		// Check if we have enough space in the destination buffer
+		if matchLen > len(dst)-dstPos {
+			return 0, io.ErrShortBuffer
+		}
+
+		// Copy match data (LZ4 allows overlap between match and destination)
+		copyMatch(dst, dstPos, offset, matchLen)
+
+		dstPos += matchLen
+
+		// The last sequence of a block never has a match part
+		if srcPos >= len(src) {
+			return 0, errors.New("invalid block: last sequence must contain only literals")
+		}
+	}
+
+	return dstPos, nil
+}
@@ -0,0 +1,133 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// randomData returns size bytes of incompressible data
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return data
+}
+
+// testBlock is a hand-encoded block: 4 literals, a match of 8 at offset 4,
+// then 5 literals. It decodes to testDecoded.
+var (
+	testBlock   = []byte{0x44, 'a', 'b', 'c', 'd', 4, 0, 0x50, 'v', 'w', 'x', 'y', 'z'}
+	testDecoded = []byte("abcdabcdabcdvwxyz")
+)
+
+// TestDecompress tests the decoders on a hand-encoded block
+func TestDecompress(t *testing.T) {
+	dst := make([]byte, len(testDecoded))
+	if n, err := DecompressInto(testBlock, dst); err != nil || !bytes.Equal(dst[:n], testDecoded) {
+		t.Errorf("DecompressInto() = %q, %v, want %q", dst[:n], err, testDecoded)
+	}
+	if _, err := DecompressInto(testBlock, dst[:len(dst)-1]); err != io.ErrShortBuffer {
+		t.Errorf("DecompressInto(short dst) error = %v, want io.ErrShortBuffer", err)
+	}
+
+	for _, maxSize := range []int{0, len(testDecoded)} {
+		got, err := DecompressAlloc(testBlock, maxSize)
+		if err != nil || !bytes.Equal(got, testDecoded) {
+			t.Errorf("DecompressAlloc(maxSize %d) = %q, %v, want %q", maxSize, got, err, testDecoded)
+		}
+	}
+	if _, err := DecompressAlloc(testBlock, len(testDecoded)-1); err != io.ErrShortBuffer {
+		t.Errorf("DecompressAlloc(short maxSize) error = %v, want io.ErrShortBuffer", err)
+	}
+
+	if n, err := DecodedLen(testBlock, MaxBlockSize); n != len(testDecoded) || err != nil {
+		t.Errorf("DecodedLen() = %d, %v, want %d", n, err, len(testDecoded))
+	}
+	if _, err := DecodedLen(testBlock, len(testDecoded)-1); err == nil {
+		t.Errorf("DecodedLen() past maxSize succeeded")
+	}
+
+	var got bytes.Buffer
+	n, err := DecompressFunc(testBlock, func(chunk []byte) error {
+		got.Write(chunk)
+		return nil
+	}, 0)
+	if n != len(testDecoded) || err != nil || !bytes.Equal(got.Bytes(), testDecoded) {
+		t.Errorf("DecompressFunc() = %d, %v, emitting %q", n, err, got.Bytes())
+	}
+}
+
+// TestDecompressAppend tests decoding after a dictionary that matches reach
+// back into
+func TestDecompressAppend(t *testing.T) {
+	// 1 literal, then a match of 6 at offset 7, reaching into the prefix,
+	// then 5 literals
+	src := []byte{0x12, '!', 7, 0, 0x50, 'v', 'w', 'x', 'y', 'z'}
+	prefix := []byte("prefix")
+
+	got, err := DecompressAppend(bytes.Clone(prefix), src, 12)
+	if want := "prefix!prefixvwxyz"; err != nil || string(got) != want {
+		t.Errorf("DecompressAppend() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := DecompressAppend(bytes.Clone(prefix), src, 11); err == nil {
+		t.Errorf("DecompressAppend() past maxSize succeeded")
+	}
+	if _, err := DecompressAppend(nil, src, 100); err == nil {
+		t.Errorf("DecompressAppend() with an offset before the output succeeded")
+	}
+}
+
+// TestLiteralRun tests recognizing blocks made of a single literal run
+func TestLiteralRun(t *testing.T) {
+	for _, size := range []int{1, 14, 15, 16, 269, 270, 300 * 1024} {
+		data := randomData(size)
+		b := []byte{byte(min(size, 15)) << 4}
+		for n := size - 15; n >= 0; n -= 255 {
+			b = append(b, byte(min(n, 255)))
+			if n < 255 {
+				break
+			}
+		}
+		b = append(b, data...)
+
+		lits, ok := LiteralRun(b)
+		if !ok || !bytes.Equal(lits, data) {
+			t.Errorf("LiteralRun(%d bytes) = %d bytes, %v", size, len(lits), ok)
+		}
+	}
+
+	for _, b := range [][]byte{nil, testBlock, {0x50, 'a', 'b', 'c', 'd'}, {0xF0, 2, 'a'}, {0xF0, 255}} {
+		if _, ok := LiteralRun(b); ok {
+			t.Errorf("LiteralRun(% x) = true", b)
+		}
+	}
+}
+
+// TestSequenceReaderReset tests walking blocks with a reused SequenceReader
+func TestSequenceReaderReset(t *testing.T) {
+	var sr SequenceReader
+	for range 2 {
+		sr.Reset(testBlock)
+		var seqs []Sequence
+		for {
+			seq, err := sr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			seqs = append(seqs, seq)
+		}
+		if len(seqs) != 2 || seqs[0].Offset != 4 || seqs[0].MatchLen != 8 || string(seqs[1].Literals) != "vwxyz" {
+			t.Errorf("sequences = %+v", seqs)
+		}
+	}
+
+	sr.Reset([]byte{0x40, 'a'})
+	if _, err := sr.Next(); !errors.Is(err, ErrCorruptBlock) {
+		t.Errorf("Next() of a truncated block error = %v, want ErrCorruptBlock", err)
+	}
+}
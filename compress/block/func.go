@@ -0,0 +1,151 @@
+package block
+
+import "io"
+
+// emitWindow is the decoded history DecompressFunc keeps for matches,
+// and emitBufferSize the size of its whole buffer: the history plus room
+// for the output of up to another 64KB before it is handed to emit
+const (
+	emitWindow     = 64 << 10
+	emitBufferSize = 2 * emitWindow
+)
+
+// DecompressFunc decompresses an LZ4 block like DecompressInto, but
+// hands the output to emit in consecutive chunks instead of returning it.
+// Only the last 64KB, which matches can reach back to, are kept, so any
+// block decodes in a fixed 128KB buffer, straight into a socket, a hash or
+// a ring buffer. A block holding a single run of literals, such as a stored
+// one, is emitted straight from src without any copy.
+//
+// Chunks hold at most 128KB and are only valid during the call to emit,
+// which must copy what it keeps. An error from emit stops decoding and is
+// returned. maxSize bounds the decompressed size; zero or less means
+// MaxBlockSize. It returns the number of bytes emitted, which is less than
+// the block's size only if it also returns an error.
+func DecompressFunc(src []byte, emit func(chunk []byte) error, maxSize int) (int, error) {
+	if len(src) == 0 {
+		return 0, errEmptySource
+	}
+	if maxSize <= 0 || maxSize > MaxBlockSize {
+		maxSize = MaxBlockSize
+	}
+
+	if lits, ok := LiteralRun(src); ok {
+		if len(lits) > maxSize {
+			return 0, errTooLarge
+		}
+		var n int
+		for n < len(lits) {
+			chunk := lits[n:min(n+emitBufferSize, len(lits))]
+			if err := emit(chunk); err != nil {
+				return n, err
+			}
+			n += len(chunk)
+		}
+		return n, nil
+	}
+
+	e := chunkEmitter{buf: make([]byte, 0, emitBufferSize), emit: emit}
+	sr := SequenceReader{src: src}
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return e.emitted, err
+		}
+
+		if room := maxSize - e.decoded; len(seq.Literals) > room || seq.MatchLen > room-len(seq.Literals) {
+			return e.emitted, errTooLarge
+		}
+		if err := e.literals(seq.Literals); err != nil {
+			return e.emitted, err
+		}
+		if seq.MatchLen == 0 {
+			continue
+		}
+		if seq.Offset == 0 || seq.Offset > e.decoded {
+			return e.emitted, errOffset
+		}
+		if err := e.match(seq.Offset, seq.MatchLen); err != nil {
+			return e.emitted, err
+		}
+	}
+
+	if err := e.flush(); err != nil {
+		return e.emitted, err
+	}
+	return e.emitted, nil
+}
+
+// chunkEmitter decodes into a fixed buffer, emitting what it holds and
+// sliding it down to the last emitWindow bytes whenever it fills up
+type chunkEmitter struct {
+	buf  []byte
+	emit func([]byte) error
+	// next is the position in buf of the first byte not yet emitted
+	next int
+	// decoded and emitted count the bytes of the block so far
+	decoded int
+	emitted int
+}
+
+// flush hands the bytes not yet emitted to emit
+func (e *chunkEmitter) flush() error {
+	if e.next == len(e.buf) {
+		return nil
+	}
+	if err := e.emit(e.buf[e.next:]); err != nil {
+		return err
+	}
+	e.emitted += len(e.buf) - e.next
+	e.next = len(e.buf)
+	return nil
+}
+
+// room makes space in the buffer if it is full and returns how much there is
+func (e *chunkEmitter) room() (int, error) {
+	if len(e.buf) == cap(e.buf) {
+		if err := e.flush(); err != nil {
+			return 0, err
+		}
+		n := copy(e.buf, e.buf[len(e.buf)-emitWindow:])
+		e.buf = e.buf[:n]
+		e.next = n
+	}
+	return cap(e.buf) - len(e.buf), nil
+}
+
+// literals appends lits to the output
+func (e *chunkEmitter) literals(lits []byte) error {
+	for len(lits) > 0 {
+		room, err := e.room()
+		if err != nil {
+			return err
+		}
+		n := copy(e.buf[len(e.buf):len(e.buf)+room], lits)
+		e.buf = e.buf[:len(e.buf)+n]
+		e.decoded += n
+		lits = lits[n:]
+	}
+	return nil
+}
+
+// match appends length bytes found offset bytes back in the output, which
+// the buffer always still holds as offset is at most format.MaxOffset
+func (e *chunkEmitter) match(offset, length int) error {
+	for length > 0 {
+		room, err := e.room()
+		if err != nil {
+			return err
+		}
+		n := min(length, room)
+		pos := len(e.buf)
+		e.buf = e.buf[:pos+n]
+		copyMatch(e.buf, pos, offset, n)
+		e.decoded += n
+		length -= n
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package block
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// copyMatch writes the length bytes found offset bytes back in dst to
+// dst[pos:]. A match may overlap the bytes it produces, repeating a pattern
+// of offset bytes. Matches that don't overlap are a single copy; the others
+// are left to copyOverlap, keeping copyMatch small enough to inline.
+func copyMatch(dst []byte, pos, offset, length int) {
+	if offset >= length {
+		copy(dst[pos:pos+length], dst[pos-offset:])
+		return
+	}
+	copyOverlap(dst, pos, offset, length)
+}
+
+// copyOverlap is copyMatch for matches that overlap the bytes they produce.
+// It copies the pattern in chunks that double in size, so that even a run
+// of one byte takes only log2(length) copies instead of a byte loop.
+// Patterns shorter than a word are first spread over 8 bytes and stored a
+// word at a time, sparing short matches a string of tiny copies.
+func copyOverlap(dst []byte, pos, offset, length int) {
+	src := pos - offset
+	end := pos + length
+
+	if offset < 8 && pos+8 <= end {
+		// The pattern is followed by at least 8-offset bytes, about to be
+		// overwritten, so a word loaded at src holds it
+		w := binary.LittleEndian.Uint64(dst[src:]) & (1<<(8*offset) - 1)
+		for n := offset; n < 8; n *= 2 {
+			w |= w << (8 * n)
+		}
+
+		// Each store starts a whole number of patterns after the last, so
+		// they all write the same word. Past 32 bytes, doubling copies are
+		// as fast.
+		step := patternStep[offset]
+		for pos+8 <= end && pos-src < 32 {
+			binary.LittleEndian.PutUint64(dst[pos:], w)
+			pos += step
+		}
+	}
+
+	// dst[src:pos] always holds a whole number of patterns
+	for pos < end {
+		pos += copy(dst[pos:end], dst[src:pos])
+	}
+}
+
+// patternStep is, for each pattern length below 8, the largest whole number
+// of patterns that fits in 8 bytes
+var patternStep = [8]int{0, 8, 8, 6, 8, 5, 6, 7}
+
+// AppendMatch appends the length bytes found offset bytes back in out,
+// growing it if needed, and returns the extended slice. The match may
+// overlap the bytes it produces, as in LZ4 and other LZ77 formats; offset
+// must be between 1 and len(out).
+func AppendMatch(out []byte, offset, length int) []byte {
+	pos := len(out)
+	out = slices.Grow(out, length)[:pos+length]
+	copyMatch(out, pos, offset, length)
+	return out
+}
@@ -0,0 +1,69 @@
+package block
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// copyMatchBytes is the byte-at-a-time match copy copyMatch replaces
+func copyMatchBytes(dst []byte, pos, offset, length int) {
+	for i := 0; i < length; i++ {
+		dst[pos+i] = dst[pos-offset+i]
+	}
+}
+
+// TestCopyMatch tests overlapping and non-overlapping matches against a
+// byte-at-a-time copy
+func TestCopyMatch(t *testing.T) {
+	prefix := randomData(100)
+
+	for _, offset := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 15, 16, 31, 32, 33, 100} {
+		for _, length := range []int{4, 5, 7, 8, 9, 15, 16, 17, 31, 32, 33, 39, 40, 64, 99, 100, 101, 1000} {
+			want := append(bytes.Clone(prefix), make([]byte, length)...)
+			copyMatchBytes(want, len(prefix), offset, length)
+
+			got := append(bytes.Clone(prefix), make([]byte, length)...)
+			copyMatch(got, len(prefix), offset, length)
+			if !bytes.Equal(got, want) {
+				t.Errorf("copyMatch(offset %d, length %d) differs from a byte copy", offset, length)
+			}
+
+			if got := AppendMatch(bytes.Clone(prefix), offset, length); !bytes.Equal(got, want) {
+				t.Errorf("AppendMatch(offset %d, length %d) differs from a byte copy", offset, length)
+			}
+		}
+	}
+}
+
+// TestDecompressSmallOffsets tests blocks holding a single match at every
+// offset from 1 to 15, across lengths on both sides of the word stores and
+// the switch to doubling copies
+func TestDecompressSmallOffsets(t *testing.T) {
+	prefix := randomData(16)
+	tail := randomData(format.LastLiterals)
+
+	for offset := 1; offset <= 15; offset++ {
+		for length := 4; length <= 80; length++ {
+			want := append(bytes.Clone(prefix), make([]byte, length)...)
+			copyMatchBytes(want, len(prefix), offset, length)
+			want = append(want, tail...)
+
+			// The literals, then the match, then the last literals
+			block := []byte{15<<4 | byte(min(length-4, 15)), byte(len(prefix) - 15)}
+			block = append(block, prefix...)
+			block = append(block, byte(offset), 0)
+			if length-4 >= 15 {
+				block = append(block, byte(length-4-15))
+			}
+			block = append(block, byte(len(tail))<<4)
+			block = append(block, tail...)
+
+			got, err := DecompressAlloc(block, len(want))
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("DecompressAlloc(offset %d, length %d) = %v, %v, want %v", offset, length, got, err, want)
+			}
+		}
+	}
+}
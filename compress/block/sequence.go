@@ -0,0 +1,108 @@
+package block
+
+import (
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// ErrCorruptBlock indicates a block whose sequences can't be parsed
+var ErrCorruptBlock = errors.New("corrupt LZ4 block")
+
+// Sequence describes one LZ4 sequence: a run of literals followed by an
+// optional back-reference. The final sequence of a block has MatchLen 0.
+type Sequence struct {
+	// Literals references the literal bytes inside the compressed block
+	Literals []byte
+	// Offset is the back-reference distance (0 for the final sequence)
+	Offset int
+	// MatchLen is the full match length including the implicit minimum of 4
+	MatchLen int
+	// Pos is the byte offset of the sequence token in the compressed block
+	Pos int
+}
+
+// SequenceReader walks the sequences of a compressed block without decoding it
+type SequenceReader struct {
+	src []byte
+	pos int
+}
+
+// NewSequenceReader creates a SequenceReader over a compressed block
+func NewSequenceReader(block []byte) *SequenceReader {
+	return &SequenceReader{src: block}
+}
+
+// Reset makes sr walk block from its first sequence, so that one
+// SequenceReader can be reused without allocating
+func (sr *SequenceReader) Reset(block []byte) {
+	*sr = SequenceReader{src: block}
+}
+
+// Next returns the next sequence in the block.
+// It returns io.EOF once the whole block has been consumed, and
+// ErrCorruptBlock for a block that ends in a match rather than literals.
+func (sr *SequenceReader) Next() (Sequence, error) {
+	if sr.pos >= len(sr.src) {
+		return Sequence{}, io.EOF
+	}
+
+	seq := Sequence{Pos: sr.pos}
+	token := sr.src[sr.pos]
+	sr.pos++
+
+	// Literal length with optional extension bytes
+	literalLen, ok := sr.readLength(int(token >> 4))
+	if !ok || literalLen > len(sr.src)-sr.pos {
+		return Sequence{}, ErrCorruptBlock
+	}
+	seq.Literals = sr.src[sr.pos : sr.pos+literalLen]
+	sr.pos += literalLen
+
+	// The last sequence has no match part
+	if sr.pos == len(sr.src) {
+		return seq, nil
+	}
+
+	if sr.pos+2 > len(sr.src) {
+		return Sequence{}, ErrCorruptBlock
+	}
+	seq.Offset = int(sr.src[sr.pos]) | int(sr.src[sr.pos+1])<<8
+	sr.pos += 2
+
+	matchLen, ok := sr.readLength(int(token & 0x0F))
+	if !ok {
+		return Sequence{}, ErrCorruptBlock
+	}
+	seq.MatchLen = matchLen + format.MinMatch
+
+	// The last sequence of a block never has a match part
+	if sr.pos == len(sr.src) {
+		return Sequence{}, ErrCorruptBlock
+	}
+
+	return seq, nil
+}
+
+// readLength decodes the extension bytes of a 4-bit length field
+func (sr *SequenceReader) readLength(n int) (int, bool) {
+	if n != 15 {
+		return n, true
+	}
+
+	for {
+		if sr.pos >= len(sr.src) {
+			return 0, false
+		}
+		l := int(sr.src[sr.pos])
+		sr.pos++
+		n += l
+		if n > maxLength {
+			return 0, false
+		}
+		if l != 255 {
+			return n, true
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package block
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// The rules of the block format a ValidatingDecoder checks beyond those
+// needed to decode a block. liblz4's decoder and those of most hardware
+// depend on them, so a block breaking one may decode here and fail there.
+var (
+	// ErrMatchOffset indicates a match offset of 0, or one reaching back
+	// before the start of the block and the dictionary before it
+	ErrMatchOffset = errors.New("match offset out of range")
+	// ErrMatchNearEnd indicates a match starting within the last 12 bytes
+	// of the block
+	ErrMatchNearEnd = errors.New("match starts within the last 12 bytes")
+	// ErrLastLiterals indicates a match ending within the last 5 bytes of
+	// the block, which must be literals
+	ErrLastLiterals = errors.New("match ends within the last 5 bytes")
+	// ErrNonCanonical indicates a length field encoded otherwise than an
+	// encoder would: every length has one encoding, except that the match
+	// length of the final token is unused and must be 0
+	ErrNonCanonical = errors.New("non-canonical length encoding")
+)
+
+// SequenceError reports the first sequence of a block that breaks a rule
+type SequenceError struct {
+	// Index is the index of the sequence in the block, from 0
+	Index int
+	// Pos is the byte offset in the compressed block of the field at fault:
+	// the match offset for ErrMatchOffset, else the token of the sequence
+	Pos int
+	// RawPos is the offset of the sequence's literals in the decompressed
+	// block
+	RawPos int
+	// Err is the rule broken: one of the errors above, ErrCorruptBlock for
+	// a sequence that can't be parsed, or the error of a block too large
+	Err error
+}
+
+func (e *SequenceError) Error() string {
+	return fmt.Sprintf("sequence %d at byte %d: %v", e.Index, e.Pos, e.Err)
+}
+
+func (e *SequenceError) Unwrap() error {
+	return e.Err
+}
+
+// ValidatingDecoder decodes blocks while checking every rule of the block
+// format, including those the other decoders don't need and let pass, and
+// reports the first sequence breaking one as a SequenceError. It is slower
+// than DecompressInto and meant for testing encoders: a new match finder
+// or parser fails at the sequence it got wrong rather than in another
+// decoder later.
+type ValidatingDecoder struct {
+	// Dict is the data before the block that its matches may reach into,
+	// as for DecompressAppend; only its last 65535 bytes are in reach
+	Dict []byte
+	// MaxSize bounds the decompressed size. Zero or less means
+	// MaxBlockSize.
+	MaxSize int
+}
+
+// Decode decompresses src into a new buffer, failing with a SequenceError
+// at the first sequence that breaks a rule
+func (d *ValidatingDecoder) Decode(src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errEmptySource
+	}
+	maxSize := d.MaxSize
+	if maxSize <= 0 || maxSize > MaxBlockSize {
+		maxSize = MaxBlockSize
+	}
+
+	// The rules at the end of the block need its size. A block that can't
+	// be parsed to the end fails at the sequence that can't be parsed, and
+	// until then only the other rules are checked.
+	size, err := DecodedLen(src, maxSize)
+	if err != nil {
+		size = -1
+	}
+
+	dict := d.Dict[max(len(d.Dict)-format.MaxOffset, 0):]
+	out := append([]byte(nil), dict...)
+	sr := SequenceReader{src: src}
+	for i := 0; ; i++ {
+		rawPos := len(out) - len(dict)
+		fail := func(pos int, err error) error {
+			return &SequenceError{Index: i, Pos: pos, RawPos: rawPos, Err: err}
+		}
+
+		token := sr.pos
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return out[len(dict):], nil
+		}
+		if err != nil {
+			return nil, fail(token, err)
+		}
+
+		room := maxSize - rawPos
+		if len(seq.Literals) > room || seq.MatchLen > room-len(seq.Literals) {
+			return nil, fail(token, errTooLarge)
+		}
+		out = append(out, seq.Literals...)
+
+		if seq.MatchLen == 0 {
+			if format.Token(src[token]).Match() != 0 {
+				return nil, fail(token, ErrNonCanonical)
+			}
+			continue
+		}
+
+		start := rawPos + len(seq.Literals)
+		if seq.Offset == 0 || seq.Offset > len(out) {
+			offsetPos := token + 1 + format.ExtensionSize(len(seq.Literals)) + len(seq.Literals)
+			return nil, fail(offsetPos, ErrMatchOffset)
+		}
+		if size >= 0 && start > size-format.MFLimit {
+			return nil, fail(token, ErrMatchNearEnd)
+		}
+		if size >= 0 && start+seq.MatchLen > size-format.LastLiterals {
+			return nil, fail(token, ErrLastLiterals)
+		}
+		out = AppendMatch(out, seq.Offset, seq.MatchLen)
+	}
+}
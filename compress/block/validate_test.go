@@ -0,0 +1,57 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestValidatingDecoder tests hand-encoded blocks that break each rule,
+// and the sequence and byte reported for them
+func TestValidatingDecoder(t *testing.T) {
+	if got, err := (&ValidatingDecoder{}).Decode(testBlock); err != nil || !bytes.Equal(got, testDecoded) {
+		t.Fatalf("Decode() = %q, %v, want %q", got, err, testDecoded)
+	}
+
+	tests := []struct {
+		name      string
+		block     []byte
+		dict      []byte
+		wantErr   error
+		wantIndex int
+		wantPos   int
+	}{
+		// The match at offset 5 reaches one byte before the block
+		{"OffsetBeforeStart", []byte{0x44, 'a', 'b', 'c', 'd', 5, 0, 0x50, 'v', 'w', 'x', 'y', 'z'}, nil, ErrMatchOffset, 0, 5},
+		{"OffsetZero", []byte{0x44, 'a', 'b', 'c', 'd', 0, 0, 0x50, 'v', 'w', 'x', 'y', 'z'}, nil, ErrMatchOffset, 0, 5},
+		// The same match is in reach of a dictionary
+		{"OffsetInDict", []byte{0x44, 'a', 'b', 'c', 'd', 5, 0, 0x50, 'v', 'w', 'x', 'y', 'z'}, []byte("xy"), nil, 0, 0},
+		// 4 literals and a match of 4 leave 4 literals: 12 bytes in all,
+		// so the match starts 8 bytes before the end
+		{"MatchNearEnd", []byte{0x40, 'a', 'b', 'c', 'd', 4, 0, 0x40, 'v', 'w', 'x', 'y'}, nil, ErrMatchNearEnd, 0, 0},
+		// The match starts 12 bytes before the end of 16 bytes, but ends 4
+		// bytes before it
+		{"LastLiterals", []byte{0x44, 'a', 'b', 'c', 'd', 4, 0, 0x40, 'w', 'x', 'y', 'z'}, nil, ErrLastLiterals, 0, 0},
+		{"FinalMatchLength", []byte{0x44, 'a', 'b', 'c', 'd', 4, 0, 0x53, 'v', 'w', 'x', 'y', 'z'}, nil, ErrNonCanonical, 1, 7},
+		{"Truncated", testBlock[:6], nil, ErrCorruptBlock, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := ValidatingDecoder{Dict: tt.dict}
+			_, err := d.Decode(tt.block)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Decode() error = %v", err)
+				}
+				return
+			}
+			var se *SequenceError
+			if !errors.As(err, &se) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want a SequenceError of %v", err, tt.wantErr)
+			}
+			if se.Index != tt.wantIndex || se.Pos != tt.wantPos {
+				t.Errorf("sequence %d at byte %d, want %d at %d", se.Index, se.Pos, tt.wantIndex, tt.wantPos)
+			}
+		})
+	}
+}
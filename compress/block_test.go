@@ -2,15 +2,20 @@ package compress
 
 import (
 	"bytes"
-	"crypto/rand"
+	"errors"
+	"io"
 	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
+// seeds numbers the data of the generators below, so that every call
+// returns new data and runs are reproducible
+var seeds testgen.Seq
+
 // Helper functions for generating test data
 func generateRandomData(size int) []byte {
-	data := make([]byte, size)
-	rand.Read(data)
-	return data
+	return testgen.Random(seeds.Next(), size)
 }
 
 func generateCompressibleData(size int) []byte {
@@ -293,6 +298,204 @@ func TestDecompressBlock(t *testing.T) {
 	})
 }
 
+// TestDecompressBlockUnknownSize tests decompressing blocks without knowing
+// their size, which takes a single allocation of the exact size
+func TestDecompressBlockUnknownSize(t *testing.T) {
+	for _, size := range []int{20, 64*1024 + 1, 1 << 20, MaxBlockSize} {
+		data := generateCompressibleData(size)
+		block, err := CompressBlock(data, nil)
+		if err != nil {
+			t.Fatalf("CompressBlock() error = %v", err)
+		}
+
+		got, err := DecompressBlock(block, nil, 0)
+		if err != nil {
+			t.Fatalf("DecompressBlock(%d bytes) error = %v", size, err)
+		}
+		if !bytes.Equal(got, data) || cap(got) != size {
+			t.Errorf("DecompressBlock() = %d bytes with capacity %d, want %d", len(got), cap(got), size)
+		}
+
+		allocs := testing.AllocsPerRun(5, func() {
+			DecompressBlock(block, nil, 0)
+		})
+		if allocs != 1 {
+			t.Errorf("DecompressBlock(%d bytes) allocations = %v, want 1", size, allocs)
+		}
+	}
+
+	// Blocks decoding to more than MaxBlockSize are rejected by the scan
+	huge := append([]byte{0x1F, 'a', 1, 0}, bytes.Repeat([]byte{255}, MaxBlockSize/255+1)...)
+	huge = append(huge, 0, 0x10, 'a')
+	if _, err := DecompressBlock(huge, nil, 0); err == nil {
+		t.Errorf("DecompressBlock() of an oversized block succeeded")
+	}
+}
+
+// TestDecompressBlockLiteralRun tests blocks holding only literals, which
+// are copied without the sequence loop
+func TestDecompressBlockLiteralRun(t *testing.T) {
+	for _, size := range []int{1, 14, 15, 16, 269, 270, 300 * 1024} {
+		data := generateRandomData(size)
+		block := storeBlock(data, make([]byte, compressBound(size)))
+		if _, ok := literalRun(block); !ok {
+			t.Fatalf("literalRun(%d bytes) = false", size)
+		}
+
+		for _, maxSize := range []int{0, size, size + 10} {
+			got, err := DecompressBlock(block, nil, maxSize)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("DecompressBlock(%d bytes, maxSize %d) = %d bytes, %v", size, maxSize, len(got), err)
+			}
+		}
+		if _, err := DecompressBlock(block, nil, size-1); err == nil && size > 1 {
+			t.Errorf("DecompressBlock(%d bytes) with maxSize %d succeeded", size, size-1)
+		}
+
+		dst := make([]byte, size)
+		allocs := testing.AllocsPerRun(5, func() {
+			DecompressBlock(block, dst, size)
+		})
+		if allocs != 0 {
+			t.Errorf("DecompressBlock(%d bytes) allocations = %v, want 0", size, allocs)
+		}
+	}
+
+	// Blocks with matches, or a literal run of the wrong length, aren't taken
+	// for a single run
+	compressed, err := CompressBlock(generateCompressibleData(10000), nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+	for _, block := range [][]byte{compressed, {0x50, 'a', 'b', 'c', 'd'}, {0xF0, 2, 'a'}, {0xF0, 255}} {
+		if _, ok := literalRun(block); ok {
+			t.Errorf("literalRun(% x) = true", block[:min(len(block), 8)])
+		}
+	}
+	if _, err := DecompressBlock([]byte{0x50, 'a', 'b', 'c', 'd'}, nil, 10); err == nil {
+		t.Errorf("DecompressBlock() of a truncated literal run succeeded")
+	}
+}
+
+// TestDecompressBlockInto tests decompressing into a caller's buffer, which
+// never allocates and fails with io.ErrShortBuffer when the output doesn't fit
+func TestDecompressBlockInto(t *testing.T) {
+	compressible := generateCompressibleData(100 * 1024)
+	compressed, err := CompressBlock(compressible, nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+	random := generateRandomData(1000)
+	stored := storeBlock(random, nil)
+
+	tests := []struct {
+		name  string
+		block []byte
+		want  []byte
+	}{
+		{"Sequences", compressed, compressible},
+		{"Literal run", stored, random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, size := range []int{len(tt.want), len(tt.want) + 100} {
+				dst := make([]byte, size)
+				n, err := DecompressBlockInto(tt.block, dst)
+				if err != nil || !bytes.Equal(dst[:n], tt.want) {
+					t.Errorf("DecompressBlockInto(%d byte dst) = %d, %v, want %d", size, n, err, len(tt.want))
+				}
+			}
+
+			short := make([]byte, len(tt.want)-1)
+			if _, err := DecompressBlockInto(tt.block, short); !errors.Is(err, io.ErrShortBuffer) {
+				t.Errorf("DecompressBlockInto(%d byte dst) error = %v, want %v", len(short), err, io.ErrShortBuffer)
+			}
+
+			dst := make([]byte, len(tt.want))
+			allocs := testing.AllocsPerRun(5, func() {
+				DecompressBlockInto(tt.block, dst)
+			})
+			if allocs != 0 {
+				t.Errorf("DecompressBlockInto() allocations = %v, want 0", allocs)
+			}
+		})
+	}
+
+	if _, err := DecompressBlockInto(nil, make([]byte, 10)); err == nil {
+		t.Errorf("DecompressBlockInto() of an empty block succeeded")
+	}
+}
+
+// TestDecompressBlockAlloc tests decompressing into a new buffer, sized by
+// maxSize or by a scan pass when maxSize is zero
+func TestDecompressBlockAlloc(t *testing.T) {
+	compressible := generateCompressibleData(100 * 1024)
+	compressed, err := CompressBlock(compressible, nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+	random := generateRandomData(1000)
+	stored := storeBlock(random, nil)
+
+	tests := []struct {
+		name  string
+		block []byte
+		want  []byte
+	}{
+		{"Sequences", compressed, compressible},
+		{"Literal run", stored, random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, maxSize := range []int{0, len(tt.want), len(tt.want) + 100} {
+				got, err := DecompressBlockAlloc(tt.block, maxSize)
+				if err != nil || !bytes.Equal(got, tt.want) {
+					t.Errorf("DecompressBlockAlloc(maxSize %d) = %d bytes, %v, want %d", maxSize, len(got), err, len(tt.want))
+				}
+			}
+
+			if _, err := DecompressBlockAlloc(tt.block, len(tt.want)-1); !errors.Is(err, io.ErrShortBuffer) {
+				t.Errorf("DecompressBlockAlloc(maxSize %d) error = %v, want %v", len(tt.want)-1, err, io.ErrShortBuffer)
+			}
+
+			allocs := testing.AllocsPerRun(5, func() {
+				DecompressBlockAlloc(tt.block, 0)
+			})
+			if allocs != 1 {
+				t.Errorf("DecompressBlockAlloc() allocations = %v, want 1", allocs)
+			}
+		})
+	}
+}
+
+// TestDecompressBlockDeprecated tests that DecompressBlock keeps its old
+// behavior: a dst of maxSize bytes or more is reused, a shorter one is
+// replaced, and output beyond maxSize is an error
+func TestDecompressBlockDeprecated(t *testing.T) {
+	data := generateCompressibleData(10000)
+	block, err := CompressBlock(data, nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+
+	dst := make([]byte, len(data))
+	got, err := DecompressBlock(block, dst, len(data))
+	if err != nil || !bytes.Equal(got, data) || &got[0] != &dst[0] {
+		t.Errorf("DecompressBlock() with a large dst = %d bytes, %v, want %d in dst", len(got), err, len(data))
+	}
+
+	got, err = DecompressBlock(block, make([]byte, 10), len(data))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("DecompressBlock() with a short dst = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+
+	if _, err := DecompressBlock(block, nil, len(data)-1); err == nil || errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("DecompressBlock() beyond maxSize error = %v, want the maxSize error", err)
+	}
+}
+
 // Test round-trip compression/decompression with various data sizes and patterns
 func TestCompressDecompressRoundTrip(t *testing.T) {
 	// For v0.1, skip the full round-trip tests
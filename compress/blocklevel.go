@@ -0,0 +1,114 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SetLevel changes the compression level of the Writer, for streams that
+// mix content worth different effort, such as small headers and bulk data.
+// It takes effect at the next block boundary: data already buffered is
+// compressed at the new level when its block is flushed. Blocks are
+// independent, so readers need no setting. The level stays across Reset.
+func (z *Writer) SetLevel(level CompressionLevel) error {
+	if level < NoCompression || level > MaxLevel {
+		return ErrInvalidCompressionLevel
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	// A block in the background may be compressing at the old level
+	z.wait()
+	z.level = level
+	return nil
+}
+
+// Level returns the compression level of the Writer
+func (z *Writer) Level() CompressionLevel {
+	z.lock()
+	defer z.unlock()
+	return z.level
+}
+
+// WriteLevel writes p compressed at level, whatever the level of the
+// Writer. Data buffered by earlier Writes is first flushed as a block of
+// its own at the Writer's level, then p is compressed at once in blocks of
+// at most the block size; later Writes go back to the Writer's level.
+// Writers with content-defined chunking reject it, as it would end chunks
+// at write boundaries.
+func (z *Writer) WriteLevel(p []byte, level CompressionLevel) (int, error) {
+	if level < NoCompression || level > MaxLevel {
+		return 0, ErrInvalidCompressionLevel
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	if err := z.startDirect("WriteLevel"); err != nil {
+		return 0, err
+	}
+
+	defer func(saved CompressionLevel) { z.level = saved }(z.level)
+	z.level = level
+
+	var written int
+	for len(p) > 0 {
+		n := min(len(p), z.blockSize)
+		if err := z.compressBlock(p[:n]); err != nil {
+			return written, err
+		}
+		z.accept(p[:n])
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// startDirect prepares for writing blocks straight from the caller's data
+// for the method named: it waits for the block in flight, writes the frame
+// header if needed and flushes buffered data as a block of its own. The
+// caller must hold z.mu.
+func (z *Writer) startDirect(method string) error {
+	if err := z.wait(); err != nil {
+		return err
+	}
+	if z.closed {
+		return errors.New("write to closed stream")
+	}
+	if z.chunker != nil {
+		return fmt.Errorf("%w: %s with chunking", ErrInvalidChunking, method)
+	}
+
+	if !z.wroteHeader {
+		if err := z.writeFrameHeader(); err != nil {
+			return err
+		}
+		z.wroteHeader = true
+	}
+	if z.bufUsed > 0 {
+		if err := z.flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCompressor makes z.compressor compress at z.level. The Compressor of
+// the level it replaces is kept, so streams alternating between two levels
+// don't reallocate match finder tables at every switch.
+func (z *Writer) setCompressor() error {
+	if z.compressor != nil && z.compressor.Level() == z.level {
+		return nil
+	}
+	if z.otherCompressor != nil && z.otherCompressor.Level() == z.level {
+		z.compressor, z.otherCompressor = z.otherCompressor, z.compressor
+		return nil
+	}
+	c, err := NewCompressor(z.level)
+	if err != nil {
+		return err
+	}
+	z.compressor, z.otherCompressor = c, z.compressor
+	return nil
+}
@@ -0,0 +1,153 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// wantBlockSize returns the size of block as a Writer at level stores it
+func wantBlockSize(t *testing.T, block []byte, level CompressionLevel) int {
+	t.Helper()
+	if level == NoCompression {
+		return len(block)
+	}
+	out, err := CompressBlockLevel(block, nil, level)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel(%d) error = %v", level, err)
+	}
+	return min(len(out), len(block))
+}
+
+// TestWriterSetLevel tests that a level set mid-frame applies to the block
+// flushed next, including the data already buffered for it
+func TestWriterSetLevel(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := testgen.Text(testgen.DefaultSeed, 6*blockSize)
+	block := func(i int) []byte { return data[i*blockSize : (i+1)*blockSize] }
+	levels := []CompressionLevel{1, MaxLevel, NoCompression, MaxLevel, 1, 9}
+
+	for _, async := range []bool{false, true} {
+		var buf bytes.Buffer
+		var sizes []int
+		w := NewWriterWithOptions(&buf, WriterOptions{
+			Level:           DefaultLevel,
+			BlockSize:       blockSize,
+			BlockChecksum:   XXH32,
+			ContentChecksum: true,
+			AsyncFlush:      async,
+		})
+		w.SetMetricsRecorder(metricsFunc(func(compressed, raw int, _ time.Duration) { sizes = append(sizes, compressed) }))
+		for i, level := range levels {
+			// Block i stays buffered until the next Write or Close flushes it
+			if _, err := w.Write(block(i)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.SetLevel(level); err != nil {
+				t.Fatalf("SetLevel(%d) error = %v", level, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		got, err := io.ReadAll(NewReader(&buf))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("async %v: ReadAll() = %d bytes, %v, want %d", async, len(got), err, len(data))
+		}
+		var want []int
+		for i, level := range levels {
+			want = append(want, wantBlockSize(t, block(i), level))
+		}
+		if !slices.Equal(sizes, want) {
+			t.Errorf("async %v: block sizes = %v, want %v", async, sizes, want)
+		}
+	}
+
+	w := NewWriter(io.Discard)
+	if err := w.SetLevel(MaxLevel + 1); err != ErrInvalidCompressionLevel {
+		t.Errorf("SetLevel(%d) error = %v, want %v", MaxLevel+1, err, ErrInvalidCompressionLevel)
+	}
+}
+
+// TestWriterWriteLevel tests that WriteLevel compresses only its own data
+// at its level, with a dictionary as well
+func TestWriterWriteLevel(t *testing.T) {
+	const blockSize = 64 * 1024
+	head := testgen.Text(testgen.DefaultSeed, 1000)
+	bulk := testgen.Text(testgen.DefaultSeed+1, 150*1024)
+	tail := testgen.Text(testgen.DefaultSeed+2, 20*1024)
+	data := slices.Concat(head, bulk, tail)
+
+	store := NewMemoryDictionaryStore()
+	store.Publish(testgen.Text(testgen.DefaultSeed+3, 32*1024))
+	for _, dicts := range []DictionaryStore{nil, store} {
+		var buf bytes.Buffer
+		var sizes []int
+		w := NewWriterWithOptions(&buf, WriterOptions{Level: MaxLevel, BlockSize: blockSize, Dictionaries: dicts})
+		w.SetMetricsRecorder(metricsFunc(func(compressed, raw int, _ time.Duration) { sizes = append(sizes, raw) }))
+		if _, err := w.Write(head); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if n, err := w.WriteLevel(bulk, 1); n != len(bulk) || err != nil {
+			t.Fatalf("WriteLevel() = %d, %v", n, err)
+		}
+		if _, err := w.Write(tail); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		got, err := io.ReadAll(NewReaderWithOptions(&buf, ReaderOptions{Dictionaries: dicts}))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("ReadAll() = %d bytes, %v, want %d", len(got), err, len(data))
+		}
+		// head ends a block of its own, and bulk is cut at the block size
+		if want := []int{1000, blockSize, blockSize, 22 * 1024, 20 * 1024}; !slices.Equal(sizes, want) {
+			t.Errorf("block sizes = %v, want %v", sizes, want)
+		}
+		if w.Level() != MaxLevel {
+			t.Errorf("Level() after WriteLevel = %d, want %d", w.Level(), MaxLevel)
+		}
+	}
+
+	w := NewWriter(io.Discard)
+	if _, err := w.WriteLevel(bulk, -1); err != ErrInvalidCompressionLevel {
+		t.Errorf("WriteLevel(-1) error = %v, want %v", err, ErrInvalidCompressionLevel)
+	}
+}
+
+// TestEncoderEncodeLevel tests that messages encoded at different levels
+// are compressed at them and decode with one Decoder
+func TestEncoderEncodeLevel(t *testing.T) {
+	msg := testgen.Text(testgen.DefaultSeed, 32*1024)
+	levels := []CompressionLevel{1, MaxLevel, NoCompression, DefaultLevel}
+
+	var buf bytes.Buffer
+	enc := NewEncoderLevel(&buf, DefaultLevel)
+	for _, level := range levels {
+		before := buf.Len()
+		if err := enc.EncodeLevel(msg, level); err != nil {
+			t.Fatalf("EncodeLevel(%d) error = %v", level, err)
+		}
+		if got, want := buf.Len()-before, messageHeaderSize+wantBlockSize(t, msg, level); got != want {
+			t.Errorf("level %d: message of %d bytes, want %d", level, got, want)
+		}
+	}
+	if err := enc.EncodeLevel(msg, MaxLevel+1); err != ErrInvalidCompressionLevel {
+		t.Errorf("EncodeLevel(%d) error = %v, want %v", MaxLevel+1, err, ErrInvalidCompressionLevel)
+	}
+
+	dec := NewDecoder(&buf)
+	for range levels {
+		got, err := dec.Decode()
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf("Decode() = %d bytes, %v, want %d", len(got), err, len(msg))
+		}
+	}
+}
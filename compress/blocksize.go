@@ -0,0 +1,118 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BlockSizeCode selects the maximum block size of a frame, as stored in the
+// block descriptor of the frame header. The codes and their sizes are fixed
+// by the LZ4 frame format and will not change.
+type BlockSizeCode uint8
+
+// Block size codes defined by the LZ4 frame format
+const (
+	BlockSize64KB  BlockSizeCode = 4
+	BlockSize256KB BlockSizeCode = 5
+	BlockSize1MB   BlockSizeCode = 6
+	BlockSize4MB   BlockSizeCode = 7
+)
+
+var (
+	// ErrInvalidBlockSizeCode indicates a block size code outside 4-7
+	ErrInvalidBlockSizeCode = errors.New("invalid block size code")
+
+	// ErrWriterStarted indicates a setting that can't change once the frame header is written
+	ErrWriterStarted = errors.New("writer already started")
+)
+
+// Size returns the maximum block size in bytes, or 0 for an invalid code
+func (c BlockSizeCode) Size() int {
+	if c < BlockSize64KB || c > BlockSize4MB {
+		return 0
+	}
+	// 64KB for code 4, growing by a factor of 4 up to 4MB for code 7
+	return maxBlockSize >> (2 * (BlockSize4MB - c))
+}
+
+// String returns the maximum block size of the code, such as "64KB"
+func (c BlockSizeCode) String() string {
+	switch size := c.Size(); {
+	case size == 0:
+		return fmt.Sprintf("BlockSizeCode(%d)", uint8(c))
+	case size < 1024*1024:
+		return fmt.Sprintf("%dKB", size/1024)
+	default:
+		return fmt.Sprintf("%dMB", size/(1024*1024))
+	}
+}
+
+// BlockSizeFromCode returns the maximum block size in bytes of a block size
+// code as stored in a frame header, or 0 for an invalid code. It is the same
+// as code.Size().
+func BlockSizeFromCode(code BlockSizeCode) int {
+	return code.Size()
+}
+
+// CodeFromBlockSize returns the smallest block size code whose maximum holds
+// blocks of size bytes. Sizes above 4MB get BlockSize4MB, the largest code.
+func CodeFromBlockSize(size int) BlockSizeCode {
+	code := BlockSize64KB
+	for code < BlockSize4MB && size > code.Size() {
+		code++
+	}
+	return code
+}
+
+// SetBlockSizeCode sets the maximum block size of the frame. It must be called
+// before the first Write; afterwards ErrWriterStarted is returned.
+// Smaller blocks lower latency and memory use at some cost in ratio.
+func (z *Writer) SetBlockSizeCode(code BlockSizeCode) error {
+	z.lock()
+	defer z.unlock()
+
+	if code.Size() == 0 {
+		return ErrInvalidBlockSizeCode
+	}
+	if z.wroteHeader {
+		return ErrWriterStarted
+	}
+
+	z.header.blockSizeCode = uint8(code)
+	z.blockSize = code.Size()
+	z.autoBlockSize = false
+	return nil
+}
+
+// SetSizeHint tells the Writer the expected uncompressed size of the stream.
+// Unless a block size was chosen explicitly, the Writer then uses the smallest
+// block size holding the whole stream, so small streams don't pay for 4MB
+// blocks. The hint is ignored once writing has started.
+func (z *Writer) SetSizeHint(size int64) {
+	z.lock()
+	defer z.unlock()
+	z.applySizeHint(size)
+}
+
+// applySizeHint shrinks an automatically chosen block size to fit size bytes
+func (z *Writer) applySizeHint(size int64) {
+	if !z.autoBlockSize || z.wroteHeader || size < 0 {
+		return
+	}
+
+	code := uint8(BlockSize4MB)
+	if size <= maxBlockSize {
+		code = uint8(CodeFromBlockSize(int(size)))
+	}
+	z.header.blockSizeCode = code
+	z.blockSize = BlockSizeCode(code).Size()
+}
+
+// growBuffer makes sure the block buffer can hold a full block.
+// Buffers are allocated on first use so that short streams with large
+// blocks don't reserve memory they never fill.
+func (z *Writer) growBuffer() {
+	if len(z.buf) < z.blockSize {
+		z.buf = make([]byte, z.blockSize)
+	}
+}
@@ -0,0 +1,194 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestBlockSizeCodeSize tests the block sizes of each code
+func TestBlockSizeCodeSize(t *testing.T) {
+	tests := []struct {
+		code BlockSizeCode
+		want int
+	}{
+		{0, 0},
+		{3, 0},
+		{BlockSize64KB, 64 * 1024},
+		{BlockSize256KB, 256 * 1024},
+		{BlockSize1MB, 1024 * 1024},
+		{BlockSize4MB, 4 * 1024 * 1024},
+		{8, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.code.Size(); got != tt.want {
+			t.Errorf("BlockSizeCode(%d).Size() = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+// TestBlockSizeCodeHelpers tests converting between block sizes and codes
+func TestBlockSizeCodeHelpers(t *testing.T) {
+	tests := []struct {
+		size int
+		want BlockSizeCode
+	}{
+		{-1, BlockSize64KB},
+		{0, BlockSize64KB},
+		{64 * 1024, BlockSize64KB},
+		{64*1024 + 1, BlockSize256KB},
+		{256 * 1024, BlockSize256KB},
+		{1024 * 1024, BlockSize1MB},
+		{1024*1024 + 1, BlockSize4MB},
+		{4 * 1024 * 1024, BlockSize4MB},
+		{8 * 1024 * 1024, BlockSize4MB},
+	}
+	for _, tt := range tests {
+		if got := CodeFromBlockSize(tt.size); got != tt.want {
+			t.Errorf("CodeFromBlockSize(%d) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+
+	for code := BlockSizeCode(0); code <= 8; code++ {
+		if got, want := BlockSizeFromCode(code), code.Size(); got != want {
+			t.Errorf("BlockSizeFromCode(%d) = %d, want %d", code, got, want)
+		}
+		if size := code.Size(); size > 0 && CodeFromBlockSize(size) != code {
+			t.Errorf("CodeFromBlockSize(%d) = %d, want %d", size, CodeFromBlockSize(size), code)
+		}
+	}
+
+	names := map[BlockSizeCode]string{
+		BlockSize64KB:  "64KB",
+		BlockSize256KB: "256KB",
+		BlockSize1MB:   "1MB",
+		BlockSize4MB:   "4MB",
+		3:              "BlockSizeCode(3)",
+	}
+	for code, want := range names {
+		if got := code.String(); got != want {
+			t.Errorf("BlockSizeCode(%d).String() = %q, want %q", code, got, want)
+		}
+	}
+}
+
+// TestWriterBlockSizeCode tests the block size advertised and used by writers
+func TestWriterBlockSizeCode(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	tests := []struct {
+		name      string
+		newWriter func(w io.Writer) *Writer
+		wantCode  uint8
+		wantBlock int
+	}{
+		{
+			"Default",
+			func(w io.Writer) *Writer { return NewWriter(w) },
+			7, 4 * 1024 * 1024,
+		},
+		{
+			"SetBlockSizeCode",
+			func(w io.Writer) *Writer {
+				z := NewWriterLevel(w, FastLevel)
+				if err := z.SetBlockSizeCode(BlockSize64KB); err != nil {
+					t.Fatalf("SetBlockSizeCode() error = %v", err)
+				}
+				return z
+			},
+			4, 64 * 1024,
+		},
+		{
+			"SetSizeHint",
+			func(w io.Writer) *Writer {
+				z := NewWriterLevel(w, FastLevel)
+				z.SetSizeHint(200 * 1024)
+				return z
+			},
+			5, 256 * 1024,
+		},
+		{
+			"Explicit code ignores hint",
+			func(w io.Writer) *Writer {
+				z := NewWriter(w)
+				if err := z.SetBlockSizeCode(BlockSize1MB); err != nil {
+					t.Fatalf("SetBlockSizeCode() error = %v", err)
+				}
+				z.SetSizeHint(1000)
+				return z
+			},
+			6, 1024 * 1024,
+		},
+		{
+			"Options code",
+			func(w io.Writer) *Writer {
+				return NewWriterWithOptions(w, WriterOptions{BlockSizeCode: BlockSize256KB})
+			},
+			5, 256 * 1024,
+		},
+		{
+			"Options code with smaller blocks",
+			func(w io.Writer) *Writer {
+				return NewWriterWithOptions(w, WriterOptions{BlockSizeCode: BlockSize1MB, BlockSize: 100 * 1024})
+			},
+			6, 100 * 1024,
+		},
+		{
+			"Options size hint",
+			func(w io.Writer) *Writer {
+				return NewWriterWithOptions(w, WriterOptions{SizeHint: 1000})
+			},
+			4, 64 * 1024,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tt.newWriter(&buf)
+			if w.blockSize != tt.wantBlock {
+				t.Errorf("blockSize = %d, want %d", w.blockSize, tt.wantBlock)
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r := NewReader(bytes.NewReader(buf.Bytes()))
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+			}
+			if r.header.blockSizeCode != tt.wantCode {
+				t.Errorf("blockSizeCode = %d, want %d", r.header.blockSizeCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestSetBlockSizeCodeErrors tests rejected block size changes
+func TestSetBlockSizeCodeErrors(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.SetBlockSizeCode(3); err != ErrInvalidBlockSizeCode {
+		t.Errorf("SetBlockSizeCode(3) error = %v, want %v", err, ErrInvalidBlockSizeCode)
+	}
+
+	if _, err := w.Write([]byte("started")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.SetBlockSizeCode(BlockSize64KB); err != ErrWriterStarted {
+		t.Errorf("SetBlockSizeCode() after Write error = %v, want %v", err, ErrWriterStarted)
+	}
+
+	// The size hint is ignored once the header is written
+	w.SetSizeHint(10)
+	if w.blockSize != 4*1024*1024 {
+		t.Errorf("blockSize = %d after late SetSizeHint, want %d", w.blockSize, 4*1024*1024)
+	}
+}
@@ -0,0 +1,74 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDecompressionBomb indicates a frame that went past the
+// DecompressionLimits of the Reader decoding it
+var ErrDecompressionBomb = errors.New("decompression limit exceeded")
+
+// DecompressionLimits bounds what each frame a Reader decodes may expand
+// to, so that services decoding untrusted streams, such as request bodies,
+// can't be made to spend unbounded memory or time on a small input. A frame
+// past a limit fails with ErrDecompressionBomb before any data of the block
+// that crossed it is returned. Zero fields impose no limit.
+type DecompressionLimits struct {
+	// MaxRatio is the largest ratio of the bytes a frame decompresses to
+	// over the compressed bytes read for it so far, header included. LZ4
+	// reaches about 255 at most; most data stays well under 10. The
+	// sparse holes of a stream count together as one more frame.
+	MaxRatio float64
+	// MaxBlocks is the most blocks a frame may hold, empty ones included
+	MaxBlocks int
+	// MaxFrameSize is the most bytes a frame may decompress to
+	MaxFrameSize uint64
+}
+
+// exceeded returns an error if a frame that read in compressed bytes
+// and decompressed to out bytes has gone past l
+func (l *DecompressionLimits) exceeded(in, out uint64) error {
+	if l.MaxFrameSize > 0 && out > l.MaxFrameSize {
+		return fmt.Errorf("%w: frame decompresses to more than %d bytes", ErrDecompressionBomb, l.MaxFrameSize)
+	}
+	if l.MaxRatio > 0 && float64(out) > l.MaxRatio*float64(in) {
+		return fmt.Errorf("%w: frame expands more than %gx", ErrDecompressionBomb, l.MaxRatio)
+	}
+	return nil
+}
+
+// startFrameLimits starts counting a frame against the limits; the magic
+// number of the frame has just been read
+func (r *Reader) startFrameLimits() {
+	r.frameStart = r.consumed.Load() - 4
+	r.frameOut = 0
+	r.frameBlocks = 0
+}
+
+// limitBlock counts a block of the current frame with the given size word
+// against the limits, and its data if it is stored
+func (r *Reader) limitBlock(word uint32) error {
+	if r.limits == (DecompressionLimits{}) {
+		return nil
+	}
+	r.frameBlocks++
+	if r.limits.MaxBlocks > 0 && r.frameBlocks > r.limits.MaxBlocks {
+		return fmt.Errorf("%w: frame holds more than %d blocks", ErrDecompressionBomb, r.limits.MaxBlocks)
+	}
+	if word&0x80000000 != 0 {
+		size := int(word & 0x7FFFFFFF)
+		return r.limitOutput(size, size)
+	}
+	return nil
+}
+
+// limitOutput counts n more decompressed bytes of the current frame against
+// the limits, with pending compressed bytes of it still to be read
+func (r *Reader) limitOutput(n, pending int) error {
+	if r.limits == (DecompressionLimits{}) {
+		return nil
+	}
+	r.frameOut += uint64(n)
+	return r.limits.exceeded(r.consumed.Load()-r.frameStart+uint64(pending), r.frameOut)
+}
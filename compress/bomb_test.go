@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// readLimited decompresses stream with limits
+func readLimited(stream []byte, limits DecompressionLimits) ([]byte, error) {
+	return io.ReadAll(NewReaderWithOptions(bytes.NewReader(stream), ReaderOptions{Limits: limits, SparseHoles: true}))
+}
+
+// TestDecompressionLimits tests that frames past each limit fail with
+// ErrDecompressionBomb and that frames within them read in full
+func TestDecompressionLimits(t *testing.T) {
+	// A short pattern over and over expands over 200 times
+	repeated, err := EncodeFrame(testgen.Pattern(testgen.DefaultSeed, 16<<20, 64, 0), WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := testgen.Text(testgen.DefaultSeed, 1<<20)
+	small, err := EncodeFrame(text, WriterOptions{BlockSize: 64 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := EncodeFrame(text, WriterOptions{Store: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		stream []byte
+		limits DecompressionLimits
+		bomb   bool
+	}{
+		{"NoLimits", repeated, DecompressionLimits{}, false},
+		{"Ratio", repeated, DecompressionLimits{MaxRatio: 50}, true},
+		{"RatioText", small, DecompressionLimits{MaxRatio: 10}, false},
+		{"FrameSize", small, DecompressionLimits{MaxFrameSize: 1<<20 - 1}, true},
+		{"FrameSizeExact", small, DecompressionLimits{MaxFrameSize: 1 << 20}, false},
+		{"FrameSizePerFrame", append(bytes.Clone(small), small...), DecompressionLimits{MaxFrameSize: 1 << 20}, false},
+		{"FrameSizeStored", stored, DecompressionLimits{MaxFrameSize: 1 << 19}, true},
+		{"Blocks", small, DecompressionLimits{MaxBlocks: 15}, true},
+		{"BlocksExact", small, DecompressionLimits{MaxBlocks: 16}, false},
+		{"Hole", append(holeFrame(t, 1<<30), small...), DecompressionLimits{MaxRatio: 1000}, true},
+		{"HoleSize", append(holeFrame(t, 1<<30), small...), DecompressionLimits{MaxFrameSize: 2 << 20}, true},
+		{"HolesTogether", bytes.Join([][]byte{holeFrame(t, 3<<19), holeFrame(t, 3<<19), small}, nil), DecompressionLimits{MaxFrameSize: 2 << 20}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLimited(tt.stream, tt.limits)
+			if tt.bomb {
+				if !errors.Is(err, ErrDecompressionBomb) {
+					t.Errorf("Read() error = %v, want %v", err, ErrDecompressionBomb)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Read() error = %v", err)
+			}
+			if len(got)%(1<<20) != 0 || len(got) == 0 {
+				t.Errorf("Read() = %d bytes", len(got))
+			}
+		})
+	}
+}
+
+// TestDecompressionLimitsStop tests that the data of the block crossing a
+// limit isn't returned, and that Skip is held to the limits as well
+func TestDecompressionLimitsStop(t *testing.T) {
+	data := testgen.JSON(testgen.DefaultSeed, 1<<20)
+	frame, err := EncodeFrame(data, WriterOptions{BlockSize: 64 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := DecompressionLimits{MaxFrameSize: 100 << 10}
+
+	got, err := readLimited(frame, limits)
+	if !errors.Is(err, ErrDecompressionBomb) || len(got) != 64<<10 || !bytes.Equal(got, data[:len(got)]) {
+		t.Errorf("Read() = %d bytes, %v, want %d bytes and %v", len(got), err, 64<<10, ErrDecompressionBomb)
+	}
+
+	zr := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{Limits: limits})
+	if n, err := zr.Skip(int64(len(data))); !errors.Is(err, ErrDecompressionBomb) || n != 64<<10 {
+		t.Errorf("Skip() = %d, %v, want %d, %v", n, err, 64<<10, ErrDecompressionBomb)
+	}
+}
@@ -0,0 +1,21 @@
+package compress
+
+import "math"
+
+// compressBound returns the worst-case size of a compressed block holding n
+// bytes. It saturates at math.MaxInt instead of overflowing, so that an
+// allocation of the bound fails cleanly rather than with a negative size.
+//
+// The bound holds however the matches fragment the block. A sequence ending
+// in a match takes a token, its literals, their extension bytes and a
+// 2-byte offset, and covers at least 4 bytes more than its literals, so it
+// outgrows its input only by the extension bytes past the first, one per
+// 255 literals. The last run of literals adds its token and extension
+// bytes. That comes to at most n/255 + 2 bytes over n; the rest of the 16
+// is slack that liblz4's LZ4_COMPRESSBOUND also has.
+func compressBound(n int) int {
+	if n > math.MaxInt-16-n/255 {
+		return math.MaxInt
+	}
+	return n + n/255 + 16
+}
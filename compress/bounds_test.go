@@ -0,0 +1,155 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// TestCompressBound tests the worst-case size near the limits of int
+func TestCompressBound(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 16},
+		{255, 272},
+		{MaxBlockSize, MaxBlockSize + MaxBlockSize/255 + 16},
+		{math.MaxInt, math.MaxInt},
+		{math.MaxInt - 16, math.MaxInt},
+	}
+	n := math.MaxInt32
+	if strconv.IntSize == 64 {
+		// 2GB-adjacent sizes only overflow on 32-bit platforms
+		tests = append(tests, struct{ n, want int }{n, n + n/255 + 16})
+	} else {
+		tests = append(tests, struct{ n, want int }{math.MaxInt32 - 100, math.MaxInt32})
+	}
+
+	for _, tt := range tests {
+		if got := compressBound(tt.n); got != tt.want {
+			t.Errorf("compressBound(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+// fragmentedData returns size bytes of random literal runs just under, at
+// and just over the lengths that take another extension byte, each followed
+// by the same 4 bytes, so that compressing it emits as many extension
+// bytes and as short matches as the format allows
+func fragmentedData(seed int64, size int) []byte {
+	rng := testgen.Rand(seed)
+	runs := []int{0, 14, 15, 16, 269, 270, 271, 524, 525}
+	quad := []byte{0xA5, 0x5A, 0xC3, 0x3C}
+	data := make([]byte, 0, size+600)
+	for len(data) < size {
+		data = append(data, testgen.Random(rng.Int63(), runs[rng.Intn(len(runs))])...)
+		data = append(data, quad...)
+	}
+	return data[:size]
+}
+
+// TestCompressBoundFragmented tests that every block encoder stays within
+// compressBound on inputs that fragment into short matches between literal
+// runs, writing into a buffer of exactly the bound without reallocating it
+func TestCompressBoundFragmented(t *testing.T) {
+	encoders := []struct {
+		name     string
+		compress func(src, dst []byte) ([]byte, error)
+	}{
+		{"V2", func(src, dst []byte) ([]byte, error) { return CompressBlockV2Level(src, dst, DefaultLevel) }},
+		{"Stable", func(src, dst []byte) ([]byte, error) { return CompressBlockStable(src, dst, DefaultLevel) }},
+	}
+	for _, level := range []CompressionLevel{NoCompression, 1, 2, 3, DefaultLevel, MaxLevel} {
+		encoders = append(encoders, struct {
+			name     string
+			compress func(src, dst []byte) ([]byte, error)
+		}{level.String(), func(src, dst []byte) ([]byte, error) { return CompressBlockLevel(src, dst, level) }})
+	}
+
+	for _, size := range []int{MinBlockSize, 300, 4096, 64 << 10, 1 << 20} {
+		sources := map[string][]byte{
+			"Fragmented":  fragmentedData(testgen.DefaultSeed, size),
+			"Adversarial": testgen.Adversarial(testgen.DefaultSeed, size),
+			"Random":      testgen.Random(testgen.DefaultSeed, size),
+		}
+		for kind, src := range sources {
+			for _, enc := range encoders {
+				dst := make([]byte, compressBound(size))
+				out, err := enc.compress(src, dst)
+				if err != nil {
+					t.Fatalf("%s(%s, %d): error = %v", enc.name, kind, size, err)
+				}
+				if len(out) > len(dst) || &out[:1][0] != &dst[0] {
+					t.Errorf("%s(%s, %d): %d bytes outside the %d byte bound", enc.name, kind, size, len(out), len(dst))
+				}
+				got, err := DecompressBlock(out, nil, size)
+				if err != nil || !bytes.Equal(got, src) {
+					t.Fatalf("%s(%s, %d): DecompressBlock() = %d bytes, %v", enc.name, kind, size, len(got), err)
+				}
+			}
+		}
+	}
+}
+
+// overlongBlock returns a block whose literal or match length runs past
+// the decoders' cap of math.MaxInt32-255, which would overflow an int on
+// 32-bit platforms
+func overlongBlock(match bool) []byte {
+	block := []byte{0xF0}
+	if match {
+		block = []byte{0x0F, 1, 0}
+	}
+	block = append(block, bytes.Repeat([]byte{255}, (math.MaxInt32-255)/255+2)...)
+	return append(block, 0, 0x10, 'a')
+}
+
+// TestDecodeLengthOverflow tests that blocks with overlong lengths are
+// rejected by every decoder rather than overflowing
+func TestDecodeLengthOverflow(t *testing.T) {
+	for _, match := range []bool{false, true} {
+		block := overlongBlock(match)
+
+		if _, err := DecompressBlock(block, nil, 1<<20); err == nil {
+			t.Errorf("DecompressBlock() succeeded, match = %v", match)
+		}
+		if _, err := DecompressBlock(block, nil, 0); err == nil {
+			t.Errorf("DecompressBlock() of unknown size succeeded, match = %v", match)
+		}
+		if _, err := DecompressBlockDict(block, []byte("dictionary"), 1<<20); err == nil {
+			t.Errorf("DecompressBlockDict() succeeded, match = %v", match)
+		}
+
+		sr := NewSequenceReader(block)
+		var err error
+		for err == nil {
+			_, err = sr.Next()
+		}
+		if err != ErrCorruptBlock {
+			t.Errorf("SequenceReader.Next() error = %v, want %v, match = %v", err, ErrCorruptBlock, match)
+		}
+	}
+}
+
+// TestSkipHugeHole tests skipping into a hole of the largest size an int64
+// can count
+func TestSkipHugeHole(t *testing.T) {
+	var stream bytes.Buffer
+	if err := writeSparseHole(&stream, math.MaxInt64); err != nil {
+		t.Fatalf("writeSparseHole() error = %v", err)
+	}
+	stream.Write(compressFrame(t, []byte("tail"), nil))
+
+	r := NewReaderWithOptions(&stream, ReaderOptions{SparseHoles: true})
+	if n, err := r.Skip(1000); n != 1000 || err != nil {
+		t.Fatalf("Skip() = %d, %v, want 1000, nil", n, err)
+	}
+	p := make([]byte, 10)
+	if _, err := io.ReadFull(r, p); err != nil || !bytes.Equal(p, make([]byte, 10)) {
+		t.Errorf("ReadFull() = %v, %v, want zeros", p, err)
+	}
+}
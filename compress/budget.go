@@ -0,0 +1,98 @@
+package compress
+
+import "time"
+
+const (
+	// budgetCheckInterval is the number of input bytes the encoder gets
+	// through between looks at the clock
+	budgetCheckInterval = 4096
+	// degradedSkipTrigger is the skipTrigger of a degraded search, which
+	// starts skipping after 16 misses
+	degradedSkipTrigger = 4
+)
+
+// BudgetStats reports how the compression of a block kept to its
+// BlockOptions.TimeBudget
+type BudgetStats struct {
+	// Elapsed is the time spent compressing the block
+	Elapsed time.Duration
+	// Degraded is set if the search was cut down to a single candidate per
+	// position, with quicker skipping, as the budget ran low. DegradedAt is
+	// the input position it happened at.
+	Degraded   bool
+	DegradedAt int
+	// Exhausted is set if the budget ran out, leaving the input from
+	// ExhaustedAt on as literals
+	Exhausted   bool
+	ExhaustedAt int
+}
+
+// timeBudget tracks the encoder's progress against a time budget
+type timeBudget struct {
+	start  time.Time
+	budget time.Duration
+	// next is the input position of the next look at the clock
+	next  int
+	stats BudgetStats
+}
+
+// newTimeBudget starts the clock on a budget
+func newTimeBudget(budget time.Duration) *timeBudget {
+	return &timeBudget{start: time.Now(), budget: budget}
+}
+
+// check looks at the clock once every budgetCheckInterval bytes of input.
+// Once three quarters of the budget are spent it degrades the search of hc,
+// and it reports whether the budget has run out.
+func (b *timeBudget) check(pos int, hc *HCMatcher) bool {
+	if pos < b.next {
+		return false
+	}
+	b.next = pos + budgetCheckInterval
+
+	elapsed := time.Since(b.start)
+	if elapsed >= b.budget {
+		b.stats.Exhausted = true
+		b.stats.ExhaustedAt = pos
+		return true
+	}
+	if !b.stats.Degraded && elapsed >= b.budget-b.budget/4 {
+		b.stats.Degraded = true
+		b.stats.DegradedAt = pos
+		hc.maxAttempts = 1
+		if hc.skipTrigger > degradedSkipTrigger {
+			hc.skipTrigger = degradedSkipTrigger
+		}
+	}
+	return false
+}
+
+// CompressWithStats compresses the block like CompressToBuffer and also
+// reports how the compression kept to the block's TimeBudget. Without a
+// budget only Elapsed is set.
+func (b *Block[T]) CompressWithStats(dst []byte) ([]byte, BudgetStats, error) {
+	if b.options.TimeBudget <= 0 || b.level == NoCompression {
+		start := time.Now()
+		out, err := compressHC(b.input, 0, dst, b.level)
+		return out, BudgetStats{Elapsed: time.Since(start)}, err
+	}
+
+	matcher := newHCMatcherFor(b.level, len(b.input))
+	matcher.budget = newTimeBudget(b.options.TimeBudget)
+	out, err := compressWith(matcher, b.input, 0, dst)
+	stats := matcher.budget.stats
+	stats.Elapsed = time.Since(matcher.budget.start)
+	return out, stats, err
+}
+
+// CompressBlockBudget compresses src at the given level within a soft time
+// budget, trading ratio for time as the budget runs low, and reports
+// whether it had to. If dst is nil or too small, a new buffer will be
+// allocated.
+func CompressBlockBudget(src, dst []byte, level CompressionLevel, budget time.Duration) ([]byte, BudgetStats, error) {
+	block, err := NewBlockWithOptions(src, level, BlockOptions{TimeBudget: budget})
+	if err != nil {
+		return nil, BudgetStats{}, err
+	}
+	return block.CompressWithStats(dst)
+}
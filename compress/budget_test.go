@@ -0,0 +1,89 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestCompressBlockBudget tests that a generous budget changes nothing and
+// that a spent one leaves the block as literals that still decompress
+func TestCompressBlockBudget(t *testing.T) {
+	src := generateCompressibleData(256 * 1024)
+
+	want, err := CompressBlockLevel(src, nil, MaxLevel)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel() error = %v", err)
+	}
+	got, stats, err := CompressBlockBudget(src, nil, MaxLevel, time.Hour)
+	if err != nil {
+		t.Fatalf("CompressBlockBudget() error = %v", err)
+	}
+	if !bytes.Equal(got, want) || stats.Degraded || stats.Exhausted || stats.Elapsed <= 0 {
+		t.Errorf("CompressBlockBudget() with an hour = %d bytes, %+v, want the %d of CompressBlockLevel", len(got), stats, len(want))
+	}
+
+	got, stats, err = CompressBlockBudget(src, nil, MaxLevel, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("CompressBlockBudget() error = %v", err)
+	}
+	if !stats.Exhausted || stats.ExhaustedAt != 0 {
+		t.Errorf("CompressBlockBudget() with a nanosecond stats = %+v, want exhausted at 0", stats)
+	}
+	if lits, ok := literalRun(got); !ok || !bytes.Equal(lits, src) {
+		t.Errorf("CompressBlockBudget() with a nanosecond = %d bytes, want the input as literals", len(got))
+	}
+
+	if _, _, err := CompressBlockBudget(src[:8], nil, MaxLevel, time.Second); err != ErrInvalidBlockSize {
+		t.Errorf("CompressBlockBudget() of 8 bytes error = %v, want %v", err, ErrInvalidBlockSize)
+	}
+}
+
+// TestTimeBudgetCheck tests that the search is degraded once three quarters
+// of the budget are spent, and that the clock is only read every
+// budgetCheckInterval bytes
+func TestTimeBudgetCheck(t *testing.T) {
+	hc := NewHCMatcher(MaxLevel)
+	b := newTimeBudget(time.Hour)
+
+	if b.check(0, hc) || b.stats.Degraded {
+		t.Fatalf("check() at the start = %+v, want no change", b.stats)
+	}
+
+	// An earlier start makes 80% of the budget spent
+	b.start = time.Now().Add(-48 * time.Minute)
+	if b.check(100, hc) || b.stats.Degraded {
+		t.Errorf("check() before the next interval degraded the search")
+	}
+	if b.check(budgetCheckInterval, hc) || !b.stats.Degraded || b.stats.DegradedAt != budgetCheckInterval {
+		t.Errorf("check() at 80%% of the budget = %+v, want degraded at %d", b.stats, budgetCheckInterval)
+	}
+	if hc.maxAttempts != 1 || hc.skipTrigger != degradedSkipTrigger {
+		t.Errorf("degraded matcher has %d attempts and trigger %d, want 1 and %d", hc.maxAttempts, hc.skipTrigger, degradedSkipTrigger)
+	}
+
+	b.start = time.Now().Add(-2 * time.Hour)
+	if !b.check(2*budgetCheckInterval, hc) || !b.stats.Exhausted || b.stats.DegradedAt != budgetCheckInterval {
+		t.Errorf("check() past the budget = %+v, want exhausted", b.stats)
+	}
+}
+
+// TestBlockTimeBudget tests that Block honours the budget in its options
+func TestBlockTimeBudget(t *testing.T) {
+	src := generateCompressibleData(64 * 1024)
+	block, err := NewBlockWithOptions(src, DefaultLevel, BlockOptions{TimeBudget: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("NewBlockWithOptions() error = %v", err)
+	}
+	out, err := block.CompressToBuffer(nil)
+	if err != nil {
+		t.Fatalf("CompressToBuffer() error = %v", err)
+	}
+	got, err := DecompressBlock(out, nil, len(src))
+	if err != nil || !bytes.Equal(got, src) {
+		t.Errorf("DecompressBlock() = %d bytes, %v, want %d", len(got), err, len(src))
+	}
+	if _, ok := literalRun(out); !ok {
+		t.Errorf("CompressToBuffer() past the budget compressed the block")
+	}
+}
@@ -0,0 +1,82 @@
+package compress
+
+import "errors"
+
+// ErrReaderClosed is returned when reading from a closed Reader
+var ErrReaderClosed = errors.New("reader is closed")
+
+// BufferProvider supplies the block buffers of a Reader, so that embedders
+// with their own pools control its memory. A Reader holds at most two
+// buffers at a time, one for the compressed block and one it decodes to,
+// each up to the frame's block size plus 64KB for frames with a dictionary.
+// Implementations must be safe for concurrent use if shared by Readers used
+// concurrently.
+type BufferProvider interface {
+	// Get returns a buffer with a capacity of at least size bytes. The
+	// Reader owns it until it passes it to Put.
+	Get(size int) []byte
+	// Put takes back a buffer from Get that the Reader no longer uses,
+	// either because it needs a larger one or because it was closed.
+	// Nothing read from the Reader refers to it, as Read copies out.
+	Put(buf []byte)
+}
+
+// growBuffer returns buf, extended to its capacity, if it holds size bytes.
+// Otherwise it returns a buffer of at least size bytes from the Reader's
+// BufferProvider, or a new one without a provider. pooled tracks whether the
+// buffer came from the provider, which only gets back buffers it handed out.
+func (r *Reader) growBuffer(buf []byte, pooled *bool, size int) []byte {
+	if cap(buf) >= size {
+		return buf[:cap(buf)]
+	}
+	if *pooled {
+		r.buffers.Put(buf)
+	}
+	*pooled = false
+	if r.buffers == nil {
+		return make([]byte, size)
+	}
+
+	buf = r.buffers.Get(size)
+	if cap(buf) < size {
+		// A provider that can't hand out the size asked for keeps its buffer
+		r.buffers.Put(buf)
+		return make([]byte, size)
+	}
+	*pooled = true
+	return buf[:cap(buf)]
+}
+
+// Close releases the Reader's block buffers, handing them back to its
+// BufferProvider if it has one. It doesn't close the underlying reader
+// unless the CloseUnderlying option is set. Read and Skip return
+// ErrReaderClosed afterwards. The first Close returns the first checksum
+// mismatch read past in ChecksumDeferred mode, if any, or else the error of
+// closing the underlying reader.
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.out.reset()
+	r.storedLeft = 0
+
+	if r.blockBufPooled {
+		r.buffers.Put(r.blockBuf)
+	}
+	if r.blockOutPooled {
+		r.buffers.Put(r.blockOut)
+	}
+	r.blockBuf, r.blockOut = nil, nil
+	r.blockBufPooled, r.blockOutPooled = false, false
+
+	if r.closer != nil {
+		if err := r.closer.Close(); r.checksumErr == nil {
+			return err
+		}
+	}
+	return r.checksumErr
+}
@@ -0,0 +1,107 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// countingProvider is a BufferProvider that tracks its outstanding buffers
+type countingProvider struct {
+	gets, puts int
+	out        map[*byte]bool
+	short      bool
+}
+
+func (p *countingProvider) Get(size int) []byte {
+	p.gets++
+	if p.short {
+		size /= 2
+	}
+	buf := make([]byte, size)
+	p.out[&buf[:1][0]] = true
+	return buf
+}
+
+func (p *countingProvider) Put(buf []byte) {
+	p.puts++
+	delete(p.out, &buf[:1][0])
+}
+
+// TestReaderBufferProvider tests that a Reader takes its block buffers from
+// its BufferProvider and hands them all back on Close
+func TestReaderBufferProvider(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+	random := generateRandomData(200 * 1024)
+	store := NewMemoryDictionaryStore()
+	store.Publish(dictionaryFrom(generateRecords(200, 0)))
+
+	tests := []struct {
+		name  string
+		frame []byte
+		data  []byte
+		short bool
+		// unbuffered is set for stored blocks, which stream straight into
+		// Read's buffer without a block buffer
+		unbuffered bool
+	}{
+		{"Compressible", compressFrame(t, data, nil), data, false, false},
+		{"Stored", compressFrame(t, random, nil), random, false, true},
+		{"Dictionary", writeDictFrame(t, data, store), data, false, false},
+		{"Short Buffers", compressFrame(t, data, nil), data, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &countingProvider{out: make(map[*byte]bool), short: tt.short}
+			r := NewReaderWithOptions(bytes.NewReader(tt.frame), ReaderOptions{Dictionaries: store, Buffers: p})
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Fatal("ReadAll() data mismatch")
+			}
+			if p.gets == 0 && !tt.unbuffered {
+				t.Error("Get() never called")
+			} else if p.gets != 0 && tt.unbuffered {
+				t.Errorf("Get() called %d times, want 0", p.gets)
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if p.gets != p.puts || len(p.out) != 0 {
+				t.Errorf("Get() calls = %d, Put() calls = %d, outstanding = %d, want balanced", p.gets, p.puts, len(p.out))
+			}
+			if err := r.Close(); err != nil {
+				t.Errorf("second Close() error = %v", err)
+			}
+			if p.gets != p.puts {
+				t.Errorf("second Close() Put() calls = %d, want %d", p.puts, p.gets)
+			}
+		})
+	}
+}
+
+// TestReaderClosed tests that a closed Reader refuses reads and skips
+func TestReaderClosed(t *testing.T) {
+	data := generateCompressibleData(200 * 1024)
+	r := NewReader(bytes.NewReader(compressFrame(t, data, nil)))
+
+	buf := make([]byte, 1000)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if n, err := r.Read(buf); n != 0 || err != ErrReaderClosed {
+		t.Errorf("Read() = %d, %v, want 0, %v", n, err, ErrReaderClosed)
+	}
+	if n, err := r.Skip(10); n != 0 || err != ErrReaderClosed {
+		t.Errorf("Skip() = %d, %v, want 0, %v", n, err, ErrReaderClosed)
+	}
+}
@@ -0,0 +1,335 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/internal/xxh64"
+)
+
+const (
+	// chunkIndexMagic is the skippable frame magic number of the chunk index
+	// written after a frame compressed with content-defined chunking
+	chunkIndexMagic = format.ChunkIndexMagic
+
+	// chunkIndexEntrySize is the size of one chunk index entry: the block
+	// size word as stored, the chunk length and its XXH64 hash
+	chunkIndexEntrySize = 16
+
+	// Default content-defined chunk sizes
+	defaultChunkMin = 16 * 1024
+	defaultChunkAvg = 64 * 1024
+	defaultChunkMax = 256 * 1024
+)
+
+var (
+	// ErrInvalidChunking indicates chunk sizes out of range or not ordered
+	// as MinSize <= AvgSize <= MaxSize
+	ErrInvalidChunking = errors.New("invalid chunking options")
+
+	// ErrNoChunkIndex indicates a frame that isn't followed by a chunk index
+	ErrNoChunkIndex = errors.New("frame has no chunk index")
+)
+
+// ChunkingOptions configures content-defined chunking. Zero sizes take the
+// defaults of 16KB minimum, 64KB average and 256KB maximum.
+type ChunkingOptions struct {
+	// MinSize is the smallest chunk cut before the end of the stream
+	MinSize int
+	// AvgSize is the chunk size the boundaries are tuned for; it is rounded
+	// down to a power of two
+	AvgSize int
+	// MaxSize is the largest chunk. It is further limited by the block size.
+	MaxSize int
+}
+
+// ChunkInfo describes one chunk of a frame written with content-defined
+// chunking. Each chunk is stored as exactly one block.
+type ChunkInfo struct {
+	// Offset is the offset of the chunk in the uncompressed stream
+	Offset uint64
+	// Length is the uncompressed length of the chunk
+	Length int
+	// BlockOffset is the offset of the chunk's block, starting at its size
+	// word, from the start of the stream
+	BlockOffset int64
+	// BlockSize is the size of the block data as stored
+	BlockSize int
+	// Compressed is false for blocks stored uncompressed
+	Compressed bool
+	// Hash is the XXH64 hash of the uncompressed chunk
+	Hash uint64
+}
+
+// gearTable maps bytes to the random values rolled into the FastCDC
+// fingerprint. It is generated from a fixed seed so that chunk boundaries,
+// and with them deduplication, stay stable across releases.
+var gearTable = func() (table [256]uint64) {
+	state := uint64(0x475A3458434443) // "GZ4XCDC"
+	for i := range table {
+		// splitmix64
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunker finds content-defined chunk boundaries with FastCDC
+type chunker struct {
+	minSize, avgSize, maxSize int
+	// maskS is used below the average size and has more bits than maskL,
+	// which is used above it, pulling chunk sizes towards the average
+	maskS, maskL uint64
+}
+
+// newChunker returns a chunker for the given options
+func newChunker(opts ChunkingOptions) (*chunker, error) {
+	if opts.MinSize == 0 {
+		opts.MinSize = defaultChunkMin
+	}
+	if opts.AvgSize == 0 {
+		opts.AvgSize = defaultChunkAvg
+	}
+	if opts.MaxSize == 0 {
+		opts.MaxSize = defaultChunkMax
+	}
+	if opts.MinSize < 64 || opts.MinSize > opts.AvgSize || opts.AvgSize > opts.MaxSize || opts.MaxSize > maxBlockSize {
+		return nil, ErrInvalidChunking
+	}
+
+	// The fingerprint's top bits depend on the most bytes, so masks use them
+	avgBits := bits.Len(uint(opts.AvgSize)) - 1
+	return &chunker{
+		minSize: opts.MinSize,
+		avgSize: opts.AvgSize,
+		maxSize: opts.MaxSize,
+		maskS:   ^uint64(0) << (64 - (avgBits + 1)),
+		maskL:   ^uint64(0) << (64 - (avgBits - 1)),
+	}, nil
+}
+
+// cut returns the length of the chunk at the start of data, which holds
+// either at least limit bytes or the rest of the stream
+func (c *chunker) cut(data []byte, limit int) int {
+	n := min(len(data), min(c.maxSize, limit))
+	if n <= c.minSize {
+		return n
+	}
+	normal := min(c.avgSize, n)
+
+	var fp uint64
+	i := c.minSize
+	for ; i < normal; i++ {
+		fp = fp<<1 + gearTable[data[i]]
+		if fp&c.maskS == 0 {
+			return i
+		}
+	}
+	for ; i < n; i++ {
+		fp = fp<<1 + gearTable[data[i]]
+		if fp&c.maskL == 0 {
+			return i
+		}
+	}
+	return n
+}
+
+// SetChunking enables content-defined chunking. Instead of filling blocks to
+// the block size, the Writer ends blocks at boundaries found by FastCDC in
+// the data itself, so identical runs of data in different streams, or at
+// different offsets, compress to identical blocks. After the frame, Close
+// writes a chunk index recording the length and XXH64 hash of every chunk,
+// which ReadChunkIndex returns for deduplication and delta sync. Readers that
+// don't know the index skip it. SetChunking must be called before the first
+// Write; afterwards ErrWriterStarted is returned.
+func (z *Writer) SetChunking(opts ChunkingOptions) error {
+	c, err := newChunker(opts)
+	if err != nil {
+		return err
+	}
+
+	z.lock()
+	defer z.unlock()
+
+	if z.wroteHeader {
+		return ErrWriterStarted
+	}
+	z.chunker = c
+	return nil
+}
+
+// Chunks returns the chunks written so far when chunking is enabled
+func (z *Writer) Chunks() []ChunkInfo {
+	z.lock()
+	defer z.unlock()
+	return append([]ChunkInfo(nil), z.chunks...)
+}
+
+// writeChunked buffers p and writes every chunk that can be cut from it.
+// A chunk is only cut once its maximum size is buffered, or at Close, so
+// boundaries don't depend on how the data was split across writes.
+func (z *Writer) writeChunked(p []byte) (int, error) {
+	limit := min(z.chunker.maxSize, z.blockSize)
+
+	var written int
+	for len(p) > 0 {
+		n := copy(z.buf[z.bufUsed:limit], p)
+		z.bufUsed += n
+		z.accept(p[:n])
+		p = p[n:]
+		written += n
+
+		if z.bufUsed == limit {
+			if err := z.flushChunk(limit); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flushChunk writes the next chunk of the buffer as a block and records it
+func (z *Writer) flushChunk(limit int) error {
+	data := z.buf[:z.bufUsed]
+	n := z.chunker.cut(data, limit)
+	chunk := data[:n]
+
+	blockOffset := z.bytesOut.Load()
+	if err := z.compressBlock(chunk); err != nil {
+		return err
+	}
+
+	size := int(z.bytesOut.Load()-blockOffset) - 4
+	if z.header.blockChecksum {
+		size -= 4
+	}
+	var offset uint64
+	if len(z.chunks) > 0 {
+		last := z.chunks[len(z.chunks)-1]
+		offset = last.Offset + uint64(last.Length)
+	}
+	z.chunks = append(z.chunks, ChunkInfo{
+		Offset:      offset,
+		Length:      n,
+		BlockOffset: int64(blockOffset),
+		BlockSize:   size,
+		Compressed:  size < n,
+		Hash:        xxh64.Checksum(chunk),
+	})
+
+	z.bufUsed = copy(z.buf, data[n:])
+	return nil
+}
+
+// writeChunkIndex writes the chunk index frame
+func (z *Writer) writeChunkIndex() error {
+	out := make([]byte, 8, 8+len(z.chunks)*chunkIndexEntrySize)
+	binary.LittleEndian.PutUint32(out[0:4], chunkIndexMagic)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(z.chunks)*chunkIndexEntrySize))
+	for _, c := range z.chunks {
+		word := uint32(c.BlockSize)
+		if !c.Compressed {
+			word |= 0x80000000
+		}
+		out = binary.LittleEndian.AppendUint32(out, word)
+		out = binary.LittleEndian.AppendUint32(out, uint32(c.Length))
+		out = binary.LittleEndian.AppendUint64(out, c.Hash)
+	}
+	_, err := z.writeOut(out)
+	return err
+}
+
+// ReadChunkIndex reads a frame written with content-defined chunking from r,
+// without decompressing it, and returns the chunks listed in the index that
+// follows it. Block offsets count from the start of r. It returns
+// ErrNoChunkIndex if the frame has no index.
+func ReadChunkIndex(r io.Reader) ([]ChunkInfo, error) {
+	zr := NewReader(r)
+	if err := zr.ReadHeader(); err != nil {
+		return nil, err
+	}
+
+	// Walk the blocks to find where each one starts
+	type block struct {
+		offset int64
+		word   uint32
+	}
+	var blocks []block
+	offset := int64(zr.Consumed())
+	var sizeBuf [4]byte
+	for {
+		if _, err := io.ReadFull(zr.r, sizeBuf[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		word := binary.LittleEndian.Uint32(sizeBuf[:])
+		if word == 0 {
+			break
+		}
+
+		skip := int64(word & 0x7FFFFFFF)
+		if skip > maxBlockSize {
+			return nil, errors.New("block size too large")
+		}
+		// Empty stored blocks carry no chunk
+		if skip > 0 {
+			blocks = append(blocks, block{offset, word})
+		}
+		if zr.header.blockChecksum {
+			skip += 4
+		}
+		if _, err := io.CopyN(io.Discard, zr.r, skip); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		offset = int64(zr.Consumed())
+	}
+	if zr.header.contentChecksum {
+		if _, err := io.CopyN(io.Discard, zr.r, 4); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+
+	var head [8]byte
+	if _, err := io.ReadFull(zr.r, head[:]); err != nil {
+		if err == io.EOF {
+			return nil, ErrNoChunkIndex
+		}
+		return nil, unexpectedEOF(err)
+	}
+	if binary.LittleEndian.Uint32(head[0:4]) != chunkIndexMagic {
+		return nil, ErrNoChunkIndex
+	}
+	size := binary.LittleEndian.Uint32(head[4:8])
+	if size != uint32(len(blocks)*chunkIndexEntrySize) {
+		return nil, ErrInvalidFrame
+	}
+	index := make([]byte, size)
+	if _, err := io.ReadFull(zr.r, index); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	chunks := make([]ChunkInfo, len(blocks))
+	var pos uint64
+	for i, b := range blocks {
+		entry := index[i*chunkIndexEntrySize:]
+		if binary.LittleEndian.Uint32(entry[0:4]) != b.word {
+			return nil, ErrInvalidFrame
+		}
+		length := binary.LittleEndian.Uint32(entry[4:8])
+		chunks[i] = ChunkInfo{
+			Offset:      pos,
+			Length:      int(length),
+			BlockOffset: b.offset,
+			BlockSize:   int(b.word & 0x7FFFFFFF),
+			Compressed:  b.word&0x80000000 == 0,
+			Hash:        binary.LittleEndian.Uint64(entry[8:16]),
+		}
+		pos += uint64(length)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,186 @@
+package compress
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// compressChunked compresses data with chunking, writing it in pieces of
+// the given size, and returns the stream and the chunks written
+func compressChunked(t *testing.T, data []byte, opts WriterOptions, piece int) ([]byte, []ChunkInfo) {
+	t.Helper()
+
+	if opts.Chunking == nil {
+		opts.Chunking = &ChunkingOptions{}
+	}
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, opts)
+	for p := data; len(p) > 0; {
+		n := min(piece, len(p))
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes(), w.Chunks()
+}
+
+// TestChunkingRoundTrip tests that chunked frames decode and that chunk
+// boundaries don't depend on how the input was written
+func TestChunkingRoundTrip(t *testing.T) {
+	data := append(generateRandomData(700*1024), generateCompressibleData(900*1024)...)
+
+	want, chunks := compressChunked(t, data, WriterOptions{}, len(data))
+	got, _ := readFrame(t, want)
+	if !bytes.Equal(got, data) {
+		t.Fatalf("decoded %d bytes, want %d", len(got), len(data))
+	}
+
+	for _, piece := range []int{1000, 4096, 65536, 300000} {
+		stream, _ := compressChunked(t, data, WriterOptions{}, piece)
+		if !bytes.Equal(stream, want) {
+			t.Errorf("writes of %d bytes produced a different stream", piece)
+		}
+	}
+
+	var total int
+	for i, c := range chunks {
+		if c.Offset != uint64(total) {
+			t.Errorf("chunk %d offset = %d, want %d", i, c.Offset, total)
+		}
+		total += c.Length
+		if c.Length > defaultChunkMax || (c.Length < defaultChunkMin && i != len(chunks)-1) {
+			t.Errorf("chunk %d length = %d, want %d-%d", i, c.Length, defaultChunkMin, defaultChunkMax)
+		}
+	}
+	if total != len(data) {
+		t.Errorf("chunks cover %d bytes, want %d", total, len(data))
+	}
+
+	// Repetitive data has few boundaries, so sizes are only tuned on random data
+	random := generateRandomData(4 * 1024 * 1024)
+	_, chunks = compressChunked(t, random, WriterOptions{}, len(random))
+	if avg := len(random) / len(chunks); avg < defaultChunkAvg/2 || avg > defaultChunkAvg*2 {
+		t.Errorf("average chunk size = %d, want about %d", avg, defaultChunkAvg)
+	}
+}
+
+// TestChunkingDedup tests that data shifted by an insertion still shares
+// most of its chunks with the original
+func TestChunkingDedup(t *testing.T) {
+	original := generateRandomData(2 * 1024 * 1024)
+	edited := append(append([]byte("a few inserted bytes"), original[:1024*1024]...), original[1024*1024+100:]...)
+
+	_, before := compressChunked(t, original, WriterOptions{}, len(original))
+	_, after := compressChunked(t, edited, WriterOptions{}, len(edited))
+
+	known := make(map[uint64]bool)
+	for _, c := range before {
+		known[c.Hash] = true
+	}
+	shared := 0
+	for _, c := range after {
+		if known[c.Hash] {
+			shared++
+		}
+	}
+	if shared < len(after)-4 {
+		t.Errorf("%d of %d chunks shared, want all but the edited ones", shared, len(after))
+	}
+}
+
+// TestReadChunkIndex tests reading the index back from a stream
+func TestReadChunkIndex(t *testing.T) {
+	data := generateCompressibleData(1024 * 1024)
+
+	tests := []struct {
+		name string
+		opts WriterOptions
+	}{
+		{"Default", WriterOptions{}},
+		{"Block Checksums", WriterOptions{BlockChecksum: XXH32}},
+		{"Small Chunks", WriterOptions{Chunking: &ChunkingOptions{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}}},
+		{"Small Blocks", WriterOptions{BlockSize: 64 * 1024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, chunks := compressChunked(t, data, tt.opts, 10000)
+
+			got, err := ReadChunkIndex(bytes.NewReader(stream))
+			if err != nil {
+				t.Fatalf("ReadChunkIndex() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, chunks) {
+				t.Errorf("ReadChunkIndex() = %d chunks, want %d matching Chunks()", len(got), len(chunks))
+			}
+
+			// Every block can be located and decoded on its own
+			for _, c := range got {
+				block := stream[c.BlockOffset+4 : c.BlockOffset+4+int64(c.BlockSize)]
+				raw := block
+				if c.Compressed {
+					raw, err = DecompressBlock(block, nil, c.Length)
+					if err != nil {
+						t.Fatalf("DecompressBlock() error = %v", err)
+					}
+				}
+				if !bytes.Equal(raw, data[c.Offset:c.Offset+uint64(c.Length)]) {
+					t.Fatalf("chunk at %d doesn't match the input", c.Offset)
+				}
+			}
+
+			decoded, _ := readFrame(t, stream)
+			if !bytes.Equal(decoded, data) {
+				t.Errorf("decoded %d bytes, want %d", len(decoded), len(data))
+			}
+		})
+	}
+}
+
+// TestChunkingErrors tests invalid options and frames without an index
+func TestChunkingErrors(t *testing.T) {
+	invalid := []ChunkingOptions{
+		{MinSize: 8},
+		{MinSize: 100000, AvgSize: 4096},
+		{AvgSize: 1 << 20, MaxSize: 1 << 19},
+		{MaxSize: 8 * 1024 * 1024},
+	}
+	for _, opts := range invalid {
+		if err := NewWriter(nil).SetChunking(opts); err != ErrInvalidChunking {
+			t.Errorf("SetChunking(%+v) error = %v, want %v", opts, err, ErrInvalidChunking)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.SetChunking(ChunkingOptions{}); err != ErrWriterStarted {
+		t.Errorf("SetChunking() error = %v, want %v", err, ErrWriterStarted)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := ReadChunkIndex(bytes.NewReader(buf.Bytes())); err != ErrNoChunkIndex {
+		t.Errorf("ReadChunkIndex() error = %v, want %v", err, ErrNoChunkIndex)
+	}
+}
+
+// TestChunkingEmpty tests a chunked frame without data
+func TestChunkingEmpty(t *testing.T) {
+	stream, chunks := compressChunked(t, nil, WriterOptions{}, 1)
+	if len(chunks) != 0 {
+		t.Errorf("Chunks() = %d chunks, want 0", len(chunks))
+	}
+	got, err := ReadChunkIndex(bytes.NewReader(stream))
+	if err != nil || len(got) != 0 {
+		t.Errorf("ReadChunkIndex() = %d chunks, %v, want 0, nil", len(got), err)
+	}
+}
@@ -0,0 +1,59 @@
+package compress
+
+import (
+	"errors"
+	"hash/crc32"
+
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+	"github.com/harriteja/GoZ4X/internal/xxh64"
+)
+
+var (
+	// ErrBlockChecksum indicates a block whose contents don't match its checksum
+	ErrBlockChecksum = errors.New("block checksum mismatch")
+	// ErrContentChecksum indicates a frame whose data doesn't match its
+	// content checksum
+	ErrContentChecksum = errors.New("content checksum mismatch")
+)
+
+// Checksummer computes the 32-bit checksums stored after each block of a frame
+// when block checksums are enabled.
+//
+// The LZ4 frame format specifies XXH32, and only frames written with it can
+// be verified by other LZ4 implementations. The other algorithms are meant
+// for non-strict containers read back by GoZ4X, where the Reader must be
+// configured with the same Checksummer.
+type Checksummer interface {
+	// Checksum returns the checksum of p
+	Checksum(p []byte) uint32
+}
+
+// ChecksumFunc adapts a function to the Checksummer interface
+type ChecksumFunc func(p []byte) uint32
+
+// Checksum returns f(p)
+func (f ChecksumFunc) Checksum(p []byte) uint32 {
+	return f(p)
+}
+
+// crc32cTable uses the Castagnoli polynomial, which hash/crc32 computes with
+// the SSE4.2 and ARMv8 CRC32 instructions where available
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Built-in checksum algorithms
+var (
+	// XXH32 is the 32-bit xxHash used by the LZ4 frame format
+	XXH32 Checksummer = ChecksumFunc(xxh32.Checksum)
+
+	// XXH64 is the lower 32 bits of the 64-bit xxHash, which is faster than
+	// XXH32 on 64-bit platforms
+	XXH64 Checksummer = ChecksumFunc(func(p []byte) uint32 {
+		return uint32(xxh64.Checksum(p))
+	})
+
+	// CRC32C is the CRC-32 with the Castagnoli polynomial, hardware
+	// accelerated on amd64 and arm64
+	CRC32C Checksummer = ChecksumFunc(func(p []byte) uint32 {
+		return crc32.Checksum(p, crc32cTable)
+	})
+)
@@ -0,0 +1,142 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestChecksummers tests the built-in algorithms against reference values
+func TestChecksummers(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     Checksummer
+		input string
+		want  uint32
+	}{
+		{"XXH32", XXH32, "abc", 0x32D153FF},
+		{"XXH64", XXH64, "abc", 0xAD770999},
+		{"CRC32C", CRC32C, "123456789", 0xE3069283},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.Checksum([]byte(tt.input)); got != tt.want {
+			t.Errorf("%s.Checksum(%q) = %#08x, want %#08x", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestBlockChecksumRoundTrip tests writing and verifying block checksums
+func TestBlockChecksumRoundTrip(t *testing.T) {
+	data := append(generateCompressibleData(150*1024), generateRandomData(20*1024)...)
+	sum := ChecksumFunc(func(p []byte) uint32 { return uint32(len(p)) * 31 })
+
+	for name, c := range map[string]Checksummer{"XXH32": XXH32, "XXH64": XXH64, "CRC32C": CRC32C, "Custom": sum} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024, BlockChecksum: c})
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{BlockChecksum: c})
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+			}
+			if !r.Flags().Has(FlagBlockChecksum) {
+				t.Errorf("Flags() = %#x, want FlagBlockChecksum set", r.Flags())
+			}
+
+			// Readers default to XXH32, so only spec frames verify without options
+			var wantErr error
+			if name != "XXH32" {
+				wantErr = ErrBlockChecksum
+			}
+			if _, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes()))); err != wantErr {
+				t.Errorf("ReadAll() with default checksum error = %v, want %v", err, wantErr)
+			}
+		})
+	}
+}
+
+// TestBlockChecksumCorruption tests detection of corrupted blocks
+func TestBlockChecksumCorruption(t *testing.T) {
+	// The reference pattern frame carries XXH32 block checksums
+	frame := readGolden(t, "pattern.lz4")
+
+	tests := []struct {
+		name    string
+		offset  int
+		wantErr error
+	}{
+		{"Block data", 20, ErrBlockChecksum},
+		{"Checksum", len(frame) - 9, ErrBlockChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := append([]byte(nil), frame...)
+			corrupt[tt.offset] ^= 0x01
+
+			if _, err := io.ReadAll(NewReader(bytes.NewReader(corrupt))); err != tt.wantErr {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParallelWriterBlockChecksum tests that ParallelWriter frames carry
+// valid checksums for compressed and stored blocks alike
+func TestParallelWriterBlockChecksum(t *testing.T) {
+	data := append(generateCompressibleData(150*1024), generateRandomData(100*1024)...)
+
+	tests := []struct {
+		name    string
+		options ParallelWriterOptions
+	}{
+		{"Workers", ParallelWriterOptions{BlockSize: 64 * 1024, NumWorkers: 4, BlockChecksum: XXH32}},
+		{"InWrite", ParallelWriterOptions{BlockSize: 64 * 1024, MaxInFlightBytes: 64 * 1024, BlockChecksum: XXH32}},
+		{"Store", ParallelWriterOptions{BlockSize: 64 * 1024, Store: true, BlockChecksum: XXH32}},
+		{"CRC32C", ParallelWriterOptions{BlockSize: 64 * 1024, BlockChecksum: CRC32C}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			pw := NewParallelWriterWithOptions(&buf, tt.options)
+			if _, err := pw.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := pw.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{BlockChecksum: tt.options.BlockChecksum})
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+			}
+			if !r.Flags().Has(FlagBlockChecksum) {
+				t.Errorf("Flags() = %#x, want FlagBlockChecksum set", r.Flags())
+			}
+
+			// Flipping a bit of the last block's data must be caught
+			corrupt := append([]byte(nil), buf.Bytes()...)
+			corrupt[len(corrupt)-20] ^= 0x01
+			r = NewReaderWithOptions(bytes.NewReader(corrupt), ReaderOptions{BlockChecksum: tt.options.BlockChecksum})
+			if _, err := io.ReadAll(r); err != ErrBlockChecksum {
+				t.Errorf("ReadAll() of corrupt frame error = %v, want %v", err, ErrBlockChecksum)
+			}
+		})
+	}
+}
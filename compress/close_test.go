@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+)
+
+// closeWriters builds each writer under test with a content checksum
+var closeWriters = []struct {
+	name string
+	new  func(w io.Writer) io.WriteCloser
+}{
+	{"Writer", func(w io.Writer) io.WriteCloser {
+		return NewWriterWithOptions(w, WriterOptions{BlockSize: 64 * 1024, ContentChecksum: true})
+	}},
+	{"ParallelWriter", func(w io.Writer) io.WriteCloser {
+		return NewParallelWriterWithOptions(w, ParallelWriterOptions{BlockSize: 64 * 1024, ContentChecksum: true})
+	}},
+}
+
+// TestCloseContentChecksum tests that Close ends the frame with the
+// checksum of its data
+func TestCloseContentChecksum(t *testing.T) {
+	for _, tt := range closeWriters {
+		for _, size := range []int{0, 10, 300 * 1024} {
+			data := generateCompressibleData(size)
+
+			var buf bytes.Buffer
+			w := tt.new(&buf)
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("%s: Write() error = %v", tt.name, err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: Close() error = %v", tt.name, err)
+			}
+
+			frame := buf.Bytes()
+			if got, want := binary.LittleEndian.Uint32(frame[len(frame)-4:]), xxh32.Checksum(data); got != want {
+				t.Errorf("%s: content checksum of %d bytes = %#x, want %#x", tt.name, size, got, want)
+			}
+			got, header := readFrame(t, frame)
+			if !header.contentChecksum || !bytes.Equal(got, data) {
+				t.Errorf("%s: read %d bytes with content checksum %v, want %d and true", tt.name, len(got), header.contentChecksum, size)
+			}
+		}
+	}
+}
+
+// TestCloseTwice tests that closing a finished stream again is harmless
+func TestCloseTwice(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+
+	for _, tt := range closeWriters {
+		var buf bytes.Buffer
+		w := tt.new(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: Write() error = %v", tt.name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", tt.name, err)
+		}
+		n := buf.Len()
+		if err := w.Close(); err != nil {
+			t.Errorf("%s: second Close() error = %v", tt.name, err)
+		}
+		if buf.Len() != n {
+			t.Errorf("%s: second Close() wrote %d bytes, want 0", tt.name, buf.Len()-n)
+		}
+	}
+}
+
+// TestCloseStickyError tests that a failure of the underlying writer is
+// returned by every later Write and Close until Reset
+func TestCloseStickyError(t *testing.T) {
+	data := generateRandomData(200 * 1024)
+
+	for _, tt := range closeWriters {
+		sw := &shortWriter{max: 4096, limit: 1000}
+		w := tt.new(sw)
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		if !errors.Is(err, io.ErrShortWrite) {
+			t.Fatalf("%s: error = %v, want %v", tt.name, err, io.ErrShortWrite)
+		}
+
+		// Later calls fail the same way without writing anything
+		sw.limit = 1 << 30
+		n := sw.buf.Len()
+		for i := 0; i < 2; i++ {
+			if err := w.Close(); !errors.Is(err, io.ErrShortWrite) {
+				t.Errorf("%s: Close() error = %v, want %v", tt.name, err, io.ErrShortWrite)
+			}
+		}
+		if _, err := w.Write(data); !errors.Is(err, io.ErrShortWrite) {
+			t.Errorf("%s: Write() error = %v, want %v", tt.name, err, io.ErrShortWrite)
+		}
+		if sw.buf.Len() != n {
+			t.Errorf("%s: wrote %d bytes after the failure, want 0", tt.name, sw.buf.Len()-n)
+		}
+
+		// Reset starts over
+		var buf bytes.Buffer
+		w.(interface{ Reset(io.Writer) }).Reset(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: Write() after Reset() error = %v", tt.name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() after Reset() error = %v", tt.name, err)
+		}
+		if got, _ := readFrame(t, buf.Bytes()); !bytes.Equal(got, data) {
+			t.Errorf("%s: read %d bytes after Reset(), want %d", tt.name, len(got), len(data))
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package compress
+
+import "io"
+
+// NewReadCloser returns a new Reader that decompresses from rc and owns it:
+// closing the Reader closes rc, as with the CloseUnderlying option
+func NewReadCloser(rc io.ReadCloser) *Reader {
+	return NewReaderWithOptions(rc, ReaderOptions{CloseUnderlying: true})
+}
+
+// NewWriteCloser returns a new Writer that compresses to wc at the default
+// level and owns it: closing the Writer finishes the frame and then closes
+// wc, as with the CloseUnderlying option
+func NewWriteCloser(wc io.WriteCloser) *Writer {
+	return NewWriterWithOptions(wc, WriterOptions{CloseUnderlying: true})
+}
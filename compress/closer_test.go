@@ -0,0 +1,125 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// closeCounter is a file-like stream that counts its Close calls and fails
+// them with err
+type closeCounter struct {
+	io.Reader
+	io.Writer
+	closes int
+	err    error
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return c.err
+}
+
+// TestWriterCloseUnderlying tests that a Writer owning its destination
+// closes it once, after finishing the frame
+func TestWriterCloseUnderlying(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	errClose := errors.New("close failed")
+
+	t.Run("Closes once", func(t *testing.T) {
+		var buf bytes.Buffer
+		dst := &closeCounter{Writer: &buf}
+		w := NewWriteCloser(dst)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+		}
+		if dst.closes != 1 {
+			t.Errorf("destination closed %d times, want 1", dst.closes)
+		}
+		if got, _ := readFrame(t, buf.Bytes()); !bytes.Equal(got, data) {
+			t.Errorf("read %d bytes, want %d", len(got), len(data))
+		}
+	})
+
+	t.Run("Close error", func(t *testing.T) {
+		dst := &closeCounter{Writer: io.Discard, err: errClose}
+		w := NewWriteCloser(dst)
+		w.Write(data)
+		if err := w.Close(); err != errClose {
+			t.Errorf("Close() error = %v, want %v", err, errClose)
+		}
+	})
+
+	t.Run("Write error", func(t *testing.T) {
+		dst := &closeCounter{Writer: &shortWriter{max: 1 << 20}}
+		w := NewWriterWithOptions(dst, WriterOptions{CloseUnderlying: true})
+		w.Write(data)
+		if err := w.Close(); err == nil {
+			t.Errorf("Close() error = nil, want the write error")
+		}
+		if dst.closes != 1 {
+			t.Errorf("destination closed %d times, want 1", dst.closes)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		first := &closeCounter{Writer: io.Discard}
+		second := &closeCounter{Writer: io.Discard}
+		w := NewWriteCloser(first)
+		w.Close()
+		w.Reset(second)
+		w.Close()
+		if first.closes != 1 || second.closes != 1 {
+			t.Errorf("destinations closed %d and %d times, want 1 and 1", first.closes, second.closes)
+		}
+	})
+
+	t.Run("Not set", func(t *testing.T) {
+		dst := &closeCounter{Writer: io.Discard}
+		w := NewWriter(dst)
+		w.Write(data)
+		w.Close()
+		if dst.closes != 0 {
+			t.Errorf("destination closed %d times, want 0", dst.closes)
+		}
+	})
+}
+
+// TestReaderCloseUnderlying tests that a Reader owning its source closes it
+func TestReaderCloseUnderlying(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frame := compressFrame(t, data, nil)
+	errClose := errors.New("close failed")
+
+	src := &closeCounter{Reader: bytes.NewReader(frame)}
+	r := NewReadCloser(src)
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAll() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+	for i := 0; i < 2; i++ {
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+	if src.closes != 1 {
+		t.Errorf("source closed %d times, want 1", src.closes)
+	}
+
+	src = &closeCounter{Reader: bytes.NewReader(frame), err: errClose}
+	if err := NewReadCloser(src).Close(); err != errClose {
+		t.Errorf("Close() error = %v, want %v", err, errClose)
+	}
+
+	src = &closeCounter{Reader: bytes.NewReader(frame)}
+	NewReader(src).Close()
+	if src.closes != 0 {
+		t.Errorf("source closed %d times without CloseUnderlying, want 0", src.closes)
+	}
+}
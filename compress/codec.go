@@ -0,0 +1,108 @@
+package compress
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Codec compresses and decompresses whole buffers. Its methods have the
+// shape of the s2 and snappy block functions and of the codec interfaces
+// that projects put in front of gzip, zstd and s2, so GoZ4X registers as one
+// more codec with little glue. The streaming counterparts are Writer and
+// Reader, whose Reset methods let them be pooled the same way as those of
+// other compressors.
+type Codec interface {
+	// Encode compresses src, appending to dst[:0] so that dst's storage
+	// is reused when it is large enough
+	Encode(dst, src []byte) ([]byte, error)
+	// Decode decompresses src, appending to dst[:0] in the same way
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+// FrameCodec is a Codec whose encoded form is an LZ4 frame, as written by
+// EncodeFrame, so that other LZ4 tools can read it. It keeps its Writers and
+// Readers in pools, so that their buffers and match tables are allocated
+// once rather than per call. A FrameCodec is safe for concurrent use.
+type FrameCodec struct {
+	options WriterOptions
+	writers sync.Pool
+	readers sync.Pool
+}
+
+var _ Codec = (*FrameCodec)(nil)
+
+// codecWriter is a pooled Writer with the buffer it writes to
+type codecWriter struct {
+	w   *Writer
+	out appendWriter
+}
+
+// codecReader is a pooled Reader with the source it reads from
+type codecReader struct {
+	r   *Reader
+	src bytes.Reader
+}
+
+// appendWriter is an io.Writer appending to a slice
+type appendWriter struct {
+	buf []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// NewFrameCodec returns a FrameCodec writing frames with the given options
+func NewFrameCodec(options WriterOptions) *FrameCodec {
+	return &FrameCodec{options: options}
+}
+
+// Encode compresses src into an LZ4 frame declaring len(src) as its content
+// size, appending to dst[:0]
+func (c *FrameCodec) Encode(dst, src []byte) ([]byte, error) {
+	cw, _ := c.writers.Get().(*codecWriter)
+	if cw == nil {
+		cw = &codecWriter{}
+		cw.w = NewWriterWithOptions(&cw.out, c.options)
+	}
+
+	cw.out.buf = dst[:0]
+	if need := compressBound(len(src)) + maxFrameOverhead; cap(dst) < need {
+		cw.out.buf = make([]byte, 0, need)
+	}
+	cw.w.Reset(&cw.out)
+	err := cw.w.SetContentSize(uint64(len(src)))
+	if err == nil {
+		_, err = cw.w.Write(src)
+	}
+	if err == nil {
+		err = cw.w.Close()
+	}
+	out := cw.out.buf
+	cw.out.buf = nil // Don't keep the caller's buffer alive
+
+	// A failed Writer keeps its error only until the next Encode resets it
+	c.writers.Put(cw)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Decode decompresses the LZ4 stream held in src, usually a frame from
+// Encode, appending to dst[:0]. Like DecodeFrame it sizes the output from
+// the frame's content size and decodes any frames following the first.
+func (c *FrameCodec) Decode(dst, src []byte) ([]byte, error) {
+	cr, _ := c.readers.Get().(*codecReader)
+	if cr == nil {
+		cr = &codecReader{r: NewReader(nil)}
+	}
+
+	cr.src.Reset(src)
+	cr.r.Reset(&cr.src)
+	out, err := decodeFrame(cr.r, len(src), dst[:0])
+	cr.src.Reset(nil)
+	c.readers.Put(cr)
+	return out, err
+}
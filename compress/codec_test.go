@@ -0,0 +1,115 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestFrameCodec tests encoding and decoding buffers with a FrameCodec,
+// reusing the caller's buffers
+func TestFrameCodec(t *testing.T) {
+	c := NewFrameCodec(WriterOptions{Level: DefaultLevel, ContentChecksum: true})
+
+	for _, size := range []int{0, 100, 100 * 1024, 5 << 20} {
+		src := generateCompressibleData(size)
+		frame, err := c.Encode(nil, src)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) error = %v", size, err)
+		}
+		if got, err := DecodeFrame(frame); err != nil || !bytes.Equal(got, src) {
+			t.Fatalf("DecodeFrame() = %d bytes, %v, want %d", len(got), err, size)
+		}
+		got, err := c.Decode(nil, frame)
+		if err != nil || !bytes.Equal(got, src) {
+			t.Fatalf("Decode() = %d bytes, %v, want %d", len(got), err, size)
+		}
+	}
+
+	// Buffers large enough are reused, and nothing else is allocated once
+	// the pools hold a Writer and a Reader
+	src := generateCompressibleData(64 * 1024)
+	frame, _ := c.Encode(nil, src)
+	enc := make([]byte, 0, compressBound(len(src))+maxFrameOverhead)
+	dec := make([]byte, 0, len(src)+1)
+	allocs := testing.AllocsPerRun(20, func() {
+		out, err := c.Encode(enc, src)
+		if err != nil || &out[0] != &enc[:1][0] {
+			t.Fatalf("Encode() = %d bytes, %v, want them in dst", len(out), err)
+		}
+		out, err = c.Decode(dec, frame)
+		if err != nil || &out[0] != &dec[:1][0] {
+			t.Fatalf("Decode() = %d bytes, %v, want them in dst", len(out), err)
+		}
+	})
+	if allocs != 0 && !raceEnabled {
+		t.Errorf("Encode() and Decode() allocs = %v, want 0", allocs)
+	}
+
+	// A failed Decode doesn't affect the next one
+	if _, err := c.Decode(nil, frame[:len(frame)/2]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Decode() of a truncated frame error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if got, err := c.Decode(nil, frame); err != nil || !bytes.Equal(got, src) {
+		t.Errorf("Decode() after an error = %d bytes, %v, want %d", len(got), err, len(src))
+	}
+}
+
+// TestFrameCodecConcurrent tests sharing a FrameCodec between goroutines
+func TestFrameCodecConcurrent(t *testing.T) {
+	c := NewFrameCodec(WriterOptions{})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			var enc, dec []byte
+			for i := 0; i < 20; i++ {
+				src := generateCompressibleData(1000 * (g + i + 1))
+				var err error
+				if enc, err = c.Encode(enc, src); err != nil {
+					t.Errorf("Encode() error = %v", err)
+					return
+				}
+				if dec, err = c.Decode(dec, enc); err != nil || !bytes.Equal(dec, src) {
+					t.Errorf("Decode() = %d bytes, %v, want %d", len(dec), err, len(src))
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestReaderReset tests reusing a Reader for another stream, including
+// after an error and after Close
+func TestReaderReset(t *testing.T) {
+	first := generateCompressibleData(100 * 1024)
+	second := generateRandomData(50 * 1024)
+	firstFrame := compressFrame(t, first, nil)
+	secondFrame := compressFrame(t, second, func(w *Writer) { w.header.contentChecksum = true })
+
+	r := NewReader(bytes.NewReader(firstFrame[:len(firstFrame)/2]))
+	if _, err := io.ReadAll(r); err == nil {
+		t.Fatalf("ReadAll() of a truncated frame succeeded")
+	}
+
+	for _, frame := range []struct {
+		data, want []byte
+	}{{firstFrame, first}, {secondFrame, second}, {firstFrame, first}} {
+		r.Reset(bytes.NewReader(frame.data))
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, frame.want) {
+			t.Fatalf("ReadAll() after Reset = %d bytes, %v, want %d", len(got), err, len(frame.want))
+		}
+		if r.Consumed() != uint64(len(frame.data)) || r.Produced() != uint64(len(frame.want)) {
+			t.Errorf("Consumed(), Produced() = %d, %d, want %d, %d", r.Consumed(), r.Produced(), len(frame.data), len(frame.want))
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+}
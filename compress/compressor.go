@@ -0,0 +1,68 @@
+package compress
+
+// Compressor compresses blocks at a fixed level like CompressBlockLevel, but
+// keeps its match finder tables between calls, so that compressing many
+// blocks into a reused dst doesn't allocate. A Compressor is not safe for
+// concurrent use; give each goroutine its own.
+type Compressor struct {
+	level   CompressionLevel
+	matcher *HCMatcher
+	// window holds a dictionary followed by the block being compressed
+	window []byte
+	// prime holds the tables of the dictionary indexed by Prewarm
+	prime *matcherPrime
+}
+
+// NewCompressor returns a Compressor for the given level
+func NewCompressor(level CompressionLevel) (*Compressor, error) {
+	if level < 0 || level > MaxLevel {
+		return nil, ErrInvalidCompressionLevel
+	}
+	return &Compressor{level: level, matcher: NewHCMatcher(level)}, nil
+}
+
+// Level returns the compression level of c
+func (c *Compressor) Level() CompressionLevel {
+	return c.level
+}
+
+// CompressBlock compresses src into dst, producing the same output as
+// CompressBlockLevel at c's level.
+// If dst is nil or too small, a new buffer will be allocated.
+func (c *Compressor) CompressBlock(src, dst []byte) ([]byte, error) {
+	if len(src) < MinBlockSize || len(src) > MaxBlockSize {
+		return nil, ErrInvalidBlockSize
+	}
+	if c.level == NoCompression {
+		return storeBlock(src, dst), nil
+	}
+	out, err := compressWith(c.matcher, src, 0, dst)
+	c.matcher.buf = nil // Don't keep src alive
+	return out, err
+}
+
+// CompressBlockDict compresses src into dst like CompressBlockDict at c's
+// level.
+func (c *Compressor) CompressBlockDict(src, dst, dict []byte) ([]byte, error) {
+	if len(dict) == 0 {
+		return c.CompressBlock(src, dst)
+	}
+	if len(src) < MinBlockSize || len(src) > MaxBlockSize {
+		return nil, ErrInvalidBlockSize
+	}
+
+	if c.level == NoCompression {
+		return storeBlock(src, dst), nil
+	}
+
+	dict = dictWindow(dict)
+	c.window = append(append(c.window[:0], dict...), src...)
+	if !c.primedFor(dict) {
+		return compressWith(c.matcher, c.window, len(dict), dst)
+	}
+
+	c.matcher.restore(c.window, c.prime)
+	c.matcher.UpdateTables(c.prime.n, len(dict))
+	c.matcher.Advance(len(dict))
+	return compressFrom(c.matcher, c.window, len(dict), dst)
+}
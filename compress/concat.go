@@ -0,0 +1,230 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrIncompatibleFrames indicates frames that can't be merged, such as frames
+// that depend on a dictionary
+var ErrIncompatibleFrames = errors.New("frames are not compatible for concatenation")
+
+// ConcatFrames merges the LZ4 frames of several streams into a single frame
+// written to w. Every frame of every stream is copied, in order, and
+// skippable frames between them are dropped. Blocks are copied as-is without
+// recompression. The output frame uses the largest block size of the inputs,
+// keeps block checksums only if every input has them, and declares a content
+// size only if every input does. Content checksums can't be combined without
+// decoding and are dropped. Frames referencing a dictionary return
+// ErrIncompatibleFrames, since their blocks would decode against the data
+// before them instead. Streams are read to the end before anything is
+// written, so the compressed blocks are held in memory.
+func ConcatFrames(w io.Writer, frames ...io.Reader) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to concatenate")
+	}
+
+	// Read every frame up front so the output header is known before writing
+	var inputs []concatFrame
+	for _, src := range frames {
+		var err error
+		if inputs, err = readConcatFrames(inputs, src); err != nil {
+			return err
+		}
+	}
+
+	out := frameHeader{
+		blockIndependence: true,
+		blockChecksum:     true,
+		contentSize:       true,
+		blockSizeCode:     4,
+	}
+
+	for _, f := range inputs {
+		h := &f.header
+		out.blockIndependence = out.blockIndependence && h.blockIndependence
+		out.blockChecksum = out.blockChecksum && h.blockChecksum
+		out.contentSize = out.contentSize && h.contentSize
+		out.contentSizeValue += h.contentSizeValue
+		if h.blockSizeCode > out.blockSizeCode {
+			out.blockSizeCode = h.blockSizeCode
+		}
+	}
+	if !out.contentSize {
+		out.contentSizeValue = 0
+	}
+
+	if _, err := writeFull(w, appendFrameHeader(nil, &out)); err != nil {
+		return err
+	}
+
+	for _, f := range inputs {
+		if err := f.writeBlocks(w, out.blockChecksum); err != nil {
+			return err
+		}
+	}
+
+	// Write end marker
+	_, err := writeFull(w, []byte{0, 0, 0, 0})
+	return err
+}
+
+// concatFrame is a frame read by ConcatFrames: its header and its blocks,
+// with their checksums, without the end marker
+type concatFrame struct {
+	header frameHeader
+	blocks []byte
+}
+
+// readConcatFrames appends every frame of src to frames. src must hold at
+// least one frame, and nothing but frames and skippable frames.
+func readConcatFrames(frames []concatFrame, src io.Reader) ([]concatFrame, error) {
+	r := NewReader(src)
+	first := len(frames)
+	err := r.readFrameHeader()
+	for err == nil {
+		if r.header.dictID {
+			return frames, ErrIncompatibleFrames
+		}
+		var blocks bytes.Buffer
+		if err := copyFrameBlocks(&blocks, r, true); err != nil {
+			return frames, err
+		}
+		frames = append(frames, concatFrame{header: r.header, blocks: blocks.Bytes()})
+		err = r.readFrameHeader()
+	}
+	if err == io.EOF {
+		if len(frames) == first {
+			return frames, io.ErrUnexpectedEOF
+		}
+		return frames, nil
+	}
+	return frames, err
+}
+
+// writeBlocks writes the blocks of f, with their checksums if keepChecksum
+// is set
+func (f *concatFrame) writeBlocks(w io.Writer, keepChecksum bool) error {
+	for b := f.blocks; len(b) > 0; {
+		n := 4 + int(binary.LittleEndian.Uint32(b)&0x7FFFFFFF)
+		if _, err := writeFull(w, b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+		if f.header.blockChecksum {
+			if keepChecksum {
+				if _, err := writeFull(w, b[:4]); err != nil {
+					return err
+				}
+			}
+			b = b[4:]
+		}
+	}
+	return nil
+}
+
+// copyFrameBlocks copies the remaining blocks of a frame whose header has
+// already been parsed, consuming the end marker and content checksum
+func copyFrameBlocks(w io.Writer, r *Reader, keepChecksum bool) error {
+	var sizeBuf [4]byte
+	var checksum [4]byte
+
+	for {
+		if _, err := io.ReadFull(r.r, sizeBuf[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		blockSize := binary.LittleEndian.Uint32(sizeBuf[:])
+		if blockSize == 0 {
+			break
+		}
+
+		dataSize := int64(blockSize & 0x7FFFFFFF)
+		if dataSize > maxBlockSize {
+			return errors.New("block size too large")
+		}
+
+		// Empty stored blocks carry no data, so drop them
+		if dataSize == 0 {
+			if r.header.blockChecksum {
+				if _, err := io.ReadFull(r.r, checksum[:]); err != nil {
+					return unexpectedEOF(err)
+				}
+			}
+			continue
+		}
+
+		if _, err := writeFull(w, sizeBuf[:]); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(w, r.r, dataSize); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		if r.header.blockChecksum {
+			if _, err := io.ReadFull(r.r, checksum[:]); err != nil {
+				return unexpectedEOF(err)
+			}
+			if keepChecksum {
+				if _, err := writeFull(w, checksum[:]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Discard the content checksum of the input frame
+	if r.header.contentChecksum {
+		if _, err := io.ReadFull(r.r, checksum[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+
+	return nil
+}
+
+// ConcatBlocks merges independently compressed blocks into a single block
+// that decompresses to the concatenation of their contents.
+// Because the final sequence of a block carries no match, the trailing
+// literals of each block are folded into the first sequence of the next one.
+func ConcatBlocks(blocks ...[]byte) ([]byte, error) {
+	totalSize := 0
+	for _, block := range blocks {
+		totalSize += len(block)
+	}
+
+	dst := make([]byte, 0, totalSize+16)
+	var pending []byte
+
+	for _, block := range blocks {
+		sr := NewSequenceReader(block)
+		for {
+			seq, err := sr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			literals := seq.Literals
+			if len(pending) > 0 {
+				literals = append(pending, literals...)
+				pending = nil
+			}
+
+			// Hold back the final literals until the next block's first match
+			if seq.MatchLen == 0 {
+				pending = append([]byte(nil), literals...)
+				continue
+			}
+
+			dst = appendSequence(dst, literals, seq.Offset, seq.MatchLen)
+		}
+	}
+
+	// A block always ends with a literal-only sequence
+	return appendSequence(dst, pending, 0, 0), nil
+}
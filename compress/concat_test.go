@@ -0,0 +1,187 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"testing"
+)
+
+// compressFrame compresses data into a standalone frame for tests
+func compressFrame(t *testing.T, data []byte, configure func(w *Writer)) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if configure != nil {
+		configure(w)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestConcatFrames tests merging frames into a single frame
+func TestConcatFrames(t *testing.T) {
+	parts := [][]byte{
+		generateCompressibleData(100 * 1024),
+		[]byte("tiny"),
+		{},
+		generateRandomData(70 * 1024),
+	}
+
+	var frames []io.Reader
+	var want []byte
+	for i, part := range parts {
+		frame := compressFrame(t, part, func(w *Writer) {
+			// Mix block sizes and optional fields across the inputs
+			if i%2 == 1 {
+				w.header.blockSizeCode = 4
+				w.Reset(w.w)
+			}
+			w.header.contentSize = true
+			w.header.contentSizeValue = uint64(len(part))
+			w.header.contentChecksum = i == 0
+		})
+		frames = append(frames, bytes.NewReader(frame))
+		want = append(want, part...)
+	}
+
+	var out bytes.Buffer
+	if err := ConcatFrames(&out, frames...); err != nil {
+		t.Fatalf("ConcatFrames() error = %v", err)
+	}
+
+	r := NewReader(bytes.NewReader(out.Bytes()))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("concatenated frame decoded to %d bytes, want %d", len(got), len(want))
+	}
+
+	// The merged header reflects the combined inputs
+	if !r.header.contentSize || r.header.contentSizeValue != uint64(len(want)) {
+		t.Errorf("content size = %v/%d, want %d", r.header.contentSize, r.header.contentSizeValue, len(want))
+	}
+	if r.header.contentChecksum {
+		t.Errorf("content checksum should be dropped from merged frames")
+	}
+	if r.header.blockSizeCode != 7 {
+		t.Errorf("blockSizeCode = %d, want 7", r.header.blockSizeCode)
+	}
+}
+
+// TestConcatFramesStreams tests that every frame of a stream is merged,
+// skipping the skippable frames between them
+func TestConcatFramesStreams(t *testing.T) {
+	a := generateCompressibleData(50 * 1024)
+	b := generateRandomData(20 * 1024)
+	c := []byte("last segment")
+
+	linked := compressFrame(t, b, func(w *Writer) {
+		w.header.blockIndependence = false
+		w.header.blockChecksum = true
+	})
+	var stream bytes.Buffer
+	stream.Write(compressFrame(t, a, nil))
+	stream.Write(binary.LittleEndian.AppendUint32(nil, skippableMagic|3))
+	stream.Write(binary.LittleEndian.AppendUint32(nil, 5))
+	stream.WriteString("index")
+	stream.Write(linked)
+
+	var out bytes.Buffer
+	err := ConcatFrames(&out, bytes.NewReader(stream.Bytes()), bytes.NewReader(compressFrame(t, c, nil)))
+	if err != nil {
+		t.Fatalf("ConcatFrames() error = %v", err)
+	}
+	r := NewReader(bytes.NewReader(out.Bytes()))
+	got, err := io.ReadAll(r)
+	if want := slices.Concat(a, b, c); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("concatenated streams decoded to %d bytes, %v, want %d bytes", len(got), err, len(want))
+	}
+	if r.header.blockIndependence {
+		t.Errorf("merged frame claims independent blocks after a linked input")
+	}
+}
+
+// TestConcatFramesErrors tests incompatible and truncated inputs
+func TestConcatFramesErrors(t *testing.T) {
+	data := generateCompressibleData(4096)
+	plain := compressFrame(t, data, nil)
+	withDict := compressFrame(t, data, func(w *Writer) {
+		w.header.dictID = true
+		w.header.dictIDValue = 42
+	})
+
+	if err := ConcatFrames(io.Discard); err == nil {
+		t.Errorf("ConcatFrames() with no frames: error = nil, expected error")
+	}
+
+	err := ConcatFrames(io.Discard, bytes.NewReader(plain), bytes.NewReader(withDict))
+	if err != ErrIncompatibleFrames {
+		t.Errorf("ConcatFrames() with mixed dictionaries: error = %v, want %v", err, ErrIncompatibleFrames)
+	}
+	err = ConcatFrames(io.Discard, bytes.NewReader(withDict), bytes.NewReader(withDict))
+	if err != ErrIncompatibleFrames {
+		t.Errorf("ConcatFrames() with dictionaries: error = %v, want %v", err, ErrIncompatibleFrames)
+	}
+
+	err = ConcatFrames(io.Discard, bytes.NewReader(plain), bytes.NewReader(nil))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("ConcatFrames() with empty stream: error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	err = ConcatFrames(io.Discard, bytes.NewReader(append(plain, "garbage"...)))
+	if err != ErrInvalidMagic {
+		t.Errorf("ConcatFrames() with trailing data: error = %v, want %v", err, ErrInvalidMagic)
+	}
+
+	err = ConcatFrames(io.Discard, bytes.NewReader(plain), bytes.NewReader(plain[:len(plain)-6]))
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("ConcatFrames() with truncated frame: error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// TestConcatBlocks tests stitching independent blocks into one block
+func TestConcatBlocks(t *testing.T) {
+	parts := [][]byte{
+		generateCompressibleData(10000),
+		generateRandomData(3000),
+		bytes.Repeat([]byte("z"), 5000),
+		generateCompressibleData(777),
+	}
+
+	var blocks [][]byte
+	var want []byte
+	for _, part := range parts {
+		block, err := CompressBlock(part, nil)
+		if err != nil {
+			t.Fatalf("CompressBlock() error = %v", err)
+		}
+		blocks = append(blocks, block)
+		want = append(want, part...)
+	}
+
+	merged, err := ConcatBlocks(blocks...)
+	if err != nil {
+		t.Fatalf("ConcatBlocks() error = %v", err)
+	}
+
+	got, err := DecompressBlock(merged, nil, len(want))
+	if err != nil {
+		t.Fatalf("DecompressBlock() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("merged block decoded to %d bytes, want %d", len(got), len(want))
+	}
+
+	if _, err := ConcatBlocks(blocks[0], []byte{0xF0}); err != ErrCorruptBlock {
+		t.Errorf("ConcatBlocks() with corrupt block: error = %v, want %v", err, ErrCorruptBlock)
+	}
+}
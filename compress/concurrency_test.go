@@ -0,0 +1,191 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriterConcurrentWrites tests that concurrent writes are never interleaved
+func TestWriterConcurrentWrites(t *testing.T) {
+	const (
+		writers   = 8
+		writes    = 50
+		chunkSize = 3000
+	)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.SetBlockSizeCode(BlockSize64KB); err != nil {
+		t.Fatalf("SetBlockSizeCode() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+		go func(id byte) {
+			defer wg.Done()
+			chunk := bytes.Repeat([]byte{id}, chunkSize)
+			for i := 0; i < writes; i++ {
+				if _, err := w.Write(chunk); err != nil {
+					t.Errorf("Write() error = %v", err)
+					return
+				}
+				_ = w.Written()
+				_ = w.Compressed()
+			}
+		}(byte('a' + g))
+	}
+	wg.Wait()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(got) != writers*writes*chunkSize {
+		t.Fatalf("decoded %d bytes, want %d", len(got), writers*writes*chunkSize)
+	}
+	for off := 0; off < len(got); off += chunkSize {
+		chunk := got[off : off+chunkSize]
+		if !bytes.Equal(chunk, bytes.Repeat(chunk[:1], chunkSize)) {
+			t.Fatalf("chunk at offset %d interleaves data from several writes", off)
+		}
+	}
+}
+
+// TestWriterResetDuringWrite tests Reset and Close racing with Write
+func TestWriterResetDuringWrite(t *testing.T) {
+	w := NewWriterLevel(io.Discard, FastLevel)
+	if err := w.SetBlockSizeCode(BlockSize64KB); err != nil {
+		t.Fatalf("SetBlockSizeCode() error = %v", err)
+	}
+	data := generateCompressibleData(10 * 1024)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Writes fail while the writer is closed between resets
+				w.Write(data)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		w.Reset(io.Discard)
+		time.Sleep(100 * time.Microsecond)
+		w.Close()
+	}
+	close(done)
+	wg.Wait()
+
+	// The writer is still usable afterwards
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() = %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+}
+
+// TestReaderConcurrentAccessors tests querying a Reader while another goroutine reads
+func TestReaderConcurrentAccessors(t *testing.T) {
+	data := generateCompressibleData(512 * 1024)
+	frame := compressFrame(t, data, func(w *Writer) {
+		if err := w.SetBlockSizeCode(BlockSize64KB); err != nil {
+			t.Fatalf("SetBlockSizeCode() error = %v", err)
+		}
+	})
+
+	r := NewReader(bytes.NewReader(frame))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_ = r.Consumed()
+			_ = r.Produced()
+			_, _ = r.ContentSize()
+			_ = r.BlockSize()
+			_ = r.Flags()
+			if r.Produced() == uint64(len(data)) {
+				return
+			}
+		}
+	}()
+
+	got, err := io.ReadAll(r)
+	<-done
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() = %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+}
+
+// TestWriterUnsynchronized tests that an unsynchronized Writer writes the
+// same stream as a synchronized one, while another goroutine reads its
+// counters
+func TestWriterUnsynchronized(t *testing.T) {
+	data := generateCompressibleData(512 * 1024)
+	opts := WriterOptions{BlockSize: 64 * 1024, ContentChecksum: true}
+
+	for _, async := range []bool{false, true} {
+		var wantBuf, buf bytes.Buffer
+		opts.AsyncFlush = async
+		ref := NewWriterWithOptions(&wantBuf, opts)
+		opts.Unsynchronized = true
+		w := NewWriterWithOptions(&buf, opts)
+		opts.Unsynchronized = false
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			var last uint64
+			for last < uint64(len(data)) {
+				n := w.Written()
+				if n < last {
+					t.Errorf("Written() went from %d to %d", last, n)
+					return
+				}
+				last = n
+				_ = w.Compressed()
+			}
+		}()
+		for i := 0; i < len(data); i += 1000 {
+			chunk := data[i:min(i+1000, len(data))]
+			ref.Write(chunk)
+			if _, err := w.Write(chunk); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		<-done
+		ref.Close()
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), wantBuf.Bytes()) {
+			t.Errorf("async %v: unsynchronized stream of %d bytes, want the %d of a synchronized one", async, buf.Len(), wantBuf.Len())
+		}
+		if w.Compressed() != uint64(buf.Len()) {
+			t.Errorf("async %v: Compressed() = %d, want %d", async, w.Compressed(), buf.Len())
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+	"testing/iotest"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// splitWriter passes each Write on to w in pieces of 1 to max bytes, as a
+// socket does when its send buffer fills
+type splitWriter struct {
+	w   io.Writer
+	rng *rand.Rand
+	max int
+}
+
+func (sw *splitWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n := min(1+sw.rng.Intn(sw.max), len(p)-written)
+		m, err := sw.w.Write(p[written : written+n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// splitReader returns at most 1 to max bytes from each Read of r, as a
+// socket does when data arrives in segments
+type splitReader struct {
+	r   io.Reader
+	rng *rand.Rand
+	max int
+}
+
+func (sr *splitReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return sr.r.Read(p)
+	}
+	return sr.r.Read(p[:min(1+sr.rng.Intn(sr.max), len(p))])
+}
+
+// connPairs returns the connected pairs of conns the network tests run
+// over: net.Pipe, which hands each Write to the reader unbuffered, and TCP
+// over loopback when the sandbox allows it
+func connPairs(t *testing.T) map[string]func() (net.Conn, net.Conn) {
+	t.Helper()
+	pairs := map[string]func() (net.Conn, net.Conn){"Pipe": net.Pipe}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Logf("no loopback TCP: %v", err)
+		return pairs
+	}
+	t.Cleanup(func() { ln.Close() })
+	pairs["TCP"] = func() (net.Conn, net.Conn) {
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		s := <-accepted
+		if s == nil {
+			t.Fatal("Accept() failed")
+		}
+		return c, s
+	}
+	return pairs
+}
+
+// writeSplit writes data to w in pieces of 1 to max bytes
+func writeSplit(w io.Writer, data []byte, rng *rand.Rand, max int) error {
+	for len(data) > 0 {
+		n := min(1+rng.Intn(max), len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// readSplit reads r to the end into buffers of 1 to max bytes
+func readSplit(r io.Reader, rng *rand.Rand, max int) ([]byte, error) {
+	var got []byte
+	for {
+		p := make([]byte, 1+rng.Intn(max))
+		n, err := r.Read(p)
+		got = append(got, p[:n]...)
+		if err == io.EOF {
+			return got, nil
+		}
+		if err != nil {
+			return got, err
+		}
+	}
+}
+
+// TestConnStream tests Writers and Readers at the two ends of network
+// conns that split the stream at random points and one byte at a time,
+// where framing bugs hidden by bytes.Buffer show up
+func TestConnStream(t *testing.T) {
+	data := append(generateCompressibleData(300*1024), generateRandomData(100*1024)...)
+
+	writers := []struct {
+		name string
+		new  func(w io.Writer) io.WriteCloser
+	}{
+		{"Writer", func(w io.Writer) io.WriteCloser {
+			return NewWriterWithOptions(w, WriterOptions{BlockSize: 64 * 1024, BlockChecksum: XXH32, ContentChecksum: true})
+		}},
+		{"AsyncFlush", func(w io.Writer) io.WriteCloser {
+			return NewWriterWithOptions(w, WriterOptions{BlockSize: 64 * 1024, AsyncFlush: true})
+		}},
+		{"ParallelWriter", func(w io.Writer) io.WriteCloser {
+			return NewParallelWriterWithOptions(w, ParallelWriterOptions{BlockSize: 64 * 1024})
+		}},
+	}
+	splits := []struct {
+		name    string
+		maxPart int
+		oneByte bool
+	}{
+		{"Random", 3000, false},
+		{"OneByte", 1, true},
+	}
+
+	for name, pair := range connPairs(t) {
+		for _, w := range writers {
+			for _, split := range splits {
+				t.Run(name+"/"+w.name+"/"+split.name, func(t *testing.T) {
+					client, server := pair()
+					defer server.Close()
+					seed := int64(testgen.DefaultSeed)
+
+					errc := make(chan error, 1)
+					go func() {
+						defer client.Close()
+						zw := w.new(&splitWriter{w: client, rng: testgen.Rand(seed), max: split.maxPart})
+						if err := writeSplit(zw, data, testgen.Rand(seed+1), 5000); err != nil {
+							errc <- err
+							return
+						}
+						errc <- zw.Close()
+					}()
+
+					var src io.Reader = &splitReader{r: server, rng: testgen.Rand(seed + 2), max: split.maxPart}
+					if split.oneByte {
+						src = iotest.OneByteReader(server)
+					}
+					got, err := readSplit(NewReader(src), testgen.Rand(seed+3), 70*1024)
+					if err != nil {
+						t.Fatalf("Read() error = %v", err)
+					}
+					if err := <-errc; err != nil {
+						t.Fatalf("Write() error = %v", err)
+					}
+					if !bytes.Equal(got, data) {
+						t.Errorf("read %d bytes, want %d matching", len(got), len(data))
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestConnMessages tests a request and response exchange of messages over
+// network conns that split every write, which only works if the Decoder
+// never reads past the message it returns
+func TestConnMessages(t *testing.T) {
+	for name, pair := range connPairs(t) {
+		t.Run(name, func(t *testing.T) {
+			client, server := pair()
+			defer client.Close()
+
+			// The server echoes every message back with its bytes reversed
+			done := make(chan error, 1)
+			go func() {
+				defer server.Close()
+				dec := NewDecoder(&splitReader{r: server, rng: testgen.Rand(1), max: 7})
+				enc := NewEncoder(&splitWriter{w: server, rng: testgen.Rand(2), max: 100})
+				for {
+					msg, err := dec.Decode()
+					if err == io.EOF {
+						done <- nil
+						return
+					}
+					if err != nil {
+						done <- err
+						return
+					}
+					for i, j := 0, len(msg)-1; i < j; i, j = i+1, j-1 {
+						msg[i], msg[j] = msg[j], msg[i]
+					}
+					if err := enc.Encode(msg); err != nil {
+						done <- err
+						return
+					}
+				}
+			}()
+
+			enc := NewEncoder(&splitWriter{w: client, rng: testgen.Rand(3), max: 100})
+			dec := NewDecoder(iotest.OneByteReader(client))
+			for _, size := range []int{1, 12, 100, 4096, 64 * 1024} {
+				msg := generateCompressibleData(size)
+				if err := enc.Encode(msg); err != nil {
+					t.Fatalf("Encode(%d bytes) error = %v", size, err)
+				}
+				got, err := dec.Decode()
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(got) != size {
+					t.Fatalf("response = %d bytes, want %d", len(got), size)
+				}
+				for i, c := range got {
+					if c != msg[len(msg)-1-i] {
+						t.Fatalf("response to %d bytes differs at %d", size, i)
+					}
+				}
+			}
+
+			if tc, ok := client.(*net.TCPConn); ok {
+				tc.CloseWrite()
+			} else {
+				client.Close()
+			}
+			if err := <-done; err != nil {
+				t.Errorf("server error = %v", err)
+			}
+		})
+	}
+}
+
+// TestConnDropped tests that a conn closed in the middle of a frame fails
+// the Reader with io.ErrUnexpectedEOF after the blocks that arrived whole
+func TestConnDropped(t *testing.T) {
+	data := generateCompressibleData(256 * 1024)
+	var buf bytes.Buffer
+	zw := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024})
+	zw.Write(data)
+	zw.Close()
+	stream := buf.Bytes()
+
+	for name, pair := range connPairs(t) {
+		t.Run(name, func(t *testing.T) {
+			client, server := pair()
+			defer server.Close()
+			go func() {
+				defer client.Close()
+				w := &splitWriter{w: client, rng: testgen.Rand(testgen.DefaultSeed), max: 1000}
+				w.Write(stream[:len(stream)/2])
+			}()
+
+			got, err := readSplit(NewReader(server), testgen.Rand(testgen.DefaultSeed), 10*1024)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("Read() error = %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			if !bytes.Equal(got, data[:len(got)]) {
+				t.Errorf("read %d bytes that differ from the input", len(got))
+			}
+		})
+	}
+}
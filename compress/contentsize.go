@@ -0,0 +1,95 @@
+package compress
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ErrContentSizeMismatch indicates a stream whose length differs from the
+// content size declared in its frame header
+var ErrContentSizeMismatch = errors.New("content size mismatch")
+
+// SetContentSize declares the total uncompressed size of the stream in the
+// frame header, letting readers pre-allocate and report progress. It must be
+// called before the first Write; afterwards ErrWriterStarted is returned.
+// Close returns ErrContentSizeMismatch if a different number of bytes was written.
+// Unless a block size was chosen explicitly, the size also serves as a size hint.
+func (z *Writer) SetContentSize(size uint64) error {
+	z.lock()
+	defer z.unlock()
+
+	if z.wroteHeader {
+		return ErrWriterStarted
+	}
+
+	z.header.contentSize = true
+	z.header.contentSizeValue = size
+	if size <= maxBlockSize {
+		z.applySizeHint(int64(size))
+	}
+	return nil
+}
+
+// CompressFile compresses src to dst as a single frame using the given
+// writer options. When the size of src can be determined, because it is an
+// io.Seeker or a regular file, it is declared as the frame's content size.
+// It returns the number of uncompressed bytes written.
+//
+// If src is a sparse *os.File on a platform that supports SEEK_DATA and
+// SEEK_HOLE, each data region is compressed as its own frame and the holes
+// between them are recorded as skippable frames instead of compressed zeros.
+// GoZ4X readers return the holes as zeros and DecompressFile recreates them;
+// other LZ4 decoders skip them, so such output should stay within GoZ4X.
+func CompressFile(dst io.Writer, src io.Reader, options WriterOptions) (int64, error) {
+	if f, ok := src.(*os.File); ok {
+		if start, err := f.Seek(0, io.SeekCurrent); err == nil {
+			if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+				if regions, ok := dataRegions(f, start, fi.Size()); ok {
+					return compressSparse(dst, f, start, fi.Size(), regions, options)
+				}
+			}
+		}
+	}
+
+	w := NewWriterWithOptions(dst, options)
+	if size, ok := sourceSize(src); ok {
+		if err := w.SetContentSize(uint64(size)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return n, err
+	}
+
+	return n, w.Close()
+}
+
+// sourceSize returns the number of bytes left to read from src, if known
+func sourceSize(src io.Reader) (int64, bool) {
+	if s, ok := src.(io.Seeker); ok {
+		cur, err := s.Seek(0, io.SeekCurrent)
+		if err == nil {
+			end, err := s.Seek(0, io.SeekEnd)
+			if err != nil {
+				return 0, false
+			}
+			if _, err := s.Seek(cur, io.SeekStart); err != nil {
+				return 0, false
+			}
+			return end - cur, true
+		}
+	}
+
+	// Files that can't seek are assumed to be read from the start
+	if f, ok := src.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size(), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFrame decodes frame and returns its contents and parsed header
+func readFrame(t *testing.T, frame []byte) ([]byte, frameHeader) {
+	t.Helper()
+
+	r := NewReader(bytes.NewReader(frame))
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return got, r.header
+}
+
+// TestWriterSetContentSize tests declaring the content size before writing
+func TestWriterSetContentSize(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.SetContentSize(uint64(len(data))); err != nil {
+		t.Fatalf("SetContentSize() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, h := readFrame(t, buf.Bytes())
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+	if !h.contentSize || h.contentSizeValue != uint64(len(data)) {
+		t.Errorf("content size = %v/%d, want %d", h.contentSize, h.contentSizeValue, len(data))
+	}
+	// The content size doubles as a size hint
+	if h.blockSizeCode != 5 {
+		t.Errorf("blockSizeCode = %d, want 5", h.blockSizeCode)
+	}
+}
+
+// TestWriterSetContentSizeErrors tests late declarations and size mismatches
+func TestWriterSetContentSizeErrors(t *testing.T) {
+	t.Run("After Write", func(t *testing.T) {
+		w := NewWriter(io.Discard)
+		if _, err := w.Write([]byte("data")); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.SetContentSize(4); err != ErrWriterStarted {
+			t.Errorf("SetContentSize() error = %v, want %v", err, ErrWriterStarted)
+		}
+	})
+
+	for _, n := range []int{10, 30} {
+		w := NewWriter(io.Discard)
+		if err := w.SetContentSize(20); err != nil {
+			t.Fatalf("SetContentSize() error = %v", err)
+		}
+		if _, err := w.Write(make([]byte, n)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != ErrContentSizeMismatch {
+			t.Errorf("Close() after %d of 20 bytes error = %v, want %v", n, err, ErrContentSizeMismatch)
+		}
+	}
+}
+
+// TestWriterResetContentSize tests that a declared content size applies
+// to one frame only
+func TestWriterResetContentSize(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.SetContentSize(5); err != nil {
+		t.Fatalf("SetContentSize() error = %v", err)
+	}
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	data := []byte("a longer second frame")
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() after Reset error = %v", err)
+	}
+	got, h := readFrame(t, buf.Bytes())
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %q, want %q", got, data)
+	}
+	if h.contentSize {
+		t.Errorf("frame after Reset declares content size %d", h.contentSizeValue)
+	}
+}
+
+// TestCompressFile tests content size detection for different sources
+func TestCompressFile(t *testing.T) {
+	data := generateCompressibleData(50 * 1024)
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// A seeker part way through reports the bytes left to read
+	partial := bytes.NewReader(data)
+	if _, err := partial.Seek(1000, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		src      func(t *testing.T) io.Reader
+		want     []byte
+		wantSize bool
+	}{
+		{"File", func(t *testing.T) io.Reader {
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { f.Close() })
+			return f
+		}, data, true},
+		{"Seeker", func(t *testing.T) io.Reader { return bytes.NewReader(data) }, data, true},
+		{"Seeker at offset", func(t *testing.T) io.Reader { return partial }, data[1000:], true},
+		{"Plain reader", func(t *testing.T) io.Reader { return bytes.NewBuffer(data) }, data, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := CompressFile(&buf, tt.src(t), WriterOptions{Level: FastLevel})
+			if err != nil {
+				t.Fatalf("CompressFile() error = %v", err)
+			}
+			if n != int64(len(tt.want)) {
+				t.Errorf("CompressFile() = %d, want %d", n, len(tt.want))
+			}
+
+			got, h := readFrame(t, buf.Bytes())
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(tt.want))
+			}
+			if h.contentSize != tt.wantSize {
+				t.Errorf("contentSize = %v, want %v", h.contentSize, tt.wantSize)
+			}
+			if h.contentSize && h.contentSizeValue != uint64(len(tt.want)) {
+				t.Errorf("contentSizeValue = %d, want %d", h.contentSizeValue, len(tt.want))
+			}
+		})
+	}
+}
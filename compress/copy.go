@@ -0,0 +1,32 @@
+package compress
+
+import (
+	"io"
+)
+
+// Compress compresses everything read from src to dst as a single frame at
+// the given level, the way gzip.Writer is typically used with io.Copy. The
+// frame is finished only if all of src was read and written; on an error it
+// is aborted without an end marker, so a truncated stream can't be mistaken
+// for a complete one. It returns the number of uncompressed bytes read, and
+// ErrInvalidCompressionLevel for levels outside NoCompression to MaxLevel.
+func Compress(dst io.Writer, src io.Reader, level CompressionLevel) (int64, error) {
+	if level < NoCompression || level > MaxLevel {
+		return 0, ErrInvalidCompressionLevel
+	}
+
+	w := NewWriterLevel(dst, level)
+	n, err := io.Copy(w, src)
+	if err != nil {
+		w.Abort()
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// Decompress writes the decompressed contents of the LZ4 stream read from src
+// to dst, including any concatenated frames. It returns the number of
+// decompressed bytes written.
+func Decompress(dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, NewReader(src))
+}
@@ -0,0 +1,68 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// errAfterReader returns err once r is exhausted
+type errAfterReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errAfterReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err == io.EOF {
+		return n, e.err
+	}
+	return n, err
+}
+
+// TestCompressDecompress tests the io.Copy style helpers
+func TestCompressDecompress(t *testing.T) {
+	data := append(generateCompressibleData(5<<20), generateRandomData(1000)...)
+
+	for _, level := range []CompressionLevel{NoCompression, 1, DefaultLevel, MaxLevel} {
+		var frame bytes.Buffer
+		n, err := Compress(&frame, bytes.NewReader(data), level)
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("Compress(level %d) = %d, %v, want %d, nil", level, n, err, len(data))
+		}
+
+		var out bytes.Buffer
+		n, err = Decompress(&out, &frame)
+		if err != nil || n != int64(len(data)) {
+			t.Fatalf("Decompress(level %d) = %d, %v, want %d, nil", level, n, err, len(data))
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("Decompress(level %d) returned the wrong data", level)
+		}
+	}
+}
+
+// TestCompressErrors tests that failures leave no finished frame behind
+func TestCompressErrors(t *testing.T) {
+	data := generateCompressibleData(10000)
+
+	var frame bytes.Buffer
+	if _, err := Compress(&frame, bytes.NewReader(data), MaxLevel+1); err != ErrInvalidCompressionLevel {
+		t.Errorf("Compress() error = %v, want %v", err, ErrInvalidCompressionLevel)
+	}
+
+	errRead := errors.New("read failed")
+	n, err := Compress(&frame, &errAfterReader{bytes.NewReader(data), errRead}, DefaultLevel)
+	if err != errRead || n != int64(len(data)) {
+		t.Errorf("Compress() = %d, %v, want %d, %v", n, err, len(data), errRead)
+	}
+	if bytes.HasSuffix(frame.Bytes(), []byte{0, 0, 0, 0}) {
+		t.Errorf("Compress() wrote an end marker after a read error")
+	}
+
+	sw := &shortWriter{max: 100, limit: 50}
+	if _, err := Compress(sw, bytes.NewReader(data), DefaultLevel); !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Compress() error = %v, want %v", err, io.ErrShortWrite)
+	}
+}
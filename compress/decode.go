@@ -0,0 +1,97 @@
+package compress
+
+import (
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// The block decoder lives in the compress/block package, which programs that
+// only decode blocks can import on its own. The functions below keep their
+// names and behavior here.
+
+// DecompressBlock decompresses an LZ4 compressed block.
+// If dst is nil or too small, a new buffer will be allocated.
+//
+// maxSize bounds the decompressed size. If it is zero or negative the size
+// is unknown: a first pass scans the sequences for the exact decompressed
+// size, up to MaxBlockSize, so that the output is allocated only once.
+// A dst of at least maxSize bytes is decoded into as it is, bounded by its
+// own length instead.
+//
+// Deprecated: maxSize is both a bound and a hint for allocating dst. Use
+// DecompressBlockInto to decode into a buffer, bounded by its length, or
+// DecompressBlockAlloc to decode into a new one.
+func DecompressBlock(src []byte, dst []byte, maxSize int) ([]byte, error) {
+	// Validate input
+	if len(src) == 0 {
+		return nil, errors.New("empty source buffer")
+	}
+
+	// Stored and incompressible blocks are a single run of literals, which
+	// is a plain copy
+	if lits, ok := block.LiteralRun(src); ok {
+		if len(lits) > MaxBlockSize || (maxSize > 0 && len(lits) > maxSize) {
+			return nil, errors.New("decompressed data would exceed maxSize")
+		}
+		if len(dst) < len(lits) {
+			dst = make([]byte, len(lits))
+		}
+		return dst[:copy(dst, lits)], nil
+	}
+
+	if maxSize <= 0 {
+		n, err := block.DecodedLen(src, MaxBlockSize)
+		if err != nil {
+			return nil, err
+		}
+		maxSize = n
+	}
+
+	if dst == nil || len(dst) < maxSize {
+		dst = make([]byte, maxSize)
+	}
+
+	n, err := block.DecompressInto(src, dst)
+	if err == io.ErrShortBuffer {
+		err = errors.New("decompressed data would exceed maxSize")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dst[:n], nil
+}
+
+// DecompressBlockInto decompresses an LZ4 compressed block into dst and
+// returns the number of bytes written. It never allocates: the block must
+// decompress into len(dst) bytes, or it fails with io.ErrShortBuffer.
+func DecompressBlockInto(src, dst []byte) (int, error) {
+	return block.DecompressInto(src, dst)
+}
+
+// DecompressBlockAlloc decompresses an LZ4 compressed block into a new
+// buffer. maxSize bounds the decompressed size, and the buffer is allocated
+// with that size up front. If maxSize is zero or negative the size is
+// unknown: a first pass scans the sequences for the exact decompressed
+// size, up to MaxBlockSize. A block decompressing to more than maxSize
+// bytes fails with io.ErrShortBuffer.
+func DecompressBlockAlloc(src []byte, maxSize int) ([]byte, error) {
+	return block.DecompressAlloc(src, maxSize)
+}
+
+// DecompressBlockFunc decompresses an LZ4 block like DecompressBlock, but
+// hands the output to emit in consecutive chunks instead of returning it.
+// Only the last 64KB, which matches can reach back to, are kept, so any
+// block decodes in a fixed 128KB buffer, straight into a socket, a hash or
+// a ring buffer. A block holding a single run of literals, such as a stored
+// one, is emitted straight from src without any copy.
+//
+// Chunks hold at most 128KB and are only valid during the call to emit,
+// which must copy what it keeps. An error from emit stops decoding and is
+// returned. maxSize bounds the decompressed size; zero or less means
+// MaxBlockSize. It returns the number of bytes emitted, which is less than
+// the block's size only if it also returns an error.
+func DecompressBlockFunc(src []byte, emit func(chunk []byte) error, maxSize int) (int, error) {
+	return block.DecompressFunc(src, emit, maxSize)
+}
@@ -0,0 +1,111 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// literalRun is block.LiteralRun, for tests that name their blocks block
+var literalRun = block.LiteralRun
+
+// TestDecompressBlockFunc tests that the emitted chunks join up to the
+// output of DecompressBlock, in bounded chunks
+func TestDecompressBlockFunc(t *testing.T) {
+	runs := bytes.Repeat([]byte{'a'}, 1<<20)
+	inputs := map[string][]byte{
+		"Small":        []byte("hello hello hello hello hello"),
+		"Compressible": generateCompressibleData(MaxBlockSize),
+		"Runs":         append(runs, generateCompressibleData(300*1024)...),
+		"Random":       generateRandomData(200 * 1024),
+	}
+
+	for name, src := range inputs {
+		t.Run(name, func(t *testing.T) {
+			block, err := CompressBlockLevel(src, nil, DefaultLevel)
+			if err != nil {
+				t.Fatalf("CompressBlockLevel() error = %v", err)
+			}
+
+			var got bytes.Buffer
+			n, err := DecompressBlockFunc(block, func(chunk []byte) error {
+				if len(chunk) == 0 || len(chunk) > 128<<10 {
+					t.Errorf("emitted a chunk of %d bytes", len(chunk))
+				}
+				got.Write(chunk)
+				return nil
+			}, len(src))
+			if err != nil || n != len(src) || !bytes.Equal(got.Bytes(), src) {
+				t.Errorf("DecompressBlockFunc() = %d, %v, emitting %d bytes, want %d matching", n, err, got.Len(), len(src))
+			}
+		})
+	}
+}
+
+// TestDecompressBlockFuncErrors tests that emit errors stop decoding and
+// that bad blocks fail as they do for DecompressBlock
+func TestDecompressBlockFuncErrors(t *testing.T) {
+	src := generateCompressibleData(500 * 1024)
+	block, err := CompressBlockLevel(src, nil, DefaultLevel)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel() error = %v", err)
+	}
+
+	stop := errors.New("stop")
+	var chunks int
+	n, err := DecompressBlockFunc(block, func(chunk []byte) error {
+		chunks++
+		if chunks == 2 {
+			return stop
+		}
+		return nil
+	}, 0)
+	if err != stop || chunks != 2 || n != 128<<10 {
+		t.Errorf("DecompressBlockFunc() stopped by emit = %d, %v after %d chunks, want %d, %v after 2", n, err, chunks, 128<<10, stop)
+	}
+
+	discard := func([]byte) error { return nil }
+	if _, err := DecompressBlockFunc(block, discard, len(src)-1); err == nil {
+		t.Errorf("DecompressBlockFunc() with a small maxSize succeeded")
+	}
+	if _, err := DecompressBlockFunc(block[:len(block)/2], discard, 0); err == nil {
+		t.Errorf("DecompressBlockFunc() of a truncated block succeeded")
+	}
+	if _, err := DecompressBlockFunc(nil, discard, 0); err == nil {
+		t.Errorf("DecompressBlockFunc() of an empty block succeeded")
+	}
+
+	// A stored block is emitted straight from src
+	stored := storeBlock(src[:1000], nil)
+	DecompressBlockFunc(stored, func(chunk []byte) error {
+		if &chunk[0] != &stored[len(stored)-1000] {
+			t.Errorf("stored block was copied before emitting")
+		}
+		return nil
+	}, 0)
+}
+
+// BenchmarkDecompressBlockFunc compares decoding through a callback with
+// DecompressBlock
+func BenchmarkDecompressBlockFunc(b *testing.B) {
+	src := generateCompressibleData(MaxBlockSize)
+	block, _ := CompressBlockLevel(src, nil, DefaultLevel)
+	discard := func([]byte) error { return nil }
+
+	b.Run("Func", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DecompressBlockFunc(block, discard, 0)
+		}
+	})
+	b.Run("Block", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DecompressBlock(block, nil, len(src))
+		}
+	})
+}
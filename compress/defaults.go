@@ -0,0 +1,73 @@
+package compress
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+var (
+	// configuredLevel is the level used by constructors given no level
+	configuredLevel atomic.Int32
+	// configuredWorkers is the number of workers used when none is given,
+	// or 0 for GOMAXPROCS
+	configuredWorkers atomic.Int64
+)
+
+func init() {
+	configuredLevel.Store(int32(DefaultLevel))
+	loadEnvDefaults()
+}
+
+// loadEnvDefaults sets the defaults from the GOZ4X_LEVEL and GOZ4X_WORKERS
+// environment variables, so that operators can tune a fleet without code
+// changes. Invalid values are ignored.
+func loadEnvDefaults() {
+	if v, err := strconv.Atoi(os.Getenv("GOZ4X_LEVEL")); err == nil {
+		SetDefaultLevel(CompressionLevel(v))
+	}
+	if v, err := strconv.Atoi(os.Getenv("GOZ4X_WORKERS")); err == nil {
+		SetDefaultWorkers(v)
+	}
+}
+
+// SetDefaultLevel sets the level that constructors taking no level use, as
+// do options whose Level is zero and NewWriterLevel's fallback for invalid
+// levels. It starts as DefaultLevel, or the GOZ4X_LEVEL environment
+// variable if set. Block functions such as CompressBlock keep using
+// DefaultLevel. It returns ErrInvalidCompressionLevel for levels outside
+// NoCompression to MaxLevel.
+func SetDefaultLevel(level CompressionLevel) error {
+	if level < NoCompression || level > MaxLevel {
+		return ErrInvalidCompressionLevel
+	}
+	configuredLevel.Store(int32(level))
+	return nil
+}
+
+// CurrentDefaultLevel returns the level set by SetDefaultLevel
+func CurrentDefaultLevel() CompressionLevel {
+	return CompressionLevel(configuredLevel.Load())
+}
+
+// SetDefaultWorkers sets the number of workers that parallel compression
+// uses when given none. Zero, the starting value unless the GOZ4X_WORKERS
+// environment variable is set, means GOMAXPROCS. It returns
+// ErrInvalidNumWorkers for negative n.
+func SetDefaultWorkers(n int) error {
+	if n < 0 {
+		return ErrInvalidNumWorkers
+	}
+	configuredWorkers.Store(int64(n))
+	return nil
+}
+
+// CurrentDefaultWorkers returns the number of workers set by
+// SetDefaultWorkers, or GOMAXPROCS if none is set
+func CurrentDefaultWorkers() int {
+	if n := configuredWorkers.Load(); n > 0 {
+		return int(n)
+	}
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,100 @@
+package compress
+
+import (
+	"io"
+	"runtime"
+	"testing"
+)
+
+// restoreDefaults resets the configured defaults when the test ends
+func restoreDefaults(t *testing.T) {
+	t.Cleanup(func() {
+		SetDefaultLevel(DefaultLevel)
+		SetDefaultWorkers(0)
+	})
+}
+
+// TestSetDefaultLevel tests that constructors given no level use the
+// configured default
+func TestSetDefaultLevel(t *testing.T) {
+	restoreDefaults(t)
+
+	if err := SetDefaultLevel(MaxLevel + 1); err != ErrInvalidCompressionLevel {
+		t.Errorf("SetDefaultLevel(%d) error = %v, want %v", MaxLevel+1, err, ErrInvalidCompressionLevel)
+	}
+	if err := SetDefaultLevel(9); err != nil {
+		t.Fatalf("SetDefaultLevel(9) error = %v", err)
+	}
+	if got := CurrentDefaultLevel(); got != 9 {
+		t.Errorf("CurrentDefaultLevel() = %d, want 9", got)
+	}
+
+	tests := []struct {
+		name  string
+		level CompressionLevel
+	}{
+		{"NewWriter", NewWriter(io.Discard).level},
+		{"NewWriterLevel", NewWriterLevel(io.Discard, -1).level},
+		{"NewWriterWithOptions", NewWriterWithOptions(io.Discard, WriterOptions{}).level},
+		{"NewParallelWriter", NewParallelWriter(io.Discard).level},
+		{"NewEncoder", NewEncoder(io.Discard).level},
+	}
+	for _, tt := range tests {
+		if tt.level != 9 {
+			t.Errorf("%s() level = %d, want 9", tt.name, tt.level)
+		}
+	}
+
+	// Explicit levels are kept
+	if got := NewWriterWithOptions(io.Discard, WriterOptions{Level: 3}).level; got != 3 {
+		t.Errorf("NewWriterWithOptions() level = %d, want 3", got)
+	}
+}
+
+// TestSetDefaultWorkers tests the configured number of workers
+func TestSetDefaultWorkers(t *testing.T) {
+	restoreDefaults(t)
+
+	if got := CurrentDefaultWorkers(); got != runtime.GOMAXPROCS(0) {
+		t.Errorf("CurrentDefaultWorkers() = %d, want %d", got, runtime.GOMAXPROCS(0))
+	}
+	if err := SetDefaultWorkers(-1); err != ErrInvalidNumWorkers {
+		t.Errorf("SetDefaultWorkers(-1) error = %v, want %v", err, ErrInvalidNumWorkers)
+	}
+	if err := SetDefaultWorkers(3); err != nil {
+		t.Fatalf("SetDefaultWorkers(3) error = %v", err)
+	}
+	if got := CurrentDefaultWorkers(); got != 3 {
+		t.Errorf("CurrentDefaultWorkers() = %d, want 3", got)
+	}
+}
+
+// TestEnvDefaults tests the environment overrides
+func TestEnvDefaults(t *testing.T) {
+	restoreDefaults(t)
+
+	tests := []struct {
+		level, workers string
+		wantLevel      CompressionLevel
+		wantWorkers    int
+	}{
+		{"11", "5", 11, 5},
+		{"0", "0", NoCompression, runtime.GOMAXPROCS(0)},
+		{"13", "-2", DefaultLevel, runtime.GOMAXPROCS(0)},
+		{"fast", "many", DefaultLevel, runtime.GOMAXPROCS(0)},
+	}
+	for _, tt := range tests {
+		SetDefaultLevel(DefaultLevel)
+		SetDefaultWorkers(0)
+		t.Setenv("GOZ4X_LEVEL", tt.level)
+		t.Setenv("GOZ4X_WORKERS", tt.workers)
+		loadEnvDefaults()
+
+		if got := CurrentDefaultLevel(); got != tt.wantLevel {
+			t.Errorf("GOZ4X_LEVEL=%s: level = %d, want %d", tt.level, got, tt.wantLevel)
+		}
+		if got := CurrentDefaultWorkers(); got != tt.wantWorkers {
+			t.Errorf("GOZ4X_WORKERS=%s: workers = %d, want %d", tt.workers, got, tt.wantWorkers)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+	"github.com/harriteja/GoZ4X/internal/xxh64"
+)
+
+const (
+	// deltaMagic starts every delta produced by CompressDelta
+	deltaMagic = "GZ4D"
+
+	// deltaMaxHashLog bounds the match table of CompressDelta to 16MB
+	deltaMaxHashLog = 22
+)
+
+var (
+	// ErrCorruptDelta indicates a delta that can't be parsed
+	ErrCorruptDelta = errors.New("corrupt delta")
+
+	// ErrDeltaReference indicates a delta applied to a reference other than
+	// the one it was made against
+	ErrDeltaReference = errors.New("delta reference mismatch")
+)
+
+// CompressDelta encodes src as a delta against reference, for shipping new
+// versions of an artifact to holders of an old one. It works like LZ4 block
+// compression with the match window seeded with the whole reference: parts
+// of src found anywhere in reference, or earlier in src, become
+// back-references, so unchanged regions cost a few bytes however far they
+// moved. DecompressDelta needs the same reference to restore src.
+//
+// A delta starts with the magic "GZ4D", the lengths of src and reference as
+// uvarints and the XXH64 hash of reference. LZ4-style sequences follow, but
+// match offsets are uvarint distances back into reference followed by the
+// output rather than 16-bit distances, and lengths too long for the token
+// continue as uvarints rather than runs of 255.
+func CompressDelta(src, reference []byte) ([]byte, error) {
+	if len(src)+len(reference) > math.MaxInt32 {
+		return nil, ErrInvalidBlockSize
+	}
+
+	dst := []byte(deltaMagic)
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	dst = binary.AppendUvarint(dst, uint64(len(reference)))
+	dst = binary.LittleEndian.AppendUint64(dst, xxh64.Checksum(reference))
+	if len(src) == 0 {
+		return dst, nil
+	}
+
+	// Matches are searched in reference and src as one window
+	window := make([]byte, 0, len(reference)+len(src))
+	window = append(append(window, reference...), src...)
+
+	hashLog := min(max(bits.Len(uint(len(window))), 12), deltaMaxHashLog)
+	table := make([]int32, 1<<hashLog)
+	for i := range table {
+		table[i] = -1
+	}
+	hash := func(pos int) uint32 {
+		return (binary.LittleEndian.Uint32(window[pos:]) * 2654435761) >> (32 - hashLog)
+	}
+
+	for pos := 0; pos+MinMatch <= len(reference); pos++ {
+		table[hash(pos)] = int32(pos)
+	}
+
+	anchor := len(reference)
+	for pos := anchor; pos+MinMatch <= len(window); {
+		h := hash(pos)
+		candidate := int(table[h])
+		table[h] = int32(pos)
+
+		if candidate < 0 || binary.LittleEndian.Uint32(window[candidate:]) != binary.LittleEndian.Uint32(window[pos:]) {
+			// Step faster through data that doesn't match
+			pos += 1 + (pos-anchor)>>6
+			continue
+		}
+
+		// Extend the match forwards, then backwards over pending literals
+		length := MinMatch
+		for pos+length < len(window) && window[candidate+length] == window[pos+length] {
+			length++
+		}
+		start := pos
+		for start > anchor && candidate > 0 && window[start-1] == window[candidate-1] {
+			start--
+			candidate--
+			length++
+		}
+
+		// Far short matches cost more than the literals they replace
+		offset := start - candidate
+		if length < 8 && offset >= 1<<14 {
+			pos++
+			continue
+		}
+
+		dst = appendDeltaSequence(dst, window[anchor:start], offset, length)
+		for end := start + length; pos < end; pos++ {
+			if pos+MinMatch <= len(window) {
+				table[hash(pos)] = int32(pos)
+			}
+		}
+		anchor = pos
+	}
+
+	if anchor < len(window) {
+		dst = appendDeltaSequence(dst, window[anchor:], 0, 0)
+	}
+	return dst, nil
+}
+
+// appendDeltaSequence appends a sequence to a delta. A matchLen of 0 emits a
+// final literal-only sequence.
+func appendDeltaSequence(dst []byte, literals []byte, offset, matchLen int) []byte {
+	literalLen := len(literals)
+
+	literalLenCode := min(literalLen, 15)
+	matchLenCode := 0
+	if matchLen > 0 {
+		matchLenCode = min(matchLen-MinMatch, 15)
+	}
+
+	dst = append(dst, byte(literalLenCode<<4|matchLenCode))
+	if literalLen >= 15 {
+		dst = binary.AppendUvarint(dst, uint64(literalLen-15))
+	}
+	dst = append(dst, literals...)
+
+	if matchLen == 0 {
+		return dst
+	}
+
+	dst = binary.AppendUvarint(dst, uint64(offset))
+	if matchLen-MinMatch >= 15 {
+		dst = binary.AppendUvarint(dst, uint64(matchLen-MinMatch-15))
+	}
+	return dst
+}
+
+// DecompressDelta restores the data a delta was made from, given the
+// reference it was made against. It returns ErrDeltaReference if reference
+// isn't that reference and ErrCorruptDelta if the delta is malformed.
+func DecompressDelta(delta, reference []byte) ([]byte, error) {
+	if len(delta) < len(deltaMagic) || string(delta[:len(deltaMagic)]) != deltaMagic {
+		return nil, ErrCorruptDelta
+	}
+	pos := len(deltaMagic)
+
+	size, n := binary.Uvarint(delta[pos:])
+	if n <= 0 || size > math.MaxInt32 {
+		return nil, ErrCorruptDelta
+	}
+	pos += n
+	refSize, n := binary.Uvarint(delta[pos:])
+	if n <= 0 || pos+n+8 > len(delta) {
+		return nil, ErrCorruptDelta
+	}
+	pos += n
+	if refSize != uint64(len(reference)) || binary.LittleEndian.Uint64(delta[pos:]) != xxh64.Checksum(reference) {
+		return nil, ErrDeltaReference
+	}
+	pos += 8
+
+	// Decode after a copy of the reference so offsets can reach into it. The
+	// declared size can't be trusted to reserve memory up front.
+	out := make([]byte, len(reference), len(reference)+min(int(size), 64<<20))
+	copy(out, reference)
+	limit := len(reference) + int(size)
+
+	for pos < len(delta) {
+		token := delta[pos]
+		pos++
+
+		literalLen, ok := readDeltaLength(delta, &pos, int(token>>4))
+		if !ok || literalLen > len(delta)-pos || literalLen > limit-len(out) {
+			return nil, ErrCorruptDelta
+		}
+		out = append(out, delta[pos:pos+literalLen]...)
+		pos += literalLen
+		if pos == len(delta) {
+			break
+		}
+
+		offset, n := binary.Uvarint(delta[pos:])
+		if n <= 0 || offset == 0 || offset > uint64(len(out)) {
+			return nil, ErrCorruptDelta
+		}
+		pos += n
+		matchLen, ok := readDeltaLength(delta, &pos, int(token&0xF))
+		if !ok || matchLen+MinMatch > limit-len(out) {
+			return nil, ErrCorruptDelta
+		}
+		matchLen += MinMatch
+
+		out = block.AppendMatch(out, int(offset), matchLen)
+	}
+
+	if len(out) != limit {
+		return nil, ErrCorruptDelta
+	}
+	return out[len(reference):], nil
+}
+
+// readDeltaLength reads the uvarint extending a length whose token nibble
+// is code
+func readDeltaLength(delta []byte, pos *int, code int) (int, bool) {
+	if code != 15 {
+		return code, true
+	}
+	extra, n := binary.Uvarint(delta[*pos:])
+	if n <= 0 || extra > math.MaxInt32 {
+		return 0, false
+	}
+	*pos += n
+	return code + int(extra), true
+}
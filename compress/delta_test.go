@@ -0,0 +1,101 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDeltaRoundTrip tests restoring data from deltas against references
+func TestDeltaRoundTrip(t *testing.T) {
+	reference := generateRandomData(512 * 1024)
+
+	edited := bytes.Clone(reference)
+	copy(edited[1000:], "patched")
+	edited = append(edited[:300000], append([]byte("inserted in the middle"), edited[300000:]...)...)
+
+	moved := append(bytes.Clone(reference[256*1024:]), reference[:256*1024]...)
+
+	tests := []struct {
+		name      string
+		src       []byte
+		reference []byte
+		maxSize   int // Upper bound on the delta size, 0 for no check
+	}{
+		{"Empty Both", nil, nil, 0},
+		{"Empty Source", nil, reference, 0},
+		{"Empty Reference", generateCompressibleData(100 * 1024), nil, 128},
+		{"Identical", reference, reference, 64},
+		{"Edited", edited, reference, 256},
+		{"Moved Halves", moved, reference, 64},
+		{"Unrelated", generateRandomData(64 * 1024), reference, 0},
+		{"Short", []byte("abc"), []byte("abcdef"), 0},
+		{"Overlapping Matches", bytes.Repeat([]byte("ab"), 10000), []byte("a"), 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, err := CompressDelta(tt.src, tt.reference)
+			if err != nil {
+				t.Fatalf("CompressDelta() error = %v", err)
+			}
+			if tt.maxSize > 0 && len(delta) > tt.maxSize {
+				t.Errorf("delta is %d bytes, want at most %d", len(delta), tt.maxSize)
+			}
+
+			got, err := DecompressDelta(delta, tt.reference)
+			if err != nil {
+				t.Fatalf("DecompressDelta() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.src) {
+				t.Errorf("restored %d bytes, want %d", len(got), len(tt.src))
+			}
+		})
+	}
+}
+
+// TestDeltaWrongReference tests applying a delta to another reference
+func TestDeltaWrongReference(t *testing.T) {
+	reference := generateCompressibleData(10000)
+	delta, err := CompressDelta(append([]byte("new "), reference...), reference)
+	if err != nil {
+		t.Fatalf("CompressDelta() error = %v", err)
+	}
+
+	other := bytes.Clone(reference)
+	other[5000] ^= 1
+	for _, ref := range [][]byte{other, reference[1:], nil} {
+		if _, err := DecompressDelta(delta, ref); err != ErrDeltaReference {
+			t.Errorf("DecompressDelta() error = %v, want %v", err, ErrDeltaReference)
+		}
+	}
+}
+
+// TestDeltaCorrupt tests that damaged deltas are rejected without panicking
+func TestDeltaCorrupt(t *testing.T) {
+	reference := generateRandomData(4096)
+	src := append(generateCompressibleData(3000), reference[100:2000]...)
+	delta, err := CompressDelta(src, reference)
+	if err != nil {
+		t.Fatalf("CompressDelta() error = %v", err)
+	}
+
+	if _, err := DecompressDelta([]byte("GZ4"), reference); err != ErrCorruptDelta {
+		t.Errorf("DecompressDelta() error = %v, want %v", err, ErrCorruptDelta)
+	}
+
+	// Truncations before the reference hash fails to parse the header; any
+	// other damage must be reported rather than restoring wrong data
+	for n := 0; n < len(delta); n++ {
+		got, err := DecompressDelta(delta[:n], reference)
+		if err == nil {
+			t.Fatalf("DecompressDelta() of %d bytes succeeded with %d bytes", n, len(got))
+		}
+	}
+	for i := len(deltaMagic); i < len(delta); i++ {
+		damaged := bytes.Clone(delta)
+		damaged[i] ^= 0xFF
+		if got, err := DecompressDelta(damaged, reference); err == nil && bytes.Equal(got, src) {
+			t.Fatalf("damage at %d went unnoticed", i)
+		}
+	}
+}
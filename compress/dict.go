@@ -0,0 +1,246 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// maxDictWindow is the part of a dictionary blocks can reference: the
+// furthest back an LZ4 match offset reaches
+const maxDictWindow = MaxDistance
+
+var (
+	// ErrUnknownDictionary indicates a frame compressed with a dictionary
+	// that the reader's DictionaryStore doesn't hold
+	ErrUnknownDictionary = errors.New("unknown dictionary")
+
+	// ErrDictionaryRequired indicates a frame compressed with a dictionary
+	// that the reader has no way to look up, or that its ResolveDictionary
+	// didn't return. It wraps ErrUnknownDictionary.
+	ErrDictionaryRequired = fmt.Errorf("%w: frame requires a dictionary", ErrUnknownDictionary)
+
+	// ErrDictionaryExists indicates a dictionary ID that is already in use
+	ErrDictionaryExists = errors.New("dictionary ID already in use")
+)
+
+// Dictionary is a dictionary together with the ID frames refer to it by
+type Dictionary struct {
+	ID   uint32
+	Data []byte
+}
+
+// DictionaryResolver returns the dictionary with the given ID, for frames
+// that name one. It returns nil and no error if it has no such dictionary.
+type DictionaryResolver func(id uint32) ([]byte, error)
+
+// DictionaryStore holds the dictionaries writers compress with and readers
+// look up by the dictionary ID in frame headers. Implementations must be
+// safe for concurrent use.
+type DictionaryStore interface {
+	// Dictionary returns the dictionary data with the given ID
+	Dictionary(id uint32) ([]byte, bool)
+	// Current returns the dictionary new frames should be compressed with,
+	// or false to compress without one
+	Current() (Dictionary, bool)
+}
+
+// MemoryDictionaryStore is an in-memory DictionaryStore with versioned IDs.
+// Publishing a retrained dictionary makes it current atomically: frames
+// started afterwards use it, while frames already being written keep the
+// dictionary they started with, and readers can still look up older
+// versions until they are retired.
+type MemoryDictionaryStore struct {
+	mu      sync.RWMutex
+	dicts   map[uint32][]byte
+	current uint32
+	hasCur  bool
+	lastID  uint32
+}
+
+// NewMemoryDictionaryStore returns an empty MemoryDictionaryStore
+func NewMemoryDictionaryStore() *MemoryDictionaryStore {
+	return &MemoryDictionaryStore{dicts: make(map[uint32][]byte)}
+}
+
+// Publish adds data as the next version of the dictionary, makes it current
+// and returns its ID. Versions are numbered from 1 upwards, after the
+// highest ID in the store. The store keeps a reference to data, which must
+// not be modified afterwards.
+func (s *MemoryDictionaryStore) Publish(data []byte) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	s.dicts[s.lastID] = data
+	s.current = s.lastID
+	s.hasCur = true
+	return s.lastID
+}
+
+// Add registers data under a known ID, such as a dictionary persisted by an
+// earlier process, without making it current. It returns ErrDictionaryExists
+// if the ID is already in use.
+func (s *MemoryDictionaryStore) Add(id uint32, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.dicts[id]; ok {
+		return ErrDictionaryExists
+	}
+	s.dicts[id] = data
+	if id > s.lastID {
+		s.lastID = id
+	}
+	return nil
+}
+
+// SetCurrent makes the dictionary with the given ID current. It returns
+// ErrUnknownDictionary if there is no such dictionary.
+func (s *MemoryDictionaryStore) SetCurrent(id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.dicts[id]; !ok {
+		return ErrUnknownDictionary
+	}
+	s.current = id
+	s.hasCur = true
+	return nil
+}
+
+// Retire removes the dictionary with the given ID, once no stored frames
+// refer to it any more. Retiring the current dictionary leaves new frames
+// without one until another is published or made current.
+func (s *MemoryDictionaryStore) Retire(id uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.dicts, id)
+	if s.hasCur && s.current == id {
+		s.hasCur = false
+	}
+}
+
+// Dictionary implements DictionaryStore
+func (s *MemoryDictionaryStore) Dictionary(id uint32) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.dicts[id]
+	return data, ok
+}
+
+// Current implements DictionaryStore
+func (s *MemoryDictionaryStore) Current() (Dictionary, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasCur {
+		return Dictionary{}, false
+	}
+	return Dictionary{ID: s.current, Data: s.dicts[s.current]}, true
+}
+
+// dictWindow returns the part of dict that matches can reach
+func dictWindow(dict []byte) []byte {
+	if len(dict) > maxDictWindow {
+		return dict[len(dict)-maxDictWindow:]
+	}
+	return dict
+}
+
+// CompressBlockDict compresses src like CompressBlockLevel, with matches
+// allowed to reference the last 64KB of dict as if it preceded src.
+// If dst is nil or too small, a new buffer will be allocated.
+func CompressBlockDict(src, dst, dict []byte, level CompressionLevel) ([]byte, error) {
+	if len(dict) == 0 {
+		return CompressBlockLevel(src, dst, level)
+	}
+	c, err := NewCompressor(level)
+	if err != nil {
+		return nil, err
+	}
+	return c.CompressBlockDict(src, dst, dict)
+}
+
+// DecompressBlockDict decompresses a block compressed with CompressBlockDict
+// and the same dict. maxSize bounds the decompressed size.
+func DecompressBlockDict(src, dict []byte, maxSize int) ([]byte, error) {
+	return decompressBlockDict(src, nil, dict, maxSize)
+}
+
+// decompressBlockDict is DecompressBlockDict decoding into dst, which is
+// replaced by a new buffer if it is too small
+func decompressBlockDict(src, dst, dict []byte, maxSize int) ([]byte, error) {
+	if len(dict) == 0 {
+		return DecompressBlock(src, dst, maxSize)
+	}
+	if len(src) == 0 {
+		return nil, errors.New("empty source buffer")
+	}
+
+	// Decode after a copy of the dictionary so offsets can reach into it
+	dict = dictWindow(dict)
+	out := dst[:0]
+	if limit := len(dict) + maxSize; cap(out) < limit {
+		out = make([]byte, 0, limit)
+	}
+	out, err := block.DecompressAppend(append(out, dict...), src, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return out[len(dict):], nil
+}
+
+// loadDictionary snapshots the current dictionary of the store for a new
+// frame and records its ID in the frame header
+func (z *Writer) loadDictionary() {
+	if z.dicts == nil {
+		return
+	}
+
+	d, ok := z.dicts.Current()
+	z.header.dictID = ok
+	z.header.dictIDValue = d.ID
+	z.dict = d.Data
+}
+
+// dictionary returns the dictionary the current frame was compressed with,
+// from the store or else from the resolver. It is looked up once per
+// dictionary ID in a row of frames.
+func (r *Reader) dictionary() ([]byte, error) {
+	if !r.header.dictID {
+		return nil, nil
+	}
+	id := r.header.dictIDValue
+	if r.dict != nil && r.dictID == id {
+		return r.dict, nil
+	}
+	if r.dicts == nil && r.resolveDict == nil {
+		return nil, ErrDictionaryRequired
+	}
+
+	dict, ok := []byte(nil), false
+	if r.dicts != nil {
+		dict, ok = r.dicts.Dictionary(id)
+	}
+	if !ok && r.resolveDict != nil {
+		d, err := r.resolveDict(id)
+		if err != nil {
+			return nil, fmt.Errorf("resolving dictionary %d: %w", id, err)
+		}
+		if d == nil {
+			return nil, ErrDictionaryRequired
+		}
+		dict, ok = d, true
+	}
+	if !ok {
+		return nil, ErrUnknownDictionary
+	}
+	r.dict = dict
+	r.dictID = id
+	return dict, nil
+}
@@ -0,0 +1,312 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// generateRecords returns small JSON-like records that share most of their
+// structure, the data dictionaries help with
+func generateRecords(n, seed int) [][]byte {
+	records := make([][]byte, n)
+	for i := range records {
+		records[i] = []byte(fmt.Sprintf(`{"id":%d,"user":"user-%d","status":"active","region":"eu-west-1","tags":["alpha","beta"],"score":%d}`,
+			seed+i, (seed+i)*7, (seed+i)*13%1000))
+	}
+	return records
+}
+
+// dictionaryFrom concatenates records into a dictionary
+func dictionaryFrom(records [][]byte) []byte {
+	return bytes.Join(records, nil)
+}
+
+// TestBlockDictRoundTrip tests block compression with a dictionary
+func TestBlockDictRoundTrip(t *testing.T) {
+	dict := dictionaryFrom(generateRecords(200, 0))
+	large := append(generateRandomData(80*1024), dict...)
+
+	tests := []struct {
+		name string
+		src  []byte
+		dict []byte
+	}{
+		{"Record", generateRecords(1, 5000)[0], dict},
+		{"No Dictionary", generateRecords(1, 5000)[0], nil},
+		{"Compressible", generateCompressibleData(100 * 1024), dict},
+		{"Random", generateRandomData(4096), dict},
+		{"Large Dictionary", dict, large},
+	}
+
+	for _, tt := range tests {
+		for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+			t.Run(fmt.Sprintf("%s/Level %d", tt.name, level), func(t *testing.T) {
+				compressed, err := CompressBlockDict(tt.src, nil, tt.dict, level)
+				if err != nil {
+					t.Fatalf("CompressBlockDict() error = %v", err)
+				}
+				got, err := DecompressBlockDict(compressed, tt.dict, len(tt.src))
+				if err != nil {
+					t.Fatalf("DecompressBlockDict() error = %v", err)
+				}
+				if !bytes.Equal(got, tt.src) {
+					t.Errorf("decompressed %d bytes, want %d", len(got), len(tt.src))
+				}
+			})
+		}
+	}
+}
+
+// TestBlockDictRatio tests that a dictionary shrinks small records
+func TestBlockDictRatio(t *testing.T) {
+	dict := dictionaryFrom(generateRecords(200, 0))
+	var plain, withDict int
+	for _, record := range generateRecords(50, 10000) {
+		a, err := CompressBlockLevel(record, nil, DefaultLevel)
+		if err != nil {
+			t.Fatalf("CompressBlockLevel() error = %v", err)
+		}
+		b, err := CompressBlockDict(record, nil, dict, DefaultLevel)
+		if err != nil {
+			t.Fatalf("CompressBlockDict() error = %v", err)
+		}
+		plain += len(a)
+		withDict += len(b)
+	}
+	if withDict*2 > plain {
+		t.Errorf("records compressed to %d bytes with a dictionary, want at most half of %d", withDict, plain)
+	}
+}
+
+// TestBlockDictWrongDictionary tests decoding with a different dictionary
+func TestBlockDictWrongDictionary(t *testing.T) {
+	dict := dictionaryFrom(generateRecords(200, 0))
+	record := generateRecords(1, 5000)[0]
+	compressed, err := CompressBlockDict(record, nil, dict, DefaultLevel)
+	if err != nil {
+		t.Fatalf("CompressBlockDict() error = %v", err)
+	}
+	if got, err := DecompressBlockDict(compressed, dict[:100], len(record)); err == nil && bytes.Equal(got, record) {
+		t.Errorf("DecompressBlockDict() restored the record with the wrong dictionary")
+	}
+}
+
+// writeDictFrame compresses data into a frame with dictionaries from store
+func writeDictFrame(t *testing.T, data []byte, store DictionaryStore) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{Dictionaries: store, BlockSize: 64 * 1024})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// readDictFrame decompresses a frame with dictionaries from store
+func readDictFrame(frame []byte, store DictionaryStore) ([]byte, error) {
+	return io.ReadAll(NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{Dictionaries: store}))
+}
+
+// frameDictID returns the dictionary ID in the header of frame
+func frameDictID(t *testing.T, frame []byte) (uint32, bool) {
+	t.Helper()
+
+	r := NewReader(bytes.NewReader(frame))
+	if err := r.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	return r.header.dictIDValue, r.header.dictID
+}
+
+// TestDictionaryStoreFrames tests frames compressed with published
+// dictionaries, across a hot swap
+func TestDictionaryStoreFrames(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	data := dictionaryFrom(generateRecords(2000, 100000))
+
+	// Without a current dictionary frames carry no dictionary ID
+	frame := writeDictFrame(t, data, store)
+	if id, ok := frameDictID(t, frame); ok {
+		t.Errorf("frame has dictionary ID %d, want none", id)
+	}
+
+	v1 := store.Publish(dictionaryFrom(generateRecords(200, 0)))
+	frame1 := writeDictFrame(t, data, store)
+	v2 := store.Publish(dictionaryFrom(generateRecords(200, 50000)))
+	frame2 := writeDictFrame(t, data, store)
+	if v1 != 1 || v2 != 2 {
+		t.Errorf("Publish() = %d, %d, want 1, 2", v1, v2)
+	}
+
+	for _, tt := range []struct {
+		frame []byte
+		id    uint32
+	}{{frame1, v1}, {frame2, v2}} {
+		got, err := readDictFrame(tt.frame, store)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("read %d bytes, want %d", len(got), len(data))
+		}
+		if id, ok := frameDictID(t, tt.frame); !ok || id != tt.id {
+			t.Errorf("frame dictionary ID = %d, %v, want %d", id, ok, tt.id)
+		}
+	}
+
+	// Reading both frames back to back switches dictionaries
+	got, err := readDictFrame(append(bytes.Clone(frame1), frame2...), store)
+	if err != nil || !bytes.Equal(got, append(bytes.Clone(data), data...)) {
+		t.Errorf("reading concatenated frames = %d bytes, %v", len(got), err)
+	}
+
+	if _, err := readDictFrame(frame1, nil); err != ErrDictionaryRequired || !errors.Is(err, ErrUnknownDictionary) {
+		t.Errorf("Read() without a store error = %v, want %v", err, ErrDictionaryRequired)
+	}
+	store.Retire(v1)
+	if _, err := readDictFrame(frame1, store); err != ErrUnknownDictionary {
+		t.Errorf("Read() after Retire() error = %v, want %v", err, ErrUnknownDictionary)
+	}
+	if _, err := readDictFrame(frame2, store); err != nil {
+		t.Errorf("Read() of the current version error = %v", err)
+	}
+}
+
+// TestResolveDictionary tests looking up the dictionaries named by frames
+// with ReaderOptions.ResolveDictionary
+func TestResolveDictionary(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	dict := dictionaryFrom(generateRecords(200, 0))
+	id := store.Publish(dict)
+	data := dictionaryFrom(generateRecords(2000, 100000))
+	frame := writeDictFrame(t, data, store)
+	frames := append(bytes.Clone(frame), frame...)
+
+	read := func(frames []byte, store DictionaryStore, resolve DictionaryResolver) ([]byte, error) {
+		return io.ReadAll(NewReaderWithOptions(bytes.NewReader(frames), ReaderOptions{
+			Dictionaries:      store,
+			ResolveDictionary: resolve,
+		}))
+	}
+
+	// Without a store the resolver is asked once for both frames
+	var calls []uint32
+	got, err := read(frames, nil, func(id uint32) ([]byte, error) {
+		calls = append(calls, id)
+		return dict, nil
+	})
+	if err != nil || !bytes.Equal(got, append(bytes.Clone(data), data...)) {
+		t.Errorf("Read() = %d bytes, %v", len(got), err)
+	}
+	if len(calls) != 1 || calls[0] != id {
+		t.Errorf("ResolveDictionary() calls = %v, want [%d]", calls, id)
+	}
+
+	// With a store holding the dictionary the resolver isn't asked
+	calls = nil
+	if _, err := read(frame, store, func(id uint32) ([]byte, error) {
+		calls = append(calls, id)
+		return nil, nil
+	}); err != nil || len(calls) != 0 {
+		t.Errorf("Read() with a store error = %v, resolver calls = %v", err, calls)
+	}
+
+	// The resolver is asked for what the store doesn't hold
+	if _, err := read(frame, NewMemoryDictionaryStore(), func(uint32) ([]byte, error) {
+		return dict, nil
+	}); err != nil {
+		t.Errorf("Read() falling back to the resolver error = %v", err)
+	}
+
+	if _, err := read(frame, nil, func(uint32) ([]byte, error) { return nil, nil }); err != ErrDictionaryRequired {
+		t.Errorf("Read() without a resolved dictionary error = %v, want %v", err, ErrDictionaryRequired)
+	}
+	errMissing := errors.New("registry unavailable")
+	if _, err := read(frame, nil, func(uint32) ([]byte, error) { return nil, errMissing }); !errors.Is(err, errMissing) {
+		t.Errorf("Read() with a failing resolver error = %v, want %v", err, errMissing)
+	}
+}
+
+// TestMemoryDictionaryStore tests adding, selecting and retiring dictionaries
+func TestMemoryDictionaryStore(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	if _, ok := store.Current(); ok {
+		t.Errorf("Current() of an empty store = true, want false")
+	}
+	if err := store.SetCurrent(1); err != ErrUnknownDictionary {
+		t.Errorf("SetCurrent() error = %v, want %v", err, ErrUnknownDictionary)
+	}
+
+	if err := store.Add(7, []byte("persisted")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := store.Add(7, []byte("again")); err != ErrDictionaryExists {
+		t.Errorf("Add() error = %v, want %v", err, ErrDictionaryExists)
+	}
+	if _, ok := store.Current(); ok {
+		t.Errorf("Current() after Add() = true, want false")
+	}
+
+	// New versions are numbered after the highest ID added
+	if id := store.Publish([]byte("retrained")); id != 8 {
+		t.Errorf("Publish() = %d, want 8", id)
+	}
+	if err := store.SetCurrent(7); err != nil {
+		t.Fatalf("SetCurrent() error = %v", err)
+	}
+	if d, ok := store.Current(); !ok || d.ID != 7 || string(d.Data) != "persisted" {
+		t.Errorf("Current() = %d %q, %v, want 7 \"persisted\"", d.ID, d.Data, ok)
+	}
+
+	store.Retire(7)
+	if _, ok := store.Dictionary(7); ok {
+		t.Errorf("Dictionary() after Retire() = true, want false")
+	}
+	if _, ok := store.Current(); ok {
+		t.Errorf("Current() after retiring it = true, want false")
+	}
+	if data, ok := store.Dictionary(8); !ok || string(data) != "retrained" {
+		t.Errorf("Dictionary(8) = %q, %v, want \"retrained\"", data, ok)
+	}
+}
+
+// TestDictionaryHotSwap tests publishing dictionaries while frames are
+// written and read concurrently
+func TestDictionaryHotSwap(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	store.Publish(dictionaryFrom(generateRecords(200, 0)))
+	data := dictionaryFrom(generateRecords(500, 100000))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				frame := writeDictFrame(t, data, store)
+				got, err := readDictFrame(frame, store)
+				if err != nil || !bytes.Equal(got, data) {
+					errs <- fmt.Errorf("round trip = %d bytes, %v", len(got), err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 20; i++ {
+		store.Publish(dictionaryFrom(generateRecords(200, i*1000)))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
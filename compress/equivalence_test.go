@@ -0,0 +1,178 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// frameCase is a random input and configuration for comparing the serial
+// and parallel writers
+type frameCase struct {
+	Data            []byte
+	Level           CompressionLevel
+	BlockSize       int
+	Workers         int
+	ContentChecksum bool
+	UseV2           bool
+	// WriteSize is the size of the writes the data is split into
+	WriteSize int
+}
+
+// Generate implements quick.Generator with data that mixes runs of
+// compressible and random bytes
+func (frameCase) Generate(r *rand.Rand, size int) reflect.Value {
+	var data []byte
+	total := r.Intn(300 * 1024)
+	for len(data) < total {
+		n := min(1+r.Intn(64*1024), total-len(data))
+		switch r.Intn(3) {
+		case 0:
+			data = append(data, generateCompressibleData(n)...)
+		case 1:
+			data = append(data, bytes.Repeat([]byte{byte(r.Intn(256))}, n)...)
+		default:
+			chunk := make([]byte, n)
+			r.Read(chunk)
+			data = append(data, chunk...)
+		}
+	}
+
+	blockSizes := []int{0, MinBlockSize, 64 * 1024, 100 * 1024, 256 * 1024}
+	return reflect.ValueOf(frameCase{
+		Data:            data,
+		Level:           CompressionLevel(r.Intn(int(MaxLevel) + 1)),
+		BlockSize:       blockSizes[r.Intn(len(blockSizes))],
+		Workers:         1 + r.Intn(8),
+		ContentChecksum: r.Intn(2) == 0,
+		UseV2:           r.Intn(2) == 0,
+		WriteSize:       1 + r.Intn(200*1024),
+	})
+}
+
+func (c frameCase) String() string {
+	return fmt.Sprintf("%d bytes, level %d, block size %d, %d workers, checksum %v, V2 %v, writes of %d",
+		len(c.Data), c.Level, c.BlockSize, c.Workers, c.ContentChecksum, c.UseV2, c.WriteSize)
+}
+
+// writeInPieces writes c.Data to w in writes of c.WriteSize and closes it
+func (c frameCase) writeInPieces(w io.WriteCloser) error {
+	for data := c.Data; len(data) > 0; {
+		n := min(c.WriteSize, len(data))
+		if _, err := w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return w.Close()
+}
+
+// serial compresses the case with a Writer
+func (c frameCase) serial() ([]byte, error) {
+	var buf bytes.Buffer
+	err := c.writeInPieces(NewWriterWithOptions(&buf, WriterOptions{
+		Level:           c.Level,
+		Store:           c.Level == NoCompression,
+		BlockSize:       c.BlockSize,
+		ContentChecksum: c.ContentChecksum,
+	}))
+	return buf.Bytes(), err
+}
+
+// parallel compresses the case with a ParallelWriter using workers workers
+func (c frameCase) parallel(workers int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := c.writeInPieces(NewParallelWriterWithOptions(&buf, ParallelWriterOptions{
+		Level:           c.Level,
+		Store:           c.Level == NoCompression,
+		UseV2:           c.UseV2,
+		BlockSize:       c.BlockSize,
+		ContentChecksum: c.ContentChecksum,
+		NumWorkers:      workers,
+	}))
+	return buf.Bytes(), err
+}
+
+// strictDecode decodes frame with HeaderStrict
+func strictDecode(frame []byte) ([]byte, error) {
+	return io.ReadAll(NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{HeaderMode: HeaderStrict}))
+}
+
+// checkEquivalence checks that the serial and parallel writers both produce
+// strictly valid frames of the data, and that the parallel output doesn't
+// depend on the number of workers
+func checkEquivalence(c frameCase) error {
+	serial, err := c.serial()
+	if err != nil {
+		return fmt.Errorf("serial: %v", err)
+	}
+	parallel, err := c.parallel(c.Workers)
+	if err != nil {
+		return fmt.Errorf("parallel: %v", err)
+	}
+	single, err := c.parallel(1)
+	if err != nil {
+		return fmt.Errorf("parallel with 1 worker: %v", err)
+	}
+	if !bytes.Equal(parallel, single) {
+		return fmt.Errorf("parallel output with %d workers differs from 1 worker", c.Workers)
+	}
+
+	for name, frame := range map[string][]byte{"serial": serial, "parallel": parallel} {
+		got, err := strictDecode(frame)
+		if err != nil {
+			return fmt.Errorf("%s: decode: %v", name, err)
+		}
+		if !bytes.Equal(got, c.Data) {
+			return fmt.Errorf("%s: decoded %d bytes, want %d matching bytes", name, len(got), len(c.Data))
+		}
+	}
+	return nil
+}
+
+// TestParallelSerialEquivalence tests, for random inputs and options, that
+// serial and parallel frames decode to the same data under the strict
+// decoder
+func TestParallelSerialEquivalence(t *testing.T) {
+	count := 15
+	if testing.Short() {
+		count = 5
+	}
+
+	property := func(c frameCase) bool {
+		if err := checkEquivalence(c); err != nil {
+			t.Logf("%v: %v", c, err)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: count}); err != nil {
+		t.Error(err)
+	}
+}
+
+// FuzzParallelSerialEquivalence checks the property of
+// TestParallelSerialEquivalence on fuzzed data
+func FuzzParallelSerialEquivalence(f *testing.F) {
+	f.Add([]byte{}, uint8(6), uint8(4), uint16(100))
+	f.Add([]byte("hello hello hello hello hello"), uint8(1), uint8(2), uint16(3))
+	f.Add(generateCompressibleData(100*1024), uint8(12), uint8(8), uint16(5000))
+	f.Add(generateRandomData(70*1024), uint8(0), uint8(3), uint16(65535))
+
+	f.Fuzz(func(t *testing.T, data []byte, level, workers uint8, writeSize uint16) {
+		c := frameCase{
+			Data:      data,
+			Level:     CompressionLevel(level % uint8(MaxLevel+1)),
+			BlockSize: MinBlockSize,
+			Workers:   1 + int(workers%8),
+			WriteSize: 1 + int(writeSize),
+		}
+		if err := checkEquivalence(c); err != nil {
+			t.Fatalf("%v: %v", c, err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package compress
+
+import (
+	"errors"
+	"io"
+)
+
+// Some decoders, such as those of FPGAs and microcontrollers, read at most
+// a fixed number of extension bytes after a 4-bit length field. With the
+// MaxExtLenBytes option of WriterOptions or BlockOptions, compressed blocks
+// are rewritten for them by LimitExtLen: a match too long for the limit is
+// split into several matches at the same offset, each after zero literals,
+// which decode to the same bytes. A run of literals can't be split, since
+// only a match may separate two of them, so a block holding a longer run
+// fails with ErrExtLenLimit. The Writer stores such blocks uncompressed.
+
+// ErrExtLenLimit indicates a block holding a run of literals too long to
+// encode within MaxExtLenBytes extension bytes
+var ErrExtLenLimit = errors.New("literal run too long for MaxExtLenBytes")
+
+// extLenMax returns the largest length remainder encoded in a 4-bit field
+// and at most n extension bytes: all but the last of them are 255, and the
+// last one is less
+func extLenMax(n int) int {
+	return 15 + 255*n - 1
+}
+
+// LimitExtLen rewrites the compressed block src into dst so that no length
+// needs more than maxExtLenBytes extension bytes, splitting long matches.
+// It fails with ErrExtLenLimit if a run of literals is too long, and with
+// ErrCorruptBlock if src can't be parsed. A maxExtLenBytes of zero or less
+// means no limit, returning a copy of src. If dst is too small, a new
+// buffer is allocated.
+func LimitExtLen(src []byte, maxExtLenBytes int, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	if maxExtLenBytes <= 0 {
+		return append(dst, src...), nil
+	}
+
+	maxLiterals := extLenMax(maxExtLenBytes)
+	maxMatch := maxLiterals + MinMatch
+	sr := NewSequenceReader(src)
+	for {
+		seq, err := sr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return dst, nil
+			}
+			return nil, err
+		}
+		if len(seq.Literals) > maxLiterals {
+			return nil, ErrExtLenLimit
+		}
+
+		literals := seq.Literals
+		for rest := seq.MatchLen; rest > maxMatch; {
+			// The last match must start mfLimit bytes before the end of
+			// the block, mfLimit - lastLiterals before the end of the
+			// match it was split from
+			n := min(maxMatch, rest-(mfLimit-lastLiterals))
+			dst = appendSequence(dst, literals, seq.Offset, n)
+			literals = nil
+			rest -= n
+			seq.MatchLen = rest
+		}
+		dst = appendSequence(dst, literals, seq.Offset, seq.MatchLen)
+	}
+}
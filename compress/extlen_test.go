@@ -0,0 +1,144 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// checkExtLen checks that the sequences of block need at most maxExt
+// extension bytes per length, and that its last match starts mfLimit bytes
+// before the end of the decoded block
+func checkExtLen(t *testing.T, block []byte, maxExt, size int) {
+	t.Helper()
+	sr := NewSequenceReader(block)
+	var pos, lastMatch int
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if n := len(seq.Literals); n > extLenMax(maxExt) {
+			t.Fatalf("%d literals at %d, want at most %d", n, seq.Pos, extLenMax(maxExt))
+		}
+		if n := seq.MatchLen - MinMatch; n > extLenMax(maxExt) {
+			t.Fatalf("match of %d at %d, want at most %d", seq.MatchLen, seq.Pos, extLenMax(maxExt)+MinMatch)
+		}
+		pos += len(seq.Literals)
+		if seq.MatchLen > 0 {
+			lastMatch = pos
+		}
+		pos += seq.MatchLen
+	}
+	if lastMatch > size-mfLimit {
+		t.Errorf("last match starts at %d of %d bytes, want at most %d", lastMatch, size, size-mfLimit)
+	}
+}
+
+// TestLimitExtLen tests rewriting blocks of each encoder for a limited
+// number of extension bytes
+func TestLimitExtLen(t *testing.T) {
+	data := append(generateRunData(200*1024), generateCompressibleData(100*1024)...)
+
+	encoders := []struct {
+		name     string
+		compress func(src []byte) ([]byte, error)
+	}{
+		{"Fast", func(src []byte) ([]byte, error) { return CompressBlockLevel(src, nil, 1) }},
+		{"HC", func(src []byte) ([]byte, error) { return CompressBlockLevel(src, nil, MaxLevel) }},
+		{"V2", func(src []byte) ([]byte, error) { return CompressBlockV2(src, nil) }},
+	}
+
+	for _, enc := range encoders {
+		block, err := enc.compress(data)
+		if err != nil {
+			t.Fatalf("%s: compress error = %v", enc.name, err)
+		}
+		for _, maxExt := range []int{1, 2, 3} {
+			t.Run(fmt.Sprintf("%s/%d", enc.name, maxExt), func(t *testing.T) {
+				limited, err := LimitExtLen(block, maxExt, nil)
+				if err != nil {
+					t.Fatalf("LimitExtLen() error = %v", err)
+				}
+				got, err := DecompressBlockAlloc(limited, len(data))
+				if err != nil || !bytes.Equal(got, data) {
+					t.Fatalf("DecompressBlockAlloc() = %d bytes, %v, want %d", len(got), err, len(data))
+				}
+				checkExtLen(t, limited, maxExt, len(data))
+			})
+		}
+	}
+
+	// Without a limit the block is copied
+	block, _ := CompressBlock(data, nil)
+	if got, err := LimitExtLen(block, 0, nil); err != nil || !bytes.Equal(got, block) {
+		t.Errorf("LimitExtLen(0) = %d bytes, %v, want a copy", len(got), err)
+	}
+
+	// A run of literals can't be split
+	stored := storeBlock(generateRandomData(1000), nil)
+	if _, err := LimitExtLen(stored, 1, nil); err != ErrExtLenLimit {
+		t.Errorf("LimitExtLen() of 1000 literals error = %v, want %v", err, ErrExtLenLimit)
+	}
+	if _, err := LimitExtLen(stored, 4, nil); err != nil {
+		t.Errorf("LimitExtLen() of 1000 literals with 4 bytes error = %v", err)
+	}
+	if _, err := LimitExtLen([]byte{0xF0}, 1, nil); err != ErrCorruptBlock {
+		t.Errorf("LimitExtLen() of a corrupt block error = %v, want %v", err, ErrCorruptBlock)
+	}
+}
+
+// TestWriterMaxExtLenBytes tests that frames written with MaxExtLenBytes
+// hold only blocks within the limit, storing those that can't be
+func TestWriterMaxExtLenBytes(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := append(generateCompressibleData(3*blockSize), generateRandomData(blockSize)...)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: blockSize, MaxExtLenBytes: 1})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got, _ := readFrame(t, buf.Bytes()); !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes, want %d", len(got), len(data))
+	}
+
+	var fb FrameBuffer
+	if err := fb.Parse(buf.Bytes()); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var compressed int
+	for b := range fb.Blocks() {
+		if !b.Stored {
+			compressed++
+			checkExtLen(t, buf.Bytes()[b.FrameOffset:][:b.CompressedSize], 1, b.Size)
+		}
+	}
+	if compressed != 3 {
+		t.Errorf("%d compressed blocks, want 3", compressed)
+	}
+}
+
+// TestBlockMaxExtLenBytes tests the MaxExtLenBytes option of blocks
+func TestBlockMaxExtLenBytes(t *testing.T) {
+	data := generateRunData(100 * 1024)
+	b, err := NewBlockWithOptions(data, DefaultLevel, BlockOptions{MaxExtLenBytes: 1})
+	if err != nil {
+		t.Fatalf("NewBlockWithOptions() error = %v", err)
+	}
+	block, err := b.CompressToBuffer(nil)
+	if err != nil {
+		t.Fatalf("CompressToBuffer() error = %v", err)
+	}
+	if got, err := DecompressBlockAlloc(block, len(data)); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("DecompressBlockAlloc() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+	checkExtLen(t, block, 1, len(data))
+}
@@ -0,0 +1,123 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxFrameOverhead covers the header, end marker and content checksum of a
+// frame, beyond the blocks themselves
+const maxFrameOverhead = maxHeaderSize + 8
+
+// maxExpansion is the largest ratio of decompressed to compressed size an LZ4
+// block can reach. It bounds the buffer pre-sized from a frame's declared
+// content size, which could otherwise request any amount of memory.
+const maxExpansion = 255
+
+// maxPresize bounds the output CompressReader allocates up front, since a
+// length from a header such as Content-Length needn't match the source
+const maxPresize = 16 << 20
+
+// EncodeFrame compresses src into a new LZ4 frame with the given options. The
+// frame declares len(src) as its content size, which DecodeFrame and other
+// readers use to size their output. It is meant for message-passing systems
+// that already hold whole payloads, where a Writer and its io.Writer would be
+// overhead.
+func EncodeFrame(src []byte, options WriterOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(compressBound(len(src)) + maxFrameOverhead)
+
+	// The Writer never leaves this goroutine
+	options.Unsynchronized = true
+	w := NewWriterWithOptions(&buf, options)
+	if err := w.SetContentSize(uint64(len(src))); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CompressReader compresses the n bytes read from src into a new LZ4 frame
+// with the given options, like EncodeFrame for payloads that arrive through
+// an io.Reader with a known length, such as a file or a request body with a
+// Content-Length. The output is allocated once for the worst case of n
+// bytes up to 16 MiB, so it is never grown and copied for such payloads,
+// and grows as data arrives beyond that; src is read a block at a time. It
+// reads exactly n bytes; a source that ends earlier fails with
+// ErrContentSizeMismatch.
+func CompressReader(src io.Reader, n int64, options WriterOptions) ([]byte, error) {
+	if n < 0 || n > math.MaxInt-maxFrameOverhead {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	size := int(n)
+	var buf bytes.Buffer
+	buf.Grow(min(compressBound(size), maxPresize) + maxFrameOverhead)
+
+	options.Unsynchronized = true
+	w := NewWriterWithOptions(&buf, options)
+	if err := w.SetContentSize(uint64(n)); err != nil {
+		return nil, err
+	}
+	chunk := make([]byte, min(size, w.blockSize))
+	for read := 0; read < size; {
+		k, err := io.ReadFull(src, chunk[:min(len(chunk), size-read)])
+		read += k
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%w: source ended after %d of %d bytes", ErrContentSizeMismatch, read, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(chunk[:k]); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeFrame decompresses the LZ4 stream held in frame, usually a single
+// frame from EncodeFrame, into a new slice. When the frame declares its
+// content size the output is allocated once at that size. Frames that follow
+// the first are decoded too, as Reader does.
+func DecodeFrame(frame []byte) ([]byte, error) {
+	return decodeFrame(NewReader(bytes.NewReader(frame)), len(frame), nil)
+}
+
+// decodeFrame appends the data read by zr, a new Reader over a frame of
+// frameLen bytes, to out
+func decodeFrame(zr *Reader, frameLen int, out []byte) ([]byte, error) {
+	if err := zr.ReadHeader(); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	if size, ok := zr.ContentSize(); ok && size <= uint64(frameLen)*maxExpansion {
+		// Room for one more byte saves a reallocation to find the end
+		if need := len(out) + int(size) + 1; cap(out) < need {
+			out = append(make([]byte, 0, need), out...)
+		}
+	}
+
+	for {
+		if len(out) == cap(out) {
+			out = append(out, 0)[:len(out)]
+		}
+		n, err := zr.Read(out[len(out):cap(out)])
+		out = out[:len(out)+n]
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package frame gathers the frame-level API of compress: the streaming
+// Reader and Writer, the ParallelWriter, their options, and the one-shot
+// Encode and Decode for whole payloads. For raw blocks without a frame
+// around them, see the compress/block package.
+//
+// The frame codec is implemented in compress, where the writers share the
+// match finders and scratch state of the block compressor. The types here
+// are aliases of those in compress, so values pass freely between code
+// written against either package. Importing frame links all of compress;
+// only block decoding is available without it, from compress/block.
+package frame
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+type (
+	// Reader decompresses an LZ4 stream of one or more frames
+	Reader = compress.Reader
+	// ReaderOptions configures a Reader
+	ReaderOptions = compress.ReaderOptions
+	// Writer compresses data into an LZ4 frame
+	Writer = compress.Writer
+	// WriterOptions configures a Writer
+	WriterOptions = compress.WriterOptions
+	// ParallelWriter compresses the blocks of a frame on several goroutines
+	ParallelWriter = compress.ParallelWriter
+	// ParallelWriterOptions configures a ParallelWriter
+	ParallelWriterOptions = compress.ParallelWriterOptions
+	// Flags holds the feature flags of a frame header
+	Flags = compress.FrameFlags
+	// Checksummer computes block checksums
+	Checksummer = compress.Checksummer
+	// MessageOptions configures EncodeMessage
+	MessageOptions = compress.MessageOptions
+)
+
+// Frame header flags
+const (
+	FlagBlockIndependence = compress.FlagBlockIndependence
+	FlagBlockChecksum     = compress.FlagBlockChecksum
+	FlagContentSize       = compress.FlagContentSize
+	FlagContentChecksum   = compress.FlagContentChecksum
+	FlagDictID            = compress.FlagDictID
+)
+
+// NewReader returns a Reader decompressing from r
+func NewReader(r io.Reader) *Reader {
+	return compress.NewReader(r)
+}
+
+// NewReaderWithOptions returns a Reader decompressing from r with options
+func NewReaderWithOptions(r io.Reader, options ReaderOptions) *Reader {
+	return compress.NewReaderWithOptions(r, options)
+}
+
+// NewWriter returns a Writer compressing to w at the default level
+func NewWriter(w io.Writer) *Writer {
+	return compress.NewWriter(w)
+}
+
+// NewWriterWithOptions returns a Writer compressing to w with options
+func NewWriterWithOptions(w io.Writer, options WriterOptions) *Writer {
+	return compress.NewWriterWithOptions(w, options)
+}
+
+// NewParallelWriterWithOptions returns a ParallelWriter compressing to w
+// with options
+func NewParallelWriterWithOptions(w io.Writer, options ParallelWriterOptions) *ParallelWriter {
+	return compress.NewParallelWriterWithOptions(w, options)
+}
+
+// Encode compresses src into a new frame declaring its content size, as
+// compress.EncodeFrame does
+func Encode(src []byte, options WriterOptions) ([]byte, error) {
+	return compress.EncodeFrame(src, options)
+}
+
+// EncodeReader compresses the n bytes read from src into a new frame, as
+// compress.CompressReader does
+func EncodeReader(src io.Reader, n int64, options WriterOptions) ([]byte, error) {
+	return compress.CompressReader(src, n, options)
+}
+
+// Decode decompresses the frames held in frame into a new slice, as
+// compress.DecodeFrame does
+func Decode(frame []byte) ([]byte, error) {
+	return compress.DecodeFrame(frame)
+}
+
+// EncodeMessage compresses msg into a minimal frame appended to dst, as
+// compress.EncodeMessage does
+func EncodeMessage(dst, msg []byte, options MessageOptions) ([]byte, error) {
+	return compress.EncodeMessage(dst, msg, options)
+}
+
+// DecodeMessage decompresses frame appending to dst, as
+// compress.DecodeMessage does
+func DecodeMessage(dst, frame []byte) ([]byte, error) {
+	return compress.DecodeMessage(dst, frame)
+}
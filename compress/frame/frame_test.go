@@ -0,0 +1,35 @@
+package frame
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// TestRoundTrip tests that frames written through this package read back
+// through compress and the other way around
+func TestRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("frame-level API "), 10000)
+
+	encoded, err := Encode(data, WriterOptions{BlockChecksum: compress.XXH32})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	var r *compress.Reader = NewReader(bytes.NewReader(encoded))
+	if err := r.ReadHeader(); err != nil || !r.Flags().Has(FlagBlockChecksum|FlagContentSize) {
+		t.Errorf("Flags() = %v, %v, want block checksums and content size", r.Flags(), err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+
+	var buf bytes.Buffer
+	w := compress.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	if got, err := Decode(buf.Bytes()); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Decode() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+}
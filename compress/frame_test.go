@@ -0,0 +1,158 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+// TestEncodeDecodeFrame tests the one-shot frame helpers
+func TestEncodeDecodeFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		opts WriterOptions
+	}{
+		{"Empty", []byte{}, WriterOptions{}},
+		{"Small", []byte("hello, frame"), WriterOptions{}},
+		{"Compressible", generateCompressibleData(300 * 1024), WriterOptions{BlockSize: 64 * 1024}},
+		{"Random", generateRandomData(100 * 1024), WriterOptions{ContentChecksum: true, BlockChecksum: XXH32}},
+		{"Store", generateCompressibleData(10000), WriterOptions{Store: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := EncodeFrame(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeFrame() error = %v", err)
+			}
+
+			_, header := readFrame(t, frame)
+			if !header.contentSize || header.contentSizeValue != uint64(len(tt.data)) {
+				t.Errorf("content size = %d, %v, want %d, true", header.contentSizeValue, header.contentSize, len(tt.data))
+			}
+
+			got, err := DecodeFrame(frame)
+			if err != nil {
+				t.Fatalf("DecodeFrame() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("DecodeFrame() = %d bytes, want %d", len(got), len(tt.data))
+			}
+			if cap(got) > len(tt.data)+1 {
+				t.Errorf("DecodeFrame() cap = %d, want at most %d", cap(got), len(tt.data)+1)
+			}
+		})
+	}
+}
+
+// TestDecodeFrameStream tests DecodeFrame on frames from a Writer, without a
+// content size and concatenated
+func TestDecodeFrameStream(t *testing.T) {
+	first := generateCompressibleData(200 * 1024)
+	second := generateRandomData(5000)
+	stream := append(compressFrame(t, first, nil), compressFrame(t, second, nil)...)
+
+	got, err := DecodeFrame(stream)
+	if err != nil {
+		t.Fatalf("DecodeFrame() error = %v", err)
+	}
+	if !bytes.Equal(got, append(first, second...)) {
+		t.Errorf("DecodeFrame() = %d bytes, want %d", len(got), len(first)+len(second))
+	}
+}
+
+// TestDecodeFrameInvalid tests DecodeFrame on broken frames
+func TestDecodeFrameInvalid(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frame, err := EncodeFrame(data, WriterOptions{})
+	if err != nil {
+		t.Fatalf("EncodeFrame() error = %v", err)
+	}
+
+	// A content size far beyond what the frame can hold isn't allocated
+	huge := bytes.Clone(frame)
+	binary.LittleEndian.PutUint64(huge[6:], 1<<62)
+	resealHeader(huge)
+
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"Empty", nil},
+		{"Header", frame[:5]},
+		{"Truncated", frame[:len(frame)/2]},
+		{"Magic", append([]byte{1, 2, 3, 4}, frame[4:]...)},
+	}
+	for _, tt := range tests {
+		if _, err := DecodeFrame(tt.frame); err == nil {
+			t.Errorf("DecodeFrame(%s) succeeded", tt.name)
+		}
+	}
+
+	if got, err := DecodeFrame(huge); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("DecodeFrame() with a huge content size = %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+}
+
+// TestCompressReader tests that CompressReader writes the frame EncodeFrame
+// writes, into the buffer it allocated up front, reading exactly n bytes
+func TestCompressReader(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		opts WriterOptions
+	}{
+		{"Empty", []byte{}, WriterOptions{}},
+		{"Small", []byte("hello, frame"), WriterOptions{}},
+		{"Compressible", generateCompressibleData(300 * 1024), WriterOptions{BlockSize: 64 * 1024}},
+		{"Random", generateRandomData(100 * 1024), WriterOptions{ContentChecksum: true, BlockChecksum: XXH32}},
+		{"Store", generateCompressibleData(10000), WriterOptions{Store: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := EncodeFrame(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("EncodeFrame() error = %v", err)
+			}
+			src := bytes.NewReader(append(bytes.Clone(tt.data), "trailing"...))
+			frame, err := CompressReader(iotest.HalfReader(src), int64(len(tt.data)), tt.opts)
+			if err != nil {
+				t.Fatalf("CompressReader() error = %v", err)
+			}
+			if !bytes.Equal(frame, want) {
+				t.Errorf("CompressReader() = %d bytes, want the %d of EncodeFrame()", len(frame), len(want))
+			}
+			if src.Len() != len("trailing") {
+				t.Errorf("CompressReader() left %d bytes of src, want %d", src.Len(), len("trailing"))
+			}
+			// A buffer grown while writing would be at least twice the bound
+			if bound := compressBound(len(tt.data)) + maxFrameOverhead; cap(frame) >= 2*bound {
+				t.Errorf("CompressReader() cap = %d for a bound of %d", cap(frame), bound)
+			}
+		})
+	}
+
+	data := generateCompressibleData(100 * 1024)
+	if _, err := CompressReader(bytes.NewReader(data), int64(len(data))+1, WriterOptions{}); !errors.Is(err, ErrContentSizeMismatch) {
+		t.Errorf("CompressReader() of a short source error = %v, want %v", err, ErrContentSizeMismatch)
+	}
+	if _, err := CompressReader(bytes.NewReader(data), -1, WriterOptions{}); err == nil {
+		t.Error("CompressReader() of length -1 succeeded")
+	}
+
+	// A length the source doesn't back isn't allocated up front
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := CompressReader(bytes.NewReader(data), 1<<30, WriterOptions{}); !errors.Is(err, ErrContentSizeMismatch) {
+		t.Errorf("CompressReader() of a 1 GiB length error = %v, want %v", err, ErrContentSizeMismatch)
+	}
+	runtime.ReadMemStats(&after)
+	if allocs := after.TotalAlloc - before.TotalAlloc; allocs > 2*maxPresize {
+		t.Errorf("CompressReader() of a 1 GiB length allocated %d bytes", allocs)
+	}
+}
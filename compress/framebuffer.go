@@ -0,0 +1,178 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// ErrDependentBlocks indicates a frame whose blocks reference earlier blocks,
+// so that they can't be decoded on their own
+var ErrDependentBlocks = errors.New("frame blocks are not independent")
+
+// FrameBuffer gives random access to an LZ4 frame held in memory, such as a
+// compressed object in a cache. Parse records where every block starts in
+// both the frame and the uncompressed data, after which DecodeBlock and
+// ReadAt decompress only the blocks they need. A parsed FrameBuffer is safe
+// for concurrent use.
+type FrameBuffer struct {
+	frame  []byte
+	header frameHeader
+	blocks []frameBlock
+	size   int64
+}
+
+// frameBlock locates one block of a parsed frame
+type frameBlock struct {
+	// pos is the offset of the block data in the frame
+	pos int
+	// size is the size of the block data as stored
+	size int
+	// stored is set for blocks stored uncompressed
+	stored bool
+	// rawOff and rawLen locate the block in the uncompressed data
+	rawOff int64
+	rawLen int
+}
+
+// Parse indexes the blocks of frame, the first LZ4 frame in it, without
+// decompressing them. The FrameBuffer keeps a reference to frame, which must
+// not be modified while it is in use. Frames whose blocks depend on earlier
+// ones are rejected with ErrDependentBlocks and frames compressed with a
+// dictionary with ErrUnknownDictionary.
+func (fb *FrameBuffer) Parse(frame []byte) error {
+	fb.frame = nil
+	fb.blocks = fb.blocks[:0]
+	fb.size = 0
+
+	zr := NewReader(bytes.NewReader(frame))
+	if err := zr.ReadHeader(); err != nil {
+		return err
+	}
+	if !zr.header.blockIndependence {
+		return ErrDependentBlocks
+	}
+	if zr.header.dictID {
+		return ErrUnknownDictionary
+	}
+
+	pos := int(zr.Consumed())
+	for {
+		if pos+4 > len(frame) {
+			return io.ErrUnexpectedEOF
+		}
+		word := binary.LittleEndian.Uint32(frame[pos:])
+		pos += 4
+		if word == 0 {
+			break
+		}
+
+		size := int(word & 0x7FFFFFFF)
+		if size > zr.blocksizeCache {
+			return errors.New("block size too large")
+		}
+		end := pos + size
+		if zr.header.blockChecksum {
+			end += 4
+		}
+		if end > len(frame) {
+			return io.ErrUnexpectedEOF
+		}
+
+		b := frameBlock{pos: pos, size: size, stored: word&0x80000000 != 0, rawOff: fb.size}
+		if b.stored {
+			b.rawLen = size
+		} else {
+			n, err := block.DecodedLen(frame[pos:pos+size], zr.blocksizeCache)
+			if err != nil {
+				return err
+			}
+			b.rawLen = n
+		}
+		pos = end
+
+		// Empty stored blocks hold nothing to look up
+		if b.rawLen == 0 {
+			continue
+		}
+		fb.blocks = append(fb.blocks, b)
+		fb.size += int64(b.rawLen)
+	}
+
+	if zr.header.contentSize && zr.header.contentSizeValue != uint64(fb.size) {
+		return ErrContentSizeMismatch
+	}
+	fb.frame = frame
+	fb.header = zr.header
+	return nil
+}
+
+// NumBlocks returns the number of blocks holding data
+func (fb *FrameBuffer) NumBlocks() int {
+	return len(fb.blocks)
+}
+
+// Size returns the uncompressed size of the frame
+func (fb *FrameBuffer) Size() int64 {
+	return fb.size
+}
+
+// BlockOffset returns the offset of block i in the uncompressed data
+func (fb *FrameBuffer) BlockOffset(i int) int64 {
+	return fb.blocks[i].rawOff
+}
+
+// DecodeBlock returns the decompressed contents of block i in a new buffer,
+// verifying its block checksum if the frame has them
+func (fb *FrameBuffer) DecodeBlock(i int) ([]byte, error) {
+	if i < 0 || i >= len(fb.blocks) {
+		return nil, errors.New("block index out of range")
+	}
+	b := fb.blocks[i]
+	data := fb.frame[b.pos : b.pos+b.size]
+
+	if fb.header.blockChecksum {
+		if XXH32.Checksum(data) != binary.LittleEndian.Uint32(fb.frame[b.pos+b.size:]) {
+			return nil, ErrBlockChecksum
+		}
+	}
+
+	if b.stored {
+		return bytes.Clone(data), nil
+	}
+	return DecompressBlockAlloc(data, b.rawLen)
+}
+
+// ReadAt implements io.ReaderAt over the uncompressed data, decoding only the
+// blocks that overlap p
+func (fb *FrameBuffer) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= fb.size {
+		return 0, io.EOF
+	}
+
+	// The first block ending after off
+	i := sort.Search(len(fb.blocks), func(i int) bool {
+		b := fb.blocks[i]
+		return b.rawOff+int64(b.rawLen) > off
+	})
+
+	var n int
+	for ; n < len(p) && i < len(fb.blocks); i++ {
+		raw, err := fb.DecodeBlock(i)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], raw[off+int64(n)-fb.blocks[i].rawOff:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,114 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestFrameBufferReadAt tests reading ranges from a parsed frame
+func TestFrameBufferReadAt(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := append(generateCompressibleData(3*blockSize+500), generateRandomData(blockSize+300)...)
+
+	tests := []struct {
+		name      string
+		configure func(w *Writer)
+	}{
+		{"Default", func(w *Writer) { w.blockSize = blockSize }},
+		{"Block Checksums", func(w *Writer) {
+			w.blockSize = blockSize
+			w.header.blockChecksum = true
+		}},
+		{"Content Size", func(w *Writer) {
+			w.blockSize = blockSize
+			w.SetContentSize(uint64(len(data)))
+		}},
+		{"Single Block", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fb FrameBuffer
+			if err := fb.Parse(compressFrame(t, data, tt.configure)); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if fb.Size() != int64(len(data)) {
+				t.Errorf("Size() = %d, want %d", fb.Size(), len(data))
+			}
+
+			ranges := []struct{ off, n int }{
+				{0, 10},
+				{blockSize - 5, 10},
+				{blockSize, blockSize},
+				{100, 3 * blockSize},
+				{len(data) - 20, 20},
+				{0, len(data)},
+			}
+			for _, r := range ranges {
+				p := make([]byte, r.n)
+				n, err := fb.ReadAt(p, int64(r.off))
+				if err != nil || n != r.n {
+					t.Fatalf("ReadAt(%d bytes, %d) = %d, %v, want %d, nil", r.n, r.off, n, err, r.n)
+				}
+				if !bytes.Equal(p, data[r.off:r.off+r.n]) {
+					t.Errorf("ReadAt(%d bytes, %d) returned the wrong data", r.n, r.off)
+				}
+			}
+
+			// Reads past the end are short
+			p := make([]byte, 100)
+			if n, err := fb.ReadAt(p, int64(len(data)-40)); n != 40 || err != io.EOF {
+				t.Errorf("ReadAt() at the end = %d, %v, want 40, %v", n, err, io.EOF)
+			}
+			if n, err := fb.ReadAt(p, int64(len(data))); n != 0 || err != io.EOF {
+				t.Errorf("ReadAt() past the end = %d, %v, want 0, %v", n, err, io.EOF)
+			}
+
+			// The blocks put back together give the data
+			var joined []byte
+			for i := 0; i < fb.NumBlocks(); i++ {
+				if fb.BlockOffset(i) != int64(len(joined)) {
+					t.Errorf("BlockOffset(%d) = %d, want %d", i, fb.BlockOffset(i), len(joined))
+				}
+				block, err := fb.DecodeBlock(i)
+				if err != nil {
+					t.Fatalf("DecodeBlock(%d) error = %v", i, err)
+				}
+				joined = append(joined, block...)
+			}
+			if !bytes.Equal(joined, data) {
+				t.Errorf("blocks hold %d bytes, want %d", len(joined), len(data))
+			}
+		})
+	}
+}
+
+// TestFrameBufferErrors tests frames that can't be indexed or decoded
+func TestFrameBufferErrors(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frame := compressFrame(t, data, func(w *Writer) { w.header.blockChecksum = true })
+
+	var fb FrameBuffer
+	if err := fb.Parse(frame[:len(frame)-10]); err != io.ErrUnexpectedEOF {
+		t.Errorf("Parse() of a truncated frame error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	dependent := compressFrame(t, data, func(w *Writer) { w.header.blockIndependence = false })
+	if err := fb.Parse(dependent); err != ErrDependentBlocks {
+		t.Errorf("Parse() error = %v, want %v", err, ErrDependentBlocks)
+	}
+
+	// A damaged block checksum is only noticed when the block is decoded
+	damaged := bytes.Clone(frame)
+	damaged[len(damaged)-5] ^= 0xFF
+	if err := fb.Parse(damaged); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := fb.DecodeBlock(fb.NumBlocks() - 1); err != ErrBlockChecksum {
+		t.Errorf("DecodeBlock() error = %v, want %v", err, ErrBlockChecksum)
+	}
+	if _, err := fb.DecodeBlock(fb.NumBlocks()); err == nil {
+		t.Errorf("DecodeBlock() out of range succeeded")
+	}
+}
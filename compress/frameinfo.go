@@ -0,0 +1,83 @@
+package compress
+
+// FrameFlags holds the feature flags of a frame header (the FLG byte without
+// its version bits)
+type FrameFlags uint8
+
+// Frame header flags
+const (
+	FlagBlockIndependence FrameFlags = flagBlockIndependence
+	FlagBlockChecksum     FrameFlags = flagBlockChecksum
+	FlagContentSize       FrameFlags = flagContentSize
+	FlagContentChecksum   FrameFlags = flagContentChecksum
+	FlagDictID            FrameFlags = flagDictID
+)
+
+// Has reports whether all flags in f2 are set in f
+func (f FrameFlags) Has(f2 FrameFlags) bool {
+	return f&f2 == f2
+}
+
+// flags returns the feature flags of the header
+func (h *frameHeader) flags() FrameFlags {
+	var f FrameFlags
+	if h.blockIndependence {
+		f |= FlagBlockIndependence
+	}
+	if h.blockChecksum {
+		f |= FlagBlockChecksum
+	}
+	if h.contentSize {
+		f |= FlagContentSize
+	}
+	if h.contentChecksum {
+		f |= FlagContentChecksum
+	}
+	if h.dictID {
+		f |= FlagDictID
+	}
+	return f
+}
+
+// ReadHeader reads the frame header without decoding any data, so that
+// ContentSize, BlockSize and Flags can be queried before the first Read.
+// Calling it again, or after Read, has no effect.
+func (r *Reader) ReadHeader() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ensureHeader()
+}
+
+// ContentSize returns the uncompressed size declared in the frame header and
+// whether one was declared. It reports false until the header has been read
+// by ReadHeader or Read. For concatenated frames it describes the current frame.
+func (r *Reader) ContentSize() (uint64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.readHeader {
+		return 0, false
+	}
+	return r.header.contentSizeValue, r.header.contentSize
+}
+
+// BlockSize returns the maximum block size declared in the frame header, or 0
+// until the header has been read by ReadHeader or Read
+func (r *Reader) BlockSize() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.readHeader {
+		return 0
+	}
+	return r.blocksizeCache
+}
+
+// Flags returns the flags of the frame header, or 0 until the header has been
+// read by ReadHeader or Read
+func (r *Reader) Flags() FrameFlags {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.readHeader {
+		return 0
+	}
+	return r.header.flags()
+}
@@ -0,0 +1,98 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestReaderFrameInfo tests querying the frame header before reading data
+func TestReaderFrameInfo(t *testing.T) {
+	data := generateCompressibleData(20 * 1024)
+
+	tests := []struct {
+		name      string
+		configure func(*Writer)
+		wantSize  bool
+		wantBlock int
+		wantFlags FrameFlags
+	}{
+		{"Default", nil, false, 4 * 1024 * 1024, FlagBlockIndependence},
+		{
+			"Content size",
+			func(w *Writer) {
+				if err := w.SetContentSize(uint64(len(data))); err != nil {
+					t.Fatalf("SetContentSize() error = %v", err)
+				}
+			},
+			true, 64 * 1024, FlagBlockIndependence | FlagContentSize,
+		},
+		{
+			"Block checksum",
+			func(w *Writer) {
+				w.header.blockChecksum = true
+				if err := w.SetBlockSizeCode(BlockSize1MB); err != nil {
+					t.Fatalf("SetBlockSizeCode() error = %v", err)
+				}
+			},
+			false, 1024 * 1024, FlagBlockIndependence | FlagBlockChecksum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(bytes.NewReader(compressFrame(t, data, tt.configure)))
+
+			// Nothing is known before the header is read
+			if size, ok := r.ContentSize(); ok || size != 0 {
+				t.Errorf("ContentSize() before ReadHeader = %d, %v, want 0, false", size, ok)
+			}
+			if r.BlockSize() != 0 || r.Flags() != 0 {
+				t.Errorf("BlockSize(), Flags() before ReadHeader = %d, %#x, want 0, 0", r.BlockSize(), r.Flags())
+			}
+
+			if err := r.ReadHeader(); err != nil {
+				t.Fatalf("ReadHeader() error = %v", err)
+			}
+
+			size, ok := r.ContentSize()
+			if ok != tt.wantSize || (ok && size != uint64(len(data))) {
+				t.Errorf("ContentSize() = %d, %v, want %d, %v", size, ok, len(data), tt.wantSize)
+			}
+			if got := r.BlockSize(); got != tt.wantBlock {
+				t.Errorf("BlockSize() = %d, want %d", got, tt.wantBlock)
+			}
+			if got := r.Flags(); got != tt.wantFlags {
+				t.Errorf("Flags() = %#x, want %#x", got, tt.wantFlags)
+			}
+			if !r.Flags().Has(FlagBlockIndependence) || r.Flags().Has(FlagDictID) {
+				t.Errorf("Flags().Has() mismatch for %#x", r.Flags())
+			}
+
+			// Reading the header doesn't consume any data
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+// TestReaderReadHeaderError tests that header errors are reported consistently
+func TestReaderReadHeaderError(t *testing.T) {
+	r := NewReader(bytes.NewReader([]byte{0x04, 0x22, 0x4D, 0x18, 0x00}))
+
+	err := r.ReadHeader()
+	if err == nil {
+		t.Fatalf("ReadHeader() error = nil, want error")
+	}
+	if _, err2 := r.Read(make([]byte, 10)); err2 != err {
+		t.Errorf("Read() after failed ReadHeader error = %v, want %v", err2, err)
+	}
+	if _, ok := r.ContentSize(); ok {
+		t.Errorf("ContentSize() ok = true after failed ReadHeader")
+	}
+}
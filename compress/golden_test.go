@@ -0,0 +1,310 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// goldenVectors lists the reference files in testdata/golden
+var goldenVectors = []string{"empty", "hello", "lorem", "zeros", "pattern", "text", "window"}
+
+// readGolden reads a file from testdata/golden
+func readGolden(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "golden", name))
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", name, err)
+	}
+	return data
+}
+
+// TestGoldenFrames tests decoding frames produced by the reference lz4 tool
+func TestGoldenFrames(t *testing.T) {
+	for _, name := range goldenVectors {
+		t.Run(name, func(t *testing.T) {
+			want := readGolden(t, name+".raw")
+			frame := readGolden(t, name+".lz4")
+
+			got, err := io.ReadAll(NewReader(bytes.NewReader(frame)))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decoded %d bytes, want %d matching bytes", len(got), len(want))
+			}
+		})
+	}
+}
+
+// TestGoldenLinkedFrames tests decoding frames whose blocks depend on the
+// ones before them, through every way a Reader passes over blocks
+func TestGoldenLinkedFrames(t *testing.T) {
+	tests := []struct {
+		frame, raw string
+	}{
+		{"window.lz4", "window.raw"},
+		{"text-linked.lz4", "text.raw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.frame, func(t *testing.T) {
+			want := readGolden(t, tt.raw)
+			frame := readGolden(t, tt.frame)
+
+			// Small reads make the stored blocks stream into p in pieces
+			for _, size := range []int{1000, 1 << 20} {
+				got, err := io.ReadAll(bufio.NewReaderSize(NewReader(bytes.NewReader(frame)), size))
+				if err != nil {
+					t.Fatalf("ReadAll() error = %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("decoded %d bytes, want %d matching bytes", len(got), len(want))
+				}
+			}
+
+			// Skipping decodes the blocks the rest depends on
+			for _, n := range []int64{1, 65535, 65536, 70000} {
+				r := NewReader(bytes.NewReader(frame))
+				if _, err := r.Skip(n); err != nil {
+					t.Fatalf("Skip(%d) error = %v", n, err)
+				}
+				got, err := io.ReadAll(r)
+				if err != nil || !bytes.Equal(got, want[n:]) {
+					t.Errorf("after Skip(%d): ReadAll() = %d bytes, %v, want %d matching bytes", n, len(got), err, len(want)-int(n))
+				}
+			}
+
+			// Validation checks offsets against the blocks before
+			r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{ValidateSequences: true})
+			if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("ReadAll() with ValidateSequences = %d bytes, %v", len(got), err)
+			}
+		})
+	}
+}
+
+// TestGoldenBlocks tests decoding blocks produced by the reference lz4 tool
+func TestGoldenBlocks(t *testing.T) {
+	for _, name := range goldenVectors {
+		path := filepath.Join("testdata", "golden", name+".block")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			want := readGolden(t, name+".raw")
+			block := readGolden(t, name+".block")
+
+			got, err := DecompressBlock(block, nil, len(want))
+			if err != nil {
+				t.Fatalf("DecompressBlock() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decoded %d bytes, want %d matching bytes", len(got), len(want))
+			}
+		})
+	}
+}
+
+// TestSpecBlocks tests hand-written blocks covering the edge cases of the block format
+func TestSpecBlocks(t *testing.T) {
+	literals15 := []byte(strings.Repeat("x", 15))
+	literals270 := []byte(strings.Repeat("y", 270))
+
+	tests := []struct {
+		name    string
+		block   []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"Empty block", []byte{0x00}, []byte{}, false},
+		{
+			"Minimum match",
+			[]byte{0x40, 'a', 'b', 'c', 'd', 0x04, 0x00, 0x50, 'a', 'b', 'c', 'd', 'e'},
+			[]byte("abcdabcdabcde"),
+			false,
+		},
+		{
+			"Overlapping match with offset 1",
+			[]byte{0x1F, 'a', 0x01, 0x00, 0x00, 0x50, 'b', 'b', 'b', 'b', 'b'},
+			[]byte(strings.Repeat("a", 20) + "bbbbb"),
+			false,
+		},
+		{"Literal length 15", append([]byte{0xF0, 0x00}, literals15...), literals15, false},
+		{"Literal length 270", append([]byte{0xF0, 0xFF, 0x00}, literals270...), literals270, false},
+		{"Empty input", []byte{}, nil, true},
+		{"Offset 0", []byte{0x40, 'a', 'b', 'c', 'd', 0x00, 0x00, 0x50, 'a', 'b', 'c', 'd', 'e'}, nil, true},
+		{"Offset beyond output", []byte{0x40, 'a', 'b', 'c', 'd', 0x05, 0x00, 0x50, 'a', 'b', 'c', 'd', 'e'}, nil, true},
+		{"Block ending with a match", []byte{0x40, 'a', 'b', 'c', 'd', 0x04, 0x00}, nil, true},
+		{"Truncated literals", []byte{0x50, 'a', 'b', 'c'}, nil, true},
+		{"Truncated offset", []byte{0x40, 'a', 'b', 'c', 'd', 0x04}, nil, true},
+	}
+
+	// Every decoder entry point must agree on every block
+	decoders := []struct {
+		name string
+		fn   func(src []byte) ([]byte, error)
+	}{
+		{"DecompressBlock", func(src []byte) ([]byte, error) { return DecompressBlock(src, nil, 1024) }},
+		{"DecompressBlockInto", func(src []byte) ([]byte, error) {
+			dst := make([]byte, 1024)
+			n, err := DecompressBlockInto(src, dst)
+			return dst[:n], err
+		}},
+		{"DecompressBlockAlloc", func(src []byte) ([]byte, error) { return DecompressBlockAlloc(src, 0) }},
+		{"DecompressBlockFunc", func(src []byte) ([]byte, error) {
+			var out []byte
+			_, err := DecompressBlockFunc(src, func(chunk []byte) error {
+				out = append(out, chunk...)
+				return nil
+			}, 1024)
+			return out, err
+		}},
+		{"DecompressAppend", func(src []byte) ([]byte, error) { return block.DecompressAppend([]byte{}, src, 1024) }},
+	}
+
+	// DecodedLen only reads the lengths, so it lets bad offsets pass
+	badOffsets := map[string]bool{"Offset 0": true, "Offset beyond output": true}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, dec := range decoders {
+				got, err := dec.fn(tt.block)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s() error = %v, wantErr %v", dec.name, err, tt.wantErr)
+				}
+				if !tt.wantErr && !bytes.Equal(got, tt.want) {
+					t.Errorf("%s() = %q, want %q", dec.name, got, tt.want)
+				}
+			}
+
+			n, err := block.DecodedLen(tt.block, 1024)
+			if wantErr := tt.wantErr && !badOffsets[tt.name]; (err != nil) != wantErr {
+				t.Fatalf("DecodedLen() error = %v, wantErr %v", err, wantErr)
+			}
+			if !tt.wantErr && n != len(tt.want) {
+				t.Errorf("DecodedLen() = %d, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+// TestEncoderEndOfBlockRules tests that our encoders follow the end-of-block rules:
+// the block ends with at least 5 literals and no match starts in the last 12 bytes
+func TestEncoderEndOfBlockRules(t *testing.T) {
+	inputs := [][]byte{
+		bytes.Repeat([]byte{0}, 16),
+		bytes.Repeat([]byte("abcd"), 100),
+		readGolden(t, "lorem.raw"),
+		readGolden(t, "zeros.raw"),
+	}
+
+	encoders := []struct {
+		name string
+		fn   func(src []byte) ([]byte, error)
+	}{
+		{"CompressBlock", func(src []byte) ([]byte, error) { return CompressBlock(src, nil) }},
+		{"CompressBlockV2", func(src []byte) ([]byte, error) { return CompressBlockV2(src, nil) }},
+	}
+
+	for _, enc := range encoders {
+		for _, input := range inputs {
+			block, err := enc.fn(input)
+			if err != nil {
+				t.Fatalf("%s(%d bytes) error = %v", enc.name, len(input), err)
+			}
+
+			sr := NewSequenceReader(block)
+			rawPos := 0
+			var last Sequence
+			for {
+				seq, err := sr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s(%d bytes) produced a corrupt block: %v", enc.name, len(input), err)
+				}
+
+				matchStart := rawPos + len(seq.Literals)
+				if seq.MatchLen > 0 && matchStart > len(input)-mfLimit {
+					t.Errorf("%s(%d bytes) has a match at %d, within the last %d bytes", enc.name, len(input), matchStart, mfLimit)
+				}
+				rawPos = matchStart + seq.MatchLen
+				last = seq
+			}
+
+			if last.MatchLen != 0 || len(last.Literals) < lastLiterals {
+				t.Errorf("%s(%d bytes) ends with %d literals and match %d, want at least %d literals only",
+					enc.name, len(input), len(last.Literals), last.MatchLen, lastLiterals)
+			}
+		}
+	}
+}
+
+// TestGoldenHeaders tests that the frame headers we write match those of
+// the reference lz4 tool byte for byte, header checksum included
+func TestGoldenHeaders(t *testing.T) {
+	for _, name := range goldenVectors {
+		t.Run(name, func(t *testing.T) {
+			frame := readGolden(t, name+".lz4")
+			r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{HeaderMode: HeaderStrict})
+			if err := r.ensureHeader(); err != nil {
+				t.Fatalf("reading header: %v", err)
+			}
+			got := appendFrameHeader(nil, &r.header)
+			if want := frame[:len(got)]; !bytes.Equal(got, want) {
+				t.Errorf("header = % x, want % x", got, want)
+			}
+		})
+	}
+}
+
+// TestLZ4Tool tests that the reference lz4 tool decodes our frames, with
+// every optional header field and checksum, and that we decode its frames
+func TestLZ4Tool(t *testing.T) {
+	tool, err := exec.LookPath("lz4")
+	if err != nil {
+		t.Skip("lz4 tool not found")
+	}
+	data := append(generateCompressibleData(300*1024), generateRandomData(50*1024)...)
+
+	var frame bytes.Buffer
+	w := NewWriterWithOptions(&frame, WriterOptions{BlockSize: 64 * 1024, BlockChecksum: XXH32, ContentChecksum: true})
+	w.SetContentSize(uint64(len(data)))
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	cmd := exec.Command(tool, "-dc")
+	cmd.Stdin = &frame
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("lz4 -dc error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("lz4 -dc decoded %d bytes, want %d matching bytes", len(got), len(data))
+	}
+
+	for _, flags := range [][]string{{"-BI", "-BX"}, {"--content-size", "-B4"}} {
+		cmd := exec.Command(tool, append(flags, "-c")...)
+		cmd.Stdin = bytes.NewReader(data)
+		frame, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("lz4 %v error = %v", flags, err)
+		}
+		r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{HeaderMode: HeaderStrict})
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("decoding lz4 %v output = %d bytes, %v, want %d bytes", flags, len(got), err, len(data))
+		}
+	}
+}
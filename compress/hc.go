@@ -1,5 +1,7 @@
 package compress
 
+import "math/bits"
+
 const (
 	// MinMatch is the minimum match length
 	MinMatch = 4
@@ -16,14 +18,34 @@ const (
 	// HashMask is used to mask hash values
 	HashMask = HashTableSize - 1
 
-	// HashLogHC is for high compression levels (9-12)
+	// HashLogHC is for high compression levels (10-12)
 	HashLogHC = 17
 	// HashTableSizeHC is the size of high compression hash table
 	HashTableSizeHC = 1 << HashLogHC
 	// HashMaskHC is used to mask high compression hash values
 	HashMaskHC = HashTableSizeHC - 1
+
+	// minHashLog is the size, as a base-2 logarithm, of the smallest
+	// hash table
+	minHashLog = 12
 )
 
+// hashLogFor returns the size, as a base-2 logarithm, of the hash table for
+// an input of n bytes at a level whose table is 1<<levelLog entries and
+// whose searches follow up to maxAttempts candidates. Small inputs get
+// about four entries per byte, so that blocks of a few KB clear and touch a
+// fraction of the level's table. Levels searching 32 candidates or more get
+// sixteen per byte, as collisions in their chains cost more than the clear.
+// Tables beyond the level's didn't improve the ratio or speed of large
+// blocks.
+func hashLogFor(n, levelLog, maxAttempts int) int {
+	perByteLog := 2
+	if maxAttempts >= 32 {
+		perByteLog = 4
+	}
+	return min(max(bits.Len(uint(max(n-1, 1)))+perByteLog, minHashLog), levelLog)
+}
+
 // HCMatcher implements a high-compression match finder for LZ4HC
 type HCMatcher struct {
 	// Input buffer
@@ -45,39 +67,42 @@ type HCMatcher struct {
 	// End of buffer
 	end int
 
-	// Hash table size (may vary by level)
+	// Hash table size, set by Reset for the input from levelLog, the
+	// size for the level
+	levelLog      int
 	hashLog       int
 	hashSize      int
 	hashMask      int
 	useEnhancedHC bool
+
+	// skipTrigger is the level's skipTrigger for the encoder's
+	// incompressibility heuristic
+	skipTrigger uint
+
+	// budget, if set, is the time budget of the block being compressed
+	budget *timeBudget
 }
 
-// NewHCMatcher creates a new high-compression matcher
+// NewHCMatcher creates a new high-compression matcher. Its hash table is
+// sized for the level, and Reset resizes it for every input.
 func NewHCMatcher(level CompressionLevel) *HCMatcher {
-	// Determine search parameters based on level
-	maxAttempts := 0
-	hashLog := HashLog
-	windowSize := MaxDistance
-	useEnhancedHC := false
-
-	// Improved HC levels for v0.3
-	switch {
-	case level <= 3:
-		maxAttempts = 4
-		windowSize = 16 * 1024 // 16KB window for lowest levels
-	case level <= 6:
-		maxAttempts = 8
-		windowSize = 32 * 1024 // 32KB window for medium levels
-	case level <= 9:
-		maxAttempts = 16
-		windowSize = 64 * 1024 // 64KB window for high levels
-		useEnhancedHC = true
-	default:
-		maxAttempts = 32
-		windowSize = MaxDistance
-		hashLog = HashLogHC // Use larger hash table for highest levels
-		useEnhancedHC = true
-	}
+	return newHCMatcher(level, paramsForLevel(level).hashLog)
+}
+
+// newHCMatcherFor creates a matcher whose hash table is sized for an input
+// of n bytes, so that Reset doesn't resize it
+func newHCMatcherFor(level CompressionLevel, n int) *HCMatcher {
+	params := paramsForLevel(level)
+	return newHCMatcher(level, hashLogFor(n, params.hashLog, params.maxAttempts))
+}
+
+// newHCMatcher creates a matcher with a hash table of 1<<hashLog entries
+func newHCMatcher(level CompressionLevel, hashLog int) *HCMatcher {
+	// Search parameters for the level, aligned with liblz4's HC levels
+	params := paramsForLevel(level)
+	maxAttempts := params.maxAttempts
+	windowSize := params.windowSize
+	useEnhancedHC := params.hash5
 
 	hashSize := 1 << hashLog
 	hashMask := hashSize - 1
@@ -87,10 +112,12 @@ func NewHCMatcher(level CompressionLevel) *HCMatcher {
 		chainTable:    nil, // Lazily initialized
 		maxAttempts:   maxAttempts,
 		windowSize:    windowSize,
+		levelLog:      params.hashLog,
 		hashLog:       hashLog,
 		hashSize:      hashSize,
 		hashMask:      hashMask,
 		useEnhancedHC: useEnhancedHC,
+		skipTrigger:   params.skipTrigger,
 	}
 }
 
@@ -107,10 +134,23 @@ func (hc *HCMatcher) Reset(input []byte) {
 		hc.chainTable = hc.chainTable[:len(input)]
 	}
 
-	// Reset hash table
-	for i := range hc.hashTable {
-		hc.hashTable[i] = 0
+	// Size the hash table for the input, then clear it
+	hc.setHashLog(hashLogFor(len(input), hc.levelLog, hc.maxAttempts))
+	clear(hc.hashTable)
+}
+
+// setHashLog sizes the hash table to 1<<hashLog entries, reusing its memory
+// if it has room
+func (hc *HCMatcher) setHashLog(hashLog int) {
+	size := 1 << hashLog
+	if cap(hc.hashTable) < size {
+		hc.hashTable = make([]int, size)
+	} else {
+		hc.hashTable = hc.hashTable[:size]
 	}
+	hc.hashLog = hashLog
+	hc.hashSize = size
+	hc.hashMask = size - 1
 }
 
 // hash4 computes a 4-byte hash
@@ -153,8 +193,9 @@ func (hc *HCMatcher) InsertHash(pos int) {
 	// Save current hash entry in chainTable
 	hc.chainTable[pos] = hc.hashTable[h]
 
-	// Update hash table to point to current position
-	hc.hashTable[h] = pos
+	// Update hash table to point to current position. The tables hold
+	// positions plus one, so that zero means none and position 0 can match.
+	hc.hashTable[h] = pos + 1
 }
 
 // FindBestMatch finds the best match at the current position
@@ -171,10 +212,14 @@ func (hc *HCMatcher) FindBestMatch() (offset, length int) {
 		h = hc.hash4(hc.pos)
 	}
 
-	current := hc.hashTable[h]
+	current := hc.hashTable[h] - 1
+
+	// Candidates must lie within the buffer and at most windowSize back,
+	// the largest offset
+	limit := max(hc.pos-hc.windowSize, 0) - 1
 
 	// No match
-	if current <= 0 || current <= hc.pos-hc.windowSize {
+	if current <= limit {
 		hc.InsertHash(hc.pos)
 		return 0, 0
 	}
@@ -182,7 +227,6 @@ func (hc *HCMatcher) FindBestMatch() (offset, length int) {
 	// Find the best match
 	bestLength := 0
 	bestOffset := 0
-	limit := hc.pos - hc.windowSize
 	attempts := hc.maxAttempts
 
 	// Enhanced search algorithm for v0.3
@@ -223,7 +267,7 @@ func (hc *HCMatcher) FindBestMatch() (offset, length int) {
 		}
 
 		// Move to next position in chain
-		current = hc.chainTable[current]
+		current = hc.chainTable[current] - 1
 	}
 
 	// Insert current position
@@ -2,9 +2,10 @@ package compress
 
 import (
 	"bytes"
-	"math/rand"
+	"fmt"
 	"testing"
-	"time"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
 // Test creating a new HCMatcher with different compression levels
@@ -112,6 +113,53 @@ func TestHCMatcherReset(t *testing.T) {
 	}
 }
 
+// TestHCMatcherHashTableSize tests that Reset sizes the hash table for the
+// input and that a reused Compressor matches one-shot compression at every
+// size
+func TestHCMatcherHashTableSize(t *testing.T) {
+	tests := []struct {
+		level     CompressionLevel
+		inputSize int
+		hashLog   int
+	}{
+		{2, 100, minHashLog},
+		{2, 4096, 14},
+		{2, 64 * 1024, HashLog},
+		{9, 1024, 14},
+		{9, 4096, HashLog},
+		{12, 64 * 1024, HashLogHC},
+	}
+	for _, tt := range tests {
+		matcher := NewHCMatcher(tt.level)
+		matcher.Reset(generateCompressibleData(tt.inputSize))
+		if matcher.hashLog != tt.hashLog || len(matcher.hashTable) != 1<<tt.hashLog {
+			t.Errorf("level %d, %d bytes: hashLog = %d with %d entries, want %d",
+				tt.level, tt.inputSize, matcher.hashLog, len(matcher.hashTable), tt.hashLog)
+		}
+	}
+
+	for _, level := range []CompressionLevel{2, 9} {
+		c, err := NewCompressor(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []int{64 * 1024, 1000, 16 * 1024, 300, 200 * 1024, 5000} {
+			input := generateCompressibleData(size)
+			got, err := c.CompressBlock(input, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := CompressBlockLevel(input, nil, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("level %d, %d bytes: reused Compressor output differs from CompressBlockLevel", level, size)
+			}
+		}
+	}
+}
+
 // Test hash4 function
 func TestHCMatcherHash4(t *testing.T) {
 	matcher := NewHCMatcher(DefaultLevel)
@@ -166,9 +214,9 @@ func TestHCMatcherInsertHash(t *testing.T) {
 	// Calculate the hash value
 	h0 := matcher.hash4(0)
 
-	// Verify hash table entry
-	if matcher.hashTable[h0] != 0 {
-		t.Errorf("hashTable[%v] = %v, want 0", h0, matcher.hashTable[h0])
+	// Verify hash table entry, which holds the position plus one
+	if matcher.hashTable[h0] != 1 {
+		t.Errorf("hashTable[%v] = %v, want 1", h0, matcher.hashTable[h0])
 	}
 
 	// Set up a second insert at position 8
@@ -185,13 +233,13 @@ func TestHCMatcherInsertHash(t *testing.T) {
 	matcher.InsertHash(pos2)
 
 	// Now hashTable should have been updated to the new position
-	if matcher.hashTable[h0] != pos2 {
-		t.Errorf("hashTable[%v] = %v, want %v", h0, matcher.hashTable[h0], pos2)
+	if matcher.hashTable[h0] != pos2+1 {
+		t.Errorf("hashTable[%v] = %v, want %v", h0, matcher.hashTable[h0], pos2+1)
 	}
 
 	// And chainTable should link to the previous position
-	if matcher.chainTable[pos2] != 0 {
-		t.Errorf("chainTable[%v] = %v, want 0", pos2, matcher.chainTable[pos2])
+	if matcher.chainTable[pos2] != 1 {
+		t.Errorf("chainTable[%v] = %v, want 1", pos2, matcher.chainTable[pos2])
 	}
 }
 
@@ -380,57 +428,43 @@ func TestHCMatcherUpdateTables(t *testing.T) {
 
 // TestHCMatcherLevels tests the HC matcher with different compression levels
 func TestHCMatcherLevels(t *testing.T) {
-	// Test all compression levels to ensure proper behavior
-	for level := CompressionLevel(1); level <= 12; level++ {
-		t.Run("Level-"+string(rune('0'+level)), func(t *testing.T) {
-			// Create HC matcher with this level
-			matcher := NewHCMatcher(level)
+	// Search depths follow the liblz4 HC levels
+	tests := []struct {
+		level       CompressionLevel
+		maxAttempts int
+		windowSize  int
+		enhanced    bool
+		hashLog     int
+	}{
+		{1, 1, 32 * 1024, false, HashLog},
+		{2, 2, 32 * 1024, false, HashLog},
+		{3, 4, 32 * 1024, false, HashLog},
+		{4, 8, 32 * 1024, false, HashLog},
+		{5, 16, 32 * 1024, false, HashLog},
+		{6, 32, MaxDistance, false, HashLog},
+		{7, 64, MaxDistance, true, HashLog},
+		{8, 128, MaxDistance, true, HashLog},
+		{9, 256, MaxDistance, true, HashLog},
+		{10, 384, MaxDistance, true, HashLogHC},
+		{11, 512, MaxDistance, true, HashLogHC},
+		{12, 1024, MaxDistance, true, HashLogHC},
+	}
 
-			// Check configuration based on level
-			switch {
-			case level <= 3:
-				if matcher.maxAttempts != 4 {
-					t.Errorf("Expected maxAttempts to be 4 for level %d, got %d", level, matcher.maxAttempts)
-				}
-				if matcher.windowSize != 16*1024 {
-					t.Errorf("Expected windowSize to be %d for level %d, got %d", 16*1024, level, matcher.windowSize)
-				}
-				if matcher.useEnhancedHC {
-					t.Errorf("Expected useEnhancedHC to be false for level %d", level)
-				}
-			case level <= 6:
-				if matcher.maxAttempts != 8 {
-					t.Errorf("Expected maxAttempts to be 8 for level %d, got %d", level, matcher.maxAttempts)
-				}
-				if matcher.windowSize != 32*1024 {
-					t.Errorf("Expected windowSize to be %d for level %d, got %d", 32*1024, level, matcher.windowSize)
-				}
-				if matcher.useEnhancedHC {
-					t.Errorf("Expected useEnhancedHC to be false for level %d", level)
-				}
-			case level <= 9:
-				if matcher.maxAttempts != 16 {
-					t.Errorf("Expected maxAttempts to be 16 for level %d, got %d", level, matcher.maxAttempts)
-				}
-				if matcher.windowSize != 64*1024 {
-					t.Errorf("Expected windowSize to be %d for level %d, got %d", 64*1024, level, matcher.windowSize)
-				}
-				if !matcher.useEnhancedHC {
-					t.Errorf("Expected useEnhancedHC to be true for level %d", level)
-				}
-			default: // level > 9
-				if matcher.maxAttempts != 32 {
-					t.Errorf("Expected maxAttempts to be 32 for level %d, got %d", level, matcher.maxAttempts)
-				}
-				if matcher.windowSize != MaxDistance {
-					t.Errorf("Expected windowSize to be %d for level %d, got %d", MaxDistance, level, matcher.windowSize)
-				}
-				if !matcher.useEnhancedHC {
-					t.Errorf("Expected useEnhancedHC to be true for level %d", level)
-				}
-				if matcher.hashLog != HashLogHC {
-					t.Errorf("Expected hashLog to be %d for level %d, got %d", HashLogHC, level, matcher.hashLog)
-				}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Level-%d", tt.level), func(t *testing.T) {
+			matcher := NewHCMatcher(tt.level)
+
+			if matcher.maxAttempts != tt.maxAttempts {
+				t.Errorf("maxAttempts = %d, want %d", matcher.maxAttempts, tt.maxAttempts)
+			}
+			if matcher.windowSize != tt.windowSize {
+				t.Errorf("windowSize = %d, want %d", matcher.windowSize, tt.windowSize)
+			}
+			if matcher.useEnhancedHC != tt.enhanced {
+				t.Errorf("useEnhancedHC = %v, want %v", matcher.useEnhancedHC, tt.enhanced)
+			}
+			if matcher.hashLog != tt.hashLog {
+				t.Errorf("hashLog = %d, want %d", matcher.hashLog, tt.hashLog)
 			}
 		})
 	}
@@ -629,7 +663,7 @@ func TestCompressionQuality(t *testing.T) {
 
 // Helper function to create test data with repeated patterns
 func createRepeatedData(size int) []byte {
-	rand.Seed(time.Now().UnixNano())
+	rng := testgen.Rand(seeds.Next())
 	data := make([]byte, size)
 
 	// Create several patterns
@@ -638,7 +672,7 @@ func createRepeatedData(size int) []byte {
 	for i := 0; i < patternCount; i++ {
 		patterns[i] = make([]byte, 128)
 		for j := 0; j < 128; j++ {
-			patterns[i][j] = byte(rand.Intn(256))
+			patterns[i][j] = byte(rng.Intn(256))
 		}
 	}
 
@@ -646,23 +680,23 @@ func createRepeatedData(size int) []byte {
 	pos := 0
 	for pos < size {
 		// Pick a random pattern
-		pattern := patterns[rand.Intn(patternCount)]
+		pattern := patterns[rng.Intn(patternCount)]
 
 		// Determine length of this pattern (with some variation)
-		repeatCount := rand.Intn(64) + 1
+		repeatCount := rng.Intn(64) + 1
 		for i := 0; i < repeatCount && pos < size; i++ {
 			// Copy the pattern
 			copyLen := min(len(pattern), size-pos)
 			copy(data[pos:], pattern[:copyLen])
 
 			// Maybe modify a few bytes to create some variations
-			if rand.Float32() < 0.2 {
+			if rng.Float32() < 0.2 {
 				// Modify 1-3 bytes
-				modCount := rand.Intn(3) + 1
+				modCount := rng.Intn(3) + 1
 				for j := 0; j < modCount && pos+j < size; j++ {
-					modPos := pos + rand.Intn(copyLen)
+					modPos := pos + rng.Intn(copyLen)
 					if modPos < size {
-						data[modPos] = byte(rand.Intn(256))
+						data[modPos] = byte(rng.Intn(256))
 					}
 				}
 			}
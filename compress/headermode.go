@@ -0,0 +1,73 @@
+package compress
+
+import (
+	"errors"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+const (
+	// flagReserved is the reserved bit of the FLG byte
+	flagReserved = format.FlagReserved
+	// bdReserved holds the reserved bits of the BD byte
+	bdReserved = format.BDReserved
+)
+
+var (
+	// ErrUnsupportedVersion indicates a frame header with a version other
+	// than 1, read without HeaderLenient
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrReservedBits indicates a frame header with reserved bits set, read
+	// with HeaderStrict
+	ErrReservedBits = errors.New("reserved frame header bits set")
+	// ErrHeaderChecksum indicates a frame header whose HC byte doesn't
+	// match the rest of its descriptor, read without HeaderLenient
+	ErrHeaderChecksum = errors.New("frame header checksum mismatch")
+)
+
+// HeaderMode sets how a Reader treats the parts of frame headers it doesn't
+// know: other versions and reserved bits
+type HeaderMode int
+
+const (
+	// HeaderDefault accepts only version 1 headers and ignores reserved bits
+	HeaderDefault HeaderMode = iota
+	// HeaderLenient accepts headers of any version, reading them as version
+	// 1: the optional fields it knows are sized by their flags and skipped
+	// if unused, and reserved bits and the header checksum are ignored. It
+	// lets frames from newer encoders that keep the version 1 layout be
+	// read, as well as frames without optional fields from GoZ4X releases
+	// that wrote the header checksum as 0.
+	HeaderLenient
+	// HeaderStrict accepts only version 1 headers and rejects any with
+	// reserved bits set, for validating the output of encoders. It checks
+	// the header checksum, as HeaderDefault does.
+	HeaderStrict
+)
+
+// checkFLG checks the version and reserved bit of a FLG byte
+func (m HeaderMode) checkFLG(flg byte) error {
+	if m != HeaderLenient && (flg>>6)&0x3 != 1 {
+		return ErrUnsupportedVersion
+	}
+	if m == HeaderStrict && flg&flagReserved != 0 {
+		return ErrReservedBits
+	}
+	return nil
+}
+
+// checkBD checks the reserved bits of a BD byte
+func (m HeaderMode) checkBD(bd byte) error {
+	if m == HeaderStrict && bd&bdReserved != 0 {
+		return ErrReservedBits
+	}
+	return nil
+}
+
+// checkHC checks the header checksum hc of the frame descriptor desc
+func (m HeaderMode) checkHC(desc []byte, hc byte) error {
+	if m != HeaderLenient && headerChecksum(desc) != hc {
+		return ErrHeaderChecksum
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestReaderHeaderMode tests which headers each HeaderMode accepts
+func TestReaderHeaderMode(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frame := checksummedFrame(t, data)
+
+	// modify returns a copy of frame with byte i of the header changed and
+	// the header checksum updated to match
+	modify := func(i int, fn func(byte) byte) []byte {
+		m := bytes.Clone(frame)
+		m[i] = fn(m[i])
+		return resealHeader(m)
+	}
+	withVersion := func(v byte) func(byte) byte {
+		return func(b byte) byte { return b&0x3F | v<<6 }
+	}
+
+	tests := []struct {
+		name   string
+		frame  []byte
+		errors map[HeaderMode]error
+	}{
+		{"Valid", frame, map[HeaderMode]error{}},
+		{"Version 0", modify(4, withVersion(0)), map[HeaderMode]error{
+			HeaderDefault: ErrUnsupportedVersion,
+			HeaderStrict:  ErrUnsupportedVersion,
+		}},
+		{"Version 2", modify(4, withVersion(2)), map[HeaderMode]error{
+			HeaderDefault: ErrUnsupportedVersion,
+			HeaderStrict:  ErrUnsupportedVersion,
+		}},
+		{"Reserved FLG Bit", modify(4, func(b byte) byte { return b | flagReserved }), map[HeaderMode]error{
+			HeaderStrict: ErrReservedBits,
+		}},
+		{"Reserved BD Bits", modify(5, func(b byte) byte { return b | 0x81 }), map[HeaderMode]error{
+			HeaderStrict: ErrReservedBits,
+		}},
+		{"Header Checksum", func() []byte {
+			m := bytes.Clone(frame)
+			m[14] ^= 0xFF // HC follows the content size
+			return m
+		}(), map[HeaderMode]error{
+			HeaderDefault: ErrHeaderChecksum,
+			HeaderStrict:  ErrHeaderChecksum,
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []HeaderMode{HeaderDefault, HeaderLenient, HeaderStrict} {
+			r := NewReaderWithOptions(bytes.NewReader(tt.frame), ReaderOptions{HeaderMode: mode})
+			got, err := io.ReadAll(r)
+			if want := tt.errors[mode]; err != want {
+				t.Errorf("%s, mode %d: ReadAll() error = %v, want %v", tt.name, mode, err, want)
+				continue
+			}
+			if err == nil && !bytes.Equal(got, data) {
+				t.Errorf("%s, mode %d: ReadAll() data mismatch", tt.name, mode)
+			}
+		}
+	}
+}
+
+// TestWriterHeaderStrict tests that frames written with every option pass
+// HeaderStrict
+func TestWriterHeaderStrict(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frames := [][]byte{
+		compressFrame(t, data, nil),
+		checksummedFrame(t, data),
+		writeDictFrame(t, data, func() DictionaryStore {
+			s := NewMemoryDictionaryStore()
+			s.Publish(dictionaryFrom(generateRecords(100, 0)))
+			return s
+		}()),
+	}
+
+	for i, frame := range frames {
+		zr := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{HeaderMode: HeaderStrict})
+		if err := zr.ReadHeader(); err != nil {
+			t.Errorf("frame %d: ReadHeader() error = %v", i, err)
+		}
+	}
+}
+
+// resealHeader sets the header checksum of the frame at the start of b to
+// match its descriptor again, after a test changed it
+func resealHeader(b []byte) []byte {
+	n := 6
+	if b[4]&flagContentSize != 0 {
+		n += 8
+	}
+	if b[4]&flagDictID != 0 {
+		n += 4
+	}
+	b[n] = headerChecksum(b[4:n])
+	return b
+}
@@ -1,6 +1,8 @@
 package compress
 
 import (
+	"io"
+
 	"github.com/harriteja/GoZ4X/matcher"
 )
 
@@ -35,7 +37,7 @@ func NewV2Block(src []byte, level CompressionLevel, options BlockOptions) (*V2Bl
 
 	// Create configuration based on compression level
 	config := matcher.LZ4XConfig{
-		HashLog:      16,
+		HashLog:      uint(hashLogFor(len(src), 16, 8)),
 		WindowSize:   65535,
 		MaxAttempts:  8,
 		SkipStrength: 1,
@@ -43,18 +45,13 @@ func NewV2Block(src []byte, level CompressionLevel, options BlockOptions) (*V2Bl
 
 	// Adjust settings based on compression level for better performance
 	// Higher levels do more thorough searching for matches
+	config.MaxAttempts = paramsForLevel(level).maxAttempts
 	switch {
 	case level <= 3:
-		config.MaxAttempts = 4
 		config.SkipStrength = 1
-	case level <= 6:
-		config.MaxAttempts = 8
-		config.SkipStrength = 2
 	case level <= 9:
-		config.MaxAttempts = 16
 		config.SkipStrength = 2
 	default:
-		config.MaxAttempts = 32
 		config.SkipStrength = 3
 	}
 
@@ -70,13 +67,16 @@ func NewV2Block(src []byte, level CompressionLevel, options BlockOptions) (*V2Bl
 	}, nil
 }
 
-// CompressToBuffer compresses the block data to the provided buffer
+// CompressToBuffer compresses the block data to the provided buffer,
+// allocating one of the worst-case size if it is too small. Every sequence
+// is checked against the end of the buffer before it is written, so an
+// output past the bound fails with io.ErrShortBuffer instead of panicking.
 func (b *V2Block) CompressToBuffer(dst []byte) ([]byte, error) {
 	// Input data and length
 	inputLen := len(b.src)
 
 	// Calculate worst-case output size
-	worstCaseSize := inputLen + (inputLen / 255) + 16
+	worstCaseSize := compressBound(inputLen)
 
 	// Allocate buffer if needed
 	if dst == nil || len(dst) < worstCaseSize {
@@ -89,6 +89,9 @@ func (b *V2Block) CompressToBuffer(dst []byte) ([]byte, error) {
 
 	// LastLiteral is the position where the last literal block started
 	lastLiteral := 0
+	// Misses counts the positions searched since the last match
+	misses := 0
+	skipTrigger := paramsForLevel(b.level).skipTrigger
 
 	// Pre-initialize hash table for better compression
 	if b.level >= 4 {
@@ -108,19 +111,48 @@ func (b *V2Block) CompressToBuffer(dst []byte) ([]byte, error) {
 
 	// Main compression loop
 	for !b.matcher.End() {
-		// Find the best match at the current position
-		offset, matchLen := b.matcher.FindBestMatch()
+		// No match may start within the last mfLimit bytes
+		if srcPos > inputLen-mfLimit {
+			break
+		}
+
+		// Keep the last lastLiterals bytes as literals
+		matchLimit := inputLen - lastLiterals
+
+		var offset, matchLen int
+		if run := byteRun(b.src, srcPos, matchLimit); run >= rleMinRun {
+			// Match runs of one byte at offset 1, after a literal of their
+			// first byte unless the byte before them is the same
+			if srcPos == 0 || b.src[srcPos-1] != b.src[srcPos] {
+				b.matcher.AdvanceHashOnly(1)
+				srcPos++
+				run--
+			}
+			offset, matchLen = 1, run
+		} else {
+			// Find the best match at the current position
+			offset, matchLen = b.matcher.FindBestMatch()
+			matchLen = min(matchLen, matchLimit-srcPos)
+		}
 
 		// If no good match, advance and continue
 		if matchLen < 4 {
-			// No good match found, just advance one byte
+			// No good match found, advance faster the longer matches are missing
+			step := skipStep(misses, skipTrigger, inputLen-mfLimit+1-srcPos)
+			misses++
+			// Skipped positions are still hashed, so that data after an
+			// incompressible stretch can match them
 			b.matcher.Advance(1)
-			srcPos++
+			b.matcher.AdvanceHashOnly(step - 1)
+			srcPos += step
 			continue
 		}
 
 		// We found a match, output the literal sequence since the last match
 		literalLen := srcPos - lastLiteral
+		if dstPos+sequenceSize(literalLen, matchLen) > len(dst) {
+			return nil, io.ErrShortBuffer
+		}
 
 		// Write token: 4 bits for literal length, 4 bits for match length
 		literalLenCode := min(literalLen, 15)
@@ -167,6 +199,7 @@ func (b *V2Block) CompressToBuffer(dst []byte) ([]byte, error) {
 		// Advance source position and last literal marker
 		srcPos += matchLen
 		lastLiteral = srcPos
+		misses = 0
 
 		// Advance the matcher
 		b.matcher.Advance(matchLen)
@@ -175,6 +208,9 @@ func (b *V2Block) CompressToBuffer(dst []byte) ([]byte, error) {
 	// Handle the final literal block
 	if lastLiteral < inputLen {
 		literalLen := inputLen - lastLiteral
+		if dstPos+sequenceSize(literalLen, 0) > len(dst) {
+			return nil, io.ErrShortBuffer
+		}
 
 		// Write token: literal only, no match
 		literalLenCode := min(literalLen, 15)
@@ -217,6 +253,9 @@ func CompressBlockV2Level(src []byte, dst []byte, level CompressionLevel) ([]byt
 	if err != nil {
 		return nil, err
 	}
+	if level == NoCompression {
+		return storeBlock(src, dst), nil
+	}
 
 	// Compress the data
 	return block.CompressToBuffer(dst)
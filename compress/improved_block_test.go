@@ -3,8 +3,9 @@ package compress
 import (
 	"bytes"
 	"fmt"
-	"math/rand"
 	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
 func TestCompressBlockV2(t *testing.T) {
@@ -185,34 +186,31 @@ func TestCompressBlockV2VsV1(t *testing.T) {
 
 // genRandomData creates random data of the specified size
 func genRandomData(size int) []byte {
-	data := make([]byte, size)
-	for i := range data {
-		data[i] = byte(rand.Intn(256))
-	}
-	return data
+	return testgen.Random(seeds.Next(), size)
 }
 
 // genDataWithRepetition creates data with some repetitive patterns
 func genDataWithRepetition(size int) []byte {
+	rng := testgen.Rand(seeds.Next())
 	data := make([]byte, size)
 
 	// Generate base pattern
 	pattern := make([]byte, 100)
 	for i := range pattern {
-		pattern[i] = byte(rand.Intn(256))
+		pattern[i] = byte(rng.Intn(256))
 	}
 
 	// Fill with pattern and variations
 	for i := 0; i < size; {
 		// Decide whether to use exact pattern or a variation
-		if rand.Intn(10) < 7 {
+		if rng.Intn(10) < 7 {
 			// Use exact pattern
 			patternLen := min(len(pattern), size-i)
 			copy(data[i:], pattern[:patternLen])
 			i += patternLen
 		} else {
 			// Use variation
-			variation := byte(rand.Intn(5))
+			variation := byte(rng.Intn(5))
 			patternLen := min(len(pattern), size-i)
 			for j := 0; j < patternLen; j++ {
 				data[i+j] = pattern[j] + variation
@@ -0,0 +1,20 @@
+package compress
+
+// The encoders give up quickly on incompressible data the way liblz4's fast
+// compressor does. Each position searched without finding a match counts as
+// a miss, and once a level's 1<<skipTrigger misses have piled up since the
+// last match, positions start being skipped as literals without a search:
+// the step grows by one for every further 1<<skipTrigger misses, and drops
+// back to one at the next match. Random data then costs a few thousand
+// searches per megabyte at any level instead of one per byte, leaving
+// little more than hashing, while data with matches every few hundred bytes
+// never reaches the trigger. Skipped positions are still hashed, unlike in
+// liblz4, so that data following an incompressible stretch can refer back
+// to it. Higher levels wait longer before skipping, trading speed on mixed
+// data for ratio.
+
+// skipStep returns how far to advance after a miss, given the misses since
+// the last match and the level's skipTrigger, but never more than room
+func skipStep(misses int, skipTrigger uint, room int) int {
+	return max(1, min(1+misses>>skipTrigger, room))
+}
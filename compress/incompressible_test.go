@@ -0,0 +1,90 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestSkipStep tests the step growth of the incompressibility heuristic
+func TestSkipStep(t *testing.T) {
+	tests := []struct {
+		misses  int
+		trigger uint
+		room    int
+		want    int
+	}{
+		{0, 6, 100, 1},
+		{63, 6, 100, 1},
+		{64, 6, 100, 2},
+		{640, 6, 100, 11},
+		{640, 10, 100, 1},
+		{640, 6, 5, 5},
+		{640, 6, 0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := skipStep(tt.misses, tt.trigger, tt.room); got != tt.want {
+			t.Errorf("skipStep(%d, %d, %d) = %d, want %d", tt.misses, tt.trigger, tt.room, got, tt.want)
+		}
+	}
+}
+
+// TestIncompressibleRecovery tests that matches after a long incompressible
+// stretch are still found, since the step drops back at the first match
+func TestIncompressibleRecovery(t *testing.T) {
+	compressible := generateCompressibleData(256 * 1024)
+	mixed := append(generateRandomData(256*1024), compressible...)
+
+	encoders := []struct {
+		name     string
+		compress func(src []byte, level CompressionLevel) ([]byte, error)
+	}{
+		{"HC", func(src []byte, level CompressionLevel) ([]byte, error) { return CompressBlockLevel(src, nil, level) }},
+		{"V2", func(src []byte, level CompressionLevel) ([]byte, error) { return CompressBlockV2Level(src, nil, level) }},
+	}
+
+	for _, enc := range encoders {
+		for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+			t.Run(fmt.Sprintf("%s/Level-%d", enc.name, level), func(t *testing.T) {
+				alone, err := enc.compress(compressible, level)
+				if err != nil {
+					t.Fatalf("compress error = %v", err)
+				}
+				got, err := enc.compress(mixed, level)
+				if err != nil {
+					t.Fatalf("compress error = %v", err)
+				}
+
+				// The random half stays about its size, the rest compresses as alone
+				if limit := compressBound(256*1024) + len(alone) + 1024; len(got) > limit {
+					t.Errorf("compressed to %d bytes, want at most %d", len(got), limit)
+				}
+				out, err := DecompressBlock(got, nil, len(mixed))
+				if err != nil || !bytes.Equal(out, mixed) {
+					t.Errorf("DecompressBlock() = %d bytes, %v, want the input", len(out), err)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkIncompressible measures compressing random data, which the
+// heuristic keeps close to the same speed at every level
+func BenchmarkIncompressible(b *testing.B) {
+	src := generateRandomData(1 << 20)
+	dst := make([]byte, compressBound(len(src)))
+
+	for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+		c, err := NewCompressor(level)
+		if err != nil {
+			b.Fatalf("NewCompressor() error = %v", err)
+		}
+		b.Run(fmt.Sprintf("Level-%d", level), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			for i := 0; i < b.N; i++ {
+				c.CompressBlock(src, dst)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package compress
+
+import (
+	"io"
+	"iter"
+)
+
+// BlockInfo describes one block of a frame parsed by FrameBuffer
+type BlockInfo struct {
+	// Index is the position of the block among the blocks holding data
+	Index int
+	// Offset and Size locate the block in the uncompressed data
+	Offset int64
+	Size   int
+	// FrameOffset and CompressedSize locate the block data in the frame,
+	// after its size word and before any block checksum
+	FrameOffset    int
+	CompressedSize int
+	// Stored is set for blocks stored uncompressed
+	Stored bool
+}
+
+// Blocks returns an iterator over the blocks of the parsed frame, in order,
+// for ranging over its layout without decompressing anything. Blocks are
+// decoded with DecodeBlock(info.Index).
+func (fb *FrameBuffer) Blocks() iter.Seq[BlockInfo] {
+	return func(yield func(BlockInfo) bool) {
+		for i, b := range fb.blocks {
+			info := BlockInfo{
+				Index:          i,
+				Offset:         b.rawOff,
+				Size:           b.rawLen,
+				FrameOffset:    b.pos,
+				CompressedSize: b.size,
+				Stored:         b.stored,
+			}
+			if !yield(info) {
+				return
+			}
+		}
+	}
+}
+
+// Chunks returns an iterator over the decompressed data, yielding it a
+// block at a time straight from the Reader's buffer, as WriteTo does. A
+// chunk is only valid until the next iteration. The iteration stops after
+// yielding an error, which is sticky as for Read, and ends without one at
+// the end of the stream. Breaking out of the loop leaves the rest of the
+// current block to be returned by Read or a later Chunks.
+func (r *Reader) Chunks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			chunk, err := r.nextChunk()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+// nextChunk returns the unread data of the current block, reading the next
+// block if it is consumed, or io.EOF at the end of the stream. The lock is
+// only held while reading, so the body of a Chunks loop may use the Reader.
+func (r *Reader) nextChunk() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrReaderClosed
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.reachedEof {
+		return nil, io.EOF
+	}
+	if err := r.ensureHeader(); err != nil {
+		return nil, err
+	}
+
+	for r.out.len() == 0 {
+		r.out.reset()
+		if _, err := r.readBlock(nil); err != nil {
+			if err == io.EOF {
+				r.reachedEof = true
+				return nil, io.EOF
+			}
+			r.err = err
+			return nil, err
+		}
+	}
+
+	chunk := r.out.unread()
+	r.out.discard(len(chunk))
+	r.produced.Add(uint64(len(chunk)))
+	return chunk, nil
+}
@@ -0,0 +1,140 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// TestFrameBufferBlocks tests that the blocks of a parsed frame cover its
+// data in order and decode to it
+func TestFrameBufferBlocks(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := append(generateCompressibleData(3*blockSize+500), generateRandomData(blockSize)...)
+	frame := compressFrame(t, data, func(w *Writer) { w.blockSize = blockSize })
+
+	var fb FrameBuffer
+	if err := fb.Parse(frame); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var offset int64
+	var stored int
+	for info := range fb.Blocks() {
+		if info.Offset != offset {
+			t.Errorf("block %d Offset = %d, want %d", info.Index, info.Offset, offset)
+		}
+		if info.FrameOffset+info.CompressedSize > len(frame) {
+			t.Errorf("block %d ends at %d, beyond the %d byte frame", info.Index, info.FrameOffset+info.CompressedSize, len(frame))
+		}
+		raw, err := fb.DecodeBlock(info.Index)
+		if err != nil || len(raw) != info.Size || !bytes.Equal(raw, data[offset:offset+int64(info.Size)]) {
+			t.Errorf("DecodeBlock(%d) = %d bytes, %v, want %d", info.Index, len(raw), err, info.Size)
+		}
+		if info.Stored {
+			stored++
+		}
+		offset += int64(info.Size)
+	}
+	if offset != int64(len(data)) {
+		t.Errorf("blocks cover %d bytes, want %d", offset, len(data))
+	}
+	if stored != 1 {
+		t.Errorf("%d stored blocks, want 1", stored)
+	}
+
+	// Breaking out of the loop stops the iteration
+	var n int
+	for range fb.Blocks() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("%d blocks after break, want 1", n)
+	}
+}
+
+// TestReaderChunks tests ranging over the decompressed data of a stream
+func TestReaderChunks(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := append(generateCompressibleData(3*blockSize+500), generateRandomData(blockSize)...)
+	frame := compressFrame(t, data, func(w *Writer) { w.blockSize = blockSize })
+
+	t.Run("All", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(frame))
+		var got []byte
+		var chunks int
+		for chunk, err := range r.Chunks() {
+			if err != nil {
+				t.Fatalf("Chunks() error = %v", err)
+			}
+			got = append(got, chunk...)
+			chunks++
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Chunks() = %d bytes, want %d", len(got), len(data))
+		}
+		if chunks != 5 {
+			t.Errorf("Chunks() yielded %d chunks, want 5", chunks)
+		}
+		if r.Produced() != uint64(len(data)) {
+			t.Errorf("Produced() = %d, want %d", r.Produced(), len(data))
+		}
+	})
+
+	t.Run("Mixed with Read", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(frame))
+		head := make([]byte, 100)
+		if _, err := io.ReadFull(r, head); err != nil {
+			t.Fatalf("ReadFull() error = %v", err)
+		}
+		got := append([]byte(nil), head...)
+
+		// The first chunk is the rest of the block Read started, and the
+		// loop body may read from the Reader too
+		for chunk, err := range r.Chunks() {
+			if err != nil {
+				t.Fatalf("Chunks() error = %v", err)
+			}
+			got = append(got, chunk...)
+			if _, err := io.ReadFull(r, head); err != nil {
+				t.Fatalf("ReadFull() error = %v", err)
+			}
+			got = append(got, head...)
+			break
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if got = append(got, rest...); !bytes.Equal(got, data) {
+			t.Errorf("read %d bytes, want %d", len(got), len(data))
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		r := NewReader(bytes.NewReader(frame[:len(frame)/2]))
+		var errs int
+		for _, err := range r.Chunks() {
+			if err != nil {
+				if !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Errorf("Chunks() error = %v, want %v", err, io.ErrUnexpectedEOF)
+				}
+				errs++
+			}
+		}
+		if errs != 1 {
+			t.Errorf("Chunks() yielded %d errors, want 1", errs)
+		}
+		if _, err := r.Read(make([]byte, 10)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Read() after Chunks() error = %v, want %v", err, io.ErrUnexpectedEOF)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		for range NewReader(bytes.NewReader(nil)).Chunks() {
+			t.Errorf("Chunks() of an empty stream yielded a chunk")
+		}
+	})
+}
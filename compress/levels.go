@@ -0,0 +1,95 @@
+package compress
+
+import "fmt"
+
+// Compression levels follow the levels of liblz4 so settings carry over when
+// migrating. Levels 1 and 2 cover liblz4's fast compressor, and levels 3 to 12
+// search as deep as the liblz4 HC level with the same number. The targets
+// below are for CompressBlockLevel on English text; each level searches about
+// twice as many candidates as the one below it.
+//
+//	Level  Search depth  Window  Ratio target  Speed target
+//	0      none          -       1.0x          stored, NoCompression
+//	1      1             32KB    2.4x          fastest, LZ4_compress_fast
+//	2      2             32KB    2.6x          LZ4_compress_default
+//	3-5    4-16          32KB    2.8x-3.5x     about 1/2 to 1/3 of level 1
+//	6-9    32-256        64KB    3.9x-4.2x     about 1/4 to 1/12 of level 1
+//	10-12  384-1024      64KB    best          slowest, for archival use
+const (
+	// LZ4HCDefaultLevel is liblz4's default HC level (LZ4HC_CLEVEL_DEFAULT)
+	LZ4HCDefaultLevel = 9
+)
+
+// levelParams describes the match finder parameters for one compression level
+type levelParams struct {
+	maxAttempts int
+	windowSize  int
+	hash5       bool
+	hashLog     int
+	// skipTrigger sets how soon the encoders speed up over data without
+	// matches: the step grows by one every 1<<skipTrigger missed positions
+	skipTrigger uint
+}
+
+// levelTable holds the match finder parameters for levels 0 to MaxLevel.
+// Level 0 stores data without a match finder; matchers created for it use
+// the parameters of level 1.
+var levelTable = [MaxLevel + 1]levelParams{
+	{1, 32 * 1024, false, HashLog, 6},
+	{1, 32 * 1024, false, HashLog, 6},
+	{2, 32 * 1024, false, HashLog, 6},
+	{4, 32 * 1024, false, HashLog, 7},
+	{8, 32 * 1024, false, HashLog, 7},
+	{16, 32 * 1024, false, HashLog, 7},
+	{32, MaxDistance, false, HashLog, 8},
+	{64, MaxDistance, true, HashLog, 8},
+	{128, MaxDistance, true, HashLog, 9},
+	{256, MaxDistance, true, HashLog, 9},
+	{384, MaxDistance, true, HashLogHC, 10},
+	{512, MaxDistance, true, HashLogHC, 10},
+	{1024, MaxDistance, true, HashLogHC, 10},
+}
+
+// String returns "NoCompression" for level 0 and "level N" for the other
+// valid levels. Level numbers are stable: a level keeps its number and
+// its place between the levels around it, though its tuning may change.
+func (l CompressionLevel) String() string {
+	switch {
+	case l == NoCompression:
+		return "NoCompression"
+	case l > NoCompression && l <= MaxLevel:
+		return fmt.Sprintf("level %d", int(l))
+	default:
+		return fmt.Sprintf("CompressionLevel(%d)", int(l))
+	}
+}
+
+// paramsForLevel returns the match finder parameters for level, clamped to the valid range
+func paramsForLevel(level CompressionLevel) levelParams {
+	return levelTable[max(0, min(int(level), int(MaxLevel)))]
+}
+
+// LevelFromLZ4HC returns the GoZ4X level equivalent to a liblz4 HC level.
+// As in liblz4, levels below 1 select the default HC level and levels above
+// 12 are clamped to 12.
+func LevelFromLZ4HC(level int) CompressionLevel {
+	switch {
+	case level < 1:
+		return LZ4HCDefaultLevel
+	case level > int(MaxLevel):
+		return MaxLevel
+	default:
+		return CompressionLevel(level)
+	}
+}
+
+// LevelFast returns the GoZ4X level equivalent to liblz4's fast compressor
+// with the given acceleration. An acceleration of 1 or less is liblz4's
+// default and maps to level 2; any higher acceleration maps to level 1, the
+// fastest GoZ4X level.
+func LevelFast(acceleration int) CompressionLevel {
+	if acceleration <= 1 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,178 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestLevelFromLZ4HC tests the mapping of liblz4 HC levels
+func TestLevelFromLZ4HC(t *testing.T) {
+	tests := []struct {
+		hc   int
+		want CompressionLevel
+	}{
+		{-1, 9},
+		{0, 9},
+		{1, 1},
+		{3, 3},
+		{9, 9},
+		{12, 12},
+		{13, 12},
+		{100, 12},
+	}
+
+	for _, tt := range tests {
+		if got := LevelFromLZ4HC(tt.hc); got != tt.want {
+			t.Errorf("LevelFromLZ4HC(%d) = %d, want %d", tt.hc, got, tt.want)
+		}
+	}
+}
+
+// TestCompressionLevelString tests the names of levels
+func TestCompressionLevelString(t *testing.T) {
+	tests := []struct {
+		level CompressionLevel
+		want  string
+	}{
+		{NoCompression, "NoCompression"},
+		{1, "level 1"},
+		{DefaultLevel, "level 6"},
+		{MaxLevel, "level 12"},
+		{-1, "CompressionLevel(-1)"},
+		{MaxLevel + 1, "CompressionLevel(13)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.level.String(); got != tt.want {
+			t.Errorf("CompressionLevel(%d).String() = %q, want %q", int(tt.level), got, tt.want)
+		}
+	}
+}
+
+// TestLevelFast tests the mapping of liblz4 acceleration factors
+func TestLevelFast(t *testing.T) {
+	tests := []struct {
+		acceleration int
+		want         CompressionLevel
+	}{
+		{0, 2},
+		{1, 2},
+		{2, 1},
+		{65537, 1},
+	}
+
+	for _, tt := range tests {
+		if got := LevelFast(tt.acceleration); got != tt.want {
+			t.Errorf("LevelFast(%d) = %d, want %d", tt.acceleration, got, tt.want)
+		}
+	}
+}
+
+// TestLevelRatioTargets tests that higher levels never compress worse
+func TestLevelRatioTargets(t *testing.T) {
+	data := readGolden(t, "text.raw")[:64*1024]
+
+	prev := len(data) + 1
+	for level := CompressionLevel(1); level <= MaxLevel; level++ {
+		compressed, err := CompressBlockLevel(data, nil, level)
+		if err != nil {
+			t.Fatalf("CompressBlockLevel(level %d) error = %v", level, err)
+		}
+		if len(compressed) > prev {
+			t.Errorf("CompressBlockLevel(level %d) = %d bytes, want at most %d (level %d)", level, len(compressed), prev, level-1)
+		}
+		prev = len(compressed)
+	}
+}
+
+// TestNoCompression tests that level 0 stores data in valid blocks and frames
+func TestNoCompression(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	block, err := CompressBlockLevel(data[:1000], nil, NoCompression)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel() error = %v", err)
+	}
+	if len(block) != 1000+5 {
+		t.Errorf("CompressBlockLevel() = %d bytes, want %d", len(block), 1000+5)
+	}
+	if got, err := DecompressBlock(block, nil, 1000); err != nil || !bytes.Equal(got, data[:1000]) {
+		t.Errorf("DecompressBlock() = %d bytes, %v, want the input", len(got), err)
+	}
+	if got, err := CompressBlockV2Level(data[:1000], nil, NoCompression); err != nil || !bytes.Equal(got, block) {
+		t.Errorf("CompressBlockV2Level() = %d bytes, %v, want the stored block", len(got), err)
+	}
+
+	writers := []struct {
+		name  string
+		write func(w io.Writer) io.WriteCloser
+	}{
+		{"NewWriterLevel", func(w io.Writer) io.WriteCloser { return NewWriterLevel(w, NoCompression) }},
+		{"Store", func(w io.Writer) io.WriteCloser {
+			return NewWriterWithOptions(w, WriterOptions{Store: true, BlockSize: 64 * 1024, BlockChecksum: XXH32})
+		}},
+		{"Parallel", func(w io.Writer) io.WriteCloser { return NewParallelWriterLevel(w, NoCompression) }},
+	}
+	for _, tt := range writers {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tt.write(&buf)
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			compressed := 0
+			r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{
+				DebugTrace: func(ev TraceEvent) {
+					if !ev.Stored {
+						compressed++
+					}
+				},
+			})
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("read %d bytes, want %d", len(got), len(data))
+			}
+			if compressed != 0 {
+				t.Errorf("frame has %d compressed sequences, want only stored blocks", compressed)
+			}
+		})
+	}
+
+	// A zero level in options still means the default level
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.Len() >= len(data) {
+		t.Errorf("WriterOptions{} wrote %d bytes for %d, want compression", buf.Len(), len(data))
+	}
+}
+
+// TestNoCompressionEncoder tests storing messages at level 0
+func TestNoCompressionEncoder(t *testing.T) {
+	msg := generateCompressibleData(10000)
+
+	var buf bytes.Buffer
+	if err := NewEncoderLevel(&buf, NoCompression).Encode(msg); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if buf.Len() != messageHeaderSize+len(msg) {
+		t.Errorf("Encode() wrote %d bytes, want %d", buf.Len(), messageHeaderSize+len(msg))
+	}
+	got, err := NewDecoder(&buf).Decode()
+	if err != nil || !bytes.Equal(got, msg) {
+		t.Errorf("Decode() = %d bytes, %v, want the message", len(got), err)
+	}
+}
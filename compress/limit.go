@@ -0,0 +1,61 @@
+package compress
+
+import "errors"
+
+// ErrOutputLimit indicates a block that doesn't compress into the maximum
+// output size it was given
+var ErrOutputLimit = errors.New("compressed block exceeds output limit")
+
+// sequenceSize returns the encoded size of a sequence with literalLen
+// literals followed by a match of matchLen bytes, or by none if matchLen is 0
+func sequenceSize(literalLen, matchLen int) int {
+	n := 1 + literalLen + lengthSize(literalLen)
+	if matchLen > 0 {
+		n += 2 + lengthSize(matchLen-MinMatch)
+	}
+	return n
+}
+
+// lengthSize returns the number of extension bytes of a length field
+func lengthSize(n int) int {
+	if n < 15 {
+		return 0
+	}
+	return (n-15)/255 + 1
+}
+
+// CompressBlockLimit compresses src like CompressBlock, but into at most
+// maxOut bytes. The encoder gives up with ErrOutputLimit as soon as the
+// output is sure to exceed maxOut, so incompressible input costs only the
+// work done on its first maxOut bytes or so instead of the whole block.
+// If dst is nil or shorter than maxOut, a new buffer will be allocated.
+func CompressBlockLimit(src, dst []byte, maxOut int) ([]byte, error) {
+	c, err := NewCompressor(DefaultLevel)
+	if err != nil {
+		return nil, err
+	}
+	return c.CompressBlockLimit(src, dst, maxOut)
+}
+
+// CompressBlockLimit compresses src into at most maxOut bytes at c's level,
+// returning ErrOutputLimit as soon as the output is sure to exceed it. When
+// the block fits, the output is the same as that of CompressBlock.
+func (c *Compressor) CompressBlockLimit(src, dst []byte, maxOut int) ([]byte, error) {
+	if len(src) < MinBlockSize || len(src) > MaxBlockSize {
+		return nil, ErrInvalidBlockSize
+	}
+	if c.level == NoCompression {
+		if sequenceSize(len(src), 0) > maxOut {
+			return nil, ErrOutputLimit
+		}
+		return storeBlock(src, dst), nil
+	}
+	if maxOut < 1 {
+		return nil, ErrOutputLimit
+	}
+
+	c.matcher.Reset(src)
+	out, err := compressLimit(c.matcher, src, 0, dst, min(maxOut, compressBound(len(src))))
+	c.matcher.buf = nil // Don't keep src alive
+	return out, err
+}
@@ -0,0 +1,99 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCompressBlockLimit tests that blocks fitting the limit compress as
+// without one, and that the others fail with ErrOutputLimit
+func TestCompressBlockLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+	}{
+		{"Compressible", generateCompressibleData(64 * 1024)},
+		{"Random", generateRandomData(16 * 1024)},
+		{"Repeated", bytes.Repeat([]byte{'z'}, 100*1024)},
+		{"Short", []byte("0123456789abcdefgh")},
+	}
+
+	for _, tt := range tests {
+		for _, level := range []CompressionLevel{NoCompression, 1, DefaultLevel, MaxLevel} {
+			c, err := NewCompressor(level)
+			if err != nil {
+				t.Fatalf("NewCompressor(%d) error = %v", level, err)
+			}
+			want, err := c.CompressBlock(tt.src, nil)
+			if err != nil {
+				t.Fatalf("%s: CompressBlock() error = %v", tt.name, err)
+			}
+
+			got, err := c.CompressBlockLimit(tt.src, nil, len(want))
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s at level %d: CompressBlockLimit(%d) = %d bytes, %v, want the CompressBlock output",
+					tt.name, level, len(want), len(got), err)
+			}
+			if _, err := c.CompressBlockLimit(tt.src, nil, len(want)-1); err != ErrOutputLimit {
+				t.Errorf("%s at level %d: CompressBlockLimit(%d) error = %v, want %v",
+					tt.name, level, len(want)-1, err, ErrOutputLimit)
+			}
+
+			// A failed call leaves the Compressor usable
+			again, err := c.CompressBlock(tt.src, nil)
+			if err != nil || !bytes.Equal(again, want) {
+				t.Errorf("%s at level %d: CompressBlock() after the limit differs", tt.name, level)
+			}
+		}
+	}
+}
+
+// TestCompressBlockLimitDst tests that the output goes to a dst of maxOut
+// bytes, however large the worst case
+func TestCompressBlockLimitDst(t *testing.T) {
+	src := generateCompressibleData(256 * 1024)
+	want, err := CompressBlock(src, nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+
+	dst := make([]byte, len(want))
+	got, err := CompressBlockLimit(src, dst, len(dst))
+	if err != nil {
+		t.Fatalf("CompressBlockLimit() error = %v", err)
+	}
+	if &got[0] != &dst[0] || !bytes.Equal(got, want) {
+		t.Errorf("CompressBlockLimit() did not compress into dst")
+	}
+
+	if _, err := CompressBlockLimit(src[:8], nil, 100); err != ErrInvalidBlockSize {
+		t.Errorf("CompressBlockLimit() of a short block error = %v, want %v", err, ErrInvalidBlockSize)
+	}
+	if _, err := CompressBlockLimit(src, nil, 0); err != ErrOutputLimit {
+		t.Errorf("CompressBlockLimit(0) error = %v, want %v", err, ErrOutputLimit)
+	}
+}
+
+// BenchmarkCompressBlockLimit compares giving up on incompressible data
+// with compressing it in full and comparing sizes afterwards
+func BenchmarkCompressBlockLimit(b *testing.B) {
+	src := generateRandomData(1 << 20)
+	c, err := NewCompressor(DefaultLevel)
+	if err != nil {
+		b.Fatalf("NewCompressor() error = %v", err)
+	}
+	dst := make([]byte, compressBound(len(src)))
+
+	b.Run("Limit", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for i := 0; i < b.N; i++ {
+			c.CompressBlockLimit(src, dst, len(src)/2)
+		}
+	})
+	b.Run("Full", func(b *testing.B) {
+		b.SetBytes(int64(len(src)))
+		for i := 0; i < b.N; i++ {
+			c.CompressBlock(src, dst)
+		}
+	})
+}
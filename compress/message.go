@@ -0,0 +1,228 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// messageHeaderSize is the size of the per-message header:
+	// payload length (4 bytes) followed by the uncompressed length (4 bytes)
+	messageHeaderSize = 8
+
+	// messageStoredFlag marks a payload that is stored without compression
+	messageStoredFlag = 0x80000000
+
+	// MaxMessageSize is the largest uncompressed message an Encoder accepts
+	MaxMessageSize = MaxBlockSize
+)
+
+var (
+	// ErrMessageTooLarge indicates a message exceeds MaxMessageSize
+	ErrMessageTooLarge = errors.New("message too large")
+	// ErrInvalidMessage indicates a corrupted message header or payload
+	ErrInvalidMessage = errors.New("invalid message")
+)
+
+// Encoder writes length-prefixed compressed messages to an io.Writer.
+// Each call to Encode produces exactly one self-contained message, so the
+// receiving Decoder can recover message boundaries without extra framing.
+type Encoder struct {
+	w     io.Writer
+	level CompressionLevel
+	useV2 bool
+	buf   []byte
+	mu    sync.Mutex
+}
+
+// Decoder reads messages written by an Encoder from an io.Reader
+type Decoder struct {
+	r   io.Reader
+	hdr [messageHeaderSize]byte
+	buf []byte
+	mu  sync.Mutex
+}
+
+// NewEncoder creates a new Encoder with the default compression level set by
+// SetDefaultLevel
+func NewEncoder(w io.Writer) *Encoder {
+	return NewEncoderLevel(w, CurrentDefaultLevel())
+}
+
+// NewEncoderLevel creates a new Encoder with the specified compression level
+func NewEncoderLevel(w io.Writer, level CompressionLevel) *Encoder {
+	return NewEncoderWithOptions(w, WriterOptions{Level: level, Store: level == NoCompression})
+}
+
+// NewEncoderWithOptions creates a new Encoder with custom options.
+// Only Level, Store and UseV2 are used; messages are always encoded as a
+// single block.
+func NewEncoderWithOptions(w io.Writer, options WriterOptions) *Encoder {
+	level := options.Level
+	if options.Store {
+		level = NoCompression
+	} else if level < 1 || level > MaxLevel {
+		level = CurrentDefaultLevel()
+	}
+
+	return &Encoder{
+		w:     w,
+		level: level,
+		useV2: options.UseV2,
+	}
+}
+
+// Encode compresses msg and writes it as a single message.
+// The header and payload are issued in one Write call so that message
+// oriented transports never observe a partial message from the Encoder.
+func (e *Encoder) Encode(msg []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.encode(msg, e.level)
+}
+
+// EncodeLevel writes msg as a single message like Encode, but compressed at
+// level instead of the Encoder's level, for protocols whose messages differ
+// in size and worth, such as small control messages and bulk payloads.
+// Decoders need no setting to read it.
+func (e *Encoder) EncodeLevel(msg []byte, level CompressionLevel) error {
+	if level < NoCompression || level > MaxLevel {
+		return ErrInvalidCompressionLevel
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.encode(msg, level)
+}
+
+// encode compresses msg at level and writes it; the caller must hold e.mu
+func (e *Encoder) encode(msg []byte, level CompressionLevel) error {
+	if len(msg) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	// Make sure the scratch buffer can hold the worst case
+	worstCase := messageHeaderSize + compressBound(len(msg))
+	if cap(e.buf) < worstCase {
+		e.buf = make([]byte, worstCase)
+	}
+	e.buf = e.buf[:worstCase]
+
+	payloadLen := uint32(len(msg)) | messageStoredFlag
+	payload := msg
+
+	// Small messages can't be compressed by the block encoder
+	if len(msg) >= MinBlockSize && level != NoCompression {
+		var compressed []byte
+		var err error
+		if e.useV2 {
+			compressed, err = CompressBlockV2Level(msg, e.buf[messageHeaderSize:], level)
+		} else {
+			compressed, err = CompressBlockLevel(msg, e.buf[messageHeaderSize:], level)
+		}
+
+		// Only keep the compressed form if it actually saved space
+		if err == nil && len(compressed) < len(msg) {
+			payloadLen = uint32(len(compressed))
+			payload = compressed
+		}
+	}
+
+	binary.LittleEndian.PutUint32(e.buf[0:4], payloadLen)
+	binary.LittleEndian.PutUint32(e.buf[4:8], uint32(len(msg)))
+
+	// Stored payloads still live in msg, so copy them after the header
+	n := copy(e.buf[messageHeaderSize:], payload)
+
+	return writeMessage(e.w, e.buf[:messageHeaderSize+n])
+}
+
+// Reset discards the Encoder state and switches to writing to w
+func (e *Encoder) Reset(w io.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.w = w
+}
+
+// NewDecoder creates a new Decoder reading messages from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads and decompresses the next message.
+// It returns io.EOF when the stream ends cleanly between messages and
+// io.ErrUnexpectedEOF when the stream ends in the middle of a message.
+// The returned slice is owned by the caller.
+func (d *Decoder) Decode() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := io.ReadFull(d.r, d.hdr[:]); err != nil {
+		return nil, err
+	}
+
+	payloadLen := binary.LittleEndian.Uint32(d.hdr[0:4])
+	rawLen := binary.LittleEndian.Uint32(d.hdr[4:8])
+
+	stored := payloadLen&messageStoredFlag != 0
+	payloadLen &^= messageStoredFlag
+
+	// Validate lengths before allocating anything
+	if rawLen > MaxMessageSize {
+		return nil, ErrInvalidMessage
+	}
+	if stored && payloadLen != rawLen {
+		return nil, ErrInvalidMessage
+	}
+	if !stored && (rawLen == 0 || int(payloadLen) > compressBound(int(rawLen))) {
+		return nil, ErrInvalidMessage
+	}
+
+	if stored {
+		msg := make([]byte, rawLen)
+		if _, err := io.ReadFull(d.r, msg); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return msg, nil
+	}
+
+	if cap(d.buf) < int(payloadLen) {
+		d.buf = make([]byte, payloadLen)
+	}
+	d.buf = d.buf[:payloadLen]
+
+	if _, err := io.ReadFull(d.r, d.buf); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	msg, err := DecompressBlockAlloc(d.buf, int(rawLen))
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) != int(rawLen) {
+		return nil, ErrInvalidMessage
+	}
+
+	return msg, nil
+}
+
+// Reset discards the Decoder state and switches to reading from r
+func (d *Decoder) Reset(r io.Reader) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.r = r
+}
+
+// unexpectedEOF converts a clean EOF in the middle of a message into io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
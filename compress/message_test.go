@@ -0,0 +1,126 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// TestEncoderDecoderRoundTrip tests that messages survive an Encoder/Decoder round trip
+func TestEncoderDecoderRoundTrip(t *testing.T) {
+	messages := [][]byte{
+		{},
+		[]byte("hi"),
+		[]byte("a short message that is not compressible"),
+		generateCompressibleData(1024),
+		generateRandomData(4096),
+		generateCompressibleData(256 * 1024),
+	}
+
+	for _, useV2 := range []bool{false, true} {
+		var buf bytes.Buffer
+		enc := NewEncoderWithOptions(&buf, WriterOptions{Level: DefaultLevel, UseV2: useV2})
+
+		for i, msg := range messages {
+			if err := enc.Encode(msg); err != nil {
+				t.Fatalf("Encode(%d) error = %v", i, err)
+			}
+		}
+
+		dec := NewDecoder(&buf)
+		for i, want := range messages {
+			got, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("Decode(%d) error = %v", i, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Decode(%d) returned %d bytes, want %d matching bytes", i, len(got), len(want))
+			}
+		}
+
+		// The stream ends cleanly after the last message
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("Decode() at end of stream error = %v, want io.EOF", err)
+		}
+	}
+}
+
+// TestEncoderSingleWrite tests that each message is issued as a single Write
+func TestEncoderSingleWrite(t *testing.T) {
+	var cw countingWriter
+	enc := NewEncoder(&cw)
+
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode(generateCompressibleData(4096)); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+
+	if cw.writes != 3 {
+		t.Errorf("Encode issued %d writes for 3 messages, want 3", cw.writes)
+	}
+}
+
+// TestEncoderMessageTooLarge tests the message size limit
+func TestEncoderMessageTooLarge(t *testing.T) {
+	enc := NewEncoder(io.Discard)
+	if err := enc.Encode(make([]byte, MaxMessageSize+1)); err != ErrMessageTooLarge {
+		t.Errorf("Encode() error = %v, want %v", err, ErrMessageTooLarge)
+	}
+}
+
+// TestDecoderErrors tests truncated and corrupted message handling
+func TestDecoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(generateCompressibleData(2048)); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"Truncated header", valid[:5], io.ErrUnexpectedEOF},
+		{"Truncated payload", valid[:len(valid)-1], io.ErrUnexpectedEOF},
+		{
+			"Raw length too large",
+			func() []byte {
+				m := append([]byte(nil), valid...)
+				binary.LittleEndian.PutUint32(m[4:8], MaxMessageSize+1)
+				return m
+			}(),
+			ErrInvalidMessage,
+		},
+		{
+			"Stored length mismatch",
+			func() []byte {
+				m := append([]byte(nil), valid...)
+				binary.LittleEndian.PutUint32(m[0:4], 10|messageStoredFlag)
+				return m
+			}(),
+			ErrInvalidMessage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDecoder(bytes.NewReader(tt.data)).Decode()
+			if err != tt.wantErr {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// countingWriter counts the number of Write calls it receives
+type countingWriter struct {
+	writes int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.writes++
+	return len(p), nil
+}
@@ -0,0 +1,38 @@
+package compress
+
+import "time"
+
+// MetricsRecorder receives per-block measurements from streaming readers and writers.
+// Implementations must be safe for concurrent use if shared between streams.
+type MetricsRecorder interface {
+	// RecordBlock is called once for every block written or read, with the
+	// size of the block as stored in the frame, its uncompressed size and the
+	// time spent compressing or decompressing it. Blocks stored uncompressed
+	// report equal sizes.
+	RecordBlock(compressed, raw int, dur time.Duration)
+}
+
+// SetMetricsRecorder sets the recorder notified of every block the Writer
+// writes. A nil recorder disables metrics.
+func (z *Writer) SetMetricsRecorder(m MetricsRecorder) {
+	z.lock()
+	defer z.unlock()
+	z.wait()
+	z.metrics = m
+}
+
+// SetMetricsRecorder sets the recorder notified of every block the Reader
+// decodes. A nil recorder disables metrics.
+func (r *Reader) SetMetricsRecorder(m MetricsRecorder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = m
+}
+
+// SetMetricsRecorder sets the recorder notified of every block the
+// ParallelWriter writes. A nil recorder disables metrics.
+func (pw *ParallelWriter) SetMetricsRecorder(m MetricsRecorder) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	pw.metrics = m
+}
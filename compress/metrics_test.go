@@ -0,0 +1,79 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockRecorder collects the blocks reported to a MetricsRecorder
+type blockRecorder struct {
+	mu         sync.Mutex
+	blocks     int
+	compressed int
+	raw        int
+}
+
+func (br *blockRecorder) RecordBlock(compressed, raw int, dur time.Duration) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	br.blocks++
+	br.compressed += compressed
+	br.raw += raw
+}
+
+// TestMetricsRecorder tests that the Writer and Reader report every block
+func TestMetricsRecorder(t *testing.T) {
+	data := append(generateCompressibleData(150*1024), generateRandomData(20*1024)...)
+
+	var written blockRecorder
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024})
+	w.SetMetricsRecorder(&written)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if written.blocks != 3 || written.raw != len(data) {
+		t.Errorf("Writer recorded %d blocks/%d bytes, want 3/%d", written.blocks, written.raw, len(data))
+	}
+	if written.compressed >= written.raw {
+		t.Errorf("Writer recorded %d compressed bytes for %d raw bytes", written.compressed, written.raw)
+	}
+
+	var read blockRecorder
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	r.SetMetricsRecorder(&read)
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	if read.blocks != written.blocks || read.compressed != written.compressed || read.raw != written.raw {
+		t.Errorf("Reader recorded %d blocks %d/%d bytes, want %d blocks %d/%d bytes",
+			read.blocks, read.compressed, read.raw, written.blocks, written.compressed, written.raw)
+	}
+}
+
+// TestParallelWriterMetricsRecorder tests that the ParallelWriter reports every block
+func TestParallelWriterMetricsRecorder(t *testing.T) {
+	data := generateCompressibleData(150 * 1024)
+
+	var written blockRecorder
+	pw := NewParallelWriterWithOptions(io.Discard, ParallelWriterOptions{BlockSize: 64 * 1024})
+	pw.SetMetricsRecorder(&written)
+	if _, err := pw.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if written.blocks != 3 || written.raw != len(data) {
+		t.Errorf("ParallelWriter recorded %d blocks/%d bytes, want 3/%d", written.blocks, written.raw, len(data))
+	}
+}
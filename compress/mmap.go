@@ -0,0 +1,48 @@
+package compress
+
+import (
+	"io"
+	"os"
+)
+
+// CompressMmap compresses the file at path to dst as a single frame with its
+// content size declared. Where the platform supports it the file is memory
+// mapped and its blocks are compressed straight from the mapping, avoiding
+// the copies of reading it through a buffer; elsewhere it falls back to
+// CompressFile. The mapping is released before CompressMmap returns and no
+// reference to it is kept. The file must not be truncated while it is being
+// compressed, which makes the mapped memory inaccessible.
+// It returns the number of uncompressed bytes written.
+func CompressMmap(dst io.Writer, path string, options WriterOptions) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	data, unmap, err := mmapFile(f)
+	if err != nil {
+		return 0, err
+	}
+	if data == nil {
+		return CompressFile(dst, f, options)
+	}
+	defer unmap()
+
+	return compressMapped(dst, data, options)
+}
+
+// compressMapped compresses data as one frame. The writer copies at most the
+// final partial block of data, which Close flushes before data is unmapped.
+func compressMapped(dst io.Writer, data []byte, options WriterOptions) (int64, error) {
+	w := NewWriterWithOptions(dst, options)
+	if err := w.SetContentSize(uint64(len(data))); err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), w.Close()
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package compress
+
+import "os"
+
+// mmapFile returns nil data as memory mapping isn't supported on this
+// platform, so files are read instead
+func mmapFile(f *os.File) ([]byte, func(), error) {
+	return nil, nil, nil
+}
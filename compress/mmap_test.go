@@ -0,0 +1,97 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCompressMmap tests that mapped files compress to the same frames as
+// files read through CompressFile
+func TestCompressMmap(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", nil},
+		{"Small", []byte("memory mapped input")},
+		{"One Block", generateCompressibleData(64 * 1024)},
+		{"Several Blocks", generateCompressibleData(5*1024*1024 + 123)},
+		{"Random", generateRandomData(300 * 1024)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			var mapped bytes.Buffer
+			n, err := CompressMmap(&mapped, path, WriterOptions{})
+			if err != nil {
+				t.Fatalf("CompressMmap() error = %v", err)
+			}
+			if n != int64(len(tt.data)) {
+				t.Errorf("CompressMmap() = %d, want %d", n, len(tt.data))
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer f.Close()
+			var read bytes.Buffer
+			if _, err := CompressFile(&read, f, WriterOptions{}); err != nil {
+				t.Fatalf("CompressFile() error = %v", err)
+			}
+			if !bytes.Equal(mapped.Bytes(), read.Bytes()) {
+				t.Errorf("CompressMmap() wrote %d bytes, CompressFile() %d", mapped.Len(), read.Len())
+			}
+
+			got, h := readFrame(t, mapped.Bytes())
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(tt.data))
+			}
+			if !h.contentSize || h.contentSizeValue != uint64(len(tt.data)) {
+				t.Errorf("content size = %v/%d, want %d", h.contentSize, h.contentSizeValue, len(tt.data))
+			}
+		})
+	}
+}
+
+// TestCompressMmapMissing tests a path that doesn't exist
+func TestCompressMmapMissing(t *testing.T) {
+	if _, err := CompressMmap(io.Discard, filepath.Join(t.TempDir(), "missing"), WriterOptions{}); !os.IsNotExist(err) {
+		t.Errorf("CompressMmap() error = %v, want not exist", err)
+	}
+}
+
+// TestWriterDoesNotRetainInput tests that changing the input after Write
+// doesn't affect the output, so callers may release it
+func TestWriterDoesNotRetainInput(t *testing.T) {
+	data := generateCompressibleData(3*64*1024 + 500)
+	input := bytes.Clone(data)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.SetBlockSizeCode(BlockSize64KB); err != nil {
+		t.Fatalf("SetBlockSizeCode() error = %v", err)
+	}
+	if _, err := w.Write(input); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for i := range input {
+		input[i] = 0xFF
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, _ := readFrame(t, buf.Bytes())
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,29 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package compress
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the contents of f read-only. It returns nil data when f can't
+// be mapped, because it is empty, not a regular file or too large to address,
+// so that it is read instead.
+func mmapFile(f *os.File) ([]byte, func(), error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fi.Size()
+	if !fi.Mode().IsRegular() || size <= 0 || int64(int(size)) != size {
+		return nil, nil, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, nil
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
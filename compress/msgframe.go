@@ -0,0 +1,190 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+)
+
+const (
+	// messageBlockSize is the block size of message frames, the smallest
+	// the frame format declares
+	messageBlockSize = 64 << 10
+	// messageBlockSizeCode is the block size code of messageBlockSize
+	messageBlockSizeCode = 4
+)
+
+// MessageOptions configures EncodeMessage
+type MessageOptions struct {
+	// Level sets the compression level. Zero means the default set by
+	// SetDefaultLevel.
+	Level CompressionLevel
+	// Checksum adds a content checksum of the message to the frame, which
+	// DecodeMessage verifies
+	Checksum bool
+}
+
+// messageCompressor is a pooled Compressor with the buffer it compresses
+// blocks into
+type messageCompressor struct {
+	c   *Compressor
+	buf []byte
+}
+
+// messageCompressors pools the Compressors of EncodeMessage by level
+var messageCompressors [MaxLevel + 1]sync.Pool
+
+// EncodeMessage compresses msg into a minimal LZ4 frame appended to dst and
+// returns the extended slice. The frame has 64KB independent blocks and no
+// content size, so it costs 15 bytes over a bare block, 19 with a checksum,
+// and no Writer is set up: it suits messages of a few KB sent one per
+// frame, where EncodeFrame's Writer dominates the cost. Any LZ4 reader can
+// decode it; DecodeMessage does so fastest.
+func EncodeMessage(dst, msg []byte, options MessageOptions) ([]byte, error) {
+	level := options.Level
+	if level == 0 {
+		level = CurrentDefaultLevel()
+	}
+	if level < 0 || level > MaxLevel {
+		return nil, ErrInvalidCompressionLevel
+	}
+
+	mc, _ := messageCompressors[level].Get().(*messageCompressor)
+	if mc == nil {
+		c, err := NewCompressor(level)
+		if err != nil {
+			return nil, err
+		}
+		mc = &messageCompressor{c: c}
+	}
+	defer messageCompressors[level].Put(mc)
+
+	h := frameHeader{
+		blockIndependence: true,
+		contentChecksum:   options.Checksum,
+		blockSizeCode:     messageBlockSizeCode,
+	}
+	dst = appendFrameHeader(dst, &h)
+
+	for src := msg; len(src) > 0; {
+		n := min(len(src), messageBlockSize)
+		var err error
+		dst, err = mc.appendBlock(dst, src[:n])
+		if err != nil {
+			return nil, err
+		}
+		src = src[n:]
+	}
+
+	dst = binary.LittleEndian.AppendUint32(dst, 0)
+	if options.Checksum {
+		dst = binary.LittleEndian.AppendUint32(dst, xxh32.Checksum(msg))
+	}
+	return dst, nil
+}
+
+// appendBlock appends src to dst as a block with its size, stored as is
+// when compression doesn't save space
+func (mc *messageCompressor) appendBlock(dst, src []byte) ([]byte, error) {
+	if len(src) >= MinBlockSize && mc.c.Level() != NoCompression {
+		out, err := mc.c.CompressBlock(src, mc.buf)
+		if err != nil {
+			return nil, err
+		}
+		mc.buf = out[:cap(out)]
+		if len(out) < len(src) {
+			dst = binary.LittleEndian.AppendUint32(dst, uint32(len(out)))
+			return append(dst, out...), nil
+		}
+	}
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(src))|0x80000000)
+	return append(dst, src...), nil
+}
+
+// DecodeMessage decompresses frame, appending the output to dst, and
+// returns the extended slice. Frames like those EncodeMessage writes, with
+// independent blocks, no content size, dictionary or block checksums, are
+// decoded in place without a Reader; anything else is decoded as
+// DecodeFrame does, including frames that follow the first.
+func DecodeMessage(dst, frame []byte) ([]byte, error) {
+	if out, ok, err := decodeMessage(dst, frame); ok {
+		return out, err
+	}
+	out, err := decodeFrame(NewReader(bytes.NewReader(frame)), len(frame), dst)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeMessage decodes frame if it is a single frame DecodeMessage handles
+// in place, reporting false without an error for any other
+func decodeMessage(dst, frame []byte) ([]byte, bool, error) {
+	const headerSize = 7
+	if len(frame) < headerSize || binary.LittleEndian.Uint32(frame) != frameMagic {
+		return nil, false, nil
+	}
+	flg, bd := frame[4], frame[5]
+	if flg&^flagContentChecksum != 1<<6|flagBlockIndependence || bd&0x8F != 0 || bd>>4 < 4 {
+		return nil, false, nil
+	}
+	// A Reader reports a bad header checksum
+	if frame[6] != headerChecksum(frame[4:6]) {
+		return nil, false, nil
+	}
+	blockMax := format.BlockMaxSize(int(bd >> 4))
+
+	start := len(dst)
+	for pos := headerSize; ; {
+		if pos+4 > len(frame) {
+			return nil, true, io.ErrUnexpectedEOF
+		}
+		word := binary.LittleEndian.Uint32(frame[pos:])
+		pos += 4
+		if word == 0 {
+			if flg&flagContentChecksum != 0 {
+				if pos+4 > len(frame) {
+					return nil, true, io.ErrUnexpectedEOF
+				}
+				if binary.LittleEndian.Uint32(frame[pos:]) != xxh32.Checksum(dst[start:]) {
+					return nil, true, ErrContentChecksum
+				}
+				pos += 4
+			}
+			if pos != len(frame) {
+				// More frames follow, which the Reader decodes
+				return nil, false, nil
+			}
+			return dst, true, nil
+		}
+
+		size := int(word & 0x7FFFFFFF)
+		if size > blockMax {
+			return nil, true, ErrInvalidFrame
+		}
+		if size > len(frame)-pos {
+			return nil, true, io.ErrUnexpectedEOF
+		}
+		data := frame[pos : pos+size]
+		pos += size
+
+		if word&0x80000000 != 0 {
+			dst = append(dst, data...)
+			continue
+		}
+		n, err := block.DecodedLen(data, blockMax)
+		if err != nil {
+			return nil, true, err
+		}
+		dst = slices.Grow(dst, n)[:len(dst)+n]
+		if _, err := block.DecompressInto(data, dst[len(dst)-n:]); err != nil {
+			return nil, true, err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// TestMessageRoundTrip tests EncodeMessage and DecodeMessage on messages
+// from empty to several blocks, and that other readers decode the frames
+func TestMessageRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 10, 100, 1 << 10, 4 << 10, 70 << 10, 200 << 10} {
+		for _, kind := range testgen.Corpus {
+			msg := kind.Generate(testgen.DefaultSeed, size)
+			for _, checksum := range []bool{false, true} {
+				frame, err := EncodeMessage(nil, msg, MessageOptions{Checksum: checksum})
+				if err != nil {
+					t.Fatalf("%s(%d): EncodeMessage() error = %v", kind.Name, size, err)
+				}
+
+				prefix := []byte("prefix")
+				got, err := DecodeMessage(bytes.Clone(prefix), frame)
+				if err != nil || !bytes.Equal(got, append(prefix, msg...)) {
+					t.Errorf("%s(%d): DecodeMessage() = %d bytes, %v", kind.Name, size, len(got), err)
+				}
+				if got, err := io.ReadAll(NewReader(bytes.NewReader(frame))); err != nil || !bytes.Equal(got, msg) {
+					t.Errorf("%s(%d): Reader read %d bytes, %v", kind.Name, size, len(got), err)
+				}
+			}
+		}
+	}
+}
+
+// TestMessageOverhead tests that a message frame holding one block costs 15
+// bytes over the block, 19 with a checksum
+func TestMessageOverhead(t *testing.T) {
+	msg := testgen.JSON(testgen.DefaultSeed, 2<<10)
+	blk, err := CompressBlockLevel(msg, nil, CurrentDefaultLevel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		checksum bool
+		overhead int
+	}{{false, 15}, {true, 19}} {
+		frame, err := EncodeMessage(nil, msg, MessageOptions{Checksum: tt.checksum})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(frame) - len(blk); got != tt.overhead {
+			t.Errorf("checksum %v: overhead = %d bytes, want %d", tt.checksum, got, tt.overhead)
+		}
+	}
+
+	if _, err := EncodeMessage(nil, msg, MessageOptions{Level: MaxLevel + 1}); err != ErrInvalidCompressionLevel {
+		t.Errorf("EncodeMessage() at level %d error = %v, want %v", MaxLevel+1, err, ErrInvalidCompressionLevel)
+	}
+}
+
+// TestDecodeMessageFrames tests DecodeMessage on frames EncodeMessage doesn't
+// write, and on corrupt ones
+func TestDecodeMessageFrames(t *testing.T) {
+	msg := testgen.Text(testgen.DefaultSeed, 8<<10)
+	frame, err := EncodeFrame(msg, WriterOptions{BlockChecksum: XXH32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecodeMessage(nil, frame); err != nil || !bytes.Equal(got, msg) {
+		t.Errorf("DecodeMessage(EncodeFrame()) = %d bytes, %v", len(got), err)
+	}
+
+	frame, err = EncodeMessage(nil, msg, MessageOptions{Checksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecodeMessage(nil, append(bytes.Clone(frame), frame...)); err != nil || !bytes.Equal(got, append(bytes.Clone(msg), msg...)) {
+		t.Errorf("DecodeMessage() of two frames = %d bytes, %v", len(got), err)
+	}
+
+	bad := bytes.Clone(frame)
+	binary.LittleEndian.PutUint32(bad[len(bad)-4:], 0)
+	if _, err := DecodeMessage(nil, bad); err != ErrContentChecksum {
+		t.Errorf("DecodeMessage() with a bad checksum error = %v, want %v", err, ErrContentChecksum)
+	}
+	for _, n := range []int{8, 20, len(frame) - 6, len(frame) - 2} {
+		if _, err := DecodeMessage(nil, frame[:n]); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("DecodeMessage() of %d of %d bytes error = %v, want %v", n, len(frame), err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+// TestDecodeMessageAllocs tests that decoding into a large enough dst
+// doesn't allocate
+func TestDecodeMessageAllocs(t *testing.T) {
+	msg := testgen.JSON(testgen.DefaultSeed, 4<<10)
+	frame, err := EncodeMessage(nil, msg, MessageOptions{Checksum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]byte, 0, len(msg))
+	if n := testing.AllocsPerRun(100, func() {
+		if _, err := DecodeMessage(dst, frame); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 && !raceEnabled {
+		t.Errorf("DecodeMessage() allocations = %v, want 0", n)
+	}
+}
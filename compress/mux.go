@@ -0,0 +1,247 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+const (
+	// muxLabelMagic is the skippable frame magic number announcing the label
+	// and length of the LZ4 frame that follows it in a multiplexed stream
+	muxLabelMagic = format.MuxLabelMagic
+
+	// muxLabelHeaderSize is the size of the label frame header: magic, payload
+	// size and the length of the following LZ4 frame
+	muxLabelHeaderSize = 12
+
+	// maxMuxLabel bounds labels, so that a corrupt label frame can't make a
+	// Demuxer allocate up to 4 GiB for one
+	maxMuxLabel = 4096
+)
+
+var (
+	// ErrInvalidMuxStream indicates data in a multiplexed stream that isn't
+	// a labeled frame
+	ErrInvalidMuxStream = errors.New("invalid multiplexed stream")
+
+	// ErrMuxLabelTooLong indicates a label of more than 4096 bytes
+	ErrMuxLabelTooLong = errors.New("mux label too long")
+)
+
+// Muxer interleaves several labeled substreams over one io.Writer.
+//
+// Every call to Write emits a skippable frame holding the label and the
+// length of the LZ4 frame that follows it, then the LZ4 frame itself. A
+// Demuxer splits the stream back into one reader per label; a plain Reader
+// skips the labels and decodes all substreams concatenated in write order.
+// A Muxer is safe for concurrent use.
+type Muxer struct {
+	w       io.Writer
+	options WriterOptions
+	buf     bytes.Buffer
+	mu      sync.Mutex
+}
+
+// NewMuxer creates a new Muxer writing frames with the given options
+func NewMuxer(w io.Writer, options WriterOptions) *Muxer {
+	return &Muxer{w: w, options: options}
+}
+
+// Write compresses p as one frame of the substream named label.
+// The label and frame are issued in a single Write call to the underlying
+// writer, so frames of different substreams never interleave.
+func (m *Muxer) Write(label string, p []byte) error {
+	if len(label) > maxMuxLabel {
+		return ErrMuxLabelTooLong
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.buf.Reset()
+	m.buf.Write(make([]byte, muxLabelHeaderSize))
+	m.buf.WriteString(label)
+	labelEnd := m.buf.Len()
+
+	fw := NewWriterWithOptions(&m.buf, m.options)
+	if err := fw.SetContentSize(uint64(len(p))); err != nil {
+		return err
+	}
+	if _, err := fw.Write(p); err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+
+	out := m.buf.Bytes()
+	binary.LittleEndian.PutUint32(out[0:4], muxLabelMagic)
+	binary.LittleEndian.PutUint32(out[4:8], uint32(labelEnd-8))
+	binary.LittleEndian.PutUint32(out[8:12], uint32(len(out)-labelEnd))
+
+	return writeMessage(m.w, out)
+}
+
+// Channel returns an io.Writer whose writes are sent as frames of the
+// substream named label
+func (m *Muxer) Channel(label string) io.Writer {
+	return &muxChannel{m: m, label: label}
+}
+
+// muxChannel is an io.Writer bound to one label of a Muxer
+type muxChannel struct {
+	m     *Muxer
+	label string
+}
+
+// Write implements io.Writer
+func (c *muxChannel) Write(p []byte) (int, error) {
+	if err := c.m.Write(c.label, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Demuxer splits a stream written by a Muxer into one reader per label.
+//
+// The source is read on demand: reading from one label queues the still
+// compressed frames of other labels it passes over, and frames are only
+// decompressed when their own label is read. Frames of labels that are never
+// read stay queued in memory. A Demuxer and its readers are safe for
+// concurrent use.
+type Demuxer struct {
+	r        io.Reader
+	channels map[string]*demuxChannel
+	labels   []string
+	err      error
+	mu       sync.Mutex
+}
+
+// demuxChannel holds the queued frames of one label
+type demuxChannel struct {
+	frames [][]byte
+	cur    *Reader
+	seen   bool
+}
+
+// NewDemuxer creates a new Demuxer reading a multiplexed stream from r
+func NewDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{r: r, channels: make(map[string]*demuxChannel)}
+}
+
+// Reader returns a reader for the substream named label. It returns io.EOF
+// once the underlying stream ends and all frames of the label were read.
+func (d *Demuxer) Reader(label string) io.Reader {
+	return &demuxReader{d: d, label: label}
+}
+
+// Labels returns the labels seen so far, in order of first appearance
+func (d *Demuxer) Labels() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.labels...)
+}
+
+// channel returns the queue for label, creating it if needed
+func (d *Demuxer) channel(label string) *demuxChannel {
+	ch, ok := d.channels[label]
+	if !ok {
+		ch = &demuxChannel{}
+		d.channels[label] = ch
+	}
+	return ch
+}
+
+// readFrame reads the next labeled frame from the source and queues it
+func (d *Demuxer) readFrame() error {
+	var hdr [muxLabelHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(d.r, hdr[:4]); err != nil {
+			return err
+		}
+		magic := binary.LittleEndian.Uint32(hdr[:4])
+		if magic&skippableMagicMask != skippableMagic {
+			return ErrInvalidMuxStream
+		}
+		if _, err := io.ReadFull(d.r, hdr[4:8]); err != nil {
+			return unexpectedEOF(err)
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:8]))
+
+		// Other skippable frames carry user data unrelated to the substreams
+		if magic != muxLabelMagic {
+			if _, err := io.CopyN(io.Discard, d.r, size); err != nil {
+				return unexpectedEOF(err)
+			}
+			continue
+		}
+
+		if size < 4 || size-4 > maxMuxLabel {
+			return ErrInvalidMuxStream
+		}
+		if _, err := io.ReadFull(d.r, hdr[8:12]); err != nil {
+			return unexpectedEOF(err)
+		}
+		label := make([]byte, size-4)
+		if _, err := io.ReadFull(d.r, label); err != nil {
+			return unexpectedEOF(err)
+		}
+		// The frame length isn't trusted to size a buffer up front: it grows
+		// only as far as the source actually holds
+		var frame bytes.Buffer
+		if _, err := io.CopyN(&frame, d.r, int64(binary.LittleEndian.Uint32(hdr[8:12]))); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		ch := d.channel(string(label))
+		if !ch.seen {
+			ch.seen = true
+			d.labels = append(d.labels, string(label))
+		}
+		ch.frames = append(ch.frames, frame.Bytes())
+		return nil
+	}
+}
+
+// demuxReader reads the substream of one label
+type demuxReader struct {
+	d     *Demuxer
+	label string
+}
+
+// Read implements io.Reader
+func (r *demuxReader) Read(p []byte) (int, error) {
+	d := r.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ch := d.channel(r.label)
+	for {
+		if ch.cur != nil {
+			n, err := ch.cur.Read(p)
+			if n > 0 || err != io.EOF {
+				return n, err
+			}
+			ch.cur = nil
+		}
+
+		if len(ch.frames) > 0 {
+			ch.cur = NewReader(bytes.NewReader(ch.frames[0]))
+			ch.frames[0] = nil
+			ch.frames = ch.frames[1:]
+			continue
+		}
+
+		// Pull more of the source until a frame for this label arrives.
+		// Errors, including the final io.EOF, are reported to every label.
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.err = d.readFrame()
+	}
+}
@@ -0,0 +1,171 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestMuxRoundTrip tests interleaved substreams read back per label
+func TestMuxRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMuxer(&buf, WriterOptions{Level: FastLevel})
+
+	want := map[string][]byte{}
+	labels := []string{"partition-0", "partition-1", "partition-2"}
+	for i := 0; i < 30; i++ {
+		label := labels[i%len(labels)]
+		chunk := []byte(fmt.Sprintf("%s record %d: %s\n", label, i, bytes.Repeat([]byte("x"), i*50)))
+		if err := m.Write(label, chunk); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		want[label] = append(want[label], chunk...)
+	}
+
+	// Channels and empty writes share the same framing
+	ch := m.Channel("partition-1")
+	if _, err := ch.Write(nil); err != nil {
+		t.Fatalf("Channel.Write() error = %v", err)
+	}
+	if _, err := io.Copy(ch, bytes.NewReader(generateCompressibleData(100*1024))); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	want["partition-1"] = append(want["partition-1"], generateCompressibleData(100*1024)...)
+
+	d := NewDemuxer(bytes.NewReader(buf.Bytes()))
+
+	// Read the labels out of order so earlier frames are queued
+	for _, label := range []string{"partition-2", "partition-0", "partition-1", "unknown"} {
+		got, err := io.ReadAll(d.Reader(label))
+		if err != nil {
+			t.Fatalf("ReadAll(%s) error = %v", label, err)
+		}
+		if !bytes.Equal(got, want[label]) {
+			t.Errorf("Reader(%s) returned %d bytes, want %d", label, len(got), len(want[label]))
+		}
+	}
+
+	if got := d.Labels(); fmt.Sprint(got) != fmt.Sprint(labels) {
+		t.Errorf("Labels() = %v, want %v", got, labels)
+	}
+
+	// A plain Reader decodes every substream in write order
+	all, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+	if err != nil {
+		t.Fatalf("Reader ReadAll() error = %v", err)
+	}
+	total := 0
+	for _, data := range want {
+		total += len(data)
+	}
+	if len(all) != total {
+		t.Errorf("Reader returned %d bytes, want %d", len(all), total)
+	}
+}
+
+// TestDemuxerConcurrent tests reading several labels from different goroutines
+func TestDemuxerConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMuxer(&buf, WriterOptions{})
+
+	const channels = 4
+	want := make([][]byte, channels)
+	for i := 0; i < 100; i++ {
+		c := i % channels
+		chunk := generateCompressibleData(1000 + i)
+		if err := m.Write(fmt.Sprint(c), chunk); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		want[c] = append(want[c], chunk...)
+	}
+
+	d := NewDemuxer(&buf)
+	var wg sync.WaitGroup
+	got := make([][]byte, channels)
+	errs := make([]error, channels)
+	for c := 0; c < channels; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			got[c], errs[c] = io.ReadAll(d.Reader(fmt.Sprint(c)))
+		}(c)
+	}
+	wg.Wait()
+
+	for c := 0; c < channels; c++ {
+		if errs[c] != nil {
+			t.Errorf("channel %d: ReadAll() error = %v", c, errs[c])
+		}
+		if !bytes.Equal(got[c], want[c]) {
+			t.Errorf("channel %d: got %d bytes, want %d", c, len(got[c]), len(want[c]))
+		}
+	}
+}
+
+// TestDemuxerErrors tests malformed multiplexed streams
+func TestDemuxerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewMuxer(&buf, WriterOptions{}).Write("a", generateCompressibleData(4096)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	stream := buf.Bytes()
+
+	// Unrelated skippable frames are ignored
+	skippable := binary.LittleEndian.AppendUint32(nil, skippableMagic)
+	skippable = binary.LittleEndian.AppendUint32(skippable, 3)
+	skippable = append(skippable, 1, 2, 3)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"Valid", stream, nil},
+		{"Skippable frame", append(append([]byte(nil), skippable...), stream...), nil},
+		{"Bare LZ4 frame", stream[muxLabelHeaderSize+1:], ErrInvalidMuxStream},
+		{"Truncated frame", stream[:len(stream)-10], io.ErrUnexpectedEOF},
+		{"Truncated label", stream[:6], io.ErrUnexpectedEOF},
+		{"Label too long", labelFrame(maxMuxLabel+1, 0), ErrInvalidMuxStream},
+		{"Missing frame", labelFrame(1, 0xFFFFFFFF), io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			_, err := io.ReadAll(NewDemuxer(bytes.NewReader(tt.data)).Reader("a"))
+			runtime.ReadMemStats(&after)
+			if n := after.TotalAlloc - before.TotalAlloc; n > 8<<20 {
+				t.Errorf("ReadAll() allocated %d bytes", n)
+			}
+			if err != tt.wantErr {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// labelFrame returns the header of a label frame with a label of n bytes
+// announcing an LZ4 frame of frameLen bytes, without the frame
+func labelFrame(n int, frameLen uint32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, muxLabelMagic)
+	b = binary.LittleEndian.AppendUint32(b, uint32(n+4))
+	b = binary.LittleEndian.AppendUint32(b, frameLen)
+	return append(b, bytes.Repeat([]byte("a"), n)...)
+}
+
+// TestMuxerLabelTooLong tests that a Muxer refuses labels a Demuxer rejects
+func TestMuxerLabelTooLong(t *testing.T) {
+	m := NewMuxer(io.Discard, WriterOptions{})
+	if err := m.Write(strings.Repeat("a", maxMuxLabel), nil); err != nil {
+		t.Errorf("Write(longest label) error = %v", err)
+	}
+	if err := m.Write(strings.Repeat("a", maxMuxLabel+1), nil); err != ErrMuxLabelTooLong {
+		t.Errorf("Write(long label) error = %v, want ErrMuxLabelTooLong", err)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !race
+
+package compress
+
+// raceEnabled is set when testing with the race detector
+const raceEnabled = false
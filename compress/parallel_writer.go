@@ -5,13 +5,18 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"time"
+
+	"github.com/harriteja/GoZ4X/internal/xxh32"
 )
 
 // ErrWriterClosed is returned when writing to a closed writer
 var ErrWriterClosed = errors.New("writer is closed")
 
 // ParallelWriter is an io.WriteCloser that compresses data to an LZ4 stream
-// using multiple goroutines for better performance.
+// using multiple goroutines for better performance. Each full block is
+// compressed on its own goroutine while Write fills the next one; blocks are
+// written in order, by Write and Close, as they finish.
 type ParallelWriter struct {
 	// Underlying writer
 	w io.Writer
@@ -28,82 +33,151 @@ type ParallelWriter struct {
 	written     uint64
 	header      frameHeader
 	buf         []byte
+	// content is the running checksum of the frame's data
+	content xxh32.Digest
+	// checksum computes block checksums, if enabled in header
+	checksum Checksummer
+	// err is the first error returned by the underlying writer, which
+	// Write and Close keep returning
+	err error
 
 	// Buffer for collecting data before compression
 	buffer    []byte
 	bufferOff int
 
+	// Blocks being compressed or waiting to be written, oldest first, and
+	// the most that may be. Zero slots compress each block in Write.
+	pending []*parallelBlock
+	workers int
+	slots   int
+	// maxInFlight is MaxInFlightBytes, or 0 for no limit
+	maxInFlight int
+	// spareData and spareBuf hold the buffers of written blocks for reuse
+	spareData [][]byte
+	spareBuf  [][]byte
+
+	// Optional per-block metrics
+	metrics MetricsRecorder
+
 	// Synchronization
 	mu sync.Mutex
 }
 
 // ParallelWriterOptions provides configuration options for a ParallelWriter
 type ParallelWriterOptions struct {
-	// Level sets the compression level
+	// Level sets the compression level. Zero means the default set by
+	// SetDefaultLevel; set Store for NoCompression.
 	Level CompressionLevel
+	// Store writes every block uncompressed, as level NoCompression does.
+	// Level is ignored.
+	Store bool
 	// UseV2 enables the improved v0.2 compression algorithm
 	UseV2 bool
 	// BlockSize sets the size of compression blocks
 	BlockSize int
-	// NumWorkers sets the number of worker goroutines (0 = use GOMAXPROCS)
+	// ContentChecksum enables the XXH32 checksum of the whole frame's data,
+	// written after the end marker
+	ContentChecksum bool
+	// BlockChecksum, if set, enables block checksums computed with the given
+	// algorithm, as for Writer. Use XXH32 for frames other LZ4
+	// implementations can verify.
+	BlockChecksum Checksummer
+	// NumWorkers sets the number of worker goroutines (0 = use
+	// CurrentDefaultWorkers)
 	NumWorkers int
+	// MaxInFlightBytes bounds the data held at once: the block being filled
+	// plus the blocks being compressed or waiting to be written, counted at
+	// the block size. Write blocks until enough of them are written, so that
+	// memory stays predictable however fast the data arrives. Fewer workers
+	// run if their blocks wouldn't fit, down to compressing each block in
+	// Write, and the block size shrinks below the limit. Zero means one
+	// block per worker.
+	MaxInFlightBytes int
+}
+
+// parallelBlock is a block handed to a worker goroutine
+type parallelBlock struct {
+	// data is the uncompressed block and buf the buffer it is compressed to
+	data []byte
+	buf  []byte
+	// compressed, sum, err and elapsed are the results, set before done
+	// closes. compressed is nil if the block is stored raw, and sum is the
+	// checksum of the block as stored.
+	compressed []byte
+	sum        uint32
+	err        error
+	elapsed    time.Duration
+	done       chan struct{}
 }
 
 // NewParallelWriter creates a new ParallelWriter with default options
 func NewParallelWriter(w io.Writer) *ParallelWriter {
-	return NewParallelWriterLevel(w, DefaultLevel)
+	return NewParallelWriterLevel(w, CurrentDefaultLevel())
 }
 
 // NewParallelWriterLevel creates a new ParallelWriter with specified level
 func NewParallelWriterLevel(w io.Writer, level CompressionLevel) *ParallelWriter {
 	return NewParallelWriterWithOptions(w, ParallelWriterOptions{
 		Level: level,
+		Store: level == NoCompression,
 	})
 }
 
 // NewParallelWriterWithOptions creates a new ParallelWriter with custom options
 func NewParallelWriterWithOptions(w io.Writer, options ParallelWriterOptions) *ParallelWriter {
 	// Set defaults for unspecified options
-	if options.Level == 0 {
-		options.Level = DefaultLevel
+	if options.Store {
+		options.Level = NoCompression
+	} else if options.Level == 0 {
+		options.Level = CurrentDefaultLevel()
 	}
 
 	blockSize := options.BlockSize
 	if blockSize <= 0 {
 		blockSize = DefaultChunkSize
 	}
+	if options.MaxInFlightBytes > 0 {
+		blockSize = max(min(blockSize, options.MaxInFlightBytes), MinBlockSize)
+	}
+
+	workers := options.NumWorkers
+	if workers <= 0 {
+		workers = CurrentDefaultWorkers()
+	}
 
 	// Initialize header
 	header := frameHeader{
 		blockIndependence: true,
-		blockChecksum:     false,
+		blockChecksum:     options.BlockChecksum != nil,
 		contentSize:       false,
-		contentChecksum:   false,
+		contentChecksum:   options.ContentChecksum,
 		dictID:            false,
-		blockSizeCode:     5, // Default to 256KB blocks
-	}
-
-	// Adjust block size code based on blockSize
-	switch {
-	case blockSize <= 64*1024:
-		header.blockSizeCode = 4 // 64KB
-	case blockSize <= 256*1024:
-		header.blockSizeCode = 5 // 256KB
-	case blockSize <= 1024*1024:
-		header.blockSizeCode = 6 // 1MB
-	default:
-		header.blockSizeCode = 7 // 4MB
-	}
-
-	return &ParallelWriter{
-		w:         w,
-		level:     options.Level,
-		useV2:     options.UseV2,
-		blockSize: blockSize,
-		header:    header,
-		buf:       make([]byte, 16), // buffer for encoding headers
-		buffer:    make([]byte, blockSize),
-		bufferOff: 0,
+		blockSizeCode:     uint8(CodeFromBlockSize(blockSize)),
+	}
+
+	pw := &ParallelWriter{
+		w:           w,
+		level:       options.Level,
+		useV2:       options.UseV2,
+		blockSize:   blockSize,
+		header:      header,
+		checksum:    options.BlockChecksum,
+		buf:         make([]byte, maxHeaderSize), // buffer for encoding headers
+		buffer:      make([]byte, blockSize),
+		bufferOff:   0,
+		maxInFlight: max(options.MaxInFlightBytes, 0),
+	}
+	pw.setWorkers(workers)
+	return pw
+}
+
+// setWorkers sets the number of workers and the number of blocks that may be
+// in flight, which the block being filled leaves room for under maxInFlight
+func (pw *ParallelWriter) setWorkers(n int) {
+	pw.workers = n
+	pw.slots = n
+	if pw.maxInFlight > 0 {
+		pw.slots = min(n, pw.maxInFlight/pw.blockSize-1)
 	}
 }
 
@@ -112,6 +186,9 @@ func (pw *ParallelWriter) Write(p []byte) (int, error) {
 	pw.mu.Lock()
 	defer pw.mu.Unlock()
 
+	if pw.err != nil {
+		return 0, pw.err
+	}
 	if pw.closed {
 		return 0, ErrWriterClosed
 	}
@@ -136,6 +213,9 @@ func (pw *ParallelWriter) Write(p []byte) (int, error) {
 
 		// Copy data to buffer
 		copy(pw.buffer[pw.bufferOff:], input[:n])
+		if pw.header.contentChecksum {
+			pw.content.Write(input[:n])
+		}
 		pw.bufferOff += n
 		input = input[n:]
 		totalWritten += n
@@ -152,24 +232,50 @@ func (pw *ParallelWriter) Write(p []byte) (int, error) {
 	return totalWritten, nil
 }
 
-// Close implements io.Closer
+// Close implements io.Closer. It flushes buffered data and finishes the
+// frame with the end marker and, if enabled, the content checksum. Like
+// Writer.Close, closing again returns nil once Close has succeeded, while an
+// error from the underlying writer is returned by every later call.
 func (pw *ParallelWriter) Close() error {
 	pw.mu.Lock()
 	defer pw.mu.Unlock()
 
+	if pw.err != nil {
+		return pw.err
+	}
 	if pw.closed {
 		return nil
 	}
 
-	// Flush any remaining data
+	// An empty stream still needs its frame header
+	if !pw.wroteHeader {
+		if err := pw.writeFrameHeader(); err != nil {
+			return err
+		}
+		pw.wroteHeader = true
+	}
+
+	// Flush any remaining data and wait for the blocks in flight
 	if pw.bufferOff > 0 {
 		if err := pw.flushBuffer(); err != nil {
 			return err
 		}
 	}
+	for len(pw.pending) > 0 {
+		if err := pw.writeNext(); err != nil {
+			return err
+		}
+	}
 
-	// Write end marker (empty block)
-	if _, err := pw.w.Write([]byte{0, 0, 0, 0}); err != nil {
+	// Write end marker (empty block), then the content checksum
+	end := pw.buf[:8]
+	binary.LittleEndian.PutUint32(end, 0)
+	n := 4
+	if pw.header.contentChecksum {
+		binary.LittleEndian.PutUint32(end[4:], pw.content.Sum32())
+		n = 8
+	}
+	if err := pw.writeOut(end[:n]); err != nil {
 		return err
 	}
 
@@ -186,14 +292,27 @@ func (pw *ParallelWriter) Reset(w io.Writer) {
 	pw.w = w
 	pw.bufferOff = 0
 	pw.closed = false
+	pw.err = nil
 	pw.wroteHeader = false
 	pw.written = 0
+	pw.dropPending()
+
+	// Abort releases the buffer
+	if len(pw.buffer) < pw.blockSize {
+		pw.buffer = make([]byte, pw.blockSize)
+	}
 }
 
-// SetNumWorkers sets the number of worker goroutines
-// This is a no-op in the base implementation but is overridden by actual parallel writers
+// SetNumWorkers sets the number of worker goroutines, within the limit of
+// MaxInFlightBytes. A value of 0 means CurrentDefaultWorkers.
 func (pw *ParallelWriter) SetNumWorkers(n int) {
-	// No operation in base implementation
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if n <= 0 {
+		n = CurrentDefaultWorkers()
+	}
+	pw.setWorkers(n)
 }
 
 // SetChunkSize sets the chunk size for parallel compression
@@ -202,104 +321,169 @@ func (pw *ParallelWriter) SetChunkSize(size int) {
 	// No operation in base implementation
 }
 
-// flushBuffer compresses and writes the current buffer
+// flushBuffer hands the current buffer to a worker, first writing finished
+// blocks until there is a free slot, or compresses and writes it at once if
+// there are no slots
 func (pw *ParallelWriter) flushBuffer() error {
 	if pw.bufferOff == 0 {
 		return nil
 	}
 
-	// Compress buffer
-	var compressed []byte
-	var err error
-
-	// Allocate a buffer for compressed data with safety margin
-	maxCompressedSize := pw.bufferOff + (pw.bufferOff / 255) + 16
-	compressedBuf := make([]byte, maxCompressedSize)
-
-	if pw.useV2 {
-		compressed, err = CompressBlockV2Level(pw.buffer[:pw.bufferOff], compressedBuf, pw.level)
-	} else {
-		compressed, err = CompressBlockLevel(pw.buffer[:pw.bufferOff], compressedBuf, pw.level)
-	}
+	b := &parallelBlock{data: pw.buffer[:pw.bufferOff], buf: takeSpare(&pw.spareBuf)}
+	pw.bufferOff = 0
 
-	if err != nil {
+	if pw.slots <= 0 {
+		pw.compress(b)
+		err := pw.writeBlock(b)
+		pw.spareBuf = append(pw.spareBuf, b.buf)
 		return err
 	}
 
-	// Check if compression actually helped
-	if len(compressed) >= pw.bufferOff {
-		// Write uncompressed block
-		blockSize := uint32(pw.bufferOff | 0x80000000) // Set high bit to indicate uncompressed
-		binary.LittleEndian.PutUint32(pw.buf[:4], blockSize)
-		if _, err := pw.w.Write(pw.buf[:4]); err != nil {
+	for len(pw.pending) >= pw.slots {
+		if err := pw.writeNext(); err != nil {
 			return err
 		}
+	}
+	b.done = make(chan struct{})
+	pw.pending = append(pw.pending, b)
+	go func() {
+		pw.compress(b)
+		close(b.done)
+	}()
+
+	// Fill a spare buffer meanwhile, and write what's already finished
+	pw.buffer = takeSpare(&pw.spareData)
+	if pw.buffer == nil {
+		pw.buffer = make([]byte, pw.blockSize)
+	}
+	for len(pw.pending) > 0 {
+		select {
+		case <-pw.pending[0].done:
+			if err := pw.writeNext(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
 
-		// Write original data
-		if _, err := pw.w.Write(pw.buffer[:pw.bufferOff]); err != nil {
-			return err
+// takeSpare removes and returns the last buffer of spare, or nil
+func takeSpare(spare *[][]byte) []byte {
+	n := len(*spare)
+	if n == 0 {
+		return nil
+	}
+	buf := (*spare)[n-1]
+	*spare = (*spare)[:n-1]
+	return buf
+}
+
+// compress compresses b.data and computes its block checksum. It runs on
+// a worker goroutine, touching nothing but b.
+func (pw *ParallelWriter) compress(b *parallelBlock) {
+	start := time.Now()
+	if size := compressBound(len(b.data)); len(b.buf) < size {
+		b.buf = make([]byte, size)
+	}
+
+	// Stored levels and tiny blocks are written raw
+	b.compressed = nil
+	if pw.level != NoCompression && len(b.data) >= MinBlockSize {
+		if pw.useV2 {
+			b.compressed, b.err = CompressBlockV2Level(b.data, b.buf, pw.level)
+		} else {
+			b.compressed, b.err = CompressBlockLevel(b.data, b.buf, pw.level)
 		}
-	} else {
-		// Write compressed block
-		blockSize := uint32(len(compressed))
-		binary.LittleEndian.PutUint32(pw.buf[:4], blockSize)
-		if _, err := pw.w.Write(pw.buf[:4]); err != nil {
-			return err
+		if len(b.compressed) >= len(b.data) {
+			b.compressed = nil
 		}
+	}
 
-		// Write compressed data
-		if _, err := pw.w.Write(compressed); err != nil {
-			return err
+	// The block checksum covers the block data as stored
+	if b.err == nil && pw.header.blockChecksum {
+		if b.compressed != nil {
+			b.sum = pw.checksum.Checksum(b.compressed)
+		} else {
+			b.sum = pw.checksum.Checksum(b.data)
 		}
 	}
+	b.elapsed = time.Since(start)
+}
 
-	// Reset buffer
-	pw.bufferOff = 0
-	return nil
+// writeNext waits for the oldest block in flight and writes it, keeping its
+// buffers for reuse
+func (pw *ParallelWriter) writeNext() error {
+	b := pw.pending[0]
+	<-b.done
+	pw.pending[0] = nil
+	pw.pending = pw.pending[1:]
+
+	err := pw.writeBlock(b)
+	pw.spareData = append(pw.spareData, b.data[:cap(b.data)])
+	pw.spareBuf = append(pw.spareBuf, b.buf)
+	return err
 }
 
-// writeFrameHeader writes the LZ4 frame header
-func (pw *ParallelWriter) writeFrameHeader() error {
-	// Magic number (4 bytes)
-	binary.LittleEndian.PutUint32(pw.buf[:4], frameMagic)
-	if _, err := pw.w.Write(pw.buf[:4]); err != nil {
-		return err
+// dropPending abandons the blocks in flight. Their workers finish on their
+// own, so their buffers aren't reused.
+func (pw *ParallelWriter) dropPending() {
+	clear(pw.pending)
+	pw.pending = pw.pending[:0]
+}
+
+// writeBlock writes a compressed block, or its data if compression didn't
+// help, followed by its checksum if enabled. A failed compression is sticky
+// like a failed write, since the frame can't continue without the block.
+func (pw *ParallelWriter) writeBlock(b *parallelBlock) error {
+	if b.err != nil {
+		if pw.err == nil {
+			pw.err = b.err
+		}
+		return b.err
 	}
 
-	// Frame descriptor byte
-	var flgValue byte = 0
-	if pw.header.blockIndependence {
-		flgValue |= flagBlockIndependence
+	data := b.compressed
+	size := uint32(len(data))
+	if data == nil {
+		data = b.data
+		size = uint32(len(data)) | 0x80000000 // Set high bit to indicate uncompressed
 	}
-	if pw.header.blockChecksum {
-		flgValue |= flagBlockChecksum
+
+	binary.LittleEndian.PutUint32(pw.buf[:4], size)
+	if err := pw.writeOut(pw.buf[:4]); err != nil {
+		return err
 	}
-	if pw.header.contentSize {
-		flgValue |= flagContentSize
+	if err := pw.writeOut(data); err != nil {
+		return err
 	}
-	if pw.header.contentChecksum {
-		flgValue |= flagContentChecksum
+	if pw.header.blockChecksum {
+		binary.LittleEndian.PutUint32(pw.buf[:4], b.sum)
+		if err := pw.writeOut(pw.buf[:4]); err != nil {
+			return err
+		}
 	}
-	if pw.header.dictID {
-		flgValue |= flagDictID
+
+	if pw.metrics != nil {
+		pw.metrics.RecordBlock(len(data), len(b.data), b.elapsed)
 	}
+	return nil
+}
 
-	// BD byte (contains block size code)
-	bdValue := (pw.header.blockSizeCode & 0x7) << 4
+// writeFrameHeader writes the LZ4 frame header, encoded like the Writer's
+// so that it carries the version bits readers check
+func (pw *ParallelWriter) writeFrameHeader() error {
+	pw.content.Reset()
 
-	// Write FLG and BD bytes
-	pw.buf[0] = flgValue
-	pw.buf[1] = bdValue
-	if _, err := pw.w.Write(pw.buf[:2]); err != nil {
-		return err
-	}
+	return pw.writeOut(appendFrameHeader(pw.buf[:0], &pw.header))
+}
 
-	// HC byte (header checksum)
-	checksum := (flgValue >> 2) + (flgValue << 6) + (bdValue >> 2) + (bdValue << 6)
-	pw.buf[0] = checksum
-	if _, err := pw.w.Write(pw.buf[:1]); err != nil {
-		return err
+// writeOut writes p to the underlying writer, remembering its first error
+func (pw *ParallelWriter) writeOut(p []byte) error {
+	_, err := writeFull(pw.w, p)
+	if err != nil && pw.err == nil {
+		pw.err = err
 	}
-
-	return nil
+	return err
 }
@@ -0,0 +1,138 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestParallelWriterWorkers tests that the number of workers and the
+// in-flight limit don't change the frame written
+func TestParallelWriterWorkers(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := append(generateCompressibleData(20*blockSize+100), generateRandomData(3*blockSize)...)
+
+	var want []byte
+	for _, opts := range []ParallelWriterOptions{
+		{NumWorkers: 1, MaxInFlightBytes: blockSize},
+		{NumWorkers: 1},
+		{NumWorkers: 4},
+		{NumWorkers: 8, MaxInFlightBytes: 3 * blockSize},
+		{NumWorkers: 16, UseV2: true},
+	} {
+		opts.BlockSize = blockSize
+		opts.ContentChecksum = true
+
+		var buf bytes.Buffer
+		w := NewParallelWriterWithOptions(&buf, opts)
+		for p := data; len(p) > 0; {
+			n := min(len(p), 10000)
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Fatalf("%+v: Write() error = %v", opts, err)
+			}
+			p = p[n:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%+v: Close() error = %v", opts, err)
+		}
+
+		if got, _ := readFrame(t, buf.Bytes()); !bytes.Equal(got, data) {
+			t.Errorf("%+v: read %d bytes, want %d", opts, len(got), len(data))
+		}
+		if opts.UseV2 {
+			continue
+		}
+		if want == nil {
+			want = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%+v: frame differs from a single worker's", opts)
+		}
+	}
+}
+
+// TestParallelWriterMaxInFlight tests that the blocks held never exceed
+// MaxInFlightBytes
+func TestParallelWriterMaxInFlight(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := generateCompressibleData(40 * blockSize)
+
+	tests := []struct {
+		limit     int
+		blockSize int
+	}{
+		{4 * blockSize, blockSize},
+		{4*blockSize + 100, blockSize},
+		{blockSize, blockSize},
+		{blockSize / 2, blockSize / 2},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := NewParallelWriterWithOptions(&buf, ParallelWriterOptions{
+			BlockSize:        blockSize,
+			NumWorkers:       16,
+			MaxInFlightBytes: tt.limit,
+		})
+		if w.blockSize != tt.blockSize {
+			t.Errorf("limit %d: block size = %d, want %d", tt.limit, w.blockSize, tt.blockSize)
+		}
+
+		for p := data; len(p) > 0; {
+			n := min(len(p), 30000)
+			if _, err := w.Write(p[:n]); err != nil {
+				t.Fatalf("limit %d: Write() error = %v", tt.limit, err)
+			}
+			p = p[n:]
+
+			// Every block buffer allocated is in flight, spare or being filled
+			if held := (len(w.pending) + len(w.spareData) + 1) * w.blockSize; held > tt.limit {
+				t.Fatalf("limit %d: holding %d bytes", tt.limit, held)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("limit %d: Close() error = %v", tt.limit, err)
+		}
+		if got, _ := readFrame(t, buf.Bytes()); !bytes.Equal(got, data) {
+			t.Errorf("limit %d: read %d bytes, want %d", tt.limit, len(got), len(data))
+		}
+	}
+}
+
+// slowReader reads from r, pausing before every read
+type slowReader struct {
+	r io.Reader
+}
+
+func (s slowReader) Read(p []byte) (int, error) {
+	time.Sleep(100 * time.Microsecond)
+	return s.r.Read(p[:min(len(p), 4096)])
+}
+
+// TestParallelWriterPipe tests streaming through an io.Pipe read more
+// slowly than it is written
+func TestParallelWriterPipe(t *testing.T) {
+	data := generateCompressibleData(2 << 20)
+
+	pr, pw := io.Pipe()
+	go func() {
+		w := NewParallelWriterWithOptions(pw, ParallelWriterOptions{
+			BlockSize:        64 * 1024,
+			NumWorkers:       8,
+			MaxInFlightBytes: 256 * 1024,
+		})
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	got, err := io.ReadAll(NewReader(slowReader{pr}))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes, want %d", len(got), len(data))
+	}
+}
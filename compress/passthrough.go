@@ -0,0 +1,105 @@
+package compress
+
+import (
+	"io"
+	"time"
+)
+
+// SetPassthrough switches the Writer between compressing blocks and storing
+// them raw. In passthrough mode blocks are written as stored blocks without
+// running the encoder, as at level NoCompression, while block and content
+// checksums are still computed, so a pipeline keeps LZ4 framing and
+// integrity checks at little more than copy speed. It can be switched at any
+// time, even mid-frame: it applies to blocks flushed after the call, and
+// readers need no setting to read the result.
+func (z *Writer) SetPassthrough(on bool) {
+	z.lock()
+	defer z.unlock()
+
+	// A block in the background may be checking the mode
+	z.wait()
+	z.passthrough = on
+}
+
+// WriteStored writes p as stored blocks without trying to compress it, for
+// data the caller already knows won't compress, such as encrypted or
+// compressed data, saving the trial compression Write would run on it. Data
+// buffered by earlier Writes is first flushed as a block of its own, and p
+// is written at once in blocks of at most the block size, with checksums as
+// for Write. Writers with content-defined chunking reject it, as it would
+// end chunks at write boundaries.
+func (z *Writer) WriteStored(p []byte) (int, error) {
+	z.lock()
+	defer z.unlock()
+
+	if err := z.startDirect("WriteStored"); err != nil {
+		return 0, err
+	}
+
+	var written int
+	for len(p) > 0 {
+		n := min(len(p), z.blockSize)
+		if err := z.writeStored(p[:n], time.Now()); err != nil {
+			return written, err
+		}
+		z.accept(p[:n])
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// WriteTo implements io.WriterTo, so io.Copy and Decompress hand each
+// decoded block to w straight from the Reader's buffer rather than copying
+// it through an intermediate one. Stored blocks, such as those of
+// passthrough frames, are read into that buffer and written out without
+// another copy. It returns the number of bytes written, and follows the
+// rules of Read: errors are sticky, and a clean end returns nil.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, ErrReaderClosed
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.reachedEof {
+		return 0, nil
+	}
+	if err := r.ensureHeader(); err != nil {
+		if err == io.EOF {
+			r.reachedEof = true
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var written int64
+	for {
+		if r.out.len() == 0 {
+			r.out.reset()
+			if _, err := r.readBlock(nil); err != nil {
+				if err == io.EOF {
+					r.reachedEof = true
+					return written, nil
+				}
+				r.err = err
+				return written, err
+			}
+			continue
+		}
+
+		n, err := w.Write(r.out.unread())
+		r.out.discard(n)
+		written += int64(n)
+		r.produced.Add(uint64(n))
+		if err == nil && r.out.len() > 0 {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
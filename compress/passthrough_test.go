@@ -0,0 +1,167 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+// TestWriterPassthrough tests that blocks written in passthrough mode are
+// stored with their checksums, and that the mode can change mid-frame
+func TestWriterPassthrough(t *testing.T) {
+	data := generateCompressibleData(6 * 64 * 1024)
+
+	for _, async := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriterWithOptions(&buf, WriterOptions{
+			BlockSize:       64 * 1024,
+			BlockChecksum:   XXH32,
+			ContentChecksum: true,
+			AsyncFlush:      async,
+		})
+		block := func(i int) []byte { return data[i*64*1024 : (i+1)*64*1024] }
+		for i := 0; i < 6; i++ {
+			// Blocks are flushed when the next write finds them full
+			w.SetPassthrough(i >= 2 && i < 4)
+			if i == 5 {
+				w.SetPassthrough(false)
+			}
+			if _, err := w.Write(block(i)); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		stored := map[int]bool{}
+		r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{DebugTrace: func(ev TraceEvent) {
+			stored[ev.Block] = ev.Stored
+		}})
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("async %v: ReadAll() = %d bytes, %v, want %d", async, len(got), err, len(data))
+		}
+		// Block i is flushed by the write of block i+1, under its setting
+		for i, want := range []bool{false, true, true, false, false, false} {
+			if stored[i] != want {
+				t.Errorf("async %v: block %d stored = %v, want %v", async, i, stored[i], want)
+			}
+		}
+	}
+}
+
+// TestWriterWriteStored tests that WriteStored stores data that would
+// compress, after flushing what Write buffered, and keeps the checksums
+func TestWriterWriteStored(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+	head, raw, tail := data[:100*1024], data[100*1024:250*1024], data[250*1024:]
+
+	for _, async := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriterWithOptions(&buf, WriterOptions{
+			BlockSize:       64 * 1024,
+			BlockChecksum:   XXH32,
+			ContentChecksum: true,
+			AsyncFlush:      async,
+		})
+		if _, err := w.Write(head); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if n, err := w.WriteStored(raw); n != len(raw) || err != nil {
+			t.Fatalf("WriteStored() = %d, %v", n, err)
+		}
+		if _, err := w.Write(tail); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if w.Written() != uint64(len(data)) {
+			t.Errorf("Written() = %d, want %d", w.Written(), len(data))
+		}
+
+		// Each block's size is where its last sequence ends
+		var sizes []int
+		var stored []bool
+		r := NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{DebugTrace: func(ev TraceEvent) {
+			if ev.Block == len(sizes) {
+				sizes, stored = append(sizes, 0), append(stored, ev.Stored)
+			}
+			sizes[ev.Block] = ev.RawPos + len(ev.Literals) + ev.MatchLen
+		}})
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("async %v: ReadAll() = %d bytes, %v, want %d", async, len(got), err, len(data))
+		}
+		// The 36KB left of head ends a block of its own before the stored ones
+		wantSizes := []int{64 << 10, 36 << 10, 64 << 10, 64 << 10, 22 << 10, 50 << 10}
+		wantStored := []bool{false, false, true, true, true, false}
+		if !slices.Equal(sizes, wantSizes) || !slices.Equal(stored, wantStored) {
+			t.Errorf("async %v: blocks of %v bytes, stored %v, want %v, %v", async, sizes, stored, wantSizes, wantStored)
+		}
+	}
+
+	w := NewWriter(io.Discard)
+	if err := w.SetChunking(ChunkingOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteStored(raw); !errors.Is(err, ErrInvalidChunking) {
+		t.Errorf("WriteStored() with chunking error = %v, want %v", err, ErrInvalidChunking)
+	}
+	w = NewWriter(io.Discard)
+	w.Close()
+	if _, err := w.WriteStored(raw); err == nil {
+		t.Error("WriteStored() after Close succeeded")
+	}
+}
+
+// TestReaderWriteTo tests that WriteTo returns the same data as Read, for
+// stored and compressed frames, and keeps Read's error rules
+func TestReaderWriteTo(t *testing.T) {
+	data := append(generateCompressibleData(200*1024), generateRandomData(100*1024)...)
+
+	for _, store := range []bool{false, true} {
+		frame := compressFrame(t, data, func(w *Writer) {
+			w.SetPassthrough(store)
+			w.header.blockChecksum = true
+		})
+
+		var out bytes.Buffer
+		r := NewReader(bytes.NewReader(frame))
+		n, err := r.WriteTo(&out)
+		if err != nil || n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+			t.Errorf("store %v: WriteTo() = %d, %v, want %d matching bytes", store, n, err, len(data))
+		}
+		if n, err := r.WriteTo(&out); n != 0 || err != nil {
+			t.Errorf("WriteTo() at the end = %d, %v, want 0, nil", n, err)
+		}
+		if r.Produced() != uint64(len(data)) {
+			t.Errorf("Produced() = %d, want %d", r.Produced(), len(data))
+		}
+
+		// A truncated frame writes what it can, then fails for good
+		out.Reset()
+		r = NewReader(bytes.NewReader(frame[:len(frame)/2]))
+		_, err = r.WriteTo(&out)
+		if !errors.Is(err, io.ErrUnexpectedEOF) || !bytes.Equal(out.Bytes(), data[:out.Len()]) {
+			t.Errorf("store %v: WriteTo() of a truncated frame error = %v after %d bytes", store, err, out.Len())
+		}
+		if _, err2 := r.Read(make([]byte, 1)); err2 != err {
+			t.Errorf("Read() after WriteTo() failed = %v, want %v", err2, err)
+		}
+	}
+
+	// A failing destination keeps the undelivered data
+	r := NewReader(bytes.NewReader(compressFrame(t, data, nil)))
+	sw := &shortWriter{max: 1000, limit: 5000}
+	if _, err := r.WriteTo(sw); err != io.ErrShortWrite {
+		t.Errorf("WriteTo() to a full writer error = %v, want %v", err, io.ErrShortWrite)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(append(sw.buf.Bytes(), rest...), data) {
+		t.Errorf("ReadAll() after a failed WriteTo() = %d bytes, %v, want the remaining %d", len(rest), err, len(data)-sw.buf.Len())
+	}
+}
@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+// perfTolerance is how far throughput may fall below its budget or baseline
+// before TestPerformanceBudgets fails
+const perfTolerance = 0.15
+
+// perfBudgets are the documented performance budgets of CompressBlockLevel on
+// testdata/golden/text.raw, kept in sync with the table in README.md. Speeds
+// are single-core floors for a CI runner; ratios are exact properties of the
+// encoder and hold on any machine.
+var perfBudgets = []struct {
+	level    CompressionLevel
+	minMBps  float64
+	minRatio float64
+}{
+	{1, 100, 2.4},
+	{2, 85, 2.55},
+	{6, 20, 3.95},
+	{9, 6, 4.45},
+	{12, 4, 4.45},
+}
+
+// perfResult is the measured performance of one level
+type perfResult struct {
+	MBps  float64 `json:"mbps"`
+	Ratio float64 `json:"ratio"`
+}
+
+// measureLevel compresses data at level and returns the best throughput of a
+// few runs, which is less affected by noisy neighbours than the mean
+func measureLevel(t *testing.T, data []byte, level CompressionLevel) perfResult {
+	t.Helper()
+
+	dst := make([]byte, len(data)+len(data)/255+16)
+	compressed, err := CompressBlockLevel(data, dst, level)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel(level %d) error = %v", level, err)
+	}
+	result := perfResult{Ratio: float64(len(data)) / float64(len(compressed))}
+
+	for run := 0; run < 3; run++ {
+		r := testing.Benchmark(func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				CompressBlockLevel(data, dst, level)
+			}
+		})
+		mbps := float64(r.Bytes) * float64(r.N) / r.T.Seconds() / 1e6
+		if mbps > result.MBps {
+			result.MBps = mbps
+		}
+	}
+	return result
+}
+
+// TestPerformanceBudgets checks the documented speed and ratio of each level.
+// Timing is too noisy for ordinary test runs, so it only runs with
+// GOZ4X_PERF=1. If GOZ4X_PERF_BASELINE names a file, throughput is also
+// compared with the results recorded there, which are written on the first
+// run, so a change slowing any level down by more than 15% on the same
+// machine fails.
+func TestPerformanceBudgets(t *testing.T) {
+	if os.Getenv("GOZ4X_PERF") != "1" {
+		t.Skip("set GOZ4X_PERF=1 to run performance budget tests")
+	}
+	data := readGolden(t, "text.raw")
+
+	baselinePath := os.Getenv("GOZ4X_PERF_BASELINE")
+	var baseline map[string]perfResult
+	if baselinePath != "" {
+		raw, err := os.ReadFile(baselinePath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			t.Fatalf("ReadFile() error = %v", err)
+		default:
+			if err := json.Unmarshal(raw, &baseline); err != nil {
+				t.Fatalf("baseline %s: %v", baselinePath, err)
+			}
+		}
+	}
+
+	results := make(map[string]perfResult)
+	for _, budget := range perfBudgets {
+		key := fmt.Sprintf("level%d", budget.level)
+		t.Run(key, func(t *testing.T) {
+			got := measureLevel(t, data, budget.level)
+			results[key] = got
+			t.Logf("%.1f MB/s, ratio %.3f", got.MBps, got.Ratio)
+
+			if got.Ratio < budget.minRatio {
+				t.Errorf("ratio = %.3f, want at least %.2f", got.Ratio, budget.minRatio)
+			}
+			if floor := budget.minMBps * (1 - perfTolerance); got.MBps < floor {
+				t.Errorf("throughput = %.1f MB/s, want at least %.1f (budget %.0f MB/s)", got.MBps, floor, budget.minMBps)
+			}
+			if base, ok := baseline[key]; ok {
+				if floor := base.MBps * (1 - perfTolerance); got.MBps < floor {
+					t.Errorf("throughput = %.1f MB/s, more than 15%% below the baseline of %.1f MB/s", got.MBps, base.MBps)
+				}
+			}
+		})
+	}
+
+	if baselinePath != "" && baseline == nil && !t.Failed() {
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if err := os.WriteFile(baselinePath, raw, 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		t.Logf("wrote baseline %s", baselinePath)
+	}
+}
@@ -0,0 +1,99 @@
+package compress
+
+import "bytes"
+
+// matcherPrime holds the match finder tables of a dictionary indexed by
+// Prewarm, so that blocks compressed with it start from a copy instead of
+// hashing the dictionary again
+type matcherPrime struct {
+	// dict is a copy of the indexed dictionary window
+	dict []byte
+	// hashTable and chainTable are the tables after inserting the first n
+	// positions of dict. The hashes of the last few positions read bytes of
+	// the block that follows, so they are inserted with each block.
+	hashTable  []int
+	chainTable []int
+	n          int
+	// hashLog is the size of hashTable, set for the blocks expected
+	hashLog int
+}
+
+// Prewarm prepares c for blocks like sample, so that the first of them is
+// compressed as fast as the ones after it. The tables are sized for blocks
+// of len(sample) bytes, and the hashes of sample are inserted once and kept,
+// so that CompressBlockDict with sample as the dictionary copies them instead
+// of hashing the dictionary for every block. Output is unchanged.
+//
+// Blocks compressed without a dictionary can't reuse the hashes, as their
+// matches may only reference data the decoder has: to benefit from a
+// representative sample beyond sizing, use it as the dictionary on both
+// sides. A later Prewarm replaces the sample.
+func (c *Compressor) Prewarm(sample []byte) {
+	c.prewarm(sample, len(sample))
+}
+
+// prewarm is Prewarm for blocks of blockSize bytes
+func (c *Compressor) prewarm(sample []byte, blockSize int) {
+	c.prime = nil
+	if c.level == NoCompression || len(sample) == 0 {
+		return
+	}
+
+	dict := dictWindow(sample)
+	size := len(dict) + blockSize
+	m := c.matcher
+	if cap(m.chainTable) < size {
+		m.chainTable = make([]int, size)
+	}
+	if cap(c.window) < size {
+		c.window = make([]byte, 0, size)
+	}
+
+	// Positions whose hashes only read dict; hash5 reads one byte more.
+	// The hash table is sized for the dictionary followed by a block.
+	n := max(len(dict)-5, 0)
+	m.Reset(dict)
+	m.setHashLog(hashLogFor(size, m.levelLog, m.maxAttempts))
+	clear(m.hashTable)
+	m.UpdateTables(0, n)
+	m.buf = nil
+
+	c.prime = &matcherPrime{
+		dict:       bytes.Clone(dict),
+		hashTable:  append([]int(nil), m.hashTable...),
+		chainTable: append([]int(nil), m.chainTable[:n]...),
+		n:          n,
+		hashLog:    m.hashLog,
+	}
+}
+
+// primedFor reports whether dict, as passed to CompressBlockDict, is the
+// sample indexed by Prewarm
+func (c *Compressor) primedFor(dict []byte) bool {
+	return c.prime != nil && bytes.Equal(c.prime.dict, dict)
+}
+
+// restore prepares the matcher for input, which starts with the dictionary
+// of p, from the tables saved in p. It leaves the matcher as Reset followed
+// by UpdateTables(0, p.n) would, which it falls back to if input needs a
+// hash table of another size.
+func (hc *HCMatcher) restore(input []byte, p *matcherPrime) {
+	if hashLogFor(len(input), hc.levelLog, hc.maxAttempts) != p.hashLog {
+		hc.Reset(input)
+		hc.UpdateTables(0, p.n)
+		return
+	}
+
+	hc.setHashLog(p.hashLog)
+	hc.buf = input
+	hc.end = len(input)
+	hc.pos = 0
+
+	if cap(hc.chainTable) < len(input) {
+		hc.chainTable = make([]int, len(input))
+	} else {
+		hc.chainTable = hc.chainTable[:len(input)]
+	}
+	copy(hc.chainTable, p.chainTable)
+	copy(hc.hashTable, p.hashTable)
+}
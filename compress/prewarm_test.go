@@ -0,0 +1,63 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestPrewarm tests that a prewarmed Compressor produces the same blocks
+func TestPrewarm(t *testing.T) {
+	dict := generateCompressibleData(100 * 1024)
+	blocks := [][]byte{
+		append(bytes.Clone(dict[5000:9000]), generateRandomData(2000)...),
+		generateCompressibleData(64 * 1024),
+		dict[len(dict)-3000:],
+		[]byte("short block of text"),
+	}
+
+	for _, level := range []CompressionLevel{NoCompression, 1, DefaultLevel, 9, MaxLevel} {
+		plain, err := NewCompressor(level)
+		if err != nil {
+			t.Fatalf("NewCompressor(%d) error = %v", level, err)
+		}
+		warm, _ := NewCompressor(level)
+		warm.Prewarm(dict)
+
+		for i, block := range blocks {
+			for _, d := range [][]byte{dict, nil, dict[:1000]} {
+				want, err := plain.CompressBlockDict(block, nil, d)
+				if err != nil {
+					t.Fatalf("CompressBlockDict() error = %v", err)
+				}
+				got, err := warm.CompressBlockDict(block, nil, d)
+				if err != nil {
+					t.Fatalf("prewarmed CompressBlockDict() error = %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("level %d block %d dict %d bytes: prewarmed output differs", level, i, len(d))
+				}
+			}
+		}
+	}
+}
+
+// TestPrewarmCopiesSample tests that changing the sample after Prewarm
+// doesn't corrupt blocks compressed with the new contents
+func TestPrewarmCopiesSample(t *testing.T) {
+	dict := generateCompressibleData(10000)
+	c, _ := NewCompressor(DefaultLevel)
+	c.Prewarm(dict)
+
+	for i := range dict {
+		dict[i] ^= 0x5A
+	}
+	block := append(bytes.Clone(dict[100:2000]), dict[4000:6000]...)
+	compressed, err := c.CompressBlockDict(block, nil, dict)
+	if err != nil {
+		t.Fatalf("CompressBlockDict() error = %v", err)
+	}
+	got, err := DecompressBlockDict(compressed, dict, len(block))
+	if err != nil || !bytes.Equal(got, block) {
+		t.Errorf("DecompressBlockDict() = %d bytes, %v, want %d bytes", len(got), err, len(block))
+	}
+}
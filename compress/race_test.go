@@ -0,0 +1,7 @@
+//go:build race
+
+package compress
+
+// raceEnabled is set when testing with the race detector, under which
+// sync.Pool drops items at random, so pooled calls may allocate
+const raceEnabled = true
@@ -0,0 +1,34 @@
+package compress
+
+import (
+	"io"
+)
+
+// Recompress decodes the LZ4 frame read from src and re-encodes it to dst
+// using the given writer options. Data is streamed one block at a time, so
+// memory use is bounded by the block sizes of the two frames rather than by
+// the size of the content. A declared content size is carried over to the
+// new frame. Only the first frame is transcoded: src is left just past it,
+// so that concatenated frames can be transcoded by calling Recompress
+// again. It returns the number of uncompressed bytes transcoded.
+func Recompress(dst io.Writer, src io.Reader, options WriterOptions) (int64, error) {
+	r := NewReader(src)
+	r.firstFrame = true
+	if err := r.ensureHeader(); err != nil {
+		return 0, err
+	}
+
+	w := NewWriterWithOptions(dst, options)
+	if r.header.contentSize {
+		if err := w.SetContentSize(r.header.contentSizeValue); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return n, err
+	}
+
+	return n, w.Close()
+}
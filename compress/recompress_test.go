@@ -0,0 +1,97 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestRecompress tests transcoding frames between levels and algorithms
+func TestRecompress(t *testing.T) {
+	data := append(generateCompressibleData(300*1024), generateRandomData(20*1024)...)
+
+	var original bytes.Buffer
+	w := NewWriterWithOptions(&original, WriterOptions{Level: 1, BlockSize: 64 * 1024})
+	w.header.contentSize = true
+	w.header.contentSizeValue = uint64(len(data))
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		options WriterOptions
+	}{
+		{"Max level", WriterOptions{Level: MaxLevel}},
+		{"V2 algorithm", WriterOptions{Level: DefaultLevel, UseV2: true}},
+		{"Small blocks", WriterOptions{Level: FastLevel, BlockSize: 32 * 1024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			n, err := Recompress(&out, bytes.NewReader(original.Bytes()), tt.options)
+			if err != nil {
+				t.Fatalf("Recompress() error = %v", err)
+			}
+			if n != int64(len(data)) {
+				t.Errorf("Recompress() = %d bytes, want %d", n, len(data))
+			}
+
+			r := NewReader(&out)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("recompressed frame decoded to %d bytes, want %d", len(got), len(data))
+			}
+			if !r.header.contentSize || r.header.contentSizeValue != uint64(len(data)) {
+				t.Errorf("content size was not carried over")
+			}
+		})
+	}
+
+	// Invalid input surfaces the header error
+	if _, err := Recompress(io.Discard, bytes.NewReader([]byte("not lz4")), WriterOptions{}); err == nil {
+		t.Errorf("Recompress() with invalid input: error = nil, expected error")
+	}
+}
+
+// TestRecompressConcatenated tests that only the first of concatenated
+// frames is transcoded, so its content size still holds
+func TestRecompressConcatenated(t *testing.T) {
+	first, second := generateCompressibleData(40*1024), generateRandomData(1024)
+	var src bytes.Buffer
+	for _, data := range [][]byte{first, second} {
+		frame, err := EncodeFrame(data, WriterOptions{Level: FastLevel})
+		if err != nil {
+			t.Fatalf("EncodeFrame() error = %v", err)
+		}
+		src.Write(frame)
+	}
+
+	for i, want := range [][]byte{first, second} {
+		var out bytes.Buffer
+		n, err := Recompress(&out, &src, WriterOptions{Level: MaxLevel})
+		if err != nil {
+			t.Fatalf("frame %d: Recompress() error = %v", i, err)
+		}
+		if n != int64(len(want)) {
+			t.Errorf("frame %d: Recompress() = %d bytes, want %d", i, n, len(want))
+		}
+		got, err := DecodeFrame(out.Bytes())
+		if err != nil {
+			t.Fatalf("frame %d: DecodeFrame() error = %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d: recompressed frame decoded to %d bytes, want %d", i, len(got), len(want))
+		}
+	}
+	if src.Len() != 0 {
+		t.Errorf("%d bytes left unread, want 0", src.Len())
+	}
+}
@@ -0,0 +1,31 @@
+package compress
+
+import "encoding/binary"
+
+// V2Block matches runs of one repeated byte, such as the zeros of sparse
+// buffers and the padding of fixed-width records, without asking its
+// matcher. At each position it first compares the next 8 bytes with the
+// current byte, which rejects most positions in one load, and a run of at
+// least rleMinRun bytes becomes a single match at offset 1: from the run's
+// start if the byte before it is the same, or after a literal of its first
+// byte otherwise. The matcher would find such runs only after walking its
+// hash chain at every position, and never finds runs of zeros, whose 4-byte
+// hash is the one it leaves out of its table.
+
+// rleMinRun is the shortest run of one byte matched without the matcher
+const rleMinRun = 16
+
+// byteRun returns the length of the run of copies of src[pos] starting at
+// pos, not counting bytes at or beyond limit
+func byteRun(src []byte, pos, limit int) int {
+	c := src[pos]
+	pattern := uint64(c) * 0x0101010101010101
+	n := pos
+	for n+8 <= limit && binary.LittleEndian.Uint64(src[n:]) == pattern {
+		n += 8
+	}
+	for n < limit && src[n] == c {
+		n++
+	}
+	return n - pos
+}
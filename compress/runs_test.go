@@ -0,0 +1,103 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// generateRunData returns size bytes of padded records: short text fields
+// followed by long runs of zeros or spaces, as in sparse buffers and
+// fixed-width files
+func generateRunData(size int) []byte {
+	text := generateCompressibleData(size)
+	data := make([]byte, 0, size)
+	for i := 0; len(data) < size; i++ {
+		data = append(data, text[i*37%len(text):][:min(20+i%13, size-len(data))]...)
+		pad := byte(0)
+		if i%3 == 0 {
+			pad = ' '
+		}
+		for n := 30 + i*7%200; n > 0 && len(data) < size; n-- {
+			data = append(data, pad)
+		}
+	}
+	return data
+}
+
+// TestCompressBlockV2Runs tests that runs of one byte round-trip at any
+// position, including the start and end of a block, that they are matched
+// at offset 1, and that padded records compress well
+func TestCompressBlockV2Runs(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		// runs is the number of runs the block holds, and minRatio the
+		// compression ratio wanted
+		runs     int
+		minRatio float64
+	}{
+		{"All zeros", make([]byte, 64*1024), 1, 200},
+		{"Run at start", append(bytes.Repeat([]byte{'x'}, 100), generateRandomData(1000)...), 1, 0},
+		{"Run at end", append(generateRandomData(1000), bytes.Repeat([]byte{'x'}, 100)...), 1, 0},
+		{"Run after same byte", append(append(generateRandomData(500), 'y', 'y'), bytes.Repeat([]byte{'y'}, 40)...), 1, 0},
+		{"Runs of 15 and 16", append(append(generateRandomData(100), bytes.Repeat([]byte{'a'}, 15)...), append(bytes.Repeat([]byte{'b'}, 16), generateRandomData(100)...)...), 1, 0},
+		{"Padded records", generateRunData(256 * 1024), 100, 8},
+	}
+
+	for _, tt := range tests {
+		for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+			t.Run(fmt.Sprintf("%s/Level-%d", tt.name, level), func(t *testing.T) {
+				compressed, err := CompressBlockV2Level(tt.data, nil, level)
+				if err != nil {
+					t.Fatalf("CompressBlockV2Level() error = %v", err)
+				}
+				got, err := DecompressBlock(compressed, nil, len(tt.data))
+				if err != nil || !bytes.Equal(got, tt.data) {
+					t.Fatalf("DecompressBlock() = %d bytes, %v, want %d", len(got), err, len(tt.data))
+				}
+
+				var matched int
+				sr := NewSequenceReader(compressed)
+				for {
+					seq, err := sr.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("Next() error = %v", err)
+					}
+					if seq.Offset == 1 && seq.MatchLen >= rleMinRun-1 {
+						matched++
+					}
+				}
+				if matched < tt.runs {
+					t.Errorf("%d long matches at offset 1, want at least %d", matched, tt.runs)
+				}
+				if ratio := float64(len(tt.data)) / float64(len(compressed)); ratio < tt.minRatio {
+					t.Errorf("ratio = %.1f, want at least %.1f", ratio, tt.minRatio)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkCompressBlockV2Runs measures compressing padded records, which
+// are mostly runs of zeros and spaces
+func BenchmarkCompressBlockV2Runs(b *testing.B) {
+	src := generateRunData(1 << 20)
+	dst := make([]byte, compressBound(len(src)))
+
+	for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+		b.Run(fmt.Sprintf("Level-%d", level), func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			var n int
+			for i := 0; i < b.N; i++ {
+				out, _ := CompressBlockV2Level(src, dst, level)
+				n = len(out)
+			}
+			b.ReportMetric(float64(len(src))/float64(n), "ratio")
+		})
+	}
+}
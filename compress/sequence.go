@@ -0,0 +1,74 @@
+package compress
+
+import "github.com/harriteja/GoZ4X/compress/block"
+
+// ErrCorruptBlock indicates a block whose sequences can't be parsed
+var ErrCorruptBlock = block.ErrCorruptBlock
+
+// Sequence describes one LZ4 sequence: a run of literals followed by an
+// optional back-reference. It is block.Sequence.
+type Sequence = block.Sequence
+
+// SequenceReader walks the sequences of a compressed block without decoding
+// it. It is block.SequenceReader.
+type SequenceReader = block.SequenceReader
+
+// NewSequenceReader creates a SequenceReader over a compressed block
+func NewSequenceReader(b []byte) *SequenceReader {
+	return block.NewSequenceReader(b)
+}
+
+// The rules of the block format a ValidatingDecoder checks beyond those
+// needed to decode a block. They are the errors of package block.
+var (
+	ErrMatchOffset  = block.ErrMatchOffset
+	ErrMatchNearEnd = block.ErrMatchNearEnd
+	ErrLastLiterals = block.ErrLastLiterals
+	ErrNonCanonical = block.ErrNonCanonical
+)
+
+// ValidatingDecoder decodes blocks while checking every rule of the block
+// format, for testing encoders. It is block.ValidatingDecoder.
+type ValidatingDecoder = block.ValidatingDecoder
+
+// SequenceError reports the first sequence of a block that breaks a rule,
+// with its index and byte offset. It is block.SequenceError.
+type SequenceError = block.SequenceError
+
+// appendSequence appends an encoded sequence to dst.
+// A matchLen of 0 emits a final literal-only sequence.
+func appendSequence(dst []byte, literals []byte, offset, matchLen int) []byte {
+	literalLen := len(literals)
+
+	literalLenCode := min(literalLen, 15)
+	matchLenCode := 0
+	if matchLen > 0 {
+		matchLenCode = min(matchLen-MinMatch, 15)
+	}
+
+	dst = append(dst, byte(literalLenCode<<4|matchLenCode))
+	if literalLen >= 15 {
+		dst = appendLength(dst, literalLen-15)
+	}
+	dst = append(dst, literals...)
+
+	if matchLen == 0 {
+		return dst
+	}
+
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if matchLen-MinMatch >= 15 {
+		dst = appendLength(dst, matchLen-MinMatch-15)
+	}
+
+	return dst
+}
+
+// appendLength appends the extension bytes for a length remainder
+func appendLength(dst []byte, remaining int) []byte {
+	for remaining >= 255 {
+		dst = append(dst, 255)
+		remaining -= 255
+	}
+	return append(dst, byte(remaining))
+}
@@ -0,0 +1,190 @@
+package compress
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// messageResetFlag marks, in the uncompressed length of a session message,
+// the first message after the start or a Reset of the EncoderSession
+const messageResetFlag = 0x80000000
+
+// EncoderSession compresses a sequence of messages with a shared history:
+// each message may reference the last 64KB of the messages before it, as
+// the dependent blocks of a frame do, which compresses streams of similar
+// small messages such as metrics or log lines far better than compressing
+// each on its own. Every message keeps the length-prefixed framing of
+// Encoder, so a transport can deliver them separately, but they must be
+// decompressed in order by one DecoderSession. Reset starts a new history,
+// and the next message tells the DecoderSession to do the same.
+type EncoderSession struct {
+	compressor *Compressor
+	// history holds the end of the messages compressed since the last reset
+	history []byte
+	// reset is set until the first message after a reset is compressed
+	reset bool
+	mu    sync.Mutex
+}
+
+// DecoderSession decompresses the messages of an EncoderSession, in the
+// order they were compressed
+type DecoderSession struct {
+	history []byte
+	// started is set once a message starting a history has been read
+	started bool
+	mu      sync.Mutex
+}
+
+// NewEncoderSession returns an EncoderSession compressing at the given level
+func NewEncoderSession(level CompressionLevel) (*EncoderSession, error) {
+	c, err := NewCompressor(level)
+	if err != nil {
+		return nil, err
+	}
+	return &EncoderSession{compressor: c, reset: true}, nil
+}
+
+// Compress compresses msg into a single message, which may reference the
+// messages compressed before it since the last Reset.
+// If dst is nil or too small, a new buffer will be allocated.
+func (s *EncoderSession) Compress(msg, dst []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(msg) > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	worstCase := messageHeaderSize + compressBound(len(msg))
+	if len(dst) < worstCase {
+		dst = make([]byte, worstCase)
+	}
+
+	payloadLen := uint32(len(msg)) | messageStoredFlag
+	payload := msg
+
+	// Small messages can't be compressed by the block encoder
+	if len(msg) >= MinBlockSize && s.compressor.Level() != NoCompression {
+		compressed, err := s.compressor.CompressBlockDict(msg, dst[messageHeaderSize:], s.history)
+		if err == nil && len(compressed) < len(msg) {
+			payloadLen = uint32(len(compressed))
+			payload = compressed
+		}
+	}
+
+	rawLen := uint32(len(msg))
+	if s.reset {
+		rawLen |= messageResetFlag
+		s.reset = false
+	}
+	binary.LittleEndian.PutUint32(dst[0:4], payloadLen)
+	binary.LittleEndian.PutUint32(dst[4:8], rawLen)
+	n := copy(dst[messageHeaderSize:], payload)
+
+	s.history = appendHistory(s.history, msg)
+	return dst[:messageHeaderSize+n], nil
+}
+
+// Reset discards the history, so that the next message references none of
+// the messages before it
+func (s *EncoderSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = s.history[:0]
+	s.reset = true
+}
+
+// NewDecoderSession returns a DecoderSession for the messages of an
+// EncoderSession
+func NewDecoderSession() *DecoderSession {
+	return &DecoderSession{}
+}
+
+// Decompress decompresses data, which must hold exactly one message of an
+// EncoderSession, following the messages decompressed before it. Messages
+// before the first one an EncoderSession compressed after starting or a
+// Reset fail with ErrInvalidMessage, as do all messages after an error
+// until the next such message, since the history no longer matches the
+// encoder's. The returned slice is owned by the caller.
+func (s *DecoderSession) Decompress(data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, err := s.decompress(data)
+	if err != nil {
+		s.started = false
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decompress decompresses one message; the caller must hold s.mu
+func (s *DecoderSession) decompress(data []byte) ([]byte, error) {
+	if len(data) < messageHeaderSize {
+		return nil, ErrInvalidMessage
+	}
+	payloadLen := binary.LittleEndian.Uint32(data[0:4])
+	rawLen := binary.LittleEndian.Uint32(data[4:8])
+	payload := data[messageHeaderSize:]
+
+	stored := payloadLen&messageStoredFlag != 0
+	payloadLen &^= messageStoredFlag
+	reset := rawLen&messageResetFlag != 0
+	rawLen &^= messageResetFlag
+
+	if rawLen > MaxMessageSize || int(payloadLen) != len(payload) {
+		return nil, ErrInvalidMessage
+	}
+	if stored && payloadLen != rawLen {
+		return nil, ErrInvalidMessage
+	}
+	if !stored && (rawLen == 0 || int(payloadLen) > compressBound(int(rawLen))) {
+		return nil, ErrInvalidMessage
+	}
+	if !reset && !s.started {
+		return nil, ErrInvalidMessage
+	}
+
+	if reset {
+		s.history = s.history[:0]
+		s.started = true
+	}
+
+	var msg []byte
+	if stored {
+		msg = append([]byte(nil), payload...)
+	} else {
+		var err error
+		msg, err = decompressBlockDict(payload, nil, s.history, int(rawLen))
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) != int(rawLen) {
+			return nil, ErrInvalidMessage
+		}
+	}
+
+	s.history = appendHistory(s.history, msg)
+	return msg, nil
+}
+
+// Reset discards the history, for reading the messages of another
+// EncoderSession
+func (s *DecoderSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = s.history[:0]
+	s.started = false
+}
+
+// appendHistory appends msg to history, keeping only the last 64KB that
+// matches can reach
+func appendHistory(history, msg []byte) []byte {
+	history = append(history, msg...)
+	if over := len(history) - maxDictWindow; over > 0 {
+		history = history[:copy(history, history[over:])]
+	}
+	return history
+}
@@ -0,0 +1,161 @@
+package compress
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestSessionRoundTrip tests that session messages decompress in order and
+// compress far better than independent messages
+func TestSessionRoundTrip(t *testing.T) {
+	records := generateRecords(500, 0)
+
+	for _, level := range []CompressionLevel{NoCompression, 1, DefaultLevel, MaxLevel} {
+		enc, err := NewEncoderSession(level)
+		if err != nil {
+			t.Fatalf("NewEncoderSession(%d) error = %v", level, err)
+		}
+		dec := NewDecoderSession()
+
+		var session, independent int
+		for i, rec := range records {
+			msg, err := enc.Compress(rec, nil)
+			if err != nil {
+				t.Fatalf("level %d: Compress(%d) error = %v", level, i, err)
+			}
+			got, err := dec.Decompress(msg)
+			if err != nil || !bytes.Equal(got, rec) {
+				t.Fatalf("level %d: Decompress(%d) = %q, %v, want %q", level, i, got, err, rec)
+			}
+			session += len(msg)
+
+			var buf bytes.Buffer
+			if err := NewEncoderLevel(&buf, level).Encode(rec); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			independent += buf.Len()
+		}
+
+		if level != NoCompression && session*3 > independent {
+			t.Errorf("level %d: session messages took %d bytes, want under a third of the %d of independent ones",
+				level, session, independent)
+		}
+	}
+}
+
+// TestSessionReset tests that a Reset lets a new DecoderSession start at the
+// next message, and that a DecoderSession can't start anywhere else
+func TestSessionReset(t *testing.T) {
+	records := generateRecords(20, 0)
+	enc, err := NewEncoderSession(DefaultLevel)
+	if err != nil {
+		t.Fatalf("NewEncoderSession() error = %v", err)
+	}
+
+	var msgs [][]byte
+	for i, rec := range records {
+		if i == 10 {
+			enc.Reset()
+		}
+		msg, err := enc.Compress(rec, nil)
+		if err != nil {
+			t.Fatalf("Compress(%d) error = %v", i, err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	// Joining mid-history fails until the message after the Reset
+	dec := NewDecoderSession()
+	for i := 5; i < len(msgs); i++ {
+		got, err := dec.Decompress(msgs[i])
+		switch {
+		case i < 10 && err != ErrInvalidMessage:
+			t.Errorf("Decompress(%d) error = %v, want %v", i, err, ErrInvalidMessage)
+		case i >= 10 && (err != nil || !bytes.Equal(got, records[i])):
+			t.Errorf("Decompress(%d) = %q, %v, want %q", i, got, err, records[i])
+		}
+	}
+
+	// A reset decoder can read the stream from the start again
+	dec.Reset()
+	for i, msg := range msgs {
+		if got, err := dec.Decompress(msg); err != nil || !bytes.Equal(got, records[i]) {
+			t.Errorf("Decompress(%d) after Reset = %q, %v, want %q", i, got, err, records[i])
+		}
+	}
+}
+
+// TestSessionErrors tests that a bad message stops the session until the
+// next reset message
+func TestSessionErrors(t *testing.T) {
+	records := generateRecords(6, 0)
+	enc, err := NewEncoderSession(DefaultLevel)
+	if err != nil {
+		t.Fatalf("NewEncoderSession() error = %v", err)
+	}
+	var msgs [][]byte
+	for i, rec := range records {
+		if i == 4 {
+			enc.Reset()
+		}
+		msg, err := enc.Compress(rec, nil)
+		if err != nil {
+			t.Fatalf("Compress(%d) error = %v", i, err)
+		}
+		msgs = append(msgs, msg)
+	}
+
+	dec := NewDecoderSession()
+	if _, err := dec.Decompress(msgs[0]); err != nil {
+		t.Fatalf("Decompress(0) error = %v", err)
+	}
+	if _, err := dec.Decompress(msgs[1][:len(msgs[1])-1]); err != ErrInvalidMessage {
+		t.Errorf("Decompress() of a short message error = %v, want %v", err, ErrInvalidMessage)
+	}
+	for i := 2; i < 4; i++ {
+		if _, err := dec.Decompress(msgs[i]); err != ErrInvalidMessage {
+			t.Errorf("Decompress(%d) after an error = %v, want %v", i, err, ErrInvalidMessage)
+		}
+	}
+	for i := 4; i < 6; i++ {
+		if got, err := dec.Decompress(msgs[i]); err != nil || !bytes.Equal(got, records[i]) {
+			t.Errorf("Decompress(%d) after the reset = %q, %v, want %q", i, got, err, records[i])
+		}
+	}
+
+	if _, err := dec.Decompress(make([]byte, 4)); err != ErrInvalidMessage {
+		t.Errorf("Decompress() of a truncated header error = %v, want %v", err, ErrInvalidMessage)
+	}
+	if _, err := enc.Compress(make([]byte, MaxMessageSize+1), nil); err != ErrMessageTooLarge {
+		t.Errorf("Compress() of a large message error = %v, want %v", err, ErrMessageTooLarge)
+	}
+	if _, err := NewEncoderSession(MaxLevel + 1); err != ErrInvalidCompressionLevel {
+		t.Errorf("NewEncoderSession(%d) error = %v, want %v", MaxLevel+1, err, ErrInvalidCompressionLevel)
+	}
+}
+
+// TestSessionLargeMessages tests messages larger than the history window,
+// which only the last 64KB of can be referenced
+func TestSessionLargeMessages(t *testing.T) {
+	enc, err := NewEncoderSession(DefaultLevel)
+	if err != nil {
+		t.Fatalf("NewEncoderSession() error = %v", err)
+	}
+	dec := NewDecoderSession()
+	dst := make([]byte, 0)
+
+	for i, size := range []int{100 * 1024, 10, 70 * 1024, 300 * 1024, 0, 50} {
+		msg := generateCompressibleData(size)
+		if i%2 == 1 {
+			msg = generateRandomData(size)
+		}
+		dst, err = enc.Compress(msg, dst)
+		if err != nil {
+			t.Fatalf("Compress(%d) error = %v", i, err)
+		}
+		got, err := dec.Decompress(dst)
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Errorf("Decompress(%d) = %d bytes, %v, want %d matching bytes", i, len(got), err, len(msg))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errInvalidWrite indicates an io.Writer reporting an impossible byte count
+var errInvalidWrite = errors.New("invalid write result")
+
+// shortWriteError reports a write that stopped early without an error from
+// the underlying writer, wrapping io.ErrShortWrite
+func shortWriteError(written, total int) error {
+	return fmt.Errorf("%w: %d of %d bytes written", io.ErrShortWrite, written, total)
+}
+
+// writeFull writes all of p to w. io.Writer implementations must return an
+// error when they write less than asked, but some don't, and carrying on
+// would silently corrupt the stream, so writes that stop early are retried
+// with the rest of p. A write that makes no progress fails with a
+// shortWriteError. The returned count is the number of bytes of p written.
+func writeFull(w io.Writer, p []byte) (int, error) {
+	var written int
+	for written < len(p) {
+		n, err := w.Write(p[written:])
+		if n < 0 || n > len(p)-written {
+			return written, errInvalidWrite
+		}
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n == 0 {
+			return written, shortWriteError(written, len(p))
+		}
+	}
+	return written, nil
+}
+
+// writeMessage writes p to w in a single call, for outputs that may be
+// message oriented, where retrying the rest of p would split the message.
+// A write that stops early fails with a shortWriteError.
+func writeMessage(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err != nil {
+		return err
+	}
+	if n != len(p) {
+		return shortWriteError(n, len(p))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// shortWriter accepts at most max bytes per Write without reporting an
+// error, and nothing at all once limit bytes have been written
+type shortWriter struct {
+	buf   bytes.Buffer
+	max   int
+	limit int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	n := min(min(len(p), w.max), w.limit-w.buf.Len())
+	w.buf.Write(p[:n])
+	return n, nil
+}
+
+// TestWriterShortWrites tests that writes cut short by the underlying writer
+// are completed
+func TestWriterShortWrites(t *testing.T) {
+	data := append(generateCompressibleData(200*1024), generateRandomData(100*1024)...)
+
+	writers := []struct {
+		name string
+		new  func(w io.Writer) io.WriteCloser
+	}{
+		{"Writer", func(w io.Writer) io.WriteCloser {
+			return NewWriterWithOptions(w, WriterOptions{BlockSize: 64 * 1024, BlockChecksum: XXH32})
+		}},
+		{"ParallelWriter", func(w io.Writer) io.WriteCloser {
+			return NewParallelWriterWithOptions(w, ParallelWriterOptions{BlockSize: 64 * 1024})
+		}},
+	}
+
+	for _, tt := range writers {
+		t.Run(tt.name, func(t *testing.T) {
+			sw := &shortWriter{max: 1000, limit: len(data) * 2}
+			w := tt.new(sw)
+			if _, err := w.Write(data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			got, err := io.ReadAll(NewReader(&sw.buf))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("read %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+// TestWriterStalledWrites tests that a writer that stops accepting data
+// without an error is reported rather than silently truncating the stream
+func TestWriterStalledWrites(t *testing.T) {
+	data := generateRandomData(200 * 1024)
+
+	sw := &shortWriter{max: 4096, limit: 100000}
+	w := NewWriterWithOptions(sw, WriterOptions{BlockSize: 64 * 1024})
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("Write() error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if w.Compressed() != uint64(sw.buf.Len()) {
+		t.Errorf("Compressed() = %d, want the %d bytes written", w.Compressed(), sw.buf.Len())
+	}
+
+	pw := NewParallelWriterWithOptions(&shortWriter{max: 4096, limit: 100000}, ParallelWriterOptions{BlockSize: 64 * 1024})
+	_, err = pw.Write(data)
+	if err == nil {
+		err = pw.Close()
+	}
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("ParallelWriter error = %v, want %v", err, io.ErrShortWrite)
+	}
+}
+
+// TestEncoderShortWrite tests that messages are never split across writes
+func TestEncoderShortWrite(t *testing.T) {
+	sw := &shortWriter{max: 100, limit: 1 << 20}
+	err := NewEncoder(sw).Encode(generateRandomData(1000))
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Errorf("Encode() error = %v, want %v", err, io.ErrShortWrite)
+	}
+	if sw.buf.Len() != 100 {
+		t.Errorf("Encode() wrote %d bytes, want a single write of 100", sw.buf.Len())
+	}
+}
@@ -0,0 +1,170 @@
+package compress
+
+import (
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+)
+
+// ErrNegativeSkip indicates a negative count passed to Skip
+var ErrNegativeSkip = errors.New("negative skip")
+
+// Skip discards the next n bytes of decompressed data, much faster than
+// reading them. In frames with independent blocks, stored blocks that lie
+// entirely within the skipped range are passed over without reading them,
+// by seeking if the Reader's source is an io.Seeker, and compressed blocks
+// are only scanned for their decompressed length instead of being decoded; only
+// the block holding the new position is decompressed. Blocks passed over
+// aren't reported to the debug trace or metrics, stored blocks that are
+// seeked over aren't checked against their block checksums, and the content
+// checksum of a frame with blocks passed over isn't checked.
+//
+// Skip returns the number of bytes skipped, which is less than n only if it
+// also returns an error. If the stream ends first, the error is io.EOF.
+// Other errors are sticky, as they are for Read.
+func (r *Reader) Skip(n int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, ErrReaderClosed
+	}
+	if n < 0 {
+		return 0, ErrNegativeSkip
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if r.reachedEof {
+		return 0, io.EOF
+	}
+	if err := r.ensureHeader(); err != nil {
+		return 0, err
+	}
+
+	skipped, err := r.skip(n)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return skipped, err
+}
+
+// skip discards the next n bytes after the header; the caller must hold r.mu
+func (r *Reader) skip(n int64) (int64, error) {
+	var skipped int64
+	for skipped < n {
+		left := n - skipped
+
+		// Consume what is left of the current block, then of a pending hole
+		if avail := int64(r.out.len()); avail > 0 {
+			k := min64(avail, left)
+			r.out.discard(int(k))
+			skipped += k
+			continue
+		}
+		r.out.reset()
+		if r.storedLeft > 0 {
+			k := int(min64(int64(r.storedLeft), left))
+			if err := r.discard(int64(k)); err != nil {
+				return skipped, err
+			}
+			r.contentUnchecked = true
+			r.storedLeft -= k
+			skipped += int64(k)
+			continue
+		}
+		if r.holeLeft > 0 {
+			k := left
+			if r.holeLeft < uint64(left) {
+				k = int64(r.holeLeft)
+			}
+			r.holeLeft -= uint64(k)
+			skipped += k
+			continue
+		}
+
+		word, err := r.nextBlock()
+		if err != nil {
+			if err == io.EOF {
+				r.reachedEof = true
+			}
+			return skipped, err
+		}
+		if word == 0 {
+			continue // A hole was queued
+		}
+
+		size := int64(word & 0x7FFFFFFF)
+		stored := word&0x80000000 != 0
+		// Stored blocks that later ones depend on are read to keep the history
+		if stored && size <= left && r.header.blockIndependence {
+			k := size
+			if r.header.blockChecksum {
+				k += 4
+			}
+			if err := r.discard(k); err != nil {
+				return skipped, err
+			}
+			r.contentUnchecked = true
+			r.blockIndex++
+			skipped += size
+			continue
+		}
+
+		data, err := r.readBlockData(word)
+		if err != nil {
+			return skipped, err
+		}
+
+		// Blocks that depend on earlier ones are decoded to keep the history
+		if !stored && r.header.blockIndependence {
+			length, err := block.DecodedLen(data, r.blocksizeCache)
+			if err != nil {
+				return skipped, err
+			}
+			if int64(length) <= left {
+				if err := r.limitOutput(length, 0); err != nil {
+					return skipped, err
+				}
+				r.contentUnchecked = true
+				r.blockIndex++
+				skipped += int64(length)
+				continue
+			}
+		}
+
+		if err := r.decodeBlock(data, !stored); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+// discard passes over the next n bytes of the source, seeking if it can
+func (r *Reader) discard(n int64) error {
+	if r.seeker != nil {
+		if _, err := r.seeker.Seek(n, io.SeekCurrent); err == nil {
+			r.consumed.Add(uint64(n))
+			return nil
+		}
+		// Sources such as pipes claim to seek but can't
+		r.seeker = nil
+	}
+
+	if _, err := io.CopyN(io.Discard, r.r, n); err != nil {
+		return truncated("block body", err)
+	}
+	return nil
+}
+
+// min64 returns the smaller of a or b
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
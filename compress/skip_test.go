@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// onlyReader hides every method of a reader but Read
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+// TestReaderSkip tests skipping into streams of mixed blocks and frames
+func TestReaderSkip(t *testing.T) {
+	const blockSize = 64 * 1024
+
+	// Compressed and stored blocks, a hole and a second frame
+	first := append(generateCompressibleData(3*blockSize+100), generateRandomData(2*blockSize)...)
+	second := generateCompressibleData(blockSize + 5000)
+	var stream bytes.Buffer
+	stream.Write(compressFrame(t, first, func(w *Writer) {
+		w.blockSize = blockSize
+		w.header.blockChecksum = true
+	}))
+	if err := writeSparseHole(&stream, 70000); err != nil {
+		t.Fatalf("writeSparseHole() error = %v", err)
+	}
+	stream.Write(compressFrame(t, second, func(w *Writer) { w.blockSize = blockSize }))
+
+	data := append(append(bytes.Clone(first), make([]byte, 70000)...), second...)
+	offsets := []int64{0, 1, 1000, blockSize, blockSize + 1, 3*blockSize + 100, 4 * blockSize, int64(len(first)) + 5, int64(len(first)) + 70000, int64(len(data)) - 1, int64(len(data))}
+
+	for _, source := range []struct {
+		name string
+		wrap func(io.Reader) io.Reader
+	}{
+		{"Seeker", func(r io.Reader) io.Reader { return r }},
+		{"Reader", func(r io.Reader) io.Reader { return onlyReader{r} }},
+	} {
+		t.Run(source.name, func(t *testing.T) {
+			for _, off := range offsets {
+				r := NewReaderWithOptions(source.wrap(bytes.NewReader(stream.Bytes())), ReaderOptions{SparseHoles: true})
+				skipped, err := r.Skip(off)
+				if err != nil || skipped != off {
+					t.Fatalf("Skip(%d) = %d, %v, want %d, nil", off, skipped, err, off)
+				}
+				rest, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("ReadAll() after Skip(%d) error = %v", off, err)
+				}
+				if !bytes.Equal(rest, data[off:]) {
+					t.Errorf("after Skip(%d) read %d bytes, want the last %d", off, len(rest), len(data)-int(off))
+				}
+			}
+
+			// Skips can follow reads and other skips
+			r := NewReaderWithOptions(source.wrap(bytes.NewReader(stream.Bytes())), ReaderOptions{SparseHoles: true})
+			p := make([]byte, 10)
+			var pos int64
+			for _, step := range []int64{5000, blockSize, 3 * blockSize, 100000} {
+				if _, err := io.ReadFull(r, p); err != nil {
+					t.Fatalf("Read() error = %v", err)
+				}
+				if !bytes.Equal(p, data[pos:pos+10]) {
+					t.Fatalf("Read() at %d returned the wrong data", pos)
+				}
+				if _, err := r.Skip(step); err != nil {
+					t.Fatalf("Skip() error = %v", err)
+				}
+				pos += 10 + step
+			}
+		})
+	}
+}
+
+// TestReaderSkipPastEnd tests skipping beyond the end of the stream
+func TestReaderSkipPastEnd(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	frame := compressFrame(t, data, nil)
+
+	r := NewReader(bytes.NewReader(frame))
+	skipped, err := r.Skip(int64(len(data)) + 10)
+	if err != io.EOF || skipped != int64(len(data)) {
+		t.Errorf("Skip() = %d, %v, want %d, %v", skipped, err, len(data), io.EOF)
+	}
+	if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read() = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+
+	if _, err := NewReader(bytes.NewReader(frame)).Skip(-1); err != ErrNegativeSkip {
+		t.Errorf("Skip(-1) error = %v, want %v", err, ErrNegativeSkip)
+	}
+}
+
+// TestReaderSkipDoesNotDecode tests that only the block holding the target
+// offset is decoded
+func TestReaderSkipDoesNotDecode(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := generateCompressibleData(10 * blockSize)
+	frame := compressFrame(t, data, func(w *Writer) { w.blockSize = blockSize })
+
+	decoded := make(map[int]bool)
+	r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{
+		DebugTrace: func(ev TraceEvent) { decoded[ev.Block] = true },
+	})
+	if _, err := r.Skip(9*blockSize + 10); err != nil {
+		t.Fatalf("Skip() error = %v", err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(rest, data[9*blockSize+10:]) {
+		t.Errorf("read %d bytes, want %d", len(rest), len(data)-9*blockSize-10)
+	}
+	if len(decoded) != 1 || !decoded[9] {
+		t.Errorf("decoded blocks %v, want only block 9", decoded)
+	}
+}
@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// Format is the kind of LZ4 data Sniff finds at the start of a payload
+type Format int
+
+const (
+	// FormatUnknown is data that isn't LZ4, or a prefix too short to tell
+	FormatUnknown Format = iota
+	// FormatFrame is a stream of LZ4 frames, possibly starting with a
+	// skippable frame, which Reader decodes
+	FormatFrame
+	// FormatLegacy is a stream in the legacy frame format, which GoZ4X
+	// doesn't decode
+	FormatLegacy
+	// FormatBlock is a raw compressed block, which DecompressBlock decodes
+	FormatBlock
+)
+
+// String returns the name of the format
+func (f Format) String() string {
+	switch f {
+	case FormatFrame:
+		return "LZ4 frame"
+	case FormatLegacy:
+		return "legacy LZ4 frame"
+	case FormatBlock:
+		return "raw LZ4 block"
+	default:
+		return "not LZ4"
+	}
+}
+
+// Sniff identifies the kind of LZ4 data prefix starts, so that services
+// accepting payloads that may or may not be compressed can pick a decoder.
+// Frames are told by their magic numbers. Raw blocks have none and are
+// recognized by parsing their sequences: a prefix holding two matches that
+// reach back only into the data before them, or a whole block with a match
+// that parses to its end, is taken for one. Other data rarely passes, but
+// it can, so a decode error still means the payload wasn't a block after
+// all. A block of literals only, as incompressible data becomes, can't be
+// told from other data and is FormatUnknown. A prefix of a few hundred
+// bytes suffices; under 4 bytes is FormatUnknown.
+func Sniff(prefix []byte) Format {
+	if len(prefix) < format.MagicSize {
+		return FormatUnknown
+	}
+	switch magic := binary.LittleEndian.Uint32(prefix); {
+	case magic == format.FrameMagic || format.IsSkippable(magic):
+		return FormatFrame
+	case magic == format.LegacyMagic:
+		return FormatLegacy
+	}
+	if sniffBlock(prefix) {
+		return FormatBlock
+	}
+	return FormatUnknown
+}
+
+// sniffBlock reports whether prefix parses as the start of a compressed
+// block, with every match in reach of the output before it and either two
+// matches, or one and the end of the block
+func sniffBlock(prefix []byte) bool {
+	sr := block.NewSequenceReader(prefix)
+	decoded, matches := 0, 0
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return matches >= 1
+		}
+		if err != nil {
+			// The prefix ends within a sequence
+			return matches >= 2
+		}
+		decoded += len(seq.Literals)
+		if seq.MatchLen == 0 {
+			continue
+		}
+		if seq.Offset == 0 || seq.Offset > decoded {
+			return false
+		}
+		decoded += seq.MatchLen
+		matches++
+	}
+}
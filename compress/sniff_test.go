@@ -0,0 +1,68 @@
+package compress
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// TestSniff tests Sniff on frames, blocks and their prefixes, and on data
+// that isn't LZ4
+func TestSniff(t *testing.T) {
+	type sniffTest struct {
+		name string
+		data []byte
+		want Format
+	}
+	tests := []sniffTest{
+		{"Empty", nil, FormatUnknown},
+		{"Short", []byte{0x04, 0x22}, FormatUnknown},
+		{"Legacy", binary.LittleEndian.AppendUint32(nil, format.LegacyMagic), FormatLegacy},
+		{"Skippable", binary.LittleEndian.AppendUint32(nil, format.StatsTrailerMagic), FormatFrame},
+		{"Gzip", []byte{0x1f, 0x8b, 0x08, 0x00, 0, 0, 0, 0, 0, 0x03}, FormatUnknown},
+		{"Zeros", make([]byte, 1024), FormatUnknown},
+	}
+	for _, kind := range testgen.Corpus {
+		data := kind.Generate(testgen.DefaultSeed, 64<<10)
+		frame, err := EncodeFrame(data, WriterOptions{Level: DefaultLevel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		blk, err := CompressBlock(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests,
+			sniffTest{kind.Name + "/Frame", frame[:16], FormatFrame},
+			sniffTest{kind.Name + "/Raw", data[:512], FormatUnknown})
+
+		// A block of random data is one run of literals, which can't be
+		// told from other data, and adversarial data may start with more
+		// random bytes than the prefix holds
+		if kind.Name != "Random" {
+			tests = append(tests, sniffTest{kind.Name + "/Block", blk, FormatBlock})
+		}
+		if kind.Name != "Random" && kind.Name != "Adversarial" {
+			tests = append(tests, sniffTest{kind.Name + "/BlockPrefix", blk[:512], FormatBlock})
+		}
+	}
+
+	for _, tt := range tests {
+		if got := Sniff(tt.data); got != tt.want {
+			t.Errorf("Sniff(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Random data is hardly ever taken for a block
+	misread := 0
+	for seed := int64(0); seed < 10000; seed++ {
+		if Sniff(testgen.Random(seed, 256)) != FormatUnknown {
+			misread++
+		}
+	}
+	if misread > 0 {
+		t.Errorf("Sniff() took %d of 10000 random inputs for LZ4", misread)
+	}
+}
@@ -0,0 +1,192 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+const (
+	// sparseHoleMagic is the skippable frame magic number recording a hole
+	// of a sparse file: a run of zeros that was never stored
+	sparseHoleMagic = format.SparseHoleMagic
+
+	// sparseHoleSize is the payload size of a hole frame, the hole length
+	sparseHoleSize = 8
+
+	// sparsePageSize is the granularity at which DecompressFile looks for
+	// runs of zeros to leave as holes
+	sparsePageSize = 4 * 1024
+)
+
+// zeroBlock backs the zeros returned for holes and is never written
+var zeroBlock [64 * 1024]byte
+
+// fileRegion is a range of a file holding data, from off up to end
+type fileRegion struct {
+	off, end int64
+}
+
+// maxHoleTotal is the most zeros the hole frames of a stream may stand for,
+// the largest size of a file
+const maxHoleTotal = math.MaxInt64
+
+// addHole queues the n zeros of a hole frame. The holes of a stream count
+// together against the largest file size and the Limits of the Reader.
+func (r *Reader) addHole(n uint64) error {
+	if n > maxHoleTotal-r.holeTotal {
+		return fmt.Errorf("%w: sparse holes of more than %d bytes", ErrInvalidFrame, uint64(maxHoleTotal))
+	}
+	r.holeFrames++
+	r.holeTotal += n
+	if err := r.limits.exceeded(r.holeFrames*(8+sparseHoleSize), r.holeTotal); err != nil {
+		return err
+	}
+	r.holeLeft += n
+	return nil
+}
+
+// readHole returns the next run of zeros of a pending sparse hole
+func (r *Reader) readHole() {
+	n := uint64(len(zeroBlock))
+	if r.holeLeft < n {
+		n = r.holeLeft
+	}
+	r.holeLeft -= n
+	r.out.set(zeroBlock[:n])
+}
+
+// compressSparse compresses the data regions of f between start and end as
+// one frame each, recording the holes between them as hole frames
+func compressSparse(dst io.Writer, f *os.File, start, end int64, regions []fileRegion, options WriterOptions) (int64, error) {
+	// Readers need at least one frame, even for a file that is all hole
+	if len(regions) == 0 {
+		regions = []fileRegion{{start, start}}
+	}
+
+	pos := start
+	for _, region := range regions {
+		if region.off > pos {
+			if err := writeSparseHole(dst, region.off-pos); err != nil {
+				return pos - start, err
+			}
+		}
+
+		w := NewWriterWithOptions(dst, options)
+		if err := w.SetContentSize(uint64(region.end - region.off)); err != nil {
+			return pos - start, err
+		}
+		n, err := io.Copy(w, io.NewSectionReader(f, region.off, region.end-region.off))
+		if err != nil {
+			return region.off - start + n, err
+		}
+		if err := w.Close(); err != nil {
+			return region.off - start + n, err
+		}
+		pos = region.end
+	}
+
+	if end > pos {
+		if err := writeSparseHole(dst, end-pos); err != nil {
+			return pos - start, err
+		}
+	}
+
+	// Leave f where reading it to the end would have
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return end - start, err
+	}
+	return end - start, nil
+}
+
+// writeSparseHole writes a hole frame for n bytes of zeros
+func writeSparseHole(w io.Writer, n int64) error {
+	var frame [8 + sparseHoleSize]byte
+	binary.LittleEndian.PutUint32(frame[0:4], sparseHoleMagic)
+	binary.LittleEndian.PutUint32(frame[4:8], sparseHoleSize)
+	binary.LittleEndian.PutUint64(frame[8:], uint64(n))
+	_, err := writeFull(w, frame[:])
+	return err
+}
+
+// DecompressFile decompresses src into dst, leaving runs of zeros as holes
+// so that sparse files compressed with CompressFile come back sparse. dst is
+// written from its current offset and truncated after the decompressed data.
+// It returns the number of decompressed bytes.
+func DecompressFile(dst *os.File, src io.Reader) (int64, error) {
+	return DecompressFileWithOptions(dst, src, ReaderOptions{})
+}
+
+// DecompressFileWithOptions is DecompressFile reading src with options,
+// such as Limits to bound the size of an untrusted file. SparseHoles is
+// always set.
+func DecompressFileWithOptions(dst *os.File, src io.Reader, options ReaderOptions) (int64, error) {
+	options.SparseHoles = true
+	start, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	sw := &sparseWriter{f: dst}
+	zr := NewReaderWithOptions(src, options)
+	n, err := io.CopyBuffer(sw, zr, make([]byte, len(zeroBlock)))
+	if err != nil {
+		return n, err
+	}
+
+	// Zeros skipped at the end only become part of the file through Truncate
+	if err := dst.Truncate(start + n); err != nil {
+		return n, err
+	}
+	_, err = dst.Seek(start+n, io.SeekStart)
+	return n, err
+}
+
+// sparseWriter writes to a file, seeking over pages of zeros instead of
+// writing them
+type sparseWriter struct {
+	f    *os.File
+	skip int64
+}
+
+func (w *sparseWriter) Write(p []byte) (int, error) {
+	for off := 0; off < len(p); {
+		end := min(off+sparsePageSize, len(p))
+		if isZeros(p[off:end]) {
+			w.skip += int64(end - off)
+			off = end
+			continue
+		}
+
+		// Extend the run of data pages so they're written together
+		for end < len(p) {
+			next := min(end+sparsePageSize, len(p))
+			if isZeros(p[end:next]) {
+				break
+			}
+			end = next
+		}
+
+		if w.skip > 0 {
+			if _, err := w.f.Seek(w.skip, io.SeekCurrent); err != nil {
+				return off, err
+			}
+			w.skip = 0
+		}
+		if n, err := w.f.Write(p[off:end]); err != nil {
+			return off + n, err
+		}
+		off = end
+	}
+	return len(p), nil
+}
+
+// isZeros reports whether p holds only zero bytes
+func isZeros(p []byte) bool {
+	return bytes.Equal(p, zeroBlock[:len(p)])
+}
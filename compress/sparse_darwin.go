@@ -0,0 +1,7 @@
+package compress
+
+// lseek whence values for finding data and holes
+const (
+	seekHole = 3
+	seekData = 4
+)
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package compress
+
+import "os"
+
+// dataRegions reports false as holes can't be found on this platform, so
+// files are always read as a whole
+func dataRegions(f *os.File, start, end int64) ([]fileRegion, bool) {
+	return nil, false
+}
@@ -0,0 +1,10 @@
+//go:build linux || freebsd
+// +build linux freebsd
+
+package compress
+
+// lseek whence values for finding data and holes
+const (
+	seekData = 3
+	seekHole = 4
+)
@@ -0,0 +1,277 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// holeFrame returns a hole frame for n bytes of zeros
+func holeFrame(t *testing.T, n int64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := writeSparseHole(&buf, n); err != nil {
+		t.Fatalf("writeSparseHole() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestReaderSparseHoles tests that hole frames are read back as zeros
+func TestReaderSparseHoles(t *testing.T) {
+	a := generateCompressibleData(10 * 1024)
+	b := generateRandomData(3 * 1024)
+	zeros := func(n int) []byte { return make([]byte, n) }
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	tests := []struct {
+		name   string
+		stream []byte
+		want   []byte
+	}{
+		{
+			name:   "Middle",
+			stream: join(compressFrame(t, a, nil), holeFrame(t, 5000), compressFrame(t, b, nil)),
+			want:   join(a, zeros(5000), b),
+		},
+		{
+			name:   "Leading",
+			stream: join(holeFrame(t, 4096), compressFrame(t, a, nil)),
+			want:   join(zeros(4096), a),
+		},
+		{
+			name:   "Trailing",
+			stream: join(compressFrame(t, a, nil), holeFrame(t, 200*1024)),
+			want:   join(a, zeros(200*1024)),
+		},
+		{
+			name:   "Adjacent",
+			stream: join(compressFrame(t, a, nil), holeFrame(t, 100), holeFrame(t, 70*1024), compressFrame(t, b, nil)),
+			want:   join(a, zeros(100+70*1024), b),
+		},
+		{
+			name:   "Only Hole",
+			stream: join(compressFrame(t, nil, nil), holeFrame(t, 1000)),
+			want:   zeros(1000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(NewReaderWithOptions(bytes.NewReader(tt.stream), ReaderOptions{SparseHoles: true}))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("decoded %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+// TestReaderSparseHoleTruncated tests a hole frame cut short
+func TestReaderSparseHoleTruncated(t *testing.T) {
+	stream := append(compressFrame(t, []byte("data"), nil), holeFrame(t, 100)[:12]...)
+	zr := NewReaderWithOptions(bytes.NewReader(stream), ReaderOptions{SparseHoles: true})
+	if _, err := io.ReadAll(zr); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAll() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// TestReaderSparseHolesUntrusted tests that a default Reader skips hole
+// frames, and that holes past the largest file size fail
+func TestReaderSparseHolesUntrusted(t *testing.T) {
+	data := []byte("data")
+	stream := bytes.Join([][]byte{holeFrame(t, 1<<62), compressFrame(t, data, nil), holeFrame(t, 1<<40)}, nil)
+	if got, err := io.ReadAll(NewReader(bytes.NewReader(stream))); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() = %d bytes, %v, want %q", len(got), err, data)
+	}
+
+	stream = bytes.Join([][]byte{holeFrame(t, 1<<62), holeFrame(t, 1<<62), compressFrame(t, data, nil)}, nil)
+	zr := NewReaderWithOptions(bytes.NewReader(stream), ReaderOptions{SparseHoles: true})
+	if _, err := zr.Read(make([]byte, 10)); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("Read() error = %v, want %v", err, ErrInvalidFrame)
+	}
+}
+
+// writeSparseFile creates a file of the given size holding data at each offset
+// and nothing elsewhere, so file systems that support it leave holes
+func writeSparseFile(t *testing.T, size int64, data map[int64][]byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sparse")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(size); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	for off, p := range data {
+		if _, err := f.WriteAt(p, off); err != nil {
+			t.Fatalf("WriteAt() error = %v", err)
+		}
+	}
+	return path
+}
+
+// TestCompressFileSparse tests compressing and restoring sparse files
+func TestCompressFileSparse(t *testing.T) {
+	const mb = 1024 * 1024
+
+	tests := []struct {
+		name string
+		size int64
+		data map[int64][]byte
+	}{
+		{
+			name: "Holes Between Data",
+			size: 4 * mb,
+			data: map[int64][]byte{
+				0:      generateCompressibleData(64 * 1024),
+				2 * mb: generateRandomData(100 * 1024),
+			},
+		},
+		{
+			name: "Leading And Trailing Holes",
+			size: 3 * mb,
+			data: map[int64][]byte{mb: generateCompressibleData(128 * 1024)},
+		},
+		{
+			name: "All Hole",
+			size: 2 * mb,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSparseFile(t, tt.size, tt.data)
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+
+			src, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer src.Close()
+
+			var buf bytes.Buffer
+			n, err := CompressFile(&buf, src, WriterOptions{})
+			if err != nil {
+				t.Fatalf("CompressFile() error = %v", err)
+			}
+			if n != tt.size {
+				t.Errorf("CompressFile() = %d, want %d", n, tt.size)
+			}
+			if pos, _ := src.Seek(0, io.SeekCurrent); pos != tt.size {
+				t.Errorf("source offset = %d, want %d", pos, tt.size)
+			}
+			// Holes are recorded rather than compressed where they can be found
+			_, sparse := dataRegions(src, 0, tt.size)
+			if sparse && int64(buf.Len()) > tt.size/16 {
+				t.Errorf("compressed to %d bytes, want holes left out", buf.Len())
+			}
+
+			got, err := io.ReadAll(NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{SparseHoles: true}))
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("decoded %d bytes, want %d", len(got), len(want))
+			}
+
+			dst, err := os.Create(filepath.Join(t.TempDir(), "restored"))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer dst.Close()
+
+			n, err = DecompressFile(dst, bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("DecompressFile() error = %v", err)
+			}
+			if n != tt.size {
+				t.Errorf("DecompressFile() = %d, want %d", n, tt.size)
+			}
+			restored, err := os.ReadFile(dst.Name())
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(restored, want) {
+				t.Errorf("restored %d bytes, want %d", len(restored), len(want))
+			}
+			if _, restoredSparse := dataRegions(dst, 0, tt.size); sparse && !restoredSparse {
+				t.Errorf("restored file has no holes")
+			}
+		})
+	}
+}
+
+// TestCompressFileSparseOffset tests that compression starts at the current
+// offset of the source file
+func TestCompressFileSparseOffset(t *testing.T) {
+	data := generateCompressibleData(32 * 1024)
+	path := writeSparseFile(t, 2*1024*1024, map[int64][]byte{1024 * 1024: data})
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer src.Close()
+	if _, err := src.Seek(512*1024, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := CompressFile(&buf, src, WriterOptions{}); err != nil {
+		t.Fatalf("CompressFile() error = %v", err)
+	}
+	got, err := io.ReadAll(NewReaderWithOptions(&buf, ReaderOptions{SparseHoles: true}))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, want[512*1024:]) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(want)-512*1024)
+	}
+}
+
+// TestDecompressFileDense tests restoring an ordinary stream that holds
+// runs of zeros between data
+func TestDecompressFileDense(t *testing.T) {
+	data := append(generateRandomData(5000), make([]byte, 300*1024)...)
+	data = append(data, generateCompressibleData(20*1024)...)
+	data = append(data, make([]byte, 10*1024)...)
+	frame := compressFrame(t, data, nil)
+
+	dst, err := os.Create(filepath.Join(t.TempDir(), "restored"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer dst.Close()
+
+	n, err := DecompressFile(dst, bytes.NewReader(frame))
+	if err != nil {
+		t.Fatalf("DecompressFile() error = %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("DecompressFile() = %d, want %d", n, len(data))
+	}
+	got, err := os.ReadFile(dst.Name())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("restored %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,53 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package compress
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// dataRegions finds the data regions of f between start and end using
+// SEEK_DATA and SEEK_HOLE. It reports false if the range has no holes or the
+// file system can't tell, in which case f should be read as a whole.
+func dataRegions(f *os.File, start, end int64) ([]fileRegion, bool) {
+	if start >= end {
+		return nil, false
+	}
+	// Probing moves the offset, which readers of f still expect at start
+	defer f.Seek(start, io.SeekStart)
+
+	var regions []fileRegion
+	for off := start; off < end; {
+		data, err := f.Seek(off, seekData)
+		if err != nil {
+			// ENXIO means there is no data past off, only a trailing hole
+			if errors.Is(err, syscall.ENXIO) {
+				break
+			}
+			return nil, false
+		}
+		if data >= end {
+			break
+		}
+
+		hole, err := f.Seek(data, seekHole)
+		if err != nil {
+			return nil, false
+		}
+		if hole > end {
+			hole = end
+		}
+		regions = append(regions, fileRegion{data, hole})
+		off = hole
+	}
+
+	// File systems without hole support report the whole file as data
+	if len(regions) == 1 && regions[0] == (fileRegion{start, end}) {
+		return nil, false
+	}
+	return regions, true
+}
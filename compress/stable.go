@@ -0,0 +1,155 @@
+package compress
+
+// The stable encoder is a frozen copy of the v1 HC encoder. Its output for a
+// given input and level is part of the public contract and must never change,
+// so that systems deduplicating or content-addressing compressed bytes keep
+// working across library upgrades. Improvements belong in CompressBlockLevel
+// and CompressBlockV2Level, or in new opt-in encoders; never edit this file in
+// a way that changes its output. TestCompressBlockStableGolden pins the bytes.
+
+// CompressBlockStable compresses src with the frozen v1 encoder.
+// Unlike CompressBlockLevel, whose output may improve between releases, the
+// output of CompressBlockStable is guaranteed to be byte-identical for the
+// same input and level in every future version of GoZ4X.
+// If dst is nil or too small, a new buffer will be allocated.
+func CompressBlockStable(src []byte, dst []byte, level CompressionLevel) ([]byte, error) {
+	if len(src) < MinBlockSize || len(src) > MaxBlockSize {
+		return nil, ErrInvalidBlockSize
+	}
+	if level < 0 || level > MaxLevel {
+		return nil, ErrInvalidCompressionLevel
+	}
+
+	m := newStableMatcher(src, level)
+	inputLen := len(src)
+
+	worstCaseSize := compressBound(inputLen)
+	if len(dst) < worstCaseSize {
+		dst = make([]byte, 0, worstCaseSize)
+	}
+	dst = dst[:0]
+
+	pos := 0
+	lastLiteral := 0
+	for pos < inputLen-MinMatch && pos <= inputLen-mfLimit {
+		offset, matchLen := m.findBestMatch(pos)
+		matchLen = min(matchLen, inputLen-lastLiterals-pos)
+
+		if matchLen < MinMatch {
+			pos++
+			continue
+		}
+
+		dst = appendSequence(dst, src[lastLiteral:pos], offset, matchLen)
+		pos += matchLen
+		lastLiteral = pos
+	}
+
+	return appendSequence(dst, src[lastLiteral:], 0, 0), nil
+}
+
+// stableMatcher is the frozen hash chain match finder of the v1 encoder
+type stableMatcher struct {
+	buf         []byte
+	hashTable   []int
+	chainTable  []int
+	maxAttempts int
+	windowSize  int
+	hashLog     int
+	useHash5    bool
+}
+
+// newStableMatcher creates the match finder for the given level
+func newStableMatcher(src []byte, level CompressionLevel) *stableMatcher {
+	m := &stableMatcher{
+		buf:        src,
+		hashLog:    16,
+		chainTable: make([]int, len(src)),
+	}
+
+	switch {
+	case level <= 3:
+		m.maxAttempts, m.windowSize = 4, 16*1024
+	case level <= 6:
+		m.maxAttempts, m.windowSize = 8, 32*1024
+	case level <= 9:
+		m.maxAttempts, m.windowSize, m.useHash5 = 16, 64*1024, true
+	default:
+		m.maxAttempts, m.windowSize, m.useHash5 = 32, 65535, true
+		m.hashLog = 17
+	}
+
+	m.hashTable = make([]int, 1<<m.hashLog)
+	return m
+}
+
+// hash returns the hash bucket of the bytes at pos, or 0 near the end of input
+func (m *stableMatcher) hash(pos int) uint32 {
+	n := 4
+	if m.useHash5 {
+		n = 5
+	}
+	if pos+n > len(m.buf) {
+		return 0
+	}
+
+	v := uint32(m.buf[pos]) | uint32(m.buf[pos+1])<<8 | uint32(m.buf[pos+2])<<16 | uint32(m.buf[pos+3])<<24
+	if m.useHash5 {
+		v = v*2654435761 + uint32(m.buf[pos+4])
+	}
+	return ((v * 2654435761) >> (32 - m.hashLog)) & uint32(1<<m.hashLog-1)
+}
+
+// insert records pos in the hash chains
+func (m *stableMatcher) insert(pos int) {
+	h := m.hash(pos)
+	if h == 0 {
+		return
+	}
+	m.chainTable[pos] = m.hashTable[h]
+	m.hashTable[h] = pos
+}
+
+// findBestMatch returns the longest match for pos and records pos in the chains
+func (m *stableMatcher) findBestMatch(pos int) (offset, length int) {
+	buf := m.buf
+	current := m.hashTable[m.hash(pos)]
+	limit := pos - m.windowSize
+
+	if current <= 0 || current <= limit {
+		m.insert(pos)
+		return 0, 0
+	}
+
+	bestLength := 0
+	bestOffset := 0
+	maxLength := len(buf) - pos
+
+	for attempts := m.maxAttempts; current > limit && attempts > 0; attempts-- {
+		length := 0
+		if buf[current] == buf[pos] && buf[current+1] == buf[pos+1] &&
+			buf[current+2] == buf[pos+2] && buf[current+3] == buf[pos+3] {
+			length = 4
+			for length < maxLength && buf[pos+length] == buf[current+length] {
+				length++
+			}
+		}
+
+		if length > bestLength {
+			bestLength = length
+			bestOffset = pos - current
+			if length >= MaxMatch || (m.useHash5 && length >= 64) {
+				break
+			}
+		}
+
+		current = m.chainTable[current]
+	}
+
+	m.insert(pos)
+
+	if bestLength >= MinMatch {
+		return bestOffset, bestLength
+	}
+	return 0, 0
+}
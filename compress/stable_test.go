@@ -0,0 +1,64 @@
+package compress
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// TestCompressBlockStableGolden pins the output of the frozen encoder.
+// These hashes must never change; a failure here means CompressBlockStable
+// no longer produces the bytes earlier releases produced.
+func TestCompressBlockStableGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  CompressionLevel
+		size   int
+		sha256 string
+	}{
+		{"lorem", 1, 1533, "a7fb63e564ff86d10ae88de0e9d971f5eab8470e72dbb7232cd1427fe70ab77e"},
+		{"lorem", 6, 1464, "2d3b1d0c288a806f6d9b6f63c61db5617760b0bea49e457c9d583476e282ac5d"},
+		{"lorem", 9, 1402, "bac60d8b0f50b57a376bebab4cbccbf5a5f9f05971fe394a2108e78a07a180e2"},
+		{"lorem", 12, 1397, "79f786a640849e375932ba17ce2c30b46303a1e941d1a40bba5b9f7cea31016a"},
+		{"zeros", 6, 100394, "e11d03dbacd262a13ba1a2adf0ba579692bfda470bfbb7796ef210a9fad90221"},
+		{"pattern", 6, 331, "33f40fbb888c060cd125d13373e89cd0978aaa58ad6a8e19bc81879606c8f926"},
+		{"text", 1, 23097, "2e0d2fc10fc747c60c959a44e27372d150f6584b936ff424b252d60fca1d627b"},
+		{"text", 6, 20733, "5b89ee32c4f6b98ea2747a38e55fffb8a6272760b9532a94bfc1544d0ac62094"},
+		{"text", 9, 17683, "f2e50038695239e30478b0fc85bd52f187797e5673ca1b55b454cd718899d3a4"},
+		{"text", 12, 16543, "41c0a07464ccd9001cd39fa4826a76add99a854f6b869a38ded944fa5f5f1a0c"},
+	}
+
+	for _, tt := range tests {
+		input := readGolden(t, tt.name+".raw")
+		if tt.name == "text" {
+			input = input[:64*1024]
+		}
+
+		block, err := CompressBlockStable(input, nil, tt.level)
+		if err != nil {
+			t.Fatalf("CompressBlockStable(%s, %d) error = %v", tt.name, tt.level, err)
+		}
+
+		sum := sha256.Sum256(block)
+		if len(block) != tt.size || hex.EncodeToString(sum[:]) != tt.sha256 {
+			t.Errorf("CompressBlockStable(%s, %d) = %d bytes with sha256 %x, want %d bytes with sha256 %s",
+				tt.name, tt.level, len(block), sum, tt.size, tt.sha256)
+		}
+
+		got, err := DecompressBlock(block, nil, len(input))
+		if err != nil || !bytes.Equal(got, input) {
+			t.Errorf("CompressBlockStable(%s, %d) output doesn't round trip: %v", tt.name, tt.level, err)
+		}
+	}
+}
+
+// TestCompressBlockStableErrors tests input validation
+func TestCompressBlockStableErrors(t *testing.T) {
+	if _, err := CompressBlockStable(make([]byte, MinBlockSize-1), nil, DefaultLevel); err != ErrInvalidBlockSize {
+		t.Errorf("CompressBlockStable() with short input: error = %v, want %v", err, ErrInvalidBlockSize)
+	}
+	if _, err := CompressBlockStable(make([]byte, 64), nil, MaxLevel+1); err != ErrInvalidCompressionLevel {
+		t.Errorf("CompressBlockStable() with invalid level: error = %v, want %v", err, ErrInvalidCompressionLevel)
+	}
+}
@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/format"
+)
+
+const (
+	// statsTrailerMagic is the skippable frame magic number of the stats
+	// trailer written after a frame when WriterOptions.StatsTrailer is set
+	statsTrailerMagic = format.StatsTrailerMagic
+
+	// statsTrailerSize is the size of the stats trailer data: compressed
+	// and uncompressed totals, block count and XXH64 of the content
+	statsTrailerSize = 28
+)
+
+// ErrNoFrameStats indicates a stream that doesn't end with a stats trailer
+var ErrNoFrameStats = errors.New("stream has no stats trailer")
+
+// FrameStats summarizes a frame, as recorded in the stats trailer that
+// follows it
+type FrameStats struct {
+	// Compressed is the size of the frame as stored, including its header,
+	// end marker, content checksum and chunk index but not the trailer
+	Compressed uint64
+	// Uncompressed is the size of the frame's data
+	Uncompressed uint64
+	// Blocks is the number of blocks holding data
+	Blocks int
+	// Hash is the XXH64 hash of the frame's data
+	Hash uint64
+}
+
+// Ratio returns the uncompressed size divided by the compressed size
+func (s FrameStats) Ratio() float64 {
+	if s.Compressed == 0 {
+		return 0
+	}
+	return float64(s.Uncompressed) / float64(s.Compressed)
+}
+
+// writeStatsTrailer writes the stats trailer of the frame just finished
+func (z *Writer) writeStatsTrailer() error {
+	var out [8 + statsTrailerSize]byte
+	binary.LittleEndian.PutUint32(out[0:4], statsTrailerMagic)
+	binary.LittleEndian.PutUint32(out[4:8], statsTrailerSize)
+	binary.LittleEndian.PutUint64(out[8:16], z.bytesOut.Load())
+	binary.LittleEndian.PutUint64(out[16:24], z.bytesIn.Load())
+	binary.LittleEndian.PutUint32(out[24:28], uint32(z.blockIndex))
+	binary.LittleEndian.PutUint64(out[28:36], z.hash.Sum64())
+	_, err := z.writeOut(out[:])
+	return err
+}
+
+// ReadFrameStats reads the stats trailer at the end of rs, so that the
+// totals of a stream can be shown without decompressing or even scanning
+// it. For streams of several frames it describes the last one. It returns
+// ErrNoFrameStats if the stream doesn't end with a trailer.
+func ReadFrameStats(rs io.ReadSeeker) (FrameStats, error) {
+	var buf [8 + statsTrailerSize]byte
+	if _, err := rs.Seek(-int64(len(buf)), io.SeekEnd); err != nil {
+		return FrameStats{}, ErrNoFrameStats
+	}
+	if _, err := io.ReadFull(rs, buf[:]); err != nil {
+		return FrameStats{}, unexpectedEOF(err)
+	}
+	if binary.LittleEndian.Uint32(buf[0:4]) != statsTrailerMagic ||
+		binary.LittleEndian.Uint32(buf[4:8]) != statsTrailerSize {
+		return FrameStats{}, ErrNoFrameStats
+	}
+
+	return FrameStats{
+		Compressed:   binary.LittleEndian.Uint64(buf[8:16]),
+		Uncompressed: binary.LittleEndian.Uint64(buf[16:24]),
+		Blocks:       int(binary.LittleEndian.Uint32(buf[24:28])),
+		Hash:         binary.LittleEndian.Uint64(buf[28:36]),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/xxh64"
+)
+
+// TestStatsTrailer tests writing and reading back the stats trailer
+func TestStatsTrailer(t *testing.T) {
+	const blockSize = 64 * 1024
+
+	tests := []struct {
+		name   string
+		data   []byte
+		opts   WriterOptions
+		blocks int
+	}{
+		{"Empty", nil, WriterOptions{}, 0},
+		{"Small", []byte("hello, stats"), WriterOptions{}, 1},
+		{"Blocks", generateCompressibleData(5*blockSize + 10), WriterOptions{BlockSize: blockSize}, 6},
+		{"Checksums", generateRandomData(2 * blockSize), WriterOptions{BlockSize: blockSize, BlockChecksum: XXH32, ContentChecksum: true}, 2},
+		{"Chunking", generateCompressibleData(300 * 1024), WriterOptions{Chunking: &ChunkingOptions{}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.StatsTrailer = true
+			var buf bytes.Buffer
+			w := NewWriterWithOptions(&buf, tt.opts)
+			if _, err := w.Write(tt.data); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			stats, err := ReadFrameStats(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadFrameStats() error = %v", err)
+			}
+			want := FrameStats{
+				Compressed:   uint64(buf.Len() - 8 - statsTrailerSize),
+				Uncompressed: uint64(len(tt.data)),
+				Blocks:       tt.blocks,
+				Hash:         xxh64.Checksum(tt.data),
+			}
+			if tt.opts.Chunking != nil {
+				want.Blocks = len(w.Chunks())
+			}
+			if stats != want {
+				t.Errorf("ReadFrameStats() = %+v, want %+v", stats, want)
+			}
+
+			// Readers pass over the trailer
+			got, err := io.ReadAll(NewReader(bytes.NewReader(buf.Bytes())))
+			if err != nil || !bytes.Equal(got, tt.data) {
+				t.Errorf("ReadAll() = %d bytes, %v, want %d bytes", len(got), err, len(tt.data))
+			}
+		})
+	}
+}
+
+// TestReadFrameStatsMissing tests streams without a stats trailer
+func TestReadFrameStatsMissing(t *testing.T) {
+	frame := compressFrame(t, generateCompressibleData(1000), nil)
+	for _, stream := range [][]byte{nil, frame[:10], frame} {
+		if _, err := ReadFrameStats(bytes.NewReader(stream)); err != ErrNoFrameStats {
+			t.Errorf("ReadFrameStats() of %d bytes error = %v, want %v", len(stream), err, ErrNoFrameStats)
+		}
+	}
+}
+
+// TestStatsTrailerAppend tests that frames followed by a trailer aren't
+// extended in place, which would make their stats stale
+func TestStatsTrailerAppend(t *testing.T) {
+	first := generateCompressibleData(10000)
+	f := writeFrameFile(t, first, func(w *Writer) { w.stats = true })
+
+	second := generateCompressibleData(20000)
+	if got := appendAndRead(t, f, second); !bytes.Equal(got, append(first, second...)) {
+		t.Errorf("read %d bytes, want %d", len(got), len(first)+len(second))
+	}
+	if _, err := ReadFrameStats(f); err != ErrNoFrameStats {
+		t.Errorf("ReadFrameStats() error = %v, want %v", err, ErrNoFrameStats)
+	}
+}
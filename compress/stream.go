@@ -3,8 +3,15 @@ package compress
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+	"github.com/harriteja/GoZ4X/internal/xxh64"
 )
 
 const (
@@ -12,17 +19,21 @@ const (
 	DefaultChunkSize = 256 * 1024 // 256KB
 
 	// LZ4 frame constants
-	frameMagic = 0x184D2204
+	frameMagic = format.FrameMagic
 
 	// Maximum size for frame header
 	maxHeaderSize = 20
 
+	// Skippable frames use magic numbers 0x184D2A50 to 0x184D2A5F
+	skippableMagic     = format.SkippableMagic
+	skippableMagicMask = format.SkippableMagicMask
+
 	// Frame descriptor flags
-	flagBlockIndependence = 0x20
-	flagBlockChecksum     = 0x10
-	flagContentSize       = 0x08
-	flagContentChecksum   = 0x04
-	flagDictID            = 0x01
+	flagBlockIndependence = format.FlagBlockIndependence
+	flagBlockChecksum     = format.FlagBlockChecksum
+	flagContentSize       = format.FlagContentSize
+	flagContentChecksum   = format.FlagContentChecksum
+	flagDictID            = format.FlagDictID
 
 	// Maximum block size (corresponds to blockSizeCode 7)
 	maxBlockSize = 4 * 1024 * 1024
@@ -31,20 +42,154 @@ const (
 var (
 	// ErrInvalidFrame indicates an invalid frame format
 	ErrInvalidFrame = errors.New("invalid LZ4 frame format")
+	// ErrInvalidMagic indicates data where a frame magic number was expected
+	ErrInvalidMagic = errors.New("invalid LZ4 frame magic number")
 )
 
-// Reader is an io.Reader that decompresses from an LZ4 stream
+// Reader is an io.Reader that decompresses from an LZ4 stream.
+//
+// Like the readers of compress/flate and compress/gzip, a Reader moves
+// through these states:
+//
+//   - header: nothing has been read yet. The first Read, Skip or
+//     ReadHeader reads the frame header; a stream ending before it gives
+//     io.EOF, and a bad or cut-off header an error that every later call
+//     returns as well.
+//   - blocks: Read returns the data of one block after another, keeping the
+//     rest of a block for the next call when p is smaller than it, so any
+//     pattern of Read sizes returns the same bytes. At an end marker the
+//     Reader moves on to the next concatenated frame, if any.
+//   - EOF: the stream ended cleanly after a frame. Read returns 0, io.EOF
+//     from then on, and never io.EOF together with data.
+//   - failed: a block, checksum or the source failed, or the stream ended
+//     inside a frame, which gives an error wrapping io.ErrUnexpectedEOF.
+//     With ChecksumDeferred, a checksum mismatch doesn't fail the stream
+//     but is kept for Err and Close.
+//     Data decoded before the failure is returned first, and the error is
+//     sticky: every later Read and Skip returns it again.
+//   - closed: after Close, Read and Skip return ErrReaderClosed.
 type Reader struct {
 	r              io.Reader
-	buf            []byte
 	current        []byte
 	header         frameHeader
 	readHeader     bool
+	headerErr      error
 	reachedEof     bool
 	blocksizeCache int
 	mu             sync.Mutex
-	decompressed   []byte
-	bufPos         int
+	// out holds the decoded data of the current block not yet returned
+	out blockBuffer
+	// storedLeft is the number of bytes of a stored block still to be
+	// read from the source, when it is streamed straight into Read's buffer
+	storedLeft int
+	consumed   atomic.Uint64
+	produced   atomic.Uint64
+	metrics    MetricsRecorder
+	trace      TraceFunc
+	blockIndex int
+	checksum   Checksummer
+	holeLeft   uint64
+	holeEnded  bool
+	// sparse reads hole frames as zeros, set by the SparseHoles option.
+	// holeFrames and holeTotal count the hole frames read so far and the
+	// zeros they stand for.
+	sparse      bool
+	holeFrames  uint64
+	holeTotal   uint64
+	dicts       DictionaryStore
+	resolveDict DictionaryResolver
+	// limits bounds every frame, which has read the compressed bytes past
+	// frameStart and produced frameOut bytes in frameBlocks blocks so far
+	limits      DecompressionLimits
+	frameStart  uint64
+	frameOut    uint64
+	frameBlocks int
+	dict        []byte
+	dictID      uint32
+	// window holds the last 64KB decoded in a frame with dependent blocks,
+	// which its next block may reach back into, and prefix that data after
+	// the end of the dictionary while the frame is shorter than 64KB
+	window []byte
+	prefix []byte
+	// seeker is the source, if it can seek, letting Skip pass over blocks
+	seeker io.Seeker
+	// word, blockBuf and blockOut are reused by every block read, and
+	// fields by every frame header and trailer, so that reading them
+	// doesn't allocate
+	word     [4]byte
+	fields   [15]byte
+	blockBuf []byte
+	blockOut []byte
+	// buffers, if set, supplies blockBuf and blockOut and gets them back
+	// on Close, after which closed is set
+	buffers        BufferProvider
+	blockBufPooled bool
+	blockOutPooled bool
+	// verify is the checksum mode. content is the running checksum of the
+	// frame's data, unless contentUnchecked is set because Skip passed over
+	// some of it, and checksumErr the first mismatch read past.
+	verify           ChecksumMode
+	content          xxh32.Digest
+	contentUnchecked bool
+	checksumErr      error
+	closed           bool
+	// headerMode sets which frame headers are accepted
+	headerMode HeaderMode
+	// err is the first error of a block after the header, returned by
+	// every later Read and Skip
+	err error
+	// closer is the source, closed by Close, with the CloseUnderlying
+	// option set in closeUnderlying
+	closer          io.Closer
+	closeUnderlying bool
+	// validate checks the sequences of every block, set by the
+	// ValidateSequences option
+	validate bool
+	// firstFrame ends the stream after the first frame, leaving what
+	// follows unread
+	firstFrame bool
+}
+
+// blockBuffer holds the decoded data of one block as it is handed out by
+// Read and Skip: buf is the whole block and off the position of the first
+// byte not yet returned. set always starts at the beginning of a new block,
+// so the position of one block can't carry over to the next.
+type blockBuffer struct {
+	buf []byte
+	off int
+}
+
+// set replaces the buffer with the data of a new block
+func (b *blockBuffer) set(data []byte) {
+	b.buf, b.off = data, 0
+}
+
+// reset empties the buffer, dropping its reference to the block
+func (b *blockBuffer) reset() {
+	b.buf, b.off = nil, 0
+}
+
+// len returns the number of bytes not yet returned
+func (b *blockBuffer) len() int {
+	return len(b.buf) - b.off
+}
+
+// unread returns the bytes not yet returned
+func (b *blockBuffer) unread() []byte {
+	return b.buf[b.off:]
+}
+
+// read copies as much of the unread bytes as fit into p and returns the
+// number copied
+func (b *blockBuffer) read(p []byte) int {
+	n := copy(p, b.buf[b.off:])
+	b.off += n
+	return n
+}
+
+// discard passes over the next n unread bytes, which must be at most len()
+func (b *blockBuffer) discard(n int) {
+	b.off += n
 }
 
 // Writer is an io.WriteCloser that compresses to an LZ4 stream
@@ -64,6 +209,52 @@ type Writer struct {
 	useV2       bool
 	buffer      []byte
 	bufferOff   int
+	bytesIn     atomic.Uint64
+	bytesOut    atomic.Uint64
+	metrics     MetricsRecorder
+	trace       TraceFunc
+	blockIndex  int
+	// autoBlockSize is set while the block size is a default that size
+	// hints may shrink
+	autoBlockSize bool
+	checksum      Checksummer
+	chunker       *chunker
+	chunks        []ChunkInfo
+	dicts         DictionaryStore
+	dict          []byte
+	// compressor and compBuf are reused by every block written, and
+	// otherCompressor is the one of the level used before SetLevel
+	compressor      *Compressor
+	otherCompressor *Compressor
+	compBuf         []byte
+	word            [4]byte
+	// hdr holds the frame header or trailer being written, apart from any
+	// block data
+	hdr [maxHeaderSize]byte
+	// content is the running checksum of the frame's data
+	content xxh32.Digest
+	// stats enables the stats trailer, with hash as its content hash
+	stats bool
+	hash  xxh64.Digest
+	// err is the first error returned by the underlying writer. The frame
+	// is broken from then on, so Write and Close keep returning it.
+	err error
+	// async enables AsyncFlush, with spare as the buffer Write fills while
+	// the block in the other is written, and pending closed once it is
+	async   bool
+	spare   []byte
+	pending chan struct{}
+	// passthrough stores blocks raw, set by SetPassthrough
+	passthrough bool
+	// closeUnderlying makes Close close w too, setting underlyingClosed
+	closeUnderlying  bool
+	underlyingClosed bool
+	// maxExtLen is the MaxExtLenBytes option, with extBuf the buffer blocks
+	// are rewritten into
+	maxExtLen int
+	extBuf    []byte
+	// unsync skips mu, set by the Unsynchronized option
+	unsync bool
 }
 
 // frameHeader contains information about the LZ4 frame
@@ -80,116 +271,312 @@ type frameHeader struct {
 
 // WriterOptions provides configuration options for a Writer
 type WriterOptions struct {
-	// Level sets the compression level
+	// Level sets the compression level. Zero means the default set by
+	// SetDefaultLevel; set Store for NoCompression.
 	Level CompressionLevel
+	// Store writes every block uncompressed, as level NoCompression does.
+	// Level is ignored.
+	Store bool
 	// UseV2 enables the improved v0.2 compression algorithm
 	UseV2 bool
 	// BlockSize sets the size of compression blocks
 	BlockSize int
+	// BlockSizeCode sets the maximum block size advertised in the frame
+	// header. BlockSize, if smaller, still limits the blocks written.
+	// Zero derives the code from BlockSize.
+	BlockSizeCode BlockSizeCode
+	// SizeHint is the expected uncompressed size of the stream. When no
+	// block size is set, the smallest block size holding it is used.
+	SizeHint int64
+	// ContentChecksum enables the XXH32 checksum of the whole frame's data,
+	// written after the end marker
+	ContentChecksum bool
+	// StatsTrailer appends a skippable frame holding the frame's totals and
+	// the XXH64 hash of its data, which ReadFrameStats reads back without
+	// scanning the frame
+	StatsTrailer bool
+	// BlockChecksum, if set, enables block checksums computed with the given
+	// algorithm. Use XXH32 for frames other LZ4 implementations can verify.
+	BlockChecksum Checksummer
+	// DebugTrace, if set, receives the sequences of every block written
+	DebugTrace TraceFunc
+	// Chunking, if set, enables content-defined chunking with the given
+	// sizes. Invalid sizes fall back to the defaults.
+	Chunking *ChunkingOptions
+	// Dictionaries, if set, provides the dictionary each frame is compressed
+	// with: the store's current one when the frame starts
+	Dictionaries DictionaryStore
+	// AsyncFlush compresses and writes each full block on a background
+	// goroutine while Write fills the next one, so producers don't stall
+	// on compression. An error of a block in the background is returned by
+	// a later Write or Close. The debug trace and metrics recorder are
+	// called from that goroutine, one block at a time and in order. It
+	// doubles the buffer memory and is ignored with content-defined chunking.
+	AsyncFlush bool
+	// CloseUnderlying makes Close close the destination too, if it is an
+	// io.Closer, so that a Writer can own the file or connection it writes
+	CloseUnderlying bool
+	// MaxExtLenBytes, if positive, limits the extension bytes of every
+	// literal and match length for decoders that read no more, as described
+	// for LimitExtLen. Blocks that can't be encoded within it are stored.
+	MaxExtLenBytes int
+	// Unsynchronized drops the mutex every method of the Writer takes, for
+	// Writers used by a single goroutine, where it only costs time. Such a
+	// Writer must not be shared; Written and Compressed can still be read
+	// from any goroutine.
+	Unsynchronized bool
+}
+
+// ReaderOptions provides configuration options for a Reader
+type ReaderOptions struct {
+	// DebugTrace, if set, receives the sequences of every block read
+	DebugTrace TraceFunc
+	// BlockChecksum is the algorithm used to verify block checksums.
+	// Nil means XXH32, as the LZ4 frame format specifies.
+	BlockChecksum Checksummer
+	// Dictionaries looks up the dictionaries named by frame headers. Frames
+	// with a dictionary ID it doesn't hold fail with ErrUnknownDictionary.
+	Dictionaries DictionaryStore
+	// ResolveDictionary, if set, is called with the dictionary ID of a frame
+	// that Dictionaries doesn't hold, or of any frame without Dictionaries,
+	// before its first compressed block is decoded: to load dictionaries
+	// that frames from other tools name, from disk or a registry. The
+	// dictionary is kept for the following frames with the same ID. Frames
+	// fail with ErrDictionaryRequired if it returns none, or if neither is set.
+	ResolveDictionary DictionaryResolver
+	// Buffers, if set, supplies the Reader's block buffers, which Close
+	// returns to it
+	Buffers BufferProvider
+	// HeaderMode sets whether frame headers of other versions are read and
+	// whether reserved bits are rejected
+	HeaderMode HeaderMode
+	// VerifyChecksums sets whether block and content checksums are
+	// verified, and whether a mismatch stops the stream. The zero value,
+	// ChecksumVerify, verifies them all.
+	VerifyChecksums ChecksumMode
+	// CloseUnderlying makes Close close the source too, if it is an
+	// io.Closer, so that a Reader can own the file or connection it reads
+	CloseUnderlying bool
+	// Limits bounds the size, block count and expansion ratio of every
+	// frame, for readers of untrusted input
+	Limits DecompressionLimits
+	// SparseHoles reads the hole frames CompressFile writes for the holes
+	// of sparse files as runs of zeros. Without it they are skipped like
+	// any other skippable frame, as other LZ4 decoders do, since a few
+	// bytes of hole frame can stand for any number of zeros. Only set it
+	// for trusted input or with Limits; DecompressFile sets it.
+	SparseHoles bool
+	// ValidateSequences decodes every compressed block with a
+	// ValidatingDecoder, failing the stream with a SequenceError at the
+	// first sequence that breaks a rule of the block format. It is slower,
+	// and meant for testing the output of new encoders.
+	ValidateSequences bool
 }
 
 // NewReader returns a new Reader that decompresses from r
 func NewReader(r io.Reader) *Reader {
-	return &Reader{
-		r:   r,
-		buf: make([]byte, 8192),
+	z := &Reader{}
+	z.r = &countingReader{r: r, n: &z.consumed}
+	z.seeker, _ = r.(io.Seeker)
+	return z
+}
+
+// NewReaderWithOptions returns a new Reader with custom options
+func NewReaderWithOptions(r io.Reader, options ReaderOptions) *Reader {
+	z := NewReader(r)
+	z.trace = options.DebugTrace
+	z.checksum = options.BlockChecksum
+	z.dicts = options.Dictionaries
+	z.resolveDict = options.ResolveDictionary
+	z.limits = options.Limits
+	z.buffers = options.Buffers
+	z.headerMode = options.HeaderMode
+	z.verify = options.VerifyChecksums
+	z.closeUnderlying = options.CloseUnderlying
+	z.validate = options.ValidateSequences
+	z.sparse = options.SparseHoles
+	if options.CloseUnderlying {
+		z.closer, _ = r.(io.Closer)
 	}
+	return z
+}
+
+// Reset discards the Reader's state and makes it read from r, as a new
+// Reader with the same options would. Its block buffers are kept, so a
+// Reader reused through a sync.Pool allocates them only once. It also
+// reopens a closed Reader, without closing the previous source.
+func (z *Reader) Reset(r io.Reader) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.consumed.Store(0)
+	z.produced.Store(0)
+	if cr, ok := z.r.(*countingReader); ok {
+		cr.r = r
+	} else {
+		z.r = &countingReader{r: r, n: &z.consumed}
+	}
+	z.seeker, _ = r.(io.Seeker)
+	z.closer = nil
+	if z.closeUnderlying {
+		z.closer, _ = r.(io.Closer)
+	}
+
+	z.header = frameHeader{}
+	z.readHeader = false
+	z.headerErr = nil
+	z.reachedEof = false
+	z.blocksizeCache = 0
+	z.out.reset()
+	z.storedLeft = 0
+	z.blockIndex = 0
+	z.holeLeft = 0
+	z.holeEnded = false
+	z.holeFrames, z.holeTotal = 0, 0
+	z.dict, z.dictID = nil, 0
+	z.content.Reset()
+	z.contentUnchecked = false
+	z.checksumErr = nil
+	z.closed = false
+	z.err = nil
 }
 
-// Read implements io.Reader
+// Consumed returns the number of compressed bytes read from the underlying reader.
+// It is safe to call concurrently with Read.
+func (r *Reader) Consumed() uint64 {
+	return r.consumed.Load()
+}
+
+// Produced returns the number of decompressed bytes returned by Read.
+// It is safe to call concurrently with Read.
+func (r *Reader) Produced() uint64 {
+	return r.produced.Load()
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *atomic.Uint64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n.Add(uint64(n))
+	return n, err
+}
+
+// Read implements io.Reader.
+// It fills p across as many blocks as needed, so a large buffer is filled
+// completely unless the stream ends or fails. Data copied before an error is
+// returned along with it; io.EOF is only returned once no data remains.
+// A stream that ends within a frame fails with an error wrapping
+// io.ErrUnexpectedEOF that names the part of the frame cut off. Errors are
+// sticky, as described for Reader.
 func (r *Reader) Read(p []byte) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.closed {
+		return 0, ErrReaderClosed
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
 	if r.reachedEof {
 		return 0, io.EOF
 	}
 
 	// Read the frame header if we haven't yet
-	if !r.readHeader {
-		if err := r.readFrameHeader(); err != nil {
-			return 0, err
-		}
-		r.readHeader = true
-
-		// Set block size based on header
-		switch r.header.blockSizeCode {
-		case 4:
-			r.blocksizeCache = 64 * 1024
-		case 5:
-			r.blocksizeCache = 256 * 1024
-		case 6:
-			r.blocksizeCache = 1 * 1024 * 1024
-		case 7:
-			r.blocksizeCache = 4 * 1024 * 1024
-		default:
-			return 0, errors.New("invalid block size code")
-		}
+	if err := r.ensureHeader(); err != nil {
+		return 0, err
 	}
 
-	// If we have data in current buffer, return it
-	if r.decompressed != nil && r.bufPos < len(r.decompressed) {
-		n := copy(p, r.decompressed[r.bufPos:])
-		r.bufPos += n
+	n := 0
+	for n < len(p) {
+		// Refill from the next block once the current one is consumed
+		if r.out.len() == 0 {
+			r.out.reset()
 
-		// If we've consumed all decompressed data, prepare for next block
-		if r.bufPos >= len(r.decompressed) {
-			r.decompressed = nil
-			r.bufPos = 0
+			direct, err := r.readBlock(p[n:])
+			n += direct
+			r.produced.Add(uint64(direct))
+			if err != nil {
+				if err == io.EOF {
+					r.reachedEof = true
+					if n == 0 {
+						return 0, io.EOF
+					}
+					return n, nil
+				}
+				r.err = err
+				return n, err
+			}
+			continue
 		}
 
-		return n, nil
+		copied := r.out.read(p[n:])
+		n += copied
+		r.produced.Add(uint64(copied))
 	}
 
-	// Read the next block
-	if err := r.readBlock(); err != nil {
-		if err == io.EOF {
-			r.reachedEof = true
-			// Return EOF only if we haven't read anything yet
-			if r.decompressed == nil || len(r.decompressed) == 0 {
-				return 0, io.EOF
-			}
-		} else {
-			return 0, err
-		}
+	return n, nil
+}
+
+// ensureHeader reads the frame header on first use and sets up the block size
+func (r *Reader) ensureHeader() error {
+	if r.readHeader {
+		return nil
+	}
+	// A failed header read leaves the source at an unknown position
+	if r.headerErr != nil {
+		return r.headerErr
 	}
 
-	// If we have no data (empty buffer), return EOF
-	if r.decompressed == nil || len(r.decompressed) == 0 {
-		r.reachedEof = true
-		return 0, io.EOF
+	if err := r.readFrameHeader(); err != nil {
+		r.headerErr = err
+		return err
 	}
+	r.readHeader = true
 
-	// Now we have data, so read from it
-	n := copy(p, r.decompressed)
-	r.bufPos = n
+	return r.setBlockSize()
+}
 
-	// If we've consumed all decompressed data, prepare for next block
-	if r.bufPos >= len(r.decompressed) {
-		r.decompressed = nil
-		r.bufPos = 0
+// setBlockSize sets the maximum block size from the parsed frame header
+func (r *Reader) setBlockSize() error {
+	r.blocksizeCache = BlockSizeFromCode(BlockSizeCode(r.header.blockSizeCode))
+	if r.blocksizeCache == 0 {
+		return ErrInvalidBlockSizeCode
 	}
-
-	return n, nil
+	return nil
 }
 
 // readFrameHeader reads and verifies the LZ4 frame header
 func (r *Reader) readFrameHeader() error {
 	// Read magic number (4 bytes)
-	var magic uint32
-	if err := binary.Read(r.r, binary.LittleEndian, &magic); err != nil {
+	magic, err := r.readMagic()
+	if err != nil {
 		return err
 	}
 
 	// Verify magic number
 	if magic != frameMagic {
-		return errors.New("invalid LZ4 frame magic number")
+		return ErrInvalidMagic
 	}
 
+	return r.readFrameDescriptor()
+}
+
+// readFrameDescriptor reads the frame descriptor that follows the magic number
+func (r *Reader) readFrameDescriptor() error {
+	r.content.Reset()
+	r.window = r.window[:0]
+	r.contentUnchecked = false
+	r.startFrameLimits()
+
 	// Read FLG byte
-	flg := make([]byte, 1)
+	flg := r.fields[:1]
 	if _, err := io.ReadFull(r.r, flg); err != nil {
-		return err
+		return truncated("frame header", err)
 	}
 
 	// Parse flags
@@ -199,15 +586,18 @@ func (r *Reader) readFrameHeader() error {
 	r.header.contentChecksum = (flg[0] & flagContentChecksum) != 0
 	r.header.dictID = (flg[0] & flagDictID) != 0
 
-	// Check version - only v1.x supported
-	version := (flg[0] >> 6) & 0x3
-	if version != 1 {
-		return errors.New("unsupported version")
+	// Check version and reserved bit as the header mode asks
+	if err := r.headerMode.checkFLG(flg[0]); err != nil {
+		return err
 	}
 
 	// Read BD byte
-	bd := make([]byte, 1)
+	bd := r.fields[1:2]
 	if _, err := io.ReadFull(r.r, bd); err != nil {
+		return truncated("frame header", err)
+	}
+
+	if err := r.headerMode.checkBD(bd[0]); err != nil {
 		return err
 	}
 
@@ -216,156 +606,469 @@ func (r *Reader) readFrameHeader() error {
 
 	// Validate block size code (must be 4-7)
 	if r.header.blockSizeCode < 4 || r.header.blockSizeCode > 7 {
-		return errors.New("invalid block size code")
+		return ErrInvalidBlockSizeCode
 	}
 
-	// Read HC byte (header checksum) - we don't validate it in v0.1
-	hc := make([]byte, 1)
-	if _, err := io.ReadFull(r.r, hc); err != nil {
+	// Read the optional fields, the content size (8 bytes) and the
+	// dictionary ID (4 bytes), then the HC byte (header checksum) that
+	// covers them along with FLG and BD
+	n := 2
+	if r.header.contentSize {
+		n += 8
+	}
+	if r.header.dictID {
+		n += 4
+	}
+	if _, err := io.ReadFull(r.r, r.fields[2:n+1]); err != nil {
+		return truncated("frame header", err)
+	}
+	desc := r.fields[:n]
+	if err := r.headerMode.checkHC(desc, r.fields[n]); err != nil {
 		return err
 	}
+	fields := desc[2:]
 
-	// Read optional fields
-
-	// Content size (8 bytes)
 	if r.header.contentSize {
-		if err := binary.Read(r.r, binary.LittleEndian, &r.header.contentSizeValue); err != nil {
-			return err
+		r.header.contentSizeValue = binary.LittleEndian.Uint64(fields)
+		fields = fields[8:]
+	}
+	if r.header.dictID {
+		r.header.dictIDValue = binary.LittleEndian.Uint32(fields)
+	}
+
+	return nil
+}
+
+// readMagic reads the next magic number, skipping over any skippable frames.
+// Sparse hole frames are not skipped: their length is queued as zeros to be
+// returned before the blocks of the next frame.
+func (r *Reader) readMagic() (uint32, error) {
+	buf := r.word[:]
+	for {
+		// Ending before a magic number is the clean end of the stream
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return 0, truncated("frame header", err)
+			}
+			return 0, err
+		}
+
+		magic := binary.LittleEndian.Uint32(buf)
+		if magic&skippableMagicMask != skippableMagic {
+			return magic, nil
+		}
+
+		// Skippable frames carry a 4-byte size followed by user data
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return 0, truncated("skippable frame", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(buf))
+		if magic == sparseHoleMagic && size == sparseHoleSize && r.sparse {
+			hole := r.fields[:sparseHoleSize]
+			if _, err := io.ReadFull(r.r, hole); err != nil {
+				return 0, truncated("skippable frame", err)
+			}
+			if err := r.addHole(binary.LittleEndian.Uint64(hole)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, r.r, size); err != nil {
+			return 0, truncated("skippable frame", err)
 		}
 	}
+}
 
-	// Dictionary ID (4 bytes)
-	if r.header.dictID {
-		if err := binary.Read(r.r, binary.LittleEndian, &r.header.dictIDValue); err != nil {
+// nextFrame finishes the current frame and reads the header of the next
+// concatenated frame. It returns io.EOF if the stream ends after the frame.
+func (r *Reader) nextFrame() error {
+	// Verify the content checksum of the finished frame
+	if r.header.contentChecksum {
+		if _, err := io.ReadFull(r.r, r.word[:]); err != nil {
+			return truncated("content checksum", err)
+		}
+		if err := r.verifyContent(binary.LittleEndian.Uint32(r.word[:])); err != nil {
 			return err
 		}
 	}
 
-	return nil
-}
+	if r.firstFrame {
+		return io.EOF
+	}
 
-// readBlock reads and decompresses the next LZ4 block
-func (r *Reader) readBlock() error {
-	// Read block size (4 bytes)
-	var blockSize uint32
-	if err := binary.Read(r.r, binary.LittleEndian, &blockSize); err != nil {
+	// A clean end of stream keeps the header of the last frame
+	magic, err := r.readMagic()
+	if err != nil {
 		return err
 	}
+	if magic != frameMagic {
+		return ErrInvalidMagic
+	}
 
-	// Check for end marker
-	if blockSize == 0 {
-		return io.EOF
+	r.header = frameHeader{}
+	if err := r.readFrameDescriptor(); err != nil {
+		return err
 	}
+	return r.setBlockSize()
+}
 
-	// Check if block is compressed
-	isCompressed := true
-	if (blockSize & 0x80000000) != 0 {
-		// High bit set indicates uncompressed data
-		isCompressed = false
-		blockSize &= 0x7FFFFFFF // Clear the high bit
+// readBlock reads and decompresses the next LZ4 block. Stored blocks that
+// fit in p are read straight into it, saving a copy through r.out;
+// it returns the number of bytes placed in p that way. Larger stored blocks
+// are streamed into p a piece at a time if they have no block checksum,
+// which must be verified before any of their data is returned.
+func (r *Reader) readBlock(p []byte) (int, error) {
+	if r.storedLeft > 0 {
+		return r.readStored(p)
 	}
 
-	// Handle empty uncompressed block (which might be generated for small data)
-	if blockSize == 0 && !isCompressed {
-		r.decompressed = []byte{}
-		return nil
+	word, err := r.nextBlock()
+	if err != nil || word == 0 {
+		return 0, err
 	}
 
-	// Validate block size
-	if blockSize > uint32(r.blocksizeCache) {
-		return errors.New("block size too large")
+	if size := int(word & 0x7FFFFFFF); word&0x80000000 != 0 && size <= len(p) {
+		data := p[:size]
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return 0, truncated("block body", err)
+		}
+		if err := r.verifyBlock(data); err != nil {
+			return 0, err
+		}
+		if err := r.decodeBlock(data, false); err != nil {
+			return 0, err
+		}
+		r.out.reset()
+		return size, nil
+	} else if word&0x80000000 != 0 && len(p) > 0 && !r.header.blockChecksum && r.trace == nil {
+		r.blockIndex++
+		if r.metrics != nil {
+			r.metrics.RecordBlock(size, size, 0)
+		}
+		r.storedLeft = size
+		return r.readStored(p)
 	}
 
-	// Read block data
-	blockData := make([]byte, blockSize)
-	if _, err := io.ReadFull(r.r, blockData); err != nil {
-		return err
+	data, err := r.readBlockData(word)
+	if err != nil {
+		return 0, err
 	}
+	return 0, r.decodeBlock(data, word&0x80000000 == 0)
+}
 
-	// Skip block checksum if present
-	if r.header.blockChecksum {
-		checksum := make([]byte, 4)
-		if _, err := io.ReadFull(r.r, checksum); err != nil {
-			return err
+// readStored reads the rest of a streamed stored block into p, as much as
+// fits, or into r.out if p is empty
+func (r *Reader) readStored(p []byte) (int, error) {
+	if len(p) == 0 {
+		if cap(r.blockBuf) < r.storedLeft {
+			r.blockBuf = r.growBuffer(r.blockBuf, &r.blockBufPooled, r.blocksizeCache)
+		}
+		data := r.blockBuf[:r.storedLeft]
+		if _, err := io.ReadFull(r.r, data); err != nil {
+			return 0, truncated("block body", err)
 		}
+		r.storedLeft = 0
+		r.out.set(data)
+		r.hashContent(data)
+		r.keepHistory(data)
+		return 0, nil
 	}
 
+	data := p[:min(len(p), r.storedLeft)]
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return 0, truncated("block body", err)
+	}
+	r.storedLeft -= len(data)
+	r.hashContent(data)
+	r.keepHistory(data)
+	return len(data), nil
+}
+
+// nextBlock reads the size word of the next block holding data, moving on to
+// any concatenated frame. It returns 0 if it queued the zeros of a sparse
+// hole in r.out instead.
+func (r *Reader) nextBlock() (uint32, error) {
+	// Zeros of a sparse hole come before anything read after it
+	if r.holeLeft > 0 {
+		r.readHole()
+		return 0, nil
+	}
+	if r.holeEnded {
+		return 0, io.EOF
+	}
+
+	for {
+		// The end marker is still to come, so any end here is a truncation
+		if _, err := io.ReadFull(r.r, r.word[:]); err != nil {
+			return 0, truncated("block size", err)
+		}
+		blockSize := binary.LittleEndian.Uint32(r.word[:])
+
+		// Check for end marker, continuing with any concatenated frame
+		if blockSize == 0 {
+			if err := r.nextFrame(); err != nil {
+				// A trailing hole still has to be returned before the end
+				if err == io.EOF && r.holeLeft > 0 {
+					r.holeEnded = true
+					r.readHole()
+					return 0, nil
+				}
+				return 0, err
+			}
+			if r.holeLeft > 0 {
+				r.readHole()
+				return 0, nil
+			}
+			continue
+		}
+
+		// Validate block size
+		if blockSize&0x7FFFFFFF > uint32(r.blocksizeCache) {
+			return 0, errors.New("block size too large")
+		}
+		if err := r.limitBlock(blockSize); err != nil {
+			return 0, err
+		}
+
+		// Skip empty uncompressed blocks (which might be generated for small data)
+		if blockSize == 0x80000000 {
+			if err := r.verifyBlock(nil); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return blockSize, nil
+	}
+}
+
+// readBlockData reads the data of the block with the given size word and
+// verifies its checksum
+func (r *Reader) readBlockData(word uint32) ([]byte, error) {
+	blockSize := int(word & 0x7FFFFFFF) // High bit set indicates uncompressed data
+	if cap(r.blockBuf) < blockSize {
+		r.blockBuf = r.growBuffer(r.blockBuf, &r.blockBufPooled, r.blocksizeCache)
+	}
+	blockData := r.blockBuf[:blockSize]
+	if _, err := io.ReadFull(r.r, blockData); err != nil {
+		return nil, truncated("block body", err)
+	}
+
+	if err := r.verifyBlock(blockData); err != nil {
+		return nil, err
+	}
+	return blockData, nil
+}
+
+// decodeBlock makes the contents of a block read by readBlockData available
+// to Read
+func (r *Reader) decodeBlock(blockData []byte, isCompressed bool) error {
 	// If block is uncompressed, just use it
 	if !isCompressed {
-		r.decompressed = blockData
+		if r.trace != nil {
+			traceStored(r.blockIndex, blockData, r.trace)
+		}
+		r.blockIndex++
+
+		r.out.set(blockData)
+		r.hashContent(blockData)
+		r.keepHistory(blockData)
+		if r.metrics != nil {
+			r.metrics.RecordBlock(len(blockData), len(blockData), 0)
+		}
 		return nil
 	}
 
+	// Trace before decoding so a corrupt block still shows its valid prefix
+	if r.trace != nil {
+		traceBlock(r.blockIndex, blockData, r.trace)
+	}
+	r.blockIndex++
+
 	// Decompress block
-	decompressed, err := DecompressBlock(blockData, nil, r.blocksizeCache)
+	dict, err := r.dictionary()
 	if err != nil {
 		return err
 	}
+	// Dependent blocks reach back into the blocks before them too
+	if !r.header.blockIndependence {
+		dict = r.linkedPrefix(dict)
+	}
+	// Blocks are decoded after a copy of the dictionary, if any
+	need := r.blocksizeCache
+	if len(dict) > 0 {
+		need += len(dictWindow(dict))
+	}
+	r.blockOut = r.growBuffer(r.blockOut, &r.blockOutPooled, need)
+
+	start := time.Now()
+	var decompressed []byte
+	if r.validate {
+		v := ValidatingDecoder{Dict: dict, MaxSize: r.blocksizeCache}
+		if decompressed, err = v.Decode(blockData); err != nil {
+			return fmt.Errorf("block %d: %w", r.blockIndex-1, err)
+		}
+	} else if decompressed, err = decompressBlockDict(blockData, r.blockOut, dict, r.blocksizeCache); err != nil {
+		return err
+	}
+	if err := r.limitOutput(len(decompressed), 0); err != nil {
+		return err
+	}
+	if r.metrics != nil {
+		r.metrics.RecordBlock(len(blockData), len(decompressed), time.Since(start))
+	}
+
+	r.out.set(decompressed)
+	r.hashContent(decompressed)
+	r.keepHistory(decompressed)
+	return nil
+}
+
+// linkedPrefix returns the data the next block of a frame with dependent
+// blocks may reach back into: the last 64KB of dict and the blocks before it
+func (r *Reader) linkedPrefix(dict []byte) []byte {
+	if len(r.window) >= maxDictWindow || len(dict) == 0 {
+		return r.window
+	}
+	dict = dictWindow(dict)
+	keep := min(len(dict), maxDictWindow-len(r.window))
+	r.prefix = append(append(r.prefix[:0], dict[len(dict)-keep:]...), r.window...)
+	return r.prefix
+}
 
-	r.decompressed = decompressed
+// keepHistory adds decoded data of a frame with dependent blocks to the
+// window its later blocks may reach back into
+func (r *Reader) keepHistory(p []byte) {
+	if r.header.blockIndependence {
+		return
+	}
+	if len(p) >= maxDictWindow {
+		r.window = append(r.window[:0], p[len(p)-maxDictWindow:]...)
+		return
+	}
+	if over := len(r.window) + len(p) - maxDictWindow; over > 0 {
+		r.window = r.window[:copy(r.window, r.window[over:])]
+	}
+	r.window = append(r.window, p...)
+}
+
+// verifyBlock reads the checksum following a block, if the frame has block
+// checksums, and verifies it against the block data
+func (r *Reader) verifyBlock(data []byte) error {
+	if !r.header.blockChecksum {
+		return nil
+	}
+
+	if _, err := io.ReadFull(r.r, r.word[:]); err != nil {
+		return truncated("block checksum", err)
+	}
+	if r.verify == ChecksumIgnore {
+		return nil
+	}
+
+	c := r.checksum
+	if c == nil {
+		c = XXH32
+	}
+	if c.Checksum(data) != binary.LittleEndian.Uint32(r.word[:]) {
+		return r.checksumMismatch(ErrBlockChecksum)
+	}
 	return nil
 }
 
-// NewWriter creates a new LZ4 writer with default compression level
+// NewWriter creates a new LZ4 writer with the default compression level set
+// by SetDefaultLevel
 func NewWriter(w io.Writer) *Writer {
-	return NewWriterLevel(w, DefaultLevel)
+	return NewWriterLevel(w, CurrentDefaultLevel())
 }
 
-// NewWriterLevel creates a new LZ4 writer with specified compression level
+// NewWriterLevel creates a new LZ4 writer with specified compression level.
+// NoCompression stores every block uncompressed.
 func NewWriterLevel(w io.Writer, level CompressionLevel) *Writer {
 	// Ensure we have a valid compression level
-	if level < 1 || level > MaxLevel {
-		level = DefaultLevel
+	if level < NoCompression || level > MaxLevel {
+		level = CurrentDefaultLevel()
 	}
 
-	// Determine block size based on blockSizeCode 7 (4MB default)
-	blockSize := 4 * 1024 * 1024
-
+	// Blocks default to 4MB until a size hint or block size code is set;
+	// the buffer is allocated on the first write
 	z := &Writer{
 		w:     w,
 		level: level,
 		header: frameHeader{
 			blockIndependence: true,
-			blockSizeCode:     7, // 4MB blocks by default
+			blockSizeCode:     7,
 		},
-		blockSize: blockSize,
-		// Allocate a buffer large enough for the block plus header/footer overhead
-		buf: make([]byte, maxBlockSize+maxHeaderSize+16),
+		blockSize:     maxBlockSize,
+		autoBlockSize: true,
 	}
 
 	return z
 }
 
-// Reset resets the Writer to write to w
+// Reset resets the Writer to write to w, dropping any content size
+// declared for the previous frame.
+// It waits for any Write or Close in progress on another goroutine to finish.
 func (z *Writer) Reset(w io.Writer) {
+	z.lock()
+	defer z.unlock()
+	z.reset(w)
+}
+
+// reset resets the Writer to write to w; the caller must hold z.mu
+func (z *Writer) reset(w io.Writer) {
+	z.wait()
 	z.w = w
+	z.underlyingClosed = false
 	z.bufUsed = 0
 	z.closed = false
+	z.err = nil
 	z.wroteHeader = false
+	z.header.contentSize = false
+	z.header.contentSizeValue = 0
 	z.written = 0
+	z.bytesIn.Store(0)
+	z.bytesOut.Store(0)
+	z.blockIndex = 0
+	z.chunks = nil
 
 	// Re-initialize the block size based on the header block size code
-	switch z.header.blockSizeCode {
-	case 4:
-		z.blockSize = 64 * 1024
-	case 5:
-		z.blockSize = 256 * 1024
-	case 6:
-		z.blockSize = 1 * 1024 * 1024
-	case 7:
-		z.blockSize = 4 * 1024 * 1024
-	default:
+	maxSize := BlockSizeFromCode(BlockSizeCode(z.header.blockSizeCode))
+	if maxSize == 0 {
 		// Default to max block size
-		z.blockSize = 4 * 1024 * 1024
-		z.header.blockSizeCode = 7
+		z.header.blockSizeCode = uint8(BlockSize4MB)
+		maxSize = maxBlockSize
+	}
+
+	// Keep a custom block size as long as it still fits the advertised maximum
+	if z.blockSize <= 0 || z.blockSize > maxSize {
+		z.blockSize = maxSize
+	}
+}
+
+// lock takes z.mu unless the Writer is unsynchronized
+func (z *Writer) lock() {
+	if !z.unsync {
+		z.mu.Lock()
 	}
 }
 
-// Write implements io.Writer
+// unlock releases z.mu unless the Writer is unsynchronized
+func (z *Writer) unlock() {
+	if !z.unsync {
+		z.mu.Unlock()
+	}
+}
+
+// Write implements io.Writer.
+// Write does not retain p: full blocks are compressed straight from it and
+// the rest is copied into the writer's buffer.
 func (z *Writer) Write(p []byte) (int, error) {
-	z.mu.Lock()
-	defer z.mu.Unlock()
+	z.lock()
+	defer z.unlock()
 
+	if err := z.poll(); err != nil {
+		return 0, err
+	}
 	if z.closed {
 		return 0, errors.New("write to closed stream")
 	}
@@ -378,6 +1081,11 @@ func (z *Writer) Write(p []byte) (int, error) {
 		}
 		z.wroteHeader = true
 	}
+	z.growBuffer()
+
+	if z.chunker != nil {
+		return z.writeChunked(p)
+	}
 
 	var written int
 	for len(p) > 0 {
@@ -385,16 +1093,35 @@ func (z *Writer) Write(p []byte) (int, error) {
 		remaining := z.blockSize - z.bufUsed
 		if remaining == 0 {
 			// Flush current block
-			err := z.flush()
+			var err error
+			if z.async {
+				err = z.flushAsync()
+			} else {
+				err = z.flush()
+			}
 			if err != nil {
 				return written, err
 			}
 			remaining = z.blockSize
 		}
 
+		// Compress whole blocks in place while more data follows them, so
+		// the last block is still left for Close like buffered data. Blocks
+		// written in the background are copied, as p isn't retained.
+		if z.bufUsed == 0 && len(p) > z.blockSize && !z.async {
+			if err := z.compressBlock(p[:z.blockSize]); err != nil {
+				return written, err
+			}
+			z.accept(p[:z.blockSize])
+			p = p[z.blockSize:]
+			written += z.blockSize
+			continue
+		}
+
 		// Copy data to buffer
 		n := copy(z.buf[z.bufUsed:z.bufUsed+remaining], p)
 		z.bufUsed += n
+		z.accept(p[:n])
 		p = p[n:]
 		written += n
 	}
@@ -402,90 +1129,107 @@ func (z *Writer) Write(p []byte) (int, error) {
 	return written, nil
 }
 
-// writeFrameHeader writes the LZ4 frame header to the output
-func (z *Writer) writeFrameHeader() error {
-	// Ensure we have enough space for the frame header
-	headerSize := 7 // Magic number (4), FLG (1), BD (1), HC (1)
-	if z.header.contentSize {
-		headerSize += 8
-	}
-	if z.header.dictID {
-		headerSize += 4
-	}
+// Written returns the number of uncompressed bytes accepted by Write since
+// the Writer was created or last reset, including data not yet flushed.
+// It is safe to call concurrently with Write.
+func (z *Writer) Written() uint64 {
+	return z.bytesIn.Load()
+}
 
-	// Write magic number
-	binary.LittleEndian.PutUint32(z.buf[0:4], frameMagic)
+// Compressed returns the number of bytes written to the underlying writer
+// since the Writer was created or last reset, including frame overhead.
+// It is safe to call concurrently with Write.
+func (z *Writer) Compressed() uint64 {
+	return z.bytesOut.Load()
+}
 
-	// Write FLG byte
-	flg := byte(0)
-	if z.header.blockIndependence {
-		flg |= flagBlockIndependence
-	}
-	if z.header.blockChecksum {
-		flg |= flagBlockChecksum
-	}
-	if z.header.contentSize {
-		flg |= flagContentSize
+// writeOut writes all of p to the underlying writer and counts the bytes
+// written, including those of a write that failed part way
+func (z *Writer) writeOut(p []byte) (int, error) {
+	n, err := writeFull(z.w, p)
+	z.bytesOut.Add(uint64(n))
+	if err != nil && z.err == nil {
+		z.err = err
 	}
+	return n, err
+}
+
+// accept counts p as data of the frame, adding it to the content checksum
+func (z *Writer) accept(p []byte) {
+	z.bytesIn.Add(uint64(len(p)))
 	if z.header.contentChecksum {
-		flg |= flagContentChecksum
+		z.content.Write(p)
 	}
-	if z.header.dictID {
-		flg |= flagDictID
+	if z.stats {
+		z.hash.Write(p)
 	}
+}
+
+// writeFrameHeader writes the LZ4 frame header to the output
+func (z *Writer) writeFrameHeader() error {
+	z.loadDictionary()
+	z.content.Reset()
+	z.hash.Reset()
+
+	// Encode into a separate array so the header never aliases block data
+	_, err := z.writeOut(appendFrameHeader(z.hdr[:0], &z.header))
+
+	return err
+}
+
+// appendFrameHeader appends the encoded frame header h to dst
+func appendFrameHeader(dst []byte, h *frameHeader) []byte {
+	// Write magic number
+	dst = binary.LittleEndian.AppendUint32(dst, frameMagic)
+
+	// Write FLG byte
+	flg := byte(h.flags())
 	// Version is always 01 for now
 	flg |= (1 << 6)
 
-	z.buf[4] = flg
-
 	// Write BD byte (block descriptor)
 	// Block size flag (4-7) in bits 4-6
 	bd := byte(0)
-	if z.header.blockSizeCode >= 4 && z.header.blockSizeCode <= 7 {
-		bd |= (z.header.blockSizeCode & 0x7) << 4
+	if h.blockSizeCode >= 4 && h.blockSizeCode <= 7 {
+		bd |= (h.blockSizeCode & 0x7) << 4
 	} else {
 		// Default to maximum block size (7 = 4MB)
 		bd |= (7 << 4)
 	}
 
-	z.buf[5] = bd
-
-	// Write HC byte (header checksum)
-	// For v0.1, we just use a fixed value
-	hc := byte(0)
-	z.buf[6] = hc
+	start := len(dst)
+	dst = append(dst, flg, bd)
 
 	// Write optional fields
-	offset := 7
 
 	// Content size (8 bytes)
-	if z.header.contentSize {
-		binary.LittleEndian.PutUint64(z.buf[offset:offset+8], z.header.contentSizeValue)
-		offset += 8
+	if h.contentSize {
+		dst = binary.LittleEndian.AppendUint64(dst, h.contentSizeValue)
 	}
 
 	// Dictionary ID (4 bytes)
-	if z.header.dictID {
-		binary.LittleEndian.PutUint32(z.buf[offset:offset+4], z.header.dictIDValue)
-		offset += 4
+	if h.dictID {
+		dst = binary.LittleEndian.AppendUint32(dst, h.dictIDValue)
 	}
 
-	// Write header to output
-	_, err := z.w.Write(z.buf[0:offset])
+	// Write HC byte (header checksum) over FLG through the dictionary ID
+	dst = append(dst, headerChecksum(dst[start:]))
 
-	return err
+	return dst
+}
+
+// headerChecksum returns the HC byte of a frame descriptor: the second byte
+// of the XXH32 of FLG, BD and the optional fields
+func headerChecksum(desc []byte) byte {
+	return byte(xxh32.Checksum(desc) >> 8)
 }
 
 // flush compresses and writes a block
 func (z *Writer) flush() error {
 	if z.bufUsed == 0 {
 		// Nothing to flush - for empty flushes, we'll just write out
-		// an uncompressed block with length 0 to satisfy the LZ4 format
-		// Write a block size of 0x80000000 (high bit set, zero size)
-		sizeBuffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sizeBuffer, 0x80000000)
-		_, err := z.w.Write(sizeBuffer)
-		return err
+		// an empty uncompressed block to satisfy the LZ4 format
+		return z.writeBlock(nil, false)
 	}
 
 	// Ensure we don't exceed maximum block size
@@ -493,106 +1237,172 @@ func (z *Writer) flush() error {
 		return errors.New("block size too large")
 	}
 
-	// For very small data, don't try to compress
-	if z.bufUsed < 16 { // Minimum viable size for LZ4 compression
-		// Just write uncompressed block
-		blockSize := uint32(z.bufUsed) | 0x80000000 // Set high bit to indicate uncompressed
-		sizeBuffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sizeBuffer, blockSize)
-		_, err := z.w.Write(sizeBuffer)
-		if err != nil {
-			return err
-		}
-
-		// Write the raw data
-		_, err = z.w.Write(z.buf[:z.bufUsed])
-		z.written += uint64(z.bufUsed)
-		z.bufUsed = 0
+	if err := z.compressBlock(z.buf[:z.bufUsed]); err != nil {
 		return err
 	}
+	z.bufUsed = 0
 
-	// Create a slice to hold the compressed data
-	// For V0.1, we'll use a simple literal block approach for all data
-	// Worst case: 4 bytes block header + LZ4 compression overhead + data
-	maxCompSize := len(z.buf) + (len(z.buf) / 255) + 16
-	compBuf := make([]byte, maxCompSize)
+	return nil
+}
 
-	// Convert input to a Block for simplified LZ4 compression
-	inputSlice := z.buf[:z.bufUsed]
-	block, err := NewBlock(inputSlice, z.level)
-	if err != nil {
-		// On error, just store uncompressed
-		blockSize := uint32(z.bufUsed) | 0x80000000 // Set high bit to indicate uncompressed
-		sizeBuffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sizeBuffer, blockSize)
-		_, err := z.w.Write(sizeBuffer)
-		if err != nil {
-			return err
+// compressBlock compresses one block of input and writes it, stored as is
+// when compression doesn't save space
+func (z *Writer) compressBlock(inputSlice []byte) error {
+	start := time.Now()
+
+	// For very small data, don't try to compress
+	if len(inputSlice) < 16 || z.level == NoCompression || z.passthrough { // Minimum viable size for LZ4 compression
+		return z.writeStored(inputSlice, start)
+	}
+
+	// Worst case: LZ4 compression overhead + data
+	maxCompSize := compressBound(len(z.buf))
+	if len(z.compBuf) < maxCompSize {
+		z.compBuf = make([]byte, maxCompSize)
+	}
+
+	var compData []byte
+	var err error
+	if z.useV2 && z.dict == nil {
+		compData, err = CompressBlockV2Level(inputSlice, z.compBuf, z.level)
+	} else {
+		if err = z.setCompressor(); err != nil {
+			return z.writeStored(inputSlice, start)
+		}
+		// Index the frame's dictionary once rather than for every block
+		if len(z.dict) > 0 && !z.compressor.primedFor(dictWindow(z.dict)) {
+			z.compressor.prewarm(z.dict, z.blockSize)
 		}
+		compData, err = z.compressor.CompressBlockDict(inputSlice, z.compBuf, z.dict)
+	}
+	if err == nil && z.maxExtLen > 0 {
+		if compData, err = LimitExtLen(compData, z.maxExtLen, z.extBuf); err == nil {
+			z.extBuf = compData
+		}
+	}
 
-		// Write the raw data
-		_, err = z.w.Write(z.buf[:z.bufUsed])
-		z.written += uint64(z.bufUsed)
-		z.bufUsed = 0
+	if err != nil || len(compData) >= len(inputSlice) {
+		// Compression failed or didn't save space, use uncompressed
+		return z.writeStored(inputSlice, start)
+	}
+
+	// Compression succeeded and saved space
+	if err := z.writeBlock(compData, true); err != nil {
 		return err
 	}
 
-	// Compress the data
-	compData, err := block.CompressToBuffer(compBuf[4:]) // Leave space for block size
-	if err != nil || len(compData) >= z.bufUsed {
-		// Compression failed or didn't save space, use uncompressed
-		blockSize := uint32(z.bufUsed) | 0x80000000 // Set high bit to indicate uncompressed
-		sizeBuffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sizeBuffer, blockSize)
-		_, err := z.w.Write(sizeBuffer)
-		if err != nil {
-			return err
-		}
+	// Update state
+	z.recordBlock(len(compData), len(inputSlice), start)
+	z.traceBlock(compData, true)
+	z.written += uint64(len(inputSlice))
+
+	return nil
+}
 
-		// Write the raw data
-		_, err = z.w.Write(z.buf[:z.bufUsed])
-		z.written += uint64(z.bufUsed)
-		z.bufUsed = 0
+func (z *Writer) writeStored(data []byte, start time.Time) error {
+	if err := z.writeBlock(data, false); err != nil {
 		return err
 	}
 
-	// Compression succeeded and saved space
-	// Write compressed block size
-	blockSize := uint32(len(compData))
-	binary.LittleEndian.PutUint32(compBuf[:4], blockSize)
+	z.recordBlock(len(data), len(data), start)
+	z.traceBlock(data, false)
+	z.written += uint64(len(data))
+	return nil
+}
 
-	// Add block checksum if enabled
-	if z.header.blockChecksum {
-		// TODO: Calculate and append checksum
-		// For v0.1, this is not implemented
+// writeBlock writes the size word, data and, if enabled, checksum of one block
+func (z *Writer) writeBlock(data []byte, compressed bool) error {
+	size := uint32(len(data))
+	if !compressed {
+		size |= 0x80000000 // Set high bit to indicate uncompressed
 	}
 
-	// Write the block size and compressed data
-	_, err = z.w.Write(compBuf[:4])
-	if err != nil {
+	binary.LittleEndian.PutUint32(z.word[:], size)
+	if _, err := z.writeOut(z.word[:]); err != nil {
 		return err
 	}
-	_, err = z.w.Write(compData)
-	if err != nil {
+	if _, err := z.writeOut(data); err != nil {
 		return err
 	}
 
-	// Update state
-	z.written += uint64(z.bufUsed)
-	z.bufUsed = 0
-
+	// The block checksum covers the block data as stored
+	if z.header.blockChecksum {
+		binary.LittleEndian.PutUint32(z.word[:], z.blockChecksummer().Checksum(data))
+		if _, err := z.writeOut(z.word[:]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// Close implements io.Closer
+// blockChecksummer returns the algorithm used for block checksums
+func (z *Writer) blockChecksummer() Checksummer {
+	if z.checksum != nil {
+		return z.checksum
+	}
+	return XXH32
+}
+
+// traceBlock reports the sequences of a written block to the debug trace, if any.
+// Blocks not compressed are stored as is.
+func (z *Writer) traceBlock(block []byte, compressed bool) {
+	if z.trace != nil {
+		if !compressed {
+			traceStored(z.blockIndex, block, z.trace)
+		} else {
+			traceBlock(z.blockIndex, block, z.trace)
+		}
+	}
+	z.blockIndex++
+}
+
+// recordBlock reports a written block to the metrics recorder, if any
+func (z *Writer) recordBlock(compressed, raw int, start time.Time) {
+	if z.metrics != nil {
+		z.metrics.RecordBlock(compressed, raw, time.Since(start))
+	}
+}
+
+// Close implements io.Closer. It flushes buffered data and finishes the
+// frame with the end marker and, if enabled, the content checksum.
+//
+// Closing again is safe: once Close has succeeded it returns nil without
+// writing anything. If the underlying writer fails, during Close or an
+// earlier Write, the frame can't be finished and Close keeps returning that
+// error until Reset. ErrContentSizeMismatch leaves the stream open.
+//
+// With the CloseUnderlying option the underlying writer is closed as well,
+// once, whether or not the frame could be finished.
 func (z *Writer) Close() error {
-	z.mu.Lock()
-	defer z.mu.Unlock()
+	z.lock()
+	defer z.unlock()
+
+	err := z.finish()
+	if z.closeUnderlying && !z.underlyingClosed {
+		if c, ok := z.w.(io.Closer); ok {
+			z.underlyingClosed = true
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
 
+// finish flushes buffered data and writes the end of the frame for Close;
+// the caller must hold z.mu
+func (z *Writer) finish() error {
+	if err := z.wait(); err != nil {
+		return err
+	}
 	if z.closed {
 		return nil
 	}
 
+	if z.header.contentSize && z.bytesIn.Load() != z.header.contentSizeValue {
+		return ErrContentSizeMismatch
+	}
+
 	var err error
 
 	// Make sure we've written the header
@@ -605,37 +1415,45 @@ func (z *Writer) Close() error {
 	}
 
 	// Flush any remaining data
+	if z.chunker != nil {
+		for z.bufUsed > 0 {
+			if err = z.flushChunk(z.bufUsed); err != nil {
+				return err
+			}
+		}
+	}
 	if z.bufUsed > 0 {
 		err = z.flush()
 		if err != nil {
 			return err
 		}
-	} else {
+	} else if z.written == 0 {
 		// If there's no data at all, write an empty block
 		// This is necessary for valid LZ4 frames to have at least one block
-		sizeBuffer := make([]byte, 4)
-		binary.LittleEndian.PutUint32(sizeBuffer, 0x80000000) // High bit set, zero size
-		_, err = z.w.Write(sizeBuffer)
-		if err != nil {
+		if err = z.writeBlock(nil, false); err != nil {
 			return err
 		}
 	}
 
-	// Write end marker (block size = 0)
-	endMarker := make([]byte, 4)
-	_, err = z.w.Write(endMarker) // All zeros for end marker
-	if err != nil {
+	// Write the end marker (block size = 0), then the content checksum
+	end := z.hdr[:8]
+	binary.LittleEndian.PutUint32(end, 0)
+	n := 4
+	if z.header.contentChecksum {
+		binary.LittleEndian.PutUint32(end[4:], z.content.Sum32())
+		n = 8
+	}
+	if _, err = z.writeOut(end[:n]); err != nil {
 		return err
 	}
 
-	// Write content checksum if enabled
-	if z.header.contentChecksum {
-		// Placeholder for content checksum calculation
-		// In a real implementation, we would have tracked a running checksum
-		// For v0.1, we'll just write zeros
-		zeroChecksum := make([]byte, 4)
-		_, err = z.w.Write(zeroChecksum)
-		if err != nil {
+	if z.chunker != nil {
+		if err := z.writeChunkIndex(); err != nil {
+			return err
+		}
+	}
+	if z.stats {
+		if err := z.writeStatsTrailer(); err != nil {
 			return err
 		}
 	}
@@ -646,24 +1464,65 @@ func (z *Writer) Close() error {
 
 // NewWriterWithOptions creates a new Writer with custom options
 func NewWriterWithOptions(w io.Writer, options WriterOptions) *Writer {
+	// A zero level means the default level
+	level := options.Level
+	if options.Store {
+		level = NoCompression
+	} else if level == 0 {
+		level = CurrentDefaultLevel()
+	}
+
 	writer := &Writer{
-		w:           w,
-		level:       options.Level,
-		useV2:       options.UseV2,
-		blockSize:   maxBlockSize,
-		closed:      false,
-		buf:         make([]byte, 0),
-		wroteHeader: false,
+		w:         w,
+		level:     level,
+		useV2:     options.UseV2,
+		trace:     options.DebugTrace,
+		blockSize: maxBlockSize,
 	}
 
 	// Use specified block size if provided
-	if options.BlockSize > 0 {
+	if options.BlockSize > 0 && options.BlockSize < maxBlockSize {
 		writer.blockSize = options.BlockSize
 	}
 
-	// Allocate buffer
-	writer.buf = make([]byte, writer.blockSize)
-	writer.bufUsed = 0
+	// Advertise the requested block size code, or else the smallest code
+	// that fits the blocks we write
+	code := uint8(options.BlockSizeCode)
+	if options.BlockSizeCode.Size() > 0 {
+		writer.blockSize = min(writer.blockSize, options.BlockSizeCode.Size())
+	} else {
+		code = uint8(CodeFromBlockSize(writer.blockSize))
+	}
+	writer.header = frameHeader{
+		blockIndependence: true,
+		contentChecksum:   options.ContentChecksum,
+		blockSizeCode:     code,
+	}
+
+	if options.BlockChecksum != nil {
+		writer.header.blockChecksum = true
+		writer.checksum = options.BlockChecksum
+	}
+
+	if options.Chunking != nil {
+		c, err := newChunker(*options.Chunking)
+		if err != nil {
+			c, _ = newChunker(ChunkingOptions{})
+		}
+		writer.chunker = c
+	}
+
+	writer.dicts = options.Dictionaries
+	writer.stats = options.StatsTrailer
+	writer.async = options.AsyncFlush
+	writer.closeUnderlying = options.CloseUnderlying
+	writer.maxExtLen = options.MaxExtLenBytes
+	writer.unsync = options.Unsynchronized
+
+	writer.autoBlockSize = options.BlockSize <= 0 && options.BlockSizeCode.Size() == 0
+	if options.SizeHint > 0 {
+		writer.applySizeHint(options.SizeHint)
+	}
 
 	return writer
 }
@@ -689,25 +1548,25 @@ func (w *Writer) write(block []byte) error {
 	if len(compressed) >= len(block) {
 		// Write uncompressed block with appropriate flag
 		// Block size (4 bytes)
-		binary.LittleEndian.PutUint32(w.buf[:4], uint32(len(block)|0x80000000))
-		if _, err := w.w.Write(w.buf[:4]); err != nil {
+		binary.LittleEndian.PutUint32(w.buf[:4], uint32(len(block))|0x80000000)
+		if _, err := w.writeOut(w.buf[:4]); err != nil {
 			return err
 		}
 
 		// Write original data
-		if _, err := w.w.Write(block); err != nil {
+		if _, err := w.writeOut(block); err != nil {
 			return err
 		}
 	} else {
 		// Write compressed block
 		// Block size (4 bytes)
 		binary.LittleEndian.PutUint32(w.buf[:4], uint32(len(compressed)))
-		if _, err := w.w.Write(w.buf[:4]); err != nil {
+		if _, err := w.writeOut(w.buf[:4]); err != nil {
 			return err
 		}
 
 		// Write compressed data
-		if _, err := w.w.Write(compressed); err != nil {
+		if _, err := w.writeOut(compressed); err != nil {
 			return err
 		}
 	}
@@ -3,6 +3,7 @@ package compress
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -759,3 +760,275 @@ func TestMaxAndMinFunctions(t *testing.T) {
 		})
 	}
 }
+
+// TestReaderConcatenatedFrames tests reading concatenated and skippable frames as one stream
+func TestReaderConcatenatedFrames(t *testing.T) {
+	first := generateCompressibleData(50 * 1024)
+	second := []byte("second frame")
+
+	var stream bytes.Buffer
+	stream.Write(compressFrame(t, first, func(w *Writer) { w.header.contentChecksum = true }))
+
+	// A skippable frame between the two data frames is ignored
+	skippable := []byte{0x5A, 0x2A, 0x4D, 0x18, 3, 0, 0, 0, 'x', 'y', 'z'}
+	stream.Write(skippable)
+	stream.Write(compressFrame(t, []byte{}, nil))
+	stream.Write(compressFrame(t, second, nil))
+
+	got, err := io.ReadAll(NewReader(&stream))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if want := append(first, second...); !bytes.Equal(got, want) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(want))
+	}
+
+	// Trailing bytes that are not a frame are reported
+	trailing := append(compressFrame(t, second, nil), 1, 2, 3, 4, 5)
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(trailing))); err == nil {
+		t.Errorf("ReadAll() with trailing garbage: error = nil, expected error")
+	}
+}
+
+// TestReaderLargeBuffer tests that a single Read fills a buffer spanning many blocks
+func TestReaderLargeBuffer(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+	frame := compressFrame(t, data, func(w *Writer) {
+		w.header.blockSizeCode = 4
+		w.Reset(w.w)
+	})
+
+	r := NewReader(bytes.NewReader(frame))
+	buf := make([]byte, len(data)+100)
+
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if n != len(data) || !bytes.Equal(buf[:n], data) {
+		t.Fatalf("Read() = %d bytes, want %d matching bytes", n, len(data))
+	}
+
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read() after end = %d, %v, want 0, io.EOF", n, err)
+	}
+
+	// Reads of odd sizes straddle block boundaries
+	r = NewReader(bytes.NewReader(frame))
+	var got []byte
+	chunk := make([]byte, 70*1024+3)
+	for {
+		n, err := io.ReadFull(r, chunk)
+		got = append(got, chunk[:n]...)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadFull() error = %v", err)
+		}
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("ReadFull() loop decoded %d bytes, want %d", len(got), len(data))
+	}
+}
+
+// TestStreamCounters tests the byte counters on Writer and Reader
+func TestStreamCounters(t *testing.T) {
+	data := generateCompressibleData(200 * 1024)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write(data[:1000]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := w.Written(); got != 1000 {
+		t.Errorf("Written() before flush = %d, want 1000", got)
+	}
+	if _, err := w.Write(data[1000:]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := w.Written(); got != uint64(len(data)) {
+		t.Errorf("Written() = %d, want %d", got, len(data))
+	}
+	if got := w.Compressed(); got != uint64(buf.Len()) {
+		t.Errorf("Compressed() = %d, want %d", got, buf.Len())
+	}
+
+	compressedLen := buf.Len()
+	r := NewReader(&buf)
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if got := r.Consumed(); got != uint64(compressedLen) {
+		t.Errorf("Consumed() = %d, want %d", got, compressedLen)
+	}
+	if got := r.Produced(); got != uint64(len(data)) {
+		t.Errorf("Produced() = %d, want %d", got, len(data))
+	}
+
+	// Reset starts counting from zero
+	w.Reset(io.Discard)
+	if w.Written() != 0 || w.Compressed() != 0 {
+		t.Errorf("counters after Reset = %d/%d, want 0/0", w.Written(), w.Compressed())
+	}
+}
+
+// TestReaderStoredBlocks tests reading stored blocks, which are read
+// straight into large enough buffers
+func TestReaderStoredBlocks(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := generateRandomData(5*blockSize + 1000)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{Store: true, BlockSize: blockSize, BlockChecksum: XXH32})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	frame := buf.Bytes()
+
+	// Buffers that hold whole blocks and ones that don't
+	for _, size := range []int{100, blockSize - 1, blockSize, 3*blockSize + 7, len(data) + 1} {
+		r := NewReader(bytes.NewReader(frame))
+		var got []byte
+		p := make([]byte, size)
+		for {
+			n, err := r.Read(p)
+			got = append(got, p[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Read(%d) error = %v", size, err)
+			}
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Read(%d) returned %d bytes, want %d", size, len(got), len(data))
+		}
+		if r.Produced() != uint64(len(data)) {
+			t.Errorf("Read(%d) Produced() = %d, want %d", size, r.Produced(), len(data))
+		}
+	}
+
+	// Checksums are still verified
+	corrupt := bytes.Clone(frame)
+	corrupt[len(corrupt)/2] ^= 1
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(corrupt))); err != ErrBlockChecksum {
+		t.Errorf("ReadAll() of a corrupt frame error = %v, want %v", err, ErrBlockChecksum)
+	}
+}
+
+// TestReaderInterleavedReads tests that mixing one-byte reads with larger
+// reads and skips never drops or repeats data at block boundaries
+func TestReaderInterleavedReads(t *testing.T) {
+	stream, want := smallBlockStream(t)
+	sizes := []int{1, 7, 1, 1, 64*1024 - 3, 1, 3000, 1}
+
+	r := NewReader(bytes.NewReader(stream))
+	pos := 0
+	for step := 0; pos < len(want); step++ {
+		if step%5 == 4 {
+			n, err := r.Skip(13)
+			if err != nil && err != io.EOF {
+				t.Fatalf("Skip() at %d error = %v", pos, err)
+			}
+			pos += int(n)
+			continue
+		}
+
+		p := make([]byte, sizes[step%len(sizes)])
+		n, err := io.ReadFull(r, p)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("ReadFull() at %d error = %v", pos, err)
+		}
+		if !bytes.Equal(p[:n], want[pos:pos+n]) {
+			t.Fatalf("ReadFull() of %d bytes at %d returned the wrong data", len(p), pos)
+		}
+		pos += n
+		if err != nil {
+			break
+		}
+	}
+	if pos != len(want) {
+		t.Errorf("read and skipped %d bytes, want %d", pos, len(want))
+	}
+	if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Read() at the end = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+}
+
+// TestReaderStreamedStoredBlocks tests stored blocks without block
+// checksums, which are streamed into buffers too small to hold them
+func TestReaderStreamedStoredBlocks(t *testing.T) {
+	const blockSize = 64 * 1024
+	data := generateRandomData(3*blockSize + 1000)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{Store: true, BlockSize: blockSize, ContentChecksum: true})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	frame := buf.Bytes()
+
+	// Start each read mid-block, then finish with other consumers
+	p := make([]byte, 1000)
+	for _, finish := range []string{"Read", "Skip", "WriteTo", "Chunks"} {
+		r := NewReader(bytes.NewReader(frame))
+		if _, err := io.ReadFull(r, p); err != nil {
+			t.Fatalf("%s: ReadFull() error = %v", finish, err)
+		}
+		got := bytes.Clone(p)
+
+		var rest bytes.Buffer
+		var err error
+		switch finish {
+		case "Read":
+			_, err = io.CopyBuffer(&rest, struct{ io.Reader }{r}, make([]byte, 777))
+		case "Skip":
+			if _, err = r.Skip(blockSize); err == nil {
+				got = append(got, data[len(got):len(got)+blockSize]...)
+				_, err = io.Copy(&rest, r)
+			}
+		case "WriteTo":
+			_, err = r.WriteTo(&rest)
+		case "Chunks":
+			for chunk, cerr := range r.Chunks() {
+				if cerr != nil {
+					err = cerr
+					break
+				}
+				rest.Write(chunk)
+			}
+		}
+		if err != nil {
+			t.Fatalf("%s: error = %v", finish, err)
+		}
+		got = append(got, rest.Bytes()...)
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: got %d bytes, want %d", finish, len(got), len(data))
+		}
+	}
+
+	// The content checksum covers the streamed data
+	corrupt := bytes.Clone(frame)
+	corrupt[len(corrupt)/2] ^= 1
+	_, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{NewReader(bytes.NewReader(corrupt))}, p)
+	if err != ErrContentChecksum {
+		t.Errorf("reading a corrupt frame error = %v, want %v", err, ErrContentChecksum)
+	}
+
+	// A cut within a streamed block is reported as such
+	_, err = io.CopyBuffer(io.Discard, struct{ io.Reader }{NewReader(bytes.NewReader(frame[:blockSize]))}, p)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated frame error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
@@ -0,0 +1,258 @@
+package compress
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrNoFrames indicates a stream that ends before its first frame
+	ErrNoFrames = errors.New("stream holds no LZ4 frames")
+	// ErrSourceRead wraps the errors TestStream gets from its source, other
+	// than the end of the stream
+	ErrSourceRead = errors.New("read error")
+)
+
+// Exit codes of the lz4 command line tool, for the classes of error it
+// reports when decompressing or testing a file
+const (
+	ExitOK            = 0
+	ExitFailure       = 1
+	ExitDecompression = 66
+	ExitReadError     = 67
+	ExitUnfinished    = 68
+)
+
+// ErrorClass groups the errors of reading a stream the way the lz4 command
+// line tool does, so that a tool built on GoZ4X can exit with the same codes
+type ErrorClass int
+
+const (
+	// ClassNone is the class of a nil error
+	ClassNone ErrorClass = iota
+	// ClassFormat is a stream that isn't LZ4, or has a frame header that
+	// can't be read
+	ClassFormat
+	// ClassCorrupt is a frame whose blocks don't decode, or don't match
+	// their checksums or declared size
+	ClassCorrupt
+	// ClassTruncated is a stream that ends inside a frame
+	ClassTruncated
+	// ClassRead is an error from the source, seen by TestStream
+	ClassRead
+)
+
+// String returns the name of the class
+func (c ErrorClass) String() string {
+	switch c {
+	case ClassNone:
+		return "none"
+	case ClassFormat:
+		return "format"
+	case ClassCorrupt:
+		return "corrupt"
+	case ClassTruncated:
+		return "truncated"
+	case ClassRead:
+		return "read"
+	}
+	return fmt.Sprintf("ErrorClass(%d)", int(c))
+}
+
+// ExitCode returns the exit code lz4 uses for errors of the class: 1 for
+// unrecognized input, 66 for a decompression error, 67 for a read error and
+// 68 for an unfinished stream
+func (c ErrorClass) ExitCode() int {
+	switch c {
+	case ClassNone:
+		return ExitOK
+	case ClassCorrupt:
+		return ExitDecompression
+	case ClassRead:
+		return ExitReadError
+	case ClassTruncated:
+		return ExitUnfinished
+	}
+	return ExitFailure
+}
+
+// Classify returns the class of an error returned while reading a stream.
+// Errors it doesn't recognize, such as those of decoding a block, are
+// taken for corrupt data.
+func Classify(err error) ErrorClass {
+	switch {
+	case err == nil:
+		return ClassNone
+	case errors.Is(err, ErrSourceRead):
+		return ClassRead
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ClassTruncated
+	case errors.Is(err, ErrInvalidMagic), errors.Is(err, ErrInvalidFrame),
+		errors.Is(err, ErrNoFrames), errors.Is(err, ErrUnsupportedVersion),
+		errors.Is(err, ErrReservedBits), errors.Is(err, ErrInvalidBlockSizeCode),
+		errors.Is(err, ErrUnknownDictionary):
+		return ClassFormat
+	}
+	return ClassCorrupt
+}
+
+// ChecksumStatus reports what TestStream found of one kind of checksum
+type ChecksumStatus int
+
+const (
+	// ChecksumAbsent means no frame carried the checksum
+	ChecksumAbsent ChecksumStatus = iota
+	// ChecksumValid means every checksum read matched
+	ChecksumValid
+	// ChecksumInvalid means a checksum didn't match, which stopped the test
+	ChecksumInvalid
+)
+
+// String returns the name of the status
+func (s ChecksumStatus) String() string {
+	switch s {
+	case ChecksumAbsent:
+		return "absent"
+	case ChecksumValid:
+		return "valid"
+	case ChecksumInvalid:
+		return "invalid"
+	}
+	return fmt.Sprintf("ChecksumStatus(%d)", int(s))
+}
+
+// StreamReport describes a stream checked by TestStream. After an error it
+// covers the part of the stream before it.
+type StreamReport struct {
+	// Frames and Blocks count the frames and data blocks fully verified
+	Frames int
+	Blocks int
+	// Compressed is the number of bytes read from the source
+	Compressed uint64
+	// Uncompressed is the number of bytes the frames decode to, including
+	// the zeros of sparse holes
+	Uncompressed uint64
+	// BlockChecksums and ContentChecksums report the checksums verified
+	BlockChecksums   ChecksumStatus
+	ContentChecksums ChecksumStatus
+}
+
+// TestStream decodes every frame of src without returning the data, like
+// lz4 -t, verifying block and content checksums and declared content sizes,
+// which Reader doesn't check. Options set the checksum algorithm,
+// dictionaries and header mode as for a Reader, but checksums are always
+// verified, whatever VerifyChecksums says. Pass the error to Classify
+// for the class, and exit code, lz4 would report it with.
+func TestStream(src io.Reader, options ReaderOptions) (StreamReport, error) {
+	r := NewReaderWithOptions(sourceReader{src}, options)
+	r.verify = ChecksumVerify
+	defer r.Close()
+
+	var report StreamReport
+	err := r.testFrames(&report)
+	report.Compressed = r.Consumed()
+	return report, err
+}
+
+// sourceReader wraps the errors of r in ErrSourceRead, so that they can be
+// told from those of the data
+type sourceReader struct {
+	r io.Reader
+}
+
+func (sr sourceReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", ErrSourceRead, err)
+	}
+	return n, err
+}
+
+// testFrames verifies the frames of the stream up to its end
+func (r *Reader) testFrames(report *StreamReport) error {
+	for {
+		magic, err := r.readMagic()
+		report.Uncompressed += r.holeLeft
+		r.holeLeft = 0
+		if err == io.EOF {
+			if report.Frames == 0 {
+				return ErrNoFrames
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if magic != frameMagic {
+			return ErrInvalidMagic
+		}
+
+		r.header = frameHeader{}
+		if err := r.readFrameDescriptor(); err != nil {
+			return err
+		}
+		if err := r.setBlockSize(); err != nil {
+			return err
+		}
+		if err := r.testFrame(report); err != nil {
+			return err
+		}
+		report.Frames++
+	}
+}
+
+// testFrame verifies the blocks of a frame whose header has been read, its
+// declared content size and its content checksum
+func (r *Reader) testFrame(report *StreamReport) error {
+	var size uint64
+	for {
+		if _, err := io.ReadFull(r.r, r.word[:]); err != nil {
+			return truncated("block size", err)
+		}
+		word := binary.LittleEndian.Uint32(r.word[:])
+		if word == 0 {
+			break
+		}
+		if word&0x7FFFFFFF > uint32(r.blocksizeCache) {
+			return errors.New("block size too large")
+		}
+		if err := r.limitBlock(word); err != nil {
+			return err
+		}
+
+		data, err := r.readBlockData(word)
+		if err == ErrBlockChecksum {
+			report.BlockChecksums = ChecksumInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.decodeBlock(data, word&0x80000000 == 0); err != nil {
+			return err
+		}
+		if r.header.blockChecksum {
+			report.BlockChecksums = ChecksumValid
+		}
+		size += uint64(len(r.out.unread()))
+		report.Uncompressed += uint64(len(r.out.unread()))
+		report.Blocks++
+	}
+
+	if r.header.contentSize && size != r.header.contentSizeValue {
+		return ErrContentSizeMismatch
+	}
+	if !r.header.contentChecksum {
+		return nil
+	}
+	if _, err := io.ReadFull(r.r, r.word[:]); err != nil {
+		return truncated("content checksum", err)
+	}
+	if r.content.Sum32() != binary.LittleEndian.Uint32(r.word[:]) {
+		report.ContentChecksums = ChecksumInvalid
+		return ErrContentChecksum
+	}
+	report.ContentChecksums = ChecksumValid
+	return nil
+}
@@ -0,0 +1,143 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// TestTestStream tests the reports and error classes of TestStream on valid,
+// corrupt and truncated streams
+func TestTestStream(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+	frame := checksummedFrame(t, data)
+	plain := compressFrame(t, data, nil)
+	// Both frames hold a single block of the default 4MB block size
+	full := StreamReport{Frames: 1, Blocks: 1, Compressed: uint64(len(frame)), Uncompressed: uint64(len(data)),
+		BlockChecksums: ChecksumValid, ContentChecksums: ChecksumValid}
+
+	corrupt := func(b []byte, off int) []byte {
+		b = bytes.Clone(b)
+		b[off] ^= 0xFF
+		return b
+	}
+	badSize := resealHeader(corrupt(frame, 7)) // The second byte of the content size
+	badContent := corrupt(frame, len(frame)-1)
+	badBlock := corrupt(frame, len(frame)-9) // The block checksum
+
+	tests := []struct {
+		name   string
+		stream []byte
+		want   StreamReport
+		err    error
+		class  ErrorClass
+	}{
+		{"Valid", frame, full, nil, ClassNone},
+		{"Concatenated", append(bytes.Clone(frame), plain...), StreamReport{Frames: 2, Blocks: 2,
+			Compressed: uint64(len(frame) + len(plain)), Uncompressed: 2 * uint64(len(data)),
+			BlockChecksums: ChecksumValid, ContentChecksums: ChecksumValid}, nil, ClassNone},
+		{"Empty", nil, StreamReport{}, ErrNoFrames, ClassFormat},
+		{"Not LZ4", []byte("not an lz4 stream"), StreamReport{Compressed: 4}, ErrInvalidMagic, ClassFormat},
+		{"Trailing Data", append(bytes.Clone(frame), "trailing"...), StreamReport{Frames: 1, Blocks: 1,
+			Compressed: uint64(len(frame) + 4), Uncompressed: uint64(len(data)),
+			BlockChecksums: ChecksumValid, ContentChecksums: ChecksumValid}, ErrInvalidMagic, ClassFormat},
+		{"Truncated", frame[:len(frame)-2], StreamReport{Blocks: 1, Compressed: uint64(len(frame) - 2),
+			Uncompressed: uint64(len(data)), BlockChecksums: ChecksumValid}, io.ErrUnexpectedEOF, ClassTruncated},
+		{"Content Checksum", badContent, StreamReport{Blocks: 1, Compressed: uint64(len(frame)),
+			Uncompressed: uint64(len(data)), BlockChecksums: ChecksumValid, ContentChecksums: ChecksumInvalid},
+			ErrContentChecksum, ClassCorrupt},
+		{"Block Checksum", badBlock, StreamReport{Compressed: uint64(len(frame) - 8),
+			BlockChecksums: ChecksumInvalid}, ErrBlockChecksum, ClassCorrupt},
+		{"Content Size", badSize, StreamReport{Blocks: 1, Compressed: uint64(len(frame) - 4),
+			Uncompressed: uint64(len(data)), BlockChecksums: ChecksumValid}, ErrContentSizeMismatch, ClassCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := TestStream(bytes.NewReader(tt.stream), ReaderOptions{})
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("TestStream() error = %v, want %v", err, tt.err)
+			}
+			if report != tt.want {
+				t.Errorf("TestStream() = %+v, want %+v", report, tt.want)
+			}
+			if got := Classify(err); got != tt.class {
+				t.Errorf("Classify(%v) = %v, want %v", err, got, tt.class)
+			}
+		})
+	}
+}
+
+// failingReader returns its data and then err
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+// TestTestStreamReadError tests that errors from the source are classed as
+// read errors, even when they look like a truncation
+func TestTestStreamReadError(t *testing.T) {
+	frame := checksummedFrame(t, generateCompressibleData(10*1024))
+
+	for _, srcErr := range []error{errors.New("disk on fire"), io.ErrUnexpectedEOF} {
+		_, err := TestStream(&failingReader{data: frame[:20], err: srcErr}, ReaderOptions{})
+		if !errors.Is(err, ErrSourceRead) || !errors.Is(err, srcErr) {
+			t.Errorf("TestStream() error = %v, want %v wrapped in ErrSourceRead", err, srcErr)
+		}
+		if got := Classify(err); got != ClassRead || got.ExitCode() != ExitReadError {
+			t.Errorf("Classify(%v) = %v, want %v", err, got, ClassRead)
+		}
+	}
+}
+
+// TestErrorClassExitCode tests the lz4 exit codes of the error classes
+func TestErrorClassExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{ErrInvalidMagic, ExitFailure},
+		{ErrUnsupportedVersion, ExitFailure},
+		{ErrUnknownDictionary, ExitFailure},
+		{ErrCorruptBlock, ExitDecompression},
+		{ErrBlockChecksum, ExitDecompression},
+		{errors.New("invalid block: unexpected end of input"), ExitDecompression},
+		{fmt.Errorf("truncated block body: %w", io.ErrUnexpectedEOF), ExitUnfinished},
+		{fmt.Errorf("%w: %w", ErrSourceRead, errors.New("EIO")), ExitReadError},
+	}
+
+	for _, tt := range tests {
+		if got := Classify(tt.err).ExitCode(); got != tt.want {
+			t.Errorf("Classify(%v).ExitCode() = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestTestStreamDictionary tests that TestStream uses the dictionaries of
+// its options
+func TestTestStreamDictionary(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	store.Publish(dictionaryFrom(generateRecords(200, 0)))
+	data := dictionaryFrom(generateRecords(2000, 100000))
+	frame := writeDictFrame(t, data, store)
+
+	if _, err := TestStream(bytes.NewReader(frame), ReaderOptions{}); !errors.Is(err, ErrUnknownDictionary) {
+		t.Errorf("TestStream() without dictionaries error = %v, want %v", err, ErrUnknownDictionary)
+	}
+	report, err := TestStream(bytes.NewReader(frame), ReaderOptions{Dictionaries: store})
+	if err != nil || report.Uncompressed != uint64(len(data)) {
+		t.Errorf("TestStream() = %+v, %v, want %d bytes", report, err, len(data))
+	}
+}
@@ -0,0 +1,63 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+)
+
+// TraceEvent describes one sequence of a block for debug tracing
+type TraceEvent struct {
+	// Block is the index of the block within the stream
+	Block int
+	// Stored is set for blocks written uncompressed, which are reported as a
+	// single literal run
+	Stored bool
+	// RawPos is the offset of the sequence's literals in the uncompressed block
+	RawPos int
+	Sequence
+}
+
+// TraceFunc receives the sequences of every block a Writer or Reader processes
+type TraceFunc func(TraceEvent)
+
+// TraceWriter returns a TraceFunc that writes one line per sequence to w.
+// Write errors are ignored.
+func TraceWriter(w io.Writer) TraceFunc {
+	return func(ev TraceEvent) {
+		if ev.Stored {
+			fmt.Fprintf(w, "block=%d stored literals=%d\n", ev.Block, len(ev.Literals))
+			return
+		}
+		fmt.Fprintf(w, "block=%d pos=%d raw=%d literals=%d offset=%d match=%d\n",
+			ev.Block, ev.Pos, ev.RawPos, len(ev.Literals), ev.Offset, ev.MatchLen)
+	}
+}
+
+// TraceBlock reports every sequence of a compressed block to fn.
+// Sequences before a corruption are reported before ErrCorruptBlock is returned.
+func TraceBlock(block []byte, fn TraceFunc) error {
+	return traceBlock(0, block, fn)
+}
+
+// traceBlock reports the sequences of a compressed block with the given index
+func traceBlock(index int, block []byte, fn TraceFunc) error {
+	sr := NewSequenceReader(block)
+	rawPos := 0
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fn(TraceEvent{Block: index, RawPos: rawPos, Sequence: seq})
+		rawPos += len(seq.Literals) + seq.MatchLen
+	}
+}
+
+// traceStored reports an uncompressed block as a single literal run
+func traceStored(index int, data []byte, fn TraceFunc) {
+	fn(TraceEvent{Block: index, Stored: true, Sequence: Sequence{Literals: data}})
+}
@@ -0,0 +1,131 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// TestDebugTrace tests that Writer and Reader report the same sequences
+func TestDebugTrace(t *testing.T) {
+	data := append(generateCompressibleData(100*1024), []byte("tail")...)
+
+	var written []TraceEvent
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{
+		BlockSize:  64 * 1024,
+		DebugTrace: func(ev TraceEvent) { written = append(written, ev) },
+	})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// The sequences of each block cover its uncompressed data exactly
+	rawSizes := map[int]int{}
+	for _, ev := range written {
+		if ev.RawPos != rawSizes[ev.Block] {
+			t.Fatalf("block %d sequence at raw position %d, want %d", ev.Block, ev.RawPos, rawSizes[ev.Block])
+		}
+		rawSizes[ev.Block] += len(ev.Literals) + ev.MatchLen
+	}
+	if len(rawSizes) != 2 || rawSizes[0] != 64*1024 || rawSizes[1] != len(data)-64*1024 {
+		t.Errorf("traced block sizes = %v, want [%d %d]", rawSizes, 64*1024, len(data)-64*1024)
+	}
+
+	var read []TraceEvent
+	r := NewReaderWithOptions(&buf, ReaderOptions{
+		DebugTrace: func(ev TraceEvent) { read = append(read, ev) },
+	})
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+
+	if len(read) != len(written) {
+		t.Fatalf("Reader traced %d sequences, Writer traced %d", len(read), len(written))
+	}
+	for i := range read {
+		if read[i].Block != written[i].Block || read[i].Offset != written[i].Offset ||
+			read[i].MatchLen != written[i].MatchLen || !bytes.Equal(read[i].Literals, written[i].Literals) {
+			t.Fatalf("sequence %d differs between Reader and Writer", i)
+		}
+	}
+}
+
+// TestTraceBlock tests tracing standalone and corrupt blocks
+func TestTraceBlock(t *testing.T) {
+	block, err := CompressBlock(generateCompressibleData(8192), nil)
+	if err != nil {
+		t.Fatalf("CompressBlock() error = %v", err)
+	}
+
+	var out strings.Builder
+	if err := TraceBlock(block, TraceWriter(&out)); err != nil {
+		t.Fatalf("TraceBlock() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "block=0 pos=0 raw=0 ") {
+		t.Errorf("TraceWriter output starts with %q", strings.SplitN(out.String(), "\n", 2)[0])
+	}
+
+	// A truncated block reports its valid prefix before failing
+	corrupt := appendSequence(nil, []byte("abcd"), 4, 12)
+	corrupt = appendSequence(corrupt, []byte("final literals"), 0, 0)
+
+	events := 0
+	err = TraceBlock(corrupt[:len(corrupt)-1], func(TraceEvent) { events++ })
+	if err != ErrCorruptBlock {
+		t.Errorf("TraceBlock() on truncated block: error = %v, want %v", err, ErrCorruptBlock)
+	}
+	if events != 1 {
+		t.Errorf("TraceBlock() on truncated block reported %d sequences, want 1", events)
+	}
+}
+
+// TestReaderValidateSequences tests that the frames of every level pass the
+// ValidateSequences checks, and that a block the plain Reader decodes fails
+// them at the sequence breaking a rule
+func TestReaderValidateSequences(t *testing.T) {
+	for _, kind := range testgen.Corpus {
+		data := kind.Generate(testgen.DefaultSeed, 200<<10)
+		for _, level := range []CompressionLevel{1, 2, 3, DefaultLevel, MaxLevel} {
+			frame, err := EncodeFrame(data, WriterOptions{Level: level, BlockSize: 64 << 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{ValidateSequences: true})
+			got, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("%s level %d: ReadAll() = %d bytes, %v", kind.Name, level, len(got), err)
+			}
+		}
+	}
+
+	// The second sequence matches 11 bytes from the end of the block
+	blk := appendSequence(nil, []byte("abcdefghijklmnop"), 16, 16)
+	blk = appendSequence(blk, []byte("q"), 1, 4)
+	blk = appendSequence(blk, []byte("rstuvwx"), 0, 0)
+	frame := appendFrameHeader(nil, &frameHeader{blockIndependence: true, blockSizeCode: 4})
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(blk)))
+	frame = append(frame, blk...)
+	frame = binary.LittleEndian.AppendUint32(frame, 0)
+
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(frame))); err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	r := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{ValidateSequences: true})
+	_, err := io.ReadAll(r)
+	var se *SequenceError
+	if !errors.As(err, &se) || !errors.Is(err, ErrMatchNearEnd) {
+		t.Fatalf("ReadAll() error = %v, want a SequenceError of %v", err, ErrMatchNearEnd)
+	}
+	if se.Index != 1 || se.Pos != 20 || se.RawPos != 32 {
+		t.Errorf("sequence %d at byte %d, raw %d, want 1 at 20, raw 32", se.Index, se.Pos, se.RawPos)
+	}
+}
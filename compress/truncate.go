@@ -0,0 +1,17 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+)
+
+// truncated reports a stream that ends while part of a frame is being read.
+// A clean or partial EOF becomes io.ErrUnexpectedEOF, wrapped to name the
+// part, so that callers can use errors.Is to tell a cut-off stream, which
+// may be worth retrying, from a corrupt one. Other errors are unchanged.
+func truncated(part string, err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("truncated %s: %w", part, io.ErrUnexpectedEOF)
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+package compress
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// checksummedFrame compresses data into a frame with a content size, block
+// checksums and a content checksum, so that it has every part a frame can
+// be truncated in
+func checksummedFrame(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{
+		Level:           DefaultLevel,
+		ContentChecksum: true,
+		BlockChecksum:   XXH32,
+	})
+	if err := w.SetContentSize(uint64(len(data))); err != nil {
+		t.Fatalf("SetContentSize() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestReaderTruncated tests that a frame cut off anywhere fails with
+// io.ErrUnexpectedEOF naming the part being read
+func TestReaderTruncated(t *testing.T) {
+	compressible := checksummedFrame(t, generateCompressibleData(100*1024))
+	stored := checksummedFrame(t, generateRandomData(10*1024))
+
+	// The header is 4 bytes of magic, 3 of descriptor and 8 of content size
+	const header = 15
+	firstBlock := header + 4 + int(binary.LittleEndian.Uint32(compressible[header:])&0x7FFFFFFF)
+
+	skippable := make([]byte, 8, 16)
+	binary.LittleEndian.PutUint32(skippable[0:4], skippableMagic)
+	binary.LittleEndian.PutUint32(skippable[4:8], 8)
+	skippable = append(skippable, 1, 2, 3)
+
+	tests := []struct {
+		name   string
+		stream []byte
+		part   string
+	}{
+		{"Magic", compressible[:2], "frame header"},
+		{"Descriptor", compressible[:5], "frame header"},
+		{"Content Size", compressible[:10], "frame header"},
+		{"Block Size", compressible[:header+2], "block size"},
+		{"Block Boundary", compressible[:header], "block size"},
+		{"Block Body", compressible[:header+100], "block body"},
+		{"Stored Block Body", stored[:header+100], "block body"},
+		{"Block Checksum", compressible[:firstBlock+2], "block checksum"},
+		{"Next Block", compressible[:firstBlock+4], "block size"},
+		{"End Marker", compressible[:len(compressible)-6], "block size"},
+		{"Content Checksum", compressible[:len(compressible)-2], "content checksum"},
+		{"Skippable Frame", skippable, "skippable frame"},
+		{"Second Frame", append(compressible, stored[:6]...), "frame header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := io.ReadAll(NewReader(bytes.NewReader(tt.stream)))
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("ReadAll() error = %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			if !strings.Contains(err.Error(), tt.part) {
+				t.Errorf("ReadAll() error = %q, want it to name the %s", err, tt.part)
+			}
+		})
+	}
+}
+
+// TestSkipTruncated tests that Skip reports truncation like Read. The
+// source doesn't seek, as seeking past the end of a truncated block only
+// shows up at the next block size.
+func TestSkipTruncated(t *testing.T) {
+	frame := checksummedFrame(t, generateRandomData(10*1024))
+
+	tests := []struct {
+		name   string
+		stream []byte
+		part   string
+	}{
+		{"Block Body", frame[:100], "block body"},
+		{"End Marker", frame[:len(frame)-6], "block size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(io.MultiReader(bytes.NewReader(tt.stream))).Skip(1 << 20)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("Skip() error = %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			if !strings.Contains(err.Error(), tt.part) {
+				t.Errorf("Skip() error = %q, want it to name the %s", err, tt.part)
+			}
+		})
+	}
+}
+
+// TestReaderCleanEnd tests that streams ending between frames end with
+// io.EOF rather than a truncation error
+func TestReaderCleanEnd(t *testing.T) {
+	frame := checksummedFrame(t, []byte("data"))
+
+	for _, stream := range [][]byte{nil, frame, append(frame, frame...)} {
+		if _, err := io.ReadAll(NewReader(bytes.NewReader(stream))); err != nil {
+			t.Errorf("ReadAll() of %d bytes error = %v", len(stream), err)
+		}
+	}
+}
+
+// smallBlockStream returns two frames of 64KB blocks, with compressed and
+// stored blocks, and the data they hold
+func smallBlockStream(t *testing.T) (stream, data []byte) {
+	t.Helper()
+
+	data = append(generateCompressibleData(150*1024), generateRandomData(100*1024)...)
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		w := NewWriterWithOptions(&buf, WriterOptions{Level: DefaultLevel, BlockSize: 64 * 1024, BlockChecksum: XXH32})
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+	return buf.Bytes(), append(data, data...)
+}
+
+// TestReaderSmallReads tests that reads of any size return the same data
+// as ReadAll, from sources returning one byte at a time as well
+func TestReaderSmallReads(t *testing.T) {
+	stream, want := smallBlockStream(t)
+
+	for _, size := range []int{1, 3, 4096, 64*1024 - 1, 64*1024 + 1} {
+		for _, oneByte := range []bool{false, true} {
+			var src io.Reader = bytes.NewReader(stream)
+			if oneByte {
+				src = iotest.OneByteReader(src)
+			}
+			r := NewReader(src)
+			got := make([]byte, 0, len(want))
+			p := make([]byte, size)
+			for {
+				n, err := r.Read(p)
+				if n > len(p) || (err == io.EOF && n != 0) {
+					t.Fatalf("Read() = %d, %v for a buffer of %d", n, err, size)
+				}
+				got = append(got, p[:n]...)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("size %d: Read() error = %v", size, err)
+				}
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("size %d, one byte source %v: read %d bytes, want %d matching", size, oneByte, len(got), len(want))
+			}
+		}
+	}
+
+	if err := iotest.TestReader(NewReader(bytes.NewReader(stream)), want); err != nil {
+		t.Errorf("iotest.TestReader() error = %v", err)
+	}
+}
+
+// TestReaderStickyErrors tests that a Reader returns the data before a
+// failure, then the same error from every later Read and Skip, and io.EOF
+// for good after a clean end
+func TestReaderStickyErrors(t *testing.T) {
+	stream, want := smallBlockStream(t)
+	// Cut the first frame inside its fourth block
+	cut := stream[:len(stream)/2-1000]
+
+	r := NewReader(bytes.NewReader(cut))
+	got, err := io.ReadAll(iotest.OneByteReader(r))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("ReadAll() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if len(got) != 3*64*1024 || !bytes.Equal(got, want[:len(got)]) {
+		t.Errorf("ReadAll() = %d bytes before the error, want the first %d", len(got), 3*64*1024)
+	}
+	for i := 0; i < 2; i++ {
+		if n, err2 := r.Read(make([]byte, 10)); n != 0 || err2 != err {
+			t.Errorf("Read() after the error = %d, %v, want 0, %v", n, err2, err)
+		}
+		if n, err2 := r.Skip(10); n != 0 || err2 != err {
+			t.Errorf("Skip() after the error = %d, %v, want 0, %v", n, err2, err)
+		}
+	}
+
+	r = NewReader(bytes.NewReader(stream))
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+			t.Errorf("Read() after the end = %d, %v, want 0, %v", n, err, io.EOF)
+		}
+	}
+}
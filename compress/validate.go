@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrInvalidSizeHint indicates a negative size hint
+	ErrInvalidSizeHint = errors.New("invalid size hint")
+	// ErrInvalidNumWorkers indicates a negative number of workers
+	ErrInvalidNumWorkers = errors.New("invalid number of workers")
+	// ErrInvalidInFlightLimit indicates a MaxInFlightBytes that is negative
+	// or can't hold a block of MinBlockSize
+	ErrInvalidInFlightLimit = errors.New("invalid in-flight limit")
+	// ErrInvalidMaxExtLen indicates a negative MaxExtLenBytes
+	ErrInvalidMaxExtLen = errors.New("invalid extension length limit")
+)
+
+// validLevel checks a level given alongside the Store option. Without Store
+// the level must be set explicitly, as a zero Level taken for DefaultLevel
+// usually means NoCompression was meant or the level was forgotten.
+func validLevel(level CompressionLevel, store bool) error {
+	if store {
+		if level != NoCompression {
+			return ErrInvalidCompressionLevel
+		}
+		return nil
+	}
+	if level < 1 || level > MaxLevel {
+		return ErrInvalidCompressionLevel
+	}
+	return nil
+}
+
+// validBlockSize checks a block size option, where zero means the default
+func validBlockSize(size int) error {
+	if size < 0 || (size > 0 && size < MinBlockSize) || size > maxBlockSize {
+		return ErrInvalidBlockSize
+	}
+	return nil
+}
+
+// Validate reports the first setting that NewWriterWithOptions would
+// otherwise replace or ignore: a Level outside 1 to MaxLevel (or any Level
+// with Store), a BlockSize outside MinBlockSize to 4MB, an unknown
+// BlockSizeCode, a negative SizeHint or MaxExtLenBytes, or invalid Chunking
+// sizes.
+func (o WriterOptions) Validate() error {
+	if err := validLevel(o.Level, o.Store); err != nil {
+		return err
+	}
+	if err := validBlockSize(o.BlockSize); err != nil {
+		return err
+	}
+	if o.BlockSizeCode != 0 && o.BlockSizeCode.Size() == 0 {
+		return ErrInvalidBlockSizeCode
+	}
+	if o.SizeHint < 0 {
+		return ErrInvalidSizeHint
+	}
+	if o.MaxExtLenBytes < 0 {
+		return ErrInvalidMaxExtLen
+	}
+	if o.Chunking != nil {
+		return o.Chunking.Validate()
+	}
+	return nil
+}
+
+// Validate returns ErrInvalidChunking if the sizes, after zero sizes take
+// their defaults, are out of range or out of order
+func (o ChunkingOptions) Validate() error {
+	_, err := newChunker(o)
+	return err
+}
+
+// Validate reports the first setting that NewParallelWriterWithOptions would
+// otherwise replace: a Level outside 1 to MaxLevel (or any Level with Store),
+// a BlockSize outside MinBlockSize to 4MB, a negative NumWorkers or a
+// MaxInFlightBytes that can't hold a block.
+func (o ParallelWriterOptions) Validate() error {
+	if err := validLevel(o.Level, o.Store); err != nil {
+		return err
+	}
+	if err := validBlockSize(o.BlockSize); err != nil {
+		return err
+	}
+	if o.NumWorkers < 0 {
+		return ErrInvalidNumWorkers
+	}
+	if o.MaxInFlightBytes < 0 || (o.MaxInFlightBytes > 0 && o.MaxInFlightBytes < MinBlockSize) {
+		return ErrInvalidInFlightLimit
+	}
+	return nil
+}
+
+// NewCheckedWriter is NewWriterWithOptions, except that it returns the error
+// of options.Validate instead of falling back to defaults
+func NewCheckedWriter(w io.Writer, options WriterOptions) (*Writer, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewWriterWithOptions(w, options), nil
+}
+
+// NewCheckedWriterLevel is NewWriterLevel, except that it returns
+// ErrInvalidCompressionLevel for levels outside NoCompression to MaxLevel
+// instead of using DefaultLevel
+func NewCheckedWriterLevel(w io.Writer, level CompressionLevel) (*Writer, error) {
+	if level < NoCompression || level > MaxLevel {
+		return nil, ErrInvalidCompressionLevel
+	}
+	return NewWriterLevel(w, level), nil
+}
+
+// NewCheckedParallelWriter is NewParallelWriterWithOptions, except that it
+// returns the error of options.Validate instead of falling back to defaults
+func NewCheckedParallelWriter(w io.Writer, options ParallelWriterOptions) (*ParallelWriter, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewParallelWriterWithOptions(w, options), nil
+}
@@ -0,0 +1,82 @@
+package compress
+
+import (
+	"io"
+	"testing"
+)
+
+// TestWriterOptionsValidate tests that Validate reports settings the
+// constructors would silently replace
+func TestWriterOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts WriterOptions
+		want error
+	}{
+		{"Level", WriterOptions{Level: 9}, nil},
+		{"Store", WriterOptions{Store: true}, nil},
+		{"All", WriterOptions{Level: MaxLevel, BlockSize: 64 * 1024, BlockSizeCode: BlockSize4MB, SizeHint: 1000, Chunking: &ChunkingOptions{}}, nil},
+		{"ZeroLevel", WriterOptions{}, ErrInvalidCompressionLevel},
+		{"HighLevel", WriterOptions{Level: MaxLevel + 1}, ErrInvalidCompressionLevel},
+		{"NegativeLevel", WriterOptions{Level: -1}, ErrInvalidCompressionLevel},
+		{"StoreLevel", WriterOptions{Level: 9, Store: true}, ErrInvalidCompressionLevel},
+		{"NegativeBlockSize", WriterOptions{Level: 9, BlockSize: -1}, ErrInvalidBlockSize},
+		{"SmallBlockSize", WriterOptions{Level: 9, BlockSize: MinBlockSize - 1}, ErrInvalidBlockSize},
+		{"LargeBlockSize", WriterOptions{Level: 9, BlockSize: maxBlockSize + 1}, ErrInvalidBlockSize},
+		{"BlockSizeCode", WriterOptions{Level: 9, BlockSizeCode: 3}, ErrInvalidBlockSizeCode},
+		{"SizeHint", WriterOptions{Level: 9, SizeHint: -1}, ErrInvalidSizeHint},
+		{"MaxExtLenBytes", WriterOptions{Level: 9, MaxExtLenBytes: -1}, ErrInvalidMaxExtLen},
+		{"Chunking", WriterOptions{Level: 9, Chunking: &ChunkingOptions{MinSize: 1 << 20}}, ErrInvalidChunking},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); err != tt.want {
+				t.Errorf("Validate() = %v, want %v", err, tt.want)
+			}
+			w, err := NewCheckedWriter(io.Discard, tt.opts)
+			if err != tt.want || (err == nil) != (w != nil) {
+				t.Errorf("NewCheckedWriter() = %v, %v, want error %v", w, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParallelWriterOptionsValidate tests Validate on ParallelWriterOptions
+func TestParallelWriterOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ParallelWriterOptions
+		want error
+	}{
+		{"Level", ParallelWriterOptions{Level: 9, BlockSize: 64 * 1024, NumWorkers: 4}, nil},
+		{"Store", ParallelWriterOptions{Store: true}, nil},
+		{"ZeroLevel", ParallelWriterOptions{}, ErrInvalidCompressionLevel},
+		{"LargeBlockSize", ParallelWriterOptions{Level: 9, BlockSize: maxBlockSize + 1}, ErrInvalidBlockSize},
+		{"NumWorkers", ParallelWriterOptions{Level: 9, NumWorkers: -1}, ErrInvalidNumWorkers},
+		{"MaxInFlight", ParallelWriterOptions{Level: 9, MaxInFlightBytes: MinBlockSize - 1}, ErrInvalidInFlightLimit},
+	}
+
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); err != tt.want {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+		if _, err := NewCheckedParallelWriter(io.Discard, tt.opts); err != tt.want {
+			t.Errorf("%s: NewCheckedParallelWriter() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestNewCheckedWriterLevel tests that invalid levels aren't replaced
+func TestNewCheckedWriterLevel(t *testing.T) {
+	for _, level := range []CompressionLevel{NoCompression, 1, MaxLevel} {
+		if _, err := NewCheckedWriterLevel(io.Discard, level); err != nil {
+			t.Errorf("NewCheckedWriterLevel(%d) error = %v", level, err)
+		}
+	}
+	for _, level := range []CompressionLevel{-1, MaxLevel + 1} {
+		if _, err := NewCheckedWriterLevel(io.Discard, level); err != ErrInvalidCompressionLevel {
+			t.Errorf("NewCheckedWriterLevel(%d) error = %v, want %v", level, err, ErrInvalidCompressionLevel)
+		}
+	}
+}
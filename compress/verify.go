@@ -0,0 +1,63 @@
+package compress
+
+// ChecksumMode sets how a Reader treats the block and content checksums of
+// the frames it reads
+type ChecksumMode int
+
+const (
+	// ChecksumVerify verifies every checksum, failing the stream with
+	// ErrBlockChecksum or ErrContentChecksum at the first mismatch
+	ChecksumVerify ChecksumMode = iota
+	// ChecksumDeferred verifies every checksum but keeps returning data past
+	// a mismatch, recording the first one for Err and Close to report. It
+	// suits recovery tools that want whatever a damaged stream still holds.
+	ChecksumDeferred
+	// ChecksumIgnore reads checksums without verifying them, saving the
+	// time spent hashing for sources already known to be intact
+	ChecksumIgnore
+)
+
+// Err returns the first checksum mismatch read past in ChecksumDeferred
+// mode, or else the error that stopped the stream, or nil. It is safe to
+// call concurrently with Read.
+func (r *Reader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.checksumErr != nil {
+		return r.checksumErr
+	}
+	return r.err
+}
+
+// checksumMismatch returns err, a checksum mismatch, unless the Reader is in
+// ChecksumDeferred mode, where it records the first one and returns nil
+func (r *Reader) checksumMismatch(err error) error {
+	if r.verify != ChecksumDeferred {
+		return err
+	}
+	if r.checksumErr == nil {
+		r.checksumErr = err
+	}
+	return nil
+}
+
+// hashContent adds decoded data of the frame to its content checksum, if
+// the frame has one and it is verified
+func (r *Reader) hashContent(p []byte) {
+	if r.header.contentChecksum && r.verify != ChecksumIgnore {
+		r.content.Write(p)
+	}
+}
+
+// verifyContent checks the content checksum read at the end of a frame.
+// Frames whose data Skip passed over without decoding can't be checked.
+func (r *Reader) verifyContent(sum uint32) error {
+	if r.verify == ChecksumIgnore || r.contentUnchecked {
+		return nil
+	}
+	if r.content.Sum32() != sum {
+		return r.checksumMismatch(ErrContentChecksum)
+	}
+	return nil
+}
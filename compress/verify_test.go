@@ -0,0 +1,110 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// storedChecksummedFrame returns data, incompressible, as a frame of stored
+// 64KB blocks with block and content checksums
+func storedChecksummedFrame(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{
+		Level:           DefaultLevel,
+		BlockSize:       64 * 1024,
+		ContentChecksum: true,
+		BlockChecksum:   XXH32,
+	})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestReaderChecksumModes tests that mismatched block and content checksums
+// fail the stream, are reported after all the data, or are ignored
+func TestReaderChecksumModes(t *testing.T) {
+	data := generateRandomData(150 * 1024)
+	frame := storedChecksummedFrame(t, data)
+
+	// The header is 4 bytes of magic and 3 of descriptor, and the first
+	// block's data follows its size word
+	const firstBlock = 7 + 4
+	tests := []struct {
+		name   string
+		offset int
+		// wantErr is the error of each mode, and n the bytes read with
+		// ChecksumVerify
+		wantErr [3]error
+		n       int
+	}{
+		{"Intact", -1, [3]error{}, len(data)},
+		{"Block data", firstBlock + 100, [3]error{ErrBlockChecksum, ErrBlockChecksum, nil}, 0},
+		{"Block checksum", firstBlock + 64*1024, [3]error{ErrBlockChecksum, ErrBlockChecksum, nil}, 0},
+		{"Content checksum", len(frame) - 1, [3]error{ErrContentChecksum, ErrContentChecksum, nil}, len(data)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := append([]byte(nil), frame...)
+			want := data
+			if tt.offset >= 0 {
+				corrupt[tt.offset] ^= 0x01
+				if tt.offset < firstBlock+64*1024 {
+					want = append([]byte(nil), data...)
+					want[tt.offset-firstBlock] ^= 0x01
+				}
+			}
+
+			r := NewReaderWithOptions(bytes.NewReader(corrupt), ReaderOptions{})
+			got, err := io.ReadAll(r)
+			if err != tt.wantErr[ChecksumVerify] || len(got) != tt.n {
+				t.Errorf("ChecksumVerify: ReadAll() = %d bytes, %v, want %d, %v", len(got), err, tt.n, tt.wantErr[ChecksumVerify])
+			}
+			if err := r.Err(); err != tt.wantErr[ChecksumVerify] {
+				t.Errorf("ChecksumVerify: Err() = %v, want %v", err, tt.wantErr[ChecksumVerify])
+			}
+			if err := r.Close(); err != nil {
+				t.Errorf("ChecksumVerify: Close() error = %v", err)
+			}
+
+			for _, mode := range []ChecksumMode{ChecksumDeferred, ChecksumIgnore} {
+				r := NewReaderWithOptions(bytes.NewReader(corrupt), ReaderOptions{VerifyChecksums: mode})
+				got, err := io.ReadAll(r)
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("mode %d: ReadAll() = %d bytes, %v, want all %d", mode, len(got), err, len(want))
+				}
+				if err := r.Err(); err != tt.wantErr[mode] {
+					t.Errorf("mode %d: Err() = %v, want %v", mode, err, tt.wantErr[mode])
+				}
+				if err := r.Close(); err != tt.wantErr[mode] {
+					t.Errorf("mode %d: Close() error = %v, want %v", mode, err, tt.wantErr[mode])
+				}
+			}
+		})
+	}
+}
+
+// TestReaderChecksumSkip tests that skipping blocks leaves the content
+// checksum of their frame unchecked rather than failing it
+func TestReaderChecksumSkip(t *testing.T) {
+	data := generateRandomData(200 * 1024)
+	frame := storedChecksummedFrame(t, data)
+
+	for _, skip := range []int64{100, 130 * 1024} {
+		r := NewReader(bytes.NewReader(frame))
+		if _, err := r.Skip(skip); err != nil {
+			t.Fatalf("Skip(%d) error = %v", skip, err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, data[skip:]) {
+			t.Errorf("ReadAll() after Skip(%d) = %d bytes, %v, want %d", skip, len(got), err, len(data)-int(skip))
+		}
+	}
+}
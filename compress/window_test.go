@@ -0,0 +1,224 @@
+package compress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress/block"
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/internal/testgen"
+)
+
+// checkSpecLimits checks that blk, compressed from src after dict, keeps to
+// the limits of the LZ4 block format that liblz4's decoder enforces: offsets
+// from 1 to 65535 reaching no further back than dict, the last 5 bytes as
+// literals and no match starting in the last 12 bytes. It returns the
+// largest offset in blk.
+func checkSpecLimits(t *testing.T, blk, src, dict []byte) int {
+	t.Helper()
+
+	sr := block.NewSequenceReader(blk)
+	pos, maxOffset := 0, 0
+	var last block.Sequence
+	for {
+		seq, err := sr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		pos += len(seq.Literals)
+		if seq.MatchLen > 0 {
+			if seq.Offset < 1 || seq.Offset > MaxDistance || seq.Offset > pos+len(dict) {
+				t.Fatalf("match at %d has offset %d", pos, seq.Offset)
+			}
+			if pos > len(src)-format.MFLimit {
+				t.Errorf("match starts at %d, within %d bytes of the end of %d", pos, format.MFLimit, len(src))
+			}
+			maxOffset = max(maxOffset, seq.Offset)
+		}
+		pos += seq.MatchLen
+		last = seq
+	}
+	if last.MatchLen != 0 || (len(src) >= format.MFLimit && len(last.Literals) < format.LastLiterals) {
+		t.Errorf("block ends with %d literals and a match of %d", len(last.Literals), last.MatchLen)
+	}
+
+	got, err := DecompressBlockDict(blk, dict, len(src))
+	if err != nil || !bytes.Equal(got, src) {
+		t.Fatalf("DecompressBlockDict() = %d bytes, %v, want %d bytes", len(got), err, len(src))
+	}
+	return maxOffset
+}
+
+// windowEdgeData returns random data followed by a copy of its first 8KB
+// starting offset bytes after it, so that the only long matches are at
+// that offset
+func windowEdgeData(offset int) []byte {
+	data := testgen.Random(testgen.DefaultSeed, offset)
+	return append(data, data[:8<<10]...)
+}
+
+// TestMaxOffset tests that the encoders find matches exactly 65535 bytes
+// back, the largest offset, and never emit them from 65536 bytes back
+func TestMaxOffset(t *testing.T) {
+	encoders := []struct {
+		name     string
+		compress func(src []byte) ([]byte, error)
+	}{
+		{"V2", func(src []byte) ([]byte, error) { return CompressBlockV2(src, nil) }},
+	}
+	for level := CompressionLevel(1); level <= MaxLevel; level++ {
+		if paramsForLevel(level).windowSize < MaxDistance {
+			continue
+		}
+		encoders = append(encoders, struct {
+			name     string
+			compress func(src []byte) ([]byte, error)
+		}{level.String(), func(src []byte) ([]byte, error) { return CompressBlockLevel(src, nil, level) }})
+	}
+
+	for _, enc := range encoders {
+		src := windowEdgeData(MaxDistance)
+		blk, err := enc.compress(src)
+		if err != nil {
+			t.Fatalf("%s: compress error = %v", enc.name, err)
+		}
+		if got := checkSpecLimits(t, blk, src, nil); got != MaxDistance {
+			t.Errorf("%s: largest offset = %d, want %d", enc.name, got, MaxDistance)
+		}
+
+		src = windowEdgeData(MaxDistance + 1)
+		if blk, err = enc.compress(src); err != nil {
+			t.Fatalf("%s: compress error = %v", enc.name, err)
+		}
+		checkSpecLimits(t, blk, src, nil)
+		if len(blk) < len(src) {
+			t.Errorf("%s: %d bytes compressed to %d with no match in reach", enc.name, len(src), len(blk))
+		}
+	}
+}
+
+// TestMaxOffsetDependent tests matches reaching the first byte of the
+// history a dictionary or the messages of a session leave in reach, 65535
+// bytes back, across the boundary between the history and the block
+func TestMaxOffsetDependent(t *testing.T) {
+	history := testgen.Random(testgen.DefaultSeed, 100<<10)
+	window := history[len(history)-MaxDistance:]
+
+	for _, level := range []CompressionLevel{6, MaxLevel} {
+		// Starting with the first byte in reach, which matches 65535 back
+		src := window[:8<<10]
+		blk, err := CompressBlockDict(src, nil, history, level)
+		if err != nil {
+			t.Fatalf("CompressBlockDict() error = %v", err)
+		}
+		if got := checkSpecLimits(t, blk, src, window); got != MaxDistance {
+			t.Errorf("level %d: largest offset = %d, want %d", level, got, MaxDistance)
+		}
+
+		// Starting with the byte before it, every copy is 65536 back
+		src = history[len(history)-MaxDistance-1:][:8<<10]
+		if blk, err = CompressBlockDict(src, nil, history, level); err != nil {
+			t.Fatalf("CompressBlockDict() error = %v", err)
+		}
+		checkSpecLimits(t, blk, src, window)
+		if len(blk) < len(src) {
+			t.Errorf("level %d: %d bytes compressed to %d with no match in reach", level, len(src), len(blk))
+		}
+	}
+
+	// The messages of a session keep the last 65535 bytes in reach, which
+	// here start within the first message
+	enc, err := NewEncoderSession(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoderSession()
+	first, second := history[:40<<10], history[40<<10:80<<10]
+	start := len(first) + len(second) - MaxDistance
+	for _, msg := range [][]byte{first, second, first[start:][:8<<10], first[start-1:][:8<<10]} {
+		out, err := enc.Compress(msg, nil)
+		if err != nil {
+			t.Fatalf("Compress() error = %v", err)
+		}
+		got, err := dec.Decompress(out)
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Fatalf("Decompress() = %d bytes, %v, want %d bytes", len(got), err, len(msg))
+		}
+	}
+}
+
+// TestMaxBlockSizeInput tests blocks of exactly MaxBlockSize bytes, and
+// that a byte more is rejected when compressing and decompressing
+func TestMaxBlockSizeInput(t *testing.T) {
+	src := testgen.Text(testgen.DefaultSeed, MaxBlockSize)
+	blk, err := CompressBlockLevel(src, nil, DefaultLevel)
+	if err != nil {
+		t.Fatalf("CompressBlockLevel() error = %v", err)
+	}
+	checkSpecLimits(t, blk, src, nil)
+
+	dst := make([]byte, MaxBlockSize)
+	if n, err := DecompressBlockInto(blk, dst); n != MaxBlockSize || err != nil {
+		t.Errorf("DecompressBlockInto() = %d, %v", n, err)
+	}
+	if got, err := DecompressBlockAlloc(blk, 0); err != nil || len(got) != MaxBlockSize {
+		t.Errorf("DecompressBlockAlloc(maxSize 0) = %d bytes, %v", len(got), err)
+	}
+	stored := storeBlock(src, nil)
+	if got, err := DecompressBlockAlloc(stored, 0); err != nil || len(got) != MaxBlockSize {
+		t.Errorf("DecompressBlockAlloc() of a stored block = %d bytes, %v", len(got), err)
+	}
+
+	over := append(bytes.Clone(src), 'x')
+	if _, err := CompressBlockLevel(over, nil, DefaultLevel); err != ErrInvalidBlockSize {
+		t.Errorf("CompressBlockLevel(%d bytes) error = %v, want %v", len(over), err, ErrInvalidBlockSize)
+	}
+	if _, err := DecompressBlockAlloc(storeBlock(over, nil), 0); err == nil {
+		t.Errorf("DecompressBlockAlloc() of %d bytes succeeded", len(over))
+	}
+
+	// A frame of 4MB blocks holds the input in one block
+	frame, err := EncodeFrame(src, WriterOptions{BlockSize: MaxBlockSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := 0
+	zr := NewReaderWithOptions(bytes.NewReader(frame), ReaderOptions{
+		DebugTrace: func(ev TraceEvent) { blocks = max(blocks, ev.Block+1) },
+	})
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, src) {
+		t.Errorf("Read() = %d bytes, %v", len(got), err)
+	}
+	if blocks != 1 {
+		t.Errorf("frame holds %d blocks, want 1", blocks)
+	}
+}
+
+// TestSpecLimitsCorpus tests the end of block rules on every kind of data
+// and on inputs around the 12 and 13 byte minimum for a match
+func TestSpecLimitsCorpus(t *testing.T) {
+	for _, kind := range testgen.Corpus {
+		for _, size := range []int{16, 17, 18, 100, 64 << 10, 300 << 10} {
+			src := kind.Generate(testgen.DefaultSeed, size)
+			for _, level := range []CompressionLevel{1, DefaultLevel, MaxLevel} {
+				blk, err := CompressBlockLevel(src, nil, level)
+				if err != nil {
+					t.Fatalf("%s(%d): CompressBlockLevel(%d) error = %v", kind.Name, size, level, err)
+				}
+				checkSpecLimits(t, blk, src, nil)
+			}
+			if blk, err := CompressBlockV2(src, nil); !errors.Is(err, ErrInvalidBlockSize) {
+				if err != nil {
+					t.Fatalf("%s(%d): CompressBlockV2() error = %v", kind.Name, size, err)
+				}
+				checkSpecLimits(t, blk, src, nil)
+			}
+		}
+	}
+}
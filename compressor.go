@@ -0,0 +1,14 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// Compressor compresses blocks at a fixed level, reusing its tables between
+// calls so that steady-state compression doesn't allocate. It is not safe
+// for concurrent use.
+type Compressor = compress.Compressor
+
+// NewCompressor returns a Compressor for the given level. Its output is the
+// same as CompressBlockLevel's.
+func NewCompressor(level int) (*Compressor, error) {
+	return compress.NewCompressor(compress.CompressionLevel(level))
+}
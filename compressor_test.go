@@ -0,0 +1,62 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCompressor tests compressing several blocks with one Compressor
+func TestCompressor(t *testing.T) {
+	c, err := NewCompressor(9)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+
+	dst := make([]byte, 64*1024)
+	for i := 0; i < 3; i++ {
+		src := bytes.Repeat([]byte{'a' + byte(i), 'b', 'c', 'd'}, 4096)
+		compressed, err := c.CompressBlock(src, dst)
+		if err != nil {
+			t.Fatalf("CompressBlock() error = %v", err)
+		}
+		want, err := CompressBlockLevel(src, nil, 9)
+		if err != nil {
+			t.Fatalf("CompressBlockLevel() error = %v", err)
+		}
+		if !bytes.Equal(compressed, want) {
+			t.Errorf("CompressBlock() differs from CompressBlockLevel() for block %d", i)
+		}
+
+		got, err := DecompressBlock(compressed, nil, len(src))
+		if err != nil {
+			t.Fatalf("DecompressBlock() error = %v", err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("block %d decompressed to %d bytes, want %d", i, len(got), len(src))
+		}
+	}
+}
+
+// TestCompressorPrewarm tests compressing against a prewarmed dictionary
+func TestCompressorPrewarm(t *testing.T) {
+	dict := generateCompressibleData(64 * 1024)
+	src := append(bytes.Clone(dict[2000:4000]), dict[100:900]...)
+
+	c, err := NewCompressor(9)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	c.Prewarm(dict)
+
+	compressed, err := c.CompressBlockDict(src, nil, dict)
+	if err != nil {
+		t.Fatalf("CompressBlockDict() error = %v", err)
+	}
+	want, err := CompressBlockDict(src, nil, dict, 9)
+	if err != nil {
+		t.Fatalf("CompressBlockDict() error = %v", err)
+	}
+	if !bytes.Equal(compressed, want) {
+		t.Errorf("prewarmed CompressBlockDict() differs")
+	}
+}
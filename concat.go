@@ -0,0 +1,20 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// ConcatFrames merges multiple LZ4 frames into one logical frame written to w.
+// Compressed blocks are copied without recompression, which makes it cheap to
+// compact segments such as rotated log files into a single archive.
+func ConcatFrames(w io.Writer, frames ...io.Reader) error {
+	return compress.ConcatFrames(w, frames...)
+}
+
+// ConcatBlocks merges independently compressed blocks into a single block
+// that decompresses to the concatenation of their contents.
+func ConcatBlocks(blocks ...[]byte) ([]byte, error) {
+	return compress.ConcatBlocks(blocks...)
+}
@@ -0,0 +1,37 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestConcatFrames tests merging frames through the top-level API
+func TestConcatFrames(t *testing.T) {
+	first := generateCompressibleData(64 * 1024)
+	second := generateRandomData(8 * 1024)
+
+	var frames []io.Reader
+	for _, data := range [][]byte{first, second} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		frames = append(frames, &buf)
+	}
+
+	var out bytes.Buffer
+	if err := ConcatFrames(&out, frames...); err != nil {
+		t.Fatalf("ConcatFrames() error = %v", err)
+	}
+
+	got, err := io.ReadAll(NewReader(&out))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, append(first, second...)) {
+		t.Errorf("concatenated frame does not decode to the joined input")
+	}
+}
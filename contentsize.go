@@ -0,0 +1,23 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// SetContentSize declares the total uncompressed size of the stream in the
+// frame header. It must be called before the first Write, and Close fails if
+// a different number of bytes was written.
+func (w *Writer) SetContentSize(size uint64) error {
+	return w.w.SetContentSize(size)
+}
+
+// CompressFile compresses src to dst as a single frame, declaring the content
+// size when src is a file or another io.Seeker whose size can be determined.
+// Holes in sparse files are recorded instead of compressed where the platform
+// can find them; such output is meant for GoZ4X readers and DecompressFile.
+// It returns the number of uncompressed bytes written.
+func CompressFile(dst io.Writer, src io.Reader, opts WriterOptions) (int64, error) {
+	return compress.CompressFile(dst, src, opts)
+}
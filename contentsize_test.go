@@ -0,0 +1,55 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCompressFile tests compressing a file with its content size declared
+func TestCompressFile(t *testing.T) {
+	data := bytes.Repeat([]byte("content size from the file system "), 3000)
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	n, err := CompressFile(&buf, f, WriterOptions{})
+	if err != nil {
+		t.Fatalf("CompressFile() error = %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("CompressFile() = %d, want %d", n, len(data))
+	}
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+}
+
+// TestWriterSetContentSize tests that Close detects a wrong declared size
+func TestWriterSetContentSize(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.SetContentSize(100); err != nil {
+		t.Fatalf("SetContentSize() error = %v", err)
+	}
+	if _, err := w.Write([]byte("short")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("Close() error = nil, want content size mismatch")
+	}
+}
@@ -0,0 +1,73 @@
+// Package otelgoz4x adapts GoZ4X streaming metrics to OpenTelemetry.
+//
+// It lives in its own module so the core library doesn't depend on OpenTelemetry.
+package otelgoz4x
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Instrument names recorded by Recorder
+const (
+	RawSizeName        = "goz4x.block.raw_size"
+	CompressedSizeName = "goz4x.block.compressed_size"
+	DurationName       = "goz4x.block.duration"
+)
+
+// Recorder is a compress.MetricsRecorder that records block sizes and
+// latencies as OpenTelemetry histograms.
+type Recorder struct {
+	rawSize        metric.Int64Histogram
+	compressedSize metric.Int64Histogram
+	duration       metric.Float64Histogram
+	attrs          metric.MeasurementOption
+}
+
+var _ compress.MetricsRecorder = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder whose instruments are created from meter.
+// The given attributes, for example the service or direction of the stream,
+// are attached to every measurement.
+func NewRecorder(meter metric.Meter, attrs ...attribute.KeyValue) (*Recorder, error) {
+	rawSize, err := meter.Int64Histogram(RawSizeName,
+		metric.WithDescription("Uncompressed size of LZ4 blocks"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	compressedSize, err := meter.Int64Histogram(CompressedSizeName,
+		metric.WithDescription("Stored size of LZ4 blocks"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := meter.Float64Histogram(DurationName,
+		metric.WithDescription("Time spent compressing or decompressing LZ4 blocks"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		rawSize:        rawSize,
+		compressedSize: compressedSize,
+		duration:       duration,
+		attrs:          metric.WithAttributeSet(attribute.NewSet(attrs...)),
+	}, nil
+}
+
+// RecordBlock implements compress.MetricsRecorder
+func (r *Recorder) RecordBlock(compressed, raw int, dur time.Duration) {
+	ctx := context.Background()
+	r.rawSize.Record(ctx, int64(raw), r.attrs)
+	r.compressedSize.Record(ctx, int64(compressed), r.attrs)
+	r.duration.Record(ctx, dur.Seconds(), r.attrs)
+}
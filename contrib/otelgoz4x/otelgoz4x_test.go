@@ -0,0 +1,72 @@
+package otelgoz4x
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// TestRecorder tests that a Writer's blocks show up as OpenTelemetry histograms
+func TestRecorder(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	rec, err := NewRecorder(provider.Meter("test"), attribute.String("direction", "compress"))
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	data := bytes.Repeat([]byte("goz4x metrics "), 10000)
+
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{BlockSize: 64 * 1024})
+	w.SetMetricsRecorder(rec)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	sums := map[string]float64{}
+	counts := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch h := m.Data.(type) {
+			case metricdata.Histogram[int64]:
+				for _, dp := range h.DataPoints {
+					sums[m.Name] += float64(dp.Sum)
+					counts[m.Name] += dp.Count
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range h.DataPoints {
+					counts[m.Name] += dp.Count
+				}
+			}
+		}
+	}
+
+	// 140000 bytes in 64KB blocks
+	for _, name := range []string{RawSizeName, CompressedSizeName, DurationName} {
+		if counts[name] != 3 {
+			t.Errorf("%s recorded %d blocks, want 3", name, counts[name])
+		}
+	}
+	if sums[RawSizeName] != float64(len(data)) {
+		t.Errorf("%s sum = %v, want %d", RawSizeName, sums[RawSizeName], len(data))
+	}
+	if sums[CompressedSizeName] >= sums[RawSizeName] {
+		t.Errorf("%s sum = %v, want less than %v", CompressedSizeName, sums[CompressedSizeName], sums[RawSizeName])
+	}
+}
@@ -0,0 +1,21 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Compress compresses everything read from src to dst as a single frame at
+// the given level, handling the Writer's Close. If reading or writing fails
+// the frame is left unfinished. It returns the number of uncompressed bytes
+// read, and an error for levels outside 0 to 12.
+func Compress(dst io.Writer, src io.Reader, level int) (int64, error) {
+	return compress.Compress(dst, src, compress.CompressionLevel(level))
+}
+
+// Decompress writes the decompressed contents of the LZ4 stream read from src
+// to dst. It returns the number of decompressed bytes written.
+func Decompress(dst io.Writer, src io.Reader) (int64, error) {
+	return compress.Decompress(dst, src)
+}
@@ -0,0 +1,28 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestCompressDecompress tests the streaming helpers
+func TestCompressDecompress(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	var frame bytes.Buffer
+	if n, err := Compress(&frame, bytes.NewReader(data), 9); err != nil || n != int64(len(data)) {
+		t.Fatalf("Compress() = %d, %v, want %d, nil", n, err, len(data))
+	}
+
+	var out bytes.Buffer
+	if n, err := Decompress(&out, &frame); err != nil || n != int64(len(data)) {
+		t.Fatalf("Decompress() = %d, %v, want %d, nil", n, err, len(data))
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("Decompress() returned the wrong data")
+	}
+
+	if _, err := Compress(&frame, bytes.NewReader(data), 13); err == nil {
+		t.Errorf("Compress() at level 13 succeeded")
+	}
+}
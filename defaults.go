@@ -0,0 +1,18 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// SetDefaultLevel sets the compression level used by constructors that take
+// none, such as NewWriter, NewParallelWriter and NewEncoder, and by options
+// whose Level is zero. It starts at 6, or the GOZ4X_LEVEL environment
+// variable if set. Levels outside 0 to 12 are rejected.
+func SetDefaultLevel(level int) error {
+	return compress.SetDefaultLevel(compress.CompressionLevel(level))
+}
+
+// SetDefaultWorkers sets the number of workers parallel writers use when
+// given none. It starts at 0, meaning GOMAXPROCS, or the GOZ4X_WORKERS
+// environment variable if set. Negative counts are rejected.
+func SetDefaultWorkers(n int) error {
+	return compress.SetDefaultWorkers(n)
+}
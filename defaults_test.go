@@ -0,0 +1,44 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestSetDefaultLevel tests changing the level of NewWriter
+func TestSetDefaultLevel(t *testing.T) {
+	defer SetDefaultLevel(6)
+
+	if err := SetDefaultLevel(13); err == nil {
+		t.Errorf("SetDefaultLevel(13) succeeded")
+	}
+	if err := SetDefaultWorkers(-1); err == nil {
+		t.Errorf("SetDefaultWorkers(-1) succeeded")
+	}
+
+	data := generateCompressibleData(100 * 1024)
+	sizes := make(map[int]int)
+	for _, level := range []int{0, 12} {
+		if err := SetDefaultLevel(level); err != nil {
+			t.Fatalf("SetDefaultLevel(%d) error: %v", level, err)
+		}
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close error: %v", err)
+		}
+		sizes[level] = buf.Len()
+
+		got, err := io.ReadAll(NewReader(&buf))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("level %d: ReadAll returned %d bytes, %v", level, len(got), err)
+		}
+	}
+	if sizes[0] <= len(data) || sizes[12] >= len(data) {
+		t.Errorf("default level 0 wrote %d bytes and 12 wrote %d, want stored and compressed %d bytes", sizes[0], sizes[12], len(data))
+	}
+}
@@ -0,0 +1,16 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// CompressDelta encodes src as a delta against reference, with matches
+// allowed anywhere in reference, so that holders of reference can rebuild
+// src from a small patch.
+func CompressDelta(src, reference []byte) ([]byte, error) {
+	return compress.CompressDelta(src, reference)
+}
+
+// DecompressDelta restores the data a delta was made from, given the same
+// reference. It fails if reference isn't the one the delta was made against.
+func DecompressDelta(delta, reference []byte) ([]byte, error) {
+	return compress.DecompressDelta(delta, reference)
+}
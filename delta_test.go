@@ -0,0 +1,29 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDelta tests patching one version of an artifact into the next
+func TestDelta(t *testing.T) {
+	v1 := bytes.Repeat([]byte("version one of a build artifact\n"), 4000)
+	v2 := append(bytes.Clone(v1[:50000]), []byte("a changed line\n")...)
+	v2 = append(v2, v1[50000:]...)
+
+	delta, err := CompressDelta(v2, v1)
+	if err != nil {
+		t.Fatalf("CompressDelta() error = %v", err)
+	}
+	if len(delta) > 256 {
+		t.Errorf("delta is %d bytes, want at most 256", len(delta))
+	}
+
+	got, err := DecompressDelta(delta, v1)
+	if err != nil {
+		t.Fatalf("DecompressDelta() error = %v", err)
+	}
+	if !bytes.Equal(got, v2) {
+		t.Errorf("restored %d bytes, want %d", len(got), len(v2))
+	}
+}
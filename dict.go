@@ -0,0 +1,40 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// Dictionary is a dictionary together with the ID frames refer to it by.
+type Dictionary = compress.Dictionary
+
+// DictionaryStore holds dictionaries by ID for writers and readers. Set it
+// in WriterOptions and ReaderOptions.
+type DictionaryStore = compress.DictionaryStore
+
+// DictionaryResolver looks up the dictionary a frame names by its ID. Set it
+// as ReaderOptions.ResolveDictionary to read frames from other tools.
+type DictionaryResolver = compress.DictionaryResolver
+
+// ErrDictionaryRequired is returned when reading a frame compressed with a
+// dictionary that neither the Reader's DictionaryStore nor its
+// ResolveDictionary provides.
+var ErrDictionaryRequired = compress.ErrDictionaryRequired
+
+// MemoryDictionaryStore is an in-memory DictionaryStore with versioned IDs.
+type MemoryDictionaryStore = compress.MemoryDictionaryStore
+
+// NewMemoryDictionaryStore returns an empty MemoryDictionaryStore. Publish
+// retrained dictionaries to it to switch new frames over to them.
+func NewMemoryDictionaryStore() *MemoryDictionaryStore {
+	return compress.NewMemoryDictionaryStore()
+}
+
+// CompressBlockDict compresses a block at the given level with matches
+// allowed to reference the last 64KB of dict.
+func CompressBlockDict(src, dst, dict []byte, level int) ([]byte, error) {
+	return compress.CompressBlockDict(src, dst, dict, compress.CompressionLevel(level))
+}
+
+// DecompressBlockDict decompresses a block compressed with CompressBlockDict
+// and the same dict.
+func DecompressBlockDict(src, dict []byte, maxSize int) ([]byte, error) {
+	return compress.DecompressBlockDict(src, dict, maxSize)
+}
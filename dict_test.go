@@ -0,0 +1,53 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestDictionaryStore tests frames compressed with a published dictionary
+func TestDictionaryStore(t *testing.T) {
+	store := NewMemoryDictionaryStore()
+	id := store.Publish(bytes.Repeat([]byte(`{"event":"login","status":"ok"}`), 100))
+
+	data := []byte(`{"event":"login","status":"ok","user":42}`)
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{Dictionaries: store})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := io.ReadAll(NewReaderWithOptions(bytes.NewReader(buf.Bytes()), ReaderOptions{Dictionaries: store}))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("ReadAll() = %q, want %q", got, data)
+	}
+
+	if d, ok := store.Current(); !ok || d.ID != id {
+		t.Errorf("Current() = %d, %v, want %d", d.ID, ok, id)
+	}
+}
+
+// TestBlockDict tests block compression with a dictionary
+func TestBlockDict(t *testing.T) {
+	dict := bytes.Repeat([]byte("common prefix of every message "), 50)
+	src := []byte("common prefix of every message and a unique tail")
+
+	compressed, err := CompressBlockDict(src, nil, dict, 9)
+	if err != nil {
+		t.Fatalf("CompressBlockDict() error = %v", err)
+	}
+	got, err := DecompressBlockDict(compressed, dict, len(src))
+	if err != nil {
+		t.Fatalf("DecompressBlockDict() error = %v", err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Errorf("DecompressBlockDict() = %q, want %q", got, src)
+	}
+}
@@ -29,6 +29,8 @@ var (
 	threads     int
 	showVersion bool
 	useV2       bool
+	writeStats  bool
+	showInfo    bool
 )
 
 func init() {
@@ -39,6 +41,8 @@ func init() {
 	flag.IntVar(&threads, "t", runtime.GOMAXPROCS(0), "Number of threads")
 	flag.BoolVar(&showVersion, "v", false, "Show version information")
 	flag.BoolVar(&useV2, "v2", false, "Use v0.2 compression algorithm (better compression)")
+	flag.BoolVar(&writeStats, "stats", false, "Append a stats trailer when compressing")
+	flag.BoolVar(&showInfo, "i", false, "Show the stats trailer of a compressed file")
 
 	// Custom usage output
 	flag.Usage = func() {
@@ -70,6 +74,15 @@ func main() {
 	// Get input file
 	inputFile := flag.Arg(0)
 
+	// Show archive stats without decompressing
+	if showInfo {
+		if err := showFileStats(inputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Check input file exists
 	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Error: Input file %s does not exist\n", inputFile)
@@ -135,15 +148,13 @@ func compressFile(inputPath, outputPath string, level compress.CompressionLevel,
 	defer outputFile.Close()
 
 	// Create LZ4 writer
-	var lz4Writer io.WriteCloser
+	lz4Writer := compress.NewWriterWithOptions(outputFile, compress.WriterOptions{
+		Level:        level,
+		UseV2:        useV2,
+		StatsTrailer: writeStats,
+	})
 	if useV2 {
-		lz4Writer = compress.NewWriterWithOptions(outputFile, compress.WriterOptions{
-			Level: level,
-			UseV2: true,
-		})
 		fmt.Println("Using v0.2 compression algorithm")
-	} else {
-		lz4Writer = compress.NewWriterLevel(outputFile, level)
 	}
 	defer lz4Writer.Close()
 
@@ -273,3 +284,21 @@ func decompressFile(inputPath, outputPath string) error {
 
 	return nil
 }
+
+// showFileStats prints the stats trailer of a compressed file
+func showFileStats(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer f.Close()
+
+	stats, err := compress.ReadFrameStats(f)
+	if err != nil {
+		return fmt.Errorf("failed to read stats: %v", err)
+	}
+
+	fmt.Printf("%s: %d -> %d bytes (ratio %.2f), %d blocks, xxh64 %016x\n",
+		path, stats.Uncompressed, stats.Compressed, stats.Ratio(), stats.Blocks, stats.Hash)
+	return nil
+}
@@ -1,3 +1,5 @@
+//go:build !goz4x_minimal
+
 package main
 
 import (
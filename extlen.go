@@ -0,0 +1,15 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// ErrExtLenLimit indicates a block holding a run of literals too long to
+// encode within the MaxExtLenBytes limit.
+var ErrExtLenLimit = compress.ErrExtLenLimit
+
+// LimitExtLen rewrites a compressed block for decoders that read at most
+// maxExtLenBytes extension bytes per length, splitting long matches into
+// shorter ones at the same offset. Blocks with a longer run of literals
+// fail with ErrExtLenLimit.
+func LimitExtLen(src []byte, maxExtLenBytes int, dst []byte) ([]byte, error) {
+	return compress.LimitExtLen(src, maxExtLenBytes, dst)
+}
@@ -0,0 +1,180 @@
+// Package format describes the LZ4 frame and block formats as GoZ4X writes
+// and reads them: magic numbers, the bits of the frame descriptor, block
+// size words and the layout of sequence tokens. The compress package takes
+// its constants from here, so debuggers, dissectors and other tools can
+// decode GoZ4X streams without copying them.
+//
+// A frame is laid out as follows, with all integers little endian:
+//
+//	magic      4 bytes  FrameMagic
+//	FLG        1 byte   version and Flag bits
+//	BD         1 byte   block maximum size code
+//	size       8 bytes  content size, if FlagContentSize is set
+//	dict ID    4 bytes  dictionary ID, if FlagDictID is set
+//	HC         1 byte   header checksum
+//	blocks              each a 4-byte size word, the block data and, if
+//	                    FlagBlockChecksum is set, a 4-byte checksum
+//	end mark   4 bytes  EndMark
+//	checksum   4 bytes  XXH32 of the content, if FlagContentChecksum is set
+//
+// HC is the second byte of the XXH32 of the descriptor from FLG to the
+// dictionary ID.
+//
+// A compressed block is a series of sequences, each a Token, more literal
+// length bytes, the literals, a 2-byte match offset and more match length
+// bytes. The last sequence has literals only.
+package format
+
+const (
+	// MagicSize is the size of a magic number
+	MagicSize = 4
+	// FrameMagic starts every LZ4 frame
+	FrameMagic uint32 = 0x184D2204
+	// LegacyMagic starts the frames of the legacy format, which lz4 -l
+	// writes and Linux kernel images use; GoZ4X doesn't read or write them
+	LegacyMagic uint32 = 0x184C2102
+
+	// SkippableMagic is the first of the 16 magic numbers of skippable
+	// frames, which readers pass over: a magic number m is one of them if
+	// m&SkippableMagicMask == SkippableMagic. A 4-byte size of the frame's
+	// data follows the magic number.
+	SkippableMagic     uint32 = 0x184D2A50
+	SkippableMagicMask uint32 = 0xFFFFFFF0
+
+	// BundleEntryMagic, StatsTrailerMagic, ChunkIndexMagic, TreeMetaMagic,
+	// MuxLabelMagic and SparseHoleMagic are the skippable frames GoZ4X
+	// writes for the manifest entries of file bundles, its stats trailer,
+	// chunk index, the file metadata of compressed trees, multiplexed stream
+	// labels and sparse file holes
+	BundleEntryMagic  = SkippableMagic | 0x9
+	StatsTrailerMagic = SkippableMagic | 0xA
+	ChunkIndexMagic   = SkippableMagic | 0xB
+	TreeMetaMagic     = SkippableMagic | 0xC
+	MuxLabelMagic     = SkippableMagic | 0xD
+	SparseHoleMagic   = SkippableMagic | 0xE
+)
+
+// IsSkippable reports whether magic is the magic number of a skippable frame
+func IsSkippable(magic uint32) bool {
+	return magic&SkippableMagicMask == SkippableMagic
+}
+
+// Bits of the FLG byte
+const (
+	// VersionShift and VersionMask locate the version, which is Version
+	VersionShift = 6
+	VersionMask  = 0xC0
+	Version      = 1
+
+	FlagBlockIndependence = 0x20
+	FlagBlockChecksum     = 0x10
+	FlagContentSize       = 0x08
+	FlagContentChecksum   = 0x04
+	// FlagReserved must be zero
+	FlagReserved = 0x02
+	FlagDictID   = 0x01
+)
+
+// Bits of the BD byte
+const (
+	// BlockSizeShift and BlockSizeMask locate the block maximum size code
+	BlockSizeShift = 4
+	BlockSizeMask  = 0x70
+	// BDReserved holds the bits that must be zero
+	BDReserved = 0x8F
+)
+
+// Codes of the block maximum sizes
+const (
+	BlockSize64KB = iota + 4
+	BlockSize256KB
+	BlockSize1MB
+	BlockSize4MB
+)
+
+// BlockMaxSize returns the block maximum size for a code of the BD byte, or
+// 0 for an invalid code
+func BlockMaxSize(code int) int {
+	if code < BlockSize64KB || code > BlockSize4MB {
+		return 0
+	}
+	return 1 << (8 + 2*code)
+}
+
+// Sizes of the parts of a frame header
+const (
+	// DescriptorSize is the size of FLG, BD and HC
+	DescriptorSize  = 3
+	ContentSizeSize = 8
+	DictIDSize      = 4
+	// MaxHeaderSize is the size of a header with every optional field
+	MaxHeaderSize = MagicSize + DescriptorSize + ContentSizeSize + DictIDSize
+)
+
+// Block size words and checksums
+const (
+	// BlockSizeWordSize is the size of the word before each block
+	BlockSizeWordSize = 4
+	// BlockUncompressed marks, in a block size word, a block stored
+	// uncompressed; the other bits hold the size of the block data
+	BlockUncompressed uint32 = 0x80000000
+	BlockSizeWordMask uint32 = 0x7FFFFFFF
+	// EndMark is the block size word ending the blocks of a frame
+	EndMark uint32 = 0
+	// ChecksumSize is the size of block and content checksums
+	ChecksumSize = 4
+)
+
+// Sequence layout
+const (
+	// MinMatch is the shortest match, which a match length of 0 stands for
+	MinMatch = 4
+	// MaxOffset is the largest match offset, stored in OffsetSize bytes
+	MaxOffset  = 65535
+	OffsetSize = 2
+	// RunMask is the largest length a token holds; a token length of
+	// RunMask is followed by bytes adding to it, each but the last 255
+	RunMask = 15
+	// LastLiterals is the number of bytes at the end of a block that are
+	// always literals, and MFLimit the distance from the end of a block
+	// within which no match may start
+	LastLiterals = 5
+	MFLimit      = 12
+)
+
+// Token is the first byte of a sequence: the literal length in its high
+// 4 bits and the match length, less MinMatch, in its low 4 bits
+type Token byte
+
+// NewToken returns the token of a sequence with literalLen literals and a
+// match of matchLen bytes, or no match if matchLen is 0. Lengths of RunMask
+// or more are capped, with the rest left to the extension bytes.
+func NewToken(literalLen, matchLen int) Token {
+	t := Token(min(literalLen, RunMask) << 4)
+	if matchLen >= MinMatch {
+		t |= Token(min(matchLen-MinMatch, RunMask))
+	}
+	return t
+}
+
+// Literals returns the literal length held in the token; RunMask means
+// extension bytes follow
+func (t Token) Literals() int {
+	return int(t >> 4)
+}
+
+// Match returns the match length, less MinMatch, held in the token; RunMask
+// means extension bytes follow
+func (t Token) Match() int {
+	return int(t & RunMask)
+}
+
+// ExtensionSize returns the number of extension bytes following a token for
+// a length field holding n, the literal length or the match length less
+// MinMatch
+func ExtensionSize(n int) int {
+	if n < RunMask {
+		return 0
+	}
+	return (n-RunMask)/255 + 1
+}
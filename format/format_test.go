@@ -0,0 +1,168 @@
+package format_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/xxhash"
+)
+
+// TestBlockMaxSize tests the sizes of the block size codes
+func TestBlockMaxSize(t *testing.T) {
+	tests := []struct {
+		code int
+		want int
+	}{
+		{format.BlockSize64KB, 64 << 10},
+		{format.BlockSize256KB, 256 << 10},
+		{format.BlockSize1MB, 1 << 20},
+		{format.BlockSize4MB, 4 << 20},
+		{3, 0},
+		{8, 0},
+	}
+	for _, tt := range tests {
+		if got := format.BlockMaxSize(tt.code); got != tt.want {
+			t.Errorf("BlockMaxSize(%d) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+// TestToken tests building tokens and the sizes of their extension bytes
+func TestToken(t *testing.T) {
+	tests := []struct {
+		literals, match int
+		want            format.Token
+		litExt          int
+	}{
+		{0, 4, 0x00, 0},
+		{3, 10, 0x36, 0},
+		{14, 0, 0xE0, 0},
+		{15, 19, 0xFF, 1},
+		{15 + 255, 4, 0xF0, 2},
+		{300, 1000, 0xFF, 2},
+	}
+	for _, tt := range tests {
+		tok := format.NewToken(tt.literals, tt.match)
+		if tok != tt.want {
+			t.Errorf("NewToken(%d, %d) = %#x, want %#x", tt.literals, tt.match, tok, tt.want)
+		}
+		if tok.Literals() != min(tt.literals, format.RunMask) {
+			t.Errorf("Token(%#x).Literals() = %d, want %d", tok, tok.Literals(), min(tt.literals, format.RunMask))
+		}
+		if got := format.ExtensionSize(tt.literals); got != tt.litExt {
+			t.Errorf("ExtensionSize(%d) = %d, want %d", tt.literals, got, tt.litExt)
+		}
+	}
+	if !format.IsSkippable(format.ChunkIndexMagic) || format.IsSkippable(format.FrameMagic) {
+		t.Errorf("IsSkippable() doesn't tell skippable frames from LZ4 frames")
+	}
+}
+
+// TestDissectFrame walks a frame written by compress using only the
+// constants of this package, the way an external tool would
+func TestDissectFrame(t *testing.T) {
+	data := bytes.Repeat([]byte("dissect me, dissect me again. "), 10000)
+	var buf bytes.Buffer
+	w := compress.NewWriterWithOptions(&buf, compress.WriterOptions{
+		BlockSize:       64 * 1024,
+		BlockChecksum:   compress.XXH32,
+		ContentChecksum: true,
+	})
+	w.SetContentSize(uint64(len(data)))
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	frame := buf.Bytes()
+
+	if magic := binary.LittleEndian.Uint32(frame); magic != format.FrameMagic {
+		t.Fatalf("magic = %#x, want %#x", magic, format.FrameMagic)
+	}
+	flg, bd := frame[format.MagicSize], frame[format.MagicSize+1]
+	if (flg&format.VersionMask)>>format.VersionShift != format.Version {
+		t.Errorf("FLG %#x has version %d", flg, flg>>format.VersionShift)
+	}
+	for _, f := range []byte{format.FlagBlockIndependence, format.FlagBlockChecksum, format.FlagContentSize, format.FlagContentChecksum} {
+		if flg&f == 0 {
+			t.Errorf("FLG %#x lacks flag %#x", flg, f)
+		}
+	}
+	maxSize := format.BlockMaxSize(int(bd&format.BlockSizeMask) >> format.BlockSizeShift)
+	if maxSize != 64*1024 {
+		t.Errorf("BD %#x has a block maximum size of %d, want 64KB", bd, maxSize)
+	}
+
+	pos := format.MagicSize + 2
+	if size := binary.LittleEndian.Uint64(frame[pos:]); size != uint64(len(data)) {
+		t.Errorf("content size = %d, want %d", size, len(data))
+	}
+	pos += format.ContentSizeSize
+	if hc := byte(xxhash.Sum32(frame[format.MagicSize:pos]) >> 8); frame[pos] != hc {
+		t.Errorf("HC = %#x, want %#x", frame[pos], hc)
+	}
+	pos++
+
+	var blocks, decoded int
+	for {
+		word := binary.LittleEndian.Uint32(frame[pos:])
+		pos += format.BlockSizeWordSize
+		if word == format.EndMark {
+			break
+		}
+		size := int(word & format.BlockSizeWordMask)
+		block := frame[pos : pos+size]
+		if word&format.BlockUncompressed != 0 {
+			decoded += size
+		} else {
+			decoded += decodedSize(t, block)
+		}
+		pos += size + format.ChecksumSize
+		blocks++
+	}
+	pos += format.ChecksumSize
+
+	if pos != len(frame) || blocks != 5 || decoded != len(data) {
+		t.Errorf("walked %d of %d bytes, %d blocks holding %d bytes, want 5 blocks holding %d", pos, len(frame), blocks, decoded, len(data))
+	}
+}
+
+// decodedSize returns the decompressed size of a block from its sequences
+func decodedSize(t *testing.T, block []byte) int {
+	t.Helper()
+
+	length := func(pos, n int) (int, int) {
+		if n != format.RunMask {
+			return pos, n
+		}
+		for {
+			b := int(block[pos])
+			pos++
+			n += b
+			if b != 255 {
+				return pos, n
+			}
+		}
+	}
+
+	var size, pos int
+	for pos < len(block) {
+		tok := format.Token(block[pos])
+		var literals, match int
+		pos, literals = length(pos+1, tok.Literals())
+		pos += literals
+		size += literals
+		if pos == len(block) {
+			break
+		}
+		offset := int(binary.LittleEndian.Uint16(block[pos:]))
+		if offset == 0 || offset > format.MaxOffset {
+			t.Fatalf("bad offset %d", offset)
+		}
+		pos, match = length(pos+format.OffsetSize, tok.Match())
+		size += match + format.MinMatch
+	}
+	return size
+}
@@ -0,0 +1,43 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// EncodeFrame compresses src into a new LZ4 frame with the given options,
+// declaring len(src) as its content size. It suits message-passing systems
+// that already hold whole payloads.
+func EncodeFrame(src []byte, options WriterOptions) ([]byte, error) {
+	return compress.EncodeFrame(src, options)
+}
+
+// CompressReader compresses the n bytes read from src into a new LZ4 frame,
+// like EncodeFrame, with the output allocated once for the worst case of n
+// bytes up to 16 MiB. A source that ends earlier fails.
+func CompressReader(src io.Reader, n int64, options WriterOptions) ([]byte, error) {
+	return compress.CompressReader(src, n, options)
+}
+
+// DecodeFrame decompresses the LZ4 stream held in frame into a new slice,
+// allocated once when the frame declares its content size.
+func DecodeFrame(frame []byte) ([]byte, error) {
+	return compress.DecodeFrame(frame)
+}
+
+// MessageOptions configures EncodeMessage.
+type MessageOptions = compress.MessageOptions
+
+// EncodeMessage compresses msg into a minimal LZ4 frame appended to dst: 64KB
+// blocks, no content size, and a content checksum if asked for. It suits
+// messages of a few KB sent one per frame.
+func EncodeMessage(dst, msg []byte, options MessageOptions) ([]byte, error) {
+	return compress.EncodeMessage(dst, msg, options)
+}
+
+// DecodeMessage decompresses a frame from EncodeMessage, or any LZ4 frame,
+// appending the output to dst.
+func DecodeMessage(dst, frame []byte) ([]byte, error) {
+	return compress.DecodeMessage(dst, frame)
+}
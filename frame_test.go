@@ -0,0 +1,23 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestEncodeDecodeFrame tests the one-shot frame helpers
+func TestEncodeDecodeFrame(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	frame, err := EncodeFrame(data, WriterOptions{Level: 9})
+	if err != nil {
+		t.Fatalf("EncodeFrame error: %v", err)
+	}
+	got, err := DecodeFrame(frame)
+	if err != nil {
+		t.Fatalf("DecodeFrame error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("DecodeFrame returned %d bytes, want %d", len(got), len(data))
+	}
+}
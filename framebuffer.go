@@ -0,0 +1,11 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// FrameBuffer gives random access to an LZ4 frame held in memory: after
+// Parse, DecodeBlock and ReadAt decompress only the blocks they need.
+type FrameBuffer = compress.FrameBuffer
+
+// BlockInfo describes one block of a frame parsed by FrameBuffer, as
+// yielded by FrameBuffer.Blocks.
+type BlockInfo = compress.BlockInfo
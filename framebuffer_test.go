@@ -0,0 +1,74 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestFrameBuffer tests serving a byte range from a compressed frame
+func TestFrameBuffer(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 100000)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var fb FrameBuffer
+	if err := fb.Parse(buf.Bytes()); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if fb.Size() != int64(len(data)) {
+		t.Errorf("Size() = %d, want %d", fb.Size(), len(data))
+	}
+
+	p := make([]byte, 1000)
+	if _, err := fb.ReadAt(p, 500000); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	if !bytes.Equal(p, data[500000:501000]) {
+		t.Errorf("ReadAt() returned the wrong range")
+	}
+}
+
+// TestFrameBufferBlocks tests ranging over the blocks of a parsed frame and
+// over the chunks of a Reader
+func TestFrameBufferBlocks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 100000)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var fb FrameBuffer
+	if err := fb.Parse(buf.Bytes()); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var size int64
+	for info := range fb.Blocks() {
+		size += int64(info.Size)
+	}
+	if size != int64(len(data)) {
+		t.Errorf("Blocks() cover %d bytes, want %d", size, len(data))
+	}
+
+	var got []byte
+	for chunk, err := range NewReader(bytes.NewReader(buf.Bytes())).Chunks() {
+		if err != nil {
+			t.Fatalf("Chunks() error = %v", err)
+		}
+		got = append(got, chunk...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Chunks() = %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,37 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// FrameFlags holds the feature flags of a frame header.
+type FrameFlags = compress.FrameFlags
+
+// Frame header flags.
+const (
+	FlagBlockIndependence = compress.FlagBlockIndependence
+	FlagBlockChecksum     = compress.FlagBlockChecksum
+	FlagContentSize       = compress.FlagContentSize
+	FlagContentChecksum   = compress.FlagContentChecksum
+	FlagDictID            = compress.FlagDictID
+)
+
+// ReadHeader reads the frame header without decoding any data, so that
+// ContentSize, BlockSize and Flags can be queried before the first Read.
+func (r *Reader) ReadHeader() error {
+	return r.r.ReadHeader()
+}
+
+// ContentSize returns the uncompressed size declared in the frame header and
+// whether one was declared. It can be used to pre-allocate the output.
+func (r *Reader) ContentSize() (uint64, bool) {
+	return r.r.ContentSize()
+}
+
+// BlockSize returns the maximum block size declared in the frame header.
+func (r *Reader) BlockSize() int {
+	return r.r.BlockSize()
+}
+
+// Flags returns the flags of the frame header.
+func (r *Reader) Flags() FrameFlags {
+	return r.r.Flags()
+}
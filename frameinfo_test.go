@@ -0,0 +1,37 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestReaderContentSize tests pre-allocating output from the declared content size
+func TestReaderContentSize(t *testing.T) {
+	data := bytes.Repeat([]byte("pre-allocated output "), 1000)
+
+	var buf bytes.Buffer
+	if _, err := CompressFile(&buf, bytes.NewReader(data), WriterOptions{}); err != nil {
+		t.Fatalf("CompressFile() error = %v", err)
+	}
+
+	r := NewReader(&buf)
+	if err := r.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() error = %v", err)
+	}
+	size, ok := r.ContentSize()
+	if !ok || size != uint64(len(data)) {
+		t.Fatalf("ContentSize() = %d, %v, want %d, true", size, ok, len(data))
+	}
+	if !r.Flags().Has(FlagContentSize) {
+		t.Errorf("Flags() = %#x, want FlagContentSize set", r.Flags())
+	}
+	if r.BlockSize() != 64*1024 {
+		t.Errorf("BlockSize() = %d, want %d", r.BlockSize(), 64*1024)
+	}
+
+	out := make([]byte, size)
+	n, err := r.Read(out)
+	if err != nil || n != len(data) || !bytes.Equal(out, data) {
+		t.Errorf("Read() = %d, %v, want %d bytes of original data", n, err, len(data))
+	}
+}
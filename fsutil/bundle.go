@@ -0,0 +1,338 @@
+package fsutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/format"
+	"github.com/harriteja/GoZ4X/xxhash"
+)
+
+// A bundle holds a list of files in one stream, a lighter alternative to tar
+// for simple archives. Every file is stored as a manifest entry, a skippable
+// frame describing it, followed by an LZ4 frame holding its contents, so a
+// bundle also decodes with any GoZ4X reader as the files' contents one after
+// another.
+//
+// The manifest entry uses the skippable magic number format.BundleEntryMagic
+// and a payload of little-endian integers followed by the name:
+//
+//	size      8 bytes  size of the file
+//	frame     8 bytes  size of the LZ4 frame that follows the entry
+//	mode      4 bytes  file mode
+//	checksum  4 bytes  XXH32 of the file's contents
+//	name               slash-separated path of the file, up to the end
+const bundleEntryHeaderSize = 24
+
+// maxBundleName bounds the names of bundled files
+const maxBundleName = 4096
+
+var (
+	// ErrInvalidName indicates a bundled file name that isn't a relative,
+	// slash-separated path without "." or ".." elements
+	ErrInvalidName = errors.New("fsutil: invalid file name in bundle")
+
+	// ErrBundleEntry indicates a stream that doesn't continue with a
+	// manifest entry where one is expected
+	ErrBundleEntry = errors.New("fsutil: invalid bundle manifest entry")
+
+	// ErrBundleChecksum indicates an extracted file whose size or checksum
+	// doesn't match its manifest entry
+	ErrBundleChecksum = errors.New("fsutil: bundled file doesn't match its manifest entry")
+)
+
+// BundleEntry describes a file of a bundle
+type BundleEntry struct {
+	// Name is the slash-separated path of the file, relative to the
+	// directory it was bundled from
+	Name string
+	// Size is the size of the file
+	Size int64
+	// Mode holds the file's permission bits
+	Mode fs.FileMode
+	// Checksum is the XXH32 of the file's contents
+	Checksum uint32
+}
+
+// bundledFile is a file compressed for a bundle, or the error compressing it
+type bundledFile struct {
+	entry BundleEntry
+	frame []byte
+	err   error
+}
+
+// CompressFiles writes the files named by names, slash-separated paths
+// relative to dir, to w as a bundle and returns their manifest entries.
+// Files are compressed concurrently, up to NumWorkers at a time, and
+// written in the order of names.
+func CompressFiles(w io.Writer, dir string, names []string, opts Options) ([]BundleEntry, error) {
+	for _, name := range names {
+		if !validBundleName(name) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+		}
+	}
+
+	// Each file gets a channel for its result, and at most workers files
+	// are compressed ahead of the one being written
+	results := make([]chan bundledFile, len(names))
+	for i := range results {
+		results[i] = make(chan bundledFile, 1)
+	}
+	sem := make(chan struct{}, opts.workers())
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i, name := range names {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return
+			}
+			go func() {
+				results[i] <- compressBundled(filepath.Join(dir, filepath.FromSlash(name)), name, opts.Writer)
+			}()
+		}
+	}()
+
+	entries := make([]BundleEntry, 0, len(names))
+	var head []byte
+	for i := range names {
+		f := <-results[i]
+		<-sem
+		if f.err != nil {
+			return entries, f.err
+		}
+		head = appendBundleEntry(head[:0], f.entry, len(f.frame))
+		if _, err := w.Write(head); err != nil {
+			return entries, err
+		}
+		if _, err := w.Write(f.frame); err != nil {
+			return entries, err
+		}
+		entries = append(entries, f.entry)
+	}
+	return entries, nil
+}
+
+// compressBundled compresses the file at path into a frame in memory
+func compressBundled(path, name string, options compress.WriterOptions) bundledFile {
+	in, err := os.Open(path)
+	if err != nil {
+		return bundledFile{err: err}
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return bundledFile{err: err}
+	}
+	if !fi.Mode().IsRegular() {
+		return bundledFile{err: fmt.Errorf("fsutil: %s is not a regular file", path)}
+	}
+
+	var buf bytes.Buffer
+	zw := compress.NewWriterWithOptions(&buf, options)
+	if err := zw.SetContentSize(uint64(fi.Size())); err != nil {
+		return bundledFile{err: err}
+	}
+	sum := xxhash.New32()
+	n, err := io.Copy(io.MultiWriter(zw, sum), in)
+	if err != nil {
+		return bundledFile{err: err}
+	}
+	if err := zw.Close(); err != nil {
+		return bundledFile{err: err}
+	}
+	entry := BundleEntry{Name: name, Size: n, Mode: fi.Mode() & modeMask, Checksum: sum.Sum32()}
+	return bundledFile{entry: entry, frame: buf.Bytes()}
+}
+
+// ExtractFiles restores the files of the bundle read from r below dir,
+// creating directories as needed, and returns their manifest entries.
+// Every file is checked against the size and checksum of its entry. Files
+// are decompressed concurrently, up to NumWorkers at a time.
+func ExtractFiles(r io.Reader, dir string, opts Options) ([]BundleEntry, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	sem := make(chan struct{}, opts.workers())
+	var entries []BundleEntry
+	var err error
+	for {
+		var entry BundleEntry
+		var frameSize int64
+		entry, frameSize, err = readBundleEntry(r)
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+		// The buffer grows as the frame arrives rather than trusting the
+		// size in the entry
+		var buf bytes.Buffer
+		var n int64
+		if n, err = io.CopyN(&buf, r, frameSize); err != nil {
+			if n < frameSize && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			break
+		}
+		frame := buf.Bytes()
+		entries = append(entries, entry)
+
+		sem <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := extractBundled(dir, entry, frame, opts.KeepSetID); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err == nil {
+		err = firstErr
+	}
+	return entries, err
+}
+
+// extractBundled decompresses frame into the file described by entry,
+// failing as soon as it decompresses past the size of the entry
+func extractBundled(dir string, entry BundleEntry, frame []byte, keepSetID bool) error {
+	dst := filepath.Join(dir, filepath.FromSlash(entry.Name))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	sum := xxhash.New32()
+	zr := io.LimitReader(compress.NewReader(bytes.NewReader(frame)), entry.Size+1)
+	n, err := io.Copy(io.MultiWriter(out, sum), zr)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if n != entry.Size || sum.Sum32() != entry.Checksum {
+		return fmt.Errorf("%w: %s", ErrBundleChecksum, entry.Name)
+	}
+	mode := entry.Mode
+	if !keepSetID {
+		mode &^= fs.ModeSetuid | fs.ModeSetgid
+	}
+	return os.Chmod(dst, mode)
+}
+
+// ListBundle returns the manifest entries of the bundle read from r,
+// skipping over the files' contents without decompressing them
+func ListBundle(r io.Reader) ([]BundleEntry, error) {
+	var entries []BundleEntry
+	for {
+		entry, frameSize, err := readBundleEntry(r)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		if n, err := io.CopyN(io.Discard, r, frameSize); err != nil {
+			if n < frameSize {
+				err = io.ErrUnexpectedEOF
+			}
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// validBundleName reports whether name is a relative, slash-separated path
+// naming a file below the directory of the bundle
+func validBundleName(name string) bool {
+	return fs.ValidPath(name) && name != "." && path.Clean(name) == name &&
+		len(name) <= maxBundleName
+}
+
+// appendBundleEntry appends the manifest entry of a file followed by a frame
+// of frameSize bytes to b
+func appendBundleEntry(b []byte, e BundleEntry, frameSize int) []byte {
+	b = binary.LittleEndian.AppendUint32(b, format.BundleEntryMagic)
+	b = binary.LittleEndian.AppendUint32(b, uint32(bundleEntryHeaderSize+len(e.Name)))
+	b = binary.LittleEndian.AppendUint64(b, uint64(e.Size))
+	b = binary.LittleEndian.AppendUint64(b, uint64(frameSize))
+	b = binary.LittleEndian.AppendUint32(b, uint32(e.Mode))
+	b = binary.LittleEndian.AppendUint32(b, e.Checksum)
+	return append(b, e.Name...)
+}
+
+// readBundleEntry reads a manifest entry and returns it with the size of the
+// frame that follows. It returns io.EOF at the end of the bundle.
+func readBundleEntry(r io.Reader) (BundleEntry, int64, error) {
+	var head [8 + bundleEntryHeaderSize]byte
+	if _, err := io.ReadFull(r, head[:8]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrBundleEntry
+		}
+		return BundleEntry{}, 0, err
+	}
+	size := binary.LittleEndian.Uint32(head[4:8])
+	if binary.LittleEndian.Uint32(head[0:4]) != format.BundleEntryMagic ||
+		size <= bundleEntryHeaderSize || size > bundleEntryHeaderSize+maxBundleName {
+		return BundleEntry{}, 0, ErrBundleEntry
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return BundleEntry{}, 0, err
+	}
+	e := BundleEntry{
+		Name:     string(payload[bundleEntryHeaderSize:]),
+		Size:     int64(binary.LittleEndian.Uint64(payload[0:8])),
+		Mode:     fs.FileMode(binary.LittleEndian.Uint32(payload[16:20])) & modeMask,
+		Checksum: binary.LittleEndian.Uint32(payload[20:24]),
+	}
+	frameSize := int64(binary.LittleEndian.Uint64(payload[8:16]))
+	if !validBundleName(e.Name) {
+		return BundleEntry{}, 0, fmt.Errorf("%w: %q", ErrInvalidName, e.Name)
+	}
+	if e.Size < 0 || frameSize < 0 {
+		return BundleEntry{}, 0, ErrBundleEntry
+	}
+	return e, frameSize, nil
+}
@@ -0,0 +1,178 @@
+package fsutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/xxhash"
+)
+
+// bundleNames returns the names of the test tree in a fixed order
+func bundleNames(files map[string]treeFile) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// TestBundleRoundTrip tests bundling, listing and extracting files
+func TestBundleRoundTrip(t *testing.T) {
+	for _, workers := range []int{0, 1, 3} {
+		files := testTree()
+		names := bundleNames(files)
+		src := t.TempDir()
+		writeTree(t, src, files)
+
+		var bundle bytes.Buffer
+		opts := Options{NumWorkers: workers}
+		entries, err := CompressFiles(&bundle, src, names, opts)
+		if err != nil {
+			t.Fatalf("CompressFiles() error = %v", err)
+		}
+		for i, e := range entries {
+			want := files[names[i]]
+			if e.Name != names[i] || e.Size != int64(len(want.data)) || e.Mode != want.mode {
+				t.Errorf("entry %d = %+v, want %s of %d bytes, mode %v", i, e, names[i], len(want.data), want.mode)
+			}
+		}
+
+		listed, err := ListBundle(bytes.NewReader(bundle.Bytes()))
+		if err != nil || !slices.Equal(listed, entries) {
+			t.Errorf("ListBundle() = %+v, %v, want %+v", listed, err, entries)
+		}
+
+		dst := t.TempDir()
+		extracted, err := ExtractFiles(bytes.NewReader(bundle.Bytes()), dst, opts)
+		if err != nil || !slices.Equal(extracted, entries) {
+			t.Fatalf("ExtractFiles() = %+v, %v", extracted, err)
+		}
+		for name, want := range files {
+			path := filepath.Join(dst, filepath.FromSlash(name))
+			got, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(got, want.data) {
+				t.Errorf("%s: restored %d bytes, error = %v, want %d", name, len(got), err, len(want.data))
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != want.mode {
+				t.Errorf("%s: mode = %v, %v, want %v", name, fi.Mode().Perm(), err, want.mode)
+			}
+		}
+	}
+}
+
+// TestBundlePlainReader tests that a bundle decodes with a plain reader as
+// the contents of its files in order
+func TestBundlePlainReader(t *testing.T) {
+	files := testTree()
+	names := bundleNames(files)
+	src := t.TempDir()
+	writeTree(t, src, files)
+
+	var bundle, want bytes.Buffer
+	if _, err := CompressFiles(&bundle, src, names, Options{}); err != nil {
+		t.Fatalf("CompressFiles() error = %v", err)
+	}
+	for _, name := range names {
+		want.Write(files[name].data)
+	}
+
+	got, err := io.ReadAll(compress.NewReader(&bundle))
+	if err != nil || !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("ReadAll() = %d bytes, %v, want %d", len(got), err, want.Len())
+	}
+}
+
+// TestBundleErrors tests invalid names, missing files and damaged bundles
+func TestBundleErrors(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]treeFile{"a.txt": {data: bytes.Repeat([]byte("abc"), 1000), mode: 0o644}})
+
+	for _, name := range []string{"", ".", "../a.txt", "/a.txt", "dir/../a.txt", "dir//a.txt"} {
+		if _, err := CompressFiles(io.Discard, src, []string{name}, Options{}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("CompressFiles(%q) error = %v, want ErrInvalidName", name, err)
+		}
+	}
+	if _, err := CompressFiles(io.Discard, src, []string{"a.txt", "missing"}, Options{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("CompressFiles(missing) error = %v, want os.ErrNotExist", err)
+	}
+
+	var bundle bytes.Buffer
+	if _, err := CompressFiles(&bundle, src, []string{"a.txt"}, Options{}); err != nil {
+		t.Fatalf("CompressFiles() error = %v", err)
+	}
+	data := bundle.Bytes()
+
+	// A checksum that doesn't match the contents
+	bad := bytes.Clone(data)
+	bad[8+20] ^= 1
+	if _, err := ExtractFiles(bytes.NewReader(bad), t.TempDir(), Options{}); !errors.Is(err, ErrBundleChecksum) {
+		t.Errorf("ExtractFiles(bad checksum) error = %v, want ErrBundleChecksum", err)
+	}
+
+	// A name escaping the destination
+	bad = bytes.Clone(data)
+	copy(bad[8+bundleEntryHeaderSize:], "../")
+	if _, err := ListBundle(bytes.NewReader(bad)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("ListBundle(bad name) error = %v, want ErrInvalidName", err)
+	}
+
+	if _, err := ExtractFiles(bytes.NewReader(data[:len(data)-3]), t.TempDir(), Options{}); err != io.ErrUnexpectedEOF {
+		t.Errorf("ExtractFiles(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := ListBundle(bytes.NewReader(append(bytes.Clone(data), "junk1234"...))); err != ErrBundleEntry {
+		t.Errorf("ListBundle(trailing junk) error = %v, want ErrBundleEntry", err)
+	}
+}
+
+// TestBundleUntrusted tests that extraction stops at the size of an entry
+// whose frame decompresses to more, and clears setuid and setgid bits
+// unless asked to keep them
+func TestBundleUntrusted(t *testing.T) {
+	frame, err := compress.EncodeFrame(make([]byte, 8<<20), compress.WriterOptions{Level: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := appendBundleEntry(nil, BundleEntry{Name: "big", Size: 10, Mode: 0o644}, len(frame))
+	bundle = append(bundle, frame...)
+	dst := t.TempDir()
+	if _, err := ExtractFiles(bytes.NewReader(bundle), dst, Options{}); !errors.Is(err, ErrBundleChecksum) {
+		t.Errorf("ExtractFiles(oversized) error = %v, want ErrBundleChecksum", err)
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "big")); err == nil && fi.Size() > 11 {
+		t.Errorf("ExtractFiles(oversized) wrote %d bytes, want at most 11", fi.Size())
+	}
+
+	data := []byte("#!/bin/sh\n")
+	frame, err = compress.EncodeFrame(data, compress.WriterOptions{Level: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode := fs.ModeSetuid | fs.ModeSetgid | 0o755
+	entry := BundleEntry{Name: "tool", Size: int64(len(data)), Mode: mode, Checksum: xxhash.Sum32(data)}
+	bundle = append(appendBundleEntry(nil, entry, len(frame)), frame...)
+	for _, keep := range []bool{false, true} {
+		dst := t.TempDir()
+		if _, err := ExtractFiles(bytes.NewReader(bundle), dst, Options{KeepSetID: keep}); err != nil {
+			t.Fatalf("ExtractFiles() error = %v", err)
+		}
+		fi, err := os.Stat(filepath.Join(dst, "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fs.FileMode(0o755)
+		if keep {
+			want = mode
+		}
+		if got := fi.Mode() & modeMask; got != want {
+			t.Errorf("KeepSetID %v: mode = %v, want %v", keep, got, want)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package fsutil compresses and extracts whole directory trees.
+//
+// CompressTree mirrors a directory into another one, compressing every
+// regular file to a file of the same name with the ".lz4" extension. Each
+// compressed file starts with a skippable frame recording the file's mode and
+// modification time, followed by an ordinary LZ4 stream, so the files can
+// also be decoded one by one with any GoZ4X reader. ExtractTree reverses the
+// process and restores the recorded metadata. Files are processed
+// concurrently, bounded by a worker budget.
+//
+// The metadata frame uses the skippable magic number 0x184D2A5C and a 12-byte
+// payload: the file mode as a 4-byte and the modification time in
+// nanoseconds since the Unix epoch as an 8-byte little-endian integer.
+//
+// CompressFiles instead bundles a list of files into a single stream, each
+// as its own frame behind a manifest entry, and ExtractFiles restores them.
+package fsutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// Ext is the extension given to compressed files
+const Ext = ".lz4"
+
+const (
+	// metaMagic is the skippable frame magic number of the metadata frame
+	metaMagic = format.TreeMetaMagic
+
+	// metaSize is the payload size of the metadata frame
+	metaSize = 12
+
+	// modeMask selects the mode bits that are recorded and restored
+	modeMask = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+)
+
+// ErrNotDir indicates a source that isn't a directory
+var ErrNotDir = errors.New("fsutil: source is not a directory")
+
+// Options configures CompressTree and ExtractTree
+type Options struct {
+	// Writer holds the options each file is compressed with
+	Writer compress.WriterOptions
+
+	// NumWorkers bounds the number of files processed at once (0 = use
+	// compress.CurrentDefaultWorkers)
+	NumWorkers int
+
+	// KeepSetID restores the setuid and setgid bits recorded in a bundle or
+	// in the metadata of a compressed tree. ExtractFiles and ExtractTree
+	// clear them otherwise, since files from elsewhere could create
+	// programs that run as the user extracting them.
+	KeepSetID bool
+
+	// Limits bounds the size and expansion ratio of every file ExtractTree
+	// decompresses, holes included, for trees from untrusted sources. A
+	// small file can otherwise record a hole of any size.
+	Limits compress.DecompressionLimits
+}
+
+// workers returns the number of files to process at once
+func (o Options) workers() int {
+	if o.NumWorkers <= 0 {
+		return compress.CurrentDefaultWorkers()
+	}
+	return o.NumWorkers
+}
+
+// fileJob is a file to process and the path of its output
+type fileJob struct {
+	src, dst string
+	info     fs.FileInfo
+}
+
+// dirMeta is a directory whose metadata is applied once its contents exist
+type dirMeta struct {
+	path  string
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+// CompressTree compresses every regular file below the directory src into the
+// same place below dst, creating dst and its subdirectories as needed.
+// Directories keep their modes and modification times. Symbolic links and
+// other special files are skipped.
+func CompressTree(src, dst string, opts Options) error {
+	return walkTree(src, dst, opts, func(path string, d fs.DirEntry) (string, bool) {
+		return path + Ext, d.Type().IsRegular()
+	}, func(job fileJob) error {
+		return compressFile(job, opts.Writer)
+	})
+}
+
+// ExtractTree restores every ".lz4" file below the directory src into the same
+// place below dst without the extension, applying the mode and modification
+// time recorded by CompressTree. Other files are skipped.
+func ExtractTree(src, dst string, opts Options) error {
+	return walkTree(src, dst, opts, func(path string, d fs.DirEntry) (string, bool) {
+		return strings.TrimSuffix(path, Ext), d.Type().IsRegular() && strings.HasSuffix(path, Ext)
+	}, func(job fileJob) error {
+		return extractFile(job, opts)
+	})
+}
+
+// walkTree mirrors the directories of src below dst and runs process on the
+// files selected by target, which also names their destination
+func walkTree(src, dst string, opts Options, target func(string, fs.DirEntry) (string, bool), process func(fileJob) error) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return ErrNotDir
+	}
+	// A destination inside the source must not be walked into
+	absDst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	jobs := make(chan fileJob)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	for i := 0; i < opts.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				// Drain the remaining jobs once anything has failed
+				if failed() {
+					continue
+				}
+				if err := process(job); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	var dirs []dirMeta
+	walkErr := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if failed() {
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == absDst {
+				return filepath.SkipDir
+			}
+			out := filepath.Join(dst, rel)
+			// Owner access is needed to fill the directory before its own
+			// mode is applied
+			if err := os.MkdirAll(out, info.Mode().Perm()|0o700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirMeta{out, info.Mode() & modeMask, info.ModTime()})
+			return nil
+		}
+
+		out, ok := target(rel, d)
+		if !ok {
+			return nil
+		}
+		jobs <- fileJob{path, filepath.Join(dst, out), info}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+
+	if walkErr != nil {
+		return walkErr
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// Children come after their parents, so restore in reverse to keep
+	// directory times from being touched again
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := applyMeta(dirs[i].path, dirs[i].mode, dirs[i].mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressFile writes the metadata frame and compressed contents of a file
+func compressFile(job fileJob, options compress.WriterOptions) (err error) {
+	in, err := os.Open(job.src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	mode := job.info.Mode() & modeMask
+	out, err := os.OpenFile(job.dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0o200)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if _, err := out.Write(appendMeta(nil, mode, job.info.ModTime())); err != nil {
+		return err
+	}
+	if _, err := compress.CompressFile(out, in, options); err != nil {
+		return err
+	}
+	return nil
+}
+
+// extractFile restores a compressed file and the metadata it records
+func extractFile(job fileJob, opts Options) error {
+	in, err := os.Open(job.src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	r, mode, mtime, err := readMeta(in)
+	if err != nil {
+		return err
+	}
+	if !opts.KeepSetID {
+		mode &^= fs.ModeSetuid | fs.ModeSetgid
+	}
+
+	out, err := os.OpenFile(job.dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := compress.DecompressFileWithOptions(out, r, compress.ReaderOptions{Limits: opts.Limits}); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// Files without metadata keep the defaults of newly created files
+	if mtime.IsZero() {
+		return os.Chmod(job.dst, 0o644)
+	}
+	return applyMeta(job.dst, mode, mtime)
+}
+
+// applyMeta sets the mode and modification time of path
+func applyMeta(path string, mode fs.FileMode, mtime time.Time) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+	return os.Chtimes(path, mtime, mtime)
+}
+
+// appendMeta appends a metadata frame for the given mode and time to b
+func appendMeta(b []byte, mode fs.FileMode, mtime time.Time) []byte {
+	b = binary.LittleEndian.AppendUint32(b, metaMagic)
+	b = binary.LittleEndian.AppendUint32(b, metaSize)
+	b = binary.LittleEndian.AppendUint32(b, uint32(mode))
+	return binary.LittleEndian.AppendUint64(b, uint64(mtime.UnixNano()))
+}
+
+// readMeta reads the metadata frame at the start of r, if there is one, and
+// returns a reader for the compressed stream that follows. The time is zero
+// when the file has no metadata frame.
+func readMeta(r io.Reader) (io.Reader, fs.FileMode, time.Time, error) {
+	var head [8 + metaSize]byte
+	n, err := io.ReadFull(r, head[:8])
+	if err != nil || binary.LittleEndian.Uint32(head[0:4]) != metaMagic ||
+		binary.LittleEndian.Uint32(head[4:8]) != metaSize {
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return nil, 0, time.Time{}, err
+		}
+		// Hand back what was read as the start of the stream
+		return io.MultiReader(bytes.NewReader(head[:n]), r), 0, time.Time{}, nil
+	}
+
+	if _, err := io.ReadFull(r, head[8:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, time.Time{}, err
+	}
+	mode := fs.FileMode(binary.LittleEndian.Uint32(head[8:12])) & modeMask
+	mtime := time.Unix(0, int64(binary.LittleEndian.Uint64(head[12:])))
+	return r, mode, mtime, nil
+}
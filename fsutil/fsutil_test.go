@@ -0,0 +1,295 @@
+package fsutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/format"
+)
+
+// treeFile describes a file of a test tree
+type treeFile struct {
+	data  []byte
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+// testTree returns the files of a small tree with varied contents and metadata
+func testTree() map[string]treeFile {
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return map[string]treeFile{
+		"readme.txt":            {bytes.Repeat([]byte("directory tree "), 2000), 0o644, base},
+		"empty":                 {nil, 0o600, base.Add(time.Hour)},
+		"bin/tool":              {bytes.Repeat([]byte{0x7f, 'E', 'L', 'F'}, 5000), 0o755, base.Add(2 * time.Hour)},
+		"src/a/b/deep.go":       {[]byte("package deep\n"), 0o640, base.Add(3 * time.Hour)},
+		"src/a/notes.lz4.txt":   {[]byte("not compressed yet"), 0o644, base.Add(4 * time.Hour)},
+		"data/large/blocks.bin": {bytes.Repeat([]byte("0123456789abcdef"), 300*1024), 0o444, base.Add(5 * time.Hour)},
+	}
+}
+
+// writeTree creates the files below root
+func writeTree(t *testing.T, root string, files map[string]treeFile) {
+	t.Helper()
+
+	for name, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(path, f.data, 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := applyMeta(path, f.mode, f.mtime); err != nil {
+			t.Fatalf("applyMeta() error = %v", err)
+		}
+	}
+}
+
+// TestTreeRoundTrip tests compressing and extracting a tree
+func TestTreeRoundTrip(t *testing.T) {
+	for _, workers := range []int{0, 1, 3} {
+		t.Run("", func(t *testing.T) {
+			files := testTree()
+			src := t.TempDir()
+			writeTree(t, src, files)
+			dirTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+			if err := applyMeta(filepath.Join(src, "src", "a"), 0o750, dirTime); err != nil {
+				t.Fatalf("applyMeta() error = %v", err)
+			}
+
+			compressed := filepath.Join(t.TempDir(), "compressed")
+			opts := Options{NumWorkers: workers}
+			if err := CompressTree(src, compressed, opts); err != nil {
+				t.Fatalf("CompressTree() error = %v", err)
+			}
+
+			extracted := filepath.Join(t.TempDir(), "extracted")
+			if err := ExtractTree(compressed, extracted, opts); err != nil {
+				t.Fatalf("ExtractTree() error = %v", err)
+			}
+
+			for name, want := range files {
+				path := filepath.Join(extracted, filepath.FromSlash(name))
+				got, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("ReadFile() error = %v", err)
+				}
+				if !bytes.Equal(got, want.data) {
+					t.Errorf("%s: restored %d bytes, want %d", name, len(got), len(want.data))
+				}
+
+				fi, err := os.Stat(path)
+				if err != nil {
+					t.Fatalf("Stat() error = %v", err)
+				}
+				if fi.Mode().Perm() != want.mode {
+					t.Errorf("%s: mode = %v, want %v", name, fi.Mode().Perm(), want.mode)
+				}
+				if !fi.ModTime().Equal(want.mtime) {
+					t.Errorf("%s: mtime = %v, want %v", name, fi.ModTime(), want.mtime)
+				}
+			}
+
+			fi, err := os.Stat(filepath.Join(extracted, "src", "a"))
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if fi.Mode().Perm() != 0o750 || !fi.ModTime().Equal(dirTime) {
+				t.Errorf("directory mode, mtime = %v, %v, want %v, %v", fi.Mode().Perm(), fi.ModTime(), fs.FileMode(0o750), dirTime)
+			}
+		})
+	}
+}
+
+// TestCompressTreeOutput tests that compressed files decode with a plain reader
+func TestCompressTreeOutput(t *testing.T) {
+	files := testTree()
+	src := t.TempDir()
+	writeTree(t, src, files)
+
+	dst := t.TempDir()
+	if err := CompressTree(src, dst, Options{}); err != nil {
+		t.Fatalf("CompressTree() error = %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dst, "readme.txt"+Ext))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+
+	got, err := io.ReadAll(compress.NewReader(f))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if want := files["readme.txt"].data; !bytes.Equal(got, want) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(want))
+	}
+}
+
+// TestExtractTreeWithoutMetadata tests extracting plain compressed files
+func TestExtractTreeWithoutMetadata(t *testing.T) {
+	data := bytes.Repeat([]byte("plain lz4 stream "), 500)
+
+	src := t.TempDir()
+	var buf bytes.Buffer
+	w := compress.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "plain"+Ext), buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "ignored.txt"), data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	dst := t.TempDir()
+	if err := ExtractTree(src, dst, Options{}); err != nil {
+		t.Fatalf("ExtractTree() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "plain"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("restored %d bytes, want %d", len(got), len(data))
+	}
+	if _, err := os.Stat(filepath.Join(dst, "ignored.txt")); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want not exist", err)
+	}
+}
+
+// TestExtractTreeSetID tests that setuid and setgid bits recorded in the
+// metadata of a file are only restored with KeepSetID
+func TestExtractTreeSetID(t *testing.T) {
+	mode := fs.ModeSetuid | fs.ModeSetgid | 0o755
+	frame, err := compress.EncodeFrame([]byte("#!/bin/sh\n"), compress.WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := t.TempDir()
+	file := append(appendMeta(nil, mode, time.Now()), frame...)
+	if err := os.WriteFile(filepath.Join(src, "tool"+Ext), file, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	for _, keep := range []bool{false, true} {
+		dst := t.TempDir()
+		if err := ExtractTree(src, dst, Options{KeepSetID: keep}); err != nil {
+			t.Fatalf("ExtractTree() error = %v", err)
+		}
+		fi, err := os.Stat(filepath.Join(dst, "tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := fs.FileMode(0o755)
+		if keep {
+			want = mode
+		}
+		if got := fi.Mode() & modeMask; got != want {
+			t.Errorf("KeepSetID %v: mode = %v, want %v", keep, got, want)
+		}
+	}
+}
+
+// TestExtractTreeLimits tests that Limits bounds the holes a file records
+func TestExtractTreeLimits(t *testing.T) {
+	hole := func(n uint64) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, format.SparseHoleMagic)
+		b = binary.LittleEndian.AppendUint32(b, 8)
+		return binary.LittleEndian.AppendUint64(b, n)
+	}
+	empty, err := compress.EncodeFrame(nil, compress.WriterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Limits: compress.DecompressionLimits{MaxFrameSize: 1 << 20}}
+
+	for _, tt := range []struct {
+		size uint64
+		want error
+	}{
+		{4096, nil},
+		{1 << 40, compress.ErrDecompressionBomb},
+	} {
+		src := t.TempDir()
+		// A file that is all hole, as CompressFile writes it
+		file := append(appendMeta(nil, 0o644, time.Now()), empty...)
+		file = append(file, hole(tt.size)...)
+		if err := os.WriteFile(filepath.Join(src, "disk.img"+Ext), file, 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		dst := t.TempDir()
+		if err := ExtractTree(src, dst, opts); !errors.Is(err, tt.want) {
+			t.Fatalf("ExtractTree(hole %d) error = %v, want %v", tt.size, err, tt.want)
+		}
+		if tt.want != nil {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(dst, "disk.img"))
+		if err != nil || uint64(fi.Size()) != tt.size {
+			t.Errorf("extracted hole = %v, %v, want %d bytes", fi, err, tt.size)
+		}
+	}
+}
+
+// TestTreeErrors tests invalid sources and corrupt files
+func TestTreeErrors(t *testing.T) {
+	t.Run("Missing Source", func(t *testing.T) {
+		if err := CompressTree(filepath.Join(t.TempDir(), "missing"), t.TempDir(), Options{}); !os.IsNotExist(err) {
+			t.Errorf("CompressTree() error = %v, want not exist", err)
+		}
+	})
+
+	t.Run("Source Is File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := CompressTree(path, t.TempDir(), Options{}); err != ErrNotDir {
+			t.Errorf("CompressTree() error = %v, want %v", err, ErrNotDir)
+		}
+	})
+
+	t.Run("Corrupt File", func(t *testing.T) {
+		src := t.TempDir()
+		corrupt := appendMeta(nil, 0o644, time.Now())
+		corrupt = append(corrupt, "not an lz4 frame"...)
+		if err := os.WriteFile(filepath.Join(src, "bad"+Ext), corrupt, 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		if err := ExtractTree(src, t.TempDir(), Options{}); err == nil {
+			t.Error("ExtractTree() error = nil, want error")
+		}
+	})
+}
+
+// TestCompressTreeNestedDestination tests a destination inside the source
+func TestCompressTreeNestedDestination(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]treeFile{"a.txt": {[]byte("nested"), 0o644, time.Now()}})
+
+	dst := filepath.Join(src, "out")
+	if err := CompressTree(src, dst, Options{}); err != nil {
+		t.Fatalf("CompressTree() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "a.txt"+Ext)); err != nil {
+		t.Errorf("Stat() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "out")); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want not exist", err)
+	}
+}
@@ -1,12 +1,21 @@
 // Package goz4x provides a fast, pure-Go implementation of the LZ4 compression algorithm.
+//
+// All functions are safe for concurrent use. Readers, writers, encoders and
+// decoders may be shared between goroutines: their methods are serialized
+// internally and each Write is applied whole. Sharing them is rarely useful
+// though, since the order of concurrent writes is unspecified. The
+// Unsynchronized writer option drops the locking for single-goroutine use.
+//
+// The parallel and SIMD entry points pull in the v03 and v04 packages. The
+// goz4x_minimal build tag leaves them out, for binaries that only need the
+// block and stream API.
 package goz4x
 
 import (
 	"io"
+	"iter"
 
 	"github.com/harriteja/GoZ4X/compress"
-	v03 "github.com/harriteja/GoZ4X/v03"
-	v04 "github.com/harriteja/GoZ4X/v04"
 )
 
 // Version constants
@@ -37,11 +46,44 @@ func CompressBlockLevel(src []byte, dst []byte, level int) ([]byte, error) {
 
 // DecompressBlock decompresses an LZ4-compressed block.
 // It allocates a new destination slice if dst is nil or too small.
-// The maxSize parameter limits the maximum size of the decompressed data.
+// The maxSize parameter limits the maximum size of the decompressed data;
+// zero means unknown, in which case the exact size is found by a scan pass.
+//
+// Deprecated: Use DecompressBlockInto or DecompressBlockAlloc, which don't
+// treat maxSize as both a bound and an allocation hint.
 func DecompressBlock(src []byte, dst []byte, maxSize int) ([]byte, error) {
 	return compress.DecompressBlock(src, dst, maxSize)
 }
 
+// DecompressBlockInto decompresses an LZ4-compressed block into dst without
+// allocating, returning the number of bytes written. Output that doesn't
+// fit in len(dst) fails with io.ErrShortBuffer.
+func DecompressBlockInto(src, dst []byte) (int, error) {
+	return compress.DecompressBlockInto(src, dst)
+}
+
+// DecompressBlockAlloc decompresses an LZ4-compressed block into a new
+// buffer of maxSize bytes; zero means unknown, in which case the exact size
+// is found by a scan pass. Larger output fails with io.ErrShortBuffer.
+func DecompressBlockAlloc(src []byte, maxSize int) ([]byte, error) {
+	return compress.DecompressBlockAlloc(src, maxSize)
+}
+
+// DecompressBlockFunc decompresses an LZ4-compressed block, handing the
+// output to emit in chunks of at most 128KB instead of returning it, so
+// that any block decodes in constant memory. Chunks are only valid during
+// the call to emit. It returns the number of bytes emitted.
+func DecompressBlockFunc(src []byte, emit func(chunk []byte) error, maxSize int) (int, error) {
+	return compress.DecompressBlockFunc(src, emit, maxSize)
+}
+
+// CompressBlockStable compresses a byte slice with the frozen v1 encoder.
+// Its output is guaranteed to be byte-identical for the same input and level
+// in every future version, unlike CompressBlockLevel whose output may improve.
+func CompressBlockStable(src []byte, dst []byte, level int) ([]byte, error) {
+	return compress.CompressBlockStable(src, dst, compress.CompressionLevel(level))
+}
+
 // V2 API functions with improved compression
 
 // CompressBlockV2 compresses a byte slice using the v0.2 algorithm with default compression level.
@@ -56,52 +98,6 @@ func CompressBlockV2Level(src []byte, dst []byte, level int) ([]byte, error) {
 	return compress.CompressBlockV2Level(src, dst, compress.CompressionLevel(level))
 }
 
-// V3 API functions with parallel compression
-
-// CompressBlockParallel compresses a byte slice using multiple goroutines with default compression level.
-// This provides better performance on multicore systems for large inputs.
-func CompressBlockParallel(src []byte, dst []byte) ([]byte, error) {
-	return v03.CompressBlockParallel(src, dst)
-}
-
-// CompressBlockParallelLevel compresses a byte slice using multiple goroutines with the specified level.
-// This provides better performance on multicore systems for large inputs.
-func CompressBlockParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
-	return v03.CompressBlockParallelLevel(src, dst, level)
-}
-
-// CompressBlockV2Parallel compresses a byte slice using v0.2 algorithm with multiple goroutines.
-// This provides better compression ratio and better performance on multicore systems.
-func CompressBlockV2Parallel(src []byte, dst []byte) ([]byte, error) {
-	return v03.CompressBlockV2Parallel(src, dst)
-}
-
-// CompressBlockV2ParallelLevel compresses a byte slice using v0.2 algorithm and multiple goroutines.
-// This provides better compression ratio and better performance on multicore systems.
-func CompressBlockV2ParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
-	return v03.CompressBlockV2ParallelLevel(src, dst, level)
-}
-
-// V4 API functions with SIMD optimizations
-
-// CompressBlockV4 compresses a byte slice using the v0.4 algorithm with SIMD optimizations.
-// This provides better performance on modern CPUs with SIMD instruction sets.
-func CompressBlockV4(src []byte, dst []byte) ([]byte, error) {
-	return v04.CompressBlock(src, dst)
-}
-
-// CompressBlockV4Level compresses a byte slice with the v0.4 algorithm and specified level.
-// It uses SIMD instructions where possible for better performance.
-func CompressBlockV4Level(src []byte, dst []byte, level int) ([]byte, error) {
-	return v04.CompressBlockLevel(src, dst, level)
-}
-
-// CompressBlockV4Parallel compresses a byte slice using v0.4 algorithm with multiple goroutines.
-// This provides both SIMD acceleration and parallelism for maximum performance.
-func CompressBlockV4Parallel(src []byte, dst []byte) ([]byte, error) {
-	return v04.CompressBlockParallel(src, dst)
-}
-
 // Reader is an io.Reader that decompresses data from an LZ4 stream.
 type Reader struct {
 	r *compress.Reader
@@ -117,6 +113,28 @@ func (r *Reader) Read(p []byte) (int, error) {
 	return r.r.Read(p)
 }
 
+// Reset discards the Reader's state and makes it read from src, keeping its
+// options and buffers, so that Readers can be reused through a sync.Pool.
+func (r *Reader) Reset(src io.Reader) {
+	r.r.Reset(src)
+}
+
+// Chunks returns an iterator over the decompressed data, a block at a time.
+// A chunk is only valid until the next iteration, and an error ends it.
+func (r *Reader) Chunks() iter.Seq2[[]byte, error] {
+	return r.r.Chunks()
+}
+
+// Consumed returns the number of compressed bytes read from the underlying reader.
+func (r *Reader) Consumed() uint64 {
+	return r.r.Consumed()
+}
+
+// Produced returns the number of decompressed bytes returned by Read.
+func (r *Reader) Produced() uint64 {
+	return r.r.Produced()
+}
+
 // Writer is an io.WriteCloser that compresses data to an LZ4 stream.
 type Writer struct {
 	w *compress.Writer
@@ -128,7 +146,7 @@ func NewWriter(w io.Writer) *Writer {
 }
 
 // NewWriterLevel creates a new Writer that compresses to w using the specified compression level.
-// Levels range from 1 (fastest) to 12 (best compression).
+// Levels range from 1 (fastest) to 12 (best compression); NoCompression (0) stores data as is.
 func NewWriterLevel(w io.Writer, level int) *Writer {
 	return &Writer{w: compress.NewWriterLevel(w, compress.CompressionLevel(level))}
 }
@@ -137,7 +155,7 @@ func NewWriterLevel(w io.Writer, level int) *Writer {
 // It offers better compression than NewWriter.
 func NewWriterV2(w io.Writer) *Writer {
 	return &Writer{w: compress.NewWriterWithOptions(w, compress.WriterOptions{
-		Level: compress.DefaultLevel,
+		Level: compress.CurrentDefaultLevel(),
 		UseV2: true,
 	})}
 }
@@ -151,40 +169,27 @@ func NewWriterV2Level(w io.Writer, level int) *Writer {
 	})}
 }
 
-// ParallelWriter is an io.WriteCloser that compresses data in parallel for better performance.
-type ParallelWriter struct {
-	w *v03.ParallelWriter
-}
-
-// NewParallelWriter creates a new parallel writer with default options.
-func NewParallelWriter(w io.Writer) *ParallelWriter {
-	return &ParallelWriter{w: v03.NewParallelWriter(w)}
-}
-
-// NewParallelWriterLevel creates a new parallel writer with custom compression level.
-func NewParallelWriterLevel(w io.Writer, level int) *ParallelWriter {
-	return &ParallelWriter{w: v03.NewParallelWriterLevel(w, level)}
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
 }
 
-// NewParallelWriterV2 creates a new parallel writer using v0.2 algorithm with default options.
-func NewParallelWriterV2(w io.Writer) *ParallelWriter {
-	return &ParallelWriter{w: v03.NewParallelWriterWithOptions(w, v03.ParallelWriterOptions{
-		Level: int(compress.DefaultLevel),
-		UseV2: true,
-	})}
+// WriteStored writes p as stored blocks without trying to compress it, for
+// data known not to compress, such as encrypted or compressed data.
+func (w *Writer) WriteStored(p []byte) (int, error) {
+	return w.w.WriteStored(p)
 }
 
-// NewParallelWriterV2Level creates a new parallel writer using v0.2 algorithm with custom level.
-func NewParallelWriterV2Level(w io.Writer, level int) *ParallelWriter {
-	return &ParallelWriter{w: v03.NewParallelWriterWithOptions(w, v03.ParallelWriterOptions{
-		Level: level,
-		UseV2: true,
-	})}
+// WriteLevel writes p compressed at level, whatever the level of the
+// Writer, after flushing buffered data as a block of its own.
+func (w *Writer) WriteLevel(p []byte, level int) (int, error) {
+	return w.w.WriteLevel(p, compress.CompressionLevel(level))
 }
 
-// Write implements io.Writer.
-func (w *Writer) Write(p []byte) (int, error) {
-	return w.w.Write(p)
+// SetLevel changes the compression level from the next block on.
+// Levels outside 0 to 12 are rejected.
+func (w *Writer) SetLevel(level int) error {
+	return w.w.SetLevel(compress.CompressionLevel(level))
 }
 
 // Close implements io.Closer.
@@ -197,29 +202,28 @@ func (w *Writer) Reset(dst io.Writer) {
 	w.w.Reset(dst)
 }
 
-// Write implements io.Writer.
-func (pw *ParallelWriter) Write(p []byte) (int, error) {
-	return pw.w.Write(p)
-}
-
-// Close implements io.Closer.
-func (pw *ParallelWriter) Close() error {
-	return pw.w.Close()
+// Abort cancels the frame being written without finishing it. Buffered
+// data is dropped, no end marker or checksum is written and the Writer's
+// buffers are released. Write fails and Close does nothing until Reset.
+func (w *Writer) Abort() {
+	w.w.Abort()
 }
 
-// Reset resets the ParallelWriter to write to dst.
-func (pw *ParallelWriter) Reset(dst io.Writer) {
-	pw.w.Reset(dst)
+// Written returns the number of uncompressed bytes accepted by Write since
+// the Writer was created or last reset.
+func (w *Writer) Written() uint64 {
+	return w.w.Written()
 }
 
-// SetNumWorkers sets the number of worker goroutines used for compression.
-// A value of 0 means use GOMAXPROCS.
-func (pw *ParallelWriter) SetNumWorkers(n int) {
-	pw.w.SetNumWorkers(n)
+// Compressed returns the number of compressed bytes written to the
+// destination since the Writer was created or last reset.
+func (w *Writer) Compressed() uint64 {
+	return w.w.Compressed()
 }
 
-// SetChunkSize sets the chunk size used for parallel compression.
-// A value of 0 means use default chunk size.
-func (pw *ParallelWriter) SetChunkSize(size int) {
-	pw.w.SetChunkSize(size)
+// AppendTo resets the Writer to append to the LZ4 data stored in dst.
+// The last frame is continued in place when its header allows it;
+// otherwise the new data is written as an additional frame.
+func (w *Writer) AppendTo(dst io.ReadWriteSeeker) error {
+	return w.w.AppendTo(dst)
 }
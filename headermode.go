@@ -0,0 +1,23 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// HeaderMode sets how a Reader treats frame header versions and reserved
+// bits it doesn't know, when set as the HeaderMode option of ReaderOptions.
+type HeaderMode = compress.HeaderMode
+
+// Header modes. HeaderLenient reads frames of future versions that keep the
+// version 1 layout and doesn't check header checksums; HeaderStrict rejects
+// reserved bits to validate encoders.
+const (
+	HeaderDefault = compress.HeaderDefault
+	HeaderLenient = compress.HeaderLenient
+	HeaderStrict  = compress.HeaderStrict
+)
+
+// Errors returned for frame headers the HeaderMode doesn't accept.
+var (
+	ErrUnsupportedVersion = compress.ErrUnsupportedVersion
+	ErrReservedBits       = compress.ErrReservedBits
+	ErrHeaderChecksum     = compress.ErrHeaderChecksum
+)
@@ -0,0 +1,258 @@
+// Package testgen generates deterministic data for the tests and benchmarks
+// of GoZ4X. Every generator takes a seed and returns the same bytes for it
+// on every run and platform, so a failing test can be rerun on the data
+// that failed and benchmark runs compare like with like. The generators
+// draw from math/rand sources created from the seed and never from the
+// global source, which Go seeds randomly.
+//
+// The kinds of data cover what compressors meet in practice and where they
+// break: Text and JSON compress well, Binary moderately, Random not at
+// all, Pattern at a chosen rate, and Adversarial stresses the match finders
+// and decoders with runs, overlapping matches, matches at the edge of the
+// window and long hash chains.
+package testgen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+)
+
+// DefaultSeed is the seed tests use when any data of a kind will do
+const DefaultSeed = 1
+
+// Seq hands out seeds to helpers that must return different data on every
+// call, such as two random buffers joined into one input. The n-th call of
+// Next returns DefaultSeed+n, so the same go test command sees the same
+// data on every run.
+type Seq struct {
+	n atomic.Int64
+}
+
+// Next returns the next seed
+func (s *Seq) Next() int64 {
+	return DefaultSeed + s.n.Add(1) - 1
+}
+
+// Generator returns size bytes of one kind of data for seed
+type Generator func(seed int64, size int) []byte
+
+// Corpus lists every kind of data by name, for tests and benchmarks that
+// run over all of them
+var Corpus = []struct {
+	Name     string
+	Generate Generator
+}{
+	{"Text", Text},
+	{"JSON", JSON},
+	{"Binary", Binary},
+	{"Random", Random},
+	{"Adversarial", Adversarial},
+}
+
+// Rand returns a source of random numbers for seed, for helpers that need
+// data of a shape the generators don't cover
+func Rand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// Random returns size incompressible bytes
+func Random(seed int64, size int) []byte {
+	data := make([]byte, size)
+	Rand(seed).Read(data)
+	return data
+}
+
+// Pattern returns size bytes repeating a random pattern of patternSize
+// bytes, with a share noise of the bytes, from 0 to 1, replaced by random
+// ones. Its compressibility falls as noise rises.
+func Pattern(seed int64, size, patternSize int, noise float64) []byte {
+	rng := Rand(seed)
+	pattern := make([]byte, max(patternSize, 1))
+	rng.Read(pattern)
+
+	data := make([]byte, size)
+	for i := 0; i < size; i += len(pattern) {
+		copy(data[i:], pattern)
+	}
+	if noise > 0 {
+		for i := range data {
+			if rng.Float64() < noise {
+				data[i] = byte(rng.Intn(256))
+			}
+		}
+	}
+	return data
+}
+
+// words is the vocabulary of Text, most frequent first
+var words = []string{
+	"the", "of", "and", "to", "a", "in", "is", "that", "for", "it", "as",
+	"was", "with", "be", "by", "on", "not", "he", "this", "are", "or",
+	"his", "from", "at", "which", "but", "have", "an", "had", "they", "you",
+	"were", "their", "one", "all", "we", "can", "her", "has", "there",
+	"been", "if", "more", "when", "will", "would", "who", "so", "no",
+	"data", "block", "stream", "frame", "buffer", "compression", "level",
+	"match", "offset", "length", "literal", "window", "table", "checksum",
+	"reader", "writer", "header", "format", "decoder", "encoder", "memory",
+	"performance", "throughput", "latency", "network", "storage", "archive",
+	"message", "record", "system", "process", "result", "value", "number",
+	"between", "through", "because", "without", "another", "important",
+	"different", "following", "available", "general", "information",
+}
+
+// Text returns size bytes of English-like prose: sentences of words drawn
+// from a small vocabulary, more often the common ones, in paragraphs
+func Text(seed int64, size int) []byte {
+	rng := Rand(seed)
+	var buf bytes.Buffer
+	buf.Grow(size + 64)
+	for buf.Len() < size {
+		n := 6 + rng.Intn(14)
+		for i := 0; i < n; i++ {
+			// Squaring skews the draw towards the start of the list
+			u := rng.Float64()
+			w := words[int(u*u*float64(len(words)))]
+			if i == 0 {
+				buf.WriteByte(w[0] - 'a' + 'A')
+				buf.WriteString(w[1:])
+			} else {
+				buf.WriteByte(' ')
+				buf.WriteString(w)
+			}
+		}
+		buf.WriteByte('.')
+		if rng.Intn(6) == 0 {
+			buf.WriteString("\n\n")
+		} else {
+			buf.WriteByte(' ')
+		}
+	}
+	return buf.Bytes()[:size]
+}
+
+// JSON returns size bytes of JSON records, one per line, with fixed keys
+// and varying values, as in logs and API responses. The last record is
+// cut off at size.
+func JSON(seed int64, size int) []byte {
+	rng := Rand(seed)
+	themes := []string{"light", "dark", "system"}
+	var buf bytes.Buffer
+	buf.Grow(size + 512)
+	for id := 0; buf.Len() < size; id++ {
+		b := buf.AvailableBuffer()
+		b = append(b, `{"id":`...)
+		b = strconv.AppendInt(b, int64(id), 10)
+		b = append(b, `,"name":"User `...)
+		b = strconv.AppendInt(b, int64(rng.Intn(100000)), 10)
+		b = append(b, `","active":`...)
+		b = strconv.AppendBool(b, rng.Intn(2) == 1)
+		b = append(b, `,"age":`...)
+		b = strconv.AppendInt(b, int64(18+rng.Intn(80)), 10)
+		b = append(b, `,"timestamp":`...)
+		b = strconv.AppendInt(b, 1700000000000+int64(id)*1000+int64(rng.Intn(1000)), 10)
+		b = append(b, `,"preferences":{"theme":"`...)
+		b = append(b, themes[rng.Intn(len(themes))]...)
+		b = append(b, `","fontSize":`...)
+		b = strconv.AppendInt(b, int64(10+rng.Intn(5)), 10)
+		b = append(b, `},"metrics":{"logins":`...)
+		b = strconv.AppendInt(b, int64(rng.Intn(1000)), 10)
+		b = append(b, `,"clickRate":`...)
+		b = strconv.AppendFloat(b, rng.Float64(), 'f', 4, 64)
+		b = append(b, "}}\n"...)
+		buf.Write(b)
+	}
+	return buf.Bytes()[:size]
+}
+
+// binaryRecordSize is the size of a record of Binary
+const binaryRecordSize = 32
+
+// Binary returns size bytes of fixed-size binary records, as in sensor logs
+// and columnar files: little-endian timestamps rising by small steps, ids
+// from a small set, a float value on a random walk, a counter and flags
+func Binary(seed int64, size int) []byte {
+	rng := Rand(seed)
+	data := make([]byte, size+binaryRecordSize)
+	ts := uint64(1700000000000000)
+	value := 20.0
+	for i, n := 0, 0; i < size; i, n = i+binaryRecordSize, n+1 {
+		ts += uint64(1000 + rng.Intn(50))
+		value += rng.NormFloat64() * 0.1
+		r := data[i : i+binaryRecordSize]
+		binary.LittleEndian.PutUint64(r[0:], ts)
+		binary.LittleEndian.PutUint32(r[8:], uint32(rng.Intn(16)))
+		binary.LittleEndian.PutUint64(r[12:], math.Float64bits(value))
+		binary.LittleEndian.PutUint32(r[20:], uint32(n))
+		binary.LittleEndian.PutUint32(r[24:], uint32(rng.Intn(4))<<8)
+		binary.LittleEndian.PutUint32(r[28:], 0)
+	}
+	return data[:size]
+}
+
+// maxOffset is the largest match offset of the LZ4 block format
+const maxOffset = 65535
+
+// Adversarial returns size bytes made of segments that each stress one part
+// of a compressor or decoder, in an order drawn from seed:
+//
+//   - runs of a single byte, matched at offset 1
+//   - repeats of patterns of 2 to 7 bytes, which decode as overlapping
+//     matches at small offsets
+//   - copies of the bytes exactly 65535 bytes back, the largest offset, and
+//     65536 bytes back, just out of reach
+//   - one 4-byte prefix with random bytes after it, over and over, which
+//     fills hash chains with candidates that match only 4 bytes
+//   - random bytes, which don't compress
+func Adversarial(seed int64, size int) []byte {
+	rng := Rand(seed)
+	data := make([]byte, 0, size)
+	for len(data) < size {
+		n := min(1024+rng.Intn(16*1024), size-len(data))
+		switch rng.Intn(5) {
+		case 0:
+			c := byte(rng.Intn(256))
+			for i := 0; i < n; i++ {
+				data = append(data, c)
+			}
+		case 1:
+			pattern := make([]byte, 2+rng.Intn(6))
+			rng.Read(pattern)
+			for i := 0; i < n; i++ {
+				data = append(data, pattern[i%len(pattern)])
+			}
+		case 2:
+			offset := maxOffset + rng.Intn(2)
+			if len(data) < offset {
+				// Not enough history yet: random bytes to reach back to
+				// later
+				data = appendRandom(rng, data, n)
+				continue
+			}
+			for i := 0; i < n; i++ {
+				data = append(data, data[len(data)-offset])
+			}
+		case 3:
+			var prefix [4]byte
+			rng.Read(prefix[:])
+			for i := 0; i+8 <= n; i += 8 {
+				data = append(data, prefix[:]...)
+				data = appendRandom(rng, data, 4)
+			}
+		default:
+			data = appendRandom(rng, data, n)
+		}
+	}
+	return data[:size]
+}
+
+// appendRandom appends n random bytes to data
+func appendRandom(rng *rand.Rand, data []byte, n int) []byte {
+	start := len(data)
+	data = append(data, make([]byte, n)...)
+	rng.Read(data[start:])
+	return data
+}
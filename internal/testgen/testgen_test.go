@@ -0,0 +1,47 @@
+package testgen
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+)
+
+// TestDeterministic tests that every generator returns size bytes that
+// depend only on the seed
+func TestDeterministic(t *testing.T) {
+	gens := append(Corpus[:len(Corpus):len(Corpus)], struct {
+		Name     string
+		Generate Generator
+	}{"Pattern", func(seed int64, size int) []byte { return Pattern(seed, size, 4096, 0.15) }})
+
+	for _, g := range gens {
+		for _, size := range []int{0, 1, 100, 70000, 300 * 1024} {
+			a := g.Generate(DefaultSeed, size)
+			b := g.Generate(DefaultSeed, size)
+			if len(a) != size || !bytes.Equal(a, b) {
+				t.Errorf("%s(%d): %d bytes, equal = %v", g.Name, size, len(a), bytes.Equal(a, b))
+			}
+			if size >= 100 && bytes.Equal(a, g.Generate(DefaultSeed+1, size)) {
+				t.Errorf("%s(%d): same output for another seed", g.Name, size)
+			}
+		}
+	}
+}
+
+// TestStable tests that the output for a seed doesn't change between
+// releases, which would silently change what the tests and benchmarks run on
+func TestStable(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want uint32
+	}{
+		{"Random", Random(DefaultSeed, 1000), 0x0449a591},
+		{"Text", Text(DefaultSeed, 1000), 0xb7ab5570},
+	}
+	for _, tt := range tests {
+		if got := crc32.ChecksumIEEE(tt.data); got != tt.want {
+			t.Errorf("%s: CRC-32 = %#08x, want %#08x", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package xxh32 implements the 32-bit xxHash algorithm used by the LZ4 frame format
+// for header, block and content checksums.
+package xxh32
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime1 uint32 = 2654435761
+	prime2 uint32 = 2246822519
+	prime3 uint32 = 3266489917
+	prime4 uint32 = 668265263
+	prime5 uint32 = 374761393
+)
+
+// Checksum returns the xxHash32 of b with a zero seed
+func Checksum(b []byte) uint32 {
+	return ChecksumSeed(b, 0)
+}
+
+// ChecksumSeed returns the xxHash32 of b with the given seed
+func ChecksumSeed(b []byte, seed uint32) uint32 {
+	n := len(b)
+	var h uint32
+
+	if n >= 16 {
+		v := [4]uint32{seed + prime1 + prime2, seed + prime2, seed, seed - prime1}
+		blocks(&v, b)
+		b = b[n&^15:]
+		h = merge(&v)
+	} else {
+		h = seed + prime5
+	}
+
+	h += uint32(n)
+	return finalize(h, b)
+}
+
+// Digest computes an xxHash32 checksum incrementally
+type Digest struct {
+	seed    uint32
+	v       [4]uint32
+	total   uint64
+	mem     [16]byte
+	memSize int
+}
+
+// New creates a new Digest with a zero seed
+func New() *Digest {
+	return NewSeed(0)
+}
+
+// NewSeed creates a new Digest with the given seed
+func NewSeed(seed uint32) *Digest {
+	d := &Digest{seed: seed}
+	d.Reset()
+	return d
+}
+
+// Reset restores the Digest to its initial state
+func (d *Digest) Reset() {
+	d.v = [4]uint32{d.seed + prime1 + prime2, d.seed + prime2, d.seed, d.seed - prime1}
+	d.total = 0
+	d.memSize = 0
+}
+
+// Write adds more data to the running checksum. It never returns an error.
+func (d *Digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.total += uint64(n)
+
+	// Complete a partially filled stripe first
+	if d.memSize > 0 {
+		c := copy(d.mem[d.memSize:], p)
+		d.memSize += c
+		p = p[c:]
+		if d.memSize < 16 {
+			return n, nil
+		}
+		blocks(&d.v, d.mem[:])
+		d.memSize = 0
+	}
+
+	if len(p) >= 16 {
+		blocks(&d.v, p)
+		p = p[len(p)&^15:]
+	}
+
+	d.memSize = copy(d.mem[:], p)
+	return n, nil
+}
+
+// Sum32 returns the checksum of the data written so far
+func (d *Digest) Sum32() uint32 {
+	var h uint32
+	if d.total >= 16 {
+		h = merge(&d.v)
+	} else {
+		h = d.seed + prime5
+	}
+
+	h += uint32(d.total)
+	return finalize(h, d.mem[:d.memSize])
+}
+
+// blocksGeneric mixes the whole 16-byte stripes of b into the accumulators
+// v, ignoring any bytes after the last one. It is the portable version of
+// blocks.
+func blocksGeneric(v *[4]uint32, b []byte) {
+	v1, v2, v3, v4 := v[0], v[1], v[2], v[3]
+	for len(b) >= 16 {
+		v1 = round(v1, binary.LittleEndian.Uint32(b[0:4]))
+		v2 = round(v2, binary.LittleEndian.Uint32(b[4:8]))
+		v3 = round(v3, binary.LittleEndian.Uint32(b[8:12]))
+		v4 = round(v4, binary.LittleEndian.Uint32(b[12:16]))
+		b = b[16:]
+	}
+	v[0], v[1], v[2], v[3] = v1, v2, v3, v4
+}
+
+// merge converges the four accumulators into a single hash
+func merge(v *[4]uint32) uint32 {
+	return bits.RotateLeft32(v[0], 1) + bits.RotateLeft32(v[1], 7) +
+		bits.RotateLeft32(v[2], 12) + bits.RotateLeft32(v[3], 18)
+}
+
+// round mixes one 4-byte lane into an accumulator
+func round(acc, input uint32) uint32 {
+	acc += input * prime2
+	acc = bits.RotateLeft32(acc, 13)
+	return acc * prime1
+}
+
+// finalize mixes the remaining tail bytes and avalanches the hash
+func finalize(h uint32, tail []byte) uint32 {
+	for len(tail) >= 4 {
+		h += binary.LittleEndian.Uint32(tail) * prime3
+		h = bits.RotateLeft32(h, 17) * prime4
+		tail = tail[4:]
+	}
+	for _, c := range tail {
+		h += uint32(c) * prime5
+		h = bits.RotateLeft32(h, 11) * prime1
+	}
+
+	h ^= h >> 15
+	h *= prime2
+	h ^= h >> 13
+	h *= prime3
+	h ^= h >> 16
+	return h
+}
@@ -0,0 +1,44 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// round mixes the lane at off(SI) into the accumulator acc, with prime1
+// in R13 and prime2 in R14
+#define round(acc, off) \
+	MOVL  off(SI), BX \
+	IMULL R14, BX     \
+	ADDL  BX, acc     \
+	ROLL  $13, acc    \
+	IMULL R13, acc
+
+// func blocks(v *[4]uint32, b []byte)
+TEXT ·blocks(SB), NOSPLIT, $0-32
+	MOVQ v+0(FP), AX
+	MOVQ b_base+8(FP), SI
+	MOVQ b_len+16(FP), DX
+	SHRQ $4, DX
+	JZ   done
+
+	MOVL $0x9E3779B1, R13
+	MOVL $0x85EBCA77, R14
+	MOVL 0(AX), R8
+	MOVL 4(AX), R9
+	MOVL 8(AX), R10
+	MOVL 12(AX), R11
+
+loop:
+	round(R8, 0)
+	round(R9, 4)
+	round(R10, 8)
+	round(R11, 12)
+	ADDQ $16, SI
+	DECQ DX
+	JNZ  loop
+
+	MOVL R8, 0(AX)
+	MOVL R9, 4(AX)
+	MOVL R10, 8(AX)
+	MOVL R11, 12(AX)
+
+done:
+	RET
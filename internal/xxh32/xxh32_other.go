@@ -0,0 +1,9 @@
+//go:build !amd64 || purego
+
+package xxh32
+
+// blocks mixes the whole 16-byte stripes of b into the accumulators v,
+// ignoring any bytes after the last one
+func blocks(v *[4]uint32, b []byte) {
+	blocksGeneric(v, b)
+}
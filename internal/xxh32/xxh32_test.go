@@ -0,0 +1,79 @@
+package xxh32
+
+import (
+	"testing"
+)
+
+// TestChecksum tests the one-shot checksum against reference vectors
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		input string
+		seed  uint32
+		want  uint32
+	}{
+		{"", 0, 0x02CC5D05},
+		{"abc", 0, 0x32D153FF},
+		{"Nobody inspects the spammish repetition", 0, 0xE2293B2F},
+	}
+
+	for _, tt := range tests {
+		if got := ChecksumSeed([]byte(tt.input), tt.seed); got != tt.want {
+			t.Errorf("ChecksumSeed(%q, %d) = %#08x, want %#08x", tt.input, tt.seed, got, tt.want)
+		}
+	}
+}
+
+// TestDigest tests that incremental writes match the one-shot checksum
+func TestDigest(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, seed := range []uint32{0, 1, 0xDEADBEEF} {
+		for _, size := range []int{0, 1, 15, 16, 17, 100, 1000} {
+			want := ChecksumSeed(data[:size], seed)
+
+			for _, step := range []int{1, 3, 16, 64} {
+				d := NewSeed(seed)
+				for i := 0; i < size; i += step {
+					d.Write(data[i:min(i+step, size)])
+				}
+				if got := d.Sum32(); got != want {
+					t.Errorf("seed=%d size=%d step=%d: Sum32() = %#08x, want %#08x", seed, size, step, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestBlocks tests that blocks, in assembly where there is some, agrees
+// with blocksGeneric and leaves a partial stripe alone
+func TestBlocks(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i*131 + i>>3)
+	}
+
+	for _, size := range []int{0, 1, 15, 16, 17, 48, 999, 1000} {
+		for _, off := range []int{0, 1, 3} {
+			b := data[off:][:min(size, len(data)-off)]
+			v := [4]uint32{1, 2, 3, 4}
+			want := v
+			blocks(&v, b)
+			blocksGeneric(&want, b)
+			if v != want {
+				t.Errorf("size=%d off=%d: blocks() = %#x, want %#x", len(b), off, v, want)
+			}
+		}
+	}
+}
+
+// BenchmarkChecksum measures the one-shot checksum of 64KB
+func BenchmarkChecksum(b *testing.B) {
+	data := make([]byte, 64<<10)
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		Checksum(data)
+	}
+}
@@ -0,0 +1,168 @@
+// Package xxh64 implements the 64-bit xxHash algorithm
+package xxh64
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	prime1 uint64 = 11400714785074694791
+	prime2 uint64 = 14029467366897019727
+	prime3 uint64 = 1609587929392839161
+	prime4 uint64 = 9650029242287828579
+	prime5 uint64 = 2870177450012600261
+)
+
+// Checksum returns the xxHash64 of b with a zero seed
+func Checksum(b []byte) uint64 {
+	return ChecksumSeed(b, 0)
+}
+
+// ChecksumSeed returns the xxHash64 of b with the given seed
+func ChecksumSeed(b []byte, seed uint64) uint64 {
+	n := len(b)
+	var h uint64
+
+	if n >= 32 {
+		v := [4]uint64{seed + prime1 + prime2, seed + prime2, seed, seed - prime1}
+		blocks(&v, b)
+		b = b[n&^31:]
+		h = merge(&v)
+	} else {
+		h = seed + prime5
+	}
+
+	h += uint64(n)
+	return finalize(h, b)
+}
+
+// Digest computes an xxHash64 checksum incrementally
+type Digest struct {
+	seed    uint64
+	v       [4]uint64
+	total   uint64
+	mem     [32]byte
+	memSize int
+}
+
+// New creates a new Digest with a zero seed
+func New() *Digest {
+	return NewSeed(0)
+}
+
+// NewSeed creates a new Digest with the given seed
+func NewSeed(seed uint64) *Digest {
+	d := &Digest{seed: seed}
+	d.Reset()
+	return d
+}
+
+// Reset restores the Digest to its initial state
+func (d *Digest) Reset() {
+	d.v = [4]uint64{d.seed + prime1 + prime2, d.seed + prime2, d.seed, d.seed - prime1}
+	d.total = 0
+	d.memSize = 0
+}
+
+// Write adds more data to the running checksum. It never returns an error.
+func (d *Digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.total += uint64(n)
+
+	// Complete a partially filled stripe first
+	if d.memSize > 0 {
+		c := copy(d.mem[d.memSize:], p)
+		d.memSize += c
+		p = p[c:]
+		if d.memSize < 32 {
+			return n, nil
+		}
+		blocks(&d.v, d.mem[:])
+		d.memSize = 0
+	}
+
+	if len(p) >= 32 {
+		blocks(&d.v, p)
+		p = p[len(p)&^31:]
+	}
+
+	d.memSize = copy(d.mem[:], p)
+	return n, nil
+}
+
+// Sum64 returns the checksum of the data written so far
+func (d *Digest) Sum64() uint64 {
+	var h uint64
+	if d.total >= 32 {
+		h = merge(&d.v)
+	} else {
+		h = d.seed + prime5
+	}
+
+	h += d.total
+	return finalize(h, d.mem[:d.memSize])
+}
+
+// blocksGeneric mixes the whole 32-byte stripes of b into the accumulators
+// v, ignoring any bytes after the last one. It is the portable version of
+// blocks.
+func blocksGeneric(v *[4]uint64, b []byte) {
+	v1, v2, v3, v4 := v[0], v[1], v[2], v[3]
+	for len(b) >= 32 {
+		v1 = round(v1, binary.LittleEndian.Uint64(b[0:8]))
+		v2 = round(v2, binary.LittleEndian.Uint64(b[8:16]))
+		v3 = round(v3, binary.LittleEndian.Uint64(b[16:24]))
+		v4 = round(v4, binary.LittleEndian.Uint64(b[24:32]))
+		b = b[32:]
+	}
+	v[0], v[1], v[2], v[3] = v1, v2, v3, v4
+}
+
+// round mixes one 8-byte lane into an accumulator
+func round(acc, input uint64) uint64 {
+	acc += input * prime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * prime1
+}
+
+// mergeRound folds one accumulator into the converged hash
+func mergeRound(h, v uint64) uint64 {
+	h ^= round(0, v)
+	return h*prime1 + prime4
+}
+
+// merge converges the four accumulators into a single hash
+func merge(v *[4]uint64) uint64 {
+	h := bits.RotateLeft64(v[0], 1) + bits.RotateLeft64(v[1], 7) +
+		bits.RotateLeft64(v[2], 12) + bits.RotateLeft64(v[3], 18)
+	h = mergeRound(h, v[0])
+	h = mergeRound(h, v[1])
+	h = mergeRound(h, v[2])
+	return mergeRound(h, v[3])
+}
+
+// finalize mixes the remaining tail bytes and avalanches the hash
+func finalize(h uint64, tail []byte) uint64 {
+	for len(tail) >= 8 {
+		h ^= round(0, binary.LittleEndian.Uint64(tail))
+		h = bits.RotateLeft64(h, 27)*prime1 + prime4
+		tail = tail[8:]
+	}
+	if len(tail) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(tail)) * prime1
+		h = bits.RotateLeft64(h, 23)*prime2 + prime3
+		tail = tail[4:]
+	}
+	for _, c := range tail {
+		h ^= uint64(c) * prime5
+		h = bits.RotateLeft64(h, 11) * prime1
+	}
+
+	h ^= h >> 33
+	h *= prime2
+	h ^= h >> 29
+	h *= prime3
+	h ^= h >> 32
+	return h
+}
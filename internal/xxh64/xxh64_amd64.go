@@ -0,0 +1,9 @@
+//go:build amd64 && !purego
+
+package xxh64
+
+// blocks mixes the whole 32-byte stripes of b into the accumulators v,
+// ignoring any bytes after the last one
+//
+//go:noescape
+func blocks(v *[4]uint64, b []byte)
@@ -0,0 +1,44 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// round mixes the lane at off(SI) into the accumulator acc, with prime1
+// in R13 and prime2 in R14
+#define round(acc, off) \
+	MOVQ  off(SI), BX \
+	IMULQ R14, BX     \
+	ADDQ  BX, acc     \
+	ROLQ  $31, acc    \
+	IMULQ R13, acc
+
+// func blocks(v *[4]uint64, b []byte)
+TEXT ·blocks(SB), NOSPLIT, $0-32
+	MOVQ v+0(FP), AX
+	MOVQ b_base+8(FP), SI
+	MOVQ b_len+16(FP), DX
+	SHRQ $5, DX
+	JZ   done
+
+	MOVQ $0x9E3779B185EBCA87, R13
+	MOVQ $0xC2B2AE3D27D4EB4F, R14
+	MOVQ 0(AX), R8
+	MOVQ 8(AX), R9
+	MOVQ 16(AX), R10
+	MOVQ 24(AX), R11
+
+loop:
+	round(R8, 0)
+	round(R9, 8)
+	round(R10, 16)
+	round(R11, 24)
+	ADDQ $32, SI
+	DECQ DX
+	JNZ  loop
+
+	MOVQ R8, 0(AX)
+	MOVQ R9, 8(AX)
+	MOVQ R10, 16(AX)
+	MOVQ R11, 24(AX)
+
+done:
+	RET
@@ -0,0 +1,87 @@
+package xxh64
+
+import (
+	"testing"
+)
+
+// TestChecksum tests the one-shot checksum against reference vectors
+func TestChecksum(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	tests := []struct {
+		input string
+		seed  uint64
+		want  uint64
+	}{
+		{"", 0, 0xEF46DB3751D8E999},
+		{"a", 0, 0xD24EC4F1A98C6E5B},
+		{"abc", 0, 0x44BC2CF5AD770999},
+		{"Nobody inspects the spammish repetition", 0, 0xFBCEA83C8A378BF1},
+		{"Nobody inspects the spammish repetition", 0xDEADBEEF, 0x1366D5F609C44B7D},
+		{string(data), 0, 0x25275608A9CFC168},
+	}
+
+	for _, tt := range tests {
+		if got := ChecksumSeed([]byte(tt.input), tt.seed); got != tt.want {
+			t.Errorf("ChecksumSeed(%.20q, %d) = %#016x, want %#016x", tt.input, tt.seed, got, tt.want)
+		}
+	}
+}
+
+// TestDigest tests that incremental writes match the one-shot checksum
+func TestDigest(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, seed := range []uint64{0, 1, 0xDEADBEEF} {
+		for _, size := range []int{0, 1, 31, 32, 33, 100, 1000} {
+			want := ChecksumSeed(data[:size], seed)
+
+			for _, step := range []int{1, 3, 32, 64} {
+				d := NewSeed(seed)
+				for i := 0; i < size; i += step {
+					d.Write(data[i:min(i+step, size)])
+				}
+				if got := d.Sum64(); got != want {
+					t.Errorf("seed=%d size=%d step=%d: Sum64() = %#016x, want %#016x", seed, size, step, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestBlocks tests that blocks, in assembly where there is some, agrees
+// with blocksGeneric and leaves a partial stripe alone
+func TestBlocks(t *testing.T) {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i*131 + i>>3)
+	}
+
+	for _, size := range []int{0, 1, 31, 32, 33, 96, 999, 1000} {
+		for _, off := range []int{0, 1, 3} {
+			b := data[off:][:min(size, len(data)-off)]
+			v := [4]uint64{1, 2, 3, 4}
+			want := v
+			blocks(&v, b)
+			blocksGeneric(&want, b)
+			if v != want {
+				t.Errorf("size=%d off=%d: blocks() = %#x, want %#x", len(b), off, v, want)
+			}
+		}
+	}
+}
+
+// BenchmarkChecksum measures the one-shot checksum of 64KB
+func BenchmarkChecksum(b *testing.B) {
+	data := make([]byte, 64<<10)
+	b.SetBytes(int64(len(data)))
+	for range b.N {
+		Checksum(data)
+	}
+}
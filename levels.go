@@ -0,0 +1,20 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// NoCompression is the level that stores data without compressing it, in
+// valid blocks and frames.
+const NoCompression = int(compress.NoCompression)
+
+// LevelFromLZ4HC returns the level equivalent to a liblz4 HC level, for use
+// with CompressBlockLevel and NewWriterLevel. Levels below 1 select liblz4's
+// default of 9 and levels above 12 are clamped to 12.
+func LevelFromLZ4HC(level int) int {
+	return int(compress.LevelFromLZ4HC(level))
+}
+
+// LevelFast returns the level equivalent to liblz4's fast compressor with the
+// given acceleration: 2 for the default acceleration of 1, otherwise 1.
+func LevelFast(acceleration int) int {
+	return int(compress.LevelFast(acceleration))
+}
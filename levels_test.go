@@ -0,0 +1,58 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestLevelMapping tests that mapped liblz4 levels compress and round-trip
+func TestLevelMapping(t *testing.T) {
+	data := bytes.Repeat([]byte("liblz4 compatible compression levels. "), 500)
+
+	levels := []int{LevelFast(1), LevelFast(8), LevelFromLZ4HC(0), LevelFromLZ4HC(4), LevelFromLZ4HC(12)}
+	want := []int{2, 1, 9, 4, 12}
+
+	for i, level := range levels {
+		if level != want[i] {
+			t.Errorf("levels[%d] = %d, want %d", i, level, want[i])
+		}
+
+		compressed, err := CompressBlockLevel(data, nil, level)
+		if err != nil {
+			t.Fatalf("CompressBlockLevel(level %d) error = %v", level, err)
+		}
+		decompressed, err := DecompressBlock(compressed, nil, len(data))
+		if err != nil {
+			t.Fatalf("DecompressBlock(level %d) error = %v", level, err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("level %d: round trip mismatch", level)
+		}
+	}
+}
+
+// TestNoCompression tests writing a stored frame at level 0
+func TestNoCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("stored, not compressed. "), 1000)
+
+	var buf bytes.Buffer
+	w := NewWriterLevel(&buf, NoCompression)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.Len() <= len(data) {
+		t.Errorf("frame is %d bytes, want more than the %d input bytes", buf.Len(), len(data))
+	}
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,24 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// ErrOutputLimit is returned by CompressBlockLimit for a block that doesn't
+// compress into its maximum output size.
+var ErrOutputLimit = compress.ErrOutputLimit
+
+// CompressBlockLimit compresses src at the default level into at most maxOut
+// bytes, for fixed-size output slots. It gives up with ErrOutputLimit as
+// soon as the output is sure to exceed maxOut, so incompressible input is
+// rejected without compressing the rest of it.
+func CompressBlockLimit(src []byte, dst []byte, maxOut int) ([]byte, error) {
+	return compress.CompressBlockLimit(src, dst, maxOut)
+}
+
+// DecompressionLimits bounds the size, block count and expansion ratio of
+// every frame a Reader decodes. Set it as ReaderOptions.Limits when reading
+// untrusted input.
+type DecompressionLimits = compress.DecompressionLimits
+
+// ErrDecompressionBomb is returned by a Reader for a frame past its
+// DecompressionLimits.
+var ErrDecompressionBomb = compress.ErrDecompressionBomb
@@ -0,0 +1,24 @@
+package goz4x
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestCompressBlockLimit tests the root CompressBlockLimit wrapper
+func TestCompressBlockLimit(t *testing.T) {
+	src := generateCompressibleData(64 * 1024)
+	want, err := CompressBlock(src, nil)
+	if err != nil {
+		t.Fatalf("CompressBlock error: %v", err)
+	}
+
+	got, err := CompressBlockLimit(src, nil, len(want))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("CompressBlockLimit at the compressed size = %d bytes, %v, want %d bytes", len(got), err, len(want))
+	}
+	if _, err := CompressBlockLimit(src, nil, len(want)-1); !errors.Is(err, ErrOutputLimit) {
+		t.Errorf("CompressBlockLimit below the compressed size error = %v, want %v", err, ErrOutputLimit)
+	}
+}
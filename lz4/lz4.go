@@ -0,0 +1,210 @@
+// Package lz4 mirrors the API of github.com/pierrec/lz4/v4 on top of GoZ4X.
+//
+// The functions, types and options keep the names and signatures of
+// pierrec/lz4, so most programs migrate by changing the import path, and
+// tests can compare the output of both libraries through the same calls.
+// Frames and blocks are standard LZ4: the tests decode frames and blocks of
+// the reference lz4 tool and have the tool decode every kind of frame Writer
+// writes.
+//
+// The differences are those of the underlying implementation: legacy frames
+// are neither written nor read, blocks over 4MB are reported incompressible
+// rather than compressed, and compression levels are mapped to the nearest
+// GoZ4X levels, so the compressed bytes differ from pierrec/lz4's.
+package lz4
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+var (
+	// ErrInvalidSourceShortBuffer indicates a corrupt block or a
+	// destination buffer too short for its decompressed data
+	ErrInvalidSourceShortBuffer = errors.New("lz4: invalid source or destination buffer too short")
+
+	// ErrInvalidBlockChecksum indicates a block checksum mismatch
+	ErrInvalidBlockChecksum = compress.ErrBlockChecksum
+
+	// ErrInvalidFrameChecksum indicates a content checksum mismatch
+	ErrInvalidFrameChecksum = compress.ErrContentChecksum
+
+	// ErrOptionInvalidCompressionLevel indicates a level other than Fast
+	// and Level1 to Level9
+	ErrOptionInvalidCompressionLevel = errors.New("lz4: invalid compression level")
+
+	// ErrOptionInvalidBlockSize indicates a block size other than the
+	// BlockSize constants
+	ErrOptionInvalidBlockSize = errors.New("lz4: invalid block size")
+
+	// ErrOptionNotApplicable indicates an option the Reader or Writer it is
+	// applied to doesn't support
+	ErrOptionNotApplicable = errors.New("lz4: option not applicable")
+
+	// ErrOptionClosedOrError indicates options applied once a Writer has
+	// started writing, or after an error
+	ErrOptionClosedOrError = errors.New("lz4: cannot apply options on closed or in error object")
+)
+
+// CompressionLevel is the compression level of a Writer or CompressBlockHC
+type CompressionLevel uint32
+
+// Compression levels, with the values used by pierrec/lz4. Fast is GoZ4X
+// level 2, liblz4's default fast compressor, and Level1 to Level9 are GoZ4X
+// levels 4 to 12.
+const (
+	Fast   CompressionLevel = 0
+	Level1 CompressionLevel = 1 << (8 + iota)
+	Level2
+	Level3
+	Level4
+	Level5
+	Level6
+	Level7
+	Level8
+	Level9
+)
+
+// goz4x returns the GoZ4X level of l and whether l is a valid level
+func (l CompressionLevel) goz4x() (compress.CompressionLevel, bool) {
+	if l == Fast {
+		return compress.LevelFast(1), true
+	}
+	n := bits.TrailingZeros32(uint32(l)) - 8
+	if bits.OnesCount32(uint32(l)) != 1 || n < 1 || n > 9 {
+		return 0, false
+	}
+	return compress.CompressionLevel(n + 3), true
+}
+
+// BlockSize is the maximum block size of a frame
+type BlockSize uint32
+
+// Block sizes, with the values used by pierrec/lz4
+const (
+	Block64Kb BlockSize = 1 << (16 + 2*iota)
+	Block256Kb
+	Block1Mb
+	Block4Mb
+)
+
+// code returns the block size code of b and whether b is a valid size
+func (b BlockSize) code() (compress.BlockSizeCode, bool) {
+	code := compress.CodeFromBlockSize(int(b))
+	return code, code.Size() == int(b)
+}
+
+// CompressBlockBound returns the maximum size of a block compressed from n
+// bytes, saturating at math.MaxInt for sizes near it
+func CompressBlockBound(n int) int {
+	if n > math.MaxInt-16-n/255 {
+		return math.MaxInt
+	}
+	return n + n/255 + 16
+}
+
+// CompressBlock compresses src into dst at the Fast level and returns the
+// compressed size. A size of 0 means src is incompressible: its compressed
+// form isn't smaller than src or doesn't fit in dst. The last argument is
+// ignored; it is the hash table pierrec/lz4 accepts.
+func CompressBlock(src, dst []byte, _ []int) (int, error) {
+	var c Compressor
+	return c.CompressBlock(src, dst)
+}
+
+// CompressBlockHC is like CompressBlock at the given level. The last two
+// arguments are ignored.
+func CompressBlockHC(src, dst []byte, depth CompressionLevel, _, _ []int) (int, error) {
+	c := CompressorHC{Level: depth}
+	return c.CompressBlock(src, dst)
+}
+
+// UncompressBlock decompresses src into dst and returns the decompressed
+// size. It fails with ErrInvalidSourceShortBuffer if src is corrupt or the
+// data doesn't fit in len(dst).
+func UncompressBlock(src, dst []byte) (int, error) {
+	n, err := compress.DecompressBlockInto(src, dst)
+	if err != nil {
+		return 0, ErrInvalidSourceShortBuffer
+	}
+	return n, nil
+}
+
+// UncompressBlockWithDict is like UncompressBlock for blocks compressed
+// with dict as the preceding data
+func UncompressBlockWithDict(src, dst, dict []byte) (int, error) {
+	if len(dict) == 0 {
+		return UncompressBlock(src, dst)
+	}
+	out, err := compress.DecompressBlockDict(src, dict, len(dst))
+	if err != nil {
+		return 0, ErrInvalidSourceShortBuffer
+	}
+	return copy(dst, out), nil
+}
+
+// Compressor compresses blocks at the Fast level, keeping its tables
+// between calls. The zero value is ready to use. It is not safe for
+// concurrent use.
+type Compressor struct {
+	c *compress.Compressor
+}
+
+// CompressBlock is like the CompressBlock function
+func (c *Compressor) CompressBlock(src, dst []byte) (int, error) {
+	if c.c == nil {
+		level, _ := Fast.goz4x()
+		c.c, _ = compress.NewCompressor(level)
+	}
+	return compressBlock(c.c, src, dst)
+}
+
+// CompressorHC compresses blocks at Level, keeping its tables between
+// calls. The zero value compresses at the Fast level. It is not safe for
+// concurrent use.
+type CompressorHC struct {
+	// Level is the compression level
+	Level CompressionLevel
+
+	c *compress.Compressor
+}
+
+// CompressBlock is like the CompressBlockHC function at c.Level
+func (c *CompressorHC) CompressBlock(src, dst []byte) (int, error) {
+	level, ok := c.Level.goz4x()
+	if !ok {
+		return 0, ErrOptionInvalidCompressionLevel
+	}
+	if c.c == nil || c.c.Level() != level {
+		c.c, _ = compress.NewCompressor(level)
+	}
+	return compressBlock(c.c, src, dst)
+}
+
+// compressBlock compresses src into dst with c, returning 0 for blocks
+// that are incompressible or outside the sizes GoZ4X compresses
+func compressBlock(c *compress.Compressor, src, dst []byte) (int, error) {
+	if len(src) < compress.MinBlockSize || len(src) > compress.MaxBlockSize {
+		return 0, nil
+	}
+
+	// Compress in place when dst can hold any result
+	var buf []byte
+	if len(dst) >= CompressBlockBound(len(src)) {
+		buf = dst
+	}
+	out, err := c.CompressBlock(src, buf)
+	if err != nil {
+		return 0, err
+	}
+	if len(out) >= len(src) || len(out) > len(dst) {
+		return 0, nil
+	}
+	if &out[0] != &dst[0] {
+		copy(dst, out)
+	}
+	return len(out), nil
+}
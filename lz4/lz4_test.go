@@ -0,0 +1,300 @@
+package lz4
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// testData returns size bytes of compressible text
+func testData(size int) []byte {
+	words := []string{"lorem ", "ipsum ", "dolor ", "sit ", "amet ", "consectetur "}
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < size {
+		buf.WriteString(words[rng.Intn(len(words))])
+	}
+	return buf.Bytes()[:size]
+}
+
+// TestBlockRoundTrip tests the block functions at every level
+func TestBlockRoundTrip(t *testing.T) {
+	data := testData(100000)
+	levels := []CompressionLevel{Fast, Level1, Level5, Level9}
+	for _, level := range levels {
+		dst := make([]byte, CompressBlockBound(len(data)))
+		n, err := CompressBlockHC(data, dst, level, nil, nil)
+		if err != nil || n == 0 {
+			t.Fatalf("level %d: CompressBlockHC() = %d, %v", level, n, err)
+		}
+		// The blocks are standard LZ4, readable by GoZ4X directly
+		got, err := compress.DecompressBlockAlloc(dst[:n], len(data))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("level %d: DecompressBlockAlloc() error = %v", level, err)
+		}
+		out := make([]byte, len(data))
+		m, err := UncompressBlock(dst[:n], out)
+		if err != nil || m != len(data) || !bytes.Equal(out, data) {
+			t.Fatalf("level %d: UncompressBlock() = %d, %v", level, m, err)
+		}
+	}
+}
+
+// TestCompressBlockIncompressible tests that blocks which don't shrink or
+// don't fit report a size of 0
+func TestCompressBlockIncompressible(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(2)).Read(random)
+	dst := make([]byte, CompressBlockBound(len(random)))
+	if n, err := CompressBlock(random, dst, nil); n != 0 || err != nil {
+		t.Errorf("random data: CompressBlock() = %d, %v, want 0, nil", n, err)
+	}
+
+	data := testData(4096)
+	if n, err := CompressBlock(data, make([]byte, 10), nil); n != 0 || err != nil {
+		t.Errorf("short dst: CompressBlock() = %d, %v, want 0, nil", n, err)
+	}
+	if n, err := CompressBlock(data[:8], dst, nil); n != 0 || err != nil {
+		t.Errorf("tiny src: CompressBlock() = %d, %v, want 0, nil", n, err)
+	}
+
+	// A dst shorter than the bound still works when the result fits
+	var c Compressor
+	n, err := c.CompressBlock(data, make([]byte, len(data)))
+	if n == 0 || err != nil {
+		t.Errorf("dst of len(src): CompressBlock() = %d, %v", n, err)
+	}
+}
+
+// TestUncompressBlockShortBuffer tests the error for a short destination
+func TestUncompressBlockShortBuffer(t *testing.T) {
+	data := testData(4096)
+	dst := make([]byte, CompressBlockBound(len(data)))
+	n, _ := CompressBlock(data, dst, nil)
+	if _, err := UncompressBlock(dst[:n], make([]byte, 100)); err != ErrInvalidSourceShortBuffer {
+		t.Errorf("UncompressBlock() error = %v, want ErrInvalidSourceShortBuffer", err)
+	}
+}
+
+// TestInvalidLevel tests that levels other than the constants are rejected
+func TestInvalidLevel(t *testing.T) {
+	for _, level := range []CompressionLevel{1, Level1 + 1, Level9 << 1} {
+		if _, err := CompressBlockHC(testData(100), make([]byte, 200), level, nil, nil); err != ErrOptionInvalidCompressionLevel {
+			t.Errorf("CompressBlockHC(level %d) error = %v", level, err)
+		}
+		if err := NewWriter(io.Discard).Apply(CompressionLevelOption(level)); err != ErrOptionInvalidCompressionLevel {
+			t.Errorf("CompressionLevelOption(%d) error = %v", level, err)
+		}
+	}
+	if err := NewWriter(io.Discard).Apply(BlockSizeOption(1000)); err != ErrOptionInvalidBlockSize {
+		t.Errorf("BlockSizeOption(1000) error = %v", err)
+	}
+}
+
+// TestStreamRoundTrip tests Writer and Reader with various options
+func TestStreamRoundTrip(t *testing.T) {
+	data := testData(300000)
+	tests := []struct {
+		name    string
+		options []Option
+	}{
+		{"default", nil},
+		{"level", []Option{CompressionLevelOption(Level3)}},
+		{"block64k", []Option{BlockSizeOption(Block64Kb), BlockChecksumOption(true)}},
+		{"nochecksum", []Option{ChecksumOption(false)}},
+		{"size", []Option{SizeOption(uint64(len(data)))}},
+		{"concurrent", []Option{ConcurrencyOption(4), BlockSizeOption(Block64Kb)}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if err := w.Apply(tt.options...); err != nil {
+			t.Fatalf("%s: Apply() error = %v", tt.name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("%s: Write() error = %v", tt.name, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close() error = %v", tt.name, err)
+		}
+		frame := buf.Bytes()
+
+		got, err := io.ReadAll(NewReader(bytes.NewReader(frame)))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("%s: Reader got %d bytes, error = %v", tt.name, len(got), err)
+		}
+		got, err = io.ReadAll(compress.NewReader(bytes.NewReader(frame)))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("%s: compress.Reader got %d bytes, error = %v", tt.name, len(got), err)
+		}
+	}
+}
+
+// TestWriterOptionsAfterWrite tests that options can't change mid-frame but
+// survive Reset
+func TestWriterOptionsAfterWrite(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.Apply(BlockChecksumOption(true)); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello"))
+	if err := w.Apply(ChecksumOption(false)); err != ErrOptionClosedOrError {
+		t.Errorf("Apply() after Write error = %v, want ErrOptionClosedOrError", err)
+	}
+	w.Close()
+
+	buf.Reset()
+	w.Reset(&buf)
+	w.Write([]byte("hello"))
+	w.Close()
+	r := compress.NewReader(bytes.NewReader(buf.Bytes()))
+	if err := r.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Flags().Has(compress.FlagBlockChecksum) {
+		t.Error("block checksums not kept across Reset")
+	}
+}
+
+// TestOnBlockDone tests the block handler of Writer and Reader
+func TestOnBlockDone(t *testing.T) {
+	data := testData(200000)
+	var buf bytes.Buffer
+	var compressed int
+	w := NewWriter(&buf)
+	w.Apply(BlockSizeOption(Block64Kb), OnBlockDoneOption(func(n int) { compressed += n }))
+	w.Write(data)
+	w.Close()
+	if compressed == 0 || compressed >= len(data) {
+		t.Errorf("writer handler got %d compressed bytes for %d", compressed, len(data))
+	}
+
+	var raw int
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	r.Apply(OnBlockDoneOption(func(n int) { raw += n }))
+	io.Copy(io.Discard, r)
+	if raw != len(data) {
+		t.Errorf("reader handler got %d bytes, want %d", raw, len(data))
+	}
+}
+
+// TestReaderSize tests the content size reported by Reader
+func TestReaderSize(t *testing.T) {
+	data := testData(1000)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Apply(SizeOption(uint64(len(data))))
+	w.Write(data)
+	w.Close()
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	r.Read(make([]byte, 10))
+	if got := r.Size(); got != len(data) {
+		t.Errorf("Size() = %d, want %d", got, len(data))
+	}
+}
+
+// TestNotApplicable tests options that don't apply to their receiver
+func TestNotApplicable(t *testing.T) {
+	if err := NewWriter(io.Discard).Apply(LegacyOption(true)); err != ErrOptionNotApplicable {
+		t.Errorf("LegacyOption(true) error = %v", err)
+	}
+	if err := NewWriter(io.Discard).Apply(LegacyOption(false)); err != nil {
+		t.Errorf("LegacyOption(false) error = %v", err)
+	}
+	if err := NewReader(nil).Apply(BlockSizeOption(Block64Kb)); err != ErrOptionNotApplicable {
+		t.Errorf("Reader BlockSizeOption error = %v", err)
+	}
+}
+
+// goldenDir holds the frames and blocks of the reference lz4 tool
+var goldenDir = filepath.Join("..", "compress", "testdata", "golden")
+
+// TestGoldenFrames tests decoding the frames and blocks of the reference
+// lz4 tool through the pierrec/lz4 API
+func TestGoldenFrames(t *testing.T) {
+	frames, err := filepath.Glob(filepath.Join(goldenDir, "*.lz4"))
+	if err != nil || len(frames) == 0 {
+		t.Fatalf("no golden frames in %s: %v", goldenDir, err)
+	}
+	for _, path := range frames {
+		name := strings.TrimSuffix(filepath.Base(path), ".lz4")
+		raw := filepath.Join(goldenDir, strings.TrimSuffix(name, "-linked")+".raw")
+		want, err := os.ReadFile(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		frame, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := io.ReadAll(NewReader(bytes.NewReader(frame)))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s: Reader got %d bytes, error = %v, want %d bytes", name, len(got), err, len(want))
+		}
+
+		block, err := os.ReadFile(filepath.Join(goldenDir, name+".block"))
+		if err != nil {
+			continue
+		}
+		dst := make([]byte, len(want))
+		if n, err := UncompressBlock(block, dst); err != nil || !bytes.Equal(dst[:n], want) {
+			t.Errorf("%s: UncompressBlock() = %d, %v, want %d bytes", name, n, err, len(want))
+		}
+	}
+}
+
+// TestLZ4Tool tests that the reference lz4 tool decodes the frames of
+// Writer with every option, and that Reader decodes the tool's frames
+func TestLZ4Tool(t *testing.T) {
+	tool, err := exec.LookPath("lz4")
+	if err != nil {
+		t.Skip("lz4 tool not found")
+	}
+	data := testData(300000)
+
+	for _, options := range [][]Option{
+		nil,
+		{CompressionLevelOption(Level9)},
+		{BlockSizeOption(Block64Kb), BlockChecksumOption(true)},
+		{ChecksumOption(false), SizeOption(uint64(len(data)))},
+		{ConcurrencyOption(4), BlockSizeOption(Block256Kb)},
+	} {
+		var frame bytes.Buffer
+		w := NewWriter(&frame)
+		if err := w.Apply(options...); err != nil {
+			t.Fatalf("Apply() error = %v", err)
+		}
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		cmd := exec.Command(tool, "-dc")
+		cmd.Stdin = &frame
+		if got, err := cmd.Output(); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("lz4 -dc of %d options = %d bytes, %v, want %d bytes", len(options), len(got), err, len(data))
+		}
+	}
+
+	for _, flags := range [][]string{{"-1"}, {"-12", "-B4", "-BD"}, {"--content-size", "-BX", "-B5"}} {
+		cmd := exec.Command(tool, append(flags, "-c")...)
+		cmd.Stdin = bytes.NewReader(data)
+		frame, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("lz4 %v error = %v", flags, err)
+		}
+		got, err := io.ReadAll(NewReader(bytes.NewReader(frame)))
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("decoding lz4 %v output = %d bytes, %v, want %d bytes", flags, len(got), err, len(data))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package lz4
+
+// Applier is implemented by the Reader and Writer, which options configure
+type Applier interface {
+	Apply(...Option) error
+	private()
+}
+
+// Option configures a Reader or Writer through its Apply method. Options
+// that don't apply to the receiver fail with ErrOptionNotApplicable.
+type Option func(Applier) error
+
+// BlockChecksumOption enables or disables block checksums. The default is
+// disabled.
+func BlockChecksumOption(flag bool) Option {
+	return func(a Applier) error {
+		w, ok := a.(*Writer)
+		if !ok {
+			return ErrOptionNotApplicable
+		}
+		w.blockChecksum = flag
+		return nil
+	}
+}
+
+// BlockSizeOption sets the maximum block size. The default is Block4Mb.
+func BlockSizeOption(size BlockSize) Option {
+	return func(a Applier) error {
+		w, ok := a.(*Writer)
+		if !ok {
+			return ErrOptionNotApplicable
+		}
+		if _, ok := size.code(); !ok {
+			return ErrOptionInvalidBlockSize
+		}
+		w.blockSize = size
+		return nil
+	}
+}
+
+// ChecksumOption enables or disables the content checksum. The default is
+// enabled.
+func ChecksumOption(flag bool) Option {
+	return func(a Applier) error {
+		w, ok := a.(*Writer)
+		if !ok {
+			return ErrOptionNotApplicable
+		}
+		w.checksum = flag
+		return nil
+	}
+}
+
+// CompressionLevelOption sets the compression level. The default is Fast.
+func CompressionLevelOption(level CompressionLevel) Option {
+	return func(a Applier) error {
+		w, ok := a.(*Writer)
+		if !ok {
+			return ErrOptionNotApplicable
+		}
+		if _, ok := level.goz4x(); !ok {
+			return ErrOptionInvalidCompressionLevel
+		}
+		w.level = level
+		return nil
+	}
+}
+
+// ConcurrencyOption sets the number of goroutines compressing blocks. Any
+// value other than 1 compresses each full block in the background while the
+// next one fills, as the AsyncFlush writer option does. Readers accept and
+// ignore it.
+func ConcurrencyOption(n int) Option {
+	return func(a Applier) error {
+		if w, ok := a.(*Writer); ok {
+			w.async = n != 1
+		}
+		return nil
+	}
+}
+
+// LegacyOption selects the legacy frame format, which GoZ4X doesn't write:
+// enabling it fails with ErrOptionNotApplicable
+func LegacyOption(legacy bool) Option {
+	return func(a Applier) error {
+		if _, ok := a.(*Writer); !ok || legacy {
+			return ErrOptionNotApplicable
+		}
+		return nil
+	}
+}
+
+// OnBlockDoneOption sets a function called after every block, with the
+// compressed size of the block for a Writer and its decompressed size for
+// a Reader
+func OnBlockDoneOption(handler func(size int)) Option {
+	return func(a Applier) error {
+		switch a := a.(type) {
+		case *Writer:
+			a.handler = handler
+		case *Reader:
+			a.handler = handler
+		}
+		return nil
+	}
+}
+
+// SizeOption sets the uncompressed size declared in the frame header. Zero,
+// the default, declares none.
+func SizeOption(size uint64) Option {
+	return func(a Applier) error {
+		w, ok := a.(*Writer)
+		if !ok {
+			return ErrOptionNotApplicable
+		}
+		w.size = size
+		return nil
+	}
+}
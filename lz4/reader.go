@@ -0,0 +1,77 @@
+package lz4
+
+import (
+	"io"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Reader decompresses LZ4 frames read from an underlying reader.
+// Concatenated frames are read as one stream.
+type Reader struct {
+	z       *compress.Reader
+	started bool
+	handler func(size int)
+}
+
+// NewReader returns a Reader decompressing from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{z: compress.NewReader(r)}
+}
+
+// private implements Applier
+func (*Reader) private() {}
+
+// Apply applies options to the Reader. They must be applied before the
+// first Read; afterwards it fails with ErrOptionClosedOrError.
+func (r *Reader) Apply(options ...Option) error {
+	if r.started {
+		return ErrOptionClosedOrError
+	}
+	for _, o := range options {
+		if err := o(r); err != nil {
+			return err
+		}
+	}
+	if r.handler != nil {
+		r.z.SetMetricsRecorder(blockRead(r.handler))
+	} else {
+		r.z.SetMetricsRecorder(nil)
+	}
+	return nil
+}
+
+// Read decompresses data into p
+func (r *Reader) Read(p []byte) (int, error) {
+	r.started = true
+	return r.z.Read(p)
+}
+
+// WriteTo implements io.WriterTo, decompressing everything to w
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	r.started = true
+	return r.z.WriteTo(w)
+}
+
+// Size returns the uncompressed size declared in the frame header, or 0 if
+// none was declared or the header hasn't been read yet
+func (r *Reader) Size() int {
+	size, _ := r.z.ContentSize()
+	return int(size)
+}
+
+// Reset discards the Reader's state and makes it read from reader,
+// keeping the options applied so far
+func (r *Reader) Reset(reader io.Reader) {
+	r.z.Reset(reader)
+	r.started = false
+}
+
+// blockRead adapts an OnBlockDoneOption handler to a MetricsRecorder
+type blockRead func(size int)
+
+// RecordBlock implements compress.MetricsRecorder with the decompressed size
+func (f blockRead) RecordBlock(_, raw int, _ time.Duration) {
+	f(raw)
+}
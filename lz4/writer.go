@@ -0,0 +1,119 @@
+package lz4
+
+import (
+	"io"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Writer compresses data written to it as an LZ4 frame.
+// Close must be called to finish the frame.
+type Writer struct {
+	dst io.Writer
+	z   *compress.Writer
+
+	// Options, applied to z when the frame starts
+	level         CompressionLevel
+	blockSize     BlockSize
+	checksum      bool
+	blockChecksum bool
+	async         bool
+	size          uint64
+	handler       func(size int)
+}
+
+// NewWriter returns a Writer compressing to w with the default options:
+// the Fast level, 4MB blocks and a content checksum
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{dst: w, blockSize: Block4Mb, checksum: true}
+}
+
+// private implements Applier
+func (*Writer) private() {}
+
+// Apply applies options to the Writer. They must be applied before the
+// first Write; afterwards it fails with ErrOptionClosedOrError.
+func (w *Writer) Apply(options ...Option) error {
+	if w.z != nil {
+		return ErrOptionClosedOrError
+	}
+	for _, o := range options {
+		if err := o(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// start creates the underlying Writer from the options, at the first Write
+// or Close
+func (w *Writer) start() error {
+	if w.z != nil {
+		return nil
+	}
+
+	level, _ := w.level.goz4x()
+	code, _ := w.blockSize.code()
+	options := compress.WriterOptions{
+		Level:           level,
+		BlockSizeCode:   code,
+		ContentChecksum: w.checksum,
+		AsyncFlush:      w.async,
+	}
+	if w.blockChecksum {
+		options.BlockChecksum = compress.XXH32
+	}
+	z := compress.NewWriterWithOptions(w.dst, options)
+	if w.size > 0 {
+		if err := z.SetContentSize(w.size); err != nil {
+			return err
+		}
+	}
+	if w.handler != nil {
+		z.SetMetricsRecorder(blockDone(w.handler))
+	}
+	w.z = z
+	return nil
+}
+
+// Write compresses p, writing out every block it fills
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return w.z.Write(p)
+}
+
+// ReadFrom implements io.ReaderFrom, compressing everything read from r.
+// The frame still has to be finished with Close.
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return io.Copy(w.z, r)
+}
+
+// Close flushes buffered data and finishes the frame. It doesn't close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.z.Close()
+}
+
+// Reset discards the Writer's state and makes it write a new frame to
+// writer, keeping the options applied so far
+func (w *Writer) Reset(writer io.Writer) {
+	w.dst = writer
+	w.z = nil
+}
+
+// blockDone adapts an OnBlockDoneOption handler to a MetricsRecorder
+type blockDone func(size int)
+
+// RecordBlock implements compress.MetricsRecorder with the compressed size
+func (f blockDone) RecordBlock(compressed, _ int, _ time.Duration) {
+	f(compressed)
+}
@@ -122,7 +122,7 @@ func (m *GenericMatcher[I]) FindBestMatch() (offset I, length I) {
 	current := m.hashTable[h]
 
 	// No match found
-	if current <= 0 || current <= m.pos-m.windowSize {
+	if current <= 0 || current < m.pos-m.windowSize {
 		m.InsertHash(m.pos)
 		return 0, 0
 	}
@@ -130,7 +130,8 @@ func (m *GenericMatcher[I]) FindBestMatch() (offset I, length I) {
 	// Find the best match
 	var bestLength I = 0
 	var bestOffset I = 0
-	limit := m.pos - m.windowSize
+	// Offsets reach windowSize
+	limit := m.pos - m.windowSize - 1
 	attempts := m.maxAttempts
 
 	for current > limit && attempts > 0 {
@@ -125,7 +125,7 @@ func (m *LZ4XMatcher) FindBestMatch() (offset, length int) {
 	current := m.hashTable[h4]
 
 	// No match found
-	if current <= 0 || current <= m.pos-m.windowSize || current >= m.pos {
+	if current <= 0 || current < m.pos-m.windowSize || current >= m.pos {
 		m.InsertHash(m.pos)
 		return 0, 0
 	}
@@ -133,7 +133,8 @@ func (m *LZ4XMatcher) FindBestMatch() (offset, length int) {
 	// Find the best match
 	bestLength := 0
 	bestOffset := 0
-	limit := m.pos - m.windowSize
+	// Offsets reach windowSize
+	limit := m.pos - m.windowSize - 1
 	attempts := m.maxAttempts
 
 	// Check 4-byte hash matches
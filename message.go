@@ -0,0 +1,62 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Encoder writes length-prefixed compressed messages to an io.Writer.
+// It is intended for RPC-style protocols that need per-message compression
+// without managing LZ4 frames themselves.
+type Encoder struct {
+	e *compress.Encoder
+}
+
+// NewEncoder creates a new Encoder that writes messages to w using the default compression level.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{e: compress.NewEncoder(w)}
+}
+
+// NewEncoderLevel creates a new Encoder that writes messages to w using the specified compression level.
+// Levels range from 1 (fastest) to 12 (best compression).
+func NewEncoderLevel(w io.Writer, level int) *Encoder {
+	return &Encoder{e: compress.NewEncoderLevel(w, compress.CompressionLevel(level))}
+}
+
+// Encode compresses msg and writes it to the underlying writer as a single message.
+func (e *Encoder) Encode(msg []byte) error {
+	return e.e.Encode(msg)
+}
+
+// EncodeLevel writes msg as a single message like Encode, compressed at
+// level instead of the Encoder's level.
+func (e *Encoder) EncodeLevel(msg []byte, level int) error {
+	return e.e.EncodeLevel(msg, compress.CompressionLevel(level))
+}
+
+// Reset resets the Encoder to write to dst.
+func (e *Encoder) Reset(dst io.Writer) {
+	e.e.Reset(dst)
+}
+
+// Decoder reads messages written by an Encoder.
+type Decoder struct {
+	d *compress.Decoder
+}
+
+// NewDecoder creates a new Decoder that reads messages from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{d: compress.NewDecoder(r)}
+}
+
+// Decode reads and decompresses the next message.
+// It returns io.EOF once the stream ends cleanly between messages.
+func (d *Decoder) Decode() ([]byte, error) {
+	return d.d.Decode()
+}
+
+// Reset resets the Decoder to read from src.
+func (d *Decoder) Reset(src io.Reader) {
+	d.d.Reset(src)
+}
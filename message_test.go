@@ -0,0 +1,39 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestEncoderDecoder tests the message-oriented API over a shared buffer
+func TestEncoderDecoder(t *testing.T) {
+	messages := [][]byte{
+		[]byte("ping"),
+		generateCompressibleData(32 * 1024),
+		generateRandomData(1024),
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoderLevel(&buf, 9)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	for i, want := range messages {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Decode(%d) error = %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Decode(%d) mismatch", i)
+		}
+	}
+
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode() at end error = %v, want io.EOF", err)
+	}
+}
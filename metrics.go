@@ -0,0 +1,20 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// MetricsRecorder receives per-block measurements from streaming readers and
+// writers: the block size as stored, its uncompressed size and the time spent
+// compressing or decompressing it.
+type MetricsRecorder = compress.MetricsRecorder
+
+// SetMetricsRecorder sets the recorder notified of every block written.
+// A nil recorder disables metrics.
+func (w *Writer) SetMetricsRecorder(m MetricsRecorder) {
+	w.w.SetMetricsRecorder(m)
+}
+
+// SetMetricsRecorder sets the recorder notified of every block decoded.
+// A nil recorder disables metrics.
+func (r *Reader) SetMetricsRecorder(m MetricsRecorder) {
+	r.r.SetMetricsRecorder(m)
+}
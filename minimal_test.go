@@ -0,0 +1,30 @@
+package goz4x
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMinimalBuild tests that the goz4x_minimal build leaves out the
+// parallel and SIMD packages
+func TestMinimalBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping go list in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	out, err := exec.Command(goTool, "list", "-deps", "-tags", "goz4x_minimal", ".").Output()
+	if err != nil {
+		t.Fatalf("go list error = %v", err)
+	}
+	for _, pkg := range strings.Fields(string(out)) {
+		switch strings.TrimPrefix(pkg, "github.com/harriteja/GoZ4X/") {
+		case "v03", "v04", "v04/simd", "parallel":
+			t.Errorf("minimal build imports %s", pkg)
+		}
+	}
+}
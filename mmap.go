@@ -0,0 +1,14 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// CompressMmap compresses the file at path to dst as a single frame,
+// memory mapping it where the platform allows to avoid copying it through
+// read buffers. It returns the number of uncompressed bytes written.
+func CompressMmap(dst io.Writer, path string, opts WriterOptions) (int64, error) {
+	return compress.CompressMmap(dst, path, opts)
+}
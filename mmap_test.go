@@ -0,0 +1,35 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCompressMmap tests compressing a memory mapped file
+func TestCompressMmap(t *testing.T) {
+	data := bytes.Repeat([]byte("mapped straight from the page cache "), 10000)
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := CompressMmap(&buf, path, WriterOptions{Level: 6})
+	if err != nil {
+		t.Fatalf("CompressMmap() error = %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("CompressMmap() = %d, want %d", n, len(data))
+	}
+
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,50 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Muxer interleaves several labeled substreams, such as per-partition logs,
+// over one io.Writer. Each write becomes one LZ4 frame preceded by a skippable
+// frame carrying its label, so plain readers still decode the whole stream.
+type Muxer struct {
+	m *compress.Muxer
+}
+
+// NewMuxer creates a new Muxer that writes frames to w with the given options.
+func NewMuxer(w io.Writer, opts WriterOptions) *Muxer {
+	return &Muxer{m: compress.NewMuxer(w, opts)}
+}
+
+// Write compresses p as one frame of the substream named label.
+func (m *Muxer) Write(label string, p []byte) error {
+	return m.m.Write(label, p)
+}
+
+// Channel returns an io.Writer that sends every write to the substream named label.
+func (m *Muxer) Channel(label string) io.Writer {
+	return m.m.Channel(label)
+}
+
+// Demuxer splits a stream written by a Muxer into one io.Reader per label,
+// decompressing each frame only when its label is read.
+type Demuxer struct {
+	d *compress.Demuxer
+}
+
+// NewDemuxer creates a new Demuxer reading a multiplexed stream from r.
+func NewDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{d: compress.NewDemuxer(r)}
+}
+
+// Reader returns a reader for the substream named label.
+func (d *Demuxer) Reader(label string) io.Reader {
+	return d.d.Reader(label)
+}
+
+// Labels returns the labels seen so far, in order of first appearance.
+func (d *Demuxer) Labels() []string {
+	return d.d.Labels()
+}
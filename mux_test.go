@@ -0,0 +1,37 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestMuxDemux tests a multiplexed stream round trip
+func TestMuxDemux(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewMuxer(&buf, WriterOptions{})
+
+	logs := m.Channel("logs")
+	if _, err := logs.Write([]byte("first log line\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := m.Write("metrics", []byte("cpu=12")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := logs.Write([]byte("second log line\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	d := NewDemuxer(&buf)
+	metrics, err := io.ReadAll(d.Reader("metrics"))
+	if err != nil || string(metrics) != "cpu=12" {
+		t.Errorf("metrics = %q, %v, want %q", metrics, err, "cpu=12")
+	}
+	got, err := io.ReadAll(d.Reader("logs"))
+	if err != nil || string(got) != "first log line\nsecond log line\n" {
+		t.Errorf("logs = %q, %v", got, err)
+	}
+	if labels := d.Labels(); len(labels) != 2 || labels[0] != "logs" {
+		t.Errorf("Labels() = %v, want [logs metrics]", labels)
+	}
+}
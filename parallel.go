@@ -0,0 +1,125 @@
+//go:build !goz4x_minimal
+
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/v03"
+)
+
+// V3 API functions with parallel compression
+
+// CompressBlockParallel compresses a byte slice using multiple goroutines with default compression level.
+// This provides better performance on multicore systems for large inputs.
+func CompressBlockParallel(src []byte, dst []byte) ([]byte, error) {
+	return v03.CompressBlockParallel(src, dst)
+}
+
+// CompressBlockParallelLevel compresses a byte slice using multiple goroutines with the specified level.
+// This provides better performance on multicore systems for large inputs.
+func CompressBlockParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
+	return v03.CompressBlockParallelLevel(src, dst, level)
+}
+
+// CompressBlockV2Parallel compresses a byte slice using v0.2 algorithm with multiple goroutines.
+// This provides better compression ratio and better performance on multicore systems.
+func CompressBlockV2Parallel(src []byte, dst []byte) ([]byte, error) {
+	return v03.CompressBlockV2Parallel(src, dst)
+}
+
+// CompressBlockV2ParallelLevel compresses a byte slice using v0.2 algorithm and multiple goroutines.
+// This provides better compression ratio and better performance on multicore systems.
+func CompressBlockV2ParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
+	return v03.CompressBlockV2ParallelLevel(src, dst, level)
+}
+
+// UseSharedDispatcher sets whether the CompressBlockParallel functions share
+// one lazily started worker pool, the default, or start their own per call.
+func UseSharedDispatcher(on bool) {
+	v03.UseSharedDispatcher(on)
+}
+
+// ParallelWriter is an io.WriteCloser that compresses data in parallel for better performance.
+type ParallelWriter struct {
+	w *v03.ParallelWriter
+}
+
+// NewParallelWriter creates a new parallel writer with default options.
+func NewParallelWriter(w io.Writer) *ParallelWriter {
+	return &ParallelWriter{w: v03.NewParallelWriter(w)}
+}
+
+// NewParallelWriterLevel creates a new parallel writer with custom compression level.
+func NewParallelWriterLevel(w io.Writer, level int) *ParallelWriter {
+	return &ParallelWriter{w: v03.NewParallelWriterLevel(w, level)}
+}
+
+// NewParallelWriterV2 creates a new parallel writer using v0.2 algorithm with default options.
+func NewParallelWriterV2(w io.Writer) *ParallelWriter {
+	return &ParallelWriter{w: v03.NewParallelWriterWithOptions(w, v03.ParallelWriterOptions{
+		Level: int(compress.CurrentDefaultLevel()),
+		UseV2: true,
+	})}
+}
+
+// NewParallelWriterV2Level creates a new parallel writer using v0.2 algorithm with custom level.
+func NewParallelWriterV2Level(w io.Writer, level int) *ParallelWriter {
+	return &ParallelWriter{w: v03.NewParallelWriterWithOptions(w, v03.ParallelWriterOptions{
+		Level: level,
+		UseV2: true,
+	})}
+}
+
+// ParallelWriterOptions configures a parallel writer
+type ParallelWriterOptions = v03.ParallelWriterOptions
+
+// Write implements io.Writer.
+func (pw *ParallelWriter) Write(p []byte) (int, error) {
+	return pw.w.Write(p)
+}
+
+// Close implements io.Closer.
+func (pw *ParallelWriter) Close() error {
+	return pw.w.Close()
+}
+
+// Abort cancels the frame being written like Writer.Abort, also stopping
+// the compression workers.
+func (pw *ParallelWriter) Abort() {
+	pw.w.Abort()
+}
+
+// Reset resets the ParallelWriter to write to dst.
+func (pw *ParallelWriter) Reset(dst io.Writer) {
+	pw.w.Reset(dst)
+}
+
+// SetNumWorkers sets the number of worker goroutines used for compression.
+// A value of 0 means use the default set by SetDefaultWorkers.
+func (pw *ParallelWriter) SetNumWorkers(n int) {
+	pw.w.SetNumWorkers(n)
+}
+
+// SetChunkSize sets the chunk size used for parallel compression.
+// A value of 0 means use default chunk size.
+func (pw *ParallelWriter) SetChunkSize(size int) {
+	pw.w.SetChunkSize(size)
+}
+
+// SetMetricsRecorder sets the recorder notified of every block written.
+// A nil recorder disables metrics.
+func (pw *ParallelWriter) SetMetricsRecorder(m MetricsRecorder) {
+	pw.w.SetMetricsRecorder(m)
+}
+
+// NewCheckedParallelWriter creates a new parallel writer with custom options,
+// returning the error of opts.Validate instead of falling back to defaults.
+func NewCheckedParallelWriter(w io.Writer, opts ParallelWriterOptions) (*ParallelWriter, error) {
+	pw, err := v03.NewCheckedParallelWriter(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ParallelWriter{w: pw}, nil
+}
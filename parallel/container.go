@@ -0,0 +1,234 @@
+package parallel
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+)
+
+// ContainerMagic identifies data produced by CompressContainer ("GZ4C")
+const ContainerMagic = 0x43345A47
+
+// chunkHeaderSize is the size of the header preceding every chunk
+const chunkHeaderSize = 12
+
+// maxChunkRatio bounds how much a compressed chunk may expand: an LZ4
+// block grows by at most 255 bytes per input byte
+const maxChunkRatio = 255
+
+// chunkStoredFlag marks a chunk whose payload is stored uncompressed
+const chunkStoredFlag = 0x80000000
+
+var (
+	// ErrInvalidContainer indicates malformed or truncated container data
+	ErrInvalidContainer = errors.New("invalid parallel container")
+
+	// ErrChecksumMismatch indicates a chunk whose contents don't match its checksum
+	ErrChecksumMismatch = errors.New("parallel container checksum mismatch")
+)
+
+// CompressContainer compresses input in parallel into a self-describing container.
+//
+// Unlike CompressBlocks, the output records the boundaries of every chunk so
+// it can be decompressed again, in parallel, by DecompressContainer.
+// The layout is the 4-byte ContainerMagic followed by one entry per chunk and
+// a zero end marker. Each entry has a 12-byte little-endian header holding the
+// payload length (high bit set when the chunk is stored uncompressed), the
+// uncompressed length and the xxHash32 checksum of the uncompressed chunk.
+func (d *Dispatcher) CompressContainer(input []byte, level int) ([]byte, error) {
+	return d.compressContainerInternal(input, level, false)
+}
+
+// CompressContainerV2 is like CompressContainer but uses the V2 algorithm
+func (d *Dispatcher) CompressContainerV2(input []byte, level int) ([]byte, error) {
+	return d.compressContainerInternal(input, level, true)
+}
+
+// compressContainerInternal is the shared implementation for CompressContainer and CompressContainerV2
+func (d *Dispatcher) compressContainerInternal(input []byte, level int, useV2 bool) ([]byte, error) {
+	chunkSize := d.chunkSize
+	if chunkSize > compress.MaxBlockSize {
+		chunkSize = compress.MaxBlockSize
+	}
+
+	numChunks := (len(input) + chunkSize - 1) / chunkSize
+	jobs := make([]compressionJob, numChunks)
+	for i := range jobs {
+		start := i * chunkSize
+		end := min(start+chunkSize, len(input))
+
+		jobs[i] = compressionJob{
+			input:     input[start:end],
+			level:     level,
+			useV2:     useV2,
+			container: true,
+		}
+	}
+
+	results := d.runJobs(jobs)
+
+	totalSize := 8
+	for _, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		totalSize += chunkHeaderSize + len(result.output)
+	}
+
+	output := make([]byte, 4, totalSize)
+	binary.LittleEndian.PutUint32(output, ContainerMagic)
+
+	var header [chunkHeaderSize]byte
+	for _, result := range results {
+		putChunkHeader(header[:], result)
+		output = append(output, header[:]...)
+		output = append(output, result.output...)
+	}
+
+	// End marker
+	output = append(output, 0, 0, 0, 0)
+
+	return output, nil
+}
+
+// putChunkHeader encodes the header of a compressed chunk into header
+func putChunkHeader(header []byte, result compressionResult) {
+	payloadLen := uint32(len(result.output))
+	if result.stored {
+		payloadLen |= chunkStoredFlag
+	}
+	binary.LittleEndian.PutUint32(header[0:4], payloadLen)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(result.inputSize))
+	binary.LittleEndian.PutUint32(header[8:12], result.checksum)
+}
+
+// compressChunk compresses one container chunk, storing it when compression doesn't help
+func (d *Dispatcher) compressChunk(job compressionJob, s *scratch) compressionResult {
+	result := compressionResult{
+		id:        job.id,
+		inputSize: len(job.input),
+		checksum:  xxh32.Checksum(job.input),
+	}
+
+	// The block compressors reject inputs below the minimum block size
+	if len(job.input) >= compress.MinBlockSize {
+		compressed := d.compressBlock(job, s)
+		if compressed.err != nil {
+			result.err = compressed.err
+			return result
+		}
+		if len(compressed.output) < len(job.input) {
+			result.output = compressed.output
+			return result
+		}
+	}
+
+	result.output = job.input
+	result.stored = true
+	return result
+}
+
+// DecompressContainer decompresses data produced by CompressContainer,
+// decoding chunks in parallel and verifying the checksum of each one.
+func (d *Dispatcher) DecompressContainer(data []byte) ([]byte, error) {
+	if len(data) < 4 || binary.LittleEndian.Uint32(data) != ContainerMagic {
+		return nil, ErrInvalidContainer
+	}
+
+	// Walk the chunk headers to lay out the output before decoding anything
+	var jobs []compressionJob
+	var rawSizes []int
+	totalSize := 0
+	pos := 4
+
+	for {
+		if pos+4 > len(data) {
+			return nil, ErrInvalidContainer
+		}
+		payloadLen := binary.LittleEndian.Uint32(data[pos:])
+		if payloadLen == 0 {
+			pos += 4
+			break
+		}
+
+		if pos+chunkHeaderSize > len(data) {
+			return nil, ErrInvalidContainer
+		}
+		stored := payloadLen&chunkStoredFlag != 0
+		size := int(payloadLen &^ chunkStoredFlag)
+		rawLen := binary.LittleEndian.Uint32(data[pos+4:])
+		checksum := binary.LittleEndian.Uint32(data[pos+8:])
+		pos += chunkHeaderSize
+
+		// Check the raw size before converting it, as it may not fit an int
+		if rawLen > compress.MaxBlockSize {
+			return nil, ErrInvalidContainer
+		}
+		rawSize := int(rawLen)
+		if size > len(data)-pos || (stored && size != rawSize) {
+			return nil, ErrInvalidContainer
+		}
+		// Refuse raw sizes the payload can't decode to before allocating them
+		if !stored && rawSize/maxChunkRatio > size {
+			return nil, ErrInvalidContainer
+		}
+
+		jobs = append(jobs, compressionJob{
+			input:      data[pos : pos+size],
+			decompress: true,
+			stored:     stored,
+			checksum:   checksum,
+		})
+		rawSizes = append(rawSizes, rawSize)
+		totalSize += rawSize
+		pos += size
+	}
+
+	if pos != len(data) {
+		return nil, ErrInvalidContainer
+	}
+
+	// Each chunk decodes directly into its own region of the output
+	output := make([]byte, totalSize)
+	start := 0
+	for i := range jobs {
+		end := start + rawSizes[i]
+		jobs[i].dst = output[start:end:end]
+		start = end
+	}
+
+	for _, result := range d.runJobs(jobs) {
+		if result.err != nil {
+			return nil, result.err
+		}
+	}
+
+	return output, nil
+}
+
+// decompressChunk decodes one container chunk into job.dst and verifies it
+func (d *Dispatcher) decompressChunk(job compressionJob) compressionResult {
+	result := compressionResult{id: job.id, inputSize: len(job.input)}
+
+	if !job.stored {
+		n, err := compress.DecompressBlockInto(job.input, job.dst)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		if n != len(job.dst) {
+			result.err = ErrInvalidContainer
+			return result
+		}
+	} else {
+		copy(job.dst, job.input)
+	}
+
+	if xxh32.Checksum(job.dst) != job.checksum {
+		result.err = ErrChecksumMismatch
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package parallel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// TestContainerRoundTrip tests parallel compression and decompression of containers
+func TestContainerRoundTrip(t *testing.T) {
+	d := NewDispatcher(4, 64*1024)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Empty", []byte{}},
+		{"Tiny", []byte("tiny")},
+		{"Compressible", generateTestData(300*1024, 0.9)},
+		{"Incompressible", generateTestData(200*1024, 0.0)},
+		// Compresses close to the largest ratio a chunk may claim
+		{"Repeated byte", bytes.Repeat([]byte("a"), 300*1024)},
+		// The final chunk is below the minimum block size and gets stored
+		{"Small last chunk", generateTestData(2*64*1024+5, 0.8)},
+	}
+
+	for _, tt := range tests {
+		for _, useV2 := range []bool{false, true} {
+			compressFn := d.CompressContainer
+			if useV2 {
+				compressFn = d.CompressContainerV2
+			}
+
+			container, err := compressFn(tt.data, 6)
+			if err != nil {
+				t.Fatalf("%s: CompressContainer() error = %v", tt.name, err)
+			}
+
+			got, err := d.DecompressContainer(container)
+			if err != nil {
+				t.Fatalf("%s: DecompressContainer() error = %v", tt.name, err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("%s: DecompressContainer() returned %d bytes, want %d matching bytes", tt.name, len(got), len(tt.data))
+			}
+		}
+	}
+}
+
+// TestContainerWithoutStart tests that containers work without running workers
+func TestContainerWithoutStart(t *testing.T) {
+	d := NewDispatcher(2, 32*1024)
+	data := generateTestData(100*1024, 0.7)
+
+	container, err := d.CompressContainer(data, 6)
+	if err != nil {
+		t.Fatalf("CompressContainer() error = %v", err)
+	}
+
+	got, err := d.DecompressContainer(container)
+	if err != nil {
+		t.Fatalf("DecompressContainer() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("DecompressContainer() returned %d bytes, want %d matching bytes", len(got), len(data))
+	}
+}
+
+// TestContainerErrors tests detection of corrupted and truncated containers
+func TestContainerErrors(t *testing.T) {
+	d := NewDispatcher(2, 16*1024)
+	container, err := d.CompressContainer(generateTestData(64*1024, 0.8), 6)
+	if err != nil {
+		t.Fatalf("CompressContainer() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"Bad magic", append([]byte{0, 0, 0, 0}, container[4:]...), ErrInvalidContainer},
+		{"Missing end marker", container[:len(container)-4], ErrInvalidContainer},
+		{"Truncated chunk", container[:len(container)-10], ErrInvalidContainer},
+		{"Trailing data", append(append([]byte(nil), container...), 1), ErrInvalidContainer},
+		{
+			"Bad checksum",
+			func() []byte {
+				c := append([]byte(nil), container...)
+				binary.LittleEndian.PutUint32(c[12:16], binary.LittleEndian.Uint32(c[12:16])^1)
+				return c
+			}(),
+			ErrChecksumMismatch,
+		},
+		{
+			// A raw size that doesn't fit an int on 32-bit platforms
+			"Raw size overflow",
+			func() []byte {
+				c := append([]byte(nil), container...)
+				binary.LittleEndian.PutUint32(c[8:12], 0xFFFFFFFF)
+				return c
+			}(),
+			ErrInvalidContainer,
+		},
+		{
+			// Tiny chunks claiming the largest raw size each
+			"Raw size past ratio",
+			func() []byte {
+				c := binary.LittleEndian.AppendUint32(nil, ContainerMagic)
+				for range 64 {
+					c = binary.LittleEndian.AppendUint32(c, 1)
+					c = binary.LittleEndian.AppendUint32(c, compress.MaxBlockSize)
+					c = binary.LittleEndian.AppendUint32(c, 0)
+					c = append(c, 0)
+				}
+				return binary.LittleEndian.AppendUint32(c, 0)
+			}(),
+			ErrInvalidContainer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			if _, err := d.DecompressContainer(tt.data); err != tt.wantErr {
+				t.Errorf("DecompressContainer() error = %v, want %v", err, tt.wantErr)
+			}
+			runtime.ReadMemStats(&after)
+			if n := after.TotalAlloc - before.TotalAlloc; n > 8<<20 {
+				t.Errorf("DecompressContainer() allocated %d bytes", n)
+			}
+		})
+	}
+}
@@ -2,9 +2,10 @@
 package parallel
 
 import (
+	"context"
 	"errors"
-	"runtime"
 	"sync"
+	"time"
 
 	"github.com/harriteja/GoZ4X/compress"
 )
@@ -13,7 +14,14 @@ import (
 const DefaultChunkSize = 1 << 20 // 1MB
 
 // DefaultNumWorkers is the default number of worker goroutines
-const DefaultNumWorkers = 0 // 0 means use runtime.GOMAXPROCS(0)
+const DefaultNumWorkers = 0 // 0 means use compress.CurrentDefaultWorkers()
+
+// DefaultStopTimeout is how long Stop waits for calls still using the workers
+const DefaultStopTimeout = 30 * time.Second
+
+// ErrStopTimeout is returned by Shutdown when calls are still using the
+// workers at the timeout
+var ErrStopTimeout = errors.New("dispatcher stop timed out")
 
 // Dispatcher manages parallel compression of LZ4 blocks
 type Dispatcher struct {
@@ -23,17 +31,11 @@ type Dispatcher struct {
 	// Size of each chunk to compress in parallel
 	chunkSize int
 
-	// Channel for work distribution
-	jobChan chan compressionJob
-
-	// Channel for collecting results
-	resultChan chan compressionResult
+	// Chunks CompressContainerStream holds at once, or 0 for the default
+	maxPending int
 
-	// WaitGroup for worker synchronization
-	wg sync.WaitGroup
-
-	// Dispatcher state
-	running   bool
+	// The current run of the workers, or nil if they aren't running
+	session   *session
 	runningMu sync.RWMutex
 
 	// Stats
@@ -42,6 +44,21 @@ type Dispatcher struct {
 	runningJobs int
 }
 
+// session is one run of the workers, from Start to Stop. Calls register
+// with it before submitting jobs, and its channel is never closed: Stop only
+// detaches the session, so that new calls process their jobs themselves,
+// and the workers quit once every registered call has collected its
+// results and the context is cancelled.
+type session struct {
+	jobs   chan compressionJob
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// calls counts the calls submitting jobs, and workers the workers
+	calls   sync.WaitGroup
+	workers sync.WaitGroup
+}
+
 // compressionJob represents a block to be compressed
 type compressionJob struct {
 	id       int
@@ -49,6 +66,14 @@ type compressionJob struct {
 	level    int
 	useV2    bool
 	resultCh chan<- compressionResult
+
+	// Container jobs: compute a checksum of the raw data and store chunks
+	// that don't compress, or decompress a chunk into dst instead
+	container  bool
+	decompress bool
+	stored     bool
+	dst        []byte
+	checksum   uint32
 }
 
 // compressionResult represents a compressed block
@@ -57,12 +82,14 @@ type compressionResult struct {
 	output    []byte
 	err       error
 	inputSize int
+	stored    bool
+	checksum  uint32
 }
 
 // NewDispatcher creates a new parallel compression dispatcher
 func NewDispatcher(numWorkers, chunkSize int) *Dispatcher {
 	if numWorkers <= 0 {
-		numWorkers = runtime.GOMAXPROCS(0)
+		numWorkers = compress.CurrentDefaultWorkers()
 	}
 
 	if chunkSize <= 0 {
@@ -72,19 +99,32 @@ func NewDispatcher(numWorkers, chunkSize int) *Dispatcher {
 	d := &Dispatcher{
 		numWorkers: numWorkers,
 		chunkSize:  chunkSize,
-		jobChan:    make(chan compressionJob, numWorkers*2),
-		resultChan: make(chan compressionResult, numWorkers*2),
 	}
 
 	return d
 }
 
+// NewCheckedDispatcher is NewDispatcher, except that it returns an error
+// for settings it would replace: compress.ErrInvalidNumWorkers for a
+// negative numWorkers, and compress.ErrInvalidBlockSize for a negative
+// chunkSize or one above compress.MaxBlockSize, which chunks are cut down to.
+// Zero still selects the defaults.
+func NewCheckedDispatcher(numWorkers, chunkSize int) (*Dispatcher, error) {
+	if numWorkers < 0 {
+		return nil, compress.ErrInvalidNumWorkers
+	}
+	if chunkSize < 0 || chunkSize > compress.MaxBlockSize {
+		return nil, compress.ErrInvalidBlockSize
+	}
+	return NewDispatcher(numWorkers, chunkSize), nil
+}
+
 // Start launches worker goroutines
 func (d *Dispatcher) Start() error {
 	d.runningMu.Lock()
 	defer d.runningMu.Unlock()
 
-	if d.running {
+	if d.session != nil {
 		return errors.New("dispatcher already running")
 	}
 
@@ -94,54 +134,148 @@ func (d *Dispatcher) Start() error {
 	d.runningJobs = 0
 
 	// Start worker goroutines
-	d.wg.Add(d.numWorkers)
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &session{
+		jobs:   make(chan compressionJob, d.numWorkers*2),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	sess.workers.Add(d.numWorkers)
 	for i := 0; i < d.numWorkers; i++ {
-		go d.worker()
+		go d.worker(sess)
 	}
 
-	d.running = true
+	d.session = sess
 	return nil
 }
 
-// Stop shuts down worker goroutines
+// Stop shuts down worker goroutines, waiting up to DefaultStopTimeout for
+// calls still using them, as Shutdown does
 func (d *Dispatcher) Stop() {
+	d.Shutdown(DefaultStopTimeout)
+}
+
+// Shutdown shuts down worker goroutines. Calls made from now on process
+// their jobs on the calling goroutine, while calls already using the
+// workers keep them until they finish: Shutdown waits for them and the
+// workers, or returns ErrStopTimeout once timeout elapses, leaving the
+// workers to quit after the last call. A timeout of 0 or less waits as long
+// as it takes. The dispatcher can be started again right away either way.
+func (d *Dispatcher) Shutdown(timeout time.Duration) error {
 	d.runningMu.Lock()
-	defer d.runningMu.Unlock()
+	sess := d.session
+	d.session = nil
+	d.runningMu.Unlock()
 
-	if !d.running {
-		return
+	if sess == nil {
+		return nil
 	}
 
-	// Close job channel to signal workers to stop
-	close(d.jobChan)
+	// No call can register with the detached session any more
+	go func() {
+		sess.calls.Wait()
+		sess.cancel()
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-sess.ctx.Done():
+		sess.workers.Wait()
+		return nil
+	case <-expired:
+		return ErrStopTimeout
+	}
+}
+
+// acquire registers a call with the running session and returns it, or
+// returns nil if the workers aren't running and the call must process its
+// jobs itself. The call must collect the results of every job it submits
+// before calling sess.calls.Done.
+func (d *Dispatcher) acquire() *session {
+	d.runningMu.RLock()
+	defer d.runningMu.RUnlock()
+
+	if d.session == nil {
+		return nil
+	}
+	d.session.calls.Add(1)
+	return d.session
+}
 
-	// Wait for all workers to finish
-	d.wg.Wait()
+// worker processes compression jobs, reusing its scratch state across them
+func (d *Dispatcher) worker(sess *session) {
+	defer sess.workers.Done()
+
+	var s scratch
+	for {
+		select {
+		case job := <-sess.jobs:
+			// Compress the block
+			result := d.processJob(job, &s)
+
+			// Send result back
+			job.resultCh <- result
+		case <-sess.ctx.Done():
+			return
+		}
+	}
+}
 
-	// Create a new set of channels
-	d.jobChan = make(chan compressionJob, d.numWorkers*2)
-	d.resultChan = make(chan compressionResult, d.numWorkers*2)
-	d.running = false
+// processJob runs a single job of any kind with the scratch state of the
+// goroutine running it
+func (d *Dispatcher) processJob(job compressionJob, s *scratch) compressionResult {
+	switch {
+	case job.decompress:
+		return d.decompressChunk(job)
+	case job.container:
+		return d.compressChunk(job, s)
+	default:
+		return d.compressBlock(job, s)
+	}
 }
 
-// worker processes compression jobs
-func (d *Dispatcher) worker() {
-	defer d.wg.Done()
+// runJobs processes jobs on the worker pool and returns the results in job order.
+// If the dispatcher isn't running, the jobs are processed on the calling goroutine.
+func (d *Dispatcher) runJobs(jobs []compressionJob) []compressionResult {
+	results := make([]compressionResult, len(jobs))
 
-	for job := range d.jobChan {
-		// Compress the block
-		result := d.compressBlock(job)
+	sess := d.acquire()
+	if sess == nil {
+		var s scratch
+		for i, job := range jobs {
+			results[i] = d.processJob(job, &s)
+		}
+		return results
+	}
+	defer sess.calls.Done()
+
+	// Buffer every result so workers never block on a slow collector
+	resultCh := make(chan compressionResult, len(jobs))
+	for i := range jobs {
+		jobs[i].id = i
+		jobs[i].resultCh = resultCh
+		sess.jobs <- jobs[i]
+	}
 
-		// Send result back
-		job.resultCh <- result
+	for range jobs {
+		result := <-resultCh
+		results[result.id] = result
 	}
+
+	return results
 }
 
-// compressBlock compresses a single block
-func (d *Dispatcher) compressBlock(job compressionJob) compressionResult {
+// compressBlock compresses a single block into the scratch buffer, then
+// copies it out, as results outlive the job
+func (d *Dispatcher) compressBlock(job compressionJob, s *scratch) compressionResult {
 	// Create compressed buffer with safety margin
 	maxSize := len(job.input) + (len(job.input) / 255) + 16
-	compressedBuf := make([]byte, maxSize)
+	compressedBuf := s.buffer(maxSize)
 
 	var compressed []byte
 	var err error
@@ -151,8 +285,14 @@ func (d *Dispatcher) compressBlock(job compressionJob) compressionResult {
 		// Use V2 algorithm
 		compressed, err = compress.CompressBlockV2Level(job.input, compressedBuf, compress.CompressionLevel(job.level))
 	} else {
-		// Use standard algorithm
-		compressed, err = compress.CompressBlockLevel(job.input, compressedBuf, compress.CompressionLevel(job.level))
+		// Use standard algorithm, with the worker's tables for the level
+		var c *compress.Compressor
+		if c, err = s.compressor(job.level); err == nil {
+			compressed, err = c.CompressBlock(job.input, compressedBuf)
+		}
+	}
+	if err == nil {
+		compressed = append([]byte(nil), compressed...)
 	}
 
 	return compressionResult{
@@ -163,7 +303,14 @@ func (d *Dispatcher) compressBlock(job compressionJob) compressionResult {
 	}
 }
 
-// CompressBlocks compresses multiple blocks in parallel
+// CompressBlocks compresses multiple blocks in parallel.
+// The input is split on fixed chunk boundaries and the compressed chunks are
+// stitched into a single LZ4 block, so the result decodes with any standard
+// block decoder. Chunks are compressed independently, so matches never cross
+// a chunk boundary. The output depends only on the input, level and chunk
+// size, never on the number of workers or the order in which jobs complete.
+// Chunks are compressed on the worker pool if the dispatcher is running, and
+// on the calling goroutine otherwise.
 func (d *Dispatcher) CompressBlocks(input []byte, level int) ([]byte, error) {
 	return d.compressBlocksInternal(input, level, false)
 }
@@ -189,75 +336,55 @@ func (d *Dispatcher) compressBlocksInternal(input []byte, level int, useV2 bool)
 		return compress.CompressBlockLevel(input, nil, compress.CompressionLevel(level))
 	}
 
-	// Check if we need to start the dispatcher
-	d.runningMu.RLock()
-	running := d.running
-	d.runningMu.RUnlock()
-
-	if !running {
-		// Need to start the dispatcher
-		d.runningMu.Lock()
-		// Check again after acquiring the exclusive lock
-		if !d.running {
-			if err := d.Start(); err != nil {
-				d.runningMu.Unlock()
-				// If we can't start the dispatcher, do single-threaded compression
-				if useV2 {
-					return compress.CompressBlockV2Level(input, nil, compress.CompressionLevel(level))
-				}
-				return compress.CompressBlockLevel(input, nil, compress.CompressionLevel(level))
-			}
-		}
-		d.runningMu.Unlock()
+	// Every chunk must be a valid block on its own
+	chunkSize := d.chunkSize
+	if chunkSize > compress.MaxBlockSize {
+		chunkSize = compress.MaxBlockSize
 	}
 
 	// Split input into chunks
-	numChunks := (len(input) + d.chunkSize - 1) / d.chunkSize
-	results := make([]compressionResult, numChunks)
-
-	// Process all chunks synchronously for consistency
-	for i := 0; i < numChunks; i++ {
-		start := i * d.chunkSize
-		end := (i + 1) * d.chunkSize
-		if end > len(input) {
-			end = len(input)
-		}
-
-		job := compressionJob{
-			id:       i,
-			input:    input[start:end],
-			level:    level,
-			useV2:    useV2,
-			resultCh: nil, // Not needed for synchronous processing
+	numChunks := (len(input) + chunkSize - 1) / chunkSize
+	jobs := make([]compressionJob, numChunks)
+	for i := range jobs {
+		start := i * chunkSize
+		end := min(start+chunkSize, len(input))
+
+		jobs[i] = compressionJob{
+			input: input[start:end],
+			level: level,
+			useV2: useV2,
 		}
+	}
 
-		// Process directly
-		result := d.compressBlock(job)
-		results[i] = result
+	results := d.runJobs(jobs)
 
+	blocks := make([][]byte, numChunks)
+	for i, result := range results {
+		// A trailing chunk below the minimum block size is emitted as literals
+		if len(jobs[i].input) < compress.MinBlockSize {
+			blocks[i] = literalBlock(jobs[i].input)
+			continue
+		}
 		if result.err != nil {
 			return nil, result.err
 		}
+		blocks[i] = result.output
 	}
 
-	// Combine results
-	// First calculate total size
-	totalSize := 0
-	for _, result := range results {
-		totalSize += len(result.output)
-	}
-
-	// Allocate output buffer
-	output := make([]byte, totalSize)
+	// Fold each chunk's trailing literals into the next chunk's first sequence
+	return compress.ConcatBlocks(blocks...)
+}
 
-	// Copy results in order
-	pos := 0
-	for i := 0; i < numChunks; i++ {
-		copy(output[pos:], results[i].output)
-		pos += len(results[i].output)
+// literalBlock encodes data shorter than MinBlockSize as a block holding a
+// single literal-only sequence
+func literalBlock(data []byte) []byte {
+	block := make([]byte, 0, len(data)+2)
+	if len(data) < 15 {
+		block = append(block, byte(len(data)<<4))
+	} else {
+		block = append(block, 0xF0, byte(len(data)-15))
 	}
-
-	return output, nil
+	return append(block, data...)
 }
 
 // NumWorkers returns the number of worker goroutines
@@ -283,12 +410,12 @@ func (d *Dispatcher) SetNumWorkers(n int) {
 	d.runningMu.Lock()
 	defer d.runningMu.Unlock()
 
-	if d.running {
+	if d.session != nil {
 		return // Can't change while running
 	}
 
 	if n <= 0 {
-		n = runtime.GOMAXPROCS(0)
+		n = compress.CurrentDefaultWorkers()
 	}
 
 	d.numWorkers = n
@@ -2,50 +2,28 @@ package parallel
 
 import (
 	"bytes"
-	"math/rand"
+	"io"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/internal/testgen"
 )
 
+// seeds numbers the data of generateTestData, so that every call returns new
+// data and runs are reproducible
+var seeds testgen.Seq
+
 // generateTestData creates test data with varying compressibility
 func generateTestData(size int, compressibility float32) []byte {
-	rand.Seed(time.Now().UnixNano())
-	data := make([]byte, size)
-
-	// Create a pattern that will be repeated
-	patternSize := 4 * 1024 // 4KB pattern
+	// Smaller patterns for less compressible data, and more random bytes
+	patternSize := 4 * 1024
 	if compressibility < 0.5 {
-		patternSize = 256 // Smaller pattern for less compressible data
-	}
-
-	pattern := make([]byte, patternSize)
-	for i := 0; i < patternSize; i++ {
-		pattern[i] = byte(rand.Intn(256))
-	}
-
-	// Fill the buffer with the pattern and random variations
-	for i := 0; i < size; i += patternSize {
-		end := i + patternSize
-		if end > size {
-			end = size
-		}
-
-		copy(data[i:end], pattern)
-
-		// Add random variations based on compressibility
-		// Lower compressibility means more randomization
-		randomRate := 1.0 - float32(compressibility)
-		for j := i; j < end; j++ {
-			if rand.Float32() < randomRate {
-				data[j] = byte(rand.Intn(256))
-			}
-		}
+		patternSize = 256
 	}
-
-	return data
+	return testgen.Pattern(seeds.Next(), size, patternSize, 1-float64(compressibility))
 }
 
 // TestDispatcherConstruction tests the constructor function
@@ -81,6 +59,43 @@ func TestDispatcherConstruction(t *testing.T) {
 	}
 }
 
+// TestDispatcherDefaultWorkers tests that dispatchers given no worker count
+// use the configured default
+func TestDispatcherDefaultWorkers(t *testing.T) {
+	if err := compress.SetDefaultWorkers(3); err != nil {
+		t.Fatalf("SetDefaultWorkers(3) error = %v", err)
+	}
+	defer compress.SetDefaultWorkers(0)
+
+	if got := NewDispatcher(0, 0).NumWorkers(); got != 3 {
+		t.Errorf("NewDispatcher(0, 0).NumWorkers() = %d, want 3", got)
+	}
+	if got := NewDispatcher(5, 0).NumWorkers(); got != 5 {
+		t.Errorf("NewDispatcher(5, 0).NumWorkers() = %d, want 5", got)
+	}
+}
+
+// TestNewCheckedDispatcher tests that invalid settings are reported
+func TestNewCheckedDispatcher(t *testing.T) {
+	tests := []struct {
+		numWorkers, chunkSize int
+		want                  error
+	}{
+		{0, 0, nil},
+		{4, compress.MaxBlockSize, nil},
+		{-1, 0, compress.ErrInvalidNumWorkers},
+		{0, -1, compress.ErrInvalidBlockSize},
+		{0, compress.MaxBlockSize + 1, compress.ErrInvalidBlockSize},
+	}
+
+	for _, tt := range tests {
+		d, err := NewCheckedDispatcher(tt.numWorkers, tt.chunkSize)
+		if err != tt.want || (err == nil) != (d != nil) {
+			t.Errorf("NewCheckedDispatcher(%d, %d) = %v, %v, want error %v", tt.numWorkers, tt.chunkSize, d, err, tt.want)
+		}
+	}
+}
+
 // TestDispatcherStartStop tests starting and stopping the dispatcher
 func TestDispatcherStartStop(t *testing.T) {
 	d := NewDispatcher(2, 1024*1024)
@@ -110,6 +125,87 @@ func TestDispatcherStartStop(t *testing.T) {
 	d.Stop()
 }
 
+// TestDispatcherStopDuringCalls tests that stopping and restarting the
+// dispatcher while calls are compressing on it never fails a call
+func TestDispatcherStopDuringCalls(t *testing.T) {
+	d := NewDispatcher(2, 64*1024)
+	data := generateTestData(256*1024+100, 0.8)
+	want, err := d.CompressBlocks(data, 1)
+	if err != nil {
+		t.Fatalf("CompressBlocks() error = %v", err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				got, err := d.CompressBlocks(data, 1)
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("CompressBlocks() during Stop = %d bytes, %v, want %d", len(got), err, len(want))
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := d.Shutdown(0); err != nil {
+			t.Errorf("Shutdown(0) error = %v", err)
+		}
+		d.Start()
+	}
+	wg.Wait()
+	d.Stop()
+}
+
+// TestDispatcherShutdownTimeout tests that Shutdown gives up waiting on a
+// call still using the workers, which then finishes on them undisturbed
+func TestDispatcherShutdownTimeout(t *testing.T) {
+	d := NewDispatcher(2, 64*1024)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	data := generateTestData(300*1024, 0.8)
+	pr, pw := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		_, err := d.CompressContainerStream(&out, pr, 1)
+		done <- err
+	}()
+
+	// The stream holds the workers until its input ends
+	if _, err := pw.Write(data[:100*1024]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := d.Shutdown(20 * time.Millisecond); err != ErrStopTimeout {
+		t.Errorf("Shutdown() with a stream running error = %v, want %v", err, ErrStopTimeout)
+	}
+	if err := d.Start(); err != nil {
+		t.Errorf("Start() after a timed out Shutdown error = %v", err)
+	}
+
+	pw.Write(data[100*1024:])
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("CompressContainerStream() error = %v", err)
+	}
+	got, err := d.DecompressContainer(out.Bytes())
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("DecompressContainer() = %d bytes, %v, want %d", len(got), err, len(data))
+	}
+	if err := d.Shutdown(time.Second); err != nil {
+		t.Errorf("Shutdown() of an idle dispatcher error = %v", err)
+	}
+	if err := d.Shutdown(time.Second); err != nil {
+		t.Errorf("Shutdown() of a stopped dispatcher error = %v", err)
+	}
+}
+
 // TestCompressBlocks tests the main CompressBlocks function
 func TestCompressBlocks(t *testing.T) {
 	testSizes := []int{
@@ -314,3 +410,143 @@ func byteSizeToString(size int) string {
 	}
 	return string(rune('0'+size/(1024*1024*1024))) + "GB"
 }
+
+// TestDispatcherDeterministic verifies the output is independent of the worker count
+func TestDispatcherDeterministic(t *testing.T) {
+	data := generateTestData(512*1024, 0.7)
+	chunkSize := 64 * 1024
+
+	var reference []byte
+	for _, workers := range []int{1, 2, 8} {
+		d := NewDispatcher(workers, chunkSize)
+		if err := d.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		got, err := d.CompressBlocks(data, int(compress.DefaultLevel))
+		d.Stop()
+		if err != nil {
+			t.Fatalf("CompressBlocks() error = %v", err)
+		}
+
+		if reference == nil {
+			reference = got
+			continue
+		}
+		if !bytes.Equal(got, reference) {
+			t.Errorf("%d workers: output differs from single worker output", workers)
+		}
+	}
+}
+
+// TestCompressBlocksStitched tests that chunked output is a single valid block
+func TestCompressBlocksStitched(t *testing.T) {
+	// Chunk boundaries that don't line up with matches, including a final
+	// chunk below the minimum block size
+	sizes := []int{
+		64*1024 + 7,
+		3*64*1024 + 15,
+		5*64*1024 + 1234,
+	}
+
+	for _, size := range sizes {
+		data := generateTestData(size, 0.8)
+
+		for _, useV2 := range []bool{false, true} {
+			// The dispatcher is deliberately not started
+			d := NewDispatcher(4, 64*1024)
+
+			compressFn := d.CompressBlocks
+			if useV2 {
+				compressFn = d.CompressBlocksV2
+			}
+
+			compressed, err := compressFn(data, 6)
+			if err != nil {
+				t.Fatalf("CompressBlocks(%d bytes) error = %v", size, err)
+			}
+
+			// Decoding with the exact size fails if any chunk's output is misplaced
+			got, err := compress.DecompressBlock(compressed, nil, len(data))
+			if err != nil {
+				t.Fatalf("DecompressBlock(%d bytes) error = %v", size, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("DecompressBlock(%d bytes) returned %d bytes, want matching data", size, len(got))
+			}
+		}
+	}
+}
+
+// TestScratchReuse tests that a worker's scratch keeps one Compressor per
+// level and reuses its buffer
+func TestScratchReuse(t *testing.T) {
+	var s scratch
+	c1, err := s.compressor(6)
+	if err != nil {
+		t.Fatalf("compressor() error = %v", err)
+	}
+	if c2, _ := s.compressor(6); c2 != c1 {
+		t.Error("compressor() returned a new Compressor for the same level")
+	}
+	if c3, _ := s.compressor(9); c3 == c1 || c3.Level() != 9 {
+		t.Error("compressor() didn't return a Compressor for level 9")
+	}
+	if _, err := s.compressor(13); err != compress.ErrInvalidCompressionLevel {
+		t.Errorf("compressor(13) error = %v, want %v", err, compress.ErrInvalidCompressionLevel)
+	}
+
+	buf := s.buffer(1000)
+	if again := s.buffer(500); &again[0] != &buf[0] {
+		t.Error("buffer() reallocated for a smaller size")
+	}
+}
+
+// TestCompressBlocksAllocs tests that compressing on the worker pool doesn't
+// allocate match tables for every chunk
+func TestCompressBlocksAllocs(t *testing.T) {
+	d := NewDispatcher(2, 64*1024)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop()
+
+	input := generateTestData(1024*1024, 0.7)
+	if _, err := d.CompressBlocks(input, 6); err != nil {
+		t.Fatalf("CompressBlocks() error = %v", err)
+	}
+
+	// Match tables take several times the size of a chunk; without them
+	// a call allocates little more than its chunks and the output
+	const runs = 5
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < runs; i++ {
+		if _, err := d.CompressBlocks(input, 6); err != nil {
+			t.Fatalf("CompressBlocks() error = %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if perCall := (after.TotalAlloc - before.TotalAlloc) / runs; perCall > 4*uint64(len(input)) {
+		t.Errorf("CompressBlocks() allocated %d bytes per call, want at most %d", perCall, 4*len(input))
+	}
+}
+
+// BenchmarkDispatcherCompressBlocks measures chunked compression on the
+// worker pool
+func BenchmarkDispatcherCompressBlocks(b *testing.B) {
+	d := NewDispatcher(4, 64*1024)
+	if err := d.Start(); err != nil {
+		b.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop()
+
+	input := generateTestData(4*1024*1024, 0.7)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := d.CompressBlocks(input, 6); err != nil {
+			b.Fatalf("CompressBlocks() error = %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package parallel
+
+import (
+	"bytes"
+	"testing"
+	"testing/quick"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// TestDispatcherSerialEquivalence tests, for random inputs, levels and
+// worker counts, that CompressBlocks decodes to its input like a serial
+// block, that its output doesn't depend on the workers, and that containers
+// round trip
+func TestDispatcherSerialEquivalence(t *testing.T) {
+	count := 20
+	if testing.Short() {
+		count = 5
+	}
+
+	serial := NewDispatcher(1, 64*1024)
+	property := func(size uint32, level, workers uint8, compressibility uint8) bool {
+		data := generateTestData(int(size%(400*1024)), float32(compressibility%11)/10)
+		lvl := int(level % uint8(compress.MaxLevel+1))
+
+		d := NewDispatcher(1+int(workers%8), 64*1024)
+		if err := d.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		defer d.Stop()
+
+		want, err := serial.CompressBlocks(data, lvl)
+		if err != nil {
+			t.Logf("serial CompressBlocks() error = %v", err)
+			return false
+		}
+		got, err := d.CompressBlocks(data, lvl)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Logf("%d workers: CompressBlocks() differs from 1 worker (error %v)", d.NumWorkers(), err)
+			return false
+		}
+		if len(data) > 0 {
+			decoded, err := compress.DecompressBlock(got, nil, len(data))
+			if err != nil || !bytes.Equal(decoded, data) {
+				t.Logf("level %d, %d bytes: DecompressBlock() error = %v", lvl, len(data), err)
+				return false
+			}
+		}
+
+		container, err := d.CompressContainer(data, lvl)
+		if err != nil {
+			t.Logf("CompressContainer() error = %v", err)
+			return false
+		}
+		decoded, err := d.DecompressContainer(container)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Logf("DecompressContainer() error = %v", err)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: count}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,36 @@
+package parallel
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// scratch is the state a worker reuses from one job to the next: a
+// Compressor for each level it has seen, whose match tables are the bulk of
+// the memory compressing a block takes, and a buffer to compress into. It
+// belongs to one goroutine.
+type scratch struct {
+	compressors [compress.MaxLevel + 1]*compress.Compressor
+	dst         []byte
+}
+
+// compressor returns the scratch Compressor for level, creating it on first
+// use
+func (s *scratch) compressor(level int) (*compress.Compressor, error) {
+	if level < 0 || level > int(compress.MaxLevel) {
+		return nil, compress.ErrInvalidCompressionLevel
+	}
+	if s.compressors[level] == nil {
+		c, err := compress.NewCompressor(compress.CompressionLevel(level))
+		if err != nil {
+			return nil, err
+		}
+		s.compressors[level] = c
+	}
+	return s.compressors[level], nil
+}
+
+// buffer returns the scratch buffer, grown to hold at least n bytes
+func (s *scratch) buffer(n int) []byte {
+	if cap(s.dst) < n {
+		s.dst = make([]byte, n)
+	}
+	return s.dst[:n]
+}
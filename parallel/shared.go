@@ -0,0 +1,110 @@
+package parallel
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultIdleTimeout is how long the shared dispatcher keeps its workers
+// after its last user releases it
+const DefaultIdleTimeout = 30 * time.Second
+
+// shared is the process-wide dispatcher handed out by AcquireShared. It is
+// started by the first user and stopped once no user has held it for the
+// idle timeout, so that callers compressing one buffer at a time neither
+// start a pool per call nor keep one alive forever.
+var shared struct {
+	mu sync.Mutex
+	d  *Dispatcher
+	// refs is the number of users holding the dispatcher
+	refs int
+	// idle is the idle timeout, and timer the pending idle shutdown
+	idle  time.Duration
+	timer *time.Timer
+	// gen is bumped on every acquire, so that an idle shutdown armed before
+	// it does nothing
+	gen uint64
+}
+
+func init() {
+	shared.idle = DefaultIdleTimeout
+}
+
+// AcquireShared returns the shared Dispatcher, starting its workers if they
+// aren't running. It has the default chunk size and
+// compress.CurrentDefaultWorkers workers, as of when the workers start.
+// Every call must be paired with a call to ReleaseShared once the caller is
+// done with the dispatcher. Users may compress with it concurrently, but
+// must not stop it or change its settings.
+func AcquireShared() *Dispatcher {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	shared.gen++
+	if shared.timer != nil {
+		shared.timer.Stop()
+		shared.timer = nil
+	}
+	if shared.d == nil {
+		shared.d = NewDispatcher(0, 0)
+		// A new dispatcher can't be running yet
+		shared.d.Start()
+	}
+	shared.refs++
+	return shared.d
+}
+
+// ReleaseShared gives back a dispatcher returned by AcquireShared. When the
+// last user releases it, its workers stop after the idle timeout unless it
+// is acquired again in the meantime.
+func ReleaseShared() {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	if shared.refs == 0 {
+		panic("parallel: ReleaseShared without AcquireShared")
+	}
+	shared.refs--
+	if shared.refs > 0 {
+		return
+	}
+	if shared.idle <= 0 {
+		stopShared()
+		return
+	}
+	gen := shared.gen
+	shared.timer = time.AfterFunc(shared.idle, func() {
+		shared.mu.Lock()
+		defer shared.mu.Unlock()
+		if shared.refs == 0 && shared.gen == gen {
+			stopShared()
+		}
+	})
+}
+
+// stopShared stops the shared dispatcher; the caller must hold shared.mu
+// and there must be no users
+func stopShared() {
+	if shared.d != nil {
+		shared.d.Stop()
+		shared.d = nil
+	}
+	shared.timer = nil
+}
+
+// SetSharedIdleTimeout sets how long the shared dispatcher keeps its
+// workers once unused, DefaultIdleTimeout unless set. A timeout of 0 or
+// less stops them as soon as the last user releases the dispatcher. The new
+// timeout applies from the next release.
+func SetSharedIdleTimeout(d time.Duration) {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	shared.idle = d
+}
+
+// SharedRunning reports whether the shared dispatcher's workers are running
+func SharedRunning() bool {
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+	return shared.d != nil
+}
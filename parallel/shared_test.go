@@ -0,0 +1,90 @@
+package parallel
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// TestSharedDispatcher tests that the shared dispatcher is started by its
+// first user, kept for all users at once, and stopped once idle
+func TestSharedDispatcher(t *testing.T) {
+	SetSharedIdleTimeout(20 * time.Millisecond)
+	defer SetSharedIdleTimeout(DefaultIdleTimeout)
+
+	data := generateTestData(2*DefaultChunkSize+1, 0.8)
+	want, err := NewDispatcher(1, 0).CompressBlocks(data, 1)
+	if err != nil {
+		t.Fatalf("CompressBlocks() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	dispatchers := make([]*Dispatcher, 4)
+	for i := range dispatchers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			d := AcquireShared()
+			defer ReleaseShared()
+			dispatchers[i] = d
+			got, err := d.CompressBlocks(data, 1)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("CompressBlocks() on the shared dispatcher = %d bytes, %v, want %d", len(got), err, len(want))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, d := range dispatchers {
+		if d != dispatchers[0] {
+			t.Errorf("AcquireShared() call %d returned another dispatcher", i)
+		}
+	}
+	if !SharedRunning() {
+		t.Errorf("SharedRunning() = false right after the last release, want true until idle")
+	}
+
+	// An acquire before the timeout cancels the shutdown
+	AcquireShared()
+	time.Sleep(50 * time.Millisecond)
+	if !SharedRunning() {
+		t.Errorf("SharedRunning() = false while acquired")
+	}
+	ReleaseShared()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for SharedRunning() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if SharedRunning() {
+		t.Errorf("SharedRunning() = true long after the idle timeout")
+	}
+}
+
+// TestSharedDispatcherNoIdle tests that a zero idle timeout stops the
+// workers at the last release, and that the next acquire restarts them
+func TestSharedDispatcherNoIdle(t *testing.T) {
+	SetSharedIdleTimeout(0)
+	defer SetSharedIdleTimeout(DefaultIdleTimeout)
+
+	for i := 0; i < 3; i++ {
+		d := AcquireShared()
+		if _, err := d.CompressBlocks(generateTestData(compress.MaxBlockSize+1, 0.8), 1); err != nil {
+			t.Errorf("CompressBlocks() error = %v", err)
+		}
+		ReleaseShared()
+		if SharedRunning() {
+			t.Errorf("SharedRunning() = true after the last release with no idle timeout")
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("ReleaseShared() without AcquireShared didn't panic")
+		}
+	}()
+	ReleaseShared()
+}
@@ -0,0 +1,172 @@
+package parallel
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// CompressContainerStream compresses src into dst in the format of
+// CompressContainer, so that inputs too large to hold in memory can be
+// compressed in parallel. Chunks are read from src as workers free up and
+// each one is written to dst as soon as it and every chunk before it are
+// done, so at most MaxPendingChunks chunks and their output are held at
+// once. The output is the same as CompressContainer's for the whole input.
+// It returns the number of bytes written to dst.
+func (d *Dispatcher) CompressContainerStream(dst io.Writer, src io.Reader, level int) (int64, error) {
+	return d.compressContainerStream(dst, src, level, false)
+}
+
+// CompressContainerStreamV2 is like CompressContainerStream but uses the V2
+// algorithm
+func (d *Dispatcher) CompressContainerStreamV2(dst io.Writer, src io.Reader, level int) (int64, error) {
+	return d.compressContainerStream(dst, src, level, true)
+}
+
+// SetMaxPendingChunks sets how many chunks CompressContainerStream holds at
+// once, bounding its memory to about twice that many chunks. Zero or less
+// selects the default of twice the number of workers.
+func (d *Dispatcher) SetMaxPendingChunks(n int) {
+	d.maxPending = n
+}
+
+// MaxPendingChunks returns the number of chunks CompressContainerStream
+// holds at once
+func (d *Dispatcher) MaxPendingChunks() int {
+	if d.maxPending > 0 {
+		return d.maxPending
+	}
+	return 2 * d.numWorkers
+}
+
+// containerStream writes the chunks of a streamed container in order
+type containerStream struct {
+	w       io.Writer
+	written int64
+	// results holds finished chunks by id until their turn comes
+	results map[int]compressionResult
+	next    int
+	// free holds the input buffers of chunks already written
+	free [][]byte
+}
+
+// write writes b to the output, counting the bytes written
+func (cs *containerStream) write(b []byte) error {
+	n, err := cs.w.Write(b)
+	cs.written += int64(n)
+	return err
+}
+
+// flush writes the finished chunks whose turn has come, recycling their
+// input buffers
+func (cs *containerStream) flush(inputs map[int][]byte) error {
+	for {
+		result, ok := cs.results[cs.next]
+		if !ok {
+			return nil
+		}
+		if result.err != nil {
+			return result.err
+		}
+
+		var header [chunkHeaderSize]byte
+		putChunkHeader(header[:], result)
+		if err := cs.write(header[:]); err != nil {
+			return err
+		}
+		// Stored chunks are written from the input buffer, so it is only
+		// recycled afterwards
+		if err := cs.write(result.output); err != nil {
+			return err
+		}
+
+		delete(cs.results, cs.next)
+		cs.free = append(cs.free, inputs[cs.next])
+		delete(inputs, cs.next)
+		cs.next++
+	}
+}
+
+// compressContainerStream is the shared implementation for
+// CompressContainerStream and CompressContainerStreamV2
+func (d *Dispatcher) compressContainerStream(dst io.Writer, src io.Reader, level int, useV2 bool) (int64, error) {
+	chunkSize := min(d.chunkSize, compress.MaxBlockSize)
+	maxPending := d.MaxPendingChunks()
+
+	cs := &containerStream{w: dst, results: make(map[int]compressionResult)}
+	var magic [4]byte
+	binary.LittleEndian.PutUint32(magic[:], ContainerMagic)
+	if err := cs.write(magic[:]); err != nil {
+		return cs.written, err
+	}
+
+	// Stop leaves the workers to the stream until it is done
+	sess := d.acquire()
+	if sess != nil {
+		defer sess.calls.Done()
+	}
+
+	// Every pending result fits, so workers never block on an early return
+	resultCh := make(chan compressionResult, maxPending)
+	inputs := make(map[int][]byte)
+	var s scratch
+	submitted := 0
+	eof := false
+
+	for {
+		// Keep up to maxPending chunks in flight
+		for !eof && submitted-cs.next < maxPending {
+			var buf []byte
+			if n := len(cs.free); n > 0 {
+				buf, cs.free = cs.free[n-1], cs.free[:n-1]
+			} else {
+				buf = make([]byte, chunkSize)
+			}
+
+			n, err := io.ReadFull(src, buf[:chunkSize])
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return cs.written, err
+			}
+			if n == 0 {
+				cs.free = append(cs.free, buf)
+				break
+			}
+
+			job := compressionJob{
+				id:        submitted,
+				input:     buf[:n],
+				level:     level,
+				useV2:     useV2,
+				container: true,
+				resultCh:  resultCh,
+			}
+			inputs[submitted] = buf
+			submitted++
+
+			// Without workers, chunks are compressed on the calling goroutine
+			if sess == nil {
+				cs.results[job.id] = d.processJob(job, &s)
+				continue
+			}
+			sess.jobs <- job
+		}
+
+		if cs.next == submitted {
+			break
+		}
+		if sess != nil {
+			result := <-resultCh
+			cs.results[result.id] = result
+		}
+		if err := cs.flush(inputs); err != nil {
+			return cs.written, err
+		}
+	}
+
+	// End marker
+	err := cs.write([]byte{0, 0, 0, 0})
+	return cs.written, err
+}
@@ -0,0 +1,142 @@
+package parallel
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// TestCompressContainerStream tests that streamed containers match the
+// containers CompressContainer builds in memory
+func TestCompressContainerStream(t *testing.T) {
+	inputs := map[string][]byte{
+		"Empty":            {},
+		"Tiny":             []byte("tiny"),
+		"Exact chunks":     generateTestData(4*64*1024, 0.8),
+		"Small last chunk": generateTestData(5*64*1024+5, 0.8),
+		"Incompressible":   generateTestData(3*64*1024+100, 0.0),
+	}
+
+	for _, running := range []bool{false, true} {
+		d := NewDispatcher(3, 64*1024)
+		d.SetMaxPendingChunks(2)
+		if running {
+			if err := d.Start(); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+		}
+
+		for name, data := range inputs {
+			for _, useV2 := range []bool{false, true} {
+				compressFn, streamFn := d.CompressContainer, d.CompressContainerStream
+				if useV2 {
+					compressFn, streamFn = d.CompressContainerV2, d.CompressContainerStreamV2
+				}
+
+				want, err := compressFn(data, 6)
+				if err != nil {
+					t.Fatalf("%s: CompressContainer() error = %v", name, err)
+				}
+				var buf bytes.Buffer
+				n, err := streamFn(&buf, bytes.NewReader(data), 6)
+				if err != nil {
+					t.Fatalf("%s: CompressContainerStream() error = %v", name, err)
+				}
+				if n != int64(buf.Len()) || !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("%s, running %v, V2 %v: CompressContainerStream() = %d bytes, want the %d of CompressContainer()",
+						name, running, useV2, n, len(want))
+				}
+			}
+		}
+		d.Stop()
+	}
+}
+
+// boundedWriter records how much of src had been read when the first chunk
+// was written
+type boundedWriter struct {
+	src       *countingReader
+	writes    int
+	readFirst int64
+}
+
+func (w *boundedWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.writes == 2 { // After the magic number
+		w.readFirst = w.src.n
+	}
+	return len(p), nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// TestCompressContainerStreamBounded tests that only MaxPendingChunks chunks
+// are read ahead of the output
+func TestCompressContainerStreamBounded(t *testing.T) {
+	const chunkSize = 64 * 1024
+	d := NewDispatcher(2, chunkSize)
+	d.SetMaxPendingChunks(3)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop()
+
+	src := &countingReader{r: bytes.NewReader(generateTestData(40*chunkSize, 0.8))}
+	w := &boundedWriter{src: src}
+	if _, err := d.CompressContainerStream(w, src, 6); err != nil {
+		t.Fatalf("CompressContainerStream() error = %v", err)
+	}
+	if w.readFirst > 3*chunkSize {
+		t.Errorf("read %d bytes before writing the first chunk, want at most %d", w.readFirst, 3*chunkSize)
+	}
+	if d.MaxPendingChunks() != 3 {
+		t.Errorf("MaxPendingChunks() = %d, want 3", d.MaxPendingChunks())
+	}
+	d.SetMaxPendingChunks(0)
+	if d.MaxPendingChunks() != 4 {
+		t.Errorf("MaxPendingChunks() = %d, want the default of 4", d.MaxPendingChunks())
+	}
+}
+
+// errWriter fails every write
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+// TestCompressContainerStreamErrors tests that read and write errors stop
+// the stream
+func TestCompressContainerStreamErrors(t *testing.T) {
+	d := NewDispatcher(2, 64*1024)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer d.Stop()
+
+	data := generateTestData(10*64*1024, 0.8)
+	errTest := errors.New("test error")
+
+	if _, err := d.CompressContainerStream(errWriter{errTest}, bytes.NewReader(data), 6); err != errTest {
+		t.Errorf("CompressContainerStream() with a failing writer error = %v, want %v", err, errTest)
+	}
+
+	src := io.MultiReader(bytes.NewReader(data[:3*64*1024]), errReader{errTest})
+	if _, err := d.CompressContainerStream(io.Discard, src, 6); err != errTest {
+		t.Errorf("CompressContainerStream() with a failing reader error = %v, want %v", err, errTest)
+	}
+}
+
+// errReader fails every read
+type errReader struct{ err error }
+
+func (r errReader) Read(p []byte) (int, error) { return 0, r.err }
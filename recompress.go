@@ -0,0 +1,20 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// WriterOptions provides configuration options for frame writers.
+type WriterOptions = compress.WriterOptions
+
+// Recompress decodes the LZ4 frame read from src and re-encodes it to dst
+// with new options, block by block with bounded memory. It can be used to
+// upgrade archives written at a fast level to a higher level or to the v0.2
+// algorithm without loading them into memory. Only the first frame of src
+// is read; call Recompress again for any frames concatenated after it.
+// It returns the number of uncompressed bytes transcoded.
+func Recompress(dst io.Writer, src io.Reader, opts WriterOptions) (int64, error) {
+	return compress.Recompress(dst, src, opts)
+}
@@ -0,0 +1,217 @@
+package sealed
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// maxRecordSize bounds the length of a sealed record: a stored block plus its
+// kind byte and the AES-GCM tag
+const maxRecordSize = BlockSize + 1 + 16
+
+// Reader decrypts and decompresses a sealed stream
+type Reader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+
+	// block holds the current decoded block and pos the read position in it
+	block []byte
+	pos   int
+	index uint64
+	final bool
+	err   error
+
+	// offsets holds the stream offsets of the records indexed so far, for
+	// seeking, and base the position of the stream in a seekable source
+	offsets []int64
+	base    int64
+}
+
+// OpenReader reads the header of a sealed stream from r and returns a Reader
+// that decrypts it with key. If r is an io.ReadSeeker, the Reader supports Seek,
+// within the stream starting at the current position of r.
+func OpenReader(r io.Reader, key []byte) (*Reader, error) {
+	// Sources that can't report their position, such as pipes, fail to
+	// Seek later instead
+	var base int64
+	if rs, ok := r.(io.ReadSeeker); ok {
+		base, _ = rs.Seek(0, io.SeekCurrent)
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidStream
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], magic[:]) || header[4] != version || header[5] != blockSizeLog {
+		return nil, ErrInvalidStream
+	}
+
+	aead, err := newAEAD(key, header[6:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{r: r, aead: aead, header: header, base: base}, nil
+}
+
+// Read implements io.Reader
+func (z *Reader) Read(p []byte) (int, error) {
+	for z.pos >= len(z.block) {
+		if z.final {
+			return 0, io.EOF
+		}
+		if z.err != nil {
+			return 0, z.err
+		}
+		if z.err = z.next(); z.err != nil {
+			return 0, z.err
+		}
+	}
+
+	n := copy(p, z.block[z.pos:])
+	z.pos += n
+	return n, nil
+}
+
+// next reads, authenticates and decodes the next block
+func (z *Reader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(z.r, size[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+	n := binary.LittleEndian.Uint32(size[:])
+	if n < uint32(z.aead.Overhead())+1 || n > maxRecordSize {
+		return ErrInvalidStream
+	}
+
+	record := make([]byte, n)
+	if _, err := io.ReadFull(z.r, record); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrTruncated
+		}
+		return err
+	}
+
+	// Only the record sealed as final may end the stream. Decrypt into a
+	// separate buffer since a failed Open wipes its output.
+	final := false
+	plain, err := z.aead.Open(nil, nonce(z.index), record, additionalData(z.header, z.index, false))
+	if err != nil {
+		final = true
+		plain, err = z.aead.Open(nil, nonce(z.index), record, additionalData(z.header, z.index, true))
+		if err != nil {
+			return ErrAuthentication
+		}
+	}
+
+	var block []byte
+	switch plain[0] {
+	case kindStored:
+		block = plain[1:]
+	case kindCompressed:
+		block, err = compress.DecompressBlockAlloc(plain[1:], BlockSize)
+		if err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidStream
+	}
+
+	// Every block but the last is full, which lets Seek locate blocks by index
+	if len(block) > BlockSize || (!final && len(block) != BlockSize) {
+		return ErrInvalidStream
+	}
+
+	if uint64(len(z.offsets)) == z.index {
+		prev := int64(headerSize)
+		if z.index > 0 {
+			prev = z.offsets[z.index-1]
+		}
+		z.offsets = append(z.offsets, prev+4+int64(n))
+	}
+
+	z.block = block
+	z.pos = 0
+	z.final = final
+	z.index++
+	return nil
+}
+
+// Seek implements io.Seeker for sources that are themselves an io.ReadSeeker.
+// Only the block containing the new offset is decrypted; seeking forward
+// past blocks that weren't read yet only reads their lengths.
+// io.SeekEnd is not supported.
+func (z *Reader) Seek(offset int64, whence int) (int64, error) {
+	rs, ok := z.r.(io.ReadSeeker)
+	if !ok {
+		return 0, errors.New("sealed: source is not seekable")
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += z.position()
+	default:
+		return 0, errors.New("sealed: unsupported whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("sealed: negative position")
+	}
+
+	target := uint64(offset / BlockSize)
+
+	// Find the start of the target block, indexing skipped records on the way
+	for uint64(len(z.offsets)) < target {
+		start := int64(headerSize)
+		if len(z.offsets) > 0 {
+			start = z.offsets[len(z.offsets)-1]
+		}
+		if _, err := rs.Seek(z.base+start, io.SeekStart); err != nil {
+			return 0, err
+		}
+		var size [4]byte
+		if _, err := io.ReadFull(rs, size[:]); err != nil {
+			return 0, ErrTruncated
+		}
+		z.offsets = append(z.offsets, start+4+int64(binary.LittleEndian.Uint32(size[:])))
+	}
+
+	start := int64(headerSize)
+	if target > 0 {
+		start = z.offsets[target-1]
+	}
+	if _, err := rs.Seek(z.base+start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	z.index = target
+	z.block, z.pos, z.final, z.err = nil, 0, false, nil
+	if err := z.next(); err != nil {
+		z.err = err
+		return 0, err
+	}
+
+	// Seeking past the end of the final block leaves the Reader at EOF
+	z.pos = min(int(offset-int64(target)*BlockSize), len(z.block))
+	return int64(target)*BlockSize + int64(z.pos), nil
+}
+
+// position returns the current offset in the decoded stream
+func (z *Reader) position() int64 {
+	if z.block == nil {
+		return 0
+	}
+	return int64(z.index-1)*BlockSize + int64(z.pos)
+}
@@ -0,0 +1,224 @@
+package sealed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+// testData returns size bytes that mix compressible and random blocks
+func testData(size int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, size)
+	for i := range data {
+		if (i/BlockSize)%2 == 0 {
+			data[i] = byte(i % 251 % 17)
+		} else {
+			data[i] = byte(rng.Intn(256))
+		}
+	}
+	return data
+}
+
+// seal returns data sealed with key
+func seal(t *testing.T, data, key []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := SealWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("SealWriter() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// open returns the decrypted contents of a sealed stream
+func open(stream, key []byte) ([]byte, error) {
+	r, err := OpenReader(bytes.NewReader(stream), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// records splits a sealed stream into its records, including length prefixes
+func records(t *testing.T, stream []byte) [][]byte {
+	t.Helper()
+
+	var recs [][]byte
+	for pos := headerSize; pos < len(stream); {
+		n := int(binary.LittleEndian.Uint32(stream[pos:]))
+		recs = append(recs, stream[pos:pos+4+n])
+		pos += 4 + n
+	}
+	return recs
+}
+
+// TestRoundTrip tests sealing and opening streams of various sizes
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 100, BlockSize, BlockSize + 1, 3*BlockSize + 12345} {
+		data := testData(size)
+		for _, keySize := range []int{16, 24, 32} {
+			key := testKey[:keySize]
+			got, err := open(seal(t, data, key), key)
+			if err != nil {
+				t.Fatalf("size %d, key %d: open error = %v", size, keySize, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("size %d, key %d: got %d bytes, want %d", size, keySize, len(got), len(data))
+			}
+		}
+	}
+}
+
+// TestCompression tests that compressible data shrinks before encryption
+func TestCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("compress then encrypt "), 10000)
+	if stream := seal(t, data, testKey); len(stream) > len(data)/10 {
+		t.Errorf("sealed %d bytes into %d, want compression", len(data), len(stream))
+	}
+
+	// Each stream uses a fresh salt, so equal inputs don't produce equal output
+	if bytes.Equal(seal(t, data, testKey), seal(t, data, testKey)) {
+		t.Errorf("two streams sealed from the same data are identical")
+	}
+}
+
+// TestTampering tests detection of modified, reordered and truncated streams
+func TestTampering(t *testing.T) {
+	data := testData(3 * BlockSize)
+	stream := seal(t, data, testKey)
+	recs := records(t, stream)
+	if len(recs) != 3 {
+		t.Fatalf("stream has %d records, want 3", len(recs))
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{stream[:headerSize]}, parts...), nil)
+	}
+
+	flipped := append([]byte(nil), stream...)
+	flipped[len(flipped)-20] ^= 1
+
+	otherKey := append([]byte(nil), testKey...)
+	otherKey[0] ^= 1
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		key     []byte
+		wantErr error
+	}{
+		{"Flipped bit", flipped, testKey, ErrAuthentication},
+		{"Wrong key", stream, otherKey, ErrAuthentication},
+		{"Reordered", join(recs[1], recs[0], recs[2]), testKey, ErrAuthentication},
+		{"Final block dropped", join(recs[0], recs[1]), testKey, ErrTruncated},
+		{"Final block moved", join(recs[0], recs[2]), testKey, ErrAuthentication},
+		{"Cut mid record", stream[:len(stream)-5], testKey, ErrTruncated},
+		{"Header only", stream[:headerSize], testKey, ErrTruncated},
+		{"Bad magic", append([]byte("XXXX"), stream[4:]...), testKey, ErrInvalidStream},
+		{"Empty", nil, testKey, ErrInvalidStream},
+		{"Short key", stream, testKey[:10], ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := open(tt.stream, tt.key); err != tt.wantErr {
+				t.Errorf("open error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestSeek tests random access into a sealed stream
+func TestSeek(t *testing.T) {
+	data := testData(4*BlockSize + 500)
+	r, err := OpenReader(bytes.NewReader(seal(t, data, testKey)), testKey)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+
+	buf := make([]byte, 1000)
+	for _, offset := range []int64{3*BlockSize + 7, 10, BlockSize - 500, 4 * BlockSize, 2 * BlockSize, int64(len(data)) - 100} {
+		pos, err := r.Seek(offset, io.SeekStart)
+		if err != nil {
+			t.Fatalf("Seek(%d) error = %v", offset, err)
+		}
+		if pos != offset {
+			t.Errorf("Seek(%d) = %d", offset, pos)
+		}
+
+		n, err := io.ReadFull(r, buf[:min(len(buf), len(data)-int(offset))])
+		if err != nil {
+			t.Fatalf("ReadFull() at %d error = %v", offset, err)
+		}
+		if !bytes.Equal(buf[:n], data[offset:offset+int64(n)]) {
+			t.Errorf("data at offset %d doesn't match", offset)
+		}
+	}
+
+	// Relative seeks continue from the current position
+	if _, err := r.Seek(100, io.SeekStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if pos, err := r.Seek(BlockSize, io.SeekCurrent); err != nil || pos != BlockSize+100 {
+		t.Errorf("Seek(SeekCurrent) = %d, %v, want %d", pos, err, BlockSize+100)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(rest, data[BlockSize+100:]) {
+		t.Errorf("ReadAll() after seek = %d bytes, %v, want %d bytes", len(rest), err, len(data)-BlockSize-100)
+	}
+
+	// Plain readers can't seek
+	plain, err := OpenReader(bytes.NewBuffer(seal(t, data, testKey)), testKey)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	if _, err := plain.Seek(0, io.SeekStart); err == nil {
+		t.Errorf("Seek() on unseekable source error = nil, want error")
+	}
+}
+
+// TestSeekAfterPrefix tests seeking in a stream that starts past other data
+// in its file
+func TestSeekAfterPrefix(t *testing.T) {
+	data := testData(3*BlockSize + 500)
+	prefix := []byte("archive header\n")
+	path := filepath.Join(t.TempDir(), "sealed")
+	if err := os.WriteFile(path, append(prefix, seal(t, data, testKey)...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(int64(len(prefix)), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenReader(f, testKey)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	for _, offset := range []int64{2*BlockSize + 9, 5, BlockSize} {
+		if pos, err := r.Seek(offset, io.SeekStart); err != nil || pos != offset {
+			t.Fatalf("Seek(%d) = %d, %v", offset, pos, err)
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(rest, data[offset:]) {
+			t.Errorf("ReadAll() after Seek(%d) = %d bytes, %v, want %d bytes", offset, len(rest), err, len(data)-int(offset))
+		}
+	}
+}
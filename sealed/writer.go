@@ -0,0 +1,134 @@
+package sealed
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// Writer compresses and seals data written to it.
+// Close must be called to seal the final block; without it readers report
+// the stream as truncated.
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	level  compress.CompressionLevel
+	header []byte
+	buf    []byte
+	record []byte
+	index  uint64
+	closed bool
+	err    error
+}
+
+// SealWriter returns a Writer that compresses data at the default level and
+// seals it with key, which must be 16, 24 or 32 bytes long
+func SealWriter(w io.Writer, key []byte) (*Writer, error) {
+	return SealWriterLevel(w, key, int(compress.CurrentDefaultLevel()))
+}
+
+// SealWriterLevel is like SealWriter but compresses at the given level
+func SealWriterLevel(w io.Writer, key []byte, level int) (*Writer, error) {
+	header := make([]byte, headerSize)
+	copy(header, magic[:])
+	header[4] = version
+	header[5] = blockSizeLog
+	if _, err := rand.Read(header[6:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key, header[6:])
+	if err != nil {
+		return nil, err
+	}
+
+	if level < 1 || level > int(compress.MaxLevel) {
+		level = int(compress.CurrentDefaultLevel())
+	}
+
+	return &Writer{
+		w:      w,
+		aead:   aead,
+		level:  compress.CompressionLevel(level),
+		header: header,
+		buf:    make([]byte, 0, BlockSize),
+	}, nil
+}
+
+// Write implements io.Writer
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errors.New("sealed: write to closed writer")
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full block is only sealed once more data shows it isn't the last
+		if len(z.buf) == BlockSize {
+			if z.err = z.seal(false); z.err != nil {
+				return written, z.err
+			}
+		}
+
+		n := copy(z.buf[len(z.buf):BlockSize], p)
+		z.buf = z.buf[:len(z.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the final block. It does not close the underlying writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+	z.closed = true
+	if z.err != nil {
+		return z.err
+	}
+
+	z.err = z.seal(true)
+	return z.err
+}
+
+// seal compresses and encrypts the buffered block and writes it out
+func (z *Writer) seal(final bool) error {
+	if z.index == 0 {
+		if _, err := z.w.Write(z.header); err != nil {
+			return err
+		}
+	}
+
+	// Plaintext: the record kind followed by the compressed or raw block
+	plain := append(z.record[:0], kindStored)
+	plain = append(plain, z.buf...)
+	if len(z.buf) >= compress.MinBlockSize {
+		compressed, err := compress.CompressBlockLevel(z.buf, nil, z.level)
+		if err == nil && len(compressed) < len(z.buf) {
+			plain = append(plain[:1], compressed...)
+			plain[0] = kindCompressed
+		}
+	}
+
+	ad := additionalData(z.header, z.index, final)
+	out := make([]byte, 4, 4+len(plain)+z.aead.Overhead())
+	out = z.aead.Seal(out, nonce(z.index), plain, ad)
+	binary.LittleEndian.PutUint32(out, uint32(len(out)-4))
+
+	if _, err := z.w.Write(out); err != nil {
+		return err
+	}
+
+	z.record = plain
+	z.buf = z.buf[:0]
+	z.index++
+	return nil
+}
@@ -0,0 +1,24 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// EncoderSession compresses a sequence of messages that share a history, so
+// that each may reference the 64KB of messages before it. Every message
+// keeps its own length-prefixed framing, but they must be decompressed in
+// order by one DecoderSession. Reset starts a new history.
+type EncoderSession = compress.EncoderSession
+
+// DecoderSession decompresses the messages of an EncoderSession in order.
+type DecoderSession = compress.DecoderSession
+
+// NewEncoderSession returns an EncoderSession compressing at the given level,
+// from 0 (stored) to 12.
+func NewEncoderSession(level int) (*EncoderSession, error) {
+	return compress.NewEncoderSession(compress.CompressionLevel(level))
+}
+
+// NewDecoderSession returns a DecoderSession for the messages of an
+// EncoderSession.
+func NewDecoderSession() *DecoderSession {
+	return compress.NewDecoderSession()
+}
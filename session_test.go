@@ -0,0 +1,31 @@
+package goz4x
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestSession tests the root session constructors
+func TestSession(t *testing.T) {
+	enc, err := NewEncoderSession(9)
+	if err != nil {
+		t.Fatalf("NewEncoderSession error: %v", err)
+	}
+	dec := NewDecoderSession()
+
+	for i := 0; i < 50; i++ {
+		line := []byte(fmt.Sprintf("ts=%d level=info msg=\"request served\" path=/api/v1/items status=200 bytes=%d", 1700000000+i, 512+i))
+		msg, err := enc.Compress(line, nil)
+		if err != nil {
+			t.Fatalf("Compress error: %v", err)
+		}
+		got, err := dec.Decompress(msg)
+		if err != nil || !bytes.Equal(got, line) {
+			t.Fatalf("Decompress = %q, %v, want %q", got, err, line)
+		}
+		if i > 0 && len(msg) >= len(line)/2 {
+			t.Errorf("message %d compressed to %d bytes, want under %d", i, len(msg), len(line)/2)
+		}
+	}
+}
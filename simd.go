@@ -0,0 +1,25 @@
+//go:build !goz4x_minimal
+
+package goz4x
+
+import "github.com/harriteja/GoZ4X/v04"
+
+// V4 API functions with SIMD optimizations
+
+// CompressBlockV4 compresses a byte slice using the v0.4 algorithm with SIMD optimizations.
+// This provides better performance on modern CPUs with SIMD instruction sets.
+func CompressBlockV4(src []byte, dst []byte) ([]byte, error) {
+	return v04.CompressBlock(src, dst)
+}
+
+// CompressBlockV4Level compresses a byte slice with the v0.4 algorithm and specified level.
+// It uses SIMD instructions where possible for better performance.
+func CompressBlockV4Level(src []byte, dst []byte, level int) ([]byte, error) {
+	return v04.CompressBlockLevel(src, dst, level)
+}
+
+// CompressBlockV4Parallel compresses a byte slice using v0.4 algorithm with multiple goroutines.
+// This provides both SIMD acceleration and parallelism for maximum performance.
+func CompressBlockV4Parallel(src []byte, dst []byte) ([]byte, error) {
+	return v04.CompressBlockParallel(src, dst)
+}
@@ -0,0 +1,9 @@
+package goz4x
+
+// Skip discards the next n bytes of decompressed data without decompressing
+// the blocks it passes over, seeking past stored blocks when the source is an
+// io.Seeker. It is the fast way to reach the tail of a large stream. If the
+// stream ends first, it returns the bytes skipped and io.EOF.
+func (r *Reader) Skip(n int64) (int64, error) {
+	return r.r.Skip(n)
+}
@@ -0,0 +1,34 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestReaderSkip tests reading the tail of a stream after skipping the rest
+func TestReaderSkip(t *testing.T) {
+	data := bytes.Repeat([]byte("2024-01-01 12:00:00 INFO request handled\n"), 20000)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	tail := int64(1000)
+	if n, err := r.Skip(int64(len(data)) - tail); err != nil || n != int64(len(data))-tail {
+		t.Fatalf("Skip() = %d, %v, want %d, nil", n, err, int64(len(data))-tail)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, data[len(data)-int(tail):]) {
+		t.Errorf("read %d bytes, want the last %d", len(got), tail)
+	}
+}
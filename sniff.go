@@ -0,0 +1,24 @@
+package goz4x
+
+import "github.com/harriteja/GoZ4X/compress"
+
+// Format is the kind of LZ4 data Sniff finds at the start of a payload.
+type Format = compress.Format
+
+// Formats Sniff tells apart. FormatUnknown is data that isn't LZ4, or a
+// prefix too short to tell.
+const (
+	FormatUnknown = compress.FormatUnknown
+	FormatFrame   = compress.FormatFrame
+	FormatLegacy  = compress.FormatLegacy
+	FormatBlock   = compress.FormatBlock
+)
+
+// Sniff identifies the kind of LZ4 data prefix starts: frames by their magic
+// numbers, raw blocks by parsing their first sequences. Services accepting
+// payloads that may or may not be compressed use it to pick NewReader,
+// DecompressBlock or neither; a decode error still means the guess was
+// wrong. A prefix of a few hundred bytes suffices.
+func Sniff(prefix []byte) Format {
+	return compress.Sniff(prefix)
+}
@@ -0,0 +1,21 @@
+package goz4x
+
+import (
+	"io"
+	"os"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// DecompressFile decompresses src into dst, leaving runs of zeros as holes so
+// that sparse files compressed with CompressFile come back sparse. It returns
+// the number of decompressed bytes.
+func DecompressFile(dst *os.File, src io.Reader) (int64, error) {
+	return compress.DecompressFile(dst, src)
+}
+
+// DecompressFileWithOptions is DecompressFile reading src with options. SparseHoles
+// is always set.
+func DecompressFileWithOptions(dst *os.File, src io.Reader, options ReaderOptions) (int64, error) {
+	return compress.DecompressFileWithOptions(dst, src, options)
+}
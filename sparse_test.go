@@ -0,0 +1,55 @@
+package goz4x
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDecompressFile tests restoring a compressed sparse file
+func TestDecompressFile(t *testing.T) {
+	data := bytes.Repeat([]byte("sparse file data "), 1000)
+	path := filepath.Join(t.TempDir(), "disk.img")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(2 * 1024 * 1024); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	if _, err := f.WriteAt(data, 1024*1024); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := CompressFile(&buf, f, WriterOptions{}); err != nil {
+		t.Fatalf("CompressFile() error = %v", err)
+	}
+
+	dst, err := os.Create(filepath.Join(t.TempDir(), "restored.img"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer dst.Close()
+
+	n, err := DecompressFile(dst, &buf)
+	if err != nil {
+		t.Fatalf("DecompressFile() error = %v", err)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("DecompressFile() = %d, want %d", n, len(want))
+	}
+	got, err := os.ReadFile(dst.Name())
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("restored %d bytes, want %d", len(got), len(want))
+	}
+}
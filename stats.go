@@ -0,0 +1,19 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// FrameStats summarizes a frame, as recorded in its stats trailer.
+type FrameStats = compress.FrameStats
+
+// ErrNoFrameStats indicates a stream that doesn't end with a stats trailer.
+var ErrNoFrameStats = compress.ErrNoFrameStats
+
+// ReadFrameStats reads the stats trailer written after a frame with the
+// StatsTrailer option, giving the totals of the stream without scanning it.
+func ReadFrameStats(rs io.ReadSeeker) (FrameStats, error) {
+	return compress.ReadFrameStats(rs)
+}
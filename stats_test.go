@@ -0,0 +1,28 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestReadFrameStats tests reading back the stats trailer of a stream
+func TestReadFrameStats(t *testing.T) {
+	data := generateCompressibleData(300 * 1024)
+
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{BlockSize: 64 * 1024, StatsTrailer: true})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	stats, err := ReadFrameStats(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadFrameStats error: %v", err)
+	}
+	if stats.Uncompressed != uint64(len(data)) || stats.Blocks != 5 {
+		t.Errorf("stats = %+v, want %d bytes in 5 blocks", stats, len(data))
+	}
+}
@@ -0,0 +1,63 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// StreamReport describes a stream checked by TestStream: the frames and
+// blocks verified, the bytes read and produced, and the checksums found.
+type StreamReport = compress.StreamReport
+
+// ChecksumStatus reports whether a kind of checksum was absent, valid or
+// invalid in a stream checked by TestStream.
+type ChecksumStatus = compress.ChecksumStatus
+
+// Checksum statuses of a StreamReport.
+const (
+	ChecksumAbsent  = compress.ChecksumAbsent
+	ChecksumValid   = compress.ChecksumValid
+	ChecksumInvalid = compress.ChecksumInvalid
+)
+
+// ErrorClass groups stream errors the way the lz4 command line tool does.
+type ErrorClass = compress.ErrorClass
+
+// Error classes returned by Classify.
+const (
+	ClassNone      = compress.ClassNone
+	ClassFormat    = compress.ClassFormat
+	ClassCorrupt   = compress.ClassCorrupt
+	ClassTruncated = compress.ClassTruncated
+	ClassRead      = compress.ClassRead
+)
+
+// Exit codes of the lz4 command line tool, returned by ErrorClass.ExitCode.
+const (
+	ExitOK            = compress.ExitOK
+	ExitFailure       = compress.ExitFailure
+	ExitDecompression = compress.ExitDecompression
+	ExitReadError     = compress.ExitReadError
+	ExitUnfinished    = compress.ExitUnfinished
+)
+
+// Errors reported by TestStream.
+var (
+	ErrInvalidMagic    = compress.ErrInvalidMagic
+	ErrContentChecksum = compress.ErrContentChecksum
+	ErrNoFrames        = compress.ErrNoFrames
+	ErrSourceRead      = compress.ErrSourceRead
+)
+
+// TestStream decodes every frame of src without returning the data, like
+// lz4 -t, verifying checksums and declared content sizes.
+func TestStream(src io.Reader, opts ReaderOptions) (StreamReport, error) {
+	return compress.TestStream(src, opts)
+}
+
+// Classify returns the class of an error returned while reading a stream,
+// whose ExitCode is the exit code lz4 uses for it.
+func Classify(err error) ErrorClass {
+	return compress.Classify(err)
+}
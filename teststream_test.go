@@ -0,0 +1,32 @@
+package goz4x
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestTestStream tests the root TestStream and Classify wrappers
+func TestTestStream(t *testing.T) {
+	data := generateCompressibleData(100 * 1024)
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	report, err := TestStream(bytes.NewReader(buf.Bytes()), ReaderOptions{})
+	if err != nil {
+		t.Fatalf("TestStream error: %v", err)
+	}
+	if report.Frames != 1 || report.Uncompressed != uint64(len(data)) {
+		t.Errorf("TestStream = %+v, want 1 frame of %d bytes", report, len(data))
+	}
+
+	_, err = TestStream(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), ReaderOptions{})
+	if code := Classify(err).ExitCode(); code != ExitUnfinished {
+		t.Errorf("truncated stream exit code = %d, want %d", code, ExitUnfinished)
+	}
+}
@@ -0,0 +1,39 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// ReaderOptions provides configuration options for frame readers.
+type ReaderOptions = compress.ReaderOptions
+
+// TraceEvent describes one sequence of a block: its literals, match offset
+// and length, and its position in the compressed and uncompressed block.
+type TraceEvent = compress.TraceEvent
+
+// TraceFunc receives the sequences of every block processed when set as the
+// DebugTrace option of a reader or writer.
+type TraceFunc = compress.TraceFunc
+
+// NewReaderWithOptions creates a new Reader with custom options.
+func NewReaderWithOptions(r io.Reader, opts ReaderOptions) *Reader {
+	return &Reader{r: compress.NewReaderWithOptions(r, opts)}
+}
+
+// NewWriterWithOptions creates a new Writer with custom options.
+func NewWriterWithOptions(w io.Writer, opts WriterOptions) *Writer {
+	return &Writer{w: compress.NewWriterWithOptions(w, opts)}
+}
+
+// TraceWriter returns a TraceFunc that writes one line per sequence to w.
+func TraceWriter(w io.Writer) TraceFunc {
+	return compress.TraceWriter(w)
+}
+
+// TraceBlock reports every sequence of a compressed block to fn.
+// It is useful for inspecting blocks that fail to decompress.
+func TraceBlock(block []byte, fn TraceFunc) error {
+	return compress.TraceBlock(block, fn)
+}
@@ -0,0 +1,37 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestDebugTrace tests the DebugTrace options on Writer and Reader
+func TestDebugTrace(t *testing.T) {
+	data := generateCompressibleData(32 * 1024)
+
+	var writeTrace strings.Builder
+	var buf bytes.Buffer
+	w := NewWriterWithOptions(&buf, WriterOptions{DebugTrace: TraceWriter(&writeTrace)})
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	var readTrace strings.Builder
+	r := NewReaderWithOptions(&buf, ReaderOptions{DebugTrace: TraceWriter(&readTrace)})
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Read data doesn't match original")
+	}
+
+	if writeTrace.Len() == 0 || writeTrace.String() != readTrace.String() {
+		t.Errorf("Writer and Reader traces differ:\n%s\n%s", writeTrace.String(), readTrace.String())
+	}
+}
@@ -29,14 +29,14 @@ type ParallelWriter struct {
 
 // NewParallelWriter creates a new ParallelWriter with default options
 func NewParallelWriter(w io.Writer) *ParallelWriter {
-	return NewParallelWriterLevel(w, int(compress.DefaultLevel))
+	return NewParallelWriterLevel(w, int(compress.CurrentDefaultLevel()))
 }
 
 // NewParallelWriterLevel creates a new ParallelWriter with custom compression level
 func NewParallelWriterLevel(w io.Writer, level int) *ParallelWriter {
 	return NewParallelWriterWithOptions(w, ParallelWriterOptions{
 		Level:      level,
-		NumWorkers: 0, // Use default/GOMAXPROCS
+		NumWorkers: 0, // Use the default set by compress.SetDefaultWorkers
 		ChunkSize:  0, // Use default chunk size
 	})
 }
@@ -45,22 +45,51 @@ func NewParallelWriterLevel(w io.Writer, level int) *ParallelWriter {
 type ParallelWriterOptions struct {
 	// Compression level (1-12)
 	Level int
-	// Number of worker goroutines (0 = use GOMAXPROCS)
+	// Number of worker goroutines (0 = use compress.CurrentDefaultWorkers)
 	NumWorkers int
 	// Size of chunks for parallel compression (0 = use default)
 	ChunkSize int
 	// Use v0.2 algorithm for better compression
 	UseV2 bool
+	// BlockChecksum, if set, enables block checksums computed with the
+	// given algorithm
+	BlockChecksum compress.Checksummer
+}
+
+// Validate reports the first setting that NewParallelWriterWithOptions would
+// otherwise replace: a Level outside 1 to 12, a negative NumWorkers, or a
+// negative ChunkSize or one above the 4MB block limit.
+func (o ParallelWriterOptions) Validate() error {
+	if o.Level < 1 || o.Level > int(compress.MaxLevel) {
+		return compress.ErrInvalidCompressionLevel
+	}
+	if o.NumWorkers < 0 {
+		return compress.ErrInvalidNumWorkers
+	}
+	if o.ChunkSize < 0 || o.ChunkSize > compress.MaxBlockSize {
+		return compress.ErrInvalidBlockSize
+	}
+	return nil
+}
+
+// NewCheckedParallelWriter is NewParallelWriterWithOptions, except that it
+// returns the error of options.Validate instead of falling back to defaults
+func NewCheckedParallelWriter(w io.Writer, options ParallelWriterOptions) (*ParallelWriter, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewParallelWriterWithOptions(w, options), nil
 }
 
 // NewParallelWriterWithOptions creates a new ParallelWriter with custom options
 func NewParallelWriterWithOptions(w io.Writer, options ParallelWriterOptions) *ParallelWriter {
 	// Create the base Writer instead of ParallelWriter for better compatibility
 	var baseWriter *compress.Writer
-	if options.UseV2 {
+	if options.UseV2 || options.BlockChecksum != nil {
 		baseWriter = compress.NewWriterWithOptions(w, compress.WriterOptions{
-			Level: compress.CompressionLevel(options.Level),
-			UseV2: true,
+			Level:         compress.CompressionLevel(options.Level),
+			UseV2:         options.UseV2,
+			BlockChecksum: options.BlockChecksum,
 		})
 	} else {
 		baseWriter = compress.NewWriterLevel(w, compress.CompressionLevel(options.Level))
@@ -125,6 +154,19 @@ func (pw *ParallelWriter) Close() error {
 	return pw.w.Close()
 }
 
+// Abort cancels the frame being written: the workers are stopped and the
+// base writer drops its buffered data without writing anything more.
+// Reset restarts the workers for a new frame.
+func (pw *ParallelWriter) Abort() {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if pw.dispatcher != nil {
+		pw.dispatcher.Stop()
+	}
+	pw.w.Abort()
+}
+
 // SetNumWorkers sets the number of worker goroutines
 func (pw *ParallelWriter) SetNumWorkers(n int) {
 	pw.mu.Lock()
@@ -147,6 +189,15 @@ func (pw *ParallelWriter) SetChunkSize(size int) {
 	pw.chunkSize = size
 }
 
+// SetMetricsRecorder sets the recorder notified of every block written.
+// A nil recorder disables metrics.
+func (pw *ParallelWriter) SetMetricsRecorder(m compress.MetricsRecorder) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.w.SetMetricsRecorder(m)
+}
+
 // NumWorkers returns the number of worker goroutines
 func (pw *ParallelWriter) NumWorkers() int {
 	pw.mu.Lock()
@@ -1,10 +1,36 @@
 package v03
 
 import (
+	"sync/atomic"
+
 	"github.com/harriteja/GoZ4X/compress"
 	"github.com/harriteja/GoZ4X/parallel"
 )
 
+// ownDispatcher is set by UseSharedDispatcher(false)
+var ownDispatcher atomic.Bool
+
+// UseSharedDispatcher sets whether the CompressBlockParallel functions run
+// on the shared dispatcher of the parallel package, the default, or start
+// and stop a dispatcher of their own on every call. The shared workers are
+// started on first use and stopped once idle; see parallel.AcquireShared.
+func UseSharedDispatcher(on bool) {
+	ownDispatcher.Store(!on)
+}
+
+// acquireDispatcher returns a running dispatcher for one call, and the
+// function to call once done with it
+func acquireDispatcher() (*parallel.Dispatcher, func(), error) {
+	if !ownDispatcher.Load() {
+		return parallel.AcquireShared(), parallel.ReleaseShared, nil
+	}
+	dispatcher := parallel.NewDispatcher(0, 0) // Use defaults
+	if err := dispatcher.Start(); err != nil {
+		return nil, nil, err
+	}
+	return dispatcher, dispatcher.Stop, nil
+}
+
 // CompressBlockParallel compresses a byte slice using multiple goroutines with default compression level.
 // This provides better performance on multicore systems for large inputs.
 func CompressBlockParallel(src []byte, dst []byte) ([]byte, error) {
@@ -13,14 +39,14 @@ func CompressBlockParallel(src []byte, dst []byte) ([]byte, error) {
 
 // CompressBlockParallelLevel compresses a byte slice using multiple goroutines with the specified level.
 // This provides better performance on multicore systems for large inputs.
+// The result is a single LZ4 block that any block decoder can decompress.
 func CompressBlockParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
-	dispatcher := parallel.NewDispatcher(0, 0) // Use defaults
-	defer dispatcher.Stop()
-
-	if err := dispatcher.Start(); err != nil {
+	dispatcher, release, err := acquireDispatcher()
+	if err != nil {
 		// Fall back to non-parallel compression
 		return compress.CompressBlockLevel(src, dst, compress.CompressionLevel(level))
 	}
+	defer release()
 
 	return dispatcher.CompressBlocks(src, level)
 }
@@ -34,13 +60,12 @@ func CompressBlockV2Parallel(src []byte, dst []byte) ([]byte, error) {
 // CompressBlockV2ParallelLevel compresses a byte slice using v0.2 algorithm with multiple goroutines.
 // This provides better compression ratio and better performance on multicore systems.
 func CompressBlockV2ParallelLevel(src []byte, dst []byte, level int) ([]byte, error) {
-	dispatcher := parallel.NewDispatcher(0, 0) // Use defaults
-	defer dispatcher.Stop()
-
-	if err := dispatcher.Start(); err != nil {
+	dispatcher, release, err := acquireDispatcher()
+	if err != nil {
 		// Fall back to non-parallel compression
 		return compress.CompressBlockV2Level(src, dst, compress.CompressionLevel(level))
 	}
+	defer release()
 
 	return dispatcher.CompressBlocksV2(src, level)
 }
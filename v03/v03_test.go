@@ -3,55 +3,26 @@ package v03
 import (
 	"bytes"
 	"io"
-	"math/rand"
 	"runtime"
 	"testing"
-	"time"
 
 	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/internal/testgen"
+	"github.com/harriteja/GoZ4X/parallel"
 )
 
+// seeds numbers the data of the generators below, so that every call
+// returns new data and runs are reproducible
+var seeds testgen.Seq
+
 // generateCompressibleData creates test data with good compression characteristics
 func generateCompressibleData(size int) []byte {
-	rand.Seed(time.Now().UnixNano())
-	data := make([]byte, size)
-
-	// Create a pattern that repeats but with some variation
-	patternSize := 4 * 1024 // 4KB pattern
-	pattern := make([]byte, patternSize)
-	for i := 0; i < patternSize; i++ {
-		pattern[i] = byte(rand.Intn(256))
-	}
-
-	// Fill the data with the pattern and some random variations
-	for i := 0; i < size; i += patternSize {
-		end := i + patternSize
-		if end > size {
-			end = size
-		}
-
-		// Copy the pattern
-		copy(data[i:end], pattern)
-
-		// Add some random variations (15% of bytes get randomized)
-		for j := i; j < end; j++ {
-			if rand.Float32() < 0.15 {
-				data[j] = byte(rand.Intn(256))
-			}
-		}
-	}
-
-	return data
+	return testgen.Pattern(seeds.Next(), size, 4*1024, 0.15)
 }
 
 // generateRandomData creates test data with poor compression characteristics
 func generateRandomData(size int) []byte {
-	rand.Seed(time.Now().UnixNano())
-	data := make([]byte, size)
-	for i := 0; i < size; i++ {
-		data[i] = byte(rand.Intn(256))
-	}
-	return data
+	return testgen.Random(seeds.Next(), size)
 }
 
 // TestCompressBlockParallel tests the parallel compression functions
@@ -111,6 +82,38 @@ func testCompressBlockParallel(t *testing.T, input []byte) {
 	}
 }
 
+// TestUseSharedDispatcher tests that the parallel functions give the same
+// output on the shared dispatcher as on one of their own
+func TestUseSharedDispatcher(t *testing.T) {
+	input := generateCompressibleData(3 * 1024 * 1024)
+	shared, err := CompressBlockParallelLevel(input, nil, 6)
+	if err != nil {
+		t.Fatalf("CompressBlockParallelLevel error: %v", err)
+	}
+	if !parallel.SharedRunning() {
+		t.Errorf("shared dispatcher not running after CompressBlockParallelLevel")
+	}
+
+	UseSharedDispatcher(false)
+	defer UseSharedDispatcher(true)
+	own, err := CompressBlockParallelLevel(input, nil, 6)
+	if err != nil {
+		t.Fatalf("CompressBlockParallelLevel error: %v", err)
+	}
+	if !bytes.Equal(shared, own) {
+		t.Errorf("output on the shared dispatcher differs from a dispatcher of its own")
+	}
+	ownV2, err := CompressBlockV2ParallelLevel(input, nil, 6)
+	if err != nil {
+		t.Fatalf("CompressBlockV2ParallelLevel error: %v", err)
+	}
+	UseSharedDispatcher(true)
+	sharedV2, err := CompressBlockV2ParallelLevel(input, nil, 6)
+	if err != nil || !bytes.Equal(sharedV2, ownV2) {
+		t.Errorf("V2 output on the shared dispatcher = %d bytes, %v, want %d", len(sharedV2), err, len(ownV2))
+	}
+}
+
 // TestCompressBlockV2Parallel tests the parallel V2 compression functions
 func TestCompressBlockV2Parallel(t *testing.T) {
 	testSizes := []int{
@@ -212,6 +215,14 @@ func testParallelWriter(t *testing.T, input []byte) {
 		})
 	})
 
+	// Test with block checksums, which the reader verifies
+	testParallelWriterConfig(t, input, func() *ParallelWriter {
+		return NewParallelWriterWithOptions(bytes.NewBuffer(nil), ParallelWriterOptions{
+			Level:         6,
+			BlockChecksum: compress.XXH32,
+		})
+	})
+
 	// Test with custom workers and chunk size
 	testParallelWriterConfig(t, input, func() *ParallelWriter {
 		pw := NewParallelWriter(bytes.NewBuffer(nil))
@@ -485,3 +496,32 @@ func byteSizeToString(size int) string {
 	}
 	return string(rune('0'+size/(1024*1024*1024))) + "GB"
 }
+
+// TestParallelWriterOptionsValidate tests that Validate reports settings
+// NewParallelWriterWithOptions would replace
+func TestParallelWriterOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ParallelWriterOptions
+		want error
+	}{
+		{"Valid", ParallelWriterOptions{Level: 9, NumWorkers: 2, ChunkSize: 64 * 1024}, nil},
+		{"ZeroLevel", ParallelWriterOptions{}, compress.ErrInvalidCompressionLevel},
+		{"HighLevel", ParallelWriterOptions{Level: 13}, compress.ErrInvalidCompressionLevel},
+		{"NumWorkers", ParallelWriterOptions{Level: 9, NumWorkers: -1}, compress.ErrInvalidNumWorkers},
+		{"ChunkSize", ParallelWriterOptions{Level: 9, ChunkSize: compress.MaxBlockSize + 1}, compress.ErrInvalidBlockSize},
+	}
+
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); err != tt.want {
+			t.Errorf("%s: Validate() = %v, want %v", tt.name, err, tt.want)
+		}
+		pw, err := NewCheckedParallelWriter(io.Discard, tt.opts)
+		if err != tt.want {
+			t.Errorf("%s: NewCheckedParallelWriter() error = %v, want %v", tt.name, err, tt.want)
+		}
+		if pw != nil {
+			pw.Close()
+		}
+	}
+}
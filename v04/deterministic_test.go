@@ -0,0 +1,90 @@
+package v04
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/harriteja/GoZ4X/compress"
+	"github.com/harriteja/GoZ4X/v04/simd"
+)
+
+// TestDeterministicOutput verifies that Deterministic compression produces the
+// same bytes on every SIMD path and worker count, matching the reference encoder
+func TestDeterministicOutput(t *testing.T) {
+	dataSets := []struct {
+		name string
+		data []byte
+	}{
+		{"Compressible", generateCompressibleData(mediumSize)},
+		{"Incompressible", generateIncompressibleData(smallSize)},
+	}
+
+	impls := []int{simd.ImplGeneric, simd.ImplSSE41, simd.ImplAVX2, simd.ImplAVX512, simd.ImplNEON}
+	workerCounts := []int{1, 2, runtime.NumCPU()}
+
+	for _, ds := range dataSets {
+		for _, useV2 := range []bool{false, true} {
+			for _, level := range []CompressionLevel{MinLevel, DefaultLevel, MaxLevel} {
+				var reference []byte
+				var err error
+				if useV2 {
+					reference, err = compress.CompressBlockV2Level(ds.data, nil, compress.CompressionLevel(level))
+				} else {
+					reference, err = compress.CompressBlockLevel(ds.data, nil, compress.CompressionLevel(level))
+				}
+				if err != nil {
+					t.Fatalf("reference compression failed: %v", err)
+				}
+
+				for _, impl := range impls {
+					for _, workers := range workerCounts {
+						opts := DefaultOptions()
+						opts.Level = level
+						opts.UseV2 = useV2
+						opts.SIMDImpl = impl
+						opts.NumWorkers = workers
+						opts.Deterministic = true
+
+						got, err := CompressBlockWithOptions(ds.data, nil, opts)
+						if err != nil {
+							t.Fatalf("CompressBlockWithOptions() error = %v", err)
+						}
+						if !bytes.Equal(got, reference) {
+							t.Errorf("%s v2=%v level=%d impl=%s workers=%d: output differs from reference encoder",
+								ds.name, useV2, level, simd.ImplementationName(impl), workers)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestDeterministicParallelOutput verifies that parallel compression output
+// does not depend on the number of workers or GOMAXPROCS
+func TestDeterministicParallelOutput(t *testing.T) {
+	data := generateCompressibleData(largeSize)
+
+	var reference []byte
+	for _, procs := range []int{1, 2, runtime.NumCPU()} {
+		prev := runtime.GOMAXPROCS(procs)
+
+		opts := DefaultOptions()
+		opts.NumWorkers = procs
+		opts.Deterministic = true
+		got, err := CompressBlockParallelWithOptions(data, nil, opts)
+		runtime.GOMAXPROCS(prev)
+
+		if err != nil {
+			t.Fatalf("CompressBlockParallelWithOptions() error = %v", err)
+		}
+		if reference == nil {
+			reference = got
+			continue
+		}
+		if !bytes.Equal(got, reference) {
+			t.Errorf("GOMAXPROCS=%d: parallel output differs from GOMAXPROCS=1", procs)
+		}
+	}
+}
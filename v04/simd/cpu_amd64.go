@@ -3,28 +3,48 @@
 
 package simd
 
-import (
-	"runtime"
-)
+import "golang.org/x/sys/cpu"
 
-// detectCPUFeaturesImpl provides x86-64 specific CPU feature detection
-func detectCPUFeaturesImpl() {
-	// In a real implementation, this would use CPUID instructions
-	// to detect CPU features.
-
-	// For now, we'll use a conservative approach - most modern CPUs
-	// support at least SSE4.1, so we'll enable it by default
-	hasSSE41 = true
+// x86Flags names the CPUID features in the feature report
+var x86Flags = []struct {
+	name string
+	has  *bool
+}{
+	{"sse2", &cpu.X86.HasSSE2},
+	{"sse3", &cpu.X86.HasSSE3},
+	{"ssse3", &cpu.X86.HasSSSE3},
+	{"sse41", &cpu.X86.HasSSE41},
+	{"sse42", &cpu.X86.HasSSE42},
+	{"popcnt", &cpu.X86.HasPOPCNT},
+	{"bmi1", &cpu.X86.HasBMI1},
+	{"bmi2", &cpu.X86.HasBMI2},
+	{"osxsave", &cpu.X86.HasOSXSAVE},
+	{"avx", &cpu.X86.HasAVX},
+	{"avx2", &cpu.X86.HasAVX2},
+	{"avx512f", &cpu.X86.HasAVX512F},
+	{"avx512cd", &cpu.X86.HasAVX512CD},
+	{"avx512bw", &cpu.X86.HasAVX512BW},
+	{"avx512dq", &cpu.X86.HasAVX512DQ},
+	{"avx512vl", &cpu.X86.HasAVX512VL},
+	{"avx512vbmi", &cpu.X86.HasAVX512VBMI},
+	{"avx512vbmi2", &cpu.X86.HasAVX512VBMI2},
+}
 
-	// We only check runtime.GOARCH here since we're already
-	// behind an amd64 build tag. No need to check again.
-	// Simple version check to enable AVX2 only on go1.18+
-	if hasGoVersion("go1.18") {
-		hasAVX2 = true
+// detectCPUFeaturesImpl reads the x86-64 features from CPUID. The AVX
+// features are only reported if OSXSAVE is set and XCR0 shows that the
+// operating system saves the YMM (and for AVX512, ZMM and mask) registers.
+func detectCPUFeaturesImpl() {
+	report.Source = "cpuid"
+	for _, f := range x86Flags {
+		if *f.has {
+			report.Flags = append(report.Flags, f.name)
+		}
 	}
-}
 
-// hasGoVersion checks if the current Go runtime is at least the given version
-func hasGoVersion(version string) bool {
-	return runtime.Version() >= version
+	hasSSE2 = cpu.X86.HasSSE2
+	hasSSE41 = cpu.X86.HasSSE41
+	hasAVX2 = cpu.X86.HasAVX2
+	// Byte-wise matching needs the byte and word instructions at every
+	// vector length, not just the foundation
+	hasAVX512 = cpu.X86.HasAVX512F && cpu.X86.HasAVX512BW && cpu.X86.HasAVX512VL
 }
@@ -1,10 +1,48 @@
-//go:build arm64
-// +build arm64
+//go:build arm64 && !darwin
+// +build arm64,!darwin
 
 package simd
 
-// detectCPUFeaturesImpl provides ARM64 specific CPU feature detection
+import (
+	"runtime"
+
+	"golang.org/x/sys/cpu"
+)
+
+// arm64Flags names the HWCAP features in the feature report
+var arm64Flags = []struct {
+	name string
+	has  *bool
+}{
+	{"fp", &cpu.ARM64.HasFP},
+	{"asimd", &cpu.ARM64.HasASIMD},
+	{"aes", &cpu.ARM64.HasAES},
+	{"pmull", &cpu.ARM64.HasPMULL},
+	{"sha1", &cpu.ARM64.HasSHA1},
+	{"sha2", &cpu.ARM64.HasSHA2},
+	{"crc32", &cpu.ARM64.HasCRC32},
+	{"atomics", &cpu.ARM64.HasATOMICS},
+	{"asimdhp", &cpu.ARM64.HasASIMDHP},
+	{"asimdrdm", &cpu.ARM64.HasASIMDRDM},
+	{"asimddp", &cpu.ARM64.HasASIMDDP},
+	{"sve", &cpu.ARM64.HasSVE},
+}
+
+// detectCPUFeaturesImpl reads the ARM64 features from the HWCAP auxiliary
+// vector on Linux or sysctl on the BSDs. Where neither is available no
+// features are reported and the generic implementation is used.
 func detectCPUFeaturesImpl() {
-	// ARM64 always has NEON, so we enable it unconditionally
-	hasNEON = true
+	switch runtime.GOOS {
+	case "linux", "android":
+		report.Source = "hwcap"
+	case "netbsd", "openbsd":
+		report.Source = "sysctl"
+	}
+	for _, f := range arm64Flags {
+		if *f.has {
+			report.Flags = append(report.Flags, f.name)
+		}
+	}
+
+	hasNEON = cpu.ARM64.HasASIMD
 }
@@ -0,0 +1,35 @@
+//go:build darwin && arm64
+// +build darwin,arm64
+
+package simd
+
+import "golang.org/x/sys/unix"
+
+// darwinFlags maps sysctl keys to the features in the feature report
+var darwinFlags = []struct {
+	name string
+	key  string
+}{
+	{"asimd", "hw.optional.AdvSIMD"},
+	{"fp", "hw.optional.floatingpoint"},
+	{"crc32", "hw.optional.armv8_crc32"},
+	{"atomics", "hw.optional.armv8_1_atomics"},
+	{"asimdhp", "hw.optional.AdvSIMD_HPFPCvt"},
+	{"asimddp", "hw.optional.arm.FEAT_DotProd"},
+	{"sha2", "hw.optional.arm.FEAT_SHA256"},
+	{"pmull", "hw.optional.arm.FEAT_PMULL"},
+}
+
+// detectCPUFeaturesImpl reads the ARM64 features on macOS from the
+// hw.optional sysctls, as the registers that hold them can't be read there
+func detectCPUFeaturesImpl() {
+	report.Source = "sysctl"
+	for _, f := range darwinFlags {
+		if v, err := unix.SysctlUint32(f.key); err == nil && v != 0 {
+			report.Flags = append(report.Flags, f.name)
+			if f.name == "asimd" {
+				hasNEON = true
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package simd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// kernels holds the routines of one implementation that the self-test checks
+// against the golden vectors
+type kernels struct {
+	copyBytes  func(dst, src []byte) int
+	repeatCopy func(dst []byte, pos, offset, length int)
+	matchLen   func(a, b []byte) int
+	newFinder  func(windowSize int) matchFinder
+}
+
+// matchFinder is the match finding interface of every implementation
+type matchFinder interface {
+	Reset(data []byte)
+	FindMatches(p int) []Match
+}
+
+var (
+	// disabled marks the implementations that failed a self-test
+	disabled [ImplNEON + 1]atomic.Bool
+
+	// selfTestOnce runs the self-test before the first implementation is chosen
+	selfTestOnce sync.Once
+
+	// Logf receives a message for every implementation the self-test
+	// disables. It defaults to log.Printf; set it to nil before first use
+	// to stay silent.
+	Logf = log.Printf
+)
+
+// SelfTest runs every implementation available on this CPU against golden
+// vectors and disables any that produces wrong results, such as when the
+// operating system doesn't save the state of wider registers. The choice of
+// BestImplementation then falls back to the next implementation, and each
+// downgrade is reported to Logf. It runs before the first call to
+// BestImplementation and can be run again at any time; an implementation
+// once disabled stays disabled. It returns the failures found, if any.
+func SelfTest() error {
+	return selfTest(kernelsFor)
+}
+
+// selfTest checks the kernels that lookup returns for each available
+// implementation
+func selfTest(lookup func(impl int) *kernels) error {
+	var errs []error
+	for _, impl := range available() {
+		k := lookup(impl)
+		if k == nil {
+			continue
+		}
+		if err := k.check(); err != nil {
+			err = fmt.Errorf("simd: %s self-test failed: %w", ImplementationName(impl), err)
+			errs = append(errs, err)
+			if !disabled[impl].Swap(true) && Logf != nil {
+				Logf("%v; disabling it", err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Disabled reports whether impl was disabled by a failed self-test
+func Disabled(impl int) bool {
+	return impl >= 0 && impl < len(disabled) && disabled[impl].Load()
+}
+
+// available returns the implementations the detected features allow
+func available() []int {
+	f := DetectFeatures()
+	var impls []int
+	if isAMD64 && f.HasSSE41 {
+		impls = append(impls, ImplSSE41)
+	}
+	if isAMD64 && f.HasAVX2 {
+		impls = append(impls, ImplAVX2)
+	}
+	if isAMD64 && f.HasAVX512 {
+		impls = append(impls, ImplAVX512)
+	}
+	if isARM64 && f.HasNEON {
+		impls = append(impls, ImplNEON)
+	}
+	return impls
+}
+
+// goldenData returns n bytes of deterministic pseudo-random data
+func goldenData(n int) []byte {
+	data := make([]byte, n)
+	x := uint32(2463534242)
+	for i := range data {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		data[i] = byte(x)
+	}
+	return data
+}
+
+// goldenLengths covers the sizes around register widths where vector code
+// switches between its main loop and its tail
+var goldenLengths = []int{1, 3, 7, 8, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 129, 1000, 4096}
+
+// check runs the kernels against the golden vectors
+func (k *kernels) check() error {
+	src := goldenData(8192)
+
+	// Copies at every alignment of source and destination
+	for _, n := range goldenLengths {
+		for align := 0; align < 4; align++ {
+			dst := make([]byte, n+align)
+			if got := k.copyBytes(dst[align:], src[align:align+n]); got != n || !bytes.Equal(dst[align:], src[align:align+n]) {
+				return fmt.Errorf("copy of %d bytes at alignment %d is wrong", n, align)
+			}
+		}
+	}
+
+	// Match copies, which overlap their output for offsets below the length
+	for _, offset := range []int{1, 2, 3, 4, 7, 8, 15, 16, 17, 31, 32, 64} {
+		for _, n := range goldenLengths {
+			want := make([]byte, 64+n)
+			copy(want, src[:64])
+			for i := 64; i < len(want); i++ {
+				want[i] = want[i-offset]
+			}
+			got := make([]byte, 64+n)
+			copy(got, src[:64])
+			k.repeatCopy(got, 64, offset, n)
+			if !bytes.Equal(got, want) {
+				return fmt.Errorf("match copy of %d bytes at offset %d is wrong", n, offset)
+			}
+		}
+	}
+
+	// Match lengths, with the first difference anywhere in the range
+	for _, n := range goldenLengths {
+		for _, diff := range []int{0, n / 2, n - 1, n} {
+			b := bytes.Clone(src[:n])
+			if diff < n {
+				b[diff]++
+			}
+			if got := k.matchLen(src[:n], b); got != diff {
+				return fmt.Errorf("match length of %d bytes differing at %d = %d", n, diff, got)
+			}
+		}
+	}
+
+	// Matches found in data that repeats with a known period
+	const period = 100
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = src[i%period]
+	}
+	mf := k.newFinder(64 * 1024)
+	mf.Reset(data)
+	found := false
+	for p := 0; p < len(data); p++ {
+		for _, m := range mf.FindMatches(p) {
+			if m.Offset <= 0 || m.Offset > p || m.Length < 4 || p+m.Length > len(data) ||
+				!bytes.Equal(data[p:p+m.Length], data[p-m.Offset:p-m.Offset+m.Length]) {
+				return fmt.Errorf("invalid match at %d: offset %d, length %d", p, m.Offset, m.Length)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("no matches found in repeating data")
+	}
+	return nil
+}
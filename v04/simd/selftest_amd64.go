@@ -0,0 +1,27 @@
+//go:build amd64
+// +build amd64
+
+package simd
+
+import "unsafe"
+
+// kernelsFor returns the kernels of impl, or nil if it has none on this
+// platform. The AVX implementations share the SSE kernels for now.
+func kernelsFor(impl int) *kernels {
+	switch impl {
+	case ImplSSE41, ImplAVX2, ImplAVX512:
+		opt, c := NewSSECopyOptimizer(), NewSSECopier()
+		return &kernels{
+			copyBytes:  opt.CopyBytes,
+			repeatCopy: c.RepeatCopy16,
+			matchLen: func(a, b []byte) int {
+				if len(a) == 0 {
+					return 0
+				}
+				return countMatchingBytesSSE(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), len(a))
+			},
+			newFinder: func(windowSize int) matchFinder { return NewSSEMatchFinder(windowSize, 4) },
+		}
+	}
+	return nil
+}
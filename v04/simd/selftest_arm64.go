@@ -0,0 +1,26 @@
+//go:build arm64
+// +build arm64
+
+package simd
+
+import "unsafe"
+
+// kernelsFor returns the kernels of impl, or nil if it has none on this
+// platform
+func kernelsFor(impl int) *kernels {
+	if impl != ImplNEON {
+		return nil
+	}
+	opt, c := NewNEONCopyOptimizer(), NewNEONCopier()
+	return &kernels{
+		copyBytes:  opt.CopyBytes,
+		repeatCopy: c.RepeatCopy16,
+		matchLen: func(a, b []byte) int {
+			if len(a) == 0 {
+				return 0
+			}
+			return countMatchingBytesNEON(unsafe.Pointer(&a[0]), unsafe.Pointer(&b[0]), len(a))
+		},
+		newFinder: func(windowSize int) matchFinder { return NewNEONMatchFinder(windowSize, 4) },
+	}
+}
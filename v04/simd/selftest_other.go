@@ -0,0 +1,9 @@
+//go:build !amd64 && !arm64
+// +build !amd64,!arm64
+
+package simd
+
+// kernelsFor returns nil, as there are no SIMD kernels on this platform
+func kernelsFor(impl int) *kernels {
+	return nil
+}
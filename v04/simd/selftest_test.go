@@ -0,0 +1,63 @@
+package simd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSelfTest tests that the implementations on this CPU pass the self-test
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	for impl := ImplGeneric; impl <= ImplNEON; impl++ {
+		if Disabled(impl) {
+			t.Errorf("Disabled(%s) = true, want false", ImplementationName(impl))
+		}
+	}
+}
+
+// TestSelfTestDisables tests that an implementation producing wrong results
+// is disabled and BestImplementation falls back
+func TestSelfTestDisables(t *testing.T) {
+	impls := available()
+	if len(impls) == 0 {
+		t.Skip("no SIMD implementations on this CPU")
+	}
+	best := BestImplementation()
+	defer func() {
+		for i := range disabled {
+			disabled[i].Store(false)
+		}
+	}()
+
+	var logged []string
+	defer func(f func(string, ...any)) { Logf = f }(Logf)
+	Logf = func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+
+	// Break the match copy of the best implementation only
+	err := selfTest(func(impl int) *kernels {
+		k := kernelsFor(impl)
+		if impl == best && k != nil {
+			k.repeatCopy = func(dst []byte, pos, offset, length int) {
+				copy(dst[pos:pos+length], dst[pos-offset:])
+			}
+		}
+		return k
+	})
+	if err == nil {
+		t.Fatal("selfTest() with a broken kernel succeeded")
+	}
+	if !Disabled(best) {
+		t.Errorf("Disabled(%s) = false, want true", ImplementationName(best))
+	}
+	if got := BestImplementation(); got == best {
+		t.Errorf("BestImplementation() = %s, want a fallback", ImplementationName(got))
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], ImplementationName(best)) {
+		t.Errorf("logged %q, want one message naming %s", logged, ImplementationName(best))
+	}
+}
@@ -25,6 +25,9 @@ var (
 	hasAVX512 bool
 	hasNEON   bool
 
+	// report holds the raw features the flags were derived from
+	report FeatureReport
+
 	// Initialization
 	detectOnce sync.Once
 )
@@ -47,6 +50,28 @@ type Features struct {
 	HasNEON   bool
 }
 
+// FeatureReport is the raw CPU feature report that Features is derived
+// from, for telemetry
+type FeatureReport struct {
+	// Arch is the architecture, as in runtime.GOARCH
+	Arch string
+	// Source is where the features were read from: "cpuid" on amd64,
+	// "hwcap" or "sysctl" on arm64, or "none" where they can't be read
+	Source string
+	// Flags names the features found, such as "avx2" or "asimd". On amd64
+	// it includes the AVX512 subfeatures and "osxsave", and lists AVX and
+	// AVX512 features only if the operating system saves their registers.
+	Flags []string
+}
+
+// Report returns the raw CPU feature report
+func Report() FeatureReport {
+	DetectFeatures()
+	r := report
+	r.Flags = append([]string(nil), report.Flags...)
+	return r
+}
+
 // DetectFeatures initializes CPU feature detection
 func DetectFeatures() Features {
 	detectOnce.Do(func() {
@@ -62,49 +87,39 @@ func DetectFeatures() Features {
 	}
 }
 
-// detectCPUFeatures performs CPU feature detection
+// detectCPUFeatures performs CPU feature detection. Nothing is assumed from
+// the architecture alone: each flag is set only if the CPU reports the
+// feature and, for wider registers, the operating system saves their state.
 func detectCPUFeatures() {
-	// Default values based on architecture
-	if isAMD64 {
-		// x86-64 always has SSE2
-		hasSSE2 = true
-
-		// For now, make conservative assumptions about other features
-		// The actual runtime detection is implemented in CPU-specific files
-		hasSSE41 = true   // Assume SSE4.1 support for x86-64
-		hasAVX2 = false   // Don't assume AVX2 by default
-		hasAVX512 = false // Don't assume AVX512 by default
-	}
-
-	if isARM64 {
-		// ARM64 always has NEON
-		hasNEON = true
-	}
+	report.Arch = runtime.GOARCH
+	report.Source = "none"
 
 	// Call architecture-specific detection
 	// This function is implemented in CPU-specific files with build tags
 	detectCPUFeaturesImpl()
 }
 
-// BestImplementation returns the best SIMD implementation available on this CPU
+// BestImplementation returns the best SIMD implementation available on this
+// CPU that passed SelfTest, which runs on the first call
 func BestImplementation() int {
-	// Ensure features are detected
+	// Ensure features are detected and the implementations checked
 	DetectFeatures()
+	selfTestOnce.Do(func() { SelfTest() })
 
 	// Check for best available implementation
 	if isAMD64 {
-		if hasAVX512 {
+		if hasAVX512 && !Disabled(ImplAVX512) {
 			return ImplAVX512
 		}
-		if hasAVX2 {
+		if hasAVX2 && !Disabled(ImplAVX2) {
 			return ImplAVX2
 		}
-		if hasSSE41 {
+		if hasSSE41 && !Disabled(ImplSSE41) {
 			return ImplSSE41
 		}
 	}
 
-	if isARM64 && hasNEON {
+	if isARM64 && hasNEON && !Disabled(ImplNEON) {
 		return ImplNEON
 	}
 
@@ -83,3 +83,46 @@ func TestMatchFinding(t *testing.T) {
 		}
 	})
 }
+
+// TestFeatureReport tests that the feature flags agree with the raw report
+func TestFeatureReport(t *testing.T) {
+	r := Report()
+	f := DetectFeatures()
+	t.Logf("Feature report: %s via %s: %v", r.Arch, r.Source, r.Flags)
+
+	if r.Arch != runtime.GOARCH {
+		t.Errorf("Report().Arch = %q, want %q", r.Arch, runtime.GOARCH)
+	}
+	has := make(map[string]bool)
+	for _, flag := range r.Flags {
+		has[flag] = true
+	}
+
+	switch runtime.GOARCH {
+	case "amd64":
+		if r.Source != "cpuid" {
+			t.Errorf("Report().Source = %q, want %q", r.Source, "cpuid")
+		}
+		if f.HasSSE41 != has["sse41"] || f.HasAVX2 != has["avx2"] {
+			t.Errorf("Features %+v disagree with report %v", f, r.Flags)
+		}
+		if f.HasAVX512 != (has["avx512f"] && has["avx512bw"] && has["avx512vl"]) {
+			t.Errorf("HasAVX512 = %v with report %v", f.HasAVX512, r.Flags)
+		}
+		if (has["avx"] || has["avx2"]) && !has["osxsave"] {
+			t.Errorf("AVX reported without OSXSAVE: %v", r.Flags)
+		}
+	case "arm64":
+		if f.HasNEON != has["asimd"] {
+			t.Errorf("HasNEON = %v with report %v", f.HasNEON, r.Flags)
+		}
+	}
+
+	// The report is a copy
+	if len(r.Flags) > 0 {
+		r.Flags[0] = "changed"
+		if Report().Flags[0] == "changed" {
+			t.Error("Report() shares its flags with the caller")
+		}
+	}
+}
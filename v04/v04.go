@@ -4,6 +4,7 @@ package v04
 
 import (
 	"errors"
+	"math"
 	"runtime"
 
 	"github.com/harriteja/GoZ4X/compress"
@@ -11,10 +12,15 @@ import (
 	"github.com/harriteja/GoZ4X/v04/simd"
 )
 
-// MaxCompressedSize returns the maximum size required for compressing data of length sourceSize
+// MaxCompressedSize returns the maximum size required for compressing data of length sourceSize.
+// It returns math.MaxInt when the bound doesn't fit in an int, as on 32-bit
+// platforms for sizes close to 2GB.
 func MaxCompressedSize(sourceSize int) int {
 	// LZ4 worst case size formula:
 	// Maximum output = input + (input / 255) + 16
+	if sourceSize > math.MaxInt-16-sourceSize/255 {
+		return math.MaxInt
+	}
 	return sourceSize + (sourceSize / 255) + 16
 }
 
@@ -55,6 +61,12 @@ type Options struct {
 
 	// Number of worker goroutines for parallel compression
 	NumWorkers int
+
+	// Deterministic guarantees byte-identical output for identical input and
+	// options, regardless of the SIMD implementation detected at runtime or
+	// the number of workers. It pins compression to the reference encoder,
+	// which makes the output safe to checksum across machines.
+	Deterministic bool
 }
 
 // DefaultOptions returns the default options for v0.4 compression
@@ -101,6 +113,11 @@ func CompressBlockWithOptions(src []byte, dst []byte, opts Options) ([]byte, err
 		simdImpl = simd.BestImplementation()
 	}
 
+	// Deterministic output must not depend on the CPU we happen to run on
+	if opts.Deterministic {
+		simdImpl = simd.ImplGeneric
+	}
+
 	// Use SIMD optimizations when available
 	switch simdImpl {
 	case simd.ImplSSE41:
@@ -163,6 +180,8 @@ func CompressBlockParallelWithOptions(src []byte, dst []byte, opts Options) ([]b
 
 	// For now, all implementations use the v0.3 parallel framework with our SIMD acceleration
 	// In the future, we'll have SIMD-specific parallel implementations
+	// The v0.3 framework splits input on fixed chunk boundaries, so the output
+	// never depends on NumWorkers and is deterministic in both modes
 	if opts.UseV2 {
 		return v03.CompressBlockV2ParallelLevel(src, dst, int(opts.Level))
 	}
@@ -0,0 +1,29 @@
+package goz4x
+
+import (
+	"io"
+
+	"github.com/harriteja/GoZ4X/compress"
+)
+
+// NewCheckedWriter creates a new Writer with custom options, returning the
+// error of opts.Validate instead of falling back to defaults for invalid
+// settings. Unlike NewWriterWithOptions it requires an explicit Level unless
+// Store is set.
+func NewCheckedWriter(w io.Writer, opts WriterOptions) (*Writer, error) {
+	cw, err := compress.NewCheckedWriter(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: cw}, nil
+}
+
+// NewCheckedWriterLevel creates a new Writer like NewWriterLevel, but returns
+// an error for levels outside 0 to 12 instead of using the default level.
+func NewCheckedWriterLevel(w io.Writer, level int) (*Writer, error) {
+	cw, err := compress.NewCheckedWriterLevel(w, compress.CompressionLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: cw}, nil
+}
@@ -0,0 +1,34 @@
+package goz4x
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestCheckedWriters tests the constructors that validate their options
+func TestCheckedWriters(t *testing.T) {
+	if _, err := NewCheckedWriter(io.Discard, WriterOptions{}); err == nil {
+		t.Errorf("NewCheckedWriter with no level succeeded")
+	}
+	if _, err := NewCheckedWriterLevel(io.Discard, 13); err == nil {
+		t.Errorf("NewCheckedWriterLevel(13) succeeded")
+	}
+
+	data := generateCompressibleData(100 * 1024)
+	var buf bytes.Buffer
+	w, err := NewCheckedWriter(&buf, WriterOptions{Level: 9, BlockSize: 64 * 1024})
+	if err != nil {
+		t.Fatalf("NewCheckedWriter error: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	got, err := io.ReadAll(NewReader(&buf))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadAll returned %d bytes, %v, want %d bytes", len(got), err, len(data))
+	}
+}
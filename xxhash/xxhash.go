@@ -0,0 +1,148 @@
+// Package xxhash implements the 32-bit and 64-bit xxHash algorithms.
+//
+// XXH32 is the checksum of the LZ4 frame format, so Sum32 verifies the
+// header, block and content checksums of LZ4 frames. XXH64 is faster on
+// 64-bit platforms and suits general purpose hashing, such as keying
+// dictionaries. On amd64 the inner loops of both are written in assembly;
+// other platforms, and builds with the purego tag, use the pure Go code.
+package xxhash
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"github.com/harriteja/GoZ4X/internal/xxh32"
+	"github.com/harriteja/GoZ4X/internal/xxh64"
+)
+
+// Sizes of the checksums in bytes
+const (
+	Size32 = 4
+	Size64 = 8
+)
+
+// Sum32 returns the XXH32 checksum of b with a zero seed
+func Sum32(b []byte) uint32 {
+	return xxh32.Checksum(b)
+}
+
+// Sum32Seed returns the XXH32 checksum of b with the given seed
+func Sum32Seed(b []byte, seed uint32) uint32 {
+	return xxh32.ChecksumSeed(b, seed)
+}
+
+// Sum64 returns the XXH64 checksum of b with a zero seed
+func Sum64(b []byte) uint64 {
+	return xxh64.Checksum(b)
+}
+
+// Sum64Seed returns the XXH64 checksum of b with the given seed
+func Sum64Seed(b []byte, seed uint64) uint64 {
+	return xxh64.ChecksumSeed(b, seed)
+}
+
+// Digest32 computes an XXH32 checksum incrementally.
+// It implements hash.Hash32; Sum appends the checksum in big-endian order.
+type Digest32 struct {
+	d *xxh32.Digest
+}
+
+var _ hash.Hash32 = (*Digest32)(nil)
+
+// New32 creates a new Digest32 with a zero seed
+func New32() *Digest32 {
+	return New32Seed(0)
+}
+
+// New32Seed creates a new Digest32 with the given seed
+func New32Seed(seed uint32) *Digest32 {
+	return &Digest32{d: xxh32.NewSeed(seed)}
+}
+
+// Write adds more data to the running checksum. It never returns an error.
+func (d *Digest32) Write(p []byte) (int, error) {
+	return d.d.Write(p)
+}
+
+// WriteString adds a string to the running checksum. It never returns an error.
+func (d *Digest32) WriteString(s string) (int, error) {
+	return d.d.Write([]byte(s))
+}
+
+// Sum32 returns the checksum of the data written so far
+func (d *Digest32) Sum32() uint32 {
+	return d.d.Sum32()
+}
+
+// Sum appends the checksum of the data written so far to b
+func (d *Digest32) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint32(b, d.d.Sum32())
+}
+
+// Reset restores the Digest32 to its initial state, keeping its seed
+func (d *Digest32) Reset() {
+	d.d.Reset()
+}
+
+// Size returns the number of bytes Sum appends
+func (d *Digest32) Size() int {
+	return Size32
+}
+
+// BlockSize returns the size of the stripes the algorithm consumes
+func (d *Digest32) BlockSize() int {
+	return 16
+}
+
+// Digest64 computes an XXH64 checksum incrementally.
+// It implements hash.Hash64; Sum appends the checksum in big-endian order.
+type Digest64 struct {
+	d *xxh64.Digest
+}
+
+var _ hash.Hash64 = (*Digest64)(nil)
+
+// New64 creates a new Digest64 with a zero seed
+func New64() *Digest64 {
+	return New64Seed(0)
+}
+
+// New64Seed creates a new Digest64 with the given seed
+func New64Seed(seed uint64) *Digest64 {
+	return &Digest64{d: xxh64.NewSeed(seed)}
+}
+
+// Write adds more data to the running checksum. It never returns an error.
+func (d *Digest64) Write(p []byte) (int, error) {
+	return d.d.Write(p)
+}
+
+// WriteString adds a string to the running checksum. It never returns an error.
+func (d *Digest64) WriteString(s string) (int, error) {
+	return d.d.Write([]byte(s))
+}
+
+// Sum64 returns the checksum of the data written so far
+func (d *Digest64) Sum64() uint64 {
+	return d.d.Sum64()
+}
+
+// Sum appends the checksum of the data written so far to b
+func (d *Digest64) Sum(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, d.d.Sum64())
+}
+
+// Reset restores the Digest64 to its initial state, keeping its seed
+func (d *Digest64) Reset() {
+	d.d.Reset()
+}
+
+// Size returns the number of bytes Sum appends
+func (d *Digest64) Size() int {
+	return Size64
+}
+
+// BlockSize returns the size of the stripes the algorithm consumes
+func (d *Digest64) BlockSize() int {
+	return 32
+}
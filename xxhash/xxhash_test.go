@@ -0,0 +1,69 @@
+package xxhash
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestSum tests the one-shot checksums against reference vectors
+func TestSum(t *testing.T) {
+	tests := []struct {
+		input  string
+		want32 uint32
+		want64 uint64
+	}{
+		{"", 0x02CC5D05, 0xEF46DB3751D8E999},
+		{"abc", 0x32D153FF, 0x44BC2CF5AD770999},
+		{"Nobody inspects the spammish repetition", 0xE2293B2F, 0xFBCEA83C8A378BF1},
+	}
+
+	for _, tt := range tests {
+		if got := Sum32([]byte(tt.input)); got != tt.want32 {
+			t.Errorf("Sum32(%q) = %#08x, want %#08x", tt.input, got, tt.want32)
+		}
+		if got := Sum64([]byte(tt.input)); got != tt.want64 {
+			t.Errorf("Sum64(%q) = %#016x, want %#016x", tt.input, got, tt.want64)
+		}
+	}
+
+	if got := Sum64Seed([]byte("Nobody inspects the spammish repetition"), 0xDEADBEEF); got != 0x1366D5F609C44B7D {
+		t.Errorf("Sum64Seed() = %#016x, want %#016x", got, uint64(0x1366D5F609C44B7D))
+	}
+}
+
+// TestDigest tests the streaming digests against the one-shot checksums
+func TestDigest(t *testing.T) {
+	input := strings.Repeat("streaming xxhash digest ", 100)
+
+	d32 := New32Seed(7)
+	d64 := New64Seed(7)
+	if _, err := io.Copy(io.MultiWriter(d32, d64), iotest.HalfReader(strings.NewReader(input))); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+
+	if got, want := d32.Sum32(), Sum32Seed([]byte(input), 7); got != want {
+		t.Errorf("Digest32.Sum32() = %#08x, want %#08x", got, want)
+	}
+	if got, want := d64.Sum64(), Sum64Seed([]byte(input), 7); got != want {
+		t.Errorf("Digest64.Sum64() = %#016x, want %#016x", got, want)
+	}
+
+	// Sum appends big-endian bytes without changing the state
+	if got := d64.Sum([]byte{0xFF}); len(got) != 1+Size64 || got[0] != 0xFF || got[8] != byte(d64.Sum64()) {
+		t.Errorf("Digest64.Sum() = %x", got)
+	}
+	if got := d32.Sum(nil); len(got) != Size32 || got[3] != byte(d32.Sum32()) {
+		t.Errorf("Digest32.Sum() = %x", got)
+	}
+
+	// Reset keeps the seed
+	d32.Reset()
+	d64.Reset()
+	d32.WriteString("abc")
+	d64.WriteString("abc")
+	if d32.Sum32() != Sum32Seed([]byte("abc"), 7) || d64.Sum64() != Sum64Seed([]byte("abc"), 7) {
+		t.Errorf("checksums after Reset don't match the seeded one-shot checksums")
+	}
+}